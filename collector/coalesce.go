@@ -0,0 +1,120 @@
+package collector
+
+import (
+	"time"
+)
+
+// pendingUpdate is an updated event held back by the updateCoalescer
+type pendingUpdate struct {
+	event    ProcessEvent
+	deadline time.Time // When the first held update of the PID is dispatched
+}
+
+// updateCoalescer merges the updated events of a PID received within a
+// window into one event carrying the latest snapshot. It is only used by
+// the event processor goroutine and is not safe for concurrent use
+type updateCoalescer struct {
+	window  time.Duration
+	pending map[int]*pendingUpdate
+}
+
+// newUpdateCoalescer creates a coalescer holding updates for window
+func newUpdateCoalescer(window time.Duration) *updateCoalescer {
+	return &updateCoalescer{
+		window:  window,
+		pending: make(map[int]*pendingUpdate),
+	}
+}
+
+// add holds an updated event, merging it into the held update of its PID
+// if any, and returns the events to dispatch now. Other events are returned
+// after the held update of their PID, so consumers see them in order. The
+// second result is whether the event was merged into a held update
+func (c *updateCoalescer) add(event ProcessEvent, now time.Time) ([]ProcessEvent, bool) {
+	pid := event.Process.PID
+	held, ok := c.pending[pid]
+
+	if event.Type != ProcessUpdated {
+		if !ok {
+			return []ProcessEvent{event}, false
+		}
+		delete(c.pending, pid)
+		return []ProcessEvent{held.event, event}, false
+	}
+
+	if !ok {
+		c.pending[pid] = &pendingUpdate{event: event, deadline: now.Add(c.window)}
+		return nil, false
+	}
+
+	held.event = mergeUpdates(held.event, event)
+	return nil, true
+}
+
+// mergeUpdates returns the latest of two updated events of a process, with
+// their combined delta and executable change
+func mergeUpdates(earlier, latest ProcessEvent) ProcessEvent {
+	merged := latest
+	merged.ExecutableChanged = earlier.ExecutableChanged || latest.ExecutableChanged
+	merged.Delta = combineDeltas(earlier.Delta, latest.Delta)
+	return merged
+}
+
+// combineDeltas returns the change over two consecutive deltas, nil if
+// either is unknown. Rates are over the combined time
+func combineDeltas(earlier, latest *DeltaProcessInfo) *DeltaProcessInfo {
+	if earlier == nil || latest == nil {
+		return nil
+	}
+
+	combined := &DeltaProcessInfo{
+		PID:          latest.PID,
+		DeltaTime:    earlier.DeltaTime + latest.DeltaTime,
+		CPU:          earlier.CPU + latest.CPU,
+		RSS:          earlier.RSS + latest.RSS,
+		IOReadBytes:  earlier.IOReadBytes + latest.IOReadBytes,
+		IOWriteBytes: earlier.IOWriteBytes + latest.IOWriteBytes,
+	}
+	if combined.DeltaTime > 0 {
+		combined.IOReadBytesPerSec = perSecond(combined.IOReadBytes, combined.DeltaTime)
+		combined.IOWriteBytesPerSec = perSecond(combined.IOWriteBytes, combined.DeltaTime)
+	}
+	return combined
+}
+
+// due removes and returns the held updates whose window ended by now
+func (c *updateCoalescer) due(now time.Time) []ProcessEvent {
+	var events []ProcessEvent
+	for pid, held := range c.pending {
+		if !now.Before(held.deadline) {
+			events = append(events, held.event)
+			delete(c.pending, pid)
+		}
+	}
+	return events
+}
+
+// flush removes and returns all held updates
+func (c *updateCoalescer) flush() []ProcessEvent {
+	events := make([]ProcessEvent, 0, len(c.pending))
+	for pid, held := range c.pending {
+		events = append(events, held.event)
+		delete(c.pending, pid)
+	}
+	return events
+}
+
+// next returns the time until the earliest held update is due, false when
+// no update is held
+func (c *updateCoalescer) next(now time.Time) (time.Duration, bool) {
+	var earliest time.Time
+	for _, held := range c.pending {
+		if earliest.IsZero() || held.deadline.Before(earliest) {
+			earliest = held.deadline
+		}
+	}
+	if earliest.IsZero() {
+		return 0, false
+	}
+	return max(earliest.Sub(now), 0), true
+}
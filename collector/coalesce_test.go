@@ -0,0 +1,115 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestUpdateCoalescer(t *testing.T) {
+	c := newUpdateCoalescer(time.Second)
+	now := time.Now()
+
+	update := func(cpu float64) ProcessEvent {
+		return ProcessEvent{
+			Type:    ProcessUpdated,
+			Process: &ProcessInfo{PID: 1, CPU: cpu},
+			Delta:   &DeltaProcessInfo{PID: 1, DeltaTime: 100 * time.Millisecond, CPU: 1},
+		}
+	}
+
+	if events, merged := c.add(update(1), now); len(events) != 0 || merged {
+		t.Fatalf("Expected the first update to be held, got %v merged %v", events, merged)
+	}
+	if events, merged := c.add(update(2), now); len(events) != 0 || !merged {
+		t.Fatalf("Expected the second update to be merged, got %v merged %v", events, merged)
+	}
+
+	if wait, ok := c.next(now); !ok || wait != time.Second {
+		t.Errorf("Expected the held update to be due in 1s, got %v %v", wait, ok)
+	}
+	if events := c.due(now.Add(999 * time.Millisecond)); len(events) != 0 {
+		t.Errorf("Expected no due update before the window ends, got %v", events)
+	}
+
+	events := c.due(now.Add(time.Second))
+	if len(events) != 1 || events[0].Process.CPU != 2 {
+		t.Fatalf("Expected the latest update, got %v", events)
+	}
+	if delta := events[0].Delta; delta == nil || delta.CPU != 2 || delta.DeltaTime != 200*time.Millisecond {
+		t.Errorf("Expected the combined delta, got %+v", delta)
+	}
+	if _, ok := c.next(now); ok {
+		t.Errorf("Expected no held update")
+	}
+
+	// Other events of the PID are dispatched after its held update
+	c.add(update(3), now)
+	events, _ = c.add(ProcessEvent{Type: ProcessTerminated, Process: &ProcessInfo{PID: 1}}, now)
+	if len(events) != 2 || events[0].Type != ProcessUpdated || events[1].Type != ProcessTerminated {
+		t.Fatalf("Expected the held update then the terminated event, got %v", events)
+	}
+
+	// Events of other PIDs are not held back
+	c.add(update(4), now)
+	events, _ = c.add(ProcessEvent{Type: ProcessCreated, Process: &ProcessInfo{PID: 2}}, now)
+	if len(events) != 1 || events[0].Process.PID != 2 {
+		t.Fatalf("Expected only the created event of PID 2, got %v", events)
+	}
+	if events := c.flush(); len(events) != 1 || events[0].Process.CPU != 4 {
+		t.Errorf("Expected flush to return the held update, got %v", events)
+	}
+}
+
+func TestProcessScanner_UpdateCoalesceWindow(t *testing.T) {
+	config := DefaultConfig().ProcessScanner
+	config.BackpressurePolicy = BackpressureBlock
+	config.UpdateCoalesceWindow = 50 * time.Millisecond
+
+	p := NewProcessScanner(config)
+	consumer := NewMockProcessConsumer()
+	p.RegisterConsumer("test", consumer)
+
+	// One created event then 10 rapid updates of the same process
+	start := time.Now()
+	for i := 0; i <= 10; i++ {
+		p.processNewScan([]*ProcessInfo{{
+			PID:         1,
+			Name:        "busy",
+			CPU:         float64(i),
+			RSS:         int64(i) * 1024,
+			LastUpdated: start.Add(time.Duration(i) * 100 * time.Millisecond),
+		}})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.ctx = ctx
+	p.wg.Add(1)
+	go p.processEvents()
+	defer func() {
+		cancel()
+		p.wg.Wait()
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(consumer.GetEvents()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	events := consumer.GetEvents()
+	if len(events) != 2 || events[0].Type != ProcessCreated || events[1].Type != ProcessUpdated {
+		t.Fatalf("Expected a created and a single coalesced updated event, got %d events", len(events))
+	}
+
+	updated := events[1]
+	if updated.Process.CPU != 10 {
+		t.Errorf("Expected the latest snapshot, got CPU %.1f", updated.Process.CPU)
+	}
+	if delta := updated.Delta; delta == nil || delta.CPU != 10 || delta.RSS != 10*1024 || delta.DeltaTime != time.Second {
+		t.Errorf("Expected the combined delta of the 10 updates, got %+v", delta)
+	}
+	if coalesced := p.metrics.GetCounter(MetricEventsCoalesced); coalesced != 9 {
+		t.Errorf("Expected 9 coalesced events, got %d", coalesced)
+	}
+}
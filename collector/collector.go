@@ -8,8 +8,9 @@ import (
 
 // Collector defines the interface that all collectors must implement.
 type Collector interface {
-	// Init initializes the collector with a context
-	Init(ctx context.Context) error
+	// Init initializes the collector with a context and collector specific
+	// options, which may be nil
+	Init(ctx context.Context, options map[string]interface{}) error
 	
 	// Start begins the collection process
 	Start() error
@@ -86,6 +87,21 @@ type ProcessEvent struct {
 	
 	// Timestamp of the event
 	Timestamp time.Time
+	
+	// HostMemoryTotal and HostMemoryUsed are the host memory in bytes,
+	// read once per scan so all events of a scan share them. Zero when
+	// the platform could not report them
+	HostMemoryTotal uint64
+	HostMemoryUsed  uint64
+	
+	// ExecutableChanged is set on updated events when the executable hash
+	// of a running process changed since the previous scan
+	ExecutableChanged bool
+	
+	// Delta is the change of the process since it was last reported. It is
+	// set on updated events when UpdateCoalesceWindow is positive, and is
+	// nil when the samples have no LastUpdated time
+	Delta *DeltaProcessInfo
 }
 
 // ProcessEventType defines the type of process event
@@ -102,8 +118,28 @@ const (
 	ProcessTerminated ProcessEventType = "terminated"
 )
 
-// ProcessConsumer defines the interface for components that consume process information
+// ProcessConsumer defines the interface for components that consume process
+// information. It is the only interface accepted by the scanner and the
+// ConsumerRegistry, see ConsumerFunc for functions and AdaptLegacyConsumer
+// for consumers implementing OnProcessEvent
 type ProcessConsumer interface {
 	// HandleProcessEvent handles a process event
 	HandleProcessEvent(event ProcessEvent) error
 }
+
+// ReadOnlyConsumer marks a consumer that never modifies the processes it
+// receives. With ShareProcessPointers enabled it is handed the scanner's own
+// *ProcessInfo, which is immutable once cached, instead of a copy
+type ReadOnlyConsumer interface {
+	ProcessConsumer
+	
+	// ReadOnlyProcesses is a marker method, it is never called
+	ReadOnlyProcesses()
+}
+
+// Closable is implemented by consumers that keep state across events, e.g.
+// to emit a final batch. OnStreamClose is called once by Shutdown, after
+// the last event was delivered
+type Closable interface {
+	OnStreamClose() error
+}
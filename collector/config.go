@@ -20,6 +20,54 @@ type Config struct {
 	ProcessScanner ProcessScannerConfig `yaml:"processScanner"`
 }
 
+// BackpressurePolicy defines how the process scanner handles a full event channel
+type BackpressurePolicy string
+
+const (
+	// BackpressureDrop drops the new event if the channel stays full for 100ms
+	BackpressureDrop BackpressurePolicy = "drop"
+	
+	// BackpressureBlock waits until the event can be queued, stalling the scan
+	BackpressureBlock BackpressurePolicy = "block"
+	
+	// BackpressureDropOldest evicts the oldest queued event to make room
+	BackpressureDropOldest BackpressurePolicy = "drop_oldest"
+)
+
+// ConsumerMode defines how process events are delivered to consumers
+type ConsumerMode string
+
+const (
+	// ConsumerModeSync notifies consumers one after another on the event goroutine
+	ConsumerModeSync ConsumerMode = "sync"
+	
+	// ConsumerModeAsyncPerConsumer gives each consumer its own bounded queue and goroutine
+	ConsumerModeAsyncPerConsumer ConsumerMode = "async_per_consumer"
+)
+
+// CPUReportingMode defines the scale of ProcessInfo.CPU
+type CPUReportingMode string
+
+const (
+	// CPUReportingPerCore reports a percentage of a single core, a process
+	// busy on two cores reports 200%, up to 100% times the number of cores
+	CPUReportingPerCore CPUReportingMode = "per_core"
+	
+	// CPUReportingNormalized reports a percentage of the whole machine, 0-100%
+	CPUReportingNormalized CPUReportingMode = "normalized"
+)
+
+// PatternSyntax defines how include and exclude patterns are interpreted
+type PatternSyntax string
+
+const (
+	// PatternSyntaxRegex compiles patterns as regular expressions
+	PatternSyntaxRegex PatternSyntax = "regex"
+	
+	// PatternSyntaxGlob compiles patterns as path.Match style globs
+	PatternSyntaxGlob PatternSyntax = "glob"
+)
+
 // ProcessScannerConfig holds configuration for the process scanner
 type ProcessScannerConfig struct {
 	// Enabled determines whether process scanning is enabled
@@ -31,12 +79,32 @@ type ProcessScannerConfig struct {
 	// MaxProcesses is the maximum number of processes to track
 	MaxProcesses int `yaml:"maxProcesses"`
 	
+	// MaxProcessesPerScan bounds the processes read by a scan. Over it, a
+	// scan reads the cached processes and a rotating slice of the new ones,
+	// and the others keep their cached values until a later scan. 0 reads
+	// every process. Only Linux supports partial scans
+	MaxProcessesPerScan int `yaml:"maxProcessesPerScan"`
+	
 	// ExcludePatterns are regex patterns for processes to exclude
 	ExcludePatterns []string `yaml:"excludePatterns"`
 	
 	// IncludePatterns are regex patterns for processes to include
 	IncludePatterns []string `yaml:"includePatterns"`
 	
+	// PatternSyntax is the syntax of ExcludePatterns and IncludePatterns
+	PatternSyntax PatternSyntax `yaml:"patternSyntax"`
+	
+	// ExcludeUsers are users whose processes are excluded
+	ExcludeUsers []string `yaml:"excludeUsers"`
+	
+	// IncludeUsers are users whose processes are included
+	IncludeUsers []string `yaml:"includeUsers"`
+	
+	// FilterFile is a file of additional patterns, one per line, prefixed by
+	// '+' to include or '-' to exclude matching processes. It is read by Init
+	// and re-read on SIGHUP or ReloadFilters
+	FilterFile string `yaml:"filterFile"`
+	
 	// ProcFSPath is the path to procfs (Linux only)
 	ProcFSPath string `yaml:"procFSPath"`
 	
@@ -52,11 +120,96 @@ type ProcessScannerConfig struct {
 	// RetryInterval is the time to wait before retrying after a failure
 	RetryInterval time.Duration `yaml:"retryInterval"`
 	
+	// MaxCPUUsage is the CPU percentage of a single core the scanner may use.
+	// Scans over it are reported as limit breaches and slow down adaptive
+	// sampling
+	MaxCPUUsage float64 `yaml:"maxCPUUsage"`
+	
 	// AdaptiveSampling enables adaptive sampling based on system load
 	AdaptiveSampling bool `yaml:"adaptiveSampling"`
 	
 	// MaxScanTime is the maximum time allowed for a full scan
 	MaxScanTime time.Duration `yaml:"maxScanTime"`
+	
+	// BackpressurePolicy determines what happens when the event channel is full
+	BackpressurePolicy BackpressurePolicy `yaml:"backpressurePolicy"`
+	
+	// ConsumerMode determines how events are delivered to consumers
+	ConsumerMode ConsumerMode `yaml:"consumerMode"`
+	
+	// ConsumerTimeout is the maximum time a consumer may take to handle an
+	// event before it is unregistered, 0 disables the timeout
+	ConsumerTimeout time.Duration `yaml:"consumerTimeout"`
+	
+	// ConsumerQueueSize is the size of each consumer queue in async mode
+	ConsumerQueueSize int `yaml:"consumerQueueSize"`
+	
+	// TerminationGracePeriod is how long terminated processes stay in the
+	// cache after their terminated event, 0 evicts them immediately
+	TerminationGracePeriod time.Duration `yaml:"terminationGracePeriod"`
+	
+	// CollectFDDetails also resolves the targets of open file descriptors
+	// into ProcessInfo.OpenFiles (Linux only)
+	CollectFDDetails bool `yaml:"collectFDDetails"`
+	
+	// MaxOpenFiles caps the number of OpenFiles entries per process
+	MaxOpenFiles int `yaml:"maxOpenFiles"`
+	
+	// CollectConnections attributes TCP and UDP sockets to processes into
+	// ProcessInfo.ConnectionCount and ListeningPorts (Linux only)
+	CollectConnections bool `yaml:"collectConnections"`
+	
+	// ConnectionsInterval is the minimum time between connection collections.
+	// Scans in between, and scans following one over MaxCPUUsage, report the
+	// previous values. 0 collects on every scan
+	ConnectionsInterval time.Duration `yaml:"connectionsInterval"`
+	
+	// DegradedMinCPU is the CPU percentage below which updated events are
+	// not emitted while the watchdog degrades the scanner to level 2 or above
+	DegradedMinCPU float64 `yaml:"degradedMinCPU"`
+	
+	// ScanDurationPercentiles accumulates scan durations in a DDSketch to
+	// report their p50, p95 and p99. Disable on constrained hosts
+	ScanDurationPercentiles bool `yaml:"scanDurationPercentiles"`
+	
+	// ShareProcessPointers caches the collected processes without copying
+	// and hands them to ReadOnlyConsumer implementations as is. Other
+	// consumers still receive a copy
+	ShareProcessPointers bool `yaml:"shareProcessPointers"`
+	
+	// CollectExecutableHash fills ProcessInfo.ExecutableHash with a SHA-256
+	// of the process binary (Linux only)
+	CollectExecutableHash bool `yaml:"collectExecutableHash"`
+	
+	// ExecutableHashSizeKB is how much of the binary is hashed, from its
+	// start. 0 hashes the whole file
+	ExecutableHashSizeKB int `yaml:"executableHashSizeKB"`
+	
+	// CPUReportingMode is the scale of ProcessInfo.CPU, per core by default
+	CPUReportingMode CPUReportingMode `yaml:"cpuReportingMode"`
+	
+	// UpdateFields are the fields of ProcessInfo whose changes emit updated
+	// events. Empty compares all fields. The cache keeps the values of the
+	// last update, so changes of other fields are only seen in later updates
+	UpdateFields []Field `yaml:"updateFields"`
+	
+	// UpdateCPUEpsilon is the smallest change of CPU, in percentage points
+	// since the last update, that emits an updated event. 0 emits any change
+	UpdateCPUEpsilon float64 `yaml:"updateCPUEpsilon"`
+	
+	// UpdateRSSDelta is the smallest change of RSS, in bytes since the last
+	// update, that emits an updated event. 0 emits any change
+	UpdateRSSDelta int64 `yaml:"updateRSSDelta"`
+	
+	// UpdateCoalesceWindow merges the updated events of a process dispatched
+	// within the window into one event, carrying the latest snapshot and the
+	// combined Delta. Other events of the process flush its merged update
+	// first. 0 dispatches every update
+	UpdateCoalesceWindow time.Duration `yaml:"updateCoalesceWindow"`
+	
+	// CacheMaxAge is the age above which a cache saved by SaveCache is not
+	// reloaded by LoadCache. 0 reloads caches of any age
+	CacheMaxAge time.Duration `yaml:"cacheMaxAge"`
 }
 
 // DefaultConfig returns a Config with sensible defaults
@@ -71,13 +224,25 @@ func DefaultConfig() Config {
 			MaxProcesses:    3000,
 			ExcludePatterns: []string{},
 			IncludePatterns: []string{},
+			PatternSyntax:   PatternSyntaxRegex,
 			ProcFSPath:      "/proc",
 			RefreshCPUStats: true,
 			EventBatchSize:  100,
 			EventChannelSize: 1000,
 			RetryInterval:   time.Second * 5,
+			MaxCPUUsage:     0.75,
 			AdaptiveSampling: true,
 			MaxScanTime:     time.Millisecond * 200,
+			BackpressurePolicy: BackpressureDrop,
+			ConsumerMode:    ConsumerModeSync,
+			ConsumerQueueSize: 100,
+			MaxOpenFiles:      100,
+			ConnectionsInterval: time.Minute,
+			ScanDurationPercentiles: true,
+			DegradedMinCPU:    1.0,
+			ExecutableHashSizeKB: 64,
+			CPUReportingMode:  CPUReportingPerCore,
+			CacheMaxAge:       5 * time.Minute,
 		},
 	}
 }
@@ -106,6 +271,10 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("max processes must be positive")
 		}
 		
+		if c.ProcessScanner.MaxProcessesPerScan < 0 {
+			return fmt.Errorf("max processes per scan cannot be negative")
+		}
+		
 		if c.ProcessScanner.EventBatchSize <= 0 {
 			return fmt.Errorf("event batch size must be positive")
 		}
@@ -118,9 +287,87 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("retry interval cannot be less than 1 second")
 		}
 		
+		if c.ProcessScanner.MaxCPUUsage <= 0 || c.ProcessScanner.MaxCPUUsage > 5 {
+			return fmt.Errorf("process scanner max CPU usage must be between 0 and 5 percent")
+		}
+		
 		if c.ProcessScanner.MaxScanTime < time.Millisecond*10 {
 			return fmt.Errorf("max scan time cannot be less than 10 milliseconds")
 		}
+		
+		switch c.ProcessScanner.PatternSyntax {
+		case "", PatternSyntaxRegex, PatternSyntaxGlob:
+		default:
+			return fmt.Errorf("invalid pattern syntax: %s", c.ProcessScanner.PatternSyntax)
+		}
+		
+		switch c.ProcessScanner.BackpressurePolicy {
+		case "", BackpressureDrop, BackpressureBlock, BackpressureDropOldest:
+		default:
+			return fmt.Errorf("invalid backpressure policy: %s", c.ProcessScanner.BackpressurePolicy)
+		}
+		
+		switch c.ProcessScanner.ConsumerMode {
+		case "", ConsumerModeSync:
+		case ConsumerModeAsyncPerConsumer:
+			if c.ProcessScanner.ConsumerQueueSize <= 0 {
+				return fmt.Errorf("consumer queue size must be positive")
+			}
+		default:
+			return fmt.Errorf("invalid consumer mode: %s", c.ProcessScanner.ConsumerMode)
+		}
+		
+		if c.ProcessScanner.ConsumerTimeout < 0 {
+			return fmt.Errorf("consumer timeout cannot be negative")
+		}
+		
+		if c.ProcessScanner.TerminationGracePeriod < 0 {
+			return fmt.Errorf("termination grace period cannot be negative")
+		}
+		
+		if c.ProcessScanner.CollectFDDetails && c.ProcessScanner.MaxOpenFiles <= 0 {
+			return fmt.Errorf("max open files must be positive when collecting fd details")
+		}
+		
+		if c.ProcessScanner.ConnectionsInterval < 0 {
+			return fmt.Errorf("connections interval cannot be negative")
+		}
+		
+		if c.ProcessScanner.DegradedMinCPU < 0 {
+			return fmt.Errorf("degraded min CPU cannot be negative")
+		}
+		
+		if c.ProcessScanner.ExecutableHashSizeKB < 0 {
+			return fmt.Errorf("executable hash size cannot be negative")
+		}
+		
+		switch c.ProcessScanner.CPUReportingMode {
+		case "", CPUReportingPerCore, CPUReportingNormalized:
+		default:
+			return fmt.Errorf("invalid CPU reporting mode: %s", c.ProcessScanner.CPUReportingMode)
+		}
+		
+		for _, field := range c.ProcessScanner.UpdateFields {
+			if !field.IsValid() {
+				return fmt.Errorf("invalid update field: %s", field)
+			}
+		}
+		
+		if c.ProcessScanner.UpdateCPUEpsilon < 0 {
+			return fmt.Errorf("update CPU epsilon cannot be negative")
+		}
+		
+		if c.ProcessScanner.UpdateRSSDelta < 0 {
+			return fmt.Errorf("update RSS delta cannot be negative")
+		}
+		
+		if c.ProcessScanner.UpdateCoalesceWindow < 0 {
+			return fmt.Errorf("update coalesce window cannot be negative")
+		}
+		
+		if c.ProcessScanner.CacheMaxAge < 0 {
+			return fmt.Errorf("cache max age cannot be negative")
+		}
 	}
 	
 	return nil
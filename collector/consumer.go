@@ -8,9 +8,17 @@ import (
 // ConsumerRegistry manages registered process consumers
 type ConsumerRegistry struct {
 	consumers map[string]ProcessConsumer
+	order     []registeredConsumer // Delivery order, replaced rather than modified
 	mutex     sync.RWMutex
 }
 
+// registeredConsumer is a consumer with its name and delivery priority
+type registeredConsumer struct {
+	name     string
+	consumer ProcessConsumer
+	priority int
+}
+
 // NewConsumerRegistry creates a new consumer registry
 func NewConsumerRegistry() *ConsumerRegistry {
 	return &ConsumerRegistry{
@@ -18,8 +26,15 @@ func NewConsumerRegistry() *ConsumerRegistry {
 	}
 }
 
-// Register adds a consumer to the registry
+// Register adds a consumer to the registry with priority 0
 func (r *ConsumerRegistry) Register(name string, consumer ProcessConsumer) error {
+	return r.RegisterWithPriority(name, consumer, 0)
+}
+
+// RegisterWithPriority adds a consumer to the registry. Events are delivered
+// to consumers in descending priority, and in registration order within the
+// same priority, e.g. so an aggregating consumer runs before its exporter
+func (r *ConsumerRegistry) RegisterWithPriority(name string, consumer ProcessConsumer, priority int) error {
 	if name == "" {
 		return fmt.Errorf("consumer name cannot be empty")
 	}
@@ -36,6 +51,16 @@ func (r *ConsumerRegistry) Register(name string, consumer ProcessConsumer) error
 	}
 	
 	r.consumers[name] = consumer
+	
+	// Insert after all consumers with the same or a higher priority
+	i := len(r.order)
+	for i > 0 && r.order[i-1].priority < priority {
+		i--
+	}
+	order := make([]registeredConsumer, 0, len(r.order)+1)
+	order = append(order, r.order[:i]...)
+	order = append(order, registeredConsumer{name: name, consumer: consumer, priority: priority})
+	r.order = append(order, r.order[i:]...)
 	return nil
 }
 
@@ -53,6 +78,14 @@ func (r *ConsumerRegistry) Unregister(name string) error {
 	}
 	
 	delete(r.consumers, name)
+	for i, registered := range r.order {
+		if registered.name == name {
+			order := make([]registeredConsumer, 0, len(r.order)-1)
+			order = append(order, r.order[:i]...)
+			r.order = append(order, r.order[i+1:]...)
+			break
+		}
+	}
 	return nil
 }
 
@@ -77,16 +110,17 @@ func (r *ConsumerRegistry) GetConsumerNames() []string {
 	return names
 }
 
-// NotifyAll sends a process event to all registered consumers
+// NotifyAll sends a process event to the HandleProcessEvent method of all
+// registered consumers, in descending priority
 func (r *ConsumerRegistry) NotifyAll(event ProcessEvent) []error {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 	
 	var errors []error
-	for name, consumer := range r.consumers {
-		err := consumer.HandleProcessEvent(event)
+	for _, registered := range r.order {
+		err := registered.consumer.HandleProcessEvent(event)
 		if err != nil {
-			errors = append(errors, fmt.Errorf("consumer '%s' error: %w", name, err))
+			errors = append(errors, fmt.Errorf("consumer '%s' error: %w", registered.name, err))
 		}
 	}
 	
@@ -96,19 +130,11 @@ func (r *ConsumerRegistry) NotifyAll(event ProcessEvent) []error {
 // NotifyAllAsync sends a process event to all registered consumers asynchronously
 func (r *ConsumerRegistry) NotifyAllAsync(event ProcessEvent) {
 	// Make a copy of the event to ensure safety
-	eventCopy := ProcessEvent{
-		Type:      event.Type,
-		Process:   event.Process.Clone(),
-		Timestamp: event.Timestamp,
-	}
+	eventCopy := event
+	eventCopy.Process = event.Process.Clone()
 	
 	// Copy the consumer list to avoid holding the lock during notification
-	r.mutex.RLock()
-	consumers := make(map[string]ProcessConsumer, len(r.consumers))
-	for name, consumer := range r.consumers {
-		consumers[name] = consumer
-	}
-	r.mutex.RUnlock()
+	consumers := r.Snapshot()
 	
 	// Notify each consumer in a separate goroutine
 	for name, consumer := range consumers {
@@ -120,6 +146,27 @@ func (r *ConsumerRegistry) NotifyAllAsync(event ProcessEvent) {
 	}
 }
 
+// Snapshot returns a copy of the registered consumers by name
+func (r *ConsumerRegistry) Snapshot() map[string]ProcessConsumer {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	consumers := make(map[string]ProcessConsumer, len(r.consumers))
+	for name, consumer := range r.consumers {
+		consumers[name] = consumer
+	}
+	return consumers
+}
+
+// ordered returns the registered consumers in delivery order. The slice is
+// never modified, so it is returned without a copy
+func (r *ConsumerRegistry) ordered() []registeredConsumer {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	
+	return r.order
+}
+
 // ConsumerCount returns the number of registered consumers
 func (r *ConsumerRegistry) ConsumerCount() int {
 	r.mutex.RLock()
@@ -0,0 +1,52 @@
+package collector
+
+// ConsumerFunc adapts a function to the ProcessConsumer interface, e.g. for
+// consumers without state:
+//
+//	scanner.RegisterConsumer("log", collector.ConsumerFunc(func(event collector.ProcessEvent) error {
+//		log.Printf("%s %d", event.Type, event.Process.PID)
+//		return nil
+//	}))
+type ConsumerFunc func(event ProcessEvent) error
+
+// HandleProcessEvent calls f(event)
+func (f ConsumerFunc) HandleProcessEvent(event ProcessEvent) error {
+	return f(event)
+}
+
+// LegacyConsumer is the former shape of a process consumer, handling events
+// in OnProcessEvent.
+//
+// Deprecated: Implement ProcessConsumer by renaming OnProcessEvent to
+// HandleProcessEvent. Until then, register the consumer wrapped with
+// AdaptLegacyConsumer
+type LegacyConsumer interface {
+	OnProcessEvent(event ProcessEvent) error
+}
+
+// legacyConsumerAdapter delivers events to a LegacyConsumer
+type legacyConsumerAdapter struct {
+	consumer LegacyConsumer
+}
+
+// AdaptLegacyConsumer wraps a LegacyConsumer so it can be registered as a
+// ProcessConsumer. A Closable consumer is still closed on Shutdown. The
+// wrapper is never a ReadOnlyConsumer, so it receives copies of the processes
+//
+// Deprecated: Implement ProcessConsumer instead, see LegacyConsumer
+func AdaptLegacyConsumer(consumer LegacyConsumer) ProcessConsumer {
+	return &legacyConsumerAdapter{consumer: consumer}
+}
+
+// HandleProcessEvent calls OnProcessEvent of the wrapped consumer
+func (a *legacyConsumerAdapter) HandleProcessEvent(event ProcessEvent) error {
+	return a.consumer.OnProcessEvent(event)
+}
+
+// OnStreamClose closes the wrapped consumer if it is Closable
+func (a *legacyConsumerAdapter) OnStreamClose() error {
+	if closable, ok := a.consumer.(Closable); ok {
+		return closable.OnStreamClose()
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"errors"
+	"testing"
+)
+
+// legacyConsumer implements only the deprecated OnProcessEvent
+type legacyConsumer struct {
+	events []ProcessEvent
+	err    error
+	closed bool
+}
+
+// OnProcessEvent records the event
+func (c *legacyConsumer) OnProcessEvent(event ProcessEvent) error {
+	c.events = append(c.events, event)
+	return c.err
+}
+
+// closableLegacyConsumer is a legacy consumer keeping state until closed
+type closableLegacyConsumer struct {
+	legacyConsumer
+}
+
+// OnStreamClose marks the consumer closed
+func (c *closableLegacyConsumer) OnStreamClose() error {
+	c.closed = true
+	return nil
+}
+
+func TestConsumerFunc(t *testing.T) {
+	var received []ProcessEvent
+	consumer := ConsumerFunc(func(event ProcessEvent) error {
+		received = append(received, event)
+		return nil
+	})
+
+	registry := NewConsumerRegistry()
+	if err := registry.Register("func", consumer); err != nil {
+		t.Fatalf("Failed to register consumer: %v", err)
+	}
+
+	event := ProcessEvent{Type: ProcessCreated, Process: &ProcessInfo{PID: 1}}
+	if errs := registry.NotifyAll(event); len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+	if len(received) != 1 || received[0].Process.PID != 1 {
+		t.Errorf("Expected the event to reach the function, got %v", received)
+	}
+}
+
+func TestAdaptLegacyConsumer(t *testing.T) {
+	legacy := &legacyConsumer{err: errors.New("failed")}
+	closable := &closableLegacyConsumer{}
+
+	registry := NewConsumerRegistry()
+	registry.Register("legacy", AdaptLegacyConsumer(legacy))
+	registry.Register("closable", AdaptLegacyConsumer(closable))
+
+	event := ProcessEvent{Type: ProcessUpdated, Process: &ProcessInfo{PID: 2}}
+	errs := registry.NotifyAll(event)
+
+	if len(legacy.events) != 1 || len(closable.events) != 1 {
+		t.Fatalf("Expected OnProcessEvent to be called once per consumer, got %d and %d", len(legacy.events), len(closable.events))
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], legacy.err) {
+		t.Errorf("Expected the error of the legacy consumer, got %v", errs)
+	}
+
+	// The adapter forwards OnStreamClose to closable consumers only
+	for name, wrapped := range map[string]ProcessConsumer{"legacy": AdaptLegacyConsumer(legacy), "closable": AdaptLegacyConsumer(closable)} {
+		c, ok := wrapped.(Closable)
+		if !ok {
+			t.Fatalf("Expected the adapter of %s to be Closable", name)
+		}
+		if err := c.OnStreamClose(); err != nil {
+			t.Errorf("Expected no error closing %s, got %v", name, err)
+		}
+	}
+	if !closable.closed {
+		t.Errorf("Expected the closable consumer to be closed")
+	}
+
+	// Processes are copied for the adapter, which is never read-only
+	if _, ok := AdaptLegacyConsumer(legacy).(ReadOnlyConsumer); ok {
+		t.Errorf("Expected the adapter not to be a ReadOnlyConsumer")
+	}
+}
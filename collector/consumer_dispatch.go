@@ -0,0 +1,267 @@
+package collector
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// consumerDispatcher delivers process events to the registered consumers,
+// either synchronously or through a bounded queue per consumer, and
+// unregisters consumers that exceed the configured timeout
+type consumerDispatcher struct {
+	registry    *ConsumerRegistry
+	metrics     *MetricsTracker
+	mode        ConsumerMode
+	timeout     time.Duration // 0 disables the timeout
+	queueSize   int
+	shared      bool                       // Events carry shared processes, see ShareProcessPointers
+	workers     map[string]*consumerWorker // Async mode only
+	lag         map[string]time.Duration   // Event timestamp to handled, per consumer
+	diagnostics DiagnosticSink
+	mutex       sync.Mutex
+	wg          sync.WaitGroup
+}
+
+// consumerWorker owns the queue and goroutine of a single consumer
+type consumerWorker struct {
+	name     string
+	consumer ProcessConsumer
+	queue    chan ProcessEvent
+	done     chan struct{}
+}
+
+// newConsumerDispatcher creates a dispatcher from the scanner configuration
+func newConsumerDispatcher(registry *ConsumerRegistry, metrics *MetricsTracker, config ProcessScannerConfig) *consumerDispatcher {
+	mode := config.ConsumerMode
+	if mode == "" {
+		mode = ConsumerModeSync
+	}
+
+	queueSize := config.ConsumerQueueSize
+	if queueSize <= 0 {
+		queueSize = 100
+	}
+
+	return &consumerDispatcher{
+		registry:    registry,
+		metrics:     metrics,
+		mode:        mode,
+		timeout:     config.ConsumerTimeout,
+		queueSize:   queueSize,
+		shared:      config.ShareProcessPointers,
+		workers:     make(map[string]*consumerWorker),
+		lag:         make(map[string]time.Duration),
+		diagnostics: LogDiagnosticSink{},
+	}
+}
+
+// dispatch delivers an event to all registered consumers. In sync mode the
+// consumers are notified in priority order and their errors are returned. In
+// async mode every consumer has its own queue, so there is no ordering across
+// consumers, and errors are reported by the workers
+func (d *consumerDispatcher) dispatch(event ProcessEvent) []error {
+	if d.mode == ConsumerModeAsyncPerConsumer {
+		d.enqueue(d.registry.Snapshot(), event)
+		return nil
+	}
+
+	consumers := d.registry.ordered()
+	d.pruneLag(consumers)
+
+	// Consumers that may modify the process share one copy
+	var private *ProcessInfo
+
+	var errors []error
+	for _, registered := range consumers {
+		consumerEvent := event
+		if d.shared && !isReadOnly(registered.consumer) {
+			if private == nil {
+				private = event.Process.Clone()
+			}
+			consumerEvent.Process = private
+		}
+
+		if err := d.notify(registered.name, registered.consumer, consumerEvent); err != nil {
+			errors = append(errors, fmt.Errorf("consumer '%s' error: %w", registered.name, err))
+		}
+	}
+	return errors
+}
+
+// enqueue adds the event to the queue of every consumer, starting workers for
+// new consumers and stopping workers of unregistered ones
+func (d *consumerDispatcher) enqueue(consumers map[string]ProcessConsumer, event ProcessEvent) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for name, worker := range d.workers {
+		if consumer, exists := consumers[name]; !exists || consumer != worker.consumer {
+			close(worker.done)
+			delete(d.workers, name)
+			delete(d.lag, name)
+		}
+	}
+
+	for name, consumer := range consumers {
+		worker, exists := d.workers[name]
+		if !exists {
+			worker = &consumerWorker{
+				name:     name,
+				consumer: consumer,
+				queue:    make(chan ProcessEvent, d.queueSize),
+				done:     make(chan struct{}),
+			}
+			d.workers[name] = worker
+			d.wg.Add(1)
+			go d.runWorker(worker)
+		}
+
+		// Each consumer gets its own copy of the process, unless it is
+		// read-only
+		eventCopy := event
+		if !isReadOnly(consumer) {
+			eventCopy.Process = event.Process.Clone()
+		}
+
+		select {
+		case worker.queue <- eventCopy:
+		default:
+			// A slow consumer only loses its own events
+			d.metrics.IncrementCounter(MetricConsumerDropped, 1)
+			d.diagnostics.Emit(newDiagEvent(DiagEventDropped, DiagSeverityWarning,
+				map[string]interface{}{"consumer": name, "pid": event.Process.PID},
+				"Queue of consumer '%s' full, dropping event for PID %d", name, event.Process.PID))
+		}
+	}
+}
+
+// runWorker delivers queued events to a single consumer until it is stopped
+// or times out
+func (d *consumerDispatcher) runWorker(worker *consumerWorker) {
+	defer d.wg.Done()
+
+	for {
+		select {
+		case <-worker.done:
+			return
+		case event := <-worker.queue:
+			err := d.notify(worker.name, worker.consumer, event)
+			if err == errConsumerTimeout {
+				return
+			}
+			if err != nil {
+				d.metrics.IncrementCounter(MetricNotificationErrors, 1)
+				d.diagnostics.Emit(newDiagEvent(DiagConsumerError, DiagSeverityWarning,
+					map[string]interface{}{"consumer": worker.name, "error": err.Error()},
+					"Error notifying consumers: consumer '%s' error: %v", worker.name, err))
+			}
+		}
+	}
+}
+
+// errConsumerTimeout is returned by notify when a consumer was unregistered
+var errConsumerTimeout = fmt.Errorf("consumer timed out")
+
+// notify delivers an event to a single consumer, enforcing the timeout
+func (d *consumerDispatcher) notify(name string, consumer ProcessConsumer, event ProcessEvent) error {
+	if d.timeout <= 0 {
+		err := consumer.HandleProcessEvent(event)
+		d.recordLag(name, event)
+		return err
+	}
+
+	// The handler keeps running in the background if it times out
+	result := make(chan error, 1)
+	go func() {
+		result <- consumer.HandleProcessEvent(event)
+	}()
+
+	select {
+	case err := <-result:
+		d.recordLag(name, event)
+		return err
+	case <-time.After(d.timeout):
+		d.evict(name, consumer)
+		return errConsumerTimeout
+	}
+}
+
+// evict unregisters a consumer that exceeded the timeout
+func (d *consumerDispatcher) evict(name string, consumer ProcessConsumer) {
+	d.metrics.IncrementCounter(MetricConsumerTimeouts, 1)
+	d.diagnostics.Emit(newDiagEvent(DiagConsumerTimeout, DiagSeverityWarning,
+		map[string]interface{}{"consumer": name, "timeout": d.timeout},
+		"Consumer '%s' exceeded timeout of %v, unregistering", name, d.timeout))
+
+	// The consumer may have been replaced under the same name in the meantime
+	if current, exists := d.registry.GetConsumer(name); exists && current == consumer {
+		_ = d.registry.Unregister(name)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if worker, exists := d.workers[name]; exists && worker.consumer == consumer {
+		delete(d.workers, name)
+	}
+	delete(d.lag, name)
+}
+
+// recordLag records how long after its timestamp an event was handled
+func (d *consumerDispatcher) recordLag(name string, event ProcessEvent) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.lag[name] = time.Since(event.Timestamp)
+}
+
+// pruneLag forgets the lag of consumers that are no longer registered
+func (d *consumerDispatcher) pruneLag(consumers []registeredConsumer) {
+	registered := make(map[string]struct{}, len(consumers))
+	for _, consumer := range consumers {
+		registered[consumer.name] = struct{}{}
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for name := range d.lag {
+		if _, exists := registered[name]; !exists {
+			delete(d.lag, name)
+		}
+	}
+}
+
+// lagMetrics returns the lag and, in async mode, the queue depth per consumer
+func (d *consumerDispatcher) lagMetrics() map[string]float64 {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	metrics := make(map[string]float64, len(d.lag)+len(d.workers))
+	for name, lag := range d.lag {
+		metrics[MetricConsumerLag+name] = float64(lag.Milliseconds())
+	}
+	for name, worker := range d.workers {
+		metrics[MetricConsumerQueueDepth+name] = float64(len(worker.queue))
+	}
+	return metrics
+}
+
+// stop stops all consumer workers and waits for them to exit
+func (d *consumerDispatcher) stop() {
+	d.mutex.Lock()
+	for name, worker := range d.workers {
+		close(worker.done)
+		delete(d.workers, name)
+	}
+	d.mutex.Unlock()
+
+	d.wg.Wait()
+}
+
+// isReadOnly reports whether a consumer declared it never modifies processes
+func isReadOnly(consumer ProcessConsumer) bool {
+	_, ok := consumer.(ReadOnlyConsumer)
+	return ok
+}
@@ -0,0 +1,108 @@
+package collector
+
+import (
+	"testing"
+	"time"
+)
+
+// BlockingConsumer blocks on every event until released
+type BlockingConsumer struct {
+	release chan struct{}
+}
+
+// HandleProcessEvent waits for the release channel
+func (b *BlockingConsumer) HandleProcessEvent(event ProcessEvent) error {
+	<-b.release
+	return nil
+}
+
+// waitFor polls a condition until it holds or the deadline expires
+func waitFor(t *testing.T, timeout time.Duration, condition func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return condition()
+}
+
+func TestConsumerDispatcher_AsyncPerConsumer(t *testing.T) {
+	config := DefaultConfig().ProcessScanner
+	config.ConsumerMode = ConsumerModeAsyncPerConsumer
+	config.ConsumerQueueSize = 10
+
+	registry := NewConsumerRegistry()
+	metrics := NewMetricsTracker()
+	d := newConsumerDispatcher(registry, metrics, config)
+	defer d.stop()
+
+	fast := NewMockProcessConsumer()
+	slow := &BlockingConsumer{release: make(chan struct{})}
+	registry.Register("fast", fast)
+	registry.Register("slow", slow)
+
+	for pid := 1; pid <= 5; pid++ {
+		d.dispatch(ProcessEvent{Type: ProcessCreated, Process: &ProcessInfo{PID: pid}, Timestamp: time.Now()})
+	}
+
+	// The blocked consumer does not stall the fast one
+	if !waitFor(t, time.Second, func() bool { return fast.Count() == 5 }) {
+		t.Fatalf("Expected 5 events for fast consumer, got %d", fast.Count())
+	}
+
+	if _, exists := d.lagMetrics()[MetricConsumerLag+"fast"]; !exists {
+		t.Errorf("Expected lag metric for fast consumer, got %v", d.lagMetrics())
+	}
+
+	// One event is held by the blocked handler, the rest stay queued
+	if !waitFor(t, time.Second, func() bool { return d.lagMetrics()[MetricConsumerQueueDepth+"slow"] == 4 }) {
+		t.Errorf("Expected 4 queued events for slow consumer, got %v", d.lagMetrics()[MetricConsumerQueueDepth+"slow"])
+	}
+
+	close(slow.release)
+	if !waitFor(t, time.Second, func() bool { return d.lagMetrics()[MetricConsumerQueueDepth+"slow"] == 0 }) {
+		t.Errorf("Expected slow consumer queue to drain")
+	}
+}
+
+func TestConsumerDispatcher_Timeout(t *testing.T) {
+	for _, mode := range []ConsumerMode{ConsumerModeSync, ConsumerModeAsyncPerConsumer} {
+		t.Run(string(mode), func(t *testing.T) {
+			config := DefaultConfig().ProcessScanner
+			config.ConsumerMode = mode
+			config.ConsumerTimeout = 20 * time.Millisecond
+
+			registry := NewConsumerRegistry()
+			metrics := NewMetricsTracker()
+			d := newConsumerDispatcher(registry, metrics, config)
+			defer d.stop()
+
+			fast := NewMockProcessConsumer()
+			slow := &BlockingConsumer{release: make(chan struct{})}
+			defer close(slow.release)
+			registry.Register("fast", fast)
+			registry.Register("slow", slow)
+
+			d.dispatch(ProcessEvent{Type: ProcessCreated, Process: &ProcessInfo{PID: 1}, Timestamp: time.Now()})
+
+			// The slow consumer is unregistered once the timeout expires
+			if !waitFor(t, time.Second, func() bool { return registry.ConsumerCount() == 1 }) {
+				t.Fatalf("Expected slow consumer to be unregistered")
+			}
+			if _, exists := registry.GetConsumer("slow"); exists {
+				t.Errorf("Expected slow consumer to be removed")
+			}
+			if metrics.GetCounter(MetricConsumerTimeouts) != 1 {
+				t.Errorf("Expected 1 consumer timeout, got %d", metrics.GetCounter(MetricConsumerTimeouts))
+			}
+
+			d.dispatch(ProcessEvent{Type: ProcessCreated, Process: &ProcessInfo{PID: 2}, Timestamp: time.Now()})
+			if !waitFor(t, time.Second, func() bool { return fast.Count() == 2 }) {
+				t.Errorf("Expected 2 events for fast consumer, got %d", fast.Count())
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RegisterConsumerWithReplay adds a consumer that first receives a
+// ProcessCreated event for every cached process, so a consumer registered
+// while the scanner is running starts from the current state. Scans wait
+// until the consumer is registered, and events queued before the replay are
+// dispatched to the other consumers first, so the consumer neither misses
+// nor receives twice the events of a process
+func (p *ProcessScanner) RegisterConsumerWithReplay(name string, consumer ProcessConsumer) error {
+	if _, exists := p.registry.GetConsumer(name); exists {
+		return fmt.Errorf("consumer '%s' already registered", name)
+	}
+
+	// Read before scanMutex, Pause and Stop wait for scans under scannerMutex
+	p.scannerMutex.RLock()
+	status, scannerCtx := p.status, p.ctx
+	p.scannerMutex.RUnlock()
+
+	p.scanMutex.Lock()
+	defer p.scanMutex.Unlock()
+
+	// The events of earlier scans describe processes the replay already
+	// reflects. A scanner stopped meanwhile has nothing left to dispatch
+	if status == StatusRunning || status == StatusPaused {
+		_ = p.flushEvents(context.Background(), scannerCtx)
+	}
+
+	p.cacheMutex.RLock()
+	events := make([]ProcessEvent, 0, len(p.processCache))
+	for _, proc := range p.processCache {
+		process := proc
+		if !isReadOnly(consumer) {
+			process = proc.Clone()
+		}
+		events = append(events, ProcessEvent{
+			Type:            ProcessCreated,
+			Process:         process,
+			Timestamp:       time.Now(),
+			HostMemoryTotal: p.hostMemory.total,
+			HostMemoryUsed:  p.hostMemory.used,
+		})
+	}
+	p.cacheMutex.RUnlock()
+
+	for _, event := range events {
+		err := p.dispatcher.notify(name, consumer, event)
+		if err == errConsumerTimeout {
+			return fmt.Errorf("consumer '%s' timed out during replay", name)
+		}
+		if err != nil {
+			p.metrics.IncrementCounter(MetricNotificationErrors, 1)
+			p.diagnostics.Emit(newDiagEvent(DiagConsumerError, DiagSeverityWarning,
+				map[string]interface{}{"consumer": name, "error": err.Error()},
+				"Error replaying processes: consumer '%s' error: %v", name, err))
+		}
+	}
+
+	return p.registry.Register(name, consumer)
+}
@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProcessScanner_RegisterConsumerWithReplay(t *testing.T) {
+	fake := &fakePlatformCollector{}
+	addProcess := func(pid int) {
+		fake.mutex.Lock()
+		defer fake.mutex.Unlock()
+		fake.processes = append(fake.processes, &ProcessInfo{PID: pid, Name: "proc", Command: "proc"})
+	}
+	for pid := 1; pid <= 3; pid++ {
+		addProcess(pid)
+	}
+
+	scanner := newTestScanner(t, fake)
+	early := NewMockProcessConsumer()
+	scanner.RegisterConsumer("early", early)
+	if err := scanner.Start(); err != nil {
+		t.Fatalf("Failed to start scanner: %v", err)
+	}
+	defer scanner.Stop()
+
+	if err := scanner.ForceScanSync(context.Background()); err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+
+	// New processes keep appearing while the consumer registers mid-run
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for pid := 4; pid <= 40; pid++ {
+			addProcess(pid)
+			time.Sleep(2 * time.Millisecond)
+		}
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	late := NewMockProcessConsumer()
+	if err := scanner.RegisterConsumerWithReplay("late", late); err != nil {
+		t.Fatalf("Failed to register consumer: %v", err)
+	}
+	if err := scanner.RegisterConsumerWithReplay("late", late); err == nil {
+		t.Errorf("Expected an error registering a consumer name twice")
+	}
+
+	wg.Wait()
+	if err := scanner.ForceScanSync(context.Background()); err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+
+	// Both consumers saw exactly one created event per process
+	for name, consumer := range map[string]*MockProcessConsumer{"early": early, "late": late} {
+		created := make(map[int]int)
+		for _, event := range consumer.GetEvents() {
+			if event.Type == ProcessCreated {
+				created[event.Process.PID]++
+			}
+		}
+		for pid := 1; pid <= 40; pid++ {
+			if created[pid] != 1 {
+				t.Errorf("Expected consumer %s to see 1 created event for PID %d, got %d", name, pid, created[pid])
+			}
+		}
+	}
+}
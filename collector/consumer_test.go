@@ -0,0 +1,79 @@
+package collector
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// orderRecorder records the names of consumers in the order they are notified
+type orderRecorder struct {
+	mutex sync.Mutex
+	names []string
+}
+
+// orderedConsumer reports its name to the recorder on every event
+type orderedConsumer struct {
+	name     string
+	recorder *orderRecorder
+}
+
+// HandleProcessEvent records the consumer name
+func (c *orderedConsumer) HandleProcessEvent(event ProcessEvent) error {
+	c.recorder.mutex.Lock()
+	defer c.recorder.mutex.Unlock()
+	c.recorder.names = append(c.recorder.names, c.name)
+	return nil
+}
+
+func (o *orderRecorder) consumer(name string) ProcessConsumer {
+	return &orderedConsumer{name: name, recorder: o}
+}
+
+func (o *orderRecorder) take() []string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	names := o.names
+	o.names = nil
+	return names
+}
+
+func TestConsumerRegistry_Priority(t *testing.T) {
+	registry := NewConsumerRegistry()
+	recorder := &orderRecorder{}
+
+	registry.Register("default-1", recorder.consumer("default-1"))
+	registry.RegisterWithPriority("exporter", recorder.consumer("exporter"), -10)
+	registry.RegisterWithPriority("sketch", recorder.consumer("sketch"), 10)
+	registry.Register("default-2", recorder.consumer("default-2"))
+	registry.RegisterWithPriority("aggregate", recorder.consumer("aggregate"), 10)
+
+	event := ProcessEvent{Type: ProcessCreated, Process: &ProcessInfo{PID: 1}, Timestamp: time.Now()}
+
+	// Descending priority, registration order within a priority
+	expected := []string{"sketch", "aggregate", "default-1", "default-2", "exporter"}
+	for i := 0; i < 10; i++ {
+		registry.NotifyAll(event)
+		if got := recorder.take(); !reflect.DeepEqual(got, expected) {
+			t.Fatalf("Expected delivery order %v, got %v", expected, got)
+		}
+	}
+
+	// Unregistering keeps the order of the remaining consumers
+	registry.Unregister("aggregate")
+	registry.Register("default-3", recorder.consumer("default-3"))
+	registry.NotifyAll(event)
+	expected = []string{"sketch", "default-1", "default-2", "default-3", "exporter"}
+	if got := recorder.take(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected delivery order %v, got %v", expected, got)
+	}
+
+	// Sync dispatch follows the same order
+	d := newConsumerDispatcher(registry, NewMetricsTracker(), DefaultConfig().ProcessScanner)
+	defer d.stop()
+	d.dispatch(event)
+	if got := recorder.take(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected dispatch order %v, got %v", expected, got)
+	}
+}
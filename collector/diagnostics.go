@@ -0,0 +1,136 @@
+package collector
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+// diagComponent is the component reported in the diagnostic events of the scanner
+const diagComponent = "ProcessScanner"
+
+// DiagSeverity is the severity of a diagnostic event
+type DiagSeverity string
+
+const (
+	// DiagSeverityInfo reports a normal but notable change, e.g. a new scan interval
+	DiagSeverityInfo DiagSeverity = "info"
+
+	// DiagSeverityWarning reports lost data or a recoverable error
+	DiagSeverityWarning DiagSeverity = "warning"
+
+	// DiagSeverityCritical reports a failed scan or a breached resource limit
+	DiagSeverityCritical DiagSeverity = "critical"
+)
+
+// DiagEventType identifies the condition reported by a diagnostic event
+type DiagEventType string
+
+const (
+	// DiagScanError reports that listing the processes failed
+	DiagScanError DiagEventType = "ScanError"
+
+	// DiagCPUTimesError reports that refreshing the CPU times failed
+	DiagCPUTimesError DiagEventType = "CPUTimesError"
+
+	// DiagModuleOverLimit reports that a scan exceeded
+	// ProcessScannerConfig.MaxCPUUsage
+	DiagModuleOverLimit DiagEventType = "ModuleOverLimit"
+
+	// DiagScanTimeExceeded reports a scan slower than MaxScanTime
+	DiagScanTimeExceeded DiagEventType = "ScanTimeExceeded"
+
+	// DiagEventDropped reports an event dropped because a queue was full
+	DiagEventDropped DiagEventType = "EventDropped"
+
+	// DiagEventEvicted reports a queued event evicted for a newer one
+	DiagEventEvicted DiagEventType = "EventEvicted"
+
+	// DiagConsumerError reports an error returned by a consumer
+	DiagConsumerError DiagEventType = "ConsumerError"
+
+	// DiagConsumerCloseError reports an error returned by OnStreamClose
+	DiagConsumerCloseError DiagEventType = "ConsumerCloseError"
+
+	// DiagConsumerTimeout reports a consumer unregistered after a timeout
+	DiagConsumerTimeout DiagEventType = "ConsumerTimeout"
+
+	// DiagScanIntervalChanged reports an adjustment by adaptive sampling
+	DiagScanIntervalChanged DiagEventType = "ScanIntervalChanged"
+
+	// DiagDegradationChanged reports a degradation level set by the watchdog
+	DiagDegradationChanged DiagEventType = "DegradationLevelChanged"
+
+	// DiagFilterFileError reports an invalid line or a failed reload of FilterFile
+	DiagFilterFileError DiagEventType = "FilterFileError"
+
+	// DiagFiltersReloaded reports filters reloaded from FilterFile
+	DiagFiltersReloaded DiagEventType = "FiltersReloaded"
+)
+
+// DiagEvent is a structured diagnostic event
+type DiagEvent struct {
+	// Component reporting the event
+	Component string
+
+	// Type of condition
+	Type DiagEventType
+
+	// Severity of the condition
+	Severity DiagSeverity
+
+	// Message is a human readable description
+	Message string
+
+	// Fields hold the values behind the message, e.g. "pid" or "error"
+	Fields map[string]interface{}
+
+	// Timestamp of the event
+	Timestamp time.Time
+}
+
+// DiagnosticSink receives the diagnostic events of the scanner. Emit is
+// called from the scan loop and consumer goroutines and must not block
+type DiagnosticSink interface {
+	Emit(event DiagEvent)
+}
+
+// LogDiagnosticSink logs diagnostic events through the agent logger. The
+// component, type, timestamp and fields of an event are logged as fields,
+// and its severity selects the log level
+type LogDiagnosticSink struct{}
+
+// Emit logs the event
+func (LogDiagnosticSink) Emit(event DiagEvent) {
+	fields := make(logrus.Fields, len(event.Fields)+3)
+	for key, value := range event.Fields {
+		fields[key] = value
+	}
+	fields["diag_event"] = string(event.Type)
+	fields["severity"] = string(event.Severity)
+	fields["timestamp"] = event.Timestamp
+
+	entry := log.WithComponent(event.Component).WithFields(fields)
+	switch event.Severity {
+	case DiagSeverityCritical:
+		entry.Error(event.Message)
+	case DiagSeverityWarning:
+		entry.Warn(event.Message)
+	default:
+		entry.Info(event.Message)
+	}
+}
+
+// newDiagEvent creates a diagnostic event of the scanner
+func newDiagEvent(eventType DiagEventType, severity DiagSeverity, fields map[string]interface{}, format string, args ...interface{}) DiagEvent {
+	return DiagEvent{
+		Component: diagComponent,
+		Type:      eventType,
+		Severity:  severity,
+		Message:   fmt.Sprintf(format, args...),
+		Fields:    fields,
+		Timestamp: time.Now(),
+	}
+}
@@ -0,0 +1,130 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+// recordingSink records emitted diagnostic events
+type recordingSink struct {
+	mutex  sync.Mutex
+	events []DiagEvent
+}
+
+func (r *recordingSink) Emit(event DiagEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recordingSink) ofType(eventType DiagEventType) []DiagEvent {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var events []DiagEvent
+	for _, event := range r.events {
+		if event.Type == eventType {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestProcessScanner_DiagnosticSink(t *testing.T) {
+	fake := &fakePlatformCollector{scanErr: fmt.Errorf("procfs unavailable")}
+	scanner := newTestScanner(t, fake)
+	sink := &recordingSink{}
+	scanner.SetDiagnosticSink(sink)
+
+	scanner.performScan()
+
+	events := sink.ofType(DiagScanError)
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 ScanError event, got %d", len(events))
+	}
+	event := events[0]
+	if event.Component != "ProcessScanner" {
+		t.Errorf("Expected component ProcessScanner, got %s", event.Component)
+	}
+	if event.Severity != DiagSeverityCritical {
+		t.Errorf("Expected critical severity, got %s", event.Severity)
+	}
+	if event.Fields["error"] != "procfs unavailable" {
+		t.Errorf("Expected error field, got %v", event.Fields["error"])
+	}
+	if event.Message != "Error scanning processes: procfs unavailable" {
+		t.Errorf("Unexpected message: %s", event.Message)
+	}
+	if event.Timestamp.IsZero() {
+		t.Error("Expected a timestamp")
+	}
+
+	// Exceeding MaxCPUUsage is reported with the measured usage and the limit
+	fake.mutex.Lock()
+	fake.scanErr = nil
+	fake.cpuPercent = 3.0
+	fake.mutex.Unlock()
+
+	scanner.performScan()
+
+	events = sink.ofType(DiagModuleOverLimit)
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 ModuleOverLimit event, got %d", len(events))
+	}
+	if events[0].Fields["cpu_percent"] != 3.0 {
+		t.Errorf("Expected cpu_percent field, got %v", events[0].Fields["cpu_percent"])
+	}
+	if events[0].Fields["limit"] != DefaultConfig().ProcessScanner.MaxCPUUsage {
+		t.Errorf("Expected limit field, got %v", events[0].Fields["limit"])
+	}
+}
+
+func TestLogDiagnosticSink_Emit(t *testing.T) {
+	var buf bytes.Buffer
+	formatter := log.GetFormatter()
+	log.SetOutput(&buf)
+	log.SetFormatter(&logrus.JSONFormatter{})
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFormatter(formatter)
+	}()
+
+	LogDiagnosticSink{}.Emit(DiagEvent{
+		Component: "process-scanner",
+		Type:      DiagScanError,
+		Severity:  DiagSeverityWarning,
+		Message:   "scan failed",
+		Fields:    map[string]interface{}{"error": "procfs unavailable", "attempt": 3},
+		Timestamp: time.Now(),
+	})
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("log output is not JSON: %v (%q)", err, buf.String())
+	}
+	expected := map[string]interface{}{
+		"component":  "process-scanner",
+		"diag_event": string(DiagScanError),
+		"severity":   string(DiagSeverityWarning),
+		"level":      "warning",
+		"msg":        "scan failed",
+		"error":      "procfs unavailable",
+		"attempt":    float64(3),
+	}
+	for key, value := range expected {
+		if entry[key] != value {
+			t.Errorf("expected %s=%v, got %v", key, value, entry[key])
+		}
+	}
+	if _, ok := entry["timestamp"]; !ok {
+		t.Error("expected the event timestamp to be logged")
+	}
+}
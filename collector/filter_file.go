@@ -0,0 +1,161 @@
+package collector
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+)
+
+// FilterLineError is an invalid line of a filter file. The other lines of
+// the file are still applied
+type FilterLineError struct {
+	Line int    // 1-based line number
+	Text string // Content of the line
+	Err  error
+}
+
+// Error returns the line number and the reason the line is invalid
+func (e *FilterLineError) Error() string {
+	return fmt.Sprintf("line %d '%s': %v", e.Line, e.Text, e.Err)
+}
+
+// Unwrap returns the reason the line is invalid
+func (e *FilterLineError) Unwrap() error {
+	return e.Err
+}
+
+// parseFilterFile reads a filter file of one pattern per line, prefixed by
+// '+' to include or '-' to exclude matching processes. Blank lines and lines
+// starting with '#' are ignored. Invalid lines are skipped and returned as
+// FilterLineErrors, the error is set when the file cannot be read
+func parseFilterFile(path string, syntax PatternSyntax) (include, exclude []*regexp.Regexp, lineErrs []error, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pattern := strings.TrimSpace(line[1:])
+		if pattern == "" || (line[0] != '+' && line[0] != '-') {
+			lineErrs = append(lineErrs, &FilterLineError{Line: number, Text: line, Err: errors.New("expected '+' or '-' followed by a pattern")})
+			continue
+		}
+
+		compiled, compileErr := compilePatterns([]string{pattern}, syntax)
+		if compileErr != nil {
+			lineErrs = append(lineErrs, &FilterLineError{Line: number, Text: line, Err: compileErr})
+			continue
+		}
+
+		if line[0] == '+' {
+			include = append(include, compiled...)
+		} else {
+			exclude = append(exclude, compiled...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return include, exclude, lineErrs, nil
+}
+
+// loadFilters compiles the configured patterns and the patterns of
+// FilterFile, and replaces the filters of the scanner at once. Invalid lines
+// of the file are reported and skipped, the returned error joins them. If
+// the file cannot be read the filters are left unchanged
+func (p *ProcessScanner) loadFilters() error {
+	exclude, err := compilePatterns(p.config.ExcludePatterns, p.config.PatternSyntax)
+	if err != nil {
+		return fmt.Errorf("invalid exclude pattern %w", err)
+	}
+
+	include, err := compilePatterns(p.config.IncludePatterns, p.config.PatternSyntax)
+	if err != nil {
+		return fmt.Errorf("invalid include pattern %w", err)
+	}
+
+	var lineErrs []error
+	if p.config.FilterFile != "" {
+		var fileInclude, fileExclude []*regexp.Regexp
+		fileInclude, fileExclude, lineErrs, err = parseFilterFile(p.config.FilterFile, p.config.PatternSyntax)
+		if err != nil {
+			return fmt.Errorf("failed to read filter file: %w", err)
+		}
+		include = append(include, fileInclude...)
+		exclude = append(exclude, fileExclude...)
+
+		for _, lineErr := range lineErrs {
+			p.diagnostics.Emit(newDiagEvent(DiagFilterFileError, DiagSeverityWarning,
+				map[string]interface{}{"file": p.config.FilterFile, "error": lineErr.Error()},
+				"Ignoring invalid filter in '%s': %v", p.config.FilterFile, lineErr))
+		}
+	}
+
+	p.filterMutex.Lock()
+	p.includeRegexps = include
+	p.excludeRegexps = exclude
+	p.filterMutex.Unlock()
+
+	if len(lineErrs) > 0 {
+		return fmt.Errorf("invalid lines in filter file '%s': %w", p.config.FilterFile, errors.Join(lineErrs...))
+	}
+	return nil
+}
+
+// ReloadFilters re-reads FilterFile and replaces the include and exclude
+// filters with the configured patterns and those of the file. Scans already
+// filtering keep the previous filters. Invalid lines are skipped and
+// returned, if the file cannot be read the filters are left unchanged
+func (p *ProcessScanner) ReloadFilters() error {
+	if p.config.FilterFile == "" {
+		return fmt.Errorf("no filter file configured")
+	}
+
+	err := p.loadFilters()
+
+	var lineErr *FilterLineError
+	if err == nil || errors.As(err, &lineErr) {
+		p.diagnostics.Emit(newDiagEvent(DiagFiltersReloaded, DiagSeverityInfo,
+			map[string]interface{}{"file": p.config.FilterFile},
+			"Reloaded process filters from '%s'", p.config.FilterFile))
+	}
+
+	return err
+}
+
+// watchFilterReloads reloads the filters on SIGHUP until the scanner is stopped
+func (p *ProcessScanner) watchFilterReloads() {
+	defer p.wg.Done()
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-hangups:
+			// Invalid lines were reported by the reload
+			var lineErr *FilterLineError
+			if err := p.ReloadFilters(); err != nil && !errors.As(err, &lineErr) {
+				p.diagnostics.Emit(newDiagEvent(DiagFilterFileError, DiagSeverityWarning,
+					map[string]interface{}{"file": p.config.FilterFile, "error": err.Error()},
+					"Error reloading filters from '%s': %v", p.config.FilterFile, err))
+			}
+		}
+	}
+}
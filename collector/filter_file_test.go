@@ -0,0 +1,138 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseFilterFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filters")
+	content := "# Fleet filters\n\n+^web\n-^batch\nweb\n+\n-[\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write filter file: %v", err)
+	}
+
+	include, exclude, lineErrs, err := parseFilterFile(path, PatternSyntaxRegex)
+	if err != nil {
+		t.Fatalf("Failed to parse filter file: %v", err)
+	}
+	if len(include) != 1 || len(exclude) != 1 {
+		t.Errorf("Expected 1 include and 1 exclude pattern, got %d and %d", len(include), len(exclude))
+	}
+
+	// Invalid lines are reported with their line numbers
+	expectedLines := []int{5, 6, 7}
+	if len(lineErrs) != len(expectedLines) {
+		t.Fatalf("Expected %d invalid lines, got %v", len(expectedLines), lineErrs)
+	}
+	for i, lineErr := range lineErrs {
+		var invalid *FilterLineError
+		if !errors.As(lineErr, &invalid) || invalid.Line != expectedLines[i] {
+			t.Errorf("Expected an error for line %d, got %v", expectedLines[i], lineErr)
+		}
+	}
+
+	if _, _, _, err := parseFilterFile(filepath.Join(t.TempDir(), "missing"), PatternSyntaxRegex); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+}
+
+func TestProcessScanner_ReloadFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filters")
+	if err := os.WriteFile(path, []byte("-^batch\n-[\n"), 0644); err != nil {
+		t.Fatalf("Failed to write filter file: %v", err)
+	}
+
+	config := DefaultConfig().ProcessScanner
+	config.ScanInterval = time.Hour
+	config.AdaptiveSampling = false
+	config.FilterFile = path
+
+	fake := &fakePlatformCollector{}
+	scanner := NewProcessScanner(config)
+	sink := &recordingSink{}
+	scanner.SetDiagnosticSink(sink)
+
+	// The invalid line is reported without failing Init
+	if err := scanner.Init(context.Background(), map[string]interface{}{"mockCollector": fake}); err != nil {
+		t.Fatalf("Failed to initialize scanner: %v", err)
+	}
+	if errs := sink.ofType(DiagFilterFileError); len(errs) != 1 {
+		t.Errorf("Expected 1 filter file error, got %d", len(errs))
+	}
+
+	consumer := NewMockProcessConsumer()
+	scanner.RegisterConsumer("test", consumer)
+	if err := scanner.Start(); err != nil {
+		t.Fatalf("Failed to start scanner: %v", err)
+	}
+	defer scanner.Stop()
+
+	// Every scan changes the CPU of the processes, so each emits updated events
+	scan := func(cpu float64) {
+		fake.mutex.Lock()
+		fake.processes = []*ProcessInfo{
+			{PID: 1, Name: "web", Command: "web", CPU: cpu},
+			{PID: 2, Name: "noisy", Command: "noisy", CPU: cpu},
+			{PID: 3, Name: "batch", Command: "batch", CPU: cpu},
+		}
+		fake.mutex.Unlock()
+		if err := scanner.ForceScanSync(context.Background()); err != nil {
+			t.Fatalf("Failed to scan: %v", err)
+		}
+	}
+
+	eventsOf := func(events []ProcessEvent, pid int) int {
+		count := 0
+		for _, event := range events {
+			if event.Process.PID == pid && event.Type != ProcessTerminated {
+				count++
+			}
+		}
+		return count
+	}
+
+	scan(10)
+	scan(20)
+	events := consumer.GetEvents()
+	if eventsOf(events, 2) != 2 || eventsOf(events, 3) != 0 {
+		t.Fatalf("Expected events for noisy and none for batch, got %d and %d", eventsOf(events, 2), eventsOf(events, 3))
+	}
+
+	// Exclude noisy mid-run
+	if err := os.WriteFile(path, []byte("-^batch\n-^noisy\n"), 0644); err != nil {
+		t.Fatalf("Failed to write filter file: %v", err)
+	}
+	if err := scanner.ReloadFilters(); err != nil {
+		t.Fatalf("Failed to reload filters: %v", err)
+	}
+	if reloads := sink.ofType(DiagFiltersReloaded); len(reloads) != 1 {
+		t.Errorf("Expected 1 reload event, got %d", len(reloads))
+	}
+
+	seen := len(consumer.GetEvents())
+	scan(30)
+	scan(40)
+	events = consumer.GetEvents()[seen:]
+	if count := eventsOf(events, 2); count != 0 {
+		t.Errorf("Expected no events for the excluded process, got %d", count)
+	}
+	if count := eventsOf(events, 1); count != 2 {
+		t.Errorf("Expected 2 updated events for web, got %d", count)
+	}
+
+	// A failed reload keeps the current filters
+	os.Remove(path)
+	if err := scanner.ReloadFilters(); err == nil {
+		t.Errorf("Expected an error reloading a missing file")
+	}
+	seen = len(consumer.GetEvents())
+	scan(50)
+	if count := eventsOf(consumer.GetEvents()[seen:], 2); count != 0 {
+		t.Errorf("Expected the filters to be kept after a failed reload, got %d events", count)
+	}
+}
@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// globToRegexp translates a path.Match style glob into an anchored regular
+// expression. '*' and '?' do not match '/', as with path.Match
+func globToRegexp(pattern string) (string, error) {
+	// path.Match reports malformed patterns regardless of the name
+	if _, err := path.Match(pattern, ""); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case inClass && c == ']':
+			inClass = false
+			b.WriteByte(']')
+		case inClass && c == '-':
+			b.WriteByte('-')
+		case inClass:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			inClass = true
+			b.WriteByte('[')
+			if i+1 < len(pattern) && pattern[i+1] == '^' {
+				i++
+				b.WriteByte('^')
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+
+	b.WriteString("$")
+	return b.String(), nil
+}
@@ -0,0 +1,83 @@
+package collector
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		names   []string
+	}{
+		{"*.exe", []string{"svchost.exe", "app.exe.bak", ".exe", "bin/app.exe", "exe"}},
+		{"nginx*", []string{"nginx", "nginx: worker", "xnginx"}},
+		{"/usr/bin/?ython*", []string{"/usr/bin/python3", "/usr/bin/jython", "/usr/bin/ython"}},
+		{"[a-c]sh", []string{"ash", "bsh", "zsh", "-sh"}},
+		{"[^a-c]sh", []string{"ash", "zsh", "$sh"}},
+		{"a.b+c", []string{"a.b+c", "aXbbc"}},
+		{`\*literal`, []string{"*literal", "xliteral"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			expr, err := globToRegexp(tt.pattern)
+			if err != nil {
+				t.Fatalf("globToRegexp returned error: %v", err)
+			}
+			re := regexp.MustCompile(expr)
+
+			// The translation must agree with path.Match
+			for _, name := range tt.names {
+				expected, _ := path.Match(tt.pattern, name)
+				if re.MatchString(name) != expected {
+					t.Errorf("Pattern %q on %q: expected match %v", tt.pattern, name, expected)
+				}
+			}
+		})
+	}
+
+	if _, err := globToRegexp("[a-"); err == nil {
+		t.Errorf("Expected error for malformed glob")
+	}
+}
+
+func TestProcessScanner_GlobPatterns(t *testing.T) {
+	config := DefaultConfig().ProcessScanner
+	config.PatternSyntax = PatternSyntaxGlob
+	config.IncludePatterns = []string{"*.exe"}
+	config.ExcludePatterns = []string{"svc*"}
+	scanner := NewProcessScanner(config)
+	if err := scanner.Init(context.Background(), nil); err != nil {
+		t.Fatalf("Failed to initialize scanner: %v", err)
+	}
+
+	processes := []*ProcessInfo{
+		{PID: 1, Name: "app.exe", Command: `C:\app\app.exe`},
+		{PID: 2, Name: "svchost.exe", Command: `C:\Windows\svchost.exe`},
+		{PID: 3, Name: "cmd", Command: "cmd /c dir"},
+	}
+	filtered := scanner.filterProcesses(processes)
+	if len(filtered) != 1 || filtered[0].PID != 1 {
+		t.Errorf("Expected only app.exe to pass the filters, got %d processes", len(filtered))
+	}
+
+	// Invalid globs name the pattern in the init error
+	config.IncludePatterns = []string{"[app"}
+	scanner = NewProcessScanner(config)
+	err := scanner.Init(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid include pattern '[app'") {
+		t.Errorf("Expected init error naming the bad pattern, got %v", err)
+	}
+
+	// The same pattern is a regex error by default
+	config.PatternSyntax = ""
+	config.IncludePatterns = []string{"*.exe"}
+	scanner = NewProcessScanner(config)
+	if err := scanner.Init(context.Background(), nil); err == nil {
+		t.Errorf("Expected regex compile error for '*.exe'")
+	}
+}
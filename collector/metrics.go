@@ -1,15 +1,31 @@
 package collector
 
 import (
+	"strings"
 	"sync"
 	"time"
+	
+	"github.com/newrelic/infrastructure-agent/sketch"
 )
 
+// durationQuantiles are the percentiles reported for timers with a sketch,
+// keyed by the suffix replacing the _ms suffix of the timer name
+var durationQuantiles = []struct {
+	suffix   string
+	quantile float64
+}{
+	{"_p50_ms", 0.50},
+	{"_p95_ms", 0.95},
+	{"_p99_ms", 0.99},
+}
+
 // MetricsTracker collects and aggregates metrics for the collector module
 type MetricsTracker struct {
 	metrics     map[string]float64
 	counters    map[string]int64
 	timers      map[string]time.Duration
+	sketches    map[string]*sketch.DDSketch // Timer durations in milliseconds
+	sketchConfig map[string]sketch.DDSketchConfig
 	mutex       sync.RWMutex
 	startTime   time.Time
 }
@@ -20,10 +36,22 @@ func NewMetricsTracker() *MetricsTracker {
 		metrics:   make(map[string]float64),
 		counters:  make(map[string]int64),
 		timers:    make(map[string]time.Duration),
+		sketches:  make(map[string]*sketch.DDSketch),
+		sketchConfig: make(map[string]sketch.DDSketchConfig),
 		startTime: time.Now(),
 	}
 }
 
+// EnableDurationSketch accumulates the durations recorded for a timer in a
+// DDSketch, so GetAllMetrics also reports their p50, p95 and p99
+func (m *MetricsTracker) EnableDurationSketch(name string, config sketch.DDSketchConfig) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	
+	m.sketches[name] = sketch.NewDDSketch(config)
+	m.sketchConfig[name] = config
+}
+
 // SetGauge sets a gauge metric value
 func (m *MetricsTracker) SetGauge(name string, value float64) {
 	m.mutex.Lock()
@@ -62,6 +90,10 @@ func (m *MetricsTracker) RecordDuration(name string, duration time.Duration) {
 	defer m.mutex.Unlock()
 	
 	m.timers[name] = duration
+	
+	if s, ok := m.sketches[name]; ok {
+		_ = s.Add(float64(duration) / float64(time.Millisecond))
+	}
 }
 
 // GetDuration gets a timer duration
@@ -104,6 +136,19 @@ func (m *MetricsTracker) GetAllMetrics() map[string]float64 {
 		result[k+"_ms"] = float64(v.Milliseconds())
 	}
 	
+	// Percentiles are missing until the first recorded duration
+	for k, s := range m.sketches {
+		if s.GetCount() == 0 {
+			continue
+		}
+		base := strings.TrimSuffix(k, "_ms")
+		for _, q := range durationQuantiles {
+			if value, err := s.GetValueAtQuantile(q.quantile); err == nil {
+				result[base+q.suffix] = value
+			}
+		}
+	}
+	
 	// Add uptime
 	result["uptime_seconds"] = float64(time.Since(m.startTime).Seconds())
 	
@@ -118,9 +163,60 @@ func (m *MetricsTracker) Reset() {
 	m.metrics = make(map[string]float64)
 	m.counters = make(map[string]int64)
 	m.timers = make(map[string]time.Duration)
+	for name, config := range m.sketchConfig {
+		m.sketches[name] = sketch.NewDDSketch(config)
+	}
 	m.startTime = time.Now()
 }
 
+// MetricsReader reads the counters of a MetricsTracker as deltas since its
+// previous read, so an exporter computes rates without keeping the previous
+// values itself. Each exporter needs its own reader
+type MetricsReader struct {
+	tracker   *MetricsTracker
+	baselines map[string]int64 // Counter values at the previous read
+	mutex     sync.Mutex
+}
+
+// NewReader returns a reader whose first read returns the counter
+// increments since the reader was created
+func (m *MetricsTracker) NewReader() *MetricsReader {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	
+	baselines := make(map[string]int64, len(m.counters))
+	for k, v := range m.counters {
+		baselines[k] = v
+	}
+	return &MetricsReader{
+		tracker:   m,
+		baselines: baselines,
+	}
+}
+
+// CounterDeltas returns the increment of every counter since the previous
+// read of this reader. Concurrent reads of a reader each return distinct
+// increments, so none is counted twice. A counter cleared by Reset counts
+// from zero
+func (r *MetricsReader) CounterDeltas() map[string]float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	
+	r.tracker.mutex.RLock()
+	defer r.tracker.mutex.RUnlock()
+	
+	deltas := make(map[string]float64, len(r.tracker.counters))
+	for k, v := range r.tracker.counters {
+		baseline := r.baselines[k]
+		if v < baseline {
+			baseline = 0
+		}
+		deltas[k] = float64(v - baseline)
+		r.baselines[k] = v
+	}
+	return deltas
+}
+
 // ProcessScannerMetrics defines the standard metrics for the process scanner
 const (
 	// Performance metrics
@@ -133,15 +229,35 @@ const (
 	MetricProcessCreated       = "process_created_total"
 	MetricProcessUpdated       = "process_updated_total"
 	MetricProcessTerminated    = "process_terminated_total"
+	MetricProcessesDeferred    = "processes_deferred"
+	MetricProcessesPartial     = "processes_partial"
 	
 	// Error metrics
 	MetricScanErrors           = "scan_errors_total"
 	MetricLimitBreaches        = "limit_breaches_total"
 	MetricNotificationErrors   = "notification_errors_total"
+	MetricEventsDropped        = "events_dropped_total"
+	MetricEventsEvicted        = "events_evicted_total"
+	MetricConsumerTimeouts     = "consumer_timeouts_total"
+	MetricConsumerDropped      = "consumer_events_dropped_total"
+	MetricFDAccessErrors       = "fd_access_errors_total"
+	MetricEventsFiltered       = "events_filtered_total"
+	MetricEventsCoalesced      = "events_coalesced_total"
+	
+	// Connection collection
+	MetricConnectionScansSkipped = "connection_scans_skipped_total"
 	
 	// Resource tracking
 	MetricScanIntervalActual   = "scan_interval_actual_ms"
 	MetricAdaptiveRateChanges  = "adaptive_rate_changes_total"
 	MetricEventQueueSize       = "event_queue_size"
 	MetricConsumerCount        = "consumer_count"
+	MetricDegradationLevel     = "degradation_level"
+	
+	// MetricBackpressurePolicy is suffixed with the effective policy and set to 1
+	MetricBackpressurePolicy   = "backpressure_policy_"
+	
+	// Per-consumer metrics are suffixed with the consumer name
+	MetricConsumerLag          = "consumer_lag_ms_"
+	MetricConsumerQueueDepth   = "consumer_queue_depth_"
 )
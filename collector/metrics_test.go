@@ -0,0 +1,186 @@
+package collector
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/sketch"
+)
+
+func TestMetricsTracker_DurationSketch(t *testing.T) {
+	m := NewMetricsTracker()
+	m.EnableDurationSketch(MetricScanDuration, sketch.DefaultConfig().DDSketch)
+
+	// Percentiles are missing until a duration is recorded
+	if _, ok := m.GetAllMetrics()["scan_duration_p50_ms"]; ok {
+		t.Errorf("Expected no percentiles before the first duration")
+	}
+
+	for i := 1; i <= 100; i++ {
+		m.RecordDuration(MetricScanDuration, time.Duration(i)*time.Millisecond)
+	}
+
+	metrics := m.GetAllMetrics()
+	expected := map[string]float64{
+		"scan_duration_p50_ms": 50,
+		"scan_duration_p95_ms": 95,
+		"scan_duration_p99_ms": 99,
+	}
+	for name, value := range expected {
+		got, ok := metrics[name]
+		if !ok {
+			t.Errorf("Expected metric %s", name)
+			continue
+		}
+		// Within the sketch relative accuracy
+		if math.Abs(got-value)/value > 0.01 {
+			t.Errorf("Expected %s ~%v, got %v", name, value, got)
+		}
+	}
+
+	// The last duration is still reported as a timer
+	if metrics[MetricScanDuration+"_ms"] != 100 {
+		t.Errorf("Expected last scan duration 100, got %v", metrics[MetricScanDuration+"_ms"])
+	}
+
+	m.Reset()
+	if _, ok := m.GetAllMetrics()["scan_duration_p50_ms"]; ok {
+		t.Errorf("Expected no percentiles after reset")
+	}
+
+	// Timers without a sketch have no percentiles
+	m.RecordDuration("other_ms", time.Millisecond)
+	if _, ok := m.GetAllMetrics()["other_p50_ms"]; ok {
+		t.Errorf("Expected no percentiles for timers without a sketch")
+	}
+}
+
+func TestMetricsReader_CounterDeltas(t *testing.T) {
+	m := NewMetricsTracker()
+	m.IncrementCounter(MetricProcessCreated, 5)
+
+	r := m.NewReader()
+	m.IncrementCounter(MetricProcessCreated, 3)
+	m.IncrementCounter(MetricProcessTerminated, 2)
+
+	deltas := r.CounterDeltas()
+	if deltas[MetricProcessCreated] != 3 || deltas[MetricProcessTerminated] != 2 {
+		t.Errorf("Expected deltas of 3 created and 2 terminated, got %v", deltas)
+	}
+	if deltas = r.CounterDeltas(); deltas[MetricProcessCreated] != 0 {
+		t.Errorf("Expected no delta without increments, got %v", deltas)
+	}
+
+	// Readers do not share their baselines
+	if deltas = m.NewReader().CounterDeltas(); deltas[MetricProcessCreated] != 0 {
+		t.Errorf("Expected a new reader to start from the current counters, got %v", deltas)
+	}
+
+	m.Reset()
+	m.IncrementCounter(MetricProcessCreated, 4)
+	if deltas = r.CounterDeltas(); deltas[MetricProcessCreated] != 4 {
+		t.Errorf("Expected a delta of 4 after reset, got %v", deltas)
+	}
+}
+
+func TestMetricsReader_Concurrent(t *testing.T) {
+	const writers, increments = 4, 1000
+
+	m := NewMetricsTracker()
+	shared := m.NewReader()
+	own := []*MetricsReader{m.NewReader(), m.NewReader()}
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < increments; j++ {
+				m.IncrementCounter(MetricProcessCreated, 1)
+			}
+		}()
+	}
+
+	// Several goroutines read the shared reader, each reads its own
+	var mu sync.Mutex
+	sharedTotal := 0.0
+	ownTotals := make([]float64, len(own))
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				delta := shared.CounterDeltas()[MetricProcessCreated]
+				mu.Lock()
+				sharedTotal += delta
+				mu.Unlock()
+				select {
+				case <-done:
+					return
+				default:
+				}
+			}
+		}()
+	}
+	for i, r := range own {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				ownTotals[i] += r.CounterDeltas()[MetricProcessCreated]
+				select {
+				case <-done:
+					return
+				default:
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	// Increments read after the readers stopped
+	sharedTotal += shared.CounterDeltas()[MetricProcessCreated]
+	for i, r := range own {
+		ownTotals[i] += r.CounterDeltas()[MetricProcessCreated]
+	}
+
+	expected := float64(writers * increments)
+	if sharedTotal != expected {
+		t.Errorf("Expected the shared reader deltas to add up to %v, got %v", expected, sharedTotal)
+	}
+	for i, total := range ownTotals {
+		if total != expected {
+			t.Errorf("Expected the deltas of reader %d to add up to %v, got %v", i, expected, total)
+		}
+	}
+}
+
+func TestProcessScanner_ScanDurationPercentiles(t *testing.T) {
+	fake := &fakePlatformCollector{processes: []*ProcessInfo{{PID: 1, Name: "init"}}}
+
+	scanner := newTestScanner(t, fake)
+	scanner.performScan()
+	if _, ok := scanner.Metrics()["scan_duration_p99_ms"]; !ok {
+		t.Errorf("Expected scan duration percentiles by default")
+	}
+
+	config := DefaultConfig().ProcessScanner
+	config.ScanDurationPercentiles = false
+	scanner = NewProcessScanner(config)
+	if err := scanner.Init(context.Background(), nil); err != nil {
+		t.Fatalf("Failed to initialize scanner: %v", err)
+	}
+	scanner.platformCollector = fake
+	scanner.performScan()
+	if _, ok := scanner.Metrics()["scan_duration_p99_ms"]; ok {
+		t.Errorf("Expected no scan duration percentiles when disabled")
+	}
+}
@@ -0,0 +1,112 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// containerIDLength is the length of docker, containerd and cri-o container IDs
+const containerIDLength = 64
+
+// containerIDPrefixes are the runtime prefixes used by systemd scope names,
+// e.g. docker-<id>.scope or cri-containerd-<id>.scope
+var containerIDPrefixes = []string{
+	"docker-",
+	"cri-containerd-",
+	"containerd-",
+	"crio-",
+	"libpod-",
+}
+
+// cgroupInfo holds the cgroup membership of a process
+type cgroupInfo struct {
+	path        string // Unified (v2) path, or the v1 path of the preferred hierarchy
+	containerID string // Empty when the process is not containerized
+}
+
+// readCgroup reads and parses /proc/<pid>/cgroup
+func readCgroup(procFSPath string, pid int) (*cgroupInfo, error) {
+	data, err := os.ReadFile(filepath.Join(procFSPath, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return nil, err
+	}
+	return parseCgroup(string(data)), nil
+}
+
+// parseCgroup parses the content of /proc/<pid>/cgroup.
+// Each line is hierarchy-ID:controller-list:cgroup-path. The cgroup v2 entry
+// (0::<path>) is preferred, then the name=systemd v1 hierarchy, then the first
+// entry. The container ID is taken from the first path that contains one
+func parseCgroup(data string) *cgroupInfo {
+	info := &cgroupInfo{}
+	var unified, systemd, first string
+
+	for _, line := range strings.Split(data, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		path := parts[2]
+
+		switch {
+		case parts[0] == "0" && parts[1] == "":
+			unified = path
+		case parts[1] == "name=systemd":
+			systemd = path
+		}
+		if first == "" {
+			first = path
+		}
+
+		if info.containerID == "" {
+			info.containerID = containerIDFromCgroup(path)
+		}
+	}
+
+	switch {
+	case unified != "":
+		info.path = unified
+	case systemd != "":
+		info.path = systemd
+	default:
+		info.path = first
+	}
+
+	return info
+}
+
+// containerIDFromCgroup extracts a container ID from a cgroup path. Handles
+// cgroupfs layouts (/docker/<id>, /kubepods/.../pod<uid>/<id>) and systemd
+// scopes (docker-<id>.scope, cri-containerd-<id>.scope, crio-<id>.scope).
+// The innermost matching path segment wins
+func containerIDFromCgroup(path string) string {
+	segments := strings.Split(path, "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		segment := strings.TrimSuffix(segments[i], ".scope")
+		for _, prefix := range containerIDPrefixes {
+			if strings.HasPrefix(segment, prefix) {
+				segment = strings.TrimPrefix(segment, prefix)
+				break
+			}
+		}
+		if isContainerID(segment) {
+			return segment
+		}
+	}
+	return ""
+}
+
+// isContainerID reports whether s looks like a 64 character hex container ID
+func isContainerID(s string) bool {
+	if len(s) != containerIDLength {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package platform
+
+import (
+	"testing"
+)
+
+const testContainerID = "3f1c5be0212bf7a4046bbb32998c8e7a1d2c3b4a5f6e7d8c9b0a1f2e3d4c5b6a"
+
+func TestParseCgroup(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		path        string
+		containerID string
+	}{
+		{
+			name:        "cgroup v2 host process",
+			data:        "0::/user.slice/user-1000.slice/session-2.scope\n",
+			path:        "/user.slice/user-1000.slice/session-2.scope",
+			containerID: "",
+		},
+		{
+			name:        "cgroup v2 docker systemd scope",
+			data:        "0::/system.slice/docker-" + testContainerID + ".scope\n",
+			path:        "/system.slice/docker-" + testContainerID + ".scope",
+			containerID: testContainerID,
+		},
+		{
+			name: "cgroup v1 docker cgroupfs",
+			data: "12:memory:/docker/" + testContainerID + "\n" +
+				"11:cpu,cpuacct:/docker/" + testContainerID + "\n" +
+				"1:name=systemd:/docker/" + testContainerID + "\n",
+			path:        "/docker/" + testContainerID,
+			containerID: testContainerID,
+		},
+		{
+			name: "cgroup v1 systemd without container",
+			data: "12:memory:/user.slice\n" +
+				"1:name=systemd:/user.slice/user-1000.slice/session-2.scope\n",
+			path:        "/user.slice/user-1000.slice/session-2.scope",
+			containerID: "",
+		},
+		{
+			name:        "CRI containerd systemd driver",
+			data:        "0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod1a2b3c4d_5e6f_7a8b_9c0d_1e2f3a4b5c6d.slice/cri-containerd-" + testContainerID + ".scope\n",
+			path:        "/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod1a2b3c4d_5e6f_7a8b_9c0d_1e2f3a4b5c6d.slice/cri-containerd-" + testContainerID + ".scope",
+			containerID: testContainerID,
+		},
+		{
+			name:        "CRI cgroupfs driver",
+			data:        "11:memory:/kubepods/besteffort/pod1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d/" + testContainerID + "\n",
+			path:        "/kubepods/besteffort/pod1a2b3c4d-5e6f-7a8b-9c0d-1e2f3a4b5c6d/" + testContainerID,
+			containerID: testContainerID,
+		},
+		{
+			name:        "CRI-O",
+			data:        "0::/kubepods.slice/kubepods-pod1a2b3c4d_5e6f.slice/crio-" + testContainerID + ".scope\n",
+			path:        "/kubepods.slice/kubepods-pod1a2b3c4d_5e6f.slice/crio-" + testContainerID + ".scope",
+			containerID: testContainerID,
+		},
+		{
+			name:        "CRI-O conmon is not a container",
+			data:        "0::/kubepods.slice/kubepods-pod1a2b3c4d_5e6f.slice/crio-conmon-" + testContainerID + ".scope\n",
+			path:        "/kubepods.slice/kubepods-pod1a2b3c4d_5e6f.slice/crio-conmon-" + testContainerID + ".scope",
+			containerID: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := parseCgroup(tt.data)
+			if info.path != tt.path {
+				t.Errorf("Expected path %q, got %q", tt.path, info.path)
+			}
+			if info.containerID != tt.containerID {
+				t.Errorf("Expected container ID %q, got %q", tt.containerID, info.containerID)
+			}
+		})
+	}
+}
+
+func TestLinuxProcessCollector_ContainerID(t *testing.T) {
+	root := t.TempDir()
+	writeSystemStat(t, root, 10000, 1)
+	writePidStat(t, root, 400, "app", 0, 0, 100)
+	writeProcFile(t, root, "400/cgroup", "0::/system.slice/docker-"+testContainerID+".scope\n")
+	writePidStat(t, root, 401, "host", 0, 0, 100)
+
+	l, _ := NewLinuxProcessCollector(map[string]interface{}{"procFSPath": root})
+
+	p, err := l.GetProcess(400)
+	if err != nil {
+		t.Fatalf("GetProcess returned error: %v", err)
+	}
+	if p.ContainerID != testContainerID {
+		t.Errorf("Expected container ID %q, got %q", testContainerID, p.ContainerID)
+	}
+	if p.Labels["container_id"] != testContainerID {
+		t.Errorf("Expected container_id label %q, got %q", testContainerID, p.Labels["container_id"])
+	}
+
+	// Missing cgroup file leaves the process unattributed
+	p, err = l.GetProcess(401)
+	if err != nil {
+		t.Fatalf("GetProcess returned error: %v", err)
+	}
+	if p.CgroupPath != "" || p.ContainerID != "" || p.Labels != nil {
+		t.Errorf("Expected no cgroup info, got %q %q %v", p.CgroupPath, p.ContainerID, p.Labels)
+	}
+}
@@ -0,0 +1,402 @@
+//go:build darwin && cgo
+
+package platform
+
+/*
+#include <errno.h>
+#include <libproc.h>
+#include <sys/proc_info.h>
+#include <mach/mach.h>
+#include <mach/mach_time.h>
+
+// host_vm_stats reads the VM statistics of the host, releasing the host port
+static kern_return_t host_vm_stats(vm_statistics64_data_t *stats) {
+	mach_msg_type_number_t count = HOST_VM_INFO64_COUNT;
+	mach_port_t host = mach_host_self();
+	kern_return_t ret = host_statistics64(host, HOST_VM_INFO64, (host_info64_t)stats, &count);
+	mach_port_deallocate(mach_task_self(), host);
+	return ret;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/newrelic/infrastructure-agent/collector"
+	"golang.org/x/sys/unix"
+)
+
+// DarwinProcessCollector collects process information on macOS through libproc
+type DarwinProcessCollector struct {
+	numCPU         int
+	timebaseNumer  uint64                  // Mach timebase numerator, task CPU times are in Mach units
+	timebaseDenom  uint64                  // Mach timebase denominator
+	cpuSamples     map[int]darwinCPUSample // Per-PID CPU times from the last GetCPUTimes
+	cpuPercent     map[int]float64         // Per-PID CPU% computed by the last GetCPUTimes
+	lastSampleTime time.Time               // Wall time of the last GetCPUTimes
+	selfSample     darwinCPUSample         // CPU times of the agent from the last GetSelfUsage
+	selfSampleTime time.Time               // Wall time of the last GetSelfUsage
+	userNames      map[string]string       // UID to username cache
+	normalizeCPU   bool                    // Report CPU% of the whole machine instead of a core
+	lastUpdateTime time.Time
+	mu             sync.Mutex
+}
+
+// darwinCPUSample is a single CPU time reading for a process
+type darwinCPUSample struct {
+	procTime  uint64 // User + system time in nanoseconds
+	startTime int64  // Process start time in microseconds, detects PID reuse
+}
+
+// darwinTask holds the libproc information of a single process
+type darwinTask struct {
+	pid       int
+	ppid      int
+	uid       uint32
+	name      string
+	status    uint32
+	startTime time.Time
+	rss       int64
+	vms       int64
+	threads   int
+	sample    darwinCPUSample
+	taskInfo  bool // Memory, thread and CPU fields are set, they need the same user or root
+}
+
+// NewDarwinProcessCollector creates a new macOS process collector
+func NewDarwinProcessCollector(options map[string]interface{}) (*DarwinProcessCollector, error) {
+	var timebase C.mach_timebase_info_data_t
+	if ret := C.mach_timebase_info(&timebase); ret != C.KERN_SUCCESS || timebase.denom == 0 {
+		return nil, fmt.Errorf("mach_timebase_info failed: %d", int(ret))
+	}
+
+	return &DarwinProcessCollector{
+		numCPU:         runtime.NumCPU(),
+		timebaseNumer:  uint64(timebase.numer),
+		timebaseDenom:  uint64(timebase.denom),
+		cpuSamples:     make(map[int]darwinCPUSample),
+		cpuPercent:     make(map[int]float64),
+		userNames:      make(map[string]string),
+		normalizeCPU:   normalizedCPUOption(options),
+		lastUpdateTime: time.Now(),
+	}, nil
+}
+
+// GetProcesses returns a list of all processes on macOS.
+// CPU usage is the percentage computed by the most recent GetCPUTimes call,
+// processes without a baseline report 0%
+func (d *DarwinProcessCollector) GetProcesses() ([]*collector.ProcessInfo, error) {
+	pids, err := listAllPIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	processes := make([]*collector.ProcessInfo, 0, len(pids))
+	for _, pid := range pids {
+		task, err := d.readTask(pid)
+		if err != nil {
+			// Process exited between listing and reading
+			continue
+		}
+		processes = append(processes, d.buildProcessInfo(task))
+	}
+
+	return processes, nil
+}
+
+// GetProcess returns detailed information about a specific process on macOS
+func (d *DarwinProcessCollector) GetProcess(pid int) (*collector.ProcessInfo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	task, err := d.readTask(pid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read process %d: %w", pid, err)
+	}
+	return d.buildProcessInfo(task), nil
+}
+
+// IsProcessRunning checks if a process is running on macOS
+func (d *DarwinProcessCollector) IsProcessRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	// EPERM means the process exists but belongs to another user
+	err := unix.Kill(pid, 0)
+	return err == nil || err == unix.EPERM
+}
+
+// GetProcessCount returns the total number of processes on macOS
+func (d *DarwinProcessCollector) GetProcessCount() (int, error) {
+	pids, err := listAllPIDs()
+	if err != nil {
+		return 0, err
+	}
+	return len(pids), nil
+}
+
+// GetCPUTimes samples per-process user and system times on macOS and
+// computes each process CPU% over the interval since the previous call
+func (d *DarwinProcessCollector) GetCPUTimes() error {
+	pids, err := listAllPIDs()
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+
+	// System time available across all cores in nanoseconds
+	var systemDelta uint64
+	if !d.lastSampleTime.IsZero() {
+		systemDelta = uint64(now.Sub(d.lastSampleTime).Nanoseconds()) * uint64(d.numCPU)
+	}
+
+	samples := make(map[int]darwinCPUSample, len(pids))
+	percent := make(map[int]float64, len(pids))
+	for _, pid := range pids {
+		task, err := d.readTask(pid)
+		if err != nil || !task.taskInfo {
+			// Exited, or owned by another user without root
+			continue
+		}
+		samples[pid] = task.sample
+
+		// First scan, new process or reused PID: no baseline yet
+		prev, ok := d.cpuSamples[pid]
+		if !ok || prev.startTime != task.sample.startTime {
+			percent[pid] = 0
+			continue
+		}
+
+		var procDelta uint64
+		if task.sample.procTime > prev.procTime {
+			procDelta = task.sample.procTime - prev.procTime
+		}
+		percent[pid] = computeCPUPercent(procDelta, systemDelta, d.numCPU, d.normalizeCPU)
+	}
+
+	// Replacing the maps drops baselines of exited processes
+	d.cpuSamples = samples
+	d.cpuPercent = percent
+	d.lastSampleTime = now
+	d.lastUpdateTime = now
+	return nil
+}
+
+// GetMemoryStats returns memory information for the macOS system. Used
+// memory counts active, wired and compressed pages like Activity Monitor
+func (d *DarwinProcessCollector) GetMemoryStats() (uint64, uint64, error) {
+	total, err := unix.SysctlUint64("hw.memsize")
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read hw.memsize: %w", err)
+	}
+
+	var stats C.vm_statistics64_data_t
+	if ret := C.host_vm_stats(&stats); ret != C.KERN_SUCCESS {
+		return 0, 0, fmt.Errorf("host_statistics64 failed: %d", int(ret))
+	}
+
+	pageSize := uint64(os.Getpagesize())
+	used := (uint64(stats.active_count) + uint64(stats.wire_count) + uint64(stats.compressor_page_count)) * pageSize
+	return total, used, nil
+}
+
+// GetSelfUsage returns the resource usage of the current process
+func (d *DarwinProcessCollector) GetSelfUsage() (float64, uint64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	task, err := d.readTask(os.Getpid())
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// CPU% since the previous call, 0 on the first call
+	now := time.Now()
+	var cpuPct float64
+	if !d.selfSampleTime.IsZero() && task.sample.procTime >= d.selfSample.procTime {
+		systemDelta := uint64(now.Sub(d.selfSampleTime).Nanoseconds()) * uint64(d.numCPU)
+		// The agent usage is compared with MaxCPUUsage, it stays per core
+		cpuPct = computeCPUPercent(task.sample.procTime-d.selfSample.procTime, systemDelta, d.numCPU, false)
+	}
+	d.selfSample = task.sample
+	d.selfSampleTime = now
+
+	return cpuPct, uint64(task.rss), nil
+}
+
+// Capabilities returns the process fields collected on macOS. Processes of
+// other users only report them when the agent runs as root
+func (d *DarwinProcessCollector) Capabilities() collector.ProcessCapabilities {
+	return collector.ProcessCapabilities{
+		CPUPercent: true,
+		RSS:        true,
+		VMS:        true,
+		Threads:    true,
+	}
+}
+
+// Shutdown cleans up any resources
+func (d *DarwinProcessCollector) Shutdown() error {
+	return nil
+}
+
+// buildProcessInfo converts a libproc task into a ProcessInfo. Caller must hold the mutex
+func (d *DarwinProcessCollector) buildProcessInfo(task *darwinTask) *collector.ProcessInfo {
+	info := &collector.ProcessInfo{
+		PID:         task.pid,
+		PPID:        task.ppid,
+		Name:        task.name,
+		User:        d.lookupUser(task.uid),
+		RSS:         task.rss,
+		VMS:         task.vms,
+		Threads:     task.threads,
+		StartTime:   task.startTime,
+		State:       darwinState(task.status),
+		LastUpdated: time.Now(),
+	}
+
+	// Only report CPU% when the sample belongs to this process instance
+	if sample, ok := d.cpuSamples[task.pid]; ok && task.taskInfo && sample.startTime == task.sample.startTime {
+		info.CPU = d.cpuPercent[task.pid]
+	}
+
+	// Arguments of other users' processes are only readable by root
+	if data, err := unix.SysctlRaw("kern.procargs2", task.pid); err == nil {
+		if executable, command, err := parseProcArgs(data); err == nil {
+			info.Executable = executable
+			info.Command = command
+		}
+	}
+	if info.Executable == "" {
+		info.Executable = pidPath(task.pid)
+	}
+	if info.Command == "" {
+		info.Command = info.Executable
+	}
+
+	return info
+}
+
+// readTask reads a process through proc_pidinfo. Processes of other users
+// fall back to the short BSD info, which has no memory, threads or CPU times
+func (d *DarwinProcessCollector) readTask(pid int) (*darwinTask, error) {
+	var all C.struct_proc_taskallinfo
+	size := C.int(unsafe.Sizeof(all))
+	if n, _ := C.proc_pidinfo(C.int(pid), C.PROC_PIDTASKALLINFO, 0, unsafe.Pointer(&all), size); n == size {
+		bsd := &all.pbsd
+		startMicros := int64(bsd.pbi_start_tvsec)*1e6 + int64(bsd.pbi_start_tvusec)
+		name := cString(bsd.pbi_name[:])
+		if name == "" {
+			name = cString(bsd.pbi_comm[:])
+		}
+
+		cpuTicks := uint64(all.ptinfo.pti_total_user) + uint64(all.ptinfo.pti_total_system)
+		return &darwinTask{
+			pid:       int(bsd.pbi_pid),
+			ppid:      int(bsd.pbi_ppid),
+			uid:       uint32(bsd.pbi_uid),
+			name:      name,
+			status:    uint32(bsd.pbi_status),
+			startTime: time.UnixMicro(startMicros),
+			rss:       int64(all.ptinfo.pti_resident_size),
+			vms:       int64(all.ptinfo.pti_virtual_size),
+			threads:   int(all.ptinfo.pti_threadnum),
+			sample: darwinCPUSample{
+				procTime:  cpuTicks * d.timebaseNumer / d.timebaseDenom,
+				startTime: startMicros,
+			},
+			taskInfo: true,
+		}, nil
+	}
+
+	var short C.struct_proc_bsdshortinfo
+	size = C.int(unsafe.Sizeof(short))
+	n, err := C.proc_pidinfo(C.int(pid), C.PROC_PIDT_SHORTBSDINFO, 0, unsafe.Pointer(&short), size)
+	if n != size {
+		return nil, fmt.Errorf("proc_pidinfo failed for process %d: %v", pid, err)
+	}
+
+	return &darwinTask{
+		pid:    int(short.pbsi_pid),
+		ppid:   int(short.pbsi_ppid),
+		uid:    uint32(short.pbsi_uid),
+		name:   cString(short.pbsi_comm[:]),
+		status: uint32(short.pbsi_status),
+	}, nil
+}
+
+// lookupUser resolves a UID to a username, caching lookups. Caller must hold the mutex
+func (d *DarwinProcessCollector) lookupUser(uid uint32) string {
+	id := strconv.FormatUint(uint64(uid), 10)
+	if name, ok := d.userNames[id]; ok {
+		return name
+	}
+
+	name := id
+	if u, err := user.LookupId(id); err == nil {
+		name = u.Username
+	}
+	d.userNames[id] = name
+	return name
+}
+
+// listAllPIDs enumerates all processes with proc_listallpids
+func listAllPIDs() ([]int, error) {
+	count, err := C.proc_listallpids(nil, 0)
+	if count <= 0 {
+		return nil, fmt.Errorf("proc_listallpids failed: %v", err)
+	}
+
+	// Leave room for processes started since the count
+	buf := make([]C.int, int(count)+64)
+	count, err = C.proc_listallpids(unsafe.Pointer(&buf[0]), C.int(len(buf))*C.int(unsafe.Sizeof(buf[0])))
+	if count <= 0 {
+		return nil, fmt.Errorf("proc_listallpids failed: %v", err)
+	}
+
+	pids := make([]int, 0, count)
+	for _, pid := range buf[:count] {
+		// PID 0 is the kernel task
+		if pid > 0 {
+			pids = append(pids, int(pid))
+		}
+	}
+	return pids, nil
+}
+
+// pidPath returns the executable path of a process, empty if not accessible
+func pidPath(pid int) string {
+	buf := make([]C.char, C.PROC_PIDPATHINFO_MAXSIZE)
+	if n := C.proc_pidpath(C.int(pid), unsafe.Pointer(&buf[0]), C.uint32_t(len(buf))); n <= 0 {
+		return ""
+	}
+	return cString(buf)
+}
+
+// cString converts a fixed size C char array that may lack a NUL terminator
+func cString(chars []C.char) string {
+	b := make([]byte, 0, len(chars))
+	for _, c := range chars {
+		if c == 0 {
+			break
+		}
+		b = append(b, byte(c))
+	}
+	return string(b)
+}
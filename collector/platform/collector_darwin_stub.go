@@ -0,0 +1,65 @@
+//go:build !darwin || !cgo
+
+package platform
+
+import (
+	"fmt"
+
+	"github.com/newrelic/infrastructure-agent/collector"
+)
+
+// errDarwinUnsupported is returned by the macOS collector when libproc is not available
+var errDarwinUnsupported = fmt.Errorf("darwin process collector is only available on darwin with cgo enabled")
+
+// DarwinProcessCollector is a no-op fallback so non-cgo builds still compile
+type DarwinProcessCollector struct{}
+
+// NewDarwinProcessCollector always fails without libproc
+func NewDarwinProcessCollector(options map[string]interface{}) (*DarwinProcessCollector, error) {
+	return nil, errDarwinUnsupported
+}
+
+// GetProcesses is not supported without libproc
+func (d *DarwinProcessCollector) GetProcesses() ([]*collector.ProcessInfo, error) {
+	return nil, errDarwinUnsupported
+}
+
+// GetProcess is not supported without libproc
+func (d *DarwinProcessCollector) GetProcess(pid int) (*collector.ProcessInfo, error) {
+	return nil, errDarwinUnsupported
+}
+
+// IsProcessRunning is not supported without libproc
+func (d *DarwinProcessCollector) IsProcessRunning(pid int) bool {
+	return false
+}
+
+// GetProcessCount is not supported without libproc
+func (d *DarwinProcessCollector) GetProcessCount() (int, error) {
+	return 0, errDarwinUnsupported
+}
+
+// GetCPUTimes is not supported without libproc
+func (d *DarwinProcessCollector) GetCPUTimes() error {
+	return errDarwinUnsupported
+}
+
+// GetMemoryStats is not supported without libproc
+func (d *DarwinProcessCollector) GetMemoryStats() (uint64, uint64, error) {
+	return 0, 0, errDarwinUnsupported
+}
+
+// GetSelfUsage is not supported without libproc
+func (d *DarwinProcessCollector) GetSelfUsage() (float64, uint64, error) {
+	return 0, 0, errDarwinUnsupported
+}
+
+// Capabilities reports no fields without libproc
+func (d *DarwinProcessCollector) Capabilities() collector.ProcessCapabilities {
+	return collector.ProcessCapabilities{}
+}
+
+// Shutdown cleans up any resources
+func (d *DarwinProcessCollector) Shutdown() error {
+	return nil
+}
@@ -0,0 +1,430 @@
+//go:build windows
+
+package platform
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/newrelic/infrastructure-agent/collector"
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modpsapi                 = windows.NewLazySystemDLL("psapi.dll")
+	procGetProcessMemoryInfo = modpsapi.NewProc("GetProcessMemoryInfo")
+	modkernel32              = windows.NewLazySystemDLL("kernel32.dll")
+	procGlobalMemoryStatusEx = modkernel32.NewProc("GlobalMemoryStatusEx")
+)
+
+// stillActive is the exit code reported by GetExitCodeProcess for running processes
+const stillActive = 259
+
+// processMemoryCounters mirrors PROCESS_MEMORY_COUNTERS, SIZE_T fields are pointer sized
+type processMemoryCounters struct {
+	CB                         uint32
+	PageFaultCount             uint32
+	PeakWorkingSetSize         uintptr
+	WorkingSetSize             uintptr
+	QuotaPeakPagedPoolUsage    uintptr
+	QuotaPagedPoolUsage        uintptr
+	QuotaPeakNonPagedPoolUsage uintptr
+	QuotaNonPagedPoolUsage     uintptr
+	PagefileUsage              uintptr
+	PeakPagefileUsage          uintptr
+}
+
+// memoryStatusEx mirrors MEMORYSTATUSEX
+type memoryStatusEx struct {
+	Length               uint32
+	MemoryLoad           uint32
+	TotalPhys            uint64
+	AvailPhys            uint64
+	TotalPageFile        uint64
+	AvailPageFile        uint64
+	TotalVirtual         uint64
+	AvailVirtual         uint64
+	AvailExtendedVirtual uint64
+}
+
+// WindowsProcessCollector collects process information on Windows
+type WindowsProcessCollector struct {
+	numCPU         int
+	cpuSamples     map[int]windowsCPUSample // Per-PID CPU times from the last GetCPUTimes
+	cpuPercent     map[int]float64          // Per-PID CPU% computed by the last GetCPUTimes
+	lastSampleTime time.Time                // Wall time of the last GetCPUTimes
+	selfSample     windowsCPUSample         // CPU times of the agent from the last GetSelfUsage
+	selfSampleTime time.Time                // Wall time of the last GetSelfUsage
+	userNames      map[string]string        // SID to account name cache
+	normalizeCPU   bool                     // Report CPU% of the whole machine instead of a core
+	lastUpdateTime time.Time
+	mu             sync.Mutex
+}
+
+// windowsCPUSample is a single CPU time reading for a process
+type windowsCPUSample struct {
+	procTime     uint64 // Kernel + user time in 100ns units
+	creationTime int64  // Process creation time, detects PID reuse
+}
+
+// windowsProcessTimes holds the result of GetProcessTimes
+type windowsProcessTimes struct {
+	startTime time.Time
+	sample    windowsCPUSample
+}
+
+// NewWindowsProcessCollector creates a new Windows process collector
+func NewWindowsProcessCollector(options map[string]interface{}) (*WindowsProcessCollector, error) {
+	return &WindowsProcessCollector{
+		numCPU:         runtime.NumCPU(),
+		cpuSamples:     make(map[int]windowsCPUSample),
+		cpuPercent:     make(map[int]float64),
+		userNames:      make(map[string]string),
+		normalizeCPU:   normalizedCPUOption(options),
+		lastUpdateTime: time.Now(),
+	}, nil
+}
+
+// GetProcesses returns a list of all processes on Windows.
+// CPU usage is the percentage computed by the most recent GetCPUTimes call,
+// processes without a baseline report 0%
+func (w *WindowsProcessCollector) GetProcesses() ([]*collector.ProcessInfo, error) {
+	entries, err := snapshotProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	processes := make([]*collector.ProcessInfo, 0, len(entries))
+	for i := range entries {
+		processes = append(processes, w.buildProcessInfo(&entries[i]))
+	}
+
+	return processes, nil
+}
+
+// GetProcess returns detailed information about a specific process on Windows
+func (w *WindowsProcessCollector) GetProcess(pid int) (*collector.ProcessInfo, error) {
+	entries, err := snapshotProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i := range entries {
+		if int(entries[i].ProcessID) == pid {
+			return w.buildProcessInfo(&entries[i]), nil
+		}
+	}
+
+	return nil, fmt.Errorf("process %d not found", pid)
+}
+
+// IsProcessRunning checks if a process is running on Windows
+func (w *WindowsProcessCollector) IsProcessRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer windows.CloseHandle(handle)
+
+	var exitCode uint32
+	if err := windows.GetExitCodeProcess(handle, &exitCode); err != nil {
+		return false
+	}
+	return exitCode == stillActive
+}
+
+// GetProcessCount returns the total number of processes on Windows
+func (w *WindowsProcessCollector) GetProcessCount() (int, error) {
+	entries, err := snapshotProcesses()
+	if err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// GetCPUTimes samples per-process kernel and user times on Windows and
+// computes each process CPU% over the interval since the previous call
+func (w *WindowsProcessCollector) GetCPUTimes() error {
+	entries, err := snapshotProcesses()
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+
+	// System time available across all cores in 100ns units
+	var systemDelta uint64
+	if !w.lastSampleTime.IsZero() {
+		elapsed := now.Sub(w.lastSampleTime)
+		systemDelta = uint64(elapsed.Nanoseconds()/100) * uint64(w.numCPU)
+	}
+
+	samples := make(map[int]windowsCPUSample, len(entries))
+	percent := make(map[int]float64, len(entries))
+	for i := range entries {
+		pid := int(entries[i].ProcessID)
+		times, err := getProcessTimes(entries[i].ProcessID)
+		if err != nil {
+			// Protected or exited process
+			continue
+		}
+		samples[pid] = times.sample
+
+		// First scan, new process or reused PID: no baseline yet
+		prev, ok := w.cpuSamples[pid]
+		if !ok || prev.creationTime != times.sample.creationTime {
+			percent[pid] = 0
+			continue
+		}
+
+		var procDelta uint64
+		if times.sample.procTime > prev.procTime {
+			procDelta = times.sample.procTime - prev.procTime
+		}
+		percent[pid] = computeCPUPercent(procDelta, systemDelta, w.numCPU, w.normalizeCPU)
+	}
+
+	// Replacing the maps drops baselines of exited processes
+	w.cpuSamples = samples
+	w.cpuPercent = percent
+	w.lastSampleTime = now
+	w.lastUpdateTime = now
+	return nil
+}
+
+// GetMemoryStats returns memory information for the Windows system
+func (w *WindowsProcessCollector) GetMemoryStats() (uint64, uint64, error) {
+	var status memoryStatusEx
+	status.Length = uint32(unsafe.Sizeof(status))
+
+	ret, _, err := procGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&status)))
+	if ret == 0 {
+		return 0, 0, fmt.Errorf("GlobalMemoryStatusEx failed: %w", err)
+	}
+
+	return status.TotalPhys, status.TotalPhys - status.AvailPhys, nil
+}
+
+// GetSelfUsage returns the resource usage of the current process
+func (w *WindowsProcessCollector) GetSelfUsage() (float64, uint64, error) {
+	pid := windows.GetCurrentProcessId()
+
+	times, err := getProcessTimes(pid)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	rss, _, err := getProcessMemory(pid)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// CPU% since the previous call, 0 on the first call
+	now := time.Now()
+	var cpuPct float64
+	if !w.selfSampleTime.IsZero() && times.sample.procTime >= w.selfSample.procTime {
+		systemDelta := uint64(now.Sub(w.selfSampleTime).Nanoseconds()/100) * uint64(w.numCPU)
+		// The agent usage is compared with MaxCPUUsage, it stays per core
+		cpuPct = computeCPUPercent(times.sample.procTime-w.selfSample.procTime, systemDelta, w.numCPU, false)
+	}
+	w.selfSample = times.sample
+	w.selfSampleTime = now
+
+	return cpuPct, rss, nil
+}
+
+// Capabilities returns the process fields collected on Windows. Protected
+// processes cannot be opened and report no CPU or memory
+func (w *WindowsProcessCollector) Capabilities() collector.ProcessCapabilities {
+	return collector.ProcessCapabilities{
+		CPUPercent: true,
+		RSS:        true,
+		VMS:        true,
+		Threads:    true,
+	}
+}
+
+// Shutdown cleans up any resources
+func (w *WindowsProcessCollector) Shutdown() error {
+	return nil
+}
+
+// buildProcessInfo converts a Toolhelp entry into a ProcessInfo, filling
+// in details from the process handle when it can be opened. Caller must hold the mutex
+func (w *WindowsProcessCollector) buildProcessInfo(entry *windows.ProcessEntry32) *collector.ProcessInfo {
+	pid := int(entry.ProcessID)
+	info := &collector.ProcessInfo{
+		PID:         pid,
+		PPID:        int(entry.ParentProcessID),
+		Name:        windows.UTF16ToString(entry.ExeFile[:]),
+		Threads:     int(entry.Threads),
+		State:       "Running",
+		LastUpdated: time.Now(),
+	}
+
+	// Protected processes cannot be opened, report what Toolhelp provides
+	if times, err := getProcessTimes(entry.ProcessID); err == nil {
+		info.StartTime = times.startTime
+
+		// Only report CPU% when the sample belongs to this process instance
+		if sample, ok := w.cpuSamples[pid]; ok && sample.creationTime == times.sample.creationTime {
+			info.CPU = w.cpuPercent[pid]
+		}
+	}
+
+	if rss, vms, err := getProcessMemory(entry.ProcessID); err == nil {
+		info.RSS = int64(rss)
+		info.VMS = int64(vms)
+	}
+
+	info.Executable = getProcessImageName(entry.ProcessID)
+	info.Command = info.Executable
+	info.User = w.lookupUser(entry.ProcessID)
+
+	return info
+}
+
+// lookupUser resolves the owner of a process, caching SID lookups. Caller must hold the mutex
+func (w *WindowsProcessCollector) lookupUser(pid uint32) string {
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return ""
+	}
+	defer windows.CloseHandle(handle)
+
+	var token windows.Token
+	if err := windows.OpenProcessToken(handle, windows.TOKEN_QUERY, &token); err != nil {
+		return ""
+	}
+	defer token.Close()
+
+	tokenUser, err := token.GetTokenUser()
+	if err != nil {
+		return ""
+	}
+
+	sid := tokenUser.User.Sid.String()
+	if name, ok := w.userNames[sid]; ok {
+		return name
+	}
+
+	name := sid
+	if account, domain, _, err := tokenUser.User.Sid.LookupAccount(""); err == nil {
+		name = account
+		if domain != "" {
+			name = domain + "\\" + account
+		}
+	}
+	w.userNames[sid] = name
+	return name
+}
+
+// snapshotProcesses enumerates all processes with the Toolhelp API
+func snapshotProcesses() ([]windows.ProcessEntry32, error) {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, fmt.Errorf("CreateToolhelp32Snapshot failed: %w", err)
+	}
+	defer windows.CloseHandle(snapshot)
+
+	var entry windows.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+
+	if err := windows.Process32First(snapshot, &entry); err != nil {
+		return nil, fmt.Errorf("Process32First failed: %w", err)
+	}
+
+	var entries []windows.ProcessEntry32
+	for {
+		entries = append(entries, entry)
+		if err := windows.Process32Next(snapshot, &entry); err != nil {
+			if err == windows.ERROR_NO_MORE_FILES {
+				break
+			}
+			return nil, fmt.Errorf("Process32Next failed: %w", err)
+		}
+	}
+
+	return entries, nil
+}
+
+// getProcessTimes returns the creation time and kernel+user time of a process
+func getProcessTimes(pid uint32) (*windowsProcessTimes, error) {
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(handle)
+
+	var creation, exit, kernel, user windows.Filetime
+	if err := windows.GetProcessTimes(handle, &creation, &exit, &kernel, &user); err != nil {
+		return nil, err
+	}
+
+	return &windowsProcessTimes{
+		startTime: time.Unix(0, creation.Nanoseconds()),
+		sample: windowsCPUSample{
+			procTime:     filetimeToUint64(kernel) + filetimeToUint64(user),
+			creationTime: creation.Nanoseconds(),
+		},
+	}, nil
+}
+
+// getProcessMemory returns the working set and private bytes of a process
+func getProcessMemory(pid uint32) (uint64, uint64, error) {
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION|windows.PROCESS_VM_READ, false, pid)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer windows.CloseHandle(handle)
+
+	var counters processMemoryCounters
+	counters.CB = uint32(unsafe.Sizeof(counters))
+
+	ret, _, err := procGetProcessMemoryInfo.Call(uintptr(handle), uintptr(unsafe.Pointer(&counters)), uintptr(counters.CB))
+	if ret == 0 {
+		return 0, 0, fmt.Errorf("GetProcessMemoryInfo failed: %w", err)
+	}
+
+	return uint64(counters.WorkingSetSize), uint64(counters.PagefileUsage), nil
+}
+
+// getProcessImageName returns the full path of the process executable
+func getProcessImageName(pid uint32) string {
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return ""
+	}
+	defer windows.CloseHandle(handle)
+
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	size := uint32(len(buf))
+	if err := windows.QueryFullProcessImageName(handle, 0, &buf[0], &size); err != nil {
+		return ""
+	}
+	return windows.UTF16ToString(buf[:size])
+}
+
+// filetimeToUint64 converts a FILETIME duration into 100ns units
+func filetimeToUint64(ft windows.Filetime) uint64 {
+	return uint64(ft.HighDateTime)<<32 | uint64(ft.LowDateTime)
+}
@@ -0,0 +1,65 @@
+//go:build !windows
+
+package platform
+
+import (
+	"fmt"
+
+	"github.com/newrelic/infrastructure-agent/collector"
+)
+
+// errWindowsOnly is returned by the Windows collector on other platforms
+var errWindowsOnly = fmt.Errorf("windows process collector is only available on windows")
+
+// WindowsProcessCollector is a no-op fallback so cross-compiles still build
+type WindowsProcessCollector struct{}
+
+// NewWindowsProcessCollector always fails outside of Windows
+func NewWindowsProcessCollector(options map[string]interface{}) (*WindowsProcessCollector, error) {
+	return nil, errWindowsOnly
+}
+
+// GetProcesses is not supported outside of Windows
+func (w *WindowsProcessCollector) GetProcesses() ([]*collector.ProcessInfo, error) {
+	return nil, errWindowsOnly
+}
+
+// GetProcess is not supported outside of Windows
+func (w *WindowsProcessCollector) GetProcess(pid int) (*collector.ProcessInfo, error) {
+	return nil, errWindowsOnly
+}
+
+// IsProcessRunning is not supported outside of Windows
+func (w *WindowsProcessCollector) IsProcessRunning(pid int) bool {
+	return false
+}
+
+// GetProcessCount is not supported outside of Windows
+func (w *WindowsProcessCollector) GetProcessCount() (int, error) {
+	return 0, errWindowsOnly
+}
+
+// GetCPUTimes is not supported outside of Windows
+func (w *WindowsProcessCollector) GetCPUTimes() error {
+	return errWindowsOnly
+}
+
+// GetMemoryStats is not supported outside of Windows
+func (w *WindowsProcessCollector) GetMemoryStats() (uint64, uint64, error) {
+	return 0, 0, errWindowsOnly
+}
+
+// GetSelfUsage is not supported outside of Windows
+func (w *WindowsProcessCollector) GetSelfUsage() (float64, uint64, error) {
+	return 0, 0, errWindowsOnly
+}
+
+// Capabilities reports no fields outside of Windows
+func (w *WindowsProcessCollector) Capabilities() collector.ProcessCapabilities {
+	return collector.ProcessCapabilities{}
+}
+
+// Shutdown cleans up any resources
+func (w *WindowsProcessCollector) Shutdown() error {
+	return nil
+}
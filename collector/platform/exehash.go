@@ -0,0 +1,53 @@
+package platform
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// defaultExecutableHashSizeKB bounds how much of a binary is hashed
+const defaultExecutableHashSizeKB = 64
+
+// executableKey identifies a version of a binary. A binary replaced or
+// modified at the same path gets a new key and is hashed again
+type executableKey struct {
+	path  string
+	mtime int64
+	size  int64
+}
+
+// statExecutable returns the cache key of the binary of a process
+func statExecutable(procFSPath string, pid int, path string) (executableKey, error) {
+	info, err := os.Stat(filepath.Join(procFSPath, strconv.Itoa(pid), "exe"))
+	if err != nil {
+		return executableKey{}, err
+	}
+	return executableKey{path: path, mtime: info.ModTime().UnixNano(), size: info.Size()}, nil
+}
+
+// hashExecutable returns the hex SHA-256 of the first maxBytes of the binary
+// of a process, or of the whole binary when maxBytes is 0. The binary is read
+// through /proc/<pid>/exe, which works for deleted binaries and other mount
+// namespaces
+func hashExecutable(procFSPath string, pid int, maxBytes int64) (string, error) {
+	file, err := os.Open(filepath.Join(procFSPath, strconv.Itoa(pid), "exe"))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if maxBytes > 0 {
+		reader = io.LimitReader(file, maxBytes)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,103 @@
+package platform
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLinuxProcessCollector_ExecutableHash(t *testing.T) {
+	root := t.TempDir()
+	writeSystemStat(t, root, 10000, 1)
+	writePidStat(t, root, 500, "server", 0, 0, 100)
+
+	binary := filepath.Join(t.TempDir(), "server")
+	content := bytes.Repeat([]byte("server binary "), 300)
+	if err := os.WriteFile(binary, content, 0o755); err != nil {
+		t.Fatalf("Failed to write binary: %v", err)
+	}
+	if err := os.Symlink(binary, filepath.Join(root, "500", "exe")); err != nil {
+		t.Fatalf("Failed to create exe symlink: %v", err)
+	}
+
+	sum := func(data []byte) string {
+		hash := sha256.Sum256(data)
+		return hex.EncodeToString(hash[:])
+	}
+
+	// Disabled by default
+	l, _ := NewLinuxProcessCollector(map[string]interface{}{"procFSPath": root})
+	p, err := l.GetProcess(500)
+	if err != nil {
+		t.Fatalf("GetProcess returned error: %v", err)
+	}
+	if p.ExecutableHash != "" {
+		t.Errorf("Expected no executable hash by default, got %s", p.ExecutableHash)
+	}
+
+	// Only the first KB is hashed
+	l, _ = NewLinuxProcessCollector(map[string]interface{}{
+		"procFSPath":            root,
+		"collectExecutableHash": true,
+		"executableHashSizeKB":  1,
+	})
+	processes, err := l.GetProcesses()
+	if err != nil || len(processes) != 1 {
+		t.Fatalf("Expected 1 process, got %v (%v)", processes, err)
+	}
+	if want := sum(content[:1024]); processes[0].ExecutableHash != want {
+		t.Errorf("Expected hash %s of the first KB, got %s", want, processes[0].ExecutableHash)
+	}
+
+	// Unchanged binaries are not hashed again
+	modTime := time.Now().Add(-time.Hour)
+	rewritten := bytes.Repeat([]byte("x"), len(content))
+	if err := os.WriteFile(binary, rewritten, 0o755); err != nil {
+		t.Fatalf("Failed to write binary: %v", err)
+	}
+	os.Chtimes(binary, modTime, modTime)
+	processes, _ = l.GetProcesses()
+	first := processes[0].ExecutableHash
+	os.WriteFile(binary, content, 0o755)
+	os.Chtimes(binary, modTime, modTime)
+	processes, _ = l.GetProcesses()
+	if processes[0].ExecutableHash != first {
+		t.Errorf("Expected cached hash %s for an unchanged mtime and size, got %s", first, processes[0].ExecutableHash)
+	}
+
+	// A new mtime invalidates the cached hash
+	os.Chtimes(binary, modTime.Add(time.Minute), modTime.Add(time.Minute))
+	processes, _ = l.GetProcesses()
+	if want := sum(content[:1024]); processes[0].ExecutableHash != want {
+		t.Errorf("Expected hash %s after the binary changed, got %s", want, processes[0].ExecutableHash)
+	}
+
+	// Hashes of binaries no longer running are dropped
+	if err := os.RemoveAll(filepath.Join(root, "500")); err != nil {
+		t.Fatalf("Failed to remove process: %v", err)
+	}
+	l.GetProcesses()
+	if len(l.exeHashes) != 0 {
+		t.Errorf("Expected hash cache to be pruned, got %v", l.exeHashes)
+	}
+
+	// Size 0 hashes the whole binary
+	writePidStat(t, root, 500, "server", 0, 0, 100)
+	os.Symlink(binary, filepath.Join(root, "500", "exe"))
+	l, _ = NewLinuxProcessCollector(map[string]interface{}{
+		"procFSPath":            root,
+		"collectExecutableHash": true,
+		"executableHashSizeKB":  0,
+	})
+	p, err = l.GetProcess(500)
+	if err != nil {
+		t.Fatalf("GetProcess returned error: %v", err)
+	}
+	if want := sum(content); p.ExecutableHash != want {
+		t.Errorf("Expected hash %s of the whole binary, got %s", want, p.ExecutableHash)
+	}
+}
@@ -0,0 +1,161 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// socketTables are the /proc/net tables attributed to processes, the bool
+// tells whether the table holds UDP sockets
+var socketTables = []struct {
+	name string
+	udp  bool
+}{
+	{"tcp", false},
+	{"tcp6", false},
+	{"udp", true},
+	{"udp6", true},
+}
+
+// TCP states from include/net/tcp_states.h as found in /proc/net/tcp
+const (
+	tcpStateListen = "0A"
+	tcpStateClose  = "07" // Unconnected UDP sockets
+)
+
+// socketInfo is a socket from the /proc/net tables
+type socketInfo struct {
+	port      int  // Local port
+	listening bool // TCP listener or bound, unconnected UDP socket
+}
+
+// connectionSample is the socket usage of a process from the last
+// collection, reused on scans that skip the socket tables
+type connectionSample struct {
+	startTicks     uint64 // Process start time, detects PID reuse
+	count          int
+	listeningPorts []int
+}
+
+// readSocketTables reads the TCP and UDP tables of the network namespace of
+// procFSPath, keyed by socket inode. Missing tables, e.g. with IPv6
+// disabled, are skipped
+func readSocketTables(procFSPath string) map[uint64]socketInfo {
+	sockets := make(map[uint64]socketInfo)
+	for _, table := range socketTables {
+		data, err := os.ReadFile(filepath.Join(procFSPath, "net", table.name))
+		if err != nil {
+			continue
+		}
+		parseSocketTable(string(data), table.udp, sockets)
+	}
+	return sockets
+}
+
+// parseSocketTable parses a /proc/net/{tcp,tcp6,udp,udp6} table into sockets.
+// Each line is: sl local_address rem_address st tx_queue:rx_queue tr:tm->when
+// retrnsmt uid timeout inode ..., with addresses as hex IP:port
+func parseSocketTable(data string, udp bool, sockets map[uint64]socketInfo) {
+	lines := strings.Split(data, "\n")
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 10 {
+			continue
+		}
+
+		// Sockets in TIME_WAIT have no inode
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil || inode == 0 {
+			continue
+		}
+
+		localPort, err := parseSocketPort(fields[1])
+		if err != nil {
+			continue
+		}
+		remotePort, err := parseSocketPort(fields[2])
+		if err != nil {
+			continue
+		}
+
+		state := fields[3]
+		listening := state == tcpStateListen
+		if udp {
+			listening = state == tcpStateClose && remotePort == 0
+		}
+
+		sockets[inode] = socketInfo{port: localPort, listening: listening}
+	}
+}
+
+// parseSocketPort extracts the port of a hex IP:port address
+func parseSocketPort(address string) (int, error) {
+	i := strings.LastIndexByte(address, ':')
+	port, err := strconv.ParseUint(address[i+1:], 16, 16)
+	if err != nil {
+		return 0, err
+	}
+	return int(port), nil
+}
+
+// readSocketInodes returns the inodes of the sockets a process has open.
+// Unreadable fd directories yield no sockets
+func readSocketInodes(procFSPath string, pid int) []uint64 {
+	fdDir := filepath.Join(procFSPath, strconv.Itoa(pid), "fd")
+	dir, err := os.Open(fdDir)
+	if err != nil {
+		return nil
+	}
+	defer dir.Close()
+
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return nil
+	}
+
+	var inodes []uint64
+	for _, name := range names {
+		target, err := os.Readlink(filepath.Join(fdDir, name))
+		if err != nil || !strings.HasPrefix(target, "socket:[") {
+			continue
+		}
+		inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]"), 10, 64)
+		if err != nil {
+			continue
+		}
+		inodes = append(inodes, inode)
+	}
+	return inodes
+}
+
+// sampleConnections attributes the sockets of a process. Listening sockets
+// contribute their port, all others count as connections. Sockets other than
+// TCP and UDP, e.g. unix sockets, are ignored
+func sampleConnections(procFSPath string, stat *procStat, sockets map[uint64]socketInfo) connectionSample {
+	sample := connectionSample{startTicks: stat.startTicks}
+
+	ports := make(map[int]struct{})
+	for _, inode := range readSocketInodes(procFSPath, stat.pid) {
+		socket, ok := sockets[inode]
+		if !ok {
+			continue
+		}
+		if socket.listening {
+			ports[socket.port] = struct{}{}
+		} else {
+			sample.count++
+		}
+	}
+
+	if len(ports) > 0 {
+		sample.listeningPorts = make([]int, 0, len(ports))
+		for port := range ports {
+			sample.listeningPorts = append(sample.listeningPorts, port)
+		}
+		sort.Ints(sample.listeningPorts)
+	}
+	return sample
+}
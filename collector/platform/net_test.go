@@ -0,0 +1,107 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testTCPTable = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0050 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1002 1 0000000000000000 100 0 0 10 0
+   2: 0100007F:0050 0100007F:C350 01 00000000:00000000 00:00000000 00000000     0        0 1003 1 0000000000000000 20 4 30 10 -1
+   3: 0100007F:0050 0100007F:C351 06 00000000:00000000 03:00000000 00000000     0        0 0 3 0000000000000000
+`
+
+const testUDPTable = `   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+  100: 00000000:0035 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 2001 2 0000000000000000 0
+  101: 0100007F:D431 0100007F:0035 01 00000000:00000000 00:00000000 00000000     0        0 2002 2 0000000000000000 0
+`
+
+func TestParseSocketTable(t *testing.T) {
+	sockets := make(map[uint64]socketInfo)
+	parseSocketTable(testTCPTable, false, sockets)
+	parseSocketTable(testUDPTable, true, sockets)
+
+	expected := map[uint64]socketInfo{
+		1001: {port: 80, listening: true},
+		1002: {port: 8080, listening: true},
+		1003: {port: 80, listening: false},
+		2001: {port: 53, listening: true},
+		2002: {port: 54321, listening: false},
+	}
+	if !reflect.DeepEqual(sockets, expected) {
+		t.Errorf("Expected sockets %v, got %v", expected, sockets)
+	}
+}
+
+func TestLinuxProcessCollector_Connections(t *testing.T) {
+	root := t.TempDir()
+	writeSystemStat(t, root, 10000, 1)
+	writeProcFile(t, root, "net/tcp", testTCPTable)
+	writeProcFile(t, root, "net/udp", testUDPTable)
+	writePidStat(t, root, 600, "server", 0, 0, 100)
+
+	fdDir := filepath.Join(root, "600", "fd")
+	if err := os.MkdirAll(fdDir, 0o755); err != nil {
+		t.Fatalf("Failed to create %s: %v", fdDir, err)
+	}
+	links := map[string]string{
+		"0": "/dev/null",
+		"3": "socket:[1001]",
+		"4": "socket:[1002]",
+		"5": "socket:[1003]",
+		"6": "socket:[2001]",
+		"7": "socket:[2002]",
+		"8": "socket:[9999]", // Unix socket, not in the tables
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(fdDir, name)); err != nil {
+			t.Fatalf("Failed to create fd symlink: %v", err)
+		}
+	}
+
+	l, _ := NewLinuxProcessCollector(map[string]interface{}{
+		"procFSPath":         root,
+		"collectConnections": true,
+	})
+
+	processes, err := l.GetProcesses()
+	if err != nil {
+		t.Fatalf("GetProcesses returned error: %v", err)
+	}
+	if len(processes) != 1 {
+		t.Fatalf("Expected 1 process, got %d", len(processes))
+	}
+	p := processes[0]
+	if p.ConnectionCount != 2 {
+		t.Errorf("Expected 2 connections, got %d", p.ConnectionCount)
+	}
+	if !reflect.DeepEqual(p.ListeningPorts, []int{53, 80, 8080}) {
+		t.Errorf("Expected listening ports [53 80 8080], got %v", p.ListeningPorts)
+	}
+
+	// Skipped collections keep the previous values
+	if err := os.Remove(filepath.Join(fdDir, "5")); err != nil {
+		t.Fatalf("Failed to remove fd symlink: %v", err)
+	}
+	l.SetConnectionCollection(false)
+	processes, _ = l.GetProcesses()
+	if processes[0].ConnectionCount != 2 {
+		t.Errorf("Expected previous 2 connections while skipped, got %d", processes[0].ConnectionCount)
+	}
+
+	l.SetConnectionCollection(true)
+	processes, _ = l.GetProcesses()
+	if processes[0].ConnectionCount != 1 {
+		t.Errorf("Expected 1 connection after collecting again, got %d", processes[0].ConnectionCount)
+	}
+
+	// Disabled collection leaves the fields empty
+	l, _ = NewLinuxProcessCollector(map[string]interface{}{"procFSPath": root})
+	processes, _ = l.GetProcesses()
+	if processes[0].ConnectionCount != 0 || processes[0].ListeningPorts != nil {
+		t.Errorf("Expected no connections without collectConnections, got %+v", processes[0])
+	}
+}
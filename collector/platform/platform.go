@@ -3,10 +3,16 @@ package platform
 
 import (
 	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"sync"
 	"time"
 	
 	"github.com/newrelic/infrastructure-agent/collector"
+	"github.com/shirou/gopsutil/v3/cpu"
 )
 
 // ProcessCollector defines the interface for platform-specific process collection
@@ -32,6 +38,9 @@ type ProcessCollector interface {
 	// GetSelfUsage returns the resource usage of the current process
 	GetSelfUsage() (float64, uint64, error) // cpu%, memory bytes, error
 	
+	// Capabilities returns which process fields the collector fills in
+	Capabilities() collector.ProcessCapabilities
+	
 	// Shutdown cleans up any resources
 	Shutdown() error
 }
@@ -50,12 +59,58 @@ func New(options map[string]interface{}) (ProcessCollector, error) {
 	}
 }
 
+// computeCPUPercent converts a process CPU time delta into a percentage of a
+// single core, given the system-wide CPU time delta across all cores over the
+// same interval. Normalized percentages are of the whole machine instead
+func computeCPUPercent(procDelta, systemDelta uint64, numCPU int, normalized bool) float64 {
+	if systemDelta == 0 {
+		return 0
+	}
+	percent := 100 * (float64(procDelta) / float64(systemDelta)) * float64(numCPU)
+	if normalized {
+		percent /= float64(numCPU)
+	}
+	return percent
+}
+
+// normalizedCPUOption reports whether the options select
+// collector.CPUReportingNormalized
+func normalizedCPUOption(options map[string]interface{}) bool {
+	mode, _ := options["cpuReportingMode"].(collector.CPUReportingMode)
+	return mode == collector.CPUReportingNormalized
+}
+
 // LinuxProcessCollector collects process information on Linux
 type LinuxProcessCollector struct {
-	procFSPath    string
-	lastCPUTimes  map[int]time.Time
-	systemCPUTime float64
-	lastUpdateTime time.Time
+	procFSPath        string
+	clockTicks        int64             // Clock ticks per second (sysconf(_SC_CLK_TCK))
+	pageSize          int64             // Memory page size in bytes
+	cpuSamples        map[int]cpuSample // Per-PID CPU times from the last GetCPUTimes
+	cpuPercent        map[int]float64   // Per-PID CPU% computed by the last GetCPUTimes
+	lastSystemJiffies uint64            // System-wide jiffies from the last GetCPUTimes
+	userNames         map[string]string // UID to username cache
+	collectFDDetails  bool              // Resolve open file targets into OpenFiles
+	maxOpenFiles      int               // Cap on OpenFiles entries per process
+	fdAccessErrors    int               // Processes whose fds could not be read in the last GetProcesses
+	collectConnections bool             // Attribute TCP and UDP sockets to processes
+	connectionsEnabled bool             // Read the socket tables on the next GetProcesses
+	connections       map[int]connectionSample // Per-PID sockets from the last collection
+	collectExeHash    bool                     // Hash process binaries into ExecutableHash
+	exeHashBytes      int64                    // Bytes hashed per binary, 0 hashes the whole file
+	exeHashes         map[executableKey]string // Hashes of the binaries seen in the last GetProcesses
+	normalizeCPU      bool                     // Report CPU% of the whole machine instead of a core
+	scanExeHashes     map[executableKey]string // Hashes of the binaries seen in the running GetProcesses
+	lastUpdateTime    time.Time
+	mu                sync.Mutex
+}
+
+// defaultMaxOpenFiles bounds OpenFiles when collectFDDetails is enabled
+const defaultMaxOpenFiles = 100
+
+// cpuSample is a single CPU time reading for a process
+type cpuSample struct {
+	procJiffies uint64 // utime + stime
+	startTicks  uint64 // Process start time, detects PID reuse
 }
 
 // NewLinuxProcessCollector creates a new Linux process collector
@@ -65,332 +120,408 @@ func NewLinuxProcessCollector(options map[string]interface{}) (*LinuxProcessColl
 		procFSPath = path
 	}
 	
+	// cpu.ClocksPerSec is resolved through sysconf(_SC_CLK_TCK)
+	clockTicks := int64(cpu.ClocksPerSec)
+	if clockTicks <= 0 {
+		clockTicks = 100 // default value
+	}
+	
+	pageSize := int64(os.Getpagesize())
+	if pageSize <= 0 {
+		pageSize = 4096 // default value
+	}
+	
+	collectFDDetails, _ := options["collectFDDetails"].(bool)
+	maxOpenFiles := defaultMaxOpenFiles
+	if max, ok := options["maxOpenFiles"].(int); ok && max > 0 {
+		maxOpenFiles = max
+	}
+	
+	collectConnections, _ := options["collectConnections"].(bool)
+	
+	collectExeHash, _ := options["collectExecutableHash"].(bool)
+	exeHashBytes := int64(defaultExecutableHashSizeKB) * 1024
+	if sizeKB, ok := options["executableHashSizeKB"].(int); ok && sizeKB >= 0 {
+		exeHashBytes = int64(sizeKB) * 1024
+	}
+	
 	return &LinuxProcessCollector{
 		procFSPath:   procFSPath,
-		lastCPUTimes: make(map[int]time.Time),
+		clockTicks:   clockTicks,
+		pageSize:     pageSize,
+		cpuSamples:   make(map[int]cpuSample),
+		cpuPercent:   make(map[int]float64),
+		userNames:    make(map[string]string),
+		collectFDDetails: collectFDDetails,
+		maxOpenFiles: maxOpenFiles,
+		collectConnections: collectConnections,
+		connectionsEnabled: true,
+		connections:  make(map[int]connectionSample),
+		collectExeHash: collectExeHash,
+		exeHashBytes: exeHashBytes,
+		exeHashes:    make(map[executableKey]string),
+		normalizeCPU: normalizedCPUOption(options),
 		lastUpdateTime: time.Now(),
 	}, nil
 }
 
-// GetProcesses returns a list of all processes on Linux
+// GetProcesses returns a list of all processes on Linux.
+// CPU usage is the percentage computed by the most recent GetCPUTimes call,
+// processes without a baseline report 0%
 func (l *LinuxProcessCollector) GetProcesses() ([]*collector.ProcessInfo, error) {
-	// In a real implementation, this would:
-	// 1. Read /proc directory
-	// 2. Parse each numeric directory (PID)
-	// 3. Extract process information from /proc/[pid]/stat, /proc/[pid]/status, etc.
-	
-	// Placeholder implementation
-	return []*collector.ProcessInfo{
-		{
-			PID:         1,
-			PPID:        0,
-			Name:        "systemd",
-			Executable:  "/usr/lib/systemd/systemd",
-			Command:     "/usr/lib/systemd/systemd --system --deserialize=31",
-			User:        "root",
-			CPU:         0.1,
-			RSS:         4 * 1024 * 1024,
-			VMS:         120 * 1024 * 1024,
-			FDs:         64,
-			Threads:     1,
-			StartTime:   time.Now().Add(-24 * time.Hour),
-			State:       "S",
-			LastUpdated: time.Now(),
-		},
-		{
-			PID:         100,
-			PPID:        1,
-			Name:        "sshd",
-			Executable:  "/usr/sbin/sshd",
-			Command:     "/usr/sbin/sshd -D",
-			User:        "root",
-			CPU:         0.2,
-			RSS:         2 * 1024 * 1024,
-			VMS:         60 * 1024 * 1024,
-			FDs:         32,
-			Threads:     1,
-			StartTime:   time.Now().Add(-12 * time.Hour),
-			State:       "S",
-			LastUpdated: time.Now(),
-		},
-	}, nil
-}
-
-// GetProcess returns detailed information about a specific process on Linux
-func (l *LinuxProcessCollector) GetProcess(pid int) (*collector.ProcessInfo, error) {
-	// Placeholder implementation
-	return &collector.ProcessInfo{
-		PID:         pid,
-		PPID:        1,
-		Name:        fmt.Sprintf("process-%d", pid),
-		Executable:  fmt.Sprintf("/usr/bin/process-%d", pid),
-		Command:     fmt.Sprintf("/usr/bin/process-%d --arg=value", pid),
-		User:        "root",
-		CPU:         0.1,
-		RSS:         2 * 1024 * 1024,
-		VMS:         60 * 1024 * 1024,
-		FDs:         32,
-		Threads:     1,
-		StartTime:   time.Now().Add(-1 * time.Hour),
-		State:       "S",
-		LastUpdated: time.Now(),
-	}, nil
-}
-
-// IsProcessRunning checks if a process is running on Linux
-func (l *LinuxProcessCollector) IsProcessRunning(pid int) bool {
-	// Placeholder implementation
-	return pid > 0 && pid < 32768
-}
-
-// GetProcessCount returns the total number of processes on Linux
-func (l *LinuxProcessCollector) GetProcessCount() (int, error) {
-	// Placeholder implementation
-	return 100, nil
-}
-
-// GetCPUTimes updates CPU times for processes on Linux
-func (l *LinuxProcessCollector) GetCPUTimes() error {
-	// Placeholder implementation
-	l.lastUpdateTime = time.Now()
-	return nil
-}
-
-// GetMemoryStats returns memory information for the Linux system
-func (l *LinuxProcessCollector) GetMemoryStats() (uint64, uint64, error) {
-	// Placeholder implementation
-	return 8 * 1024 * 1024 * 1024, 4 * 1024 * 1024 * 1024, nil
-}
-
-// GetSelfUsage returns the resource usage of the current process
-func (l *LinuxProcessCollector) GetSelfUsage() (float64, uint64, error) {
-	// Placeholder implementation
-	return 0.2, 50 * 1024 * 1024, nil
-}
-
-// Shutdown cleans up any resources
-func (l *LinuxProcessCollector) Shutdown() error {
-	return nil
-}
-
-// WindowsProcessCollector collects process information on Windows
-type WindowsProcessCollector struct {
-	lastCPUTimes  map[int]time.Time
-	systemCPUTime float64
-	lastUpdateTime time.Time
+	pids, err := listPIDs(l.procFSPath)
+	if err != nil {
+		return nil, err
+	}
+	return l.collectProcesses(pids, false)
 }
 
-// NewWindowsProcessCollector creates a new Windows process collector
-func NewWindowsProcessCollector(options map[string]interface{}) (*WindowsProcessCollector, error) {
-	return &WindowsProcessCollector{
-		lastCPUTimes: make(map[int]time.Time),
-		lastUpdateTime: time.Now(),
-	}, nil
+// ListPIDs returns the PIDs of all processes on Linux, without reading them
+func (l *LinuxProcessCollector) ListPIDs() ([]int, error) {
+	return listPIDs(l.procFSPath)
 }
 
-// GetProcesses returns a list of all processes on Windows
-func (w *WindowsProcessCollector) GetProcesses() ([]*collector.ProcessInfo, error) {
-	// Placeholder implementation
-	return []*collector.ProcessInfo{
-		{
-			PID:         4,
-			PPID:        0,
-			Name:        "System",
-			Executable:  "",
-			Command:     "",
-			User:        "SYSTEM",
-			CPU:         0.1,
-			RSS:         4 * 1024 * 1024,
-			VMS:         120 * 1024 * 1024,
-			FDs:         0,
-			Threads:     100,
-			StartTime:   time.Now().Add(-24 * time.Hour),
-			State:       "Running",
-			LastUpdated: time.Now(),
-		},
-		{
-			PID:         400,
-			PPID:        4,
-			Name:        "svchost.exe",
-			Executable:  "C:\\Windows\\System32\\svchost.exe",
-			Command:     "C:\\Windows\\System32\\svchost.exe -k LocalService",
-			User:        "SYSTEM",
-			CPU:         0.2,
-			RSS:         8 * 1024 * 1024,
-			VMS:         80 * 1024 * 1024,
-			FDs:         0,
-			Threads:     10,
-			StartTime:   time.Now().Add(-12 * time.Hour),
-			State:       "Running",
-			LastUpdated: time.Now(),
-		},
-	}, nil
+// GetProcessesByPID returns the processes with the given PIDs on Linux,
+// skipping those that exited. The connections and executable hashes of the
+// other processes are kept for later scans
+func (l *LinuxProcessCollector) GetProcessesByPID(pids []int) ([]*collector.ProcessInfo, error) {
+	return l.collectProcesses(pids, true)
 }
 
-// GetProcess returns detailed information about a specific process on Windows
-func (w *WindowsProcessCollector) GetProcess(pid int) (*collector.ProcessInfo, error) {
-	// Placeholder implementation
-	return &collector.ProcessInfo{
-		PID:         pid,
-		PPID:        4,
-		Name:        fmt.Sprintf("process-%d.exe", pid),
-		Executable:  fmt.Sprintf("C:\\Program Files\\Process\\process-%d.exe", pid),
-		Command:     fmt.Sprintf("C:\\Program Files\\Process\\process-%d.exe --arg=value", pid),
-		User:        "Administrator",
-		CPU:         0.1,
-		RSS:         4 * 1024 * 1024,
-		VMS:         60 * 1024 * 1024,
-		FDs:         0,
-		Threads:     2,
-		StartTime:   time.Now().Add(-1 * time.Hour),
-		State:       "Running",
-		LastUpdated: time.Now(),
-	}, nil
+// collectProcesses reads the processes with the given PIDs. A partial
+// collection adds to the state of the previous ones instead of replacing it
+func (l *LinuxProcessCollector) collectProcesses(pids []int, partial bool) ([]*collector.ProcessInfo, error) {
+	sys, err := readSystemCPU(l.procFSPath)
+	if err != nil {
+		return nil, err
+	}
+	
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	
+	// The socket tables are read once and attributed by inode
+	readSockets := l.collectConnections && l.connectionsEnabled
+	var sockets map[uint64]socketInfo
+	if readSockets {
+		sockets = readSocketTables(l.procFSPath)
+	}
+	connections := make(map[int]connectionSample)
+	
+	// Hashes of binaries no longer running are dropped after a full scan
+	if l.collectExeHash && !partial {
+		l.scanExeHashes = make(map[executableKey]string)
+	}
+	
+	fdAccessErrors := 0
+	processes := make([]*collector.ProcessInfo, 0, len(pids))
+	for _, pid := range pids {
+		stat, err := readProcStat(l.procFSPath, pid)
+		if err != nil {
+			// Processes the agent may not read are reported without details,
+			// others exited between listing and reading
+			if isPermissionError(err) {
+				processes = append(processes, l.partialProcessInfo(pid, err))
+			}
+			continue
+		}
+		info, fdDenied := l.buildProcessInfo(stat, sys.bootTime)
+		if fdDenied {
+			fdAccessErrors++
+		}
+		
+		// Skipped collections report the previous values
+		if l.collectConnections {
+			sample, ok := l.connections[pid]
+			if readSockets {
+				sample, ok = sampleConnections(l.procFSPath, stat, sockets), true
+			}
+			if ok && sample.startTicks == stat.startTicks {
+				connections[pid] = sample
+				info.ConnectionCount = sample.count
+				info.ListeningPorts = append([]int(nil), sample.listeningPorts...)
+			}
+		}
+		
+		processes = append(processes, info)
+	}
+	l.fdAccessErrors = fdAccessErrors
+	if l.collectConnections {
+		if partial {
+			for pid, sample := range connections {
+				l.connections[pid] = sample
+			}
+		} else {
+			l.connections = connections
+		}
+	}
+	if l.collectExeHash && !partial {
+		l.exeHashes = l.scanExeHashes
+		l.scanExeHashes = nil
+	}
+	
+	return processes, nil
 }
 
-// IsProcessRunning checks if a process is running on Windows
-func (w *WindowsProcessCollector) IsProcessRunning(pid int) bool {
-	// Placeholder implementation
-	return pid > 0 && pid < 32768
+// GetProcess returns detailed information about a specific process on Linux
+func (l *LinuxProcessCollector) GetProcess(pid int) (*collector.ProcessInfo, error) {
+	stat, err := readProcStat(l.procFSPath, pid)
+	if isPermissionError(err) {
+		return l.partialProcessInfo(pid, err), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read process %d: %w", pid, err)
+	}
+	
+	sys, err := readSystemCPU(l.procFSPath)
+	if err != nil {
+		return nil, err
+	}
+	
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	
+	info, _ := l.buildProcessInfo(stat, sys.bootTime)
+	if sample, ok := l.connections[pid]; ok && sample.startTicks == stat.startTicks {
+		info.ConnectionCount = sample.count
+		info.ListeningPorts = append([]int(nil), sample.listeningPorts...)
+	}
+	return info, nil
 }
 
-// GetProcessCount returns the total number of processes on Windows
-func (w *WindowsProcessCollector) GetProcessCount() (int, error) {
-	// Placeholder implementation
-	return 120, nil
+// FDAccessErrors returns the number of processes whose fd directory could not
+// be read during the last GetProcesses
+func (l *LinuxProcessCollector) FDAccessErrors() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	
+	return l.fdAccessErrors
 }
 
-// GetCPUTimes updates CPU times for processes on Windows
-func (w *WindowsProcessCollector) GetCPUTimes() error {
-	// Placeholder implementation
-	w.lastUpdateTime = time.Now()
-	return nil
+// SetConnectionCollection enables or disables reading the socket tables on
+// the following GetProcesses calls. While disabled, processes report the
+// connections of the last collection
+func (l *LinuxProcessCollector) SetConnectionCollection(enabled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	
+	l.connectionsEnabled = enabled
 }
 
-// GetMemoryStats returns memory information for the Windows system
-func (w *WindowsProcessCollector) GetMemoryStats() (uint64, uint64, error) {
-	// Placeholder implementation
-	return 16 * 1024 * 1024 * 1024, 8 * 1024 * 1024 * 1024, nil
+// IsProcessRunning checks if a process is running on Linux
+func (l *LinuxProcessCollector) IsProcessRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(l.procFSPath, strconv.Itoa(pid)))
+	return err == nil
 }
 
-// GetSelfUsage returns the resource usage of the current process
-func (w *WindowsProcessCollector) GetSelfUsage() (float64, uint64, error) {
-	// Placeholder implementation
-	return 0.3, 60 * 1024 * 1024, nil
+// GetProcessCount returns the total number of processes on Linux
+func (l *LinuxProcessCollector) GetProcessCount() (int, error) {
+	pids, err := listPIDs(l.procFSPath)
+	if err != nil {
+		return 0, err
+	}
+	return len(pids), nil
 }
 
-// Shutdown cleans up any resources
-func (w *WindowsProcessCollector) Shutdown() error {
+// GetCPUTimes samples per-process and system CPU times on Linux and
+// computes each process CPU% over the interval since the previous call as
+// 100 * (procDelta/systemDelta) * numCPU, or without the numCPU factor when
+// normalized
+func (l *LinuxProcessCollector) GetCPUTimes() error {
+	sys, err := readSystemCPU(l.procFSPath)
+	if err != nil {
+		return err
+	}
+	
+	pids, err := listPIDs(l.procFSPath)
+	if err != nil {
+		return err
+	}
+	
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	
+	var systemDelta uint64
+	if l.lastSystemJiffies > 0 && sys.totalJiffies > l.lastSystemJiffies {
+		systemDelta = sys.totalJiffies - l.lastSystemJiffies
+	}
+	
+	samples := make(map[int]cpuSample, len(pids))
+	percent := make(map[int]float64, len(pids))
+	for _, pid := range pids {
+		stat, err := readProcStat(l.procFSPath, pid)
+		if err != nil {
+			continue
+		}
+		
+		sample := cpuSample{
+			procJiffies: stat.utime + stat.stime,
+			startTicks:  stat.startTicks,
+		}
+		samples[pid] = sample
+		
+		// First scan, new process or reused PID: no baseline yet
+		prev, ok := l.cpuSamples[pid]
+		if !ok || prev.startTicks != sample.startTicks || systemDelta == 0 {
+			percent[pid] = 0
+			continue
+		}
+		
+		var procDelta uint64
+		if sample.procJiffies > prev.procJiffies {
+			procDelta = sample.procJiffies - prev.procJiffies
+		}
+		percent[pid] = computeCPUPercent(procDelta, systemDelta, sys.numCPU, l.normalizeCPU)
+	}
+	
+	// Replacing the maps drops baselines of exited processes
+	l.cpuSamples = samples
+	l.cpuPercent = percent
+	l.lastSystemJiffies = sys.totalJiffies
+	l.lastUpdateTime = time.Now()
 	return nil
 }
 
-// DarwinProcessCollector collects process information on macOS
-type DarwinProcessCollector struct {
-	lastCPUTimes  map[int]time.Time
-	systemCPUTime float64
-	lastUpdateTime time.Time
-}
-
-// NewDarwinProcessCollector creates a new macOS process collector
-func NewDarwinProcessCollector(options map[string]interface{}) (*DarwinProcessCollector, error) {
-	return &DarwinProcessCollector{
-		lastCPUTimes: make(map[int]time.Time),
-		lastUpdateTime: time.Now(),
-	}, nil
-}
-
-// GetProcesses returns a list of all processes on macOS
-func (d *DarwinProcessCollector) GetProcesses() ([]*collector.ProcessInfo, error) {
-	// Placeholder implementation
-	return []*collector.ProcessInfo{
-		{
-			PID:         1,
-			PPID:        0,
-			Name:        "launchd",
-			Executable:  "/sbin/launchd",
-			Command:     "/sbin/launchd",
-			User:        "root",
-			CPU:         0.1,
-			RSS:         4 * 1024 * 1024,
-			VMS:         120 * 1024 * 1024,
-			FDs:         100,
-			Threads:     5,
-			StartTime:   time.Now().Add(-24 * time.Hour),
-			State:       "S",
-			LastUpdated: time.Now(),
-		},
-		{
-			PID:         200,
-			PPID:        1,
-			Name:        "Finder",
-			Executable:  "/System/Library/CoreServices/Finder.app/Contents/MacOS/Finder",
-			Command:     "/System/Library/CoreServices/Finder.app/Contents/MacOS/Finder",
-			User:        "user",
-			CPU:         0.5,
-			RSS:         80 * 1024 * 1024,
-			VMS:         200 * 1024 * 1024,
-			FDs:         50,
-			Threads:     8,
-			StartTime:   time.Now().Add(-12 * time.Hour),
-			State:       "S",
-			LastUpdated: time.Now(),
-		},
-	}, nil
+// buildProcessInfo converts a parsed stat into a ProcessInfo and reports
+// whether the fd directory was not accessible. Caller must hold the mutex
+func (l *LinuxProcessCollector) buildProcessInfo(stat *procStat, bootTime time.Time) (*collector.ProcessInfo, bool) {
+	startTime := bootTime.Add(time.Duration(stat.startTicks) * time.Second / time.Duration(l.clockTicks))
+	
+	// Only report CPU% when the sample belongs to this process instance
+	var cpuPct float64
+	if sample, ok := l.cpuSamples[stat.pid]; ok && sample.startTicks == stat.startTicks {
+		cpuPct = l.cpuPercent[stat.pid]
+	}
+	
+	info := &collector.ProcessInfo{
+		PID:         stat.pid,
+		PPID:        stat.ppid,
+		Name:        stat.comm,
+		Executable:  readExecutable(l.procFSPath, stat.pid),
+		Command:     readCmdline(l.procFSPath, stat.pid),
+		User:        l.lookupUser(stat.pid),
+		CPU:         cpuPct,
+		RSS:         stat.rssPages * l.pageSize,
+		VMS:         int64(stat.vsize),
+		Threads:     stat.numThreads,
+		StartTime:   startTime,
+		State:       stat.state,
+		LastUpdated: time.Now(),
+	}
+	
+	// Unreadable fd directories (EACCES) leave the count at 0
+	maxDetails := 0
+	if l.collectFDDetails {
+		maxDetails = l.maxOpenFiles
+	}
+	fdDenied := false
+	if fds, files, err := readFDs(l.procFSPath, stat.pid, maxDetails); err == nil {
+		info.FDs = fds
+		info.OpenFiles = files
+	} else if isPermissionError(err) {
+		fdDenied = true
+	}
+	
+	if l.collectExeHash && info.Executable != "" {
+		info.ExecutableHash = l.executableHash(stat.pid, info.Executable)
+	}
+	
+	// Container attribution, the cgroup file may be missing on old kernels
+	if cgroup, err := readCgroup(l.procFSPath, stat.pid); err == nil {
+		info.CgroupPath = cgroup.path
+		info.ContainerID = cgroup.containerID
+		if cgroup.containerID != "" {
+			info.Labels = map[string]string{"container_id": cgroup.containerID}
+		}
+	}
+	
+	return info, fdDenied
 }
 
-// GetProcess returns detailed information about a specific process on macOS
-func (d *DarwinProcessCollector) GetProcess(pid int) (*collector.ProcessInfo, error) {
-	// Placeholder implementation
+// partialProcessInfo returns a process whose stat could not be read, with
+// the name if comm is readable
+func (l *LinuxProcessCollector) partialProcessInfo(pid int, err error) *collector.ProcessInfo {
 	return &collector.ProcessInfo{
 		PID:         pid,
-		PPID:        1,
-		Name:        fmt.Sprintf("process-%d", pid),
-		Executable:  fmt.Sprintf("/Applications/Process-%d.app/Contents/MacOS/Process-%d", pid, pid),
-		Command:     fmt.Sprintf("/Applications/Process-%d.app/Contents/MacOS/Process-%d --arg=value", pid, pid),
-		User:        "user",
-		CPU:         0.2,
-		RSS:         40 * 1024 * 1024,
-		VMS:         120 * 1024 * 1024,
-		FDs:         30,
-		Threads:     3,
-		StartTime:   time.Now().Add(-1 * time.Hour),
-		State:       "S",
+		Name:        readComm(l.procFSPath, pid),
 		LastUpdated: time.Now(),
-	}, nil
+		Partial:     true,
+		AccessError: err.Error(),
+	}
 }
 
-// IsProcessRunning checks if a process is running on macOS
-func (d *DarwinProcessCollector) IsProcessRunning(pid int) bool {
-	// Placeholder implementation
-	return pid > 0 && pid < 32768
+// executableHash returns the hash of a process binary, reusing the hash of
+// an unchanged binary. Caller must hold the mutex
+func (l *LinuxProcessCollector) executableHash(pid int, path string) string {
+	key, err := statExecutable(l.procFSPath, pid, path)
+	if err != nil {
+		return ""
+	}
+	
+	hash, ok := l.exeHashes[key]
+	if !ok {
+		if hash, err = hashExecutable(l.procFSPath, pid, l.exeHashBytes); err != nil {
+			return ""
+		}
+	}
+	
+	if l.scanExeHashes != nil {
+		l.scanExeHashes[key] = hash
+	} else {
+		l.exeHashes[key] = hash
+	}
+	return hash
 }
 
-// GetProcessCount returns the total number of processes on macOS
-func (d *DarwinProcessCollector) GetProcessCount() (int, error) {
-	// Placeholder implementation
-	return 150, nil
+// lookupUser resolves the owner of a process, caching UID lookups. Caller must hold the mutex
+func (l *LinuxProcessCollector) lookupUser(pid int) string {
+	uid, err := readUID(l.procFSPath, pid)
+	if err != nil {
+		return ""
+	}
+	
+	if name, ok := l.userNames[uid]; ok {
+		return name
+	}
+	
+	name := uid
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	l.userNames[uid] = name
+	return name
 }
 
-// GetCPUTimes updates CPU times for processes on macOS
-func (d *DarwinProcessCollector) GetCPUTimes() error {
+// GetMemoryStats returns memory information for the Linux system
+func (l *LinuxProcessCollector) GetMemoryStats() (uint64, uint64, error) {
 	// Placeholder implementation
-	d.lastUpdateTime = time.Now()
-	return nil
+	return 8 * 1024 * 1024 * 1024, 4 * 1024 * 1024 * 1024, nil
 }
 
-// GetMemoryStats returns memory information for the macOS system
-func (d *DarwinProcessCollector) GetMemoryStats() (uint64, uint64, error) {
+// GetSelfUsage returns the resource usage of the current process
+func (l *LinuxProcessCollector) GetSelfUsage() (float64, uint64, error) {
 	// Placeholder implementation
-	return 16 * 1024 * 1024 * 1024, 8 * 1024 * 1024 * 1024, nil
+	return 0.2, 50 * 1024 * 1024, nil
 }
 
-// GetSelfUsage returns the resource usage of the current process
-func (d *DarwinProcessCollector) GetSelfUsage() (float64, uint64, error) {
-	// Placeholder implementation
-	return 0.3, 60 * 1024 * 1024, nil
+// Capabilities returns the process fields collected on Linux. IO counters
+// are not read from /proc/<pid>/io, connections only when collectConnections
+// is enabled
+func (l *LinuxProcessCollector) Capabilities() collector.ProcessCapabilities {
+	return collector.ProcessCapabilities{
+		CPUPercent:  true,
+		RSS:         true,
+		VMS:         true,
+		FDs:         true,
+		Threads:     true,
+		Connections: l.collectConnections,
+		Cgroup:      true,
+	}
 }
 
 // Shutdown cleans up any resources
-func (d *DarwinProcessCollector) Shutdown() error {
+func (l *LinuxProcessCollector) Shutdown() error {
 	return nil
 }
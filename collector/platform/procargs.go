@@ -0,0 +1,62 @@
+package platform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// parseProcArgs extracts the executable path and command line from the
+// KERN_PROCARGS2 sysctl of a macOS process. The buffer starts with argc as a
+// little-endian int32, the byte order of all macOS architectures, followed
+// by the executable path, NUL padding, the argc arguments and the
+// environment, all NUL terminated
+func parseProcArgs(data []byte) (string, string, error) {
+	if len(data) < 4 {
+		return "", "", fmt.Errorf("procargs too short: %d bytes", len(data))
+	}
+	argc := int(int32(binary.LittleEndian.Uint32(data[:4])))
+	data = data[4:]
+
+	end := bytes.IndexByte(data, 0)
+	if end < 0 {
+		return "", "", fmt.Errorf("procargs without executable path")
+	}
+	executable := string(data[:end])
+	data = bytes.TrimLeft(data[end:], "\x00")
+
+	args := make([]string, 0, argc)
+	for len(args) < argc && len(data) > 0 {
+		end = bytes.IndexByte(data, 0)
+		if end < 0 {
+			end = len(data)
+		}
+		args = append(args, string(data[:end]))
+		if end == len(data) {
+			break
+		}
+		data = data[end+1:]
+	}
+
+	return executable, strings.Join(args, " "), nil
+}
+
+// darwinState maps a BSD process status (p_stat) to the single letter
+// states reported on Linux
+func darwinState(status uint32) string {
+	switch status {
+	case 1: // SIDL, being created
+		return "I"
+	case 2: // SRUN
+		return "R"
+	case 3: // SSLEEP
+		return "S"
+	case 4: // SSTOP
+		return "T"
+	case 5: // SZOMB
+		return "Z"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,85 @@
+package platform
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// procArgs builds a KERN_PROCARGS2 buffer
+func procArgs(argc int32, parts ...string) []byte {
+	data := binary.LittleEndian.AppendUint32(nil, uint32(argc))
+	for _, part := range parts {
+		data = append(data, part...)
+		data = append(data, 0)
+	}
+	return data
+}
+
+func TestParseProcArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		executable string
+		command    string
+		wantErr    bool
+	}{
+		{
+			name:       "arguments and environment",
+			data:       procArgs(3, "/usr/bin/server\x00\x00\x00", "server", "--port", "8080", "HOME=/var/root"),
+			executable: "/usr/bin/server",
+			command:    "server --port 8080",
+		},
+		{
+			name:       "no arguments",
+			data:       procArgs(0, "/sbin/launchd", "PATH=/usr/bin"),
+			executable: "/sbin/launchd",
+			command:    "",
+		},
+		{
+			name:       "truncated arguments",
+			data:       append(procArgs(3, "/bin/sleep", "sleep"), "10"...),
+			executable: "/bin/sleep",
+			command:    "sleep 10",
+		},
+		{
+			name:    "too short",
+			data:    []byte{1, 0},
+			wantErr: true,
+		},
+		{
+			name:    "no executable terminator",
+			data:    append(binary.LittleEndian.AppendUint32(nil, 1), "/bin/sh"...),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executable, command, err := parseProcArgs(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %q and %q", executable, command)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseProcArgs returned error: %v", err)
+			}
+			if executable != tt.executable {
+				t.Errorf("Expected executable %q, got %q", tt.executable, executable)
+			}
+			if command != tt.command {
+				t.Errorf("Expected command %q, got %q", tt.command, command)
+			}
+		})
+	}
+}
+
+func TestDarwinState(t *testing.T) {
+	expected := map[uint32]string{1: "I", 2: "R", 3: "S", 4: "T", 5: "Z", 9: ""}
+	for status, state := range expected {
+		if got := darwinState(status); got != state {
+			t.Errorf("Expected state %q for status %d, got %q", state, status, got)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package platform
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// procStat holds the fields of /proc/<pid>/stat used by the collector
+type procStat struct {
+	pid        int
+	comm       string
+	state      string
+	ppid       int
+	utime      uint64 // User mode jiffies
+	stime      uint64 // Kernel mode jiffies
+	numThreads int
+	startTicks uint64 // Start time in clock ticks since boot
+	vsize      uint64 // Virtual memory size in bytes
+	rssPages   int64  // Resident set size in pages
+}
+
+// systemCPU holds the system-wide CPU counters of /proc/stat
+type systemCPU struct {
+	totalJiffies uint64    // Sum of all CPU time across all cores
+	numCPU       int       // Number of cpuN lines
+	bootTime     time.Time // System boot time from btime
+}
+
+// listPIDs returns the PIDs of all processes in procfs
+func listPIDs(procFSPath string) ([]int, error) {
+	entries, err := os.ReadDir(procFSPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", procFSPath, err)
+	}
+
+	pids := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid <= 0 {
+			continue
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
+
+// readProcStat reads and parses /proc/<pid>/stat
+func readProcStat(procFSPath string, pid int) (*procStat, error) {
+	data, err := os.ReadFile(filepath.Join(procFSPath, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return nil, err
+	}
+	return parseProcStat(pid, data)
+}
+
+// parseProcStat parses the content of /proc/<pid>/stat.
+// The comm field is wrapped in parentheses and may itself contain spaces or
+// parentheses, so fields are split after the last closing parenthesis
+func parseProcStat(pid int, data []byte) (*procStat, error) {
+	start := bytes.IndexByte(data, '(')
+	end := bytes.LastIndexByte(data, ')')
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("malformed stat for pid %d", pid)
+	}
+
+	// Fields after comm, starting at field 3 (state)
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("malformed stat for pid %d: %d fields", pid, len(fields))
+	}
+
+	stat := &procStat{
+		pid:   pid,
+		comm:  string(data[start+1 : end]),
+		state: fields[0],
+	}
+
+	// Field numbers below follow proc(5), offset by the 3 leading fields
+	var errs [7]error
+	stat.ppid, errs[0] = strconv.Atoi(fields[1])
+	stat.utime, errs[1] = strconv.ParseUint(fields[11], 10, 64)
+	stat.stime, errs[2] = strconv.ParseUint(fields[12], 10, 64)
+	stat.numThreads, errs[3] = strconv.Atoi(fields[17])
+	stat.startTicks, errs[4] = strconv.ParseUint(fields[19], 10, 64)
+	stat.vsize, errs[5] = strconv.ParseUint(fields[20], 10, 64)
+	stat.rssPages, errs[6] = strconv.ParseInt(fields[21], 10, 64)
+	for _, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("malformed stat for pid %d: %w", pid, err)
+		}
+	}
+
+	return stat, nil
+}
+
+// readSystemCPU reads the aggregate CPU jiffies, core count and boot time
+// from /proc/stat
+func readSystemCPU(procFSPath string) (*systemCPU, error) {
+	file, err := os.Open(filepath.Join(procFSPath, "stat"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read system stat: %w", err)
+	}
+	defer file.Close()
+
+	sys := &systemCPU{}
+	foundTotal := false
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case fields[0] == "cpu":
+			// user nice system idle iowait irq softirq steal guest guest_nice.
+			// guest and guest_nice are already accounted in user and nice
+			for i, field := range fields[1:] {
+				if i >= 8 {
+					break
+				}
+				v, err := strconv.ParseUint(field, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("malformed cpu line in system stat: %w", err)
+				}
+				sys.totalJiffies += v
+			}
+			foundTotal = true
+		case strings.HasPrefix(fields[0], "cpu"):
+			sys.numCPU++
+		case fields[0] == "btime" && len(fields) > 1:
+			btime, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed btime in system stat: %w", err)
+			}
+			sys.bootTime = time.Unix(btime, 0)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read system stat: %w", err)
+	}
+
+	if !foundTotal {
+		return nil, fmt.Errorf("missing cpu line in system stat")
+	}
+	if sys.numCPU == 0 {
+		sys.numCPU = 1
+	}
+
+	return sys, nil
+}
+
+// readCmdline returns the command line of a process with arguments
+// separated by spaces
+func readCmdline(procFSPath string, pid int) string {
+	data, err := os.ReadFile(filepath.Join(procFSPath, strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return ""
+	}
+	data = bytes.TrimRight(data, "\x00")
+	return string(bytes.ReplaceAll(data, []byte{0}, []byte{' '}))
+}
+
+// readComm returns the process name from /proc/<pid>/comm
+func readComm(procFSPath string, pid int) string {
+	data, err := os.ReadFile(filepath.Join(procFSPath, strconv.Itoa(pid), "comm"))
+	if err != nil {
+		return ""
+	}
+	return string(bytes.TrimRight(data, "\n"))
+}
+
+// readExecutable returns the resolved path of the process executable
+func readExecutable(procFSPath string, pid int) string {
+	exe, err := os.Readlink(filepath.Join(procFSPath, strconv.Itoa(pid), "exe"))
+	if err != nil {
+		return ""
+	}
+	return exe
+}
+
+// readUID returns the real user ID of a process from /proc/<pid>/status
+func readUID(procFSPath string, pid int) (string, error) {
+	file, err := os.Open(filepath.Join(procFSPath, strconv.Itoa(pid), "status"))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Uid:") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			break
+		}
+		return fields[1], nil
+	}
+
+	return "", fmt.Errorf("missing Uid in status for pid %d", pid)
+}
+
+// readFDs counts the open file descriptors of a process and resolves the
+// targets of up to maxDetails of them, in directory order
+func readFDs(procFSPath string, pid int, maxDetails int) (int, []string, error) {
+	fdDir := filepath.Join(procFSPath, strconv.Itoa(pid), "fd")
+	dir, err := os.Open(fdDir)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer dir.Close()
+
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if maxDetails <= 0 {
+		return len(names), nil, nil
+	}
+
+	if len(names) < maxDetails {
+		maxDetails = len(names)
+	}
+	files := make([]string, 0, maxDetails)
+	for _, name := range names[:maxDetails] {
+		target, err := os.Readlink(filepath.Join(fdDir, name))
+		if err != nil {
+			// Descriptor closed since listing
+			continue
+		}
+		files = append(files, target)
+	}
+
+	return len(names), files, nil
+}
+
+// isPermissionError reports whether err is caused by missing privileges
+func isPermissionError(err error) bool {
+	return errors.Is(err, fs.ErrPermission)
+}
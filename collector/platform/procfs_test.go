@@ -0,0 +1,399 @@
+package platform
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/newrelic/infrastructure-agent/collector"
+)
+
+// writeProcFile writes a file into a fixture proc tree
+func writeProcFile(t *testing.T, root string, rel string, content string) {
+	t.Helper()
+	path := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// writeSystemStat writes a /proc/stat with the given total jiffies spread over numCPU cores
+func writeSystemStat(t *testing.T, root string, totalJiffies uint64, numCPU int) {
+	content := fmt.Sprintf("cpu  %d 0 0 0 0 0 0 0 0 0\n", totalJiffies)
+	for i := 0; i < numCPU; i++ {
+		content += fmt.Sprintf("cpu%d %d 0 0 0 0 0 0 0 0 0\n", i, totalJiffies/uint64(numCPU))
+	}
+	content += "btime 1700000000\n"
+	writeProcFile(t, root, "stat", content)
+}
+
+// writePidStat writes a /proc/<pid>/stat with the given CPU and start times
+func writePidStat(t *testing.T, root string, pid int, comm string, utime, stime, startTicks uint64) {
+	content := fmt.Sprintf("%d (%s) S 1 %d %d 0 -1 4194304 100 0 0 0 %d %d 0 0 20 0 3 0 %d 1048576 256 18446744073709551615\n",
+		pid, comm, pid, pid, utime, stime, startTicks)
+	writeProcFile(t, root, filepath.Join(strconv.Itoa(pid), "stat"), content)
+}
+
+func TestParseProcStat(t *testing.T) {
+	data := []byte("42 (weird) (name) R 7 42 42 0 -1 4194304 100 0 0 0 150 50 0 0 20 0 4 0 12345 2097152 512 18446744073709551615\n")
+
+	stat, err := parseProcStat(42, data)
+	if err != nil {
+		t.Fatalf("parseProcStat returned error: %v", err)
+	}
+
+	if stat.comm != "weird) (name" {
+		t.Errorf("Expected comm 'weird) (name', got %q", stat.comm)
+	}
+	if stat.state != "R" || stat.ppid != 7 {
+		t.Errorf("Expected state R and ppid 7, got %s and %d", stat.state, stat.ppid)
+	}
+	if stat.utime != 150 || stat.stime != 50 {
+		t.Errorf("Expected utime 150 and stime 50, got %d and %d", stat.utime, stat.stime)
+	}
+	if stat.numThreads != 4 || stat.startTicks != 12345 {
+		t.Errorf("Expected 4 threads and start 12345, got %d and %d", stat.numThreads, stat.startTicks)
+	}
+	if stat.vsize != 2097152 || stat.rssPages != 512 {
+		t.Errorf("Expected vsize 2097152 and rss 512, got %d and %d", stat.vsize, stat.rssPages)
+	}
+
+	// Malformed input
+	if _, err := parseProcStat(42, []byte("42 no-parens R")); err == nil {
+		t.Errorf("Expected error for malformed stat")
+	}
+}
+
+func TestLinuxProcessCollector_CPUPercent(t *testing.T) {
+	root := t.TempDir()
+	writeSystemStat(t, root, 10000, 4)
+	writePidStat(t, root, 100, "busy", 100, 100, 500)
+	writePidStat(t, root, 200, "idle", 10, 10, 600)
+
+	l, err := NewLinuxProcessCollector(map[string]interface{}{"procFSPath": root})
+	if err != nil {
+		t.Fatalf("NewLinuxProcessCollector returned error: %v", err)
+	}
+
+	// First scan has no baseline
+	if err := l.GetCPUTimes(); err != nil {
+		t.Fatalf("GetCPUTimes returned error: %v", err)
+	}
+	processes, err := l.GetProcesses()
+	if err != nil {
+		t.Fatalf("GetProcesses returned error: %v", err)
+	}
+	if len(processes) != 2 {
+		t.Fatalf("Expected 2 processes, got %d", len(processes))
+	}
+	for _, p := range processes {
+		if p.CPU != 0 {
+			t.Errorf("Expected 0%% CPU on first scan for pid %d, got %f", p.PID, p.CPU)
+		}
+	}
+
+	// System advances 1000 jiffies over 4 cores: busy uses 500, idle uses 0
+	writeSystemStat(t, root, 11000, 4)
+	writePidStat(t, root, 100, "busy", 350, 350, 500)
+
+	// PID 200 is reused by a new process
+	writePidStat(t, root, 200, "reused", 50, 50, 900)
+
+	if err := l.GetCPUTimes(); err != nil {
+		t.Fatalf("GetCPUTimes returned error: %v", err)
+	}
+	processes, _ = l.GetProcesses()
+
+	expected := map[int]float64{
+		100: 100 * (500.0 / 1000.0) * 4, // two of four cores
+		200: 0,                          // baseline reset by PID reuse
+	}
+	for _, p := range processes {
+		if math.Abs(p.CPU-expected[p.PID]) > 1e-9 {
+			t.Errorf("Expected %f%% CPU for pid %d, got %f", expected[p.PID], p.PID, p.CPU)
+		}
+	}
+}
+
+func TestLinuxProcessCollector_GetProcessesByPID(t *testing.T) {
+	root := t.TempDir()
+	writeSystemStat(t, root, 10000, 4)
+	for _, pid := range []int{100, 200, 300} {
+		writePidStat(t, root, pid, fmt.Sprintf("proc%d", pid), 10, 10, uint64(pid))
+	}
+
+	l, err := NewLinuxProcessCollector(map[string]interface{}{"procFSPath": root})
+	if err != nil {
+		t.Fatalf("NewLinuxProcessCollector returned error: %v", err)
+	}
+
+	pids, err := l.ListPIDs()
+	if err != nil {
+		t.Fatalf("ListPIDs returned error: %v", err)
+	}
+	if len(pids) != 3 {
+		t.Fatalf("Expected 3 PIDs, got %v", pids)
+	}
+
+	// Exited processes are skipped
+	processes, err := l.GetProcessesByPID([]int{300, 100, 400})
+	if err != nil {
+		t.Fatalf("GetProcessesByPID returned error: %v", err)
+	}
+	if len(processes) != 2 || processes[0].PID != 300 || processes[1].PID != 100 {
+		t.Fatalf("Expected processes 300 and 100, got %d processes", len(processes))
+	}
+}
+
+func TestLinuxProcessCollector_CPUReportingMode(t *testing.T) {
+	tests := []struct {
+		mode     collector.CPUReportingMode
+		expected float64
+	}{
+		{collector.CPUReportingPerCore, 200},
+		{collector.CPUReportingNormalized, 25},
+		{"", 200},
+	}
+
+	for _, tt := range tests {
+		root := t.TempDir()
+		writeSystemStat(t, root, 80000, 8)
+		writePidStat(t, root, 100, "busy", 1000, 1000, 500)
+
+		l, err := NewLinuxProcessCollector(map[string]interface{}{
+			"procFSPath":       root,
+			"cpuReportingMode": tt.mode,
+		})
+		if err != nil {
+			t.Fatalf("NewLinuxProcessCollector returned error: %v", err)
+		}
+		if err := l.GetCPUTimes(); err != nil {
+			t.Fatalf("GetCPUTimes returned error: %v", err)
+		}
+
+		// 8000 jiffies over 8 cores, the process uses 2000: two full cores
+		writeSystemStat(t, root, 88000, 8)
+		writePidStat(t, root, 100, "busy", 2000, 2000, 500)
+		if err := l.GetCPUTimes(); err != nil {
+			t.Fatalf("GetCPUTimes returned error: %v", err)
+		}
+
+		processes, err := l.GetProcesses()
+		if err != nil || len(processes) != 1 {
+			t.Fatalf("Expected 1 process, got %d (%v)", len(processes), err)
+		}
+		if math.Abs(processes[0].CPU-tt.expected) > 1e-9 {
+			t.Errorf("Mode %q: expected %f%% CPU, got %f", tt.mode, tt.expected, processes[0].CPU)
+		}
+	}
+}
+
+func TestLinuxProcessCollector_ProcessInfo(t *testing.T) {
+	root := t.TempDir()
+	writeSystemStat(t, root, 10000, 1)
+	writePidStat(t, root, 300, "server", 0, 0, 200)
+	writeProcFile(t, root, "300/cmdline", "/usr/bin/server\x00--port\x008080\x00")
+	writeProcFile(t, root, "300/status", "Name:\tserver\nUid:\t0\t0\t0\t0\n")
+
+	l, _ := NewLinuxProcessCollector(map[string]interface{}{"procFSPath": root})
+
+	p, err := l.GetProcess(300)
+	if err != nil {
+		t.Fatalf("GetProcess returned error: %v", err)
+	}
+
+	if p.Name != "server" || p.PPID != 1 || p.Threads != 3 {
+		t.Errorf("Unexpected process info: %+v", p)
+	}
+	if p.Command != "/usr/bin/server --port 8080" {
+		t.Errorf("Expected command '/usr/bin/server --port 8080', got %q", p.Command)
+	}
+	if p.User == "" {
+		t.Errorf("Expected user to be resolved")
+	}
+	if p.RSS != 256*l.pageSize {
+		t.Errorf("Expected RSS %d, got %d", 256*l.pageSize, p.RSS)
+	}
+
+	expectedStart := int64(1700000000) + 200/l.clockTicks
+	if p.StartTime.Unix() != expectedStart {
+		t.Errorf("Expected start time %d, got %d", expectedStart, p.StartTime.Unix())
+	}
+
+	if !l.IsProcessRunning(300) || l.IsProcessRunning(301) {
+		t.Errorf("IsProcessRunning returned unexpected result")
+	}
+
+	count, _ := l.GetProcessCount()
+	if count != 1 {
+		t.Errorf("Expected process count 1, got %d", count)
+	}
+
+	if _, err := l.GetProcess(999); err == nil {
+		t.Errorf("Expected error for missing process")
+	}
+}
+
+func TestLinuxProcessCollector_FileDescriptors(t *testing.T) {
+	root := t.TempDir()
+	writeSystemStat(t, root, 10000, 1)
+	writePidStat(t, root, 500, "server", 0, 0, 100)
+	fdDir := filepath.Join(root, "500", "fd")
+	if err := os.MkdirAll(fdDir, 0o755); err != nil {
+		t.Fatalf("Failed to create %s: %v", fdDir, err)
+	}
+	targets := []string{"/dev/null", "/var/log/server.log", "socket:[12345]"}
+	for i, target := range targets {
+		if err := os.Symlink(target, filepath.Join(fdDir, strconv.Itoa(i))); err != nil {
+			t.Fatalf("Failed to create fd symlink: %v", err)
+		}
+	}
+
+	l, _ := NewLinuxProcessCollector(map[string]interface{}{"procFSPath": root})
+	p, err := l.GetProcess(500)
+	if err != nil {
+		t.Fatalf("GetProcess returned error: %v", err)
+	}
+	if p.FDs != 3 {
+		t.Errorf("Expected 3 fds, got %d", p.FDs)
+	}
+	if p.OpenFiles != nil {
+		t.Errorf("Expected no open files without fd details, got %v", p.OpenFiles)
+	}
+
+	// Details are capped at maxOpenFiles
+	l, _ = NewLinuxProcessCollector(map[string]interface{}{
+		"procFSPath":       root,
+		"collectFDDetails": true,
+		"maxOpenFiles":     2,
+	})
+	p, err = l.GetProcess(500)
+	if err != nil {
+		t.Fatalf("GetProcess returned error: %v", err)
+	}
+	if p.FDs != 3 {
+		t.Errorf("Expected 3 fds, got %d", p.FDs)
+	}
+	if len(p.OpenFiles) != 2 {
+		t.Fatalf("Expected 2 open files, got %v", p.OpenFiles)
+	}
+	for _, file := range p.OpenFiles {
+		found := false
+		for _, target := range targets {
+			if file == target {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Unexpected open file %q", file)
+		}
+	}
+
+	// Root bypasses directory permissions
+	if os.Geteuid() == 0 {
+		t.Skip("Skipping permission check when running as root")
+	}
+
+	if err := os.Chmod(fdDir, 0o000); err != nil {
+		t.Fatalf("Failed to chmod %s: %v", fdDir, err)
+	}
+	defer os.Chmod(fdDir, 0o755)
+
+	processes, err := l.GetProcesses()
+	if err != nil {
+		t.Fatalf("GetProcesses returned error: %v", err)
+	}
+	if len(processes) != 1 || processes[0].FDs != 0 || processes[0].OpenFiles != nil {
+		t.Errorf("Expected process with 0 fds, got %+v", processes)
+	}
+	if l.FDAccessErrors() != 1 {
+		t.Errorf("Expected 1 fd access error, got %d", l.FDAccessErrors())
+	}
+}
+
+func TestLinuxProcessCollector_PartialProcesses(t *testing.T) {
+	// Root bypasses file permissions
+	if os.Geteuid() == 0 {
+		t.Skip("Skipping permission check when running as root")
+	}
+
+	root := t.TempDir()
+	writeSystemStat(t, root, 10000, 1)
+	writePidStat(t, root, 100, "readable", 0, 0, 100)
+	writePidStat(t, root, 200, "hidden", 0, 0, 200)
+	writeProcFile(t, root, "200/comm", "hidden\n")
+	writeProcFile(t, root, "300/comm", "exited\n")
+	stat := filepath.Join(root, "200", "stat")
+	if err := os.Chmod(stat, 0o000); err != nil {
+		t.Fatalf("Failed to chmod %s: %v", stat, err)
+	}
+	defer os.Chmod(stat, 0o644)
+
+	l, _ := NewLinuxProcessCollector(map[string]interface{}{"procFSPath": root})
+	processes, err := l.GetProcesses()
+	if err != nil {
+		t.Fatalf("GetProcesses returned error: %v", err)
+	}
+
+	// The process without stat exited and is dropped
+	byPID := make(map[int]*collector.ProcessInfo)
+	for _, p := range processes {
+		byPID[p.PID] = p
+	}
+	if len(processes) != 2 || byPID[100] == nil || byPID[200] == nil {
+		t.Fatalf("Expected PIDs 100 and 200, got %+v", processes)
+	}
+	if byPID[100].Partial || byPID[100].Name != "readable" {
+		t.Errorf("Expected a complete process 100, got %+v", byPID[100])
+	}
+
+	hidden := byPID[200]
+	if !hidden.Partial || hidden.Name != "hidden" || hidden.AccessError == "" {
+		t.Errorf("Expected a partial process 200 with name and access error, got %+v", hidden)
+	}
+	if hidden.State != "" || hidden.Threads != 0 || !hidden.StartTime.IsZero() {
+		t.Errorf("Expected no details for partial process 200, got %+v", hidden)
+	}
+
+	p, err := l.GetProcess(200)
+	if err != nil {
+		t.Fatalf("GetProcess returned error: %v", err)
+	}
+	if !p.Partial || p.Name != "hidden" {
+		t.Errorf("Expected partial process 200, got %+v", p)
+	}
+	if _, err := l.GetProcess(300); err == nil {
+		t.Errorf("Expected an error for an exited process")
+	}
+}
+
+func TestLinuxProcessCollector_Capabilities(t *testing.T) {
+	l, _ := NewLinuxProcessCollector(map[string]interface{}{"procFSPath": t.TempDir()})
+	want := collector.ProcessCapabilities{
+		CPUPercent: true,
+		RSS:        true,
+		VMS:        true,
+		FDs:        true,
+		Threads:    true,
+		Cgroup:     true,
+	}
+	if caps := l.Capabilities(); caps != want {
+		t.Errorf("Expected capabilities %+v, got %+v", want, caps)
+	}
+
+	// Connections are only reported when collected
+	l, _ = NewLinuxProcessCollector(map[string]interface{}{
+		"procFSPath":         t.TempDir(),
+		"collectConnections": true,
+	})
+	want.Connections = true
+	if caps := l.Capabilities(); caps != want {
+		t.Errorf("Expected capabilities %+v, got %+v", want, caps)
+	}
+}
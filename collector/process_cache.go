@@ -0,0 +1,79 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// cacheSnapshot is the process cache as written by SaveCache
+type cacheSnapshot struct {
+	// SavedAt is when the cache was saved
+	SavedAt time.Time `json:"savedAt"`
+
+	// Processes are the cached processes, including terminated ones still
+	// in their grace period
+	Processes []*ProcessInfo `json:"processes"`
+}
+
+// SaveCache writes the process cache as JSON, stamped with the current
+// time, so a restarted scanner can reload it with LoadCache
+func (p *ProcessScanner) SaveCache(w io.Writer) error {
+	p.cacheMutex.RLock()
+	snapshot := cacheSnapshot{
+		SavedAt:   time.Now(),
+		Processes: make([]*ProcessInfo, 0, len(p.processCache)),
+	}
+	for _, proc := range p.processCache {
+		snapshot.Processes = append(snapshot.Processes, proc.Clone())
+	}
+	p.cacheMutex.RUnlock()
+
+	sortByPID(snapshot.Processes)
+	if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+		return fmt.Errorf("failed to save process cache: %w", err)
+	}
+	return nil
+}
+
+// LoadCache replaces the process cache with one written by SaveCache, so
+// the next scan only reports the processes created, updated and terminated
+// since it was saved. It must be called before Start, and leaves the cache
+// unchanged if the saved cache is older than CacheMaxAge
+func (p *ProcessScanner) LoadCache(r io.Reader) error {
+	p.scannerMutex.RLock()
+	defer p.scannerMutex.RUnlock()
+
+	if p.status == StatusRunning || p.status == StatusPaused {
+		return fmt.Errorf("cannot load process cache while scanner is %s", p.status)
+	}
+
+	var snapshot cacheSnapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return fmt.Errorf("failed to load process cache: %w", err)
+	}
+
+	age := time.Since(snapshot.SavedAt)
+	if p.config.CacheMaxAge > 0 && age > p.config.CacheMaxAge {
+		return fmt.Errorf("process cache saved %s ago is older than %s", age.Round(time.Second), p.config.CacheMaxAge)
+	}
+
+	p.cacheMutex.Lock()
+	defer p.cacheMutex.Unlock()
+
+	p.processCache = make(map[int]*ProcessInfo, len(snapshot.Processes))
+	p.childIndex = make(map[int]map[int]struct{})
+	for _, proc := range snapshot.Processes {
+		if proc == nil {
+			continue
+		}
+		p.processCache[proc.PID] = proc
+
+		// Terminated processes left the tree when they were reported
+		if !proc.Terminated {
+			p.indexChild(proc.PPID, proc.PID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProcessScanner_SaveLoadCache(t *testing.T) {
+	config := DefaultConfig().ProcessScanner
+	config.BackpressurePolicy = BackpressureBlock
+	config.TerminationGracePeriod = time.Minute
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	processes := []*ProcessInfo{
+		{PID: 1, Name: "init", StartTime: start},
+		{PID: 2, PPID: 1, Name: "server", CPU: 12.5, RSS: 4096, StartTime: start, Labels: map[string]string{"team": "infra"}},
+		{PID: 3, PPID: 2, Name: "worker", StartTime: start},
+	}
+
+	saved := NewProcessScanner(config)
+	saved.processNewScan(processes)
+	saved.processNewScan(processes[:2])
+
+	var buf bytes.Buffer
+	if err := saved.SaveCache(&buf); err != nil {
+		t.Fatalf("Failed to save cache: %v", err)
+	}
+
+	loaded := NewProcessScanner(config)
+	if err := loaded.LoadCache(&buf); err != nil {
+		t.Fatalf("Failed to load cache: %v", err)
+	}
+
+	for _, pid := range []int{1, 2, 3} {
+		want, _ := saved.GetCachedProcess(pid)
+		got, ok := loaded.GetCachedProcess(pid)
+		if !ok || !got.Equal(want) {
+			t.Errorf("Expected PID %d to round-trip, got %+v, want %+v", pid, got, want)
+		}
+	}
+	if got, want := loaded.GetProcessTree(), saved.GetProcessTree(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected process tree %v, got %v", want, got)
+	}
+
+	// Only the changes since the save are reported
+	_, created, updated, terminated := loaded.processNewScan([]*ProcessInfo{
+		processes[0],
+		processes[1],
+		{PID: 4, PPID: 1, Name: "new", StartTime: start.Add(time.Minute)},
+	})
+	if created != 1 || updated != 0 || terminated != 0 {
+		t.Errorf("Expected 1 created process after reload, got %d created, %d updated, %d terminated",
+			created, updated, terminated)
+	}
+}
+
+func TestProcessScanner_LoadCacheStale(t *testing.T) {
+	config := DefaultConfig().ProcessScanner
+	config.CacheMaxAge = time.Minute
+	p := NewProcessScanner(config)
+
+	data, err := json.Marshal(cacheSnapshot{
+		SavedAt:   time.Now().Add(-time.Hour),
+		Processes: []*ProcessInfo{{PID: 1, Name: "init"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode cache: %v", err)
+	}
+
+	if err := p.LoadCache(bytes.NewReader(data)); err == nil {
+		t.Errorf("Expected error loading a stale cache")
+	}
+	if _, ok := p.GetCachedProcess(1); ok {
+		t.Errorf("Expected stale cache not to be loaded")
+	}
+
+	// Without a bound the age is not checked
+	p.config.CacheMaxAge = 0
+	if err := p.LoadCache(bytes.NewReader(data)); err != nil {
+		t.Fatalf("Failed to load cache without max age: %v", err)
+	}
+	if _, ok := p.GetCachedProcess(1); !ok {
+		t.Errorf("Expected cache to be loaded without max age")
+	}
+
+	if err := p.LoadCache(bytes.NewReader([]byte("not json"))); err == nil {
+		t.Errorf("Expected error loading an invalid cache")
+	}
+
+	p.status = StatusRunning
+	if err := p.LoadCache(bytes.NewReader(data)); err == nil {
+		t.Errorf("Expected error loading the cache of a running scanner")
+	}
+}
@@ -25,7 +25,9 @@ type ProcessInfo struct {
 	// User is the username of the process owner
 	User string `json:"user"`
 	
-	// CPU is the percentage of CPU usage (0-100)
+	// CPU is the percentage of CPU usage. It is a percentage of a single core,
+	// up to 100 times the number of cores, unless CPUReportingMode is
+	// CPUReportingNormalized, which reports 0-100 of the whole machine
 	CPU float64 `json:"cpu"`
 	
 	// RSS is the resident set size in bytes
@@ -55,10 +57,76 @@ type ProcessInfo struct {
 	// IOWriteBytes is the total bytes written to disk
 	IOWriteBytes int64 `json:"ioWriteBytes"`
 	
+	// CgroupPath is the cgroup the process belongs to (Linux only)
+	CgroupPath string `json:"cgroupPath,omitempty"`
+	
+	// ContainerID is the ID of the container running the process, if any
+	ContainerID string `json:"containerId,omitempty"`
+	
+	// Terminated is set on cached processes that have exited but are kept
+	// for the termination grace period
+	Terminated bool `json:"terminated,omitempty"`
+	
+	// TerminatedAt is when the process was detected as terminated
+	TerminatedAt time.Time `json:"terminatedAt,omitempty"`
+	
+	// OpenFiles are the resolved targets of the open file descriptors, only
+	// collected when CollectFDDetails is enabled (Linux only)
+	OpenFiles []string `json:"openFiles,omitempty"`
+	
+	// ConnectionCount is the number of TCP and UDP sockets that are not
+	// listening, only collected when CollectConnections is enabled (Linux only)
+	ConnectionCount int `json:"connectionCount,omitempty"`
+	
+	// ListeningPorts are the sorted local ports of listening TCP and bound
+	// UDP sockets (Linux only)
+	ListeningPorts []int `json:"listeningPorts,omitempty"`
+	
+	// ExecutableHash is the hex SHA-256 of the process binary, only collected
+	// when CollectExecutableHash is enabled (Linux only)
+	ExecutableHash string `json:"executableHash,omitempty"`
+	
+	// Partial is set when the details of the process could not be read, e.g.
+	// for processes of other users when the agent is not root. Only the PID
+	// and, if readable, the name are set
+	Partial bool `json:"partial,omitempty"`
+	
+	// AccessError is the error that left a Partial process without details
+	AccessError string `json:"accessError,omitempty"`
+	
 	// Labels are optional key-value pairs for additional information
 	Labels map[string]string `json:"labels,omitempty"`
 }
 
+// ProcessCapabilities reports which ProcessInfo fields a platform collector
+// fills in. Fields that are not collected stay at their zero value, so
+// consumers should not rank or alert on them
+type ProcessCapabilities struct {
+	// CPUPercent is set when CPU is collected
+	CPUPercent bool `json:"cpuPercent"`
+	
+	// RSS is set when RSS is collected
+	RSS bool `json:"rss"`
+	
+	// VMS is set when VMS is collected
+	VMS bool `json:"vms"`
+	
+	// FDs is set when FDs is collected
+	FDs bool `json:"fds"`
+	
+	// IO is set when IOReadBytes and IOWriteBytes are collected
+	IO bool `json:"io"`
+	
+	// Threads is set when Threads is collected
+	Threads bool `json:"threads"`
+	
+	// Connections is set when ConnectionCount and ListeningPorts are collected
+	Connections bool `json:"connections"`
+	
+	// Cgroup is set when CgroupPath and ContainerID are collected
+	Cgroup bool `json:"cgroup"`
+}
+
 // DeltaProcessInfo represents changes in process metrics between two samples
 type DeltaProcessInfo struct {
 	// PID of the process
@@ -78,6 +146,12 @@ type DeltaProcessInfo struct {
 	
 	// IOWriteBytes is the delta in bytes written to disk
 	IOWriteBytes int64 `json:"ioWriteBytes"`
+	
+	// IOReadBytesPerSec is the rate of bytes read from disk over DeltaTime
+	IOReadBytesPerSec float64 `json:"ioReadBytesPerSec"`
+	
+	// IOWriteBytesPerSec is the rate of bytes written to disk over DeltaTime
+	IOWriteBytesPerSec float64 `json:"ioWriteBytesPerSec"`
 }
 
 // CalculateDelta computes the differences between two process info snapshots
@@ -95,16 +169,26 @@ func CalculateDelta(current, previous *ProcessInfo) (*DeltaProcessInfo, error) {
 		return nil, fmt.Errorf("invalid time delta: %v", deltaTime)
 	}
 	
+	ioReadBytes := current.IOReadBytes - previous.IOReadBytes
+	ioWriteBytes := current.IOWriteBytes - previous.IOWriteBytes
+	
 	return &DeltaProcessInfo{
 		PID:         current.PID,
 		DeltaTime:   deltaTime,
 		CPU:         current.CPU - previous.CPU,
 		RSS:         current.RSS - previous.RSS,
-		IOReadBytes: current.IOReadBytes - previous.IOReadBytes,
-		IOWriteBytes: current.IOWriteBytes - previous.IOWriteBytes,
+		IOReadBytes: ioReadBytes,
+		IOWriteBytes: ioWriteBytes,
+		IOReadBytesPerSec:  perSecond(ioReadBytes, deltaTime),
+		IOWriteBytesPerSec: perSecond(ioWriteBytes, deltaTime),
 	}, nil
 }
 
+// perSecond returns the rate of a delta over a positive duration
+func perSecond(delta int64, d time.Duration) float64 {
+	return float64(delta) / d.Seconds()
+}
+
 // Clone creates a deep copy of ProcessInfo
 func (p *ProcessInfo) Clone() *ProcessInfo {
 	if p == nil {
@@ -116,6 +200,18 @@ func (p *ProcessInfo) Clone() *ProcessInfo {
 		newLabels[k] = v
 	}
 	
+	var newOpenFiles []string
+	if p.OpenFiles != nil {
+		newOpenFiles = make([]string, len(p.OpenFiles))
+		copy(newOpenFiles, p.OpenFiles)
+	}
+	
+	var newListeningPorts []int
+	if p.ListeningPorts != nil {
+		newListeningPorts = make([]int, len(p.ListeningPorts))
+		copy(newListeningPorts, p.ListeningPorts)
+	}
+	
 	return &ProcessInfo{
 		PID:         p.PID,
 		PPID:        p.PPID,
@@ -133,12 +229,81 @@ func (p *ProcessInfo) Clone() *ProcessInfo {
 		LastUpdated: p.LastUpdated,
 		IOReadBytes: p.IOReadBytes,
 		IOWriteBytes: p.IOWriteBytes,
+		CgroupPath:  p.CgroupPath,
+		ContainerID: p.ContainerID,
+		Terminated:  p.Terminated,
+		TerminatedAt: p.TerminatedAt,
+		OpenFiles:   newOpenFiles,
+		ConnectionCount: p.ConnectionCount,
+		ListeningPorts: newListeningPorts,
+		ExecutableHash: p.ExecutableHash,
+		Partial:     p.Partial,
+		AccessError: p.AccessError,
 		Labels:      newLabels,
 	}
 }
 
+// Field names a field of ProcessInfo compared by EqualExcept, as in its
+// JSON encoding
+type Field string
+
+const (
+	FieldPID             Field = "pid"
+	FieldPPID            Field = "ppid"
+	FieldName            Field = "name"
+	FieldExecutable      Field = "executable"
+	FieldCommand         Field = "command"
+	FieldUser            Field = "user"
+	FieldCPU             Field = "cpu"
+	FieldRSS             Field = "rss"
+	FieldVMS             Field = "vms"
+	FieldFDs             Field = "fds"
+	FieldThreads         Field = "threads"
+	FieldStartTime       Field = "startTime"
+	FieldState           Field = "state"
+	FieldIOReadBytes     Field = "ioReadBytes"
+	FieldIOWriteBytes    Field = "ioWriteBytes"
+	FieldCgroupPath      Field = "cgroupPath"
+	FieldContainerID     Field = "containerId"
+	FieldTerminated      Field = "terminated"
+	FieldTerminatedAt    Field = "terminatedAt"
+	FieldOpenFiles       Field = "openFiles"
+	FieldConnectionCount Field = "connectionCount"
+	FieldListeningPorts  Field = "listeningPorts"
+	FieldExecutableHash  Field = "executableHash"
+	FieldPartial         Field = "partial"
+	FieldAccessError     Field = "accessError"
+	FieldLabels          Field = "labels"
+)
+
+// AllFields lists the fields compared by Equal
+var AllFields = []Field{
+	FieldPID, FieldPPID, FieldName, FieldExecutable, FieldCommand, FieldUser,
+	FieldCPU, FieldRSS, FieldVMS, FieldFDs, FieldThreads, FieldStartTime,
+	FieldState, FieldIOReadBytes, FieldIOWriteBytes, FieldCgroupPath,
+	FieldContainerID, FieldTerminated, FieldTerminatedAt, FieldOpenFiles,
+	FieldConnectionCount, FieldListeningPorts, FieldExecutableHash, FieldPartial,
+	FieldAccessError, FieldLabels,
+}
+
+// IsValid returns whether the field is compared by Equal
+func (f Field) IsValid() bool {
+	for _, field := range AllFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
 // Equal checks if two ProcessInfo instances are equal
 func (p *ProcessInfo) Equal(other *ProcessInfo) bool {
+	return p.EqualExcept(other)
+}
+
+// EqualExcept checks if two ProcessInfo instances are equal, not comparing
+// the ignored fields. LastUpdated is never compared
+func (p *ProcessInfo) EqualExcept(other *ProcessInfo, ignore ...Field) bool {
 	if p == nil && other == nil {
 		return true
 	}
@@ -147,34 +312,78 @@ func (p *ProcessInfo) Equal(other *ProcessInfo) bool {
 		return false
 	}
 	
+	var skip map[Field]bool
+	if len(ignore) > 0 {
+		skip = make(map[Field]bool, len(ignore))
+		for _, field := range ignore {
+			skip[field] = true
+		}
+	}
+	
 	// Check basic fields
-	if p.PID != other.PID ||
-		p.PPID != other.PPID ||
-		p.Name != other.Name ||
-		p.Executable != other.Executable ||
-		p.Command != other.Command ||
-		p.User != other.User ||
-		p.CPU != other.CPU ||
-		p.RSS != other.RSS ||
-		p.VMS != other.VMS ||
-		p.FDs != other.FDs ||
-		p.Threads != other.Threads ||
-		p.State != other.State ||
-		p.IOReadBytes != other.IOReadBytes ||
-		p.IOWriteBytes != other.IOWriteBytes ||
-		!p.StartTime.Equal(other.StartTime) {
+	if (!skip[FieldPID] && p.PID != other.PID) ||
+		(!skip[FieldPPID] && p.PPID != other.PPID) ||
+		(!skip[FieldName] && p.Name != other.Name) ||
+		(!skip[FieldExecutable] && p.Executable != other.Executable) ||
+		(!skip[FieldCommand] && p.Command != other.Command) ||
+		(!skip[FieldUser] && p.User != other.User) ||
+		(!skip[FieldCPU] && p.CPU != other.CPU) ||
+		(!skip[FieldRSS] && p.RSS != other.RSS) ||
+		(!skip[FieldVMS] && p.VMS != other.VMS) ||
+		(!skip[FieldFDs] && p.FDs != other.FDs) ||
+		(!skip[FieldThreads] && p.Threads != other.Threads) ||
+		(!skip[FieldState] && p.State != other.State) ||
+		(!skip[FieldIOReadBytes] && p.IOReadBytes != other.IOReadBytes) ||
+		(!skip[FieldIOWriteBytes] && p.IOWriteBytes != other.IOWriteBytes) ||
+		(!skip[FieldCgroupPath] && p.CgroupPath != other.CgroupPath) ||
+		(!skip[FieldContainerID] && p.ContainerID != other.ContainerID) ||
+		(!skip[FieldTerminated] && p.Terminated != other.Terminated) ||
+		(!skip[FieldConnectionCount] && p.ConnectionCount != other.ConnectionCount) ||
+		(!skip[FieldExecutableHash] && p.ExecutableHash != other.ExecutableHash) ||
+		(!skip[FieldPartial] && p.Partial != other.Partial) ||
+		(!skip[FieldAccessError] && p.AccessError != other.AccessError) ||
+		(!skip[FieldTerminatedAt] && !p.TerminatedAt.Equal(other.TerminatedAt)) ||
+		(!skip[FieldStartTime] && !p.StartTime.Equal(other.StartTime)) {
 		return false
 	}
 	
-	// Check labels
-	if len(p.Labels) != len(other.Labels) {
-		return false
+	// Check open files
+	if !skip[FieldOpenFiles] {
+		if len(p.OpenFiles) != len(other.OpenFiles) {
+			return false
+		}
+		
+		for i, file := range p.OpenFiles {
+			if file != other.OpenFiles[i] {
+				return false
+			}
+		}
 	}
 	
-	for k, v := range p.Labels {
-		if otherVal, ok := other.Labels[k]; !ok || v != otherVal {
+	// Check listening ports
+	if !skip[FieldListeningPorts] {
+		if len(p.ListeningPorts) != len(other.ListeningPorts) {
 			return false
 		}
+		
+		for i, port := range p.ListeningPorts {
+			if port != other.ListeningPorts[i] {
+				return false
+			}
+		}
+	}
+	
+	// Check labels
+	if !skip[FieldLabels] {
+		if len(p.Labels) != len(other.Labels) {
+			return false
+		}
+		
+		for k, v := range p.Labels {
+			if otherVal, ok := other.Labels[k]; !ok || v != otherVal {
+				return false
+			}
+		}
 	}
 	
 	return true
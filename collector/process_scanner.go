@@ -2,13 +2,17 @@ package collector
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"regexp"
 	"runtime"
+	"sort"
 	"sync"
 	"time"
 	
 	"github.com/newrelic/infrastructure-agent/collector/platform"
+	"github.com/newrelic/infrastructure-agent/sketch"
 )
 
 // Register the process scanner at package initialization
@@ -18,40 +22,119 @@ func init() {
 	})
 }
 
+// fdAccessErrorReporter is implemented by platform collectors that report
+// how many processes had an unreadable fd directory in the last scan
+type fdAccessErrorReporter interface {
+	FDAccessErrors() int
+}
+
+// partialCollector is implemented by platform collectors that can list the
+// PIDs without reading the processes, and read only some of them
+type partialCollector interface {
+	ListPIDs() ([]int, error)
+	GetProcessesByPID(pids []int) ([]*ProcessInfo, error)
+}
+
+// connectionToggler is implemented by platform collectors that can skip the
+// collection of connections on individual scans
+type connectionToggler interface {
+	SetConnectionCollection(enabled bool)
+}
+
 // ProcessScanner implements a collector for process information
 type ProcessScanner struct {
 	config        ProcessScannerConfig
 	platformCollector platform.ProcessCollector
 	processCache  map[int]*ProcessInfo
+	childIndex    map[int]map[int]struct{} // PPID to child PIDs of cached processes
+	lastDelta     scanDelta // Event counts of the most recent processNewScan
 	lastScanTime  time.Time
 	metrics       *MetricsTracker
 	registry      *ConsumerRegistry
-	excludeRegexps []*regexp.Regexp
-	includeRegexps []*regexp.Regexp
+	dispatcher    *consumerDispatcher
+	diagnostics   DiagnosticSink // Set before Start, see SetDiagnosticSink
+	recentErrors  *scanErrorRing
+	excludeRegexps []*regexp.Regexp // Configured and FilterFile patterns, guarded by filterMutex
+	includeRegexps []*regexp.Regexp // Configured and FilterFile patterns, guarded by filterMutex
+	filterMutex   sync.RWMutex      // Swaps the patterns at once on ReloadFilters
+	excludeUsers  map[string]struct{}
+	includeUsers  map[string]struct{}
+	updates       updateComparison
+	parentCtx     context.Context // Context passed to Init, parent of restarts
 	ctx           context.Context
 	cancel        context.CancelFunc
 	scannerMutex  sync.RWMutex
 	cacheMutex    sync.RWMutex
+	scanMutex     sync.Mutex // Serializes loop and forced scans
+	hostMemory    hostMemory // Read at the start of the current scan, guarded by scanMutex
+	lastConnectionScan time.Time // Last scan that collected connections, guarded by scanMutex
+	knownCursor   int // Last cached PID read by a partial scan, guarded by scanMutex
+	newCursor     int // Last new PID read by a partial scan, guarded by scanMutex
 	scanTicker    *time.Ticker
+	pauseChannel  chan struct{} // Closed by Pause to stop the scan loop
+	scanLoopDone  chan struct{} // Closed when the scan loop returns
+	baseInterval  time.Duration // Configured ScanInterval, adaptive sampling converges back to it
+	degradationLevel int        // Set by the watchdog, 0 is normal operation
+	intervalMutex sync.Mutex    // Guards config.ScanInterval, scanTicker resets and degradationLevel
 	status        Status
 	eventChannel  chan ProcessEvent
+	coalescer     *updateCoalescer // Nil when UpdateCoalesceWindow is 0, owned by the event processor
+	flushChannel  chan chan struct{} // Requests from ForceScanSync to drain eventChannel
 	wg            sync.WaitGroup
+	scanOutcomes  []scanOutcome // Last scanHealthWindow scans, oldest first
+	healthMutex   sync.Mutex
+}
+
+// hostMemory is the host memory in bytes attached to the events of a scan
+type hostMemory struct {
+	total uint64
+	used  uint64
+}
+
+// scanDelta counts the events generated by a single scan
+type scanDelta struct {
+	created    int
+	updated    int
+	terminated int
 }
 
 // NewProcessScanner creates a new process scanner
 func NewProcessScanner(config ProcessScannerConfig) *ProcessScanner {
+	metrics := NewMetricsTracker()
+	if config.ScanDurationPercentiles {
+		metrics.EnableDurationSketch(MetricScanDuration, sketch.DefaultConfig().DDSketch)
+	}
+	registry := NewConsumerRegistry()
+	
+	var coalescer *updateCoalescer
+	if config.UpdateCoalesceWindow > 0 {
+		coalescer = newUpdateCoalescer(config.UpdateCoalesceWindow)
+	}
+	
 	return &ProcessScanner{
 		config:       config,
 		processCache: make(map[int]*ProcessInfo),
-		metrics:      NewMetricsTracker(),
-		registry:     NewConsumerRegistry(),
+		childIndex:   make(map[int]map[int]struct{}),
+		metrics:      metrics,
+		registry:     registry,
+		dispatcher:   newConsumerDispatcher(registry, metrics, config),
+		diagnostics:  LogDiagnosticSink{},
+		recentErrors: newScanErrorRing(recentErrorsSize),
 		status:       StatusInitialized,
 		eventChannel: make(chan ProcessEvent, config.EventChannelSize),
+		baseInterval: config.ScanInterval,
+		flushChannel: make(chan chan struct{}),
+		excludeUsers: userSet(config.ExcludeUsers),
+		includeUsers: userSet(config.IncludeUsers),
+		updates:      newUpdateComparison(config),
+		coalescer:    coalescer,
 	}
 }
 
-// Init initializes the process scanner
-func (p *ProcessScanner) Init(ctx context.Context) error {
+// Init initializes the process scanner. When options["mockCollector"] is a
+// platform.ProcessCollector it is used instead of the platform collector,
+// options may be nil
+func (p *ProcessScanner) Init(ctx context.Context, options map[string]interface{}) error {
 	p.scannerMutex.Lock()
 	defer p.scannerMutex.Unlock()
 	
@@ -60,37 +143,35 @@ func (p *ProcessScanner) Init(ctx context.Context) error {
 	}
 	
 	// Create a derived context
+	p.parentCtx = ctx
 	p.ctx, p.cancel = context.WithCancel(ctx)
 	
-	// Create platform-specific collector
-	options := map[string]interface{}{
-		"procFSPath": p.config.ProcFSPath,
-	}
-	
 	var err error
-	p.platformCollector, err = platform.New(options)
-	if err != nil {
-		return fmt.Errorf("failed to create platform collector: %w", err)
-	}
-	
-	// Compile exclude patterns
-	p.excludeRegexps = make([]*regexp.Regexp, 0, len(p.config.ExcludePatterns))
-	for _, pattern := range p.config.ExcludePatterns {
-		re, err := regexp.Compile(pattern)
+	if mock, ok := options["mockCollector"].(platform.ProcessCollector); ok {
+		p.platformCollector = mock
+	} else {
+		// Create platform-specific collector
+		platformOptions := map[string]interface{}{
+			"procFSPath":       p.config.ProcFSPath,
+			"collectFDDetails": p.config.CollectFDDetails,
+			"maxOpenFiles":     p.config.MaxOpenFiles,
+			"collectConnections": p.config.CollectConnections,
+			"collectExecutableHash": p.config.CollectExecutableHash,
+			"executableHashSizeKB":  p.config.ExecutableHashSizeKB,
+			"cpuReportingMode":      p.config.CPUReportingMode,
+		}
+		
+		p.platformCollector, err = platform.New(platformOptions)
 		if err != nil {
-			return fmt.Errorf("invalid exclude pattern '%s': %w", pattern, err)
+			return fmt.Errorf("failed to create platform collector: %w", err)
 		}
-		p.excludeRegexps = append(p.excludeRegexps, re)
 	}
 	
-	// Compile include patterns
-	p.includeRegexps = make([]*regexp.Regexp, 0, len(p.config.IncludePatterns))
-	for _, pattern := range p.config.IncludePatterns {
-		re, err := regexp.Compile(pattern)
-		if err != nil {
-			return fmt.Errorf("invalid include pattern '%s': %w", pattern, err)
-		}
-		p.includeRegexps = append(p.includeRegexps, re)
+	// Compile the include and exclude patterns. Invalid lines of the filter
+	// file are reported and skipped
+	var lineErr *FilterLineError
+	if err := p.loadFilters(); err != nil && !errors.As(err, &lineErr) {
+		return err
 	}
 	
 	return nil
@@ -109,14 +190,30 @@ func (p *ProcessScanner) Start() error {
 		return fmt.Errorf("scanner in invalid state: %s", p.status)
 	}
 	
+	// The event processor kept running while paused
+	if p.status == StatusPaused {
+		p.startScanLoop()
+		p.status = StatusRunning
+		return nil
+	}
+	
+	// A previous Stop cancelled the context, derive a new one
+	if p.ctx != nil && p.ctx.Err() != nil {
+		p.ctx, p.cancel = context.WithCancel(p.parentCtx)
+	}
+	
 	// Start the event processor
 	p.wg.Add(1)
 	go p.processEvents()
 	
+	// Reload the filter file on SIGHUP
+	if p.config.FilterFile != "" {
+		p.wg.Add(1)
+		go p.watchFilterReloads()
+	}
+	
 	// Start the scan ticker
-	p.scanTicker = time.NewTicker(p.config.ScanInterval)
-	p.wg.Add(1)
-	go p.scanLoop()
+	p.startScanLoop()
 	
 	// Update status
 	p.status = StatusRunning
@@ -124,12 +221,60 @@ func (p *ProcessScanner) Start() error {
 	return nil
 }
 
-// Stop halts the process scanning
-func (p *ProcessScanner) Stop() error {
+// startScanLoop starts the scan ticker and loop. Caller must hold scannerMutex
+func (p *ProcessScanner) startScanLoop() {
+	p.intervalMutex.Lock()
+	p.scanTicker = time.NewTicker(p.config.ScanInterval)
+	p.intervalMutex.Unlock()
+	
+	p.pauseChannel = make(chan struct{})
+	p.scanLoopDone = make(chan struct{})
+	p.wg.Add(1)
+	go p.scanLoop(p.pauseChannel, p.scanLoopDone)
+}
+
+// Pause stops scanning but keeps the event processor, cache and consumers.
+// Events queued before the pause are still delivered
+func (p *ProcessScanner) Pause() error {
 	p.scannerMutex.Lock()
 	defer p.scannerMutex.Unlock()
 	
 	if p.status != StatusRunning {
+		return fmt.Errorf("cannot pause scanner in state: %s", p.status)
+	}
+	
+	p.intervalMutex.Lock()
+	p.scanTicker.Stop()
+	p.intervalMutex.Unlock()
+	
+	// Wait for an in-flight scan to finish
+	close(p.pauseChannel)
+	<-p.scanLoopDone
+	
+	p.status = StatusPaused
+	return nil
+}
+
+// Resume restarts scanning after Pause, beginning with an immediate scan
+func (p *ProcessScanner) Resume() error {
+	p.scannerMutex.Lock()
+	defer p.scannerMutex.Unlock()
+	
+	if p.status != StatusPaused {
+		return fmt.Errorf("cannot resume scanner in state: %s", p.status)
+	}
+	
+	p.startScanLoop()
+	p.status = StatusRunning
+	return nil
+}
+
+// Stop halts the process scanning. A paused scanner can be stopped too
+func (p *ProcessScanner) Stop() error {
+	p.scannerMutex.Lock()
+	defer p.scannerMutex.Unlock()
+	
+	if p.status != StatusRunning && p.status != StatusPaused {
 		return fmt.Errorf("scanner not running")
 	}
 	
@@ -162,11 +307,43 @@ func (p *ProcessScanner) Status() Status {
 
 // Metrics returns performance metrics for the scanner
 func (p *ProcessScanner) Metrics() map[string]float64 {
-	return p.metrics.GetAllMetrics()
+	metrics := p.metrics.GetAllMetrics()
+	
+	metrics[MetricDegradationLevel] = float64(p.GetDegradationLevel())
+	
+	// Drop counters are reported even before the first drop
+	metrics[MetricBackpressurePolicy+string(p.backpressurePolicy())] = 1
+	metrics[MetricEventsDropped] = float64(p.metrics.GetCounter(MetricEventsDropped))
+	metrics[MetricEventsEvicted] = float64(p.metrics.GetCounter(MetricEventsEvicted))
+	
+	// Per-consumer lag and queue depth
+	for name, value := range p.dispatcher.lagMetrics() {
+		metrics[name] = value
+	}
+	
+	return metrics
+}
+
+// Capabilities returns which process fields the platform collector fills
+// in, so consumers can skip the fields it does not collect. Before Init no
+// field is reported
+func (p *ProcessScanner) Capabilities() ProcessCapabilities {
+	if p.platformCollector == nil {
+		return ProcessCapabilities{}
+	}
+	return p.platformCollector.Capabilities()
 }
 
 // Resources returns resource usage of the scanner itself
 func (p *ProcessScanner) Resources() map[string]float64 {
+	// Not initialized yet
+	if p.platformCollector == nil {
+		return map[string]float64{
+			"cpu_percent":  0,
+			"memory_bytes": 0,
+		}
+	}
+	
 	cpuPct, memBytes, err := p.platformCollector.GetSelfUsage()
 	if err != nil {
 		cpuPct, memBytes = 0, 0
@@ -186,6 +363,9 @@ func (p *ProcessScanner) Shutdown() error {
 		return err
 	}
 	
+	// Let stateful consumers flush before the state is cleared
+	p.closeConsumers()
+	
 	// Clean up resources
 	if p.platformCollector != nil {
 		err = p.platformCollector.Shutdown()
@@ -197,24 +377,65 @@ func (p *ProcessScanner) Shutdown() error {
 	// Clear process cache
 	p.cacheMutex.Lock()
 	p.processCache = make(map[int]*ProcessInfo)
+	p.childIndex = make(map[int]map[int]struct{})
 	p.cacheMutex.Unlock()
 	
 	return nil
 }
 
+// closeConsumers notifies Closable consumers in delivery order that no
+// more events follow. Errors are reported but do not stop the shutdown
+func (p *ProcessScanner) closeConsumers() {
+	for _, registered := range p.registry.ordered() {
+		closable, ok := registered.consumer.(Closable)
+		if !ok {
+			continue
+		}
+		
+		if err := closable.OnStreamClose(); err != nil {
+			p.diagnostics.Emit(newDiagEvent(DiagConsumerCloseError, DiagSeverityWarning,
+				map[string]interface{}{"consumer": registered.name, "error": err.Error()},
+				"Error closing stream of consumer '%s': %v", registered.name, err))
+		}
+	}
+}
+
 // RegisterConsumer registers a consumer to receive process events
 func (p *ProcessScanner) RegisterConsumer(name string, consumer ProcessConsumer) error {
 	return p.registry.Register(name, consumer)
 }
 
+// RegisterConsumerWithPriority adds a consumer that is notified before
+// consumers with a lower priority
+func (p *ProcessScanner) RegisterConsumerWithPriority(name string, consumer ProcessConsumer, priority int) error {
+	return p.registry.RegisterWithPriority(name, consumer, priority)
+}
+
 // UnregisterConsumer removes a registered consumer
 func (p *ProcessScanner) UnregisterConsumer(name string) error {
 	return p.registry.Unregister(name)
 }
 
+// RecentErrors returns the most recent scan errors, oldest first
+func (p *ProcessScanner) RecentErrors() []ScanError {
+	return p.recentErrors.snapshot()
+}
+
+// SetDiagnosticSink replaces the sink receiving diagnostic events, which
+// logs them by default. It must be called before Start
+func (p *ProcessScanner) SetDiagnosticSink(sink DiagnosticSink) {
+	if sink == nil {
+		sink = LogDiagnosticSink{}
+	}
+
+	p.diagnostics = sink
+	p.dispatcher.diagnostics = sink
+}
+
 // scanLoop is the main scanning loop
-func (p *ProcessScanner) scanLoop() {
+func (p *ProcessScanner) scanLoop(pause <-chan struct{}, done chan<- struct{}) {
 	defer p.wg.Done()
+	defer close(done)
 	
 	// Perform an initial scan
 	p.performScan()
@@ -223,26 +444,68 @@ func (p *ProcessScanner) scanLoop() {
 		select {
 		case <-p.ctx.Done():
 			return
+		case <-pause:
+			return
 		case <-p.scanTicker.C:
 			p.performScan()
 		}
 	}
 }
 
-// performScan executes a single scan cycle
-func (p *ProcessScanner) performScan() {
+// performScan executes a single scan cycle. It is shared by the scan loop,
+// ForceScan and ForceScanSync, the returned error is only used by the latter
+func (p *ProcessScanner) performScan() error {
+	p.scanMutex.Lock()
+	defer p.scanMutex.Unlock()
+	
 	// Record metrics for scan duration
 	stopTimer := p.metrics.StartTimer(MetricScanDuration)
 	scanStart := time.Now()
 	
+	// Connections are expensive, only collect them when due
+	if toggler, ok := p.platformCollector.(connectionToggler); ok && p.config.CollectConnections {
+		collect := p.connectionScanDue(scanStart)
+		toggler.SetConnectionCollection(collect)
+		if collect {
+			p.lastConnectionScan = scanStart
+		} else {
+			p.metrics.IncrementCounter(MetricConnectionScansSkipped, 1)
+		}
+	}
+	
 	// Get current processes
-	processes, err := p.platformCollector.GetProcesses()
+	processes, err := p.collectProcesses()
 	if err != nil {
 		p.metrics.IncrementCounter(MetricScanErrors, 1)
-		fmt.Printf("AgentDiagEvent: Error scanning processes: %v\n", err)
-		return
+		p.recentErrors.add(ScanPhaseProcesses, err)
+		p.recordScanHealth(scanOutcome{failed: true})
+		p.diagnostics.Emit(newDiagEvent(DiagScanError, DiagSeverityCritical,
+			map[string]interface{}{"error": err.Error()},
+			"Error scanning processes: %v", err))
+		return fmt.Errorf("error scanning processes: %w", err)
+	}
+	
+	// Read once so every event of this scan carries the same denominator.
+	// Events are sent without host memory when it is not available
+	p.hostMemory = hostMemory{}
+	if total, used, err := p.platformCollector.GetMemoryStats(); err == nil {
+		p.hostMemory = hostMemory{total: total, used: used}
 	}
 	
+	// Processes whose fd directory was not readable are reported with 0 fds
+	if reporter, ok := p.platformCollector.(fdAccessErrorReporter); ok {
+		p.metrics.IncrementCounter(MetricFDAccessErrors, int64(reporter.FDAccessErrors()))
+	}
+	
+	// Processes the agent may not read are reported without details
+	partial := 0
+	for _, proc := range processes {
+		if proc.Partial {
+			partial++
+		}
+	}
+	p.metrics.SetGauge(MetricProcessesPartial, float64(partial))
+	
 	// Apply filters
 	filteredProcesses := p.filterProcesses(processes)
 	
@@ -251,7 +514,10 @@ func (p *ProcessScanner) performScan() {
 		err = p.platformCollector.GetCPUTimes()
 		if err != nil {
 			p.metrics.IncrementCounter(MetricScanErrors, 1)
-			fmt.Printf("AgentDiagEvent: Error refreshing CPU times: %v\n", err)
+			p.recentErrors.add(ScanPhaseCPUTimes, err)
+			p.diagnostics.Emit(newDiagEvent(DiagCPUTimesError, DiagSeverityWarning,
+				map[string]interface{}{"error": err.Error()},
+				"Error refreshing CPU times: %v", err))
 		}
 	}
 	
@@ -271,13 +537,15 @@ func (p *ProcessScanner) performScan() {
 	
 	if cpuPct > p.config.MaxCPUUsage {
 		p.metrics.IncrementCounter(MetricLimitBreaches, 1)
-		fmt.Printf("AgentDiagEvent: ModuleOverLimit detected in process scanner. CPU: %.2f%% (limit: %.2f%%)\n",
-			cpuPct, p.config.MaxCPUUsage)
-		
-		// Adjust scan interval if adaptive sampling is enabled
-		if p.config.AdaptiveSampling {
-			p.adjustScanInterval(cpuPct)
-		}
+		p.diagnostics.Emit(newDiagEvent(DiagModuleOverLimit, DiagSeverityCritical,
+			map[string]interface{}{"cpu_percent": cpuPct, "limit": p.config.MaxCPUUsage},
+			"ModuleOverLimit detected in process scanner. CPU: %.2f%% (limit: %.2f%%)", cpuPct, p.config.MaxCPUUsage))
+	}
+	
+	// Adjust scan interval if adaptive sampling is enabled, on every scan so
+	// it can also speed back up
+	if p.config.AdaptiveSampling {
+		p.adjustScanInterval(cpuPct)
 	}
 	
 	// Stop the timer and record scan duration
@@ -291,16 +559,147 @@ func (p *ProcessScanner) performScan() {
 	// Record when we did the scan
 	p.lastScanTime = time.Now()
 	
+	p.recordScanHealth(scanOutcome{
+		breached: cpuPct > p.config.MaxCPUUsage,
+		slow:     scanDuration > p.config.MaxScanTime,
+	})
+	
 	// Check if scan took too long
 	if scanDuration > p.config.MaxScanTime {
-		fmt.Printf("AgentDiagEvent: Scan duration exceeded limit: %v (limit: %v)\n",
-			scanDuration, p.config.MaxScanTime)
+		p.diagnostics.Emit(newDiagEvent(DiagScanTimeExceeded, DiagSeverityWarning,
+			map[string]interface{}{"duration": scanDuration, "limit": p.config.MaxScanTime},
+			"Scan duration exceeded limit: %v (limit: %v)", scanDuration, p.config.MaxScanTime))
+	}
+	
+	return nil
+}
+
+// collectProcesses returns the processes of a scan. Over
+// MaxProcessesPerScan, only the PIDs chosen by selectPIDs are read and the
+// other cached processes are returned as cached, so they are neither
+// updated nor terminated. Caller must hold scanMutex
+func (p *ProcessScanner) collectProcesses() ([]*ProcessInfo, error) {
+	partial, ok := p.platformCollector.(partialCollector)
+	if !ok || p.config.MaxProcessesPerScan <= 0 {
+		return p.platformCollector.GetProcesses()
+	}
+	
+	pids, err := partial.ListPIDs()
+	if err != nil {
+		return nil, err
+	}
+	
+	if len(pids) <= p.config.MaxProcessesPerScan {
+		p.metrics.SetGauge(MetricProcessesDeferred, 0)
+		return p.platformCollector.GetProcesses()
+	}
+	
+	selected, deferred := p.selectPIDs(pids)
+	processes, err := partial.GetProcessesByPID(selected)
+	if err != nil {
+		return nil, err
+	}
+	
+	p.metrics.SetGauge(MetricProcessesDeferred, float64(len(pids)-len(selected)))
+	return append(processes, deferred...), nil
+}
+
+// selectPIDs picks MaxProcessesPerScan of the listed PIDs, cached ones
+// first, and returns them with the cached processes left out. A quarter of
+// the scan at least goes to new PIDs, and both groups are read in rotation
+// so every process is eventually covered. Caller must hold scanMutex
+func (p *ProcessScanner) selectPIDs(pids []int) ([]int, []*ProcessInfo) {
+	limit := p.config.MaxProcessesPerScan
+	
+	p.cacheMutex.RLock()
+	defer p.cacheMutex.RUnlock()
+	
+	var known, unknown []int
+	for _, pid := range pids {
+		if proc, ok := p.processCache[pid]; ok && !proc.Terminated {
+			known = append(known, pid)
+		} else {
+			unknown = append(unknown, pid)
+		}
+	}
+	sort.Ints(known)
+	sort.Ints(unknown)
+	
+	newBudget := min(len(unknown), max(limit-len(known), limit/4, 1))
+	var knownSelected, newSelected []int
+	knownSelected, p.knownCursor = rotatePIDs(known, p.knownCursor, limit-newBudget)
+	newSelected, p.newCursor = rotatePIDs(unknown, p.newCursor, newBudget)
+	
+	var deferred []*ProcessInfo
+	if len(knownSelected) < len(known) {
+		read := make(map[int]struct{}, len(knownSelected))
+		for _, pid := range knownSelected {
+			read[pid] = struct{}{}
+		}
+		deferred = make([]*ProcessInfo, 0, len(known)-len(knownSelected))
+		for _, pid := range known {
+			if _, ok := read[pid]; !ok {
+				deferred = append(deferred, p.processCache[pid])
+			}
+		}
+	}
+	
+	return append(knownSelected, newSelected...), deferred
+}
+
+// rotatePIDs returns up to n of the sorted PIDs, starting after cursor and
+// wrapping around, and the last PID returned as the next cursor
+func rotatePIDs(pids []int, cursor, n int) ([]int, int) {
+	if n >= len(pids) {
+		return pids, cursor
+	}
+	if n <= 0 {
+		return nil, cursor
+	}
+	
+	start := sort.SearchInts(pids, cursor+1)
+	selected := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		selected = append(selected, pids[(start+i)%len(pids)])
+	}
+	return selected, selected[n-1]
+}
+
+// connectionScanDue reports whether the scan starting at now should collect
+// connections. They are skipped after a scan over MaxCPUUsage and until
+// ConnectionsInterval has elapsed, so they never run more often than the
+// adaptive scan interval either
+func (p *ProcessScanner) connectionScanDue(now time.Time) bool {
+	p.healthMutex.Lock()
+	breached := len(p.scanOutcomes) > 0 && p.scanOutcomes[len(p.scanOutcomes)-1].breached
+	p.healthMutex.Unlock()
+	
+	if breached {
+		return false
+	}
+	return p.lastConnectionScan.IsZero() || now.Sub(p.lastConnectionScan) >= p.config.ConnectionsInterval
+}
+
+// recordScanHealth records the outcome of a scan for GetHealth, keeping the
+// last scanHealthWindow outcomes
+func (p *ProcessScanner) recordScanHealth(outcome scanOutcome) {
+	p.healthMutex.Lock()
+	defer p.healthMutex.Unlock()
+	
+	p.scanOutcomes = append(p.scanOutcomes, outcome)
+	if len(p.scanOutcomes) > scanHealthWindow {
+		p.scanOutcomes = p.scanOutcomes[len(p.scanOutcomes)-scanHealthWindow:]
 	}
 }
 
 // filterProcesses applies include/exclude filters to the process list
 func (p *ProcessScanner) filterProcesses(processes []*ProcessInfo) []*ProcessInfo {
-	if len(p.includeRegexps) == 0 && len(p.excludeRegexps) == 0 {
+	p.filterMutex.RLock()
+	includeRegexps, excludeRegexps := p.includeRegexps, p.excludeRegexps
+	p.filterMutex.RUnlock()
+	
+	if len(includeRegexps) == 0 && len(excludeRegexps) == 0 &&
+		len(p.includeUsers) == 0 && len(p.excludeUsers) == 0 {
 		return processes
 	}
 	
@@ -309,7 +708,7 @@ func (p *ProcessScanner) filterProcesses(processes []*ProcessInfo) []*ProcessInf
 	for _, proc := range processes {
 		// Apply exclude patterns first
 		excluded := false
-		for _, re := range p.excludeRegexps {
+		for _, re := range excludeRegexps {
 			if re.MatchString(proc.Command) || re.MatchString(proc.Name) {
 				excluded = true
 				break
@@ -320,10 +719,21 @@ func (p *ProcessScanner) filterProcesses(processes []*ProcessInfo) []*ProcessInf
 			continue
 		}
 		
+		// Excluded users win over included ones
+		if _, ok := p.excludeUsers[proc.User]; ok {
+			continue
+		}
+		
+		if len(p.includeUsers) > 0 {
+			if _, ok := p.includeUsers[proc.User]; !ok {
+				continue
+			}
+		}
+		
 		// If include patterns exist, process must match at least one
-		if len(p.includeRegexps) > 0 {
+		if len(includeRegexps) > 0 {
 			included := false
-			for _, re := range p.includeRegexps {
+			for _, re := range includeRegexps {
 				if re.MatchString(proc.Command) || re.MatchString(proc.Name) {
 					included = true
 					break
@@ -355,59 +765,219 @@ func (p *ProcessScanner) processNewScan(newProcesses []*ProcessInfo) (int, int,
 	created := 0
 	updated := 0
 	terminated := 0
+	now := time.Now()
+	minCPU := p.degradedMinCPU()
+	memory := p.hostMemory
 	
 	// Check for terminated processes
 	for pid, cachedProc := range p.processCache {
-		if _, exists := newProcessMap[pid]; !exists {
-			// Process no longer exists
-			terminated++
+		if _, exists := newProcessMap[pid]; exists {
+			continue
+		}
+		
+		// Already reported, evict once the grace period has elapsed
+		if cachedProc.Terminated {
+			if now.Sub(cachedProc.TerminatedAt) >= p.config.TerminationGracePeriod {
+				delete(p.processCache, pid)
+			}
+			continue
+		}
+		
+		// Process no longer exists
+		terminated++
+		p.unindexChild(cachedProc.PPID, pid)
+		
+		if p.config.TerminationGracePeriod > 0 {
+			// Keep final stats readable for the grace period. Shared
+			// processes are immutable, so a copy is marked instead
+			if p.config.ShareProcessPointers {
+				cachedProc = cachedProc.Clone()
+				p.processCache[pid] = cachedProc
+			}
+			cachedProc.Terminated = true
+			cachedProc.TerminatedAt = now
+		} else {
 			delete(p.processCache, pid)
-			
-			// Generate terminated event
-			p.queueEvent(ProcessEvent{
-				Type:      ProcessTerminated,
-				Process:   cachedProc.Clone(),
-				Timestamp: time.Now(),
-			})
 		}
+		
+		// Generate terminated event
+		p.queueEvent(ProcessEvent{
+			Type:            ProcessTerminated,
+			Process:         p.eventProcess(cachedProc),
+			Timestamp:       now,
+			HostMemoryTotal: memory.total,
+			HostMemoryUsed:  memory.used,
+		})
 	}
 	
 	// Check for new and updated processes
 	for pid, newProc := range newProcessMap {
 		cachedProc, exists := p.processCache[pid]
 		
+		// A PID seen again during the grace period belongs to a new process
+		if exists && cachedProc.Terminated {
+			exists = false
+		}
+		
+		// The OS recycled the PID, report the old process as terminated
+		// before the new one is created
+		if exists && isPIDReused(cachedProc, newProc) {
+			terminated++
+			delete(p.processCache, pid)
+			p.unindexChild(cachedProc.PPID, pid)
+			
+			p.queueEvent(ProcessEvent{
+				Type:            ProcessTerminated,
+				Process:         p.eventProcess(cachedProc),
+				Timestamp:       now,
+				HostMemoryTotal: memory.total,
+				HostMemoryUsed:  memory.used,
+			})
+			exists = false
+		}
+		
 		if !exists {
 			// New process
 			created++
-			p.processCache[pid] = newProc.Clone()
+			p.processCache[pid] = p.cachedProcess(newProc)
+			p.indexChild(newProc.PPID, pid)
 			
 			// Generate created event
 			p.queueEvent(ProcessEvent{
-				Type:      ProcessCreated,
-				Process:   newProc.Clone(),
-				Timestamp: time.Now(),
+				Type:            ProcessCreated,
+				Process:         p.eventProcess(newProc),
+				Timestamp:       time.Now(),
+				HostMemoryTotal: memory.total,
+				HostMemoryUsed:  memory.used,
 			})
 		} else {
 			// Existing process, check if it has changed
-			if !cachedProc.Equal(newProc) {
+			if p.updates.changed(cachedProc, newProc) {
 				updated++
-				p.processCache[pid] = newProc.Clone()
+				p.processCache[pid] = p.cachedProcess(newProc)
+				
+				// Orphans are re-parented, keep the tree in sync
+				if cachedProc.PPID != newProc.PPID {
+					p.unindexChild(cachedProc.PPID, pid)
+					p.indexChild(newProc.PPID, pid)
+				}
+				
+				// The binary of a running process was modified
+				executableChanged := cachedProc.ExecutableHash != "" && newProc.ExecutableHash != "" &&
+					cachedProc.ExecutableHash != newProc.ExecutableHash
+				
+				// Degraded scanners only report updates of busy processes,
+				// and executable changes
+				if newProc.CPU < minCPU && !executableChanged {
+					p.metrics.IncrementCounter(MetricEventsFiltered, 1)
+					continue
+				}
 				
 				// Generate updated event
-				p.queueEvent(ProcessEvent{
-					Type:      ProcessUpdated,
-					Process:   newProc.Clone(),
-					Timestamp: time.Now(),
-				})
+				event := ProcessEvent{
+					Type:              ProcessUpdated,
+					Process:           p.eventProcess(newProc),
+					Timestamp:         time.Now(),
+					HostMemoryTotal:   memory.total,
+					HostMemoryUsed:    memory.used,
+					ExecutableChanged: executableChanged,
+				}
+				
+				// Coalesced updates carry the change they replace
+				if p.coalescer != nil {
+					event.Delta, _ = CalculateDelta(newProc, cachedProc)
+				}
+				
+				p.queueEvent(event)
 			}
 		}
 	}
 	
-	return len(p.processCache), created, updated, terminated
+	p.lastDelta = scanDelta{created: created, updated: updated, terminated: terminated}
+	
+	return p.liveProcessCount(), created, updated, terminated
+}
+
+// cachedProcess returns the copy of a collected process to cache. Collected
+// processes are not reused by the platform, so shared ones are cached as is
+func (p *ProcessScanner) cachedProcess(proc *ProcessInfo) *ProcessInfo {
+	if p.config.ShareProcessPointers {
+		return proc
+	}
+	return proc.Clone()
+}
+
+// eventProcess returns the process attached to an event. Shared processes
+// are the cached ones, the dispatcher copies them for mutable consumers
+func (p *ProcessScanner) eventProcess(proc *ProcessInfo) *ProcessInfo {
+	if p.config.ShareProcessPointers {
+		return proc
+	}
+	return proc.Clone()
+}
+
+// isPIDReused reports whether two processes with the same PID are different
+// processes. Start times are authoritative, the executable is only compared
+// when a start time is missing since exec() changes it within one process
+func isPIDReused(cached, current *ProcessInfo) bool {
+	if !cached.StartTime.IsZero() && !current.StartTime.IsZero() {
+		return !cached.StartTime.Equal(current.StartTime)
+	}
+	return cached.Executable != "" && current.Executable != "" && cached.Executable != current.Executable
+}
+
+// liveProcessCount returns the number of cached processes that are not
+// terminated. Caller must hold cacheMutex
+func (p *ProcessScanner) liveProcessCount() int {
+	if p.config.TerminationGracePeriod <= 0 {
+		return len(p.processCache)
+	}
+	
+	count := 0
+	for _, proc := range p.processCache {
+		if !proc.Terminated {
+			count++
+		}
+	}
+	return count
+}
+
+// indexChild records pid as a child of ppid. Caller must hold cacheMutex
+func (p *ProcessScanner) indexChild(ppid, pid int) {
+	children, exists := p.childIndex[ppid]
+	if !exists {
+		children = make(map[int]struct{})
+		p.childIndex[ppid] = children
+	}
+	children[pid] = struct{}{}
+}
+
+// unindexChild removes pid from the children of ppid. Caller must hold cacheMutex
+func (p *ProcessScanner) unindexChild(ppid, pid int) {
+	children, exists := p.childIndex[ppid]
+	if !exists {
+		return
+	}
+	delete(children, pid)
+	if len(children) == 0 {
+		delete(p.childIndex, ppid)
+	}
 }
 
-// queueEvent adds an event to the event channel
+// queueEvent adds an event to the event channel according to the backpressure policy
 func (p *ProcessScanner) queueEvent(event ProcessEvent) {
+	switch p.backpressurePolicy() {
+	case BackpressureBlock:
+		p.queueEventBlocking(event)
+	case BackpressureDropOldest:
+		p.queueEventDropOldest(event)
+	default:
+		p.queueEventDrop(event)
+	}
+}
+
+// queueEventDrop drops the event if the channel stays full for 100ms
+func (p *ProcessScanner) queueEventDrop(event ProcessEvent) {
 	// Non-blocking send to event channel with timeout
 	select {
 	case p.eventChannel <- event:
@@ -415,10 +985,59 @@ func (p *ProcessScanner) queueEvent(event ProcessEvent) {
 	case <-time.After(100 * time.Millisecond):
 		// Channel is full or blocked
 		p.metrics.IncrementCounter(MetricNotificationErrors, 1)
-		fmt.Printf("AgentDiagEvent: Event channel full, dropping event for PID %d\n", event.Process.PID)
+		p.metrics.IncrementCounter(MetricEventsDropped, 1)
+		p.diagnostics.Emit(newDiagEvent(DiagEventDropped, DiagSeverityWarning,
+			map[string]interface{}{"pid": event.Process.PID},
+			"Event channel full, dropping event for PID %d", event.Process.PID))
+	}
+}
+
+// queueEventBlocking waits until the event is queued or the scanner is stopped
+func (p *ProcessScanner) queueEventBlocking(event ProcessEvent) {
+	// Scanner not initialized, nothing can cancel the wait
+	if p.ctx == nil {
+		p.eventChannel <- event
+		return
+	}
+	
+	select {
+	case p.eventChannel <- event:
+		// Event queued successfully
+	case <-p.ctx.Done():
+		p.metrics.IncrementCounter(MetricEventsDropped, 1)
 	}
 }
 
+// queueEventDropOldest evicts the oldest queued events until the new one fits
+func (p *ProcessScanner) queueEventDropOldest(event ProcessEvent) {
+	for {
+		select {
+		case p.eventChannel <- event:
+			return
+		default:
+		}
+		
+		// Channel is full, evict the head. The event processor may have
+		// drained it in the meantime, in which case nothing is evicted
+		select {
+		case evicted := <-p.eventChannel:
+			p.metrics.IncrementCounter(MetricEventsEvicted, 1)
+			p.diagnostics.Emit(newDiagEvent(DiagEventEvicted, DiagSeverityWarning,
+				map[string]interface{}{"pid": evicted.Process.PID},
+				"Event channel full, evicting event for PID %d", evicted.Process.PID))
+		default:
+		}
+	}
+}
+
+// backpressurePolicy returns the effective backpressure policy
+func (p *ProcessScanner) backpressurePolicy() BackpressurePolicy {
+	if p.config.BackpressurePolicy == "" {
+		return BackpressureDrop
+	}
+	return p.config.BackpressurePolicy
+}
+
 // processEvents handles events from the event channel
 func (p *ProcessScanner) processEvents() {
 	defer p.wg.Done()
@@ -428,27 +1047,87 @@ func (p *ProcessScanner) processEvents() {
 		batchSize = 100
 	}
 	
+	// Async consumer workers stop with the event processor
+	defer p.dispatcher.stop()
+	
+	// Fires when the earliest coalesced update is due, nil when none is held.
+	// Updates held when the processor stopped are due after a restart
+	var coalesceTimer <-chan time.Time
+	
 	for {
+		if p.coalescer != nil && coalesceTimer == nil {
+			if wait, ok := p.coalescer.next(time.Now()); ok {
+				coalesceTimer = time.After(wait)
+			}
+		}
+		
 		select {
 		case <-p.ctx.Done():
 			return
 		case event := <-p.eventChannel:
-			// Process the event
-			errors := p.registry.NotifyAll(event)
-			if len(errors) > 0 {
-				p.metrics.IncrementCounter(MetricNotificationErrors, int64(len(errors)))
-				for _, err := range errors {
-					fmt.Printf("AgentDiagEvent: Error notifying consumers: %v\n", err)
+			p.receiveEvent(event)
+		case <-coalesceTimer:
+			coalesceTimer = nil
+			for _, event := range p.coalescer.due(time.Now()) {
+				p.dispatchEvent(event)
+			}
+		case done := <-p.flushChannel:
+			// Dispatch everything queued before the flush was requested
+			for drained := false; !drained; {
+				select {
+				case event := <-p.eventChannel:
+					p.receiveEvent(event)
+				default:
+					drained = true
 				}
 			}
+			if p.coalescer != nil {
+				for _, event := range p.coalescer.flush() {
+					p.dispatchEvent(event)
+				}
+			}
+			close(done)
 		}
 	}
 }
 
-// adjustScanInterval modifies the scan interval based on CPU usage
+// receiveEvent dispatches an event taken from the event channel, or holds
+// it in the coalescer when updates are coalesced
+func (p *ProcessScanner) receiveEvent(event ProcessEvent) {
+	if p.coalescer == nil {
+		p.dispatchEvent(event)
+		return
+	}
+	
+	events, merged := p.coalescer.add(event, time.Now())
+	if merged {
+		p.metrics.IncrementCounter(MetricEventsCoalesced, 1)
+	}
+	for _, event := range events {
+		p.dispatchEvent(event)
+	}
+}
+
+// dispatchEvent delivers a single event to the consumers
+func (p *ProcessScanner) dispatchEvent(event ProcessEvent) {
+	errors := p.dispatcher.dispatch(event)
+	if len(errors) > 0 {
+		p.metrics.IncrementCounter(MetricNotificationErrors, int64(len(errors)))
+		for _, err := range errors {
+			p.diagnostics.Emit(newDiagEvent(DiagConsumerError, DiagSeverityWarning,
+				map[string]interface{}{"error": err.Error()},
+				"Error notifying consumers: %v", err))
+		}
+	}
+}
+
+// adjustScanInterval modifies the scan interval based on CPU usage. It slows
+// down while over MaxCPUUsage and decays back to the configured interval once
+// usage is well below it. Uses its own mutex since Stop holds scannerMutex
+// while waiting for the scan loop
 func (p *ProcessScanner) adjustScanInterval(cpuPct float64) {
-	p.scannerMutex.Lock()
-	defer p.scannerMutex.Unlock()
+	p.intervalMutex.Lock()
+	defer p.intervalMutex.Unlock()
 	
 	if !p.config.AdaptiveSampling || p.scanTicker == nil {
 		return
@@ -471,26 +1150,28 @@ func (p *ProcessScanner) adjustScanInterval(cpuPct float64) {
 		
 		if newInterval != currentInterval {
 			p.metrics.IncrementCounter(MetricAdaptiveRateChanges, 1)
-			fmt.Printf("AgentDiagEvent: Increasing scan interval from %v to %v due to high CPU usage (%.2f%%)\n",
-				currentInterval, newInterval, cpuPct)
+			p.diagnostics.Emit(newDiagEvent(DiagScanIntervalChanged, DiagSeverityInfo,
+				map[string]interface{}{"from": currentInterval, "to": newInterval, "cpu_percent": cpuPct},
+				"Increasing scan interval from %v to %v due to high CPU usage (%.2f%%)", currentInterval, newInterval, cpuPct))
 			
 			p.scanTicker.Reset(newInterval)
 			p.config.ScanInterval = newInterval
 		}
-	} else if ratio < 0.5 && currentInterval > time.Second*10 {
-		// CPU usage well below target and current interval is longer than default,
-		// decrease interval (speed up) to approach target
+	} else if baseInterval := p.degradedInterval(); ratio < 0.5 && currentInterval > baseInterval {
+		// CPU usage well below target and current interval is longer than
+		// configured, decrease interval (speed up) to approach the baseline
 		newInterval := time.Duration(float64(currentInterval) * 0.8)
 		
-		// Don't go below the original configured interval
-		if newInterval < time.Second*10 {
-			newInterval = time.Second * 10
+		// Don't go below the configured interval, scaled by the degradation level
+		if newInterval < baseInterval {
+			newInterval = baseInterval
 		}
 		
 		if newInterval != currentInterval {
 			p.metrics.IncrementCounter(MetricAdaptiveRateChanges, 1)
-			fmt.Printf("AgentDiagEvent: Decreasing scan interval from %v to %v due to low CPU usage (%.2f%%)\n",
-				currentInterval, newInterval, cpuPct)
+			p.diagnostics.Emit(newDiagEvent(DiagScanIntervalChanged, DiagSeverityInfo,
+				map[string]interface{}{"from": currentInterval, "to": newInterval, "cpu_percent": cpuPct},
+				"Decreasing scan interval from %v to %v due to low CPU usage (%.2f%%)", currentInterval, newInterval, cpuPct))
 			
 			p.scanTicker.Reset(newInterval)
 			p.config.ScanInterval = newInterval
@@ -508,7 +1189,215 @@ func (p *ProcessScanner) ForceScan() error {
 	return nil
 }
 
-// GetCachedProcesses returns a copy of the current process cache
+// ForceScanSync performs an immediate scan and returns once the cache is
+// updated and its events were delivered to the consumers, or the context is
+// done. In async consumer mode, delivery means queued to each consumer.
+// A scan interrupted by the context completes in the background
+func (p *ProcessScanner) ForceScanSync(ctx context.Context) error {
+	p.scannerMutex.RLock()
+	status, scannerCtx := p.status, p.ctx
+	p.scannerMutex.RUnlock()
+	
+	if status != StatusRunning {
+		return fmt.Errorf("scanner not running")
+	}
+	
+	result := make(chan error, 1)
+	go func() {
+		result <- p.performScan()
+	}()
+	
+	select {
+	case err := <-result:
+		if err != nil {
+			return err
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	
+	return p.flushEvents(ctx, scannerCtx)
+}
+
+// flushEvents waits until the event processor has dispatched all events
+// queued so far. scannerCtx is the context of the running event processor
+func (p *ProcessScanner) flushEvents(ctx context.Context, scannerCtx context.Context) error {
+	done := make(chan struct{})
+	
+	select {
+	case p.flushChannel <- done:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-scannerCtx.Done():
+		return fmt.Errorf("scanner stopped")
+	}
+	
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ScanOptions are filters applied by ScanOnce on top of the configured filters
+type ScanOptions struct {
+	// IncludePatterns are patterns a process must match, on command or name,
+	// in the configured PatternSyntax
+	IncludePatterns []string
+	
+	// ExcludePatterns are patterns for processes to drop, on command or name
+	ExcludePatterns []string
+	
+	// MinCPU is the minimum CPU percentage of returned processes
+	MinCPU float64
+	
+	// MinRSS is the minimum resident set size in bytes of returned processes
+	MinRSS int64
+}
+
+// ScanOnce performs a single collection and returns the processes matching both
+// the configured filters and opts. It does not touch the cache or emit events,
+// and can be called whether or not the scanner is running
+func (p *ProcessScanner) ScanOnce(ctx context.Context, opts ScanOptions) ([]*ProcessInfo, error) {
+	if p.platformCollector == nil {
+		return nil, fmt.Errorf("scanner not initialized")
+	}
+	
+	includeRegexps, err := compilePatterns(opts.IncludePatterns, p.config.PatternSyntax)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern %w", err)
+	}
+	excludeRegexps, err := compilePatterns(opts.ExcludePatterns, p.config.PatternSyntax)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exclude pattern %w", err)
+	}
+	
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	
+	processes, err := p.platformCollector.GetProcesses()
+	if err != nil {
+		return nil, fmt.Errorf("error scanning processes: %w", err)
+	}
+	
+	// The collection itself cannot be interrupted, drop the result instead
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	
+	var matched []*ProcessInfo
+	for _, proc := range p.filterProcesses(processes) {
+		if proc.CPU < opts.MinCPU || proc.RSS < opts.MinRSS {
+			continue
+		}
+		if matchesAny(excludeRegexps, proc) {
+			continue
+		}
+		if len(includeRegexps) > 0 && !matchesAny(includeRegexps, proc) {
+			continue
+		}
+		matched = append(matched, proc)
+	}
+	
+	return matched, nil
+}
+
+// compilePatterns compiles a list of regex or glob patterns. Globs are
+// translated to anchored regexes so both share the same matching
+func compilePatterns(patterns []string, syntax PatternSyntax) ([]*regexp.Regexp, error) {
+	regexps := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		expr := pattern
+		if syntax == PatternSyntaxGlob {
+			var err error
+			expr, err = globToRegexp(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("'%s': %w", pattern, err)
+			}
+		}
+		
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("'%s': %w", pattern, err)
+		}
+		regexps = append(regexps, re)
+	}
+	return regexps, nil
+}
+
+// updateComparison selects the changes of a cached process that emit an
+// updated event
+type updateComparison struct {
+	ignore     []Field // Fields not compared by EqualExcept
+	cpuEpsilon float64 // Smallest CPU change, CPU is in ignore when positive
+	rssDelta   int64   // Smallest RSS change, RSS is in ignore when positive
+}
+
+// newUpdateComparison builds the comparison configured by UpdateFields,
+// UpdateCPUEpsilon and UpdateRSSDelta
+func newUpdateComparison(config ProcessScannerConfig) updateComparison {
+	tracked := make(map[Field]bool, len(AllFields))
+	for _, field := range config.UpdateFields {
+		tracked[field] = true
+	}
+	
+	var c updateComparison
+	for _, field := range AllFields {
+		switch {
+		case len(config.UpdateFields) > 0 && !tracked[field]:
+			c.ignore = append(c.ignore, field)
+		case field == FieldCPU && config.UpdateCPUEpsilon > 0:
+			c.ignore = append(c.ignore, field)
+			c.cpuEpsilon = config.UpdateCPUEpsilon
+		case field == FieldRSS && config.UpdateRSSDelta > 0:
+			c.ignore = append(c.ignore, field)
+			c.rssDelta = config.UpdateRSSDelta
+		}
+	}
+	return c
+}
+
+// changed reports whether a process changed enough since it was cached to
+// emit an updated event
+func (c updateComparison) changed(cached, current *ProcessInfo) bool {
+	if !cached.EqualExcept(current, c.ignore...) {
+		return true
+	}
+	
+	if c.cpuEpsilon > 0 && math.Abs(current.CPU-cached.CPU) >= c.cpuEpsilon {
+		return true
+	}
+	
+	rssChange := current.RSS - cached.RSS
+	return c.rssDelta > 0 && (rssChange >= c.rssDelta || -rssChange >= c.rssDelta)
+}
+
+// userSet converts a list of user names into a set, nil when empty
+func userSet(users []string) map[string]struct{} {
+	if len(users) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{}, len(users))
+	for _, user := range users {
+		set[user] = struct{}{}
+	}
+	return set
+}
+
+// matchesAny reports whether the command or name of a process matches any pattern
+func matchesAny(regexps []*regexp.Regexp, proc *ProcessInfo) bool {
+	for _, re := range regexps {
+		if re.MatchString(proc.Command) || re.MatchString(proc.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCachedProcesses returns a copy of the current process cache, including
+// terminated processes still within the grace period
 func (p *ProcessScanner) GetCachedProcesses() []*ProcessInfo {
 	p.cacheMutex.RLock()
 	defer p.cacheMutex.RUnlock()
@@ -521,7 +1410,26 @@ func (p *ProcessScanner) GetCachedProcesses() []*ProcessInfo {
 	return processes
 }
 
-// GetCachedProcess returns a specific process from the cache
+// GetProcessCount returns the number of live processes in the cache,
+// excluding terminated processes kept for the grace period
+func (p *ProcessScanner) GetProcessCount() int {
+	p.cacheMutex.RLock()
+	defer p.cacheMutex.RUnlock()
+	
+	return p.liveProcessCount()
+}
+
+// GetScanDelta returns the number of processes created, updated and
+// terminated by the most recent scan, unlike the cumulative counters of Metrics
+func (p *ProcessScanner) GetScanDelta() (created, updated, terminated int) {
+	p.cacheMutex.RLock()
+	defer p.cacheMutex.RUnlock()
+	
+	return p.lastDelta.created, p.lastDelta.updated, p.lastDelta.terminated
+}
+
+// GetCachedProcess returns a specific process from the cache. Terminated
+// processes are returned with Terminated set until the grace period elapses
 func (p *ProcessScanner) GetCachedProcess(pid int) (*ProcessInfo, bool) {
 	p.cacheMutex.RLock()
 	defer p.cacheMutex.RUnlock()
@@ -533,3 +1441,59 @@ func (p *ProcessScanner) GetCachedProcess(pid int) (*ProcessInfo, bool) {
 	
 	return proc.Clone(), true
 }
+
+// GetProcessTree returns the PPID to child PIDs mapping of the cached processes.
+// Child PIDs are sorted in ascending order
+func (p *ProcessScanner) GetProcessTree() map[int][]int {
+	p.cacheMutex.RLock()
+	defer p.cacheMutex.RUnlock()
+	
+	tree := make(map[int][]int, len(p.childIndex))
+	for ppid, children := range p.childIndex {
+		pids := make([]int, 0, len(children))
+		for pid := range children {
+			pids = append(pids, pid)
+		}
+		sort.Ints(pids)
+		tree[ppid] = pids
+	}
+	
+	return tree
+}
+
+// GetDescendants returns copies of all cached descendants of a process,
+// in breadth-first order. The process itself is not included
+func (p *ProcessScanner) GetDescendants(pid int) []*ProcessInfo {
+	p.cacheMutex.RLock()
+	defer p.cacheMutex.RUnlock()
+	
+	var descendants []*ProcessInfo
+	
+	// PID 0 can be its own parent, track visited PIDs to avoid cycles
+	visited := map[int]bool{pid: true}
+	queue := []int{pid}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		
+		children := make([]int, 0, len(p.childIndex[current]))
+		for child := range p.childIndex[current] {
+			children = append(children, child)
+		}
+		sort.Ints(children)
+		
+		for _, child := range children {
+			if visited[child] {
+				continue
+			}
+			visited[child] = true
+			queue = append(queue, child)
+			
+			if proc, exists := p.processCache[child]; exists {
+				descendants = append(descendants, proc.Clone())
+			}
+		}
+	}
+	
+	return descendants
+}
@@ -9,13 +9,11 @@ package collector
 import (
 	"context"
 	"fmt"
-	"regexp"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/newrelic/infrastructure-agent/collector/platform"
-	"github.com/newrelic/infrastructure-agent/internal/agent/diagnostics"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -31,6 +29,26 @@ func (m *MockPlatformCollector) GetProcesses() ([]*ProcessInfo, error) {
 	return args.Get(0).([]*ProcessInfo), args.Error(1)
 }
 
+func (m *MockPlatformCollector) GetProcess(pid int) (*ProcessInfo, error) {
+	args := m.Called(pid)
+	return args.Get(0).(*ProcessInfo), args.Error(1)
+}
+
+func (m *MockPlatformCollector) IsProcessRunning(pid int) bool {
+	args := m.Called(pid)
+	return args.Bool(0)
+}
+
+func (m *MockPlatformCollector) GetProcessCount() (int, error) {
+	args := m.Called()
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockPlatformCollector) GetMemoryStats() (uint64, uint64, error) {
+	args := m.Called()
+	return args.Get(0).(uint64), args.Get(1).(uint64), args.Error(2)
+}
+
 func (m *MockPlatformCollector) GetCPUTimes() error {
 	args := m.Called()
 	return args.Error(0)
@@ -41,6 +59,11 @@ func (m *MockPlatformCollector) GetSelfUsage() (float64, uint64, error) {
 	return args.Get(0).(float64), args.Get(1).(uint64), args.Error(2)
 }
 
+func (m *MockPlatformCollector) Capabilities() ProcessCapabilities {
+	args := m.Called()
+	return args.Get(0).(ProcessCapabilities)
+}
+
 func (m *MockPlatformCollector) Shutdown() error {
 	args := m.Called()
 	return args.Error(0)
@@ -76,33 +99,8 @@ func (m *MockProcessConsumer) GetReceivedEvents() []ProcessEvent {
 
 // Helper function to initialize scanner with mock for tests
 func initProcessScannerWithMock(t *testing.T, scanner *ProcessScanner, mockCollector platform.ProcessCollector) {
-	ctx := context.Background()
-	
-	// Create a derived context
-	scanner.ctx, scanner.cancel = context.WithCancel(ctx)
-	
-	// Use the provided mock collector
-	scanner.platformCollector = mockCollector
-	
-	// Compile any patterns
-	scanner.excludeRegexps = make([]*regexp.Regexp, 0, len(scanner.config.ExcludePatterns))
-	for _, pattern := range scanner.config.ExcludePatterns {
-		re, err := regexp.Compile(pattern)
-		require.NoError(t, err)
-		scanner.excludeRegexps = append(scanner.excludeRegexps, re)
-	}
-	
-	scanner.includeRegexps = make([]*regexp.Regexp, 0, len(scanner.config.IncludePatterns))
-	for _, pattern := range scanner.config.IncludePatterns {
-		re, err := regexp.Compile(pattern)
-		require.NoError(t, err)
-		scanner.includeRegexps = append(scanner.includeRegexps, re)
-	}
-	
-	// Set status to initialized
-	scanner.statusLock.Lock()
-	scanner.status = StatusInitialized
-	scanner.statusLock.Unlock()
+	err := scanner.Init(context.Background(), map[string]interface{}{"mockCollector": mockCollector})
+	require.NoError(t, err)
 }
 
 // Test helpers
@@ -138,6 +136,7 @@ func TestProcessScanner_WithMockedCollector(t *testing.T) {
 	// Configure mock behaviors
 	mockCollector.On("GetProcesses").Return(testProcesses, nil)
 	mockCollector.On("GetCPUTimes").Return(nil)
+	mockCollector.On("GetMemoryStats").Return(uint64(0), uint64(0), nil)
 	mockCollector.On("GetSelfUsage").Return(0.3, uint64(20*1024*1024), nil)
 	mockCollector.On("Shutdown").Return(nil)
 	
@@ -155,15 +154,16 @@ func TestProcessScanner_WithMockedCollector(t *testing.T) {
 	mockConsumer.On("OnProcessEvent", mock.Anything).Return(nil)
 	
 	// Register consumer
-	err := scanner.RegisterConsumer("test-consumer", mockConsumer)
+	err := scanner.RegisterConsumer("test-consumer", AdaptLegacyConsumer(mockConsumer))
 	require.NoError(t, err)
 	
 	// Start scanner 
 	err = scanner.Start()
 	require.NoError(t, err)
 	
-	// Allow scanner to run
-	time.Sleep(200 * time.Millisecond)
+	// Wait for a scan and the delivery of its events
+	err = scanner.ForceScanSync(context.Background())
+	require.NoError(t, err)
 	
 	// Verify expected calls
 	mockCollector.AssertCalled(t, "GetProcesses")
@@ -220,6 +220,7 @@ func TestProcessScanner_LifecycleEvents(t *testing.T) {
 	mockCollector.On("GetProcesses").Return(initialProcesses, nil).Once()
 	mockCollector.On("GetProcesses").Return(updatedProcesses, nil).Once()
 	mockCollector.On("GetCPUTimes").Return(nil)
+	mockCollector.On("GetMemoryStats").Return(uint64(0), uint64(0), nil)
 	mockCollector.On("GetSelfUsage").Return(0.2, uint64(15*1024*1024), nil)
 	mockCollector.On("Shutdown").Return(nil)
 	
@@ -237,7 +238,7 @@ func TestProcessScanner_LifecycleEvents(t *testing.T) {
 	mockConsumer.On("OnProcessEvent", mock.Anything).Return(nil)
 	
 	// Register consumer
-	err := scanner.RegisterConsumer("test-consumer", mockConsumer)
+	err := scanner.RegisterConsumer("test-consumer", AdaptLegacyConsumer(mockConsumer))
 	require.NoError(t, err)
 	
 	// Start scanner 
@@ -323,6 +324,7 @@ func TestProcessScanner_FilteringWithPatterns(t *testing.T) {
 	// Configure mock behaviors
 	mockCollector.On("GetProcesses").Return(testProcesses, nil)
 	mockCollector.On("GetCPUTimes").Return(nil)
+	mockCollector.On("GetMemoryStats").Return(uint64(0), uint64(0), nil)
 	mockCollector.On("GetSelfUsage").Return(0.3, uint64(20*1024*1024), nil)
 	mockCollector.On("Shutdown").Return(nil)
 	
@@ -342,15 +344,16 @@ func TestProcessScanner_FilteringWithPatterns(t *testing.T) {
 	mockConsumer.On("OnProcessEvent", mock.Anything).Return(nil)
 	
 	// Register consumer
-	err := scanner.RegisterConsumer("test-consumer", mockConsumer)
+	err := scanner.RegisterConsumer("test-consumer", AdaptLegacyConsumer(mockConsumer))
 	require.NoError(t, err)
 	
 	// Start scanner 
 	err = scanner.Start()
 	require.NoError(t, err)
 	
-	// Allow scanner to run
-	time.Sleep(200 * time.Millisecond)
+	// Wait for a scan and the delivery of its events
+	err = scanner.ForceScanSync(context.Background())
+	require.NoError(t, err)
 	
 	// Stop scanner
 	err = scanner.Stop()
@@ -409,6 +412,7 @@ func TestProcessScanner_ErrorHandling(t *testing.T) {
 	mockCollector.On("GetProcesses").Return(testProcesses, nil).Once()
 	
 	mockCollector.On("GetCPUTimes").Return(nil)
+	mockCollector.On("GetMemoryStats").Return(uint64(0), uint64(0), nil)
 	mockCollector.On("GetSelfUsage").Return(0.1, uint64(10*1024*1024), nil)
 	mockCollector.On("Shutdown").Return(nil)
 	
@@ -421,7 +425,7 @@ func TestProcessScanner_ErrorHandling(t *testing.T) {
 		ScanInterval: 100 * time.Millisecond,
 	}
 	scanner := NewProcessScanner(config)
-	scanner.diagnostics = mockDiagnostics
+	scanner.SetDiagnosticSink(mockDiagnostics)
 	
 	// Initialize scanner with mock
 	initProcessScannerWithMock(t, scanner, mockCollector)
@@ -431,7 +435,7 @@ func TestProcessScanner_ErrorHandling(t *testing.T) {
 	mockConsumer.On("OnProcessEvent", mock.Anything).Return(nil)
 	
 	// Register consumer
-	err := scanner.RegisterConsumer("test-consumer", mockConsumer)
+	err := scanner.RegisterConsumer("test-consumer", AdaptLegacyConsumer(mockConsumer))
 	require.NoError(t, err)
 	
 	// Start scanner 
@@ -477,6 +481,7 @@ func TestProcessScanner_ResourceManagement(t *testing.T) {
 	mockCollector.On("GetSelfUsage").Return(1.0, uint64(10*1024*1024), nil).Once()
 	
 	mockCollector.On("GetCPUTimes").Return(nil)
+	mockCollector.On("GetMemoryStats").Return(uint64(0), uint64(0), nil)
 	mockCollector.On("Shutdown").Return(nil)
 	
 	// Create scanner with adaptive sampling config
@@ -514,6 +519,10 @@ type MockDiagnosticsService struct {
 	mock.Mock
 }
 
+func (m *MockDiagnosticsService) Emit(event DiagEvent) {
+	m.EmitEvent(event.Component, string(event.Type), event.Message)
+}
+
 func (m *MockDiagnosticsService) EmitEvent(component, eventType string, description string) {
 	m.Called(component, eventType, description)
 }
\ No newline at end of file
@@ -3,6 +3,7 @@ package collector
 import (
 	"context"
 	"fmt"
+	"math"
 	"sync"
 	"testing"
 	"time"
@@ -27,11 +28,8 @@ func (m *MockProcessConsumer) HandleProcessEvent(event ProcessEvent) error {
 	defer m.eventsMux.Unlock()
 	
 	// Store a copy of the event
-	eventCopy := ProcessEvent{
-		Type:      event.Type,
-		Process:   event.Process.Clone(),
-		Timestamp: event.Timestamp,
-	}
+	eventCopy := event
+	eventCopy.Process = event.Process.Clone()
 	
 	m.events = append(m.events, eventCopy)
 	return nil
@@ -139,7 +137,7 @@ func TestProcessScanner_StartStop(t *testing.T) {
 	scanner := NewProcessScanner(config)
 	
 	// Initialize scanner
-	err := scanner.Init(context.Background())
+	err := scanner.Init(context.Background(), nil)
 	if err != nil {
 		t.Fatalf("Failed to initialize scanner: %v", err)
 	}
@@ -200,7 +198,7 @@ func TestProcessScanner_ProcessEvents(t *testing.T) {
 	consumer := NewMockProcessConsumer()
 	
 	// Initialize scanner
-	err := scanner.Init(context.Background())
+	err := scanner.Init(context.Background(), nil)
 	if err != nil {
 		t.Fatalf("Failed to initialize scanner: %v", err)
 	}
@@ -274,7 +272,7 @@ func TestProcessScanner_Metrics(t *testing.T) {
 	scanner := NewProcessScanner(config)
 	
 	// Initialize scanner
-	err := scanner.Init(context.Background())
+	err := scanner.Init(context.Background(), nil)
 	if err != nil {
 		t.Fatalf("Failed to initialize scanner: %v", err)
 	}
@@ -334,7 +332,7 @@ func TestProcessScanner_AdaptiveSampling(t *testing.T) {
 	scanner := NewProcessScanner(config)
 	
 	// Initialize scanner
-	err := scanner.Init(context.Background())
+	err := scanner.Init(context.Background(), nil)
 	if err != nil {
 		t.Fatalf("Failed to initialize scanner: %v", err)
 	}
@@ -352,9 +350,12 @@ func TestProcessScanner_AdaptiveSampling(t *testing.T) {
 	p := scanner.(*ProcessScanner)
 	p.adjustScanInterval(1.0) // 1.0% CPU, 10x higher than our 0.1% limit
 	
-	// Check if the scan interval was increased
-	if p.config.ScanInterval <= time.Millisecond*100 {
-		t.Errorf("Expected scan interval to increase, but it stayed at %v", p.config.ScanInterval)
+	// Check if the scan interval was increased, the scan loop adjusts it concurrently
+	p.intervalMutex.Lock()
+	interval := p.config.ScanInterval
+	p.intervalMutex.Unlock()
+	if interval <= time.Millisecond*100 {
+		t.Errorf("Expected scan interval to increase, but it stayed at %v", interval)
 	}
 	
 	// Stop scanner
@@ -364,6 +365,50 @@ func TestProcessScanner_AdaptiveSampling(t *testing.T) {
 	}
 }
 
+func TestProcessScanner_AdaptiveSamplingRecovers(t *testing.T) {
+	fake := &fakePlatformCollector{processes: []*ProcessInfo{{PID: 1, Name: "init"}}}
+	
+	config := DefaultConfig().ProcessScanner
+	config.ScanInterval = 10 * time.Second
+	config.AdaptiveSampling = true
+	config.MaxCPUUsage = 1.0
+	scanner := NewProcessScanner(config)
+	if err := scanner.Init(context.Background(), nil); err != nil {
+		t.Fatalf("Failed to initialize scanner: %v", err)
+	}
+	scanner.platformCollector = fake
+	
+	// Scans are driven manually, the ticker only has to exist
+	scanner.scanTicker = time.NewTicker(time.Hour)
+	defer scanner.scanTicker.Stop()
+	
+	setCPU := func(cpu float64) {
+		fake.mutex.Lock()
+		fake.cpuPercent = cpu
+		fake.mutex.Unlock()
+	}
+	
+	// Spike: the interval grows
+	setCPU(3.0)
+	scanner.performScan()
+	scanner.performScan()
+	if scanner.config.ScanInterval <= config.ScanInterval {
+		t.Fatalf("Expected scan interval to increase, but it stayed at %v", scanner.config.ScanInterval)
+	}
+	
+	// Load subsides: the interval converges back to the configured one
+	setCPU(0.1)
+	for i := 0; i < 20; i++ {
+		scanner.performScan()
+	}
+	if scanner.config.ScanInterval > time.Duration(float64(config.ScanInterval)/0.8) {
+		t.Errorf("Expected scan interval within one step of %v, got %v", config.ScanInterval, scanner.config.ScanInterval)
+	}
+	if scanner.config.ScanInterval < config.ScanInterval {
+		t.Errorf("Expected scan interval not to drop below %v, got %v", config.ScanInterval, scanner.config.ScanInterval)
+	}
+}
+
 func TestProcessScanner_FilterProcesses(t *testing.T) {
 	// Create scanner with filters
 	config := DefaultConfig().ProcessScanner
@@ -372,7 +417,7 @@ func TestProcessScanner_FilterProcesses(t *testing.T) {
 	scanner := NewProcessScanner(config)
 	
 	// Initialize scanner
-	err := scanner.Init(context.Background())
+	err := scanner.Init(context.Background(), nil)
 	if err != nil {
 		t.Fatalf("Failed to initialize scanner: %v", err)
 	}
@@ -410,12 +455,73 @@ func TestProcessScanner_FilterProcesses(t *testing.T) {
 	}
 }
 
+func TestProcessScanner_FilteringWithUsers(t *testing.T) {
+	fake := &fakePlatformCollector{processes: []*ProcessInfo{
+		{PID: 1, Name: "app-api", Command: "/opt/app/api", User: "app"},
+		{PID: 2, Name: "app-worker", Command: "/opt/app/worker", User: "app"},
+		{PID: 3, Name: "systemd", Command: "/usr/lib/systemd/systemd", User: "root"},
+		{PID: 4, Name: "postgres", Command: "/usr/bin/postgres", User: "postgres"},
+		{PID: 5, Name: "app-debug", Command: "/opt/app/debug", User: "app"},
+	}}
+	
+	config := DefaultConfig().ProcessScanner
+	config.IncludeUsers = []string{"app", "root"}
+	config.ExcludeUsers = []string{"root"}
+	config.ExcludePatterns = []string{"debug"}
+	scanner := NewProcessScanner(config)
+	if err := scanner.Init(context.Background(), nil); err != nil {
+		t.Fatalf("Failed to initialize scanner: %v", err)
+	}
+	scanner.platformCollector = fake
+	
+	scanner.performScan()
+	
+	// Verify filtering results
+	pids := make(map[int]bool)
+	for _, proc := range scanner.GetCachedProcesses() {
+		pids[proc.PID] = true
+	}
+	if len(pids) != 2 || !pids[1] || !pids[2] {
+		t.Errorf("Expected processes 1 and 2 after filtering, got %v", pids)
+	}
+	
+	// Verify events match filtering
+	eventPids := make(map[int]bool)
+	for len(scanner.eventChannel) > 0 {
+		event := <-scanner.eventChannel
+		if event.Type == ProcessCreated {
+			eventPids[event.Process.PID] = true
+		}
+	}
+	if len(eventPids) != 2 || !eventPids[1] || !eventPids[2] {
+		t.Errorf("Expected created events for processes 1 and 2, got %v", eventPids)
+	}
+	
+	// Without an include list only excluded users are filtered
+	config.IncludeUsers = nil
+	config.ExcludePatterns = nil
+	scanner = NewProcessScanner(config)
+	if err := scanner.Init(context.Background(), nil); err != nil {
+		t.Fatalf("Failed to initialize scanner: %v", err)
+	}
+	
+	filtered := scanner.filterProcesses(fake.processes)
+	if len(filtered) != 4 {
+		t.Errorf("Expected 4 processes after filtering, got %d", len(filtered))
+	}
+	for _, proc := range filtered {
+		if proc.User == "root" {
+			t.Errorf("Expected processes of root to be excluded, got PID %d", proc.PID)
+		}
+	}
+}
+
 func TestProcessScanner_ProcessNewScan(t *testing.T) {
 	// Create scanner 
 	scanner := NewProcessScanner(DefaultConfig().ProcessScanner)
 	
 	// Initialize scanner
-	err := scanner.Init(context.Background())
+	err := scanner.Init(context.Background(), nil)
 	if err != nil {
 		t.Fatalf("Failed to initialize scanner: %v", err)
 	}
@@ -528,6 +634,439 @@ func TestProcessScanner_ProcessNewScan(t *testing.T) {
 	}
 }
 
+func TestProcessScanner_ProcessTree(t *testing.T) {
+	p := NewProcessScanner(DefaultConfig().ProcessScanner)
+	
+	// nginx master with two workers, one worker with a helper
+	p.processNewScan([]*ProcessInfo{
+		{PID: 1, PPID: 0, Name: "init"},
+		{PID: 10, PPID: 1, Name: "nginx"},
+		{PID: 11, PPID: 10, Name: "nginx-worker"},
+		{PID: 12, PPID: 10, Name: "nginx-worker"},
+		{PID: 13, PPID: 11, Name: "helper"},
+	})
+	
+	tree := p.GetProcessTree()
+	if len(tree[10]) != 2 || tree[10][0] != 11 || tree[10][1] != 12 {
+		t.Errorf("Expected children [11 12] for pid 10, got %v", tree[10])
+	}
+	
+	descendants := p.GetDescendants(10)
+	if len(descendants) != 3 {
+		t.Fatalf("Expected 3 descendants, got %d", len(descendants))
+	}
+	for i, pid := range []int{11, 12, 13} {
+		if descendants[i].PID != pid {
+			t.Errorf("Expected descendant %d to be pid %d, got %d", i, pid, descendants[i].PID)
+		}
+	}
+	
+	// Returned processes are copies
+	descendants[0].Name = "modified"
+	if proc, _ := p.GetCachedProcess(11); proc.Name != "nginx-worker" {
+		t.Errorf("GetDescendants returned a reference to the cache")
+	}
+	
+	// Worker 11 exits and its helper is re-parented to init
+	p.processNewScan([]*ProcessInfo{
+		{PID: 1, PPID: 0, Name: "init"},
+		{PID: 10, PPID: 1, Name: "nginx"},
+		{PID: 12, PPID: 10, Name: "nginx-worker"},
+		{PID: 13, PPID: 1, Name: "helper"},
+	})
+	
+	tree = p.GetProcessTree()
+	if len(tree[10]) != 1 || tree[10][0] != 12 {
+		t.Errorf("Expected children [12] for pid 10, got %v", tree[10])
+	}
+	if _, exists := tree[11]; exists {
+		t.Errorf("Expected no children for terminated pid 11, got %v", tree[11])
+	}
+	if len(tree[1]) != 2 || tree[1][0] != 10 || tree[1][1] != 13 {
+		t.Errorf("Expected children [10 13] for pid 1, got %v", tree[1])
+	}
+	
+	if len(p.GetDescendants(10)) != 1 {
+		t.Errorf("Expected 1 descendant after worker exit, got %d", len(p.GetDescendants(10)))
+	}
+	if len(p.GetDescendants(1)) != 3 {
+		t.Errorf("Expected 3 descendants of init, got %d", len(p.GetDescendants(1)))
+	}
+}
+
+func TestProcessScanner_BackpressurePolicy(t *testing.T) {
+	newEvent := func(pid int) ProcessEvent {
+		return ProcessEvent{Type: ProcessCreated, Process: &ProcessInfo{PID: pid}, Timestamp: time.Now()}
+	}
+	
+	config := DefaultConfig().ProcessScanner
+	config.EventChannelSize = 2
+	
+	// Drop keeps the oldest events
+	p := NewProcessScanner(config)
+	for pid := 1; pid <= 3; pid++ {
+		p.queueEvent(newEvent(pid))
+	}
+	metrics := p.Metrics()
+	if metrics[MetricEventsDropped] != 1 || metrics[MetricBackpressurePolicy+"drop"] != 1 {
+		t.Errorf("Expected 1 dropped event with drop policy, got %v", metrics)
+	}
+	if event := <-p.eventChannel; event.Process.PID != 1 {
+		t.Errorf("Expected head of channel to be pid 1, got %d", event.Process.PID)
+	}
+	
+	// DropOldest keeps the newest events
+	config.BackpressurePolicy = BackpressureDropOldest
+	p = NewProcessScanner(config)
+	for pid := 1; pid <= 5; pid++ {
+		p.queueEvent(newEvent(pid))
+	}
+	metrics = p.Metrics()
+	if metrics[MetricEventsEvicted] != 3 || metrics[MetricEventsDropped] != 0 {
+		t.Errorf("Expected 3 evicted and 0 dropped events, got %v", metrics)
+	}
+	for _, pid := range []int{4, 5} {
+		if event := <-p.eventChannel; event.Process.PID != pid {
+			t.Errorf("Expected pid %d in channel, got %d", pid, event.Process.PID)
+		}
+	}
+	
+	// Block waits for room and gives up only when the scanner stops
+	config.BackpressurePolicy = BackpressureBlock
+	p = NewProcessScanner(config)
+	ctx, cancel := context.WithCancel(context.Background())
+	p.ctx = ctx
+	p.queueEvent(newEvent(1))
+	p.queueEvent(newEvent(2))
+	
+	done := make(chan struct{})
+	go func() {
+		p.queueEvent(newEvent(3))
+		close(done)
+	}()
+	
+	select {
+	case <-done:
+		t.Fatalf("Expected queueEvent to block on a full channel")
+	case <-time.After(150 * time.Millisecond):
+	}
+	
+	// Draining one event unblocks the sender
+	<-p.eventChannel
+	<-done
+	if len(p.eventChannel) != 2 {
+		t.Errorf("Expected 2 queued events, got %d", len(p.eventChannel))
+	}
+	
+	cancel()
+	p.queueEvent(newEvent(4))
+	if p.Metrics()[MetricEventsDropped] != 1 {
+		t.Errorf("Expected 1 dropped event after cancellation")
+	}
+}
+
+func TestProcessScanner_TerminationGracePeriod(t *testing.T) {
+	config := DefaultConfig().ProcessScanner
+	config.TerminationGracePeriod = 50 * time.Millisecond
+	p := NewProcessScanner(config)
+	
+	p.processNewScan([]*ProcessInfo{
+		{PID: 1, Name: "init"},
+		{PID: 2, Name: "short-lived", RSS: 4096},
+	})
+	
+	// PID 2 exits and is kept with its final stats
+	count, _, _, terminated := p.processNewScan([]*ProcessInfo{{PID: 1, Name: "init"}})
+	if count != 1 || terminated != 1 {
+		t.Errorf("Expected 1 live and 1 terminated process, got %d and %d", count, terminated)
+	}
+	
+	proc, exists := p.GetCachedProcess(2)
+	if !exists {
+		t.Fatalf("Expected terminated process to stay cached during the grace period")
+	}
+	if !proc.Terminated || proc.TerminatedAt.IsZero() || proc.RSS != 4096 {
+		t.Errorf("Expected terminated process with final stats, got %+v", proc)
+	}
+	
+	// Later scans within the grace period do not report it again
+	_, _, _, terminated = p.processNewScan([]*ProcessInfo{{PID: 1, Name: "init"}})
+	if terminated != 0 {
+		t.Errorf("Expected no new terminations, got %d", terminated)
+	}
+	
+	time.Sleep(60 * time.Millisecond)
+	count, _, _, _ = p.processNewScan([]*ProcessInfo{{PID: 1, Name: "init"}})
+	if _, exists := p.GetCachedProcess(2); exists {
+		t.Errorf("Expected terminated process to be evicted after the grace period")
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 live process, got %d", count)
+	}
+	
+	// A reused PID within the grace period is a new process
+	p.processNewScan([]*ProcessInfo{{PID: 1, Name: "init"}, {PID: 3, Name: "old"}})
+	p.processNewScan([]*ProcessInfo{{PID: 1, Name: "init"}})
+	count, created, _, _ := p.processNewScan([]*ProcessInfo{{PID: 1, Name: "init"}, {PID: 3, Name: "new"}})
+	if count != 2 || created != 1 {
+		t.Errorf("Expected 2 live processes and 1 created, got %d and %d", count, created)
+	}
+	if proc, _ := p.GetCachedProcess(3); proc.Terminated || proc.Name != "new" {
+		t.Errorf("Expected live process 'new' for reused PID, got %+v", proc)
+	}
+}
+
+func TestProcessScanner_PIDReuse(t *testing.T) {
+	p := NewProcessScanner(DefaultConfig().ProcessScanner)
+	started := time.Now().Add(-time.Hour)
+	
+	p.processNewScan([]*ProcessInfo{
+		{PID: 100, Name: "old", Executable: "/usr/bin/old", StartTime: started, CPU: 50},
+	})
+	<-p.eventChannel
+	
+	// Same PID, different process
+	count, created, updated, terminated := p.processNewScan([]*ProcessInfo{
+		{PID: 100, Name: "new", Executable: "/usr/bin/new", StartTime: started.Add(time.Minute), CPU: 1},
+	})
+	if count != 1 || created != 1 || updated != 0 || terminated != 1 {
+		t.Errorf("Expected 1 created and 1 terminated, got created=%d updated=%d terminated=%d count=%d",
+			created, updated, terminated, count)
+	}
+	
+	// Terminate for the old process comes before create for the new one
+	first, second := <-p.eventChannel, <-p.eventChannel
+	if first.Type != ProcessTerminated || first.Process.Name != "old" {
+		t.Errorf("Expected terminated event for 'old', got %s for '%s'", first.Type, first.Process.Name)
+	}
+	if second.Type != ProcessCreated || second.Process.Name != "new" {
+		t.Errorf("Expected created event for 'new', got %s for '%s'", second.Type, second.Process.Name)
+	}
+	
+	// exec() changes the executable but keeps the start time: an update
+	_, created, updated, terminated = p.processNewScan([]*ProcessInfo{
+		{PID: 100, Name: "exec", Executable: "/usr/bin/exec", StartTime: started.Add(time.Minute), CPU: 1},
+	})
+	if created != 0 || updated != 1 || terminated != 0 {
+		t.Errorf("Expected 1 update after exec, got created=%d updated=%d terminated=%d", created, updated, terminated)
+	}
+	if event := <-p.eventChannel; event.Type != ProcessUpdated {
+		t.Errorf("Expected updated event after exec, got %s", event.Type)
+	}
+	
+	// Without start times the executable tells processes apart
+	p = NewProcessScanner(DefaultConfig().ProcessScanner)
+	p.processNewScan([]*ProcessInfo{{PID: 200, Executable: "/usr/bin/a"}})
+	_, created, _, terminated = p.processNewScan([]*ProcessInfo{{PID: 200, Executable: "/usr/bin/b"}})
+	if created != 1 || terminated != 1 {
+		t.Errorf("Expected executable change to be a PID reuse, got created=%d terminated=%d", created, terminated)
+	}
+}
+
+func TestProcessScanner_ScanOnce(t *testing.T) {
+	fake := &fakePlatformCollector{processes: []*ProcessInfo{
+		{PID: 1, Name: "systemd", Command: "/sbin/init", CPU: 0.1, RSS: 8 << 20},
+		{PID: 2, Name: "nginx", Command: "nginx: master", CPU: 2, RSS: 32 << 20},
+		{PID: 3, Name: "nginx", Command: "nginx: worker", CPU: 15, RSS: 64 << 20},
+		{PID: 4, Name: "sshd", Command: "/usr/sbin/sshd", CPU: 5, RSS: 16 << 20},
+	}}
+	
+	config := DefaultConfig().ProcessScanner
+	config.ExcludePatterns = []string{"^sshd$"}
+	scanner := NewProcessScanner(config)
+	
+	// Not initialized yet
+	if _, err := scanner.ScanOnce(context.Background(), ScanOptions{}); err == nil {
+		t.Errorf("Expected error before Init")
+	}
+	
+	if err := scanner.Init(context.Background(), nil); err != nil {
+		t.Fatalf("Failed to initialize scanner: %v", err)
+	}
+	scanner.platformCollector = fake
+	
+	tests := []struct {
+		name string
+		opts ScanOptions
+		pids []int
+	}{
+		{"configured filters only", ScanOptions{}, []int{1, 2, 3}},
+		{"include", ScanOptions{IncludePatterns: []string{"nginx"}}, []int{2, 3}},
+		{"exclude", ScanOptions{ExcludePatterns: []string{"worker"}}, []int{1, 2}},
+		{"min cpu", ScanOptions{MinCPU: 1}, []int{2, 3}},
+		{"min rss", ScanOptions{IncludePatterns: []string{"nginx"}, MinRSS: 48 << 20}, []int{3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processes, err := scanner.ScanOnce(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatalf("ScanOnce returned error: %v", err)
+			}
+			if len(processes) != len(tt.pids) {
+				t.Fatalf("Expected %d processes, got %d", len(tt.pids), len(processes))
+			}
+			for i, pid := range tt.pids {
+				if processes[i].PID != pid {
+					t.Errorf("Expected pid %d at %d, got %d", pid, i, processes[i].PID)
+				}
+			}
+		})
+	}
+	
+	// The cache and event channel are untouched
+	if len(scanner.GetCachedProcesses()) != 0 || len(scanner.eventChannel) != 0 {
+		t.Errorf("Expected ScanOnce to leave the cache and events untouched")
+	}
+	
+	if _, err := scanner.ScanOnce(context.Background(), ScanOptions{IncludePatterns: []string{"("}}); err == nil {
+		t.Errorf("Expected error for invalid pattern")
+	}
+	
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := scanner.ScanOnce(ctx, ScanOptions{}); err == nil {
+		t.Errorf("Expected error for cancelled context")
+	}
+}
+
+func TestProcessScanner_ForceScanSync(t *testing.T) {
+	fake := &fakePlatformCollector{processes: []*ProcessInfo{{PID: 1, Name: "init"}}}
+	scanner := newTestScanner(t, fake)
+	
+	if err := scanner.ForceScanSync(context.Background()); err == nil {
+		t.Errorf("Expected error when scanner is not running")
+	}
+	
+	consumer := NewMockProcessConsumer()
+	if err := scanner.RegisterConsumer("test", consumer); err != nil {
+		t.Fatalf("Failed to register consumer: %v", err)
+	}
+	if err := scanner.Start(); err != nil {
+		t.Fatalf("Failed to start scanner: %v", err)
+	}
+	defer scanner.Stop()
+	
+	fake.mutex.Lock()
+	fake.processes = append(fake.processes, &ProcessInfo{PID: 2, Name: "new"})
+	fake.mutex.Unlock()
+	
+	// The cache and the consumer are up to date on return
+	if err := scanner.ForceScanSync(context.Background()); err != nil {
+		t.Fatalf("ForceScanSync returned error: %v", err)
+	}
+	if _, ok := scanner.GetCachedProcess(2); !ok {
+		t.Errorf("Expected PID 2 in the cache")
+	}
+	created := false
+	for _, event := range consumer.GetEvents() {
+		if event.Type == ProcessCreated && event.Process.PID == 2 {
+			created = true
+		}
+	}
+	if !created {
+		t.Errorf("Expected created event for PID 2 to be delivered")
+	}
+	
+	fake.setScanError(fmt.Errorf("scan failed"))
+	if err := scanner.ForceScanSync(context.Background()); err == nil {
+		t.Errorf("Expected scan error to be returned")
+	}
+	fake.setScanError(nil)
+	
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := scanner.ForceScanSync(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// connectionFakeCollector records the connection collection toggles
+type connectionFakeCollector struct {
+	*fakePlatformCollector
+	toggles []bool
+}
+
+func (c *connectionFakeCollector) SetConnectionCollection(enabled bool) {
+	c.toggles = append(c.toggles, enabled)
+}
+
+func TestProcessScanner_ConnectionCollection(t *testing.T) {
+	fake := &connectionFakeCollector{fakePlatformCollector: &fakePlatformCollector{
+		processes: []*ProcessInfo{{PID: 1, Name: "init"}},
+	}}
+	
+	config := DefaultConfig().ProcessScanner
+	config.CollectConnections = true
+	config.ConnectionsInterval = time.Hour
+	config.AdaptiveSampling = false
+	scanner := NewProcessScanner(config)
+	if err := scanner.Init(context.Background(), nil); err != nil {
+		t.Fatalf("Failed to initialize scanner: %v", err)
+	}
+	scanner.platformCollector = fake
+	
+	// Collected on the first scan, then skipped until the interval elapses
+	scanner.performScan()
+	scanner.performScan()
+	scanner.lastConnectionScan = time.Now().Add(-2 * time.Hour)
+	scanner.performScan()
+	
+	// Skipped after a scan over the CPU budget
+	fake.mutex.Lock()
+	fake.cpuPercent = 100
+	fake.mutex.Unlock()
+	scanner.lastConnectionScan = time.Time{}
+	scanner.performScan()
+	scanner.performScan()
+	
+	expected := []bool{true, false, true, true, false}
+	if len(fake.toggles) != len(expected) {
+		t.Fatalf("Expected toggles %v, got %v", expected, fake.toggles)
+	}
+	for i := range expected {
+		if fake.toggles[i] != expected[i] {
+			t.Errorf("Expected toggles %v, got %v", expected, fake.toggles)
+			break
+		}
+	}
+	if skipped := scanner.metrics.GetCounter(MetricConnectionScansSkipped); skipped != 2 {
+		t.Errorf("Expected 2 skipped connection scans, got %d", skipped)
+	}
+}
+
+func TestProcessScanner_GetScanDelta(t *testing.T) {
+	fake := &fakePlatformCollector{processes: []*ProcessInfo{
+		{PID: 1, Name: "init"},
+		{PID: 2, Name: "sshd"},
+	}}
+	scanner := newTestScanner(t, fake)
+	
+	scanner.performScan()
+	if count := scanner.GetProcessCount(); count != 2 {
+		t.Errorf("Expected 2 processes, got %d", count)
+	}
+	if created, updated, terminated := scanner.GetScanDelta(); created != 2 || updated != 0 || terminated != 0 {
+		t.Errorf("Expected delta 2/0/0, got %d/%d/%d", created, updated, terminated)
+	}
+	
+	fake.mutex.Lock()
+	fake.processes = []*ProcessInfo{
+		{PID: 1, Name: "init", CPU: 1},
+		{PID: 3, Name: "bash"},
+		{PID: 4, Name: "vim"},
+	}
+	fake.mutex.Unlock()
+	
+	// The delta only covers the last scan
+	scanner.performScan()
+	if count := scanner.GetProcessCount(); count != 3 {
+		t.Errorf("Expected 3 processes, got %d", count)
+	}
+	if created, updated, terminated := scanner.GetScanDelta(); created != 2 || updated != 1 || terminated != 1 {
+		t.Errorf("Expected delta 2/1/1, got %d/%d/%d", created, updated, terminated)
+	}
+}
+
 func TestProcessInfo_Clone(t *testing.T) {
 	// Create a process info
 	proc := &ProcessInfo{
@@ -581,6 +1120,40 @@ func TestProcessInfo_Clone(t *testing.T) {
 	}
 }
 
+func TestProcessInfo_EqualExcept(t *testing.T) {
+	proc := &ProcessInfo{PID: 1, Name: "test", CPU: 1.0, RSS: 1024, State: "S"}
+	other := proc.Clone()
+	other.CPU = 1.5
+	
+	if proc.Equal(other) {
+		t.Errorf("Expected processes with different CPU not to be equal")
+	}
+	if !proc.EqualExcept(other, FieldCPU) {
+		t.Errorf("Expected processes to be equal ignoring CPU")
+	}
+	
+	other.State = "R"
+	if proc.EqualExcept(other, FieldCPU) {
+		t.Errorf("Expected processes with different state not to be equal ignoring CPU")
+	}
+	if !proc.EqualExcept(other, FieldCPU, FieldState) {
+		t.Errorf("Expected processes to be equal ignoring CPU and state")
+	}
+	
+	// Slices and maps are ignored as a whole
+	other = proc.Clone()
+	other.Labels = map[string]string{"key": "value"}
+	other.OpenFiles = []string{"/tmp/file"}
+	if !proc.EqualExcept(other, FieldLabels, FieldOpenFiles) {
+		t.Errorf("Expected processes to be equal ignoring labels and open files")
+	}
+	
+	var nilProc *ProcessInfo
+	if nilProc.EqualExcept(proc, AllFields...) {
+		t.Errorf("Expected nil not to equal a process")
+	}
+}
+
 func TestCalculateDelta(t *testing.T) {
 	// Create current and previous process info
 	now := time.Now()
@@ -656,3 +1229,719 @@ func TestCalculateDelta(t *testing.T) {
 		t.Errorf("Expected error with same timestamp")
 	}
 }
+
+func TestCalculateDelta_Rates(t *testing.T) {
+	now := time.Now()
+	
+	tests := []struct {
+		name          string
+		interval      time.Duration
+		readBytes     int64
+		writeBytes    int64
+		wantReadRate  float64
+		wantWriteRate float64
+	}{
+		{"one second", time.Second, 1000, 500, 1000, 500},
+		{"half second", 500 * time.Millisecond, 1000, 500, 2000, 1000},
+		{"ten seconds", 10 * time.Second, 1 << 20, 0, 104857.6, 0},
+		{"decreasing counter", 2 * time.Second, -400, 200, -200, 100},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := &ProcessInfo{PID: 1, IOReadBytes: 5000, IOWriteBytes: 7000, LastUpdated: now.Add(-tt.interval)}
+			current := &ProcessInfo{
+				PID:          1,
+				IOReadBytes:  previous.IOReadBytes + tt.readBytes,
+				IOWriteBytes: previous.IOWriteBytes + tt.writeBytes,
+				LastUpdated:  now,
+			}
+			
+			delta, err := CalculateDelta(current, previous)
+			if err != nil {
+				t.Fatalf("Failed to calculate delta: %v", err)
+			}
+			
+			// Raw deltas are kept alongside the rates
+			if delta.IOReadBytes != tt.readBytes || delta.IOWriteBytes != tt.writeBytes {
+				t.Errorf("Expected raw deltas %d and %d, got %d and %d",
+					tt.readBytes, tt.writeBytes, delta.IOReadBytes, delta.IOWriteBytes)
+			}
+			if math.Abs(delta.IOReadBytesPerSec-tt.wantReadRate) > 1e-9 {
+				t.Errorf("Expected read rate %f, got %f", tt.wantReadRate, delta.IOReadBytesPerSec)
+			}
+			if math.Abs(delta.IOWriteBytesPerSec-tt.wantWriteRate) > 1e-9 {
+				t.Errorf("Expected write rate %f, got %f", tt.wantWriteRate, delta.IOWriteBytesPerSec)
+			}
+		})
+	}
+	
+	// No rate can be computed without elapsed time
+	current := &ProcessInfo{PID: 1, IOReadBytes: 100, LastUpdated: now}
+	if _, err := CalculateDelta(current, &ProcessInfo{PID: 1, LastUpdated: now}); err == nil {
+		t.Errorf("Expected error with same timestamp")
+	}
+	if _, err := CalculateDelta(current, &ProcessInfo{PID: 1, LastUpdated: now.Add(time.Second)}); err == nil {
+		t.Errorf("Expected error with a later previous timestamp")
+	}
+}
+
+// pointerConsumer records the processes it receives without copying them
+type pointerConsumer struct {
+	processes []*ProcessInfo
+	mutex     sync.Mutex
+}
+
+// HandleProcessEvent records the event process
+func (c *pointerConsumer) HandleProcessEvent(event ProcessEvent) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.processes = append(c.processes, event.Process)
+	return nil
+}
+
+// Received returns the recorded processes
+func (c *pointerConsumer) Received() []*ProcessInfo {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]*ProcessInfo(nil), c.processes...)
+}
+
+// readOnlyPointerConsumer is a pointerConsumer that declares itself read-only
+type readOnlyPointerConsumer struct {
+	pointerConsumer
+}
+
+// ReadOnlyProcesses marks the consumer as read-only
+func (c *readOnlyPointerConsumer) ReadOnlyProcesses() {}
+
+func TestProcessScanner_ShareProcessPointers(t *testing.T) {
+	config := DefaultConfig().ProcessScanner
+	config.ShareProcessPointers = true
+	config.TerminationGracePeriod = time.Minute
+	config.BackpressurePolicy = BackpressureBlock
+	
+	p := NewProcessScanner(config)
+	readOnly := &readOnlyPointerConsumer{}
+	mutable := &pointerConsumer{}
+	p.RegisterConsumer("read-only", readOnly)
+	p.RegisterConsumer("mutable", mutable)
+	
+	scan := func(processes ...*ProcessInfo) {
+		p.processNewScan(processes)
+		for len(p.eventChannel) > 0 {
+			p.dispatchEvent(<-p.eventChannel)
+		}
+	}
+	
+	proc := &ProcessInfo{PID: 1, Name: "process1", CPU: 5}
+	scan(proc)
+	
+	// The read-only consumer gets the cached process, the other one a copy
+	if got := readOnly.Received(); len(got) != 1 || got[0] != proc || p.processCache[1] != proc {
+		t.Fatalf("Expected read-only consumer to share the cached process, got %v", got)
+	}
+	if got := mutable.Received(); len(got) != 1 || got[0] == proc || !got[0].Equal(proc) {
+		t.Fatalf("Expected mutable consumer to receive an equal copy, got %v", got)
+	}
+	
+	// Termination marks a copy, the shared process stays unchanged
+	scan()
+	received := readOnly.Received()
+	if len(received) != 2 || !received[1].Terminated {
+		t.Fatalf("Expected a terminated event for the read-only consumer, got %v", received)
+	}
+	if proc.Terminated {
+		t.Error("Expected the shared process not to be modified on termination")
+	}
+}
+
+func TestProcessScanner_CopiesProcessesByDefault(t *testing.T) {
+	config := DefaultConfig().ProcessScanner
+	config.BackpressurePolicy = BackpressureBlock
+	
+	p := NewProcessScanner(config)
+	readOnly := &readOnlyPointerConsumer{}
+	p.RegisterConsumer("read-only", readOnly)
+	
+	proc := &ProcessInfo{PID: 1, Name: "process1"}
+	p.processNewScan([]*ProcessInfo{proc})
+	p.dispatchEvent(<-p.eventChannel)
+	
+	if got := readOnly.Received(); len(got) != 1 || got[0] == proc || got[0] == p.processCache[1] {
+		t.Errorf("Expected a copy of the process without ShareProcessPointers, got %v", got)
+	}
+}
+
+// readOnlyNopConsumer discards events without copying their processes
+type readOnlyNopConsumer struct{}
+
+func (readOnlyNopConsumer) HandleProcessEvent(event ProcessEvent) error { return nil }
+func (readOnlyNopConsumer) ReadOnlyProcesses()                          {}
+
+// benchmarkProcessNewScan measures the allocations of a scan in which every
+// one of 10k processes changed, delivered to a read-only consumer
+func benchmarkProcessNewScan(b *testing.B, share bool) {
+	const processCount = 10000
+	
+	config := DefaultConfig().ProcessScanner
+	config.ShareProcessPointers = share
+	config.BackpressurePolicy = BackpressureBlock
+	config.EventChannelSize = processCount
+	
+	p := NewProcessScanner(config)
+	p.RegisterConsumer("read-only", readOnlyNopConsumer{})
+	
+	collect := func(cpu float64) []*ProcessInfo {
+		processes := make([]*ProcessInfo, processCount)
+		for i := range processes {
+			processes[i] = &ProcessInfo{PID: i + 1, Name: "process", Command: "/bin/process", CPU: cpu}
+		}
+		return processes
+	}
+	
+	p.processNewScan(collect(0))
+	for len(p.eventChannel) > 0 {
+		<-p.eventChannel
+	}
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// The platform allocates the collected processes in either mode
+		b.StopTimer()
+		processes := collect(float64(i + 1))
+		b.StartTimer()
+		
+		p.processNewScan(processes)
+		for len(p.eventChannel) > 0 {
+			p.dispatchEvent(<-p.eventChannel)
+		}
+	}
+}
+
+func BenchmarkProcessScanner_ProcessNewScan(b *testing.B) {
+	b.Run("Clone", func(b *testing.B) { benchmarkProcessNewScan(b, false) })
+	b.Run("ShareProcessPointers", func(b *testing.B) { benchmarkProcessNewScan(b, true) })
+}
+
+func TestProcessScanner_PauseResume(t *testing.T) {
+	fake := &fakePlatformCollector{processes: []*ProcessInfo{{PID: 1, Name: "init"}}}
+	scanner := newTestScanner(t, fake)
+	
+	consumer := NewMockProcessConsumer()
+	if err := scanner.RegisterConsumer("test", consumer); err != nil {
+		t.Fatalf("Failed to register consumer: %v", err)
+	}
+	if err := scanner.Start(); err != nil {
+		t.Fatalf("Failed to start scanner: %v", err)
+	}
+	if !waitFor(t, time.Second, func() bool { return consumer.CountByType(ProcessCreated) == 1 }) {
+		t.Fatalf("Expected created event for PID 1")
+	}
+	
+	if err := scanner.Pause(); err != nil {
+		t.Fatalf("Failed to pause scanner: %v", err)
+	}
+	if status := scanner.Status(); status != StatusPaused {
+		t.Errorf("Expected status %s, got %s", StatusPaused, status)
+	}
+	
+	// No scans while paused, the cache is kept
+	fake.mutex.Lock()
+	fake.processes = append(fake.processes, &ProcessInfo{PID: 2, Name: "new"})
+	fake.mutex.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := scanner.GetCachedProcess(2); ok {
+		t.Errorf("Expected no scan while paused")
+	}
+	if _, ok := scanner.GetCachedProcess(1); !ok {
+		t.Errorf("Expected the cache to survive the pause")
+	}
+	
+	// Resuming scans again and delivers to the same consumers
+	if err := scanner.Resume(); err != nil {
+		t.Fatalf("Failed to resume scanner: %v", err)
+	}
+	if status := scanner.Status(); status != StatusRunning {
+		t.Errorf("Expected status %s, got %s", StatusRunning, status)
+	}
+	if !waitFor(t, time.Second, func() bool { return consumer.CountByType(ProcessCreated) == 2 }) {
+		t.Errorf("Expected created event for PID 2 after resume, got %d created events", consumer.CountByType(ProcessCreated))
+	}
+	
+	if err := scanner.Stop(); err != nil {
+		t.Fatalf("Failed to stop scanner: %v", err)
+	}
+	if status := scanner.Status(); status != StatusStopped {
+		t.Errorf("Expected status %s, got %s", StatusStopped, status)
+	}
+}
+
+func TestProcessScanner_PauseResumeTransitions(t *testing.T) {
+	fake := &fakePlatformCollector{processes: []*ProcessInfo{{PID: 1, Name: "init"}}}
+	scanner := newTestScanner(t, fake)
+	
+	if err := scanner.Pause(); err == nil {
+		t.Errorf("Expected error pausing a scanner that was not started")
+	}
+	if err := scanner.Resume(); err == nil {
+		t.Errorf("Expected error resuming a scanner that was not paused")
+	}
+	
+	if err := scanner.Start(); err != nil {
+		t.Fatalf("Failed to start scanner: %v", err)
+	}
+	if err := scanner.Resume(); err == nil {
+		t.Errorf("Expected error resuming a running scanner")
+	}
+	if err := scanner.Pause(); err != nil {
+		t.Fatalf("Failed to pause scanner: %v", err)
+	}
+	if err := scanner.Pause(); err == nil {
+		t.Errorf("Expected error pausing a paused scanner")
+	}
+	
+	// A paused scanner can be stopped directly
+	if err := scanner.Stop(); err != nil {
+		t.Fatalf("Failed to stop paused scanner: %v", err)
+	}
+	if err := scanner.Pause(); err == nil {
+		t.Errorf("Expected error pausing a stopped scanner")
+	}
+	if err := scanner.Resume(); err == nil {
+		t.Errorf("Expected error resuming a stopped scanner")
+	}
+	
+	// Start also resumes a paused scanner
+	if err := scanner.Start(); err != nil {
+		t.Fatalf("Failed to restart scanner: %v", err)
+	}
+	if err := scanner.Pause(); err != nil {
+		t.Fatalf("Failed to pause scanner: %v", err)
+	}
+	if err := scanner.Start(); err != nil {
+		t.Fatalf("Failed to start paused scanner: %v", err)
+	}
+	if status := scanner.Status(); status != StatusRunning {
+		t.Errorf("Expected status %s, got %s", StatusRunning, status)
+	}
+	if err := scanner.Stop(); err != nil {
+		t.Fatalf("Failed to stop scanner: %v", err)
+	}
+}
+
+func TestProcessScanner_HostMemory(t *testing.T) {
+	fake := &fakePlatformCollector{
+		processes: []*ProcessInfo{{PID: 1, Name: "init"}, {PID: 2, Name: "worker"}},
+		hostTotal: 8 << 30,
+		hostUsed:  3 << 30,
+	}
+	scanner := newTestScanner(t, fake)
+	scanner.config.ScanInterval = time.Hour
+	
+	consumer := NewMockProcessConsumer()
+	scanner.RegisterConsumer("test", consumer)
+	if err := scanner.Start(); err != nil {
+		t.Fatalf("Failed to start scanner: %v", err)
+	}
+	defer scanner.Stop()
+	
+	if err := scanner.ForceScanSync(context.Background()); err != nil {
+		t.Fatalf("ForceScanSync returned error: %v", err)
+	}
+	
+	// Every event of the scan carries the host memory
+	events := consumer.GetEvents()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	for _, event := range events {
+		if event.HostMemoryTotal != 8<<30 || event.HostMemoryUsed != 3<<30 {
+			t.Errorf("Expected host memory 8GiB total and 3GiB used, got %d and %d",
+				event.HostMemoryTotal, event.HostMemoryUsed)
+		}
+	}
+	
+	// Events are still sent when host memory is not available
+	consumer.Reset()
+	fake.mutex.Lock()
+	fake.processes = append(fake.processes, &ProcessInfo{PID: 3, Name: "new"})
+	fake.memErr = fmt.Errorf("meminfo not readable")
+	fake.mutex.Unlock()
+	
+	if err := scanner.ForceScanSync(context.Background()); err != nil {
+		t.Fatalf("ForceScanSync returned error: %v", err)
+	}
+	events = consumer.GetEvents()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if events[0].HostMemoryTotal != 0 || events[0].HostMemoryUsed != 0 {
+		t.Errorf("Expected no host memory on error, got %d and %d",
+			events[0].HostMemoryTotal, events[0].HostMemoryUsed)
+	}
+}
+
+func TestProcessScanner_ExecutableChanged(t *testing.T) {
+	config := DefaultConfig().ProcessScanner
+	config.BackpressurePolicy = BackpressureBlock
+	config.DegradedMinCPU = 50
+	
+	p := NewProcessScanner(config)
+	
+	scan := func(processes ...*ProcessInfo) []ProcessEvent {
+		p.processNewScan(processes)
+		var events []ProcessEvent
+		for len(p.eventChannel) > 0 {
+			events = append(events, <-p.eventChannel)
+		}
+		return events
+	}
+	
+	scan(&ProcessInfo{PID: 1, Name: "server", ExecutableHash: "aaaa"})
+	
+	// Other changes are not flagged
+	events := scan(&ProcessInfo{PID: 1, Name: "server", ExecutableHash: "aaaa", RSS: 1024})
+	if len(events) != 1 || events[0].Type != ProcessUpdated || events[0].ExecutableChanged {
+		t.Fatalf("Expected an updated event without executable change, got %+v", events)
+	}
+	
+	// A process starting to report a hash has not changed its binary
+	scan(
+		&ProcessInfo{PID: 1, Name: "server", ExecutableHash: "aaaa", RSS: 1024},
+		&ProcessInfo{PID: 2, Name: "worker"},
+	)
+	events = scan(
+		&ProcessInfo{PID: 1, Name: "server", ExecutableHash: "aaaa", RSS: 1024},
+		&ProcessInfo{PID: 2, Name: "worker", ExecutableHash: "cccc"},
+	)
+	if len(events) != 1 || events[0].ExecutableChanged {
+		t.Fatalf("Expected an updated event without executable change for PID 2, got %+v", events)
+	}
+	
+	// Degraded scanners still report executable changes of idle processes
+	p.SetDegradationLevel(degradationFilterEvents)
+	events = scan(
+		&ProcessInfo{PID: 1, Name: "server", ExecutableHash: "bbbb", RSS: 1024},
+		&ProcessInfo{PID: 2, Name: "worker", ExecutableHash: "cccc", RSS: 1024},
+	)
+	if len(events) != 1 || events[0].Process.PID != 1 || !events[0].ExecutableChanged {
+		t.Fatalf("Expected only an executable change event for PID 1, got %+v", events)
+	}
+}
+
+func TestProcessScanner_UpdateFields(t *testing.T) {
+	config := DefaultConfig().ProcessScanner
+	config.BackpressurePolicy = BackpressureBlock
+	config.UpdateFields = []Field{FieldCPU, FieldRSS, FieldState}
+	config.UpdateCPUEpsilon = 5
+	config.UpdateRSSDelta = 1024
+	
+	p := NewProcessScanner(config)
+	
+	scan := func(processes ...*ProcessInfo) []ProcessEvent {
+		p.processNewScan(processes)
+		var events []ProcessEvent
+		for len(p.eventChannel) > 0 {
+			events = append(events, <-p.eventChannel)
+		}
+		return events
+	}
+	
+	scan(&ProcessInfo{PID: 1, Name: "server", CPU: 10, RSS: 4096, State: "S"})
+	
+	// CPU jitter below the epsilon, and changes of other fields, are not updates
+	for _, cpu := range []float64{12, 8, 14.9, 5.1} {
+		events := scan(&ProcessInfo{PID: 1, Name: "server", CPU: cpu, RSS: 4096, State: "S", Threads: int(cpu)})
+		if len(events) != 0 {
+			t.Fatalf("Expected no event for CPU %.1f, got %+v", cpu, events)
+		}
+	}
+	
+	// Changes accumulate from the last update
+	events := scan(&ProcessInfo{PID: 1, Name: "server", CPU: 15, RSS: 4096, State: "S"})
+	if len(events) != 1 || events[0].Type != ProcessUpdated || events[0].Process.CPU != 15 {
+		t.Fatalf("Expected an updated event for CPU 15, got %+v", events)
+	}
+	
+	events = scan(&ProcessInfo{PID: 1, Name: "server", CPU: 15, RSS: 4096 + 1023, State: "S"})
+	if len(events) != 0 {
+		t.Fatalf("Expected no event for RSS below the delta, got %+v", events)
+	}
+	
+	events = scan(&ProcessInfo{PID: 1, Name: "server", CPU: 15, RSS: 4096 - 1024, State: "S"})
+	if len(events) != 1 {
+		t.Fatalf("Expected an updated event for RSS crossing the delta, got %+v", events)
+	}
+	
+	events = scan(&ProcessInfo{PID: 1, Name: "server", CPU: 15, RSS: 4096 - 1024, State: "R"})
+	if len(events) != 1 || events[0].Process.State != "R" {
+		t.Fatalf("Expected an updated event for the state change, got %+v", events)
+	}
+	
+	// The default configuration reports any change
+	p = NewProcessScanner(DefaultConfig().ProcessScanner)
+	scan(&ProcessInfo{PID: 1, Name: "server", CPU: 10})
+	events = scan(&ProcessInfo{PID: 1, Name: "server", CPU: 10.1})
+	if len(events) != 1 {
+		t.Fatalf("Expected an updated event by default, got %+v", events)
+	}
+}
+
+func TestConfig_ValidateUpdateFields(t *testing.T) {
+	config := DefaultConfig()
+	config.ProcessScanner.UpdateFields = []Field{FieldCPU, FieldState}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Expected valid update fields, got %v", err)
+	}
+	
+	config.ProcessScanner.UpdateFields = []Field{"cpuPercent"}
+	if err := config.Validate(); err == nil {
+		t.Errorf("Expected an error for an unknown update field")
+	}
+	
+	config.ProcessScanner.UpdateFields = nil
+	config.ProcessScanner.UpdateCPUEpsilon = -1
+	if err := config.Validate(); err == nil {
+		t.Errorf("Expected an error for a negative CPU epsilon")
+	}
+}
+
+// partialFakeCollector is a fake platform collector supporting partial scans
+type partialFakeCollector struct {
+	*fakePlatformCollector
+	lastRead int // Processes read by the last scan
+}
+
+func (c *partialFakeCollector) ListPIDs() ([]int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	pids := make([]int, 0, len(c.processes))
+	for _, proc := range c.processes {
+		pids = append(pids, proc.PID)
+	}
+	return pids, nil
+}
+
+func (c *partialFakeCollector) GetProcessesByPID(pids []int) ([]*ProcessInfo, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	byPID := make(map[int]*ProcessInfo, len(c.processes))
+	for _, proc := range c.processes {
+		byPID[proc.PID] = proc
+	}
+	var processes []*ProcessInfo
+	for _, pid := range pids {
+		if proc, ok := byPID[pid]; ok {
+			processes = append(processes, proc.Clone())
+		}
+	}
+	c.lastRead = len(processes)
+	return processes, nil
+}
+
+func TestProcessScanner_MaxProcessesPerScan(t *testing.T) {
+	config := DefaultConfig().ProcessScanner
+	config.BackpressurePolicy = BackpressureBlock
+	config.EventChannelSize = 2000
+	config.MaxProcessesPerScan = 1000
+	
+	processes := make([]*ProcessInfo, 0, 10000)
+	for pid := 1; pid <= 10000; pid++ {
+		processes = append(processes, &ProcessInfo{PID: pid, Name: fmt.Sprintf("proc-%d", pid)})
+	}
+	fake := &partialFakeCollector{fakePlatformCollector: &fakePlatformCollector{processes: processes}}
+	
+	p := NewProcessScanner(config)
+	p.platformCollector = fake
+	
+	scan := func() (created, updated, terminated int) {
+		if err := p.performScan(); err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		if fake.lastRead > config.MaxProcessesPerScan {
+			t.Fatalf("Expected at most %d processes read, got %d", config.MaxProcessesPerScan, fake.lastRead)
+		}
+		for len(p.eventChannel) > 0 {
+			<-p.eventChannel
+		}
+		return p.GetScanDelta()
+	}
+	
+	created, _, _ := scan()
+	if created != 1000 {
+		t.Fatalf("Expected 1000 processes created by the first scan, got %d", created)
+	}
+	if deferred := p.metrics.GetGauge(MetricProcessesDeferred); deferred != 9000 {
+		t.Errorf("Expected 9000 deferred processes, got %.0f", deferred)
+	}
+	
+	// New processes are discovered over several scans, without deferred
+	// processes being reported as terminated
+	scans := 1
+	for ; p.GetProcessCount() < 10000 && scans < 100; scans++ {
+		if _, _, terminated := scan(); terminated != 0 {
+			t.Fatalf("Expected no terminated process on scan %d, got %d", scans, terminated)
+		}
+	}
+	if count := p.GetProcessCount(); count != 10000 {
+		t.Fatalf("Expected all 10000 processes covered, got %d after %d scans", count, scans)
+	}
+	
+	// Every cached process is read again within 10 scans
+	fake.mutex.Lock()
+	for _, proc := range fake.processes {
+		proc.CPU = 5
+	}
+	fake.mutex.Unlock()
+	total := 0
+	for i := 0; i < 10; i++ {
+		_, updated, _ := scan()
+		total += updated
+	}
+	if total != 10000 {
+		t.Errorf("Expected all 10000 processes updated within 10 scans, got %d", total)
+	}
+	
+	// Exited processes are terminated on the next scan, read or not
+	fake.mutex.Lock()
+	fake.processes = fake.processes[:9990]
+	fake.mutex.Unlock()
+	if _, _, terminated := scan(); terminated != 10 {
+		t.Errorf("Expected 10 terminated processes, got %d", terminated)
+	}
+}
+
+func TestProcessScanner_InitWithMockCollector(t *testing.T) {
+	fake := &fakePlatformCollector{}
+	scanner := NewProcessScanner(DefaultConfig().ProcessScanner)
+	if err := scanner.Init(context.Background(), map[string]interface{}{"mockCollector": fake}); err != nil {
+		t.Fatalf("Failed to initialize scanner: %v", err)
+	}
+	if scanner.platformCollector != fake {
+		t.Errorf("Expected the injected collector to be used, got %T", scanner.platformCollector)
+	}
+	
+	// Values that are not a collector are ignored
+	scanner = NewProcessScanner(DefaultConfig().ProcessScanner)
+	if err := scanner.Init(context.Background(), map[string]interface{}{"mockCollector": "fake"}); err != nil {
+		t.Fatalf("Failed to initialize scanner: %v", err)
+	}
+	if scanner.platformCollector == nil {
+		t.Errorf("Expected a platform collector")
+	}
+	if _, ok := scanner.platformCollector.(*fakePlatformCollector); ok {
+		t.Errorf("Expected the platform collector, got the fake")
+	}
+}
+
+func TestProcessScanner_Capabilities(t *testing.T) {
+	scanner := NewProcessScanner(DefaultConfig().ProcessScanner)
+	if caps := scanner.Capabilities(); caps != (ProcessCapabilities{}) {
+		t.Errorf("Expected no capabilities before Init, got %+v", caps)
+	}
+	
+	fake := &fakePlatformCollector{caps: ProcessCapabilities{CPUPercent: true, RSS: true}}
+	if err := scanner.Init(context.Background(), map[string]interface{}{"mockCollector": fake}); err != nil {
+		t.Fatalf("Failed to initialize scanner: %v", err)
+	}
+	if caps := scanner.Capabilities(); caps != fake.caps {
+		t.Errorf("Expected the capabilities of the collector %+v, got %+v", fake.caps, caps)
+	}
+}
+
+func TestProcessScanner_PartialProcesses(t *testing.T) {
+	fake := &fakePlatformCollector{processes: []*ProcessInfo{
+		{PID: 1, Name: "init", State: "S"},
+		{PID: 2, Name: "hidden", Partial: true, AccessError: "permission denied"},
+	}}
+	scanner := newTestScanner(t, fake)
+	
+	consumer := NewMockProcessConsumer()
+	scanner.RegisterConsumer("test", consumer)
+	if err := scanner.Start(); err != nil {
+		t.Fatalf("Failed to start scanner: %v", err)
+	}
+	defer scanner.Stop()
+	
+	if err := scanner.ForceScanSync(context.Background()); err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+	if partial := scanner.metrics.GetGauge(MetricProcessesPartial); partial != 1 {
+		t.Errorf("Expected 1 partial process, got %v", partial)
+	}
+	
+	// Partial processes are reported like the others
+	var hidden *ProcessInfo
+	for _, event := range consumer.GetEvents() {
+		if event.Type == ProcessCreated && event.Process.PID == 2 {
+			hidden = event.Process
+		}
+	}
+	if hidden == nil || !hidden.Partial || hidden.AccessError != "permission denied" {
+		t.Errorf("Expected a created event for the partial process, got %+v", hidden)
+	}
+}
+
+// closingConsumer counts events and records when its stream is closed
+type closingConsumer struct {
+	name     string
+	closed   *[]string
+	closeErr error
+	events   int
+	mutex    sync.Mutex
+}
+
+// HandleProcessEvent counts the event
+func (c *closingConsumer) HandleProcessEvent(event ProcessEvent) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.events++
+	return nil
+}
+
+// OnStreamClose records the consumer name
+func (c *closingConsumer) OnStreamClose() error {
+	*c.closed = append(*c.closed, c.name)
+	return c.closeErr
+}
+
+func TestProcessScanner_ShutdownClosesConsumers(t *testing.T) {
+	fake := &fakePlatformCollector{processes: []*ProcessInfo{{PID: 1, Name: "init"}}}
+	scanner := newTestScanner(t, fake)
+	sink := &recordingSink{}
+	scanner.SetDiagnosticSink(sink)
+	
+	var closed []string
+	exporter := &closingConsumer{name: "exporter", closed: &closed, closeErr: fmt.Errorf("flush failed")}
+	sampler := &closingConsumer{name: "sampler", closed: &closed}
+	scanner.RegisterConsumer("exporter", exporter)
+	scanner.RegisterConsumerWithPriority("sampler", sampler, 10)
+	scanner.RegisterConsumer("plain", &MockProcessConsumer{})
+	
+	if err := scanner.Start(); err != nil {
+		t.Fatalf("Failed to start scanner: %v", err)
+	}
+	if err := scanner.ForceScanSync(context.Background()); err != nil {
+		t.Fatalf("Forced scan failed: %v", err)
+	}
+	
+	if err := scanner.Shutdown(); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	
+	// Consumers are closed once, in delivery order, after their events
+	if len(closed) != 2 || closed[0] != "sampler" || closed[1] != "exporter" {
+		t.Errorf("Expected sampler then exporter to be closed, got %v", closed)
+	}
+	if sampler.events == 0 {
+		t.Error("Expected the sampler to receive events before closing")
+	}
+	
+	// A failing close is reported without failing the shutdown
+	events := sink.ofType(DiagConsumerCloseError)
+	if len(events) != 1 || events[0].Fields["consumer"] != "exporter" {
+		t.Errorf("Expected a close error for exporter, got %v", events)
+	}
+}
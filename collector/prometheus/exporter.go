@@ -0,0 +1,94 @@
+// Package prometheus exposes process scanner metrics to Prometheus.
+package prometheus
+
+import (
+	"github.com/newrelic/infrastructure-agent/collector"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultNamespace is the metric name prefix used when none is given
+const DefaultNamespace = "process_scanner"
+
+// MetricsSource provides scanner metrics, implemented by collector.ProcessScanner
+type MetricsSource interface {
+	Metrics() map[string]float64
+}
+
+// metricSpec maps a scanner metric to a Prometheus metric
+type metricSpec struct {
+	name      string // Scanner metric constant, also the Prometheus name
+	key       string // Key in the Metrics() map
+	help      string
+	valueType prom.ValueType
+}
+
+// scannerMetrics are the scanner metrics exported as gauges and counters.
+// Timers are reported by MetricsTracker with an extra _ms suffix
+var scannerMetrics = []metricSpec{
+	{collector.MetricProcessCount, collector.MetricProcessCount, "Number of processes in the scanner cache", prom.GaugeValue},
+	{collector.MetricScanDuration, collector.MetricScanDuration + "_ms", "Duration of the last scan in milliseconds", prom.GaugeValue},
+	{collector.MetricScanErrors, collector.MetricScanErrors, "Total number of failed scans", prom.CounterValue},
+	{collector.MetricLimitBreaches, collector.MetricLimitBreaches, "Total number of scans exceeding the CPU limit", prom.CounterValue},
+	{collector.MetricAdaptiveRateChanges, collector.MetricAdaptiveRateChanges, "Total number of adaptive scan interval changes", prom.CounterValue},
+	{collector.MetricCPUUsage, collector.MetricCPUUsage, "CPU usage of the scanner in percent", prom.GaugeValue},
+	{collector.MetricMemoryUsage, collector.MetricMemoryUsage, "Memory usage of the scanner in bytes", prom.GaugeValue},
+}
+
+// eventMetrics maps process event types to their scanner counters
+var eventMetrics = map[collector.ProcessEventType]string{
+	collector.ProcessCreated:    collector.MetricProcessCreated,
+	collector.ProcessUpdated:    collector.MetricProcessUpdated,
+	collector.ProcessTerminated: collector.MetricProcessTerminated,
+}
+
+// Exporter is a prometheus.Collector reading the scanner metrics on every scrape
+type Exporter struct {
+	source     MetricsSource
+	descs      []*prom.Desc // Parallel to scannerMetrics
+	eventsDesc *prom.Desc
+}
+
+// NewExporter creates an exporter for the given source. An empty namespace
+// defaults to DefaultNamespace
+func NewExporter(source MetricsSource, namespace string) *Exporter {
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+
+	descs := make([]*prom.Desc, len(scannerMetrics))
+	for i, spec := range scannerMetrics {
+		descs[i] = prom.NewDesc(prom.BuildFQName(namespace, "", spec.name), spec.help, nil, nil)
+	}
+
+	return &Exporter{
+		source: source,
+		descs:  descs,
+		eventsDesc: prom.NewDesc(
+			prom.BuildFQName(namespace, "", "process_events_total"),
+			"Total number of process events by type",
+			[]string{"type"}, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (e *Exporter) Describe(ch chan<- *prom.Desc) {
+	for _, desc := range e.descs {
+		ch <- desc
+	}
+	ch <- e.eventsDesc
+}
+
+// Collect implements prometheus.Collector
+func (e *Exporter) Collect(ch chan<- prom.Metric) {
+	metrics := e.source.Metrics()
+
+	// Metrics are missing until the first scan, report them as 0
+	for i, spec := range scannerMetrics {
+		ch <- prom.MustNewConstMetric(e.descs[i], spec.valueType, metrics[spec.key])
+	}
+
+	for eventType, name := range eventMetrics {
+		ch <- prom.MustNewConstMetric(e.eventsDesc, prom.CounterValue, metrics[name], string(eventType))
+	}
+}
@@ -0,0 +1,96 @@
+package prometheus
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/newrelic/infrastructure-agent/collector"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// staticSource returns a fixed set of metrics
+type staticSource map[string]float64
+
+func (s staticSource) Metrics() map[string]float64 {
+	return s
+}
+
+func TestExporter_Handler(t *testing.T) {
+	source := staticSource{
+		collector.MetricProcessCount:         42,
+		collector.MetricScanDuration + "_ms": 12,
+		collector.MetricScanErrors:           1,
+		collector.MetricCPUUsage:             0.5,
+		collector.MetricMemoryUsage:          1048576,
+		collector.MetricProcessCreated:       50,
+		collector.MetricProcessTerminated:    8,
+	}
+
+	// Serve the exporter on its own registry like an agent would on /metrics
+	registry := prom.NewRegistry()
+	if err := registry.Register(NewExporter(source, "")); err != nil {
+		t.Fatalf("Failed to register exporter: %v", err)
+	}
+	server := httptest.NewServer(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to scrape exporter: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read scrape: %v", err)
+	}
+
+	expected := []string{
+		"process_scanner_process_count 42",
+		"process_scanner_scan_duration_ms 12",
+		"process_scanner_scan_errors_total 1",
+		"process_scanner_limit_breaches_total 0",
+		"process_scanner_adaptive_rate_changes_total 0",
+		"process_scanner_cpu_usage_percent 0.5",
+		"process_scanner_memory_usage_bytes 1.048576e+06",
+		"# TYPE process_scanner_scan_errors_total counter",
+		"# TYPE process_scanner_process_count gauge",
+		`process_scanner_process_events_total{type="created"} 50`,
+		`process_scanner_process_events_total{type="updated"} 0`,
+		`process_scanner_process_events_total{type="terminated"} 8`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("Expected scrape to contain %q, got:\n%s", line, body)
+		}
+	}
+}
+
+func TestExporter_Namespace(t *testing.T) {
+	registry := prom.NewRegistry()
+	registry.MustRegister(NewExporter(staticSource{collector.MetricProcessCount: 3}, "agent"))
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+
+	found := false
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), "agent_") {
+			t.Errorf("Expected agent_ prefix, got %s", family.GetName())
+		}
+		if family.GetName() == "agent_process_count" {
+			found = true
+			if value := family.GetMetric()[0].GetGauge().GetValue(); value != 3 {
+				t.Errorf("Expected process count 3, got %f", value)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected agent_process_count to be exported")
+	}
+}
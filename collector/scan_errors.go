@@ -0,0 +1,66 @@
+package collector
+
+import (
+	"sync"
+	"time"
+)
+
+// recentErrorsSize is the number of scan errors kept by the scanner
+const recentErrorsSize = 32
+
+// ScanPhase identifies the step of a scan that failed
+type ScanPhase string
+
+const (
+	// ScanPhaseProcesses is listing the processes, the scan is aborted
+	ScanPhaseProcesses ScanPhase = "processes"
+
+	// ScanPhaseCPUTimes is refreshing the CPU times, the scan continues
+	ScanPhaseCPUTimes ScanPhase = "cpu_times"
+)
+
+// ScanError is an error of a single scan
+type ScanError struct {
+	Timestamp time.Time
+	Phase     ScanPhase
+	Err       error
+}
+
+// scanErrorRing keeps the most recent scan errors in a fixed-size buffer
+type scanErrorRing struct {
+	entries []ScanError
+	next    int  // Index of the next entry to overwrite
+	full    bool // All entries are in use
+	mutex   sync.Mutex
+}
+
+// newScanErrorRing creates a ring holding up to size errors
+func newScanErrorRing(size int) *scanErrorRing {
+	return &scanErrorRing{entries: make([]ScanError, size)}
+}
+
+// add records an error, overwriting the oldest one when full
+func (r *scanErrorRing) add(phase ScanPhase, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.entries[r.next] = ScanError{Timestamp: time.Now(), Phase: phase, Err: err}
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// snapshot returns a copy of the recorded errors, oldest first
+func (r *scanErrorRing) snapshot() []ScanError {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.full {
+		return append([]ScanError(nil), r.entries[:r.next]...)
+	}
+
+	errors := make([]ScanError, 0, len(r.entries))
+	errors = append(errors, r.entries[r.next:]...)
+	return append(errors, r.entries[:r.next]...)
+}
@@ -0,0 +1,53 @@
+package collector
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestScanErrorRing(t *testing.T) {
+	ring := newScanErrorRing(3)
+	if errors := ring.snapshot(); len(errors) != 0 {
+		t.Fatalf("Expected no errors, got %d", len(errors))
+	}
+
+	for i := 0; i < 5; i++ {
+		ring.add(ScanPhaseProcesses, fmt.Errorf("error %d", i))
+	}
+
+	// The oldest errors are overwritten
+	errors := ring.snapshot()
+	if len(errors) != 3 {
+		t.Fatalf("Expected 3 errors, got %d", len(errors))
+	}
+	for i, scanErr := range errors {
+		if expected := fmt.Sprintf("error %d", i+2); scanErr.Err.Error() != expected {
+			t.Errorf("Expected %q at %d, got %q", expected, i, scanErr.Err)
+		}
+	}
+}
+
+func TestProcessScanner_RecentErrors(t *testing.T) {
+	fake := &fakePlatformCollector{scanErr: fmt.Errorf("procfs unavailable")}
+	scanner := newTestScanner(t, fake)
+	scanner.SetDiagnosticSink(&recordingSink{})
+
+	scanner.performScan()
+	scanner.performScan()
+
+	errors := scanner.RecentErrors()
+	if len(errors) != 2 {
+		t.Fatalf("Expected 2 recent errors, got %d", len(errors))
+	}
+	for _, scanErr := range errors {
+		if scanErr.Phase != ScanPhaseProcesses {
+			t.Errorf("Expected phase %s, got %s", ScanPhaseProcesses, scanErr.Phase)
+		}
+		if scanErr.Err.Error() != "procfs unavailable" {
+			t.Errorf("Unexpected error: %v", scanErr.Err)
+		}
+		if scanErr.Timestamp.IsZero() {
+			t.Error("Expected a timestamp")
+		}
+	}
+}
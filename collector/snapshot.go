@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"sort"
+)
+
+// DiffSnapshots compares two lists of processes, e.g. a saved snapshot
+// before and a live one after, the way the scanner compares consecutive scans, without
+// events or side effects. Processes whose PID was reused are returned as
+// terminated and created. Updated processes are the new snapshots. Old
+// processes already marked terminated only match new processes as created.
+// Each result is sorted by PID
+func DiffSnapshots(before, after []*ProcessInfo) (created, updated, terminated []*ProcessInfo) {
+	oldByPID := make(map[int]*ProcessInfo, len(before))
+	for _, proc := range before {
+		oldByPID[proc.PID] = proc
+	}
+
+	newByPID := make(map[int]*ProcessInfo, len(after))
+	for _, proc := range after {
+		newByPID[proc.PID] = proc
+	}
+
+	for pid, oldProc := range oldByPID {
+		if _, exists := newByPID[pid]; !exists && !oldProc.Terminated {
+			terminated = append(terminated, oldProc)
+		}
+	}
+
+	for pid, newProc := range newByPID {
+		oldProc, exists := oldByPID[pid]
+		if exists && oldProc.Terminated {
+			exists = false
+		}
+
+		if exists && isPIDReused(oldProc, newProc) {
+			terminated = append(terminated, oldProc)
+			exists = false
+		}
+
+		switch {
+		case !exists:
+			created = append(created, newProc)
+		case !oldProc.Equal(newProc):
+			updated = append(updated, newProc)
+		}
+	}
+
+	sortByPID(created)
+	sortByPID(updated)
+	sortByPID(terminated)
+	return created, updated, terminated
+}
+
+// sortByPID sorts processes by ascending PID
+func sortByPID(processes []*ProcessInfo) {
+	sort.Slice(processes, func(i, j int) bool {
+		return processes[i].PID < processes[j].PID
+	})
+}
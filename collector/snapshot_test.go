@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// processPIDs returns the PIDs of processes, in order
+func processPIDs(processes []*ProcessInfo) []int {
+	result := make([]int, 0, len(processes))
+	for _, proc := range processes {
+		result = append(result, proc.PID)
+	}
+	return result
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+
+	before := []*ProcessInfo{
+		{PID: 1, Name: "init", StartTime: start},
+		{PID: 20, Name: "worker", CPU: 1, StartTime: start},
+		{PID: 3, Name: "gone", StartTime: start},
+		{PID: 4, Name: "old", StartTime: start},
+		{PID: 5, Name: "reported", StartTime: start, Terminated: true, TerminatedAt: start},
+		{PID: 6, Name: "finished", StartTime: start, Terminated: true, TerminatedAt: start},
+	}
+	after := []*ProcessInfo{
+		{PID: 1, Name: "init", StartTime: start},
+		{PID: 20, Name: "worker", CPU: 2, StartTime: start},
+		{PID: 4, Name: "new", StartTime: start.Add(time.Minute)},
+		{PID: 5, Name: "again", StartTime: start.Add(time.Minute)},
+		{PID: 7, Name: "started", StartTime: start.Add(time.Minute)},
+	}
+
+	created, updated, terminated := DiffSnapshots(before, after)
+
+	// PID 4 was reused, PID 5 was already reported as terminated
+	if got := processPIDs(created); !reflect.DeepEqual(got, []int{4, 5, 7}) {
+		t.Errorf("Expected created PIDs [4 5 7], got %v", got)
+	}
+	if got := processPIDs(updated); !reflect.DeepEqual(got, []int{20}) || updated[0].CPU != 2 {
+		t.Errorf("Expected the new snapshot of PID 20 updated, got %v", got)
+	}
+	if got := processPIDs(terminated); !reflect.DeepEqual(got, []int{3, 4}) || terminated[1].Name != "old" {
+		t.Errorf("Expected terminated PIDs [3 4] with the old PID 4, got %v", got)
+	}
+
+	// Neither snapshot is modified
+	if before[3].Name != "old" || before[4].Terminated != true || after[2].Name != "new" {
+		t.Errorf("Expected snapshots to be left unchanged")
+	}
+
+	created, updated, terminated = DiffSnapshots(nil, nil)
+	if len(created) != 0 || len(updated) != 0 || len(terminated) != 0 {
+		t.Errorf("Expected no changes between empty snapshots")
+	}
+}
@@ -0,0 +1,88 @@
+package collector
+
+import (
+	"container/heap"
+)
+
+// GetTopByCPU returns copies of the n live processes with the highest CPU
+// usage, highest first. Values are as of the last scan
+func (p *ProcessScanner) GetTopByCPU(n int) []*ProcessInfo {
+	return p.topProcesses(n, func(proc *ProcessInfo) float64 {
+		return proc.CPU
+	})
+}
+
+// GetTopByMemory returns copies of the n live processes with the highest RSS,
+// highest first. Values are as of the last scan
+func (p *ProcessScanner) GetTopByMemory(n int) []*ProcessInfo {
+	return p.topProcesses(n, func(proc *ProcessInfo) float64 {
+		return float64(proc.RSS)
+	})
+}
+
+// topProcesses selects the n cached processes with the highest value, keeping
+// only n candidates in a min-heap instead of sorting the whole cache. Ties go
+// to the lower PID
+func (p *ProcessScanner) topProcesses(n int, value func(*ProcessInfo) float64) []*ProcessInfo {
+	if n <= 0 {
+		return nil
+	}
+
+	p.cacheMutex.RLock()
+	defer p.cacheMutex.RUnlock()
+
+	candidates := &processHeap{value: value}
+	for _, proc := range p.processCache {
+		if proc.Terminated {
+			continue
+		}
+
+		if candidates.Len() < n {
+			heap.Push(candidates, proc)
+		} else if candidates.below(candidates.processes[0], proc) {
+			candidates.processes[0] = proc
+			heap.Fix(candidates, 0)
+		}
+	}
+
+	top := make([]*ProcessInfo, candidates.Len())
+	for i := len(top) - 1; i >= 0; i-- {
+		top[i] = heap.Pop(candidates).(*ProcessInfo).Clone()
+	}
+	return top
+}
+
+// processHeap is a min-heap of processes, the root is the first to be replaced
+type processHeap struct {
+	processes []*ProcessInfo
+	value     func(*ProcessInfo) float64
+}
+
+// below reports whether a ranks below b
+func (h *processHeap) below(a, b *ProcessInfo) bool {
+	va, vb := h.value(a), h.value(b)
+	if va != vb {
+		return va < vb
+	}
+	return a.PID > b.PID
+}
+
+func (h *processHeap) Len() int { return len(h.processes) }
+
+func (h *processHeap) Less(i, j int) bool {
+	return h.below(h.processes[i], h.processes[j])
+}
+
+func (h *processHeap) Swap(i, j int) {
+	h.processes[i], h.processes[j] = h.processes[j], h.processes[i]
+}
+
+func (h *processHeap) Push(x interface{}) {
+	h.processes = append(h.processes, x.(*ProcessInfo))
+}
+
+func (h *processHeap) Pop() interface{} {
+	last := h.processes[len(h.processes)-1]
+	h.processes = h.processes[:len(h.processes)-1]
+	return last
+}
@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"testing"
+)
+
+func TestProcessScanner_GetTop(t *testing.T) {
+	fake := &fakePlatformCollector{processes: []*ProcessInfo{
+		{PID: 1, Name: "init", CPU: 0.5, RSS: 4096},
+		{PID: 2, Name: "db", CPU: 40, RSS: 1 << 30},
+		{PID: 3, Name: "web", CPU: 75, RSS: 1 << 28},
+		{PID: 4, Name: "cron", CPU: 0.5, RSS: 8192},
+		{PID: 5, Name: "cache", CPU: 10, RSS: 1 << 29},
+	}}
+	scanner := newTestScanner(t, fake)
+	scanner.performScan()
+
+	assertPIDs := func(name string, processes []*ProcessInfo, expected ...int) {
+		t.Helper()
+		if len(processes) != len(expected) {
+			t.Fatalf("%s: expected %d processes, got %d", name, len(expected), len(processes))
+		}
+		for i, proc := range processes {
+			if proc.PID != expected[i] {
+				t.Errorf("%s: expected PID %d at %d, got %d", name, expected[i], i, proc.PID)
+			}
+		}
+	}
+
+	assertPIDs("top CPU", scanner.GetTopByCPU(3), 3, 2, 5)
+	assertPIDs("top memory", scanner.GetTopByMemory(2), 2, 5)
+
+	// Ties go to the lower PID, and n may exceed the cache size
+	assertPIDs("all by CPU", scanner.GetTopByCPU(10), 3, 2, 5, 1, 4)
+
+	if top := scanner.GetTopByCPU(0); len(top) != 0 {
+		t.Errorf("Expected no processes for n=0, got %d", len(top))
+	}
+
+	// Results are copies of the cache
+	top := scanner.GetTopByCPU(1)
+	top[0].CPU = 0
+	if proc, _ := scanner.GetCachedProcess(3); proc.CPU != 75 {
+		t.Errorf("Expected cached process to be unchanged, got CPU %v", proc.CPU)
+	}
+}
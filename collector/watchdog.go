@@ -0,0 +1,228 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/watchdog"
+)
+
+const (
+	// scanHealthWindow is the number of recent scans GetHealth is derived from
+	scanHealthWindow = 5
+
+	// criticalScanErrors is the number of consecutive failed scans reported as critical
+	criticalScanErrors = 3
+
+	// criticalSlowScans is the number of consecutive scans over MaxScanTime
+	// reported as critical
+	criticalSlowScans = 3
+)
+
+// scanOutcome is the result of a scan as seen by GetHealth
+type scanOutcome struct {
+	failed   bool // The processes could not be read
+	breached bool // The scan exceeded MaxCPUUsage
+	slow     bool // The scan took longer than MaxScanTime
+}
+
+// Ensure the scanner can be supervised by the watchdog
+var (
+	_ watchdog.Monitorable = (*ProcessScanner)(nil)
+	_ watchdog.Degradable  = (*ProcessScanner)(nil)
+	_ watchdog.Restartable = (*WatchdogComponent)(nil)
+)
+
+// GetResourceUsage returns the resource usage of the scanner for the watchdog
+func (p *ProcessScanner) GetResourceUsage() watchdog.ResourceUsage {
+	resources := p.Resources()
+
+	return watchdog.ResourceUsage{
+		CPUPercent:  resources["cpu_percent"],
+		MemoryBytes: uint64(resources["memory_bytes"]),
+		Timestamp:   time.Now(),
+	}
+}
+
+// GetHealth derives the scanner health from the last scanHealthWindow scans.
+// It is critical when the last scans all failed or all took longer than
+// MaxScanTime, and degraded when any recent scan failed, exceeded a limit,
+// or while adaptive sampling has slowed down scanning
+func (p *ProcessScanner) GetHealth() watchdog.HealthStatus {
+	switch p.Status() {
+	case StatusError:
+		return watchdog.HealthCritical
+	case StatusRunning:
+	default:
+		return watchdog.HealthUnknown
+	}
+
+	// Read first, so intervalMutex and healthMutex are never held together
+	sampling := p.adaptiveSamplingActive()
+
+	p.healthMutex.Lock()
+	defer p.healthMutex.Unlock()
+
+	failed := func(outcome scanOutcome) bool { return outcome.failed }
+	slow := func(outcome scanOutcome) bool { return outcome.slow }
+
+	switch {
+	case len(p.scanOutcomes) == 0:
+		return watchdog.HealthUnknown
+	case lastScansAll(p.scanOutcomes, criticalScanErrors, failed) ||
+		lastScansAll(p.scanOutcomes, criticalSlowScans, slow):
+		return watchdog.HealthCritical
+	case sampling:
+		return watchdog.HealthDegraded
+	}
+
+	for _, outcome := range p.scanOutcomes {
+		if outcome.failed || outcome.breached || outcome.slow {
+			return watchdog.HealthDegraded
+		}
+	}
+	return watchdog.HealthOK
+}
+
+// lastScansAll reports whether there are at least n outcomes and the last n
+// all match
+func lastScansAll(outcomes []scanOutcome, n int, match func(scanOutcome) bool) bool {
+	if len(outcomes) < n {
+		return false
+	}
+	for _, outcome := range outcomes[len(outcomes)-n:] {
+		if !match(outcome) {
+			return false
+		}
+	}
+	return true
+}
+
+// adaptiveSamplingActive reports whether adaptive sampling has stretched the
+// scan interval beyond the configured one
+func (p *ProcessScanner) adaptiveSamplingActive() bool {
+	p.intervalMutex.Lock()
+	defer p.intervalMutex.Unlock()
+
+	return p.config.AdaptiveSampling && p.config.ScanInterval > p.degradedInterval()
+}
+
+// IsRunning returns whether the scanner is running
+func (p *ProcessScanner) IsRunning() bool {
+	return p.Status() == StatusRunning
+}
+
+// degradationFilterEvents is the first level that drops updated events of
+// processes below DegradedMinCPU. Every level doubles the scan interval, so
+// levels 1 and 2 match the reduce_scan_frequency and filter_events actions
+// of the default watchdog config
+const degradationFilterEvents = 2
+
+// maxDegradedInterval caps the scan interval stretched by degradation
+const maxDegradedInterval = time.Minute
+
+// SetDegradationLevel applies a degradation level requested by the watchdog.
+// Level 0 restores the configured scan interval and event filtering
+func (p *ProcessScanner) SetDegradationLevel(level int) error {
+	if level < 0 {
+		return fmt.Errorf("invalid degradation level: %d", level)
+	}
+
+	p.intervalMutex.Lock()
+	defer p.intervalMutex.Unlock()
+
+	if level == p.degradationLevel {
+		return nil
+	}
+	previous := p.degradationLevel
+	p.degradationLevel = level
+
+	// Adaptive sampling resumes from the degraded interval
+	interval := p.degradedInterval()
+	if interval != p.config.ScanInterval {
+		p.config.ScanInterval = interval
+		if p.scanTicker != nil {
+			p.scanTicker.Reset(interval)
+		}
+	}
+
+	p.metrics.SetGauge(MetricDegradationLevel, float64(level))
+	p.diagnostics.Emit(newDiagEvent(DiagDegradationChanged, DiagSeverityWarning,
+		map[string]interface{}{"from": previous, "to": level, "scan_interval": interval},
+		"Process scanner degradation level changed from %d to %d, scan interval %v", previous, level, interval))
+	return nil
+}
+
+// GetDegradationLevel returns the current degradation level
+func (p *ProcessScanner) GetDegradationLevel() int {
+	p.intervalMutex.Lock()
+	defer p.intervalMutex.Unlock()
+
+	return p.degradationLevel
+}
+
+// degradedInterval is the configured scan interval doubled for each level.
+// Caller must hold intervalMutex
+func (p *ProcessScanner) degradedInterval() time.Duration {
+	interval := p.baseInterval
+	for i := 0; i < p.degradationLevel && interval < maxDegradedInterval; i++ {
+		interval *= 2
+	}
+	if interval > maxDegradedInterval && p.baseInterval < maxDegradedInterval {
+		interval = maxDegradedInterval
+	}
+	return interval
+}
+
+// degradedMinCPU is the CPU floor for updated events, 0 when not filtering
+func (p *ProcessScanner) degradedMinCPU() float64 {
+	if p.GetDegradationLevel() < degradationFilterEvents {
+		return 0
+	}
+	return p.config.DegradedMinCPU
+}
+
+// WatchdogComponent adapts a ProcessScanner to the context-aware Start and
+// Shutdown of watchdog.Restartable. A restart stops and starts scanning but
+// keeps the platform collector, cache and consumers
+type WatchdogComponent struct {
+	*ProcessScanner
+}
+
+// NewWatchdogComponent wraps an initialized scanner for registration with a watchdog
+func NewWatchdogComponent(scanner *ProcessScanner) *WatchdogComponent {
+	return &WatchdogComponent{ProcessScanner: scanner}
+}
+
+// Start resumes scanning
+func (w *WatchdogComponent) Start(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if w.IsRunning() {
+		return nil
+	}
+
+	return w.ProcessScanner.Start()
+}
+
+// Shutdown stops scanning, giving up when ctx expires
+func (w *WatchdogComponent) Shutdown(ctx context.Context) error {
+	if status := w.Status(); status != StatusRunning && status != StatusPaused {
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- w.ProcessScanner.Stop()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out stopping process scanner: %w", ctx.Err())
+	}
+}
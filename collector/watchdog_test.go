@@ -0,0 +1,384 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/watchdog"
+)
+
+// fakePlatformCollector is a platform collector with scripted results
+type fakePlatformCollector struct {
+	mutex      sync.Mutex
+	processes  []*ProcessInfo
+	scanErr    error
+	cpuPercent float64
+	memBytes   uint64
+	hostTotal  uint64
+	hostUsed   uint64
+	memErr     error
+	delay      time.Duration // Time GetProcesses takes
+	caps       ProcessCapabilities
+}
+
+func (f *fakePlatformCollector) GetProcesses() ([]*ProcessInfo, error) {
+	f.mutex.Lock()
+	processes, err, delay := f.processes, f.scanErr, f.delay
+	f.mutex.Unlock()
+
+	time.Sleep(delay)
+	return processes, err
+}
+
+func (f *fakePlatformCollector) GetProcess(pid int) (*ProcessInfo, error) {
+	return nil, fmt.Errorf("process %d not found", pid)
+}
+
+func (f *fakePlatformCollector) IsProcessRunning(pid int) bool {
+	return false
+}
+
+func (f *fakePlatformCollector) GetProcessCount() (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return len(f.processes), nil
+}
+
+func (f *fakePlatformCollector) GetCPUTimes() error {
+	return nil
+}
+
+func (f *fakePlatformCollector) GetMemoryStats() (uint64, uint64, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.hostTotal, f.hostUsed, f.memErr
+}
+
+func (f *fakePlatformCollector) GetSelfUsage() (float64, uint64, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.cpuPercent, f.memBytes, nil
+}
+
+func (f *fakePlatformCollector) Capabilities() ProcessCapabilities {
+	return f.caps
+}
+
+func (f *fakePlatformCollector) Shutdown() error {
+	return nil
+}
+
+// setScanError scripts the error returned by the next scans
+func (f *fakePlatformCollector) setScanError(err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.scanErr = err
+}
+
+// newTestScanner returns an initialized scanner backed by a fake platform collector
+func newTestScanner(t *testing.T, fake *fakePlatformCollector) *ProcessScanner {
+	t.Helper()
+	config := DefaultConfig().ProcessScanner
+	config.ScanInterval = 10 * time.Millisecond
+	config.AdaptiveSampling = false
+
+	scanner := NewProcessScanner(config)
+	if err := scanner.Init(context.Background(), map[string]interface{}{"mockCollector": fake}); err != nil {
+		t.Fatalf("Failed to initialize scanner: %v", err)
+	}
+	return scanner
+}
+
+func TestProcessScanner_GetHealth(t *testing.T) {
+	fake := &fakePlatformCollector{processes: []*ProcessInfo{{PID: 1, Name: "init"}}}
+	scanner := newTestScanner(t, fake)
+
+	if health := scanner.GetHealth(); health != watchdog.HealthUnknown {
+		t.Errorf("Expected unknown health before start, got %s", health)
+	}
+
+	if err := scanner.Start(); err != nil {
+		t.Fatalf("Failed to start scanner: %v", err)
+	}
+	defer scanner.Stop()
+
+	if !waitFor(t, time.Second, func() bool { return scanner.GetHealth() == watchdog.HealthOK }) {
+		t.Errorf("Expected ok health, got %s", scanner.GetHealth())
+	}
+
+	// Repeated scan errors escalate to critical
+	fake.setScanError(fmt.Errorf("proc unavailable"))
+	if !waitFor(t, time.Second, func() bool { return scanner.GetHealth() == watchdog.HealthCritical }) {
+		t.Errorf("Expected critical health after scan errors, got %s", scanner.GetHealth())
+	}
+
+	fake.setScanError(nil)
+	if !waitFor(t, time.Second, func() bool { return scanner.GetHealth() == watchdog.HealthOK }) {
+		t.Errorf("Expected ok health after recovery, got %s", scanner.GetHealth())
+	}
+
+	// Exceeding MaxCPUUsage degrades health
+	fake.mutex.Lock()
+	fake.cpuPercent = 50
+	fake.memBytes = 64 * 1024 * 1024
+	fake.mutex.Unlock()
+	if !waitFor(t, time.Second, func() bool { return scanner.GetHealth() == watchdog.HealthDegraded }) {
+		t.Errorf("Expected degraded health over the CPU limit, got %s", scanner.GetHealth())
+	}
+
+	usage := scanner.GetResourceUsage()
+	if usage.CPUPercent != 50 || usage.MemoryMB() != 64 {
+		t.Errorf("Expected 50%% CPU and 64 MB, got %f%% and %f MB", usage.CPUPercent, usage.MemoryMB())
+	}
+}
+
+func TestProcessScanner_GetHealthWindow(t *testing.T) {
+	fake := &fakePlatformCollector{processes: []*ProcessInfo{{PID: 1, Name: "init"}}}
+	config := DefaultConfig().ProcessScanner
+	config.ScanInterval = time.Second
+	config.AdaptiveSampling = true
+	config.MaxScanTime = 10 * time.Millisecond
+
+	scanner := NewProcessScanner(config)
+	if err := scanner.Init(context.Background(), map[string]interface{}{"mockCollector": fake}); err != nil {
+		t.Fatalf("Failed to initialize scanner: %v", err)
+	}
+	if err := scanner.Start(); err != nil {
+		t.Fatalf("Failed to start scanner: %v", err)
+	}
+	defer scanner.Stop()
+
+	script := func(update func()) {
+		fake.mutex.Lock()
+		defer fake.mutex.Unlock()
+		update()
+	}
+	scan := func(times int) {
+		for i := 0; i < times; i++ {
+			scanner.ForceScanSync(context.Background())
+		}
+	}
+	expect := func(step string, expected watchdog.HealthStatus) {
+		t.Helper()
+		if health := scanner.GetHealth(); health != expected {
+			t.Errorf("%s: expected %s health, got %s", step, expected, health)
+		}
+	}
+
+	scan(scanHealthWindow)
+	expect("successful scans", watchdog.HealthOK)
+
+	// A failed scan degrades health until it leaves the window
+	script(func() { fake.scanErr = fmt.Errorf("proc unavailable") })
+	scan(1)
+	expect("one failed scan", watchdog.HealthDegraded)
+	script(func() { fake.scanErr = nil })
+	scan(scanHealthWindow - 1)
+	expect("failure in the window", watchdog.HealthDegraded)
+	scan(1)
+	expect("failure out of the window", watchdog.HealthOK)
+
+	// Scans over MaxScanTime degrade health, repeatedly they are critical
+	script(func() { fake.delay = 20 * time.Millisecond })
+	scan(criticalSlowScans - 1)
+	expect("slow scans", watchdog.HealthDegraded)
+	scan(1)
+	expect("repeated slow scans", watchdog.HealthCritical)
+	script(func() { fake.delay = 0 })
+	scan(scanHealthWindow)
+	expect("fast scans", watchdog.HealthOK)
+
+	// CPU at MaxCPUUsage is within the limit
+	limit := config.MaxCPUUsage
+	script(func() { fake.cpuPercent = limit })
+	scan(1)
+	expect("CPU at the limit", watchdog.HealthOK)
+
+	// Adaptive sampling keeps health degraded after the CPU breach left the
+	// window, as CPU between half the limit and the limit keeps the interval
+	script(func() { fake.cpuPercent = 1.6 * limit })
+	scan(1)
+	expect("CPU over the limit", watchdog.HealthDegraded)
+	script(func() { fake.cpuPercent = 0.8 * limit })
+	scan(scanHealthWindow)
+	expect("adaptive sampling", watchdog.HealthDegraded)
+
+	// Low CPU brings the interval back to the configured one
+	script(func() { fake.cpuPercent = 0.1 * limit })
+	scan(scanHealthWindow)
+	expect("interval restored", watchdog.HealthOK)
+}
+
+func TestWatchdogComponent_Restart(t *testing.T) {
+	fake := &fakePlatformCollector{processes: []*ProcessInfo{{PID: 1, Name: "init"}}}
+	scanner := newTestScanner(t, fake)
+	component := NewWatchdogComponent(scanner)
+
+	if err := component.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start component: %v", err)
+	}
+	if err := component.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Failed to shut down component: %v", err)
+	}
+	if component.IsRunning() {
+		t.Fatalf("Expected component to be stopped")
+	}
+
+	// Starting again derives a new context and resumes scanning
+	if err := component.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to restart component: %v", err)
+	}
+	defer component.Shutdown(context.Background())
+
+	fake.mutex.Lock()
+	fake.processes = append(fake.processes, &ProcessInfo{PID: 2, Name: "restarted"})
+	fake.mutex.Unlock()
+
+	if !waitFor(t, time.Second, func() bool { _, ok := scanner.GetCachedProcess(2); return ok }) {
+		t.Errorf("Expected scanning to resume after restart")
+	}
+}
+
+func TestWatchdog_RestartsScannerOverCPUThreshold(t *testing.T) {
+	fake := &fakePlatformCollector{
+		processes:  []*ProcessInfo{{PID: 1, Name: "init"}},
+		cpuPercent: 95.0,
+	}
+	scanner := newTestScanner(t, fake)
+	component := NewWatchdogComponent(scanner)
+
+	if err := component.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start component: %v", err)
+	}
+	defer component.Shutdown(context.Background())
+
+	config := watchdog.DefaultConfig()
+	config.MonitoringInterval = 10 * time.Millisecond
+	config.DeadlockDetection.Enabled = false
+	config.GlobalBudget = watchdog.GlobalBudgetConfig{}
+	config.ComponentConfigs = map[string]watchdog.ComponentConfig{
+		"process_scanner": {
+			Enabled:            true,
+			MaxCPUPercent:      90.0,
+			MaxMemoryMB:        1000,
+			MaxFileDescriptors: 1000,
+			MaxGoroutines:      1000,
+			MaxGCPercent:       10.0,
+			CircuitBreaker: watchdog.CircuitBreakerConfig{
+				Enabled:                  true,
+				FailureThreshold:         3,
+				ResetTimeout:             30 * time.Second,
+				HalfOpenSuccessThreshold: 2,
+			},
+		},
+	}
+
+	wd, err := watchdog.NewWatchdog(config)
+	if err != nil {
+		t.Fatalf("Failed to create watchdog: %v", err)
+	}
+
+	if err := wd.RegisterComponent("process_scanner", component); err != nil {
+		t.Fatalf("Failed to register scanner: %v", err)
+	}
+
+	if err := wd.Start(); err != nil {
+		t.Fatalf("Failed to start watchdog: %v", err)
+	}
+	defer wd.Stop()
+
+	// Wait for the circuit to open and the restart to happen
+	restarted := waitFor(t, time.Second, func() bool {
+		status, err := wd.GetComponentStatus("process_scanner")
+		return err == nil && status.RestartCount >= 1
+	})
+	if !restarted {
+		t.Fatalf("Expected the watchdog to restart the scanner")
+	}
+
+	status, _ := wd.GetComponentStatus("process_scanner")
+	if status.ResourceUsage.CPUPercent != 95.0 {
+		t.Errorf("Expected reported CPU of 95%%, got %f", status.ResourceUsage.CPUPercent)
+	}
+	if status.LastRestart.IsZero() {
+		t.Errorf("Expected last restart time to be set")
+	}
+	if !scanner.IsRunning() {
+		t.Errorf("Expected scanner to be running after restart")
+	}
+}
+
+func TestProcessScanner_SetDegradationLevel(t *testing.T) {
+	fake := &fakePlatformCollector{processes: []*ProcessInfo{
+		{PID: 1, Name: "idle", CPU: 0.1},
+		{PID: 2, Name: "busy", CPU: 5},
+	}}
+	scanner := newTestScanner(t, fake)
+	base := scanner.config.ScanInterval
+
+	if err := scanner.SetDegradationLevel(-1); err == nil {
+		t.Errorf("Expected error for negative level")
+	}
+
+	// updatedPIDs rescans with new CPU values and returns the PIDs of the updated events
+	updatedPIDs := func(idleCPU, busyCPU float64) map[int]bool {
+		fake.mutex.Lock()
+		fake.processes = []*ProcessInfo{
+			{PID: 1, Name: "idle", CPU: idleCPU},
+			{PID: 2, Name: "busy", CPU: busyCPU},
+		}
+		fake.mutex.Unlock()
+
+		scanner.performScan()
+		pids := make(map[int]bool)
+		for len(scanner.eventChannel) > 0 {
+			if event := <-scanner.eventChannel; event.Type == ProcessUpdated {
+				pids[event.Process.PID] = true
+			}
+		}
+		return pids
+	}
+	updatedPIDs(0.1, 5)
+
+	if err := scanner.SetDegradationLevel(1); err != nil {
+		t.Fatalf("SetDegradationLevel returned error: %v", err)
+	}
+	if scanner.config.ScanInterval != 2*base {
+		t.Errorf("Expected scan interval %v at level 1, got %v", 2*base, scanner.config.ScanInterval)
+	}
+	if pids := updatedPIDs(0.2, 6); !pids[1] || !pids[2] {
+		t.Errorf("Expected updates of both processes at level 1, got %v", pids)
+	}
+
+	// Level 2 also filters updates of idle processes
+	if err := scanner.SetDegradationLevel(2); err != nil {
+		t.Fatalf("SetDegradationLevel returned error: %v", err)
+	}
+	if scanner.config.ScanInterval != 4*base {
+		t.Errorf("Expected scan interval %v at level 2, got %v", 4*base, scanner.config.ScanInterval)
+	}
+	if pids := updatedPIDs(0.3, 7); pids[1] || !pids[2] {
+		t.Errorf("Expected only the busy process update at level 2, got %v", pids)
+	}
+	if filtered := scanner.metrics.GetCounter(MetricEventsFiltered); filtered != 1 {
+		t.Errorf("Expected 1 filtered event, got %d", filtered)
+	}
+	if level := scanner.Metrics()[MetricDegradationLevel]; level != 2 {
+		t.Errorf("Expected degradation level metric 2, got %v", level)
+	}
+
+	// Level 0 restores normal operation
+	if err := scanner.SetDegradationLevel(0); err != nil {
+		t.Fatalf("SetDegradationLevel returned error: %v", err)
+	}
+	if scanner.GetDegradationLevel() != 0 || scanner.config.ScanInterval != base {
+		t.Errorf("Expected level 0 and scan interval %v, got %d and %v",
+			base, scanner.GetDegradationLevel(), scanner.config.ScanInterval)
+	}
+	if pids := updatedPIDs(0.4, 8); !pids[1] || !pids[2] {
+		t.Errorf("Expected updates of both processes after restoring, got %v", pids)
+	}
+}
@@ -28,8 +28,8 @@ type TopNConfig struct {
 	// CPUWeight is the weight given to CPU usage in scoring
 	CPUWeight float64 `yaml:"cpuWeight"`
 
-	// RSSWeight is the weight given to memory usage in scoring
-	RSSWeight float64 `yaml:"rssWeight"`
+	// MemoryWeight is the weight given to memory usage in scoring
+	MemoryWeight float64 `yaml:"memoryWeight"`
 
 	// MinScore is the minimum score a process must have to be tracked
 	MinScore float64 `yaml:"minScore"`
@@ -42,6 +42,31 @@ type TopNConfig struct {
 
 	// ChurnThreshold is the PID churn rate that activates optimizations
 	ChurnThreshold int `yaml:"churnThreshold"`
+
+	// HysteresisMargin is the fraction by which a process outside a full top N
+	// must exceed the score of the lowest tracked process to replace it
+	HysteresisMargin float64 `yaml:"hysteresisMargin"`
+
+	// HysteresisSamples is the number of consecutive samples a process must
+	// exceed the margin for before it replaces the lowest tracked process, 0
+	// replaces it on the first sample. Requires ChurnHandlingEnabled
+	HysteresisSamples int `yaml:"hysteresisSamples"`
+
+	// MaxSamplerCPU is the CPU percentage of Update above which the sampler
+	// only processes a subset of the processes, 0 disables the limit
+	MaxSamplerCPU float64 `yaml:"maxSamplerCPU"`
+
+	// SketchEnabled feeds the CPU and memory of every process sample into
+	// sketches, for percentiles over all processes rather than the top N
+	SketchEnabled bool `yaml:"sketchEnabled"`
+
+	// RollupWindow is the longest window Rollup can return percentiles for,
+	// 0 disables rollups. Requires SketchEnabled
+	RollupWindow time.Duration `yaml:"rollupWindow"`
+
+	// RollupBuckets is the number of sub-windows of RollupWindow, old scans
+	// age out at a granularity of RollupWindow/RollupBuckets
+	RollupBuckets int `yaml:"rollupBuckets"`
 }
 
 // DefaultConfig returns a Config with sensible defaults
@@ -53,11 +78,17 @@ func DefaultConfig() Config {
 		TopN: TopNConfig{
 			MaxProcesses:        500,
 			CPUWeight:           0.7,
-			RSSWeight:           0.3,
+			MemoryWeight:        0.3,
 			MinScore:            0.001,
 			StabilityFactor:     0.8,
 			ChurnHandlingEnabled: true,
 			ChurnThreshold:      2000, // 2000 PIDs/s
+			HysteresisMargin:    0.1,
+			HysteresisSamples:   3,
+			MaxSamplerCPU:       0.5,
+			SketchEnabled:       true,
+			RollupWindow:        5 * time.Minute,
+			RollupBuckets:       20,
 		},
 	}
 }
@@ -81,11 +112,11 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("max processes must be positive")
 	}
 
-	if c.TopN.CPUWeight < 0 || c.TopN.RSSWeight < 0 {
+	if c.TopN.CPUWeight < 0 || c.TopN.MemoryWeight < 0 {
 		return fmt.Errorf("weights cannot be negative")
 	}
 
-	if c.TopN.CPUWeight+c.TopN.RSSWeight == 0 {
+	if c.TopN.CPUWeight+c.TopN.MemoryWeight == 0 {
 		return fmt.Errorf("at least one weight must be positive")
 	}
 
@@ -93,5 +124,21 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("stability factor must be between 0 and 1")
 	}
 
+	if c.TopN.RollupWindow < 0 {
+		return fmt.Errorf("rollup window cannot be negative")
+	}
+
+	if c.TopN.RollupWindow > 0 && c.TopN.RollupBuckets <= 0 {
+		return fmt.Errorf("rollup buckets must be positive")
+	}
+
+	if c.TopN.HysteresisMargin < 0 {
+		return fmt.Errorf("hysteresis margin cannot be negative")
+	}
+
+	if c.TopN.HysteresisSamples < 0 {
+		return fmt.Errorf("hysteresis samples cannot be negative")
+	}
+
 	return nil
 }
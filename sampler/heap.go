@@ -2,6 +2,7 @@ package sampler
 
 import (
 	"container/heap"
+	"sort"
 	"sync"
 )
 
@@ -13,6 +14,7 @@ type ProcessHeap struct {
 	pidMap    map[int]int // Maps PID to index in the heap
 	mutex     sync.RWMutex
 	maxSize   int
+	exits     uint64 // Processes evicted or removed since creation
 }
 
 // NewProcessHeap creates a new process heap with the specified maximum size.
@@ -31,6 +33,17 @@ func (h *ProcessHeap) Len() int {
 	return len(h.processes)
 }
 
+// lockedHeap is the heap.Interface of a ProcessHeap whose mutex is already
+// held, as Len would otherwise try to lock it again.
+type lockedHeap struct {
+	*ProcessHeap
+}
+
+// Len returns the number of processes in the heap.
+func (h lockedHeap) Len() int {
+	return len(h.processes)
+}
+
 // Less returns whether the process at index i has a lower score than the process at index j.
 func (h *ProcessHeap) Less(i, j int) bool {
 	// Min heap based on score (lower score at the root)
@@ -79,13 +92,14 @@ func (h *ProcessHeap) Update(process *ProcessInfo) bool {
 		// Process not in heap yet
 		if len(h.processes) < h.maxSize {
 			// Heap not full, add the process
-			heap.Push(h, process)
+			heap.Push(lockedHeap{h}, process)
 			return true
 		} else if len(h.processes) > 0 && process.Score > h.processes[0].Score {
 			// Heap full but new process has higher score than minimum
 			// Remove lowest scoring process and add the new one
-			heap.Pop(h)
-			heap.Push(h, process)
+			heap.Pop(lockedHeap{h})
+			heap.Push(lockedHeap{h}, process)
+			h.exits++
 			return true
 		}
 		// Process not important enough to track
@@ -98,7 +112,7 @@ func (h *ProcessHeap) Update(process *ProcessInfo) bool {
 	h.processes[idx].RSS = process.RSS
 	h.processes[idx].Command = process.Command
 	h.processes[idx].Name = process.Name
-	heap.Fix(h, idx)
+	heap.Fix(lockedHeap{h}, idx)
 	return true
 }
 
@@ -112,10 +126,55 @@ func (h *ProcessHeap) Remove(pid int) bool {
 		return false
 	}
 
-	heap.Remove(h, idx)
+	heap.Remove(lockedHeap{h}, idx)
+	h.exits++
 	return true
 }
 
+// MinScore returns the lowest score in the heap, and whether the heap is
+// full, so a new process must beat that score to be tracked.
+func (h *ProcessHeap) MinScore() (float64, bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	if len(h.processes) == 0 {
+		return 0, false
+	}
+	return h.processes[0].Score, len(h.processes) >= h.maxSize
+}
+
+// Exits returns the number of processes that left the heap, either evicted
+// by a higher scoring process or removed.
+func (h *ProcessHeap) Exits() uint64 {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.exits
+}
+
+// TotalCPU returns the sum of the CPU usage of the processes in the heap.
+func (h *ProcessHeap) TotalCPU() float64 {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	total := 0.0
+	for _, p := range h.processes {
+		total += p.CPU
+	}
+	return total
+}
+
+// TotalRSS returns the sum of the RSS of the processes in the heap.
+func (h *ProcessHeap) TotalRSS() int64 {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	var total int64
+	for _, p := range h.processes {
+		total += p.RSS
+	}
+	return total
+}
+
 // TopN returns the top N processes with highest scores.
 // This operation is O(N log N) due to sorting.
 func (h *ProcessHeap) TopN(n int) []*ProcessInfo {
@@ -127,8 +186,8 @@ func (h *ProcessHeap) TopN(n int) []*ProcessInfo {
 	copy(processes, h.processes)
 
 	// Sort by score in descending order
-	sort := &processScoreSort{processes: processes}
-	sort.Sort()
+	sorter := &processScoreSort{processes: processes}
+	sorter.Sort()
 
 	// Return at most n processes
 	if n > len(processes) {
@@ -156,32 +215,5 @@ func (s *processScoreSort) Swap(i, j int) {
 }
 
 func (s *processScoreSort) Sort() {
-	// Implementation of sort.Sort
-	n := s.Len()
-	for i := n/2 - 1; i >= 0; i-- {
-		s.heapify(n, i)
-	}
-	for i := n - 1; i >= 0; i-- {
-		s.Swap(0, i)
-		s.heapify(i, 0)
-	}
-}
-
-func (s *processScoreSort) heapify(n, i int) {
-	largest := i
-	left := 2*i + 1
-	right := 2*i + 2
-
-	if left < n && s.Less(left, largest) {
-		largest = left
-	}
-
-	if right < n && s.Less(right, largest) {
-		largest = right
-	}
-
-	if largest != i {
-		s.Swap(i, largest)
-		s.heapify(n, largest)
-	}
+	sort.Sort(s)
 }
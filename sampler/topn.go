@@ -7,8 +7,15 @@ import (
 	"runtime"
 	"sync"
 	"time"
+
+	"github.com/newrelic/infrastructure-agent/collector"
+	"github.com/newrelic/infrastructure-agent/sketch"
 )
 
+// churnWindow is the interval over which the churn rate of process events
+// is measured
+const churnWindow = time.Second
+
 // Register the TopN sampler at package initialization
 func init() {
 	RegisterSampler("topn", func() Sampler {
@@ -17,27 +24,68 @@ func init() {
 }
 
 // TopNSampler implements a sampler that tracks the top N processes
-// based on a configurable scoring function.
+// based on a configurable scoring function. It is fed either by Update with
+// full process lists, or as a collector.ProcessConsumer with the events of a
+// process scanner.
 type TopNSampler struct {
 	config        TopNConfig
 	heap          *ProcessHeap
 	metrics       map[string]float64
 	pidHistory    map[int]bool
 	seenPIDs      map[int]time.Time
-	churnRate     float64
+	churnRate     float64 // Processes created per second
 	lastUpdate    time.Time
 	samplerStart  time.Time
 	ctx           context.Context
 	cancel        context.CancelFunc
 	mu            sync.RWMutex
 	circuitOpen   bool
-	totalCPUUsage float64 // Total CPU usage as percentage
-	totalRSSUsage int64   // Total RSS in bytes
+	totalCPUUsage float64              // Total CPU usage as percentage
+	totalRSSUsage int64                // Total RSS in bytes
+	totalCount    int                  // Number of processes in the totals
+	processes     map[int]*ProcessInfo // Last sample of each live process, fed by HandleProcessEvent
+	created       int                  // Processes created since churnStart
+	churnStart    time.Time
+	candidates    map[int]int // Consecutive samples each untracked process beat the hysteresis margin
+	topChurnRate  float64     // Processes leaving the top N per second
+	lastExits     uint64      // Heap exits at the last topChurnRate update
+	topChurnStart time.Time
+	cpuSketch     *sketch.DDSketch               // CPU of every sample, nil unless SketchEnabled
+	memorySketch  *sketch.DDSketch               // RSS of every sample, nil unless SketchEnabled
+	scanCPU       *sketch.DDSketch               // CPU of the samples since the last scan was rolled up
+	scanMemory    *sketch.DDSketch               // RSS of the samples since the last scan was rolled up
+	cpuRollup     *sketch.WindowedSketch         // Scans of the last RollupWindow, nil unless enabled
+	memoryRollup  *sketch.WindowedSketch         // Scans of the last RollupWindow, nil unless enabled
+	capabilities  *collector.ProcessCapabilities // Fields the scanner collects, nil if unknown
+}
+
+// Percentiles are the usual percentiles of a distribution.
+type Percentiles struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+}
+
+// RollupSnapshot holds the percentiles of the process samples of the scans
+// in a rollup window.
+type RollupSnapshot struct {
+	// Window is the time span covered by the snapshot
+	Window time.Duration `json:"window"`
+
+	// Samples is the number of process samples in the window
+	Samples uint64 `json:"samples"`
+
+	// CPU holds the percentiles of the CPU usage
+	CPU Percentiles `json:"cpu"`
+
+	// Memory holds the percentiles of the RSS in bytes
+	Memory Percentiles `json:"memory"`
 }
 
 // NewTopNSampler creates a new TopN sampler with the given configuration.
 func NewTopNSampler(config TopNConfig) *TopNSampler {
-	return &TopNSampler{
+	s := &TopNSampler{
 		config:       config,
 		heap:         NewProcessHeap(config.MaxProcesses),
 		metrics:      make(map[string]float64),
@@ -46,7 +94,26 @@ func NewTopNSampler(config TopNConfig) *TopNSampler {
 		lastUpdate:   time.Now(),
 		samplerStart: time.Now(),
 		circuitOpen:  false,
+		processes:    make(map[int]*ProcessInfo),
+		candidates:   make(map[int]int),
+	}
+
+	if config.SketchEnabled {
+		// Idle processes use 0% CPU, which needs the zero bucket
+		sketchConfig := sketch.DefaultConfig().DDSketch
+		sketchConfig.AllowNegative = true
+		s.cpuSketch = sketch.NewDDSketch(sketchConfig)
+		s.memorySketch = sketch.NewDDSketch(sketchConfig)
+
+		if config.RollupWindow > 0 {
+			window := sketch.WindowConfig{WindowDuration: config.RollupWindow, Buckets: config.RollupBuckets}
+			s.scanCPU = sketch.NewDDSketch(sketchConfig)
+			s.scanMemory = sketch.NewDDSketch(sketchConfig)
+			s.cpuRollup = sketch.NewWindowedSketch(sketchConfig, window, nil)
+			s.memoryRollup = sketch.NewWindowedSketch(sketchConfig, window, nil)
+		}
 	}
+	return s
 }
 
 // Init initializes the sampler with a context.
@@ -112,13 +179,14 @@ func (s *TopNSampler) Update(processes []*ProcessInfo) error {
 		p.Score = s.calculateScore(p)
 
 		// Update process in heap
-		if s.heap.Update(p) {
+		if s.offer(p) {
 			processesUpdated++
 		}
 
 		// Track totals for metrics
 		totalCPU += p.CPU
 		totalRSS += p.RSS
+		s.addToSketches(p)
 	}
 
 	// Clean up old PIDs
@@ -139,35 +207,335 @@ func (s *TopNSampler) Update(processes []*ProcessInfo) error {
 	// Update PID history for next churn calculation
 	s.pidHistory = newPIDs
 
+	// Samples are only consecutive if the process was in every update
+	for pid := range s.candidates {
+		if !newPIDs[pid] {
+			delete(s.candidates, pid)
+		}
+	}
+	if elapsed > 0 {
+		s.updateTopChurn(elapsed)
+	}
+
 	// Store CPU and RSS totals
 	s.totalCPUUsage = totalCPU
 	s.totalRSSUsage = totalRSS
+	s.totalCount = len(processes)
 
 	// Calculate metrics
 	processingTime := time.Since(start).Seconds()
 	s.metrics["topn_update_time_seconds"] = processingTime
 	s.metrics["topn_processes_tracked"] = float64(s.heap.Len())
+	s.metrics["topn_processes_sampled"] = float64(s.heap.Len())
 	s.metrics["topn_processes_updated"] = float64(processesUpdated)
-	s.metrics["topn_churn_rate"] = s.churnRate
+	s.metrics["topn_churn_rate"] = s.topChurnRate
+	s.metrics["topn_pid_churn_rate"] = s.churnRate
 	s.metrics["topn_circuit_breaker"] = 0
 	if s.circuitOpen {
 		s.metrics["topn_circuit_breaker"] = 1
 	}
 
-	// Calculate capture ratio (percentage of total resource captured by tracked processes)
-	if s.totalCPUUsage > 0 {
-		trackedCPU := 0.0
-		for _, p := range s.heap.TopN(s.config.MaxProcesses) {
-			trackedCPU += p.CPU
+	s.metrics["topn_capture_ratio"] = s.captureRatio()
+
+	// Each update is a scan of its own
+	return s.rollupScan()
+}
+
+// HandleProcessEvent implements collector.ProcessConsumer. The scanner only
+// reports processes that changed, so the last sample of each process is
+// kept until its terminated event.
+func (s *TopNSampler) HandleProcessEvent(event collector.ProcessEvent) error {
+	if event.Process == nil {
+		return fmt.Errorf("process event without process")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pid := event.Process.PID
+	previous, known := s.processes[pid]
+	var previousScore float64
+	if known {
+		previousScore = previous.Score
+		s.totalCPUUsage -= previous.CPU
+		s.totalRSSUsage -= previous.RSS
+	}
+
+	if event.Type == collector.ProcessTerminated {
+		delete(s.processes, pid)
+		delete(s.candidates, pid)
+		if s.heap.Remove(pid) {
+			s.refillHeap()
 		}
-		s.metrics["topn_capture_ratio"] = (trackedCPU / s.totalCPUUsage) * 100
 	} else {
-		s.metrics["topn_capture_ratio"] = 100 // If no CPU usage, we capture 100%
+		p := &ProcessInfo{
+			PID:       pid,
+			Name:      event.Process.Name,
+			Command:   event.Process.Command,
+			CPU:       event.Process.CPU,
+			RSS:       event.Process.RSS,
+			StartTime: event.Process.StartTime,
+		}
+		s.processes[pid] = p
+		s.totalCPUUsage += p.CPU
+		s.totalRSSUsage += p.RSS
+		s.addToSketches(p)
+
+		p.Score = s.calculateScore(p)
+		wasTop := s.heap.Contains(pid)
+		s.offer(p)
+
+		// A process outside the heap may now score higher than this one. With
+		// hysteresis it has to win its place with its own samples instead
+		if wasTop && p.Score < previousScore && !s.hysteresisEnabled() {
+			s.refillHeap()
+		}
+
+		if !known {
+			s.countCreated(event.Timestamp)
+		}
+	}
+	s.totalCount = len(s.processes)
+
+	if s.topChurnStart.IsZero() {
+		s.topChurnStart = event.Timestamp
+	} else if elapsed := event.Timestamp.Sub(s.topChurnStart); elapsed >= churnWindow {
+		s.updateTopChurn(elapsed.Seconds())
+		s.topChurnStart = event.Timestamp
 	}
 
+	s.metrics["topn_processes_tracked"] = float64(s.heap.Len())
+	s.metrics["topn_processes_sampled"] = float64(s.heap.Len())
+	s.metrics["topn_churn_rate"] = s.topChurnRate
+	s.metrics["topn_pid_churn_rate"] = s.churnRate
+	s.metrics["topn_capture_ratio"] = s.captureRatio()
+
 	return nil
 }
 
+// hysteresisEnabled returns whether processes outside a full heap need
+// HysteresisSamples consecutive samples to replace a tracked process.
+func (s *TopNSampler) hysteresisEnabled() bool {
+	return s.config.ChurnHandlingEnabled && s.config.HysteresisSamples > 0
+}
+
+// offer updates a process in the heap. With hysteresis, a process outside a
+// full heap only replaces the lowest tracked process once its score exceeded
+// the lowest score by HysteresisMargin for HysteresisSamples consecutive
+// samples, so borderline processes do not flap in and out of the top N.
+// Caller must hold mu.
+func (s *TopNSampler) offer(p *ProcessInfo) bool {
+	if !s.hysteresisEnabled() || s.heap.Contains(p.PID) {
+		return s.heap.Update(p)
+	}
+
+	lowest, full := s.heap.MinScore()
+	if !full {
+		delete(s.candidates, p.PID)
+		return s.heap.Update(p)
+	}
+
+	if p.Score <= lowest*(1+s.config.HysteresisMargin) {
+		delete(s.candidates, p.PID)
+		return false
+	}
+
+	s.candidates[p.PID]++
+	if s.candidates[p.PID] < s.config.HysteresisSamples {
+		return false
+	}
+	delete(s.candidates, p.PID)
+	return s.heap.Update(p)
+}
+
+// updateTopChurn folds the processes that left the top N in the last elapsed
+// seconds into the membership churn rate. Caller must hold mu.
+func (s *TopNSampler) updateTopChurn(elapsed float64) {
+	exits := s.heap.Exits()
+	s.topChurnRate = 0.7*s.topChurnRate + 0.3*float64(exits-s.lastExits)/elapsed
+	s.lastExits = exits
+}
+
+// refillHeap offers the known processes outside the heap to it, after a
+// tracked process left or scored lower. Caller must hold mu.
+func (s *TopNSampler) refillHeap() {
+	for pid, p := range s.processes {
+		if !s.heap.Contains(pid) {
+			s.heap.Update(p)
+		}
+	}
+}
+
+// countCreated counts a created process, and updates the churn rate once
+// churnWindow elapsed since the last update. Caller must hold mu.
+func (s *TopNSampler) countCreated(timestamp time.Time) {
+	if !s.config.ChurnHandlingEnabled {
+		return
+	}
+
+	if s.churnStart.IsZero() {
+		s.churnStart = timestamp
+	}
+	s.created++
+
+	elapsed := timestamp.Sub(s.churnStart)
+	if elapsed >= churnWindow {
+		s.churnRate = 0.7*s.churnRate + 0.3*float64(s.created)/elapsed.Seconds()
+		s.created = 0
+		s.churnStart = timestamp
+	}
+}
+
+// captureRatio returns the percentage of the total CPU used by the tracked
+// processes. Caller must hold mu.
+func (s *TopNSampler) captureRatio() float64 {
+	if s.totalCPUUsage <= 0 {
+		return 100 // If no CPU usage, we capture 100%
+	}
+	return (s.heap.TotalCPU() / s.totalCPUUsage) * 100
+}
+
+// addToSketches adds the CPU and RSS of a process sample to the sketches,
+// if enabled. Caller must hold mu.
+func (s *TopNSampler) addToSketches(p *ProcessInfo) {
+	if s.cpuSketch == nil {
+		return
+	}
+	// Out of range values are dropped, the percentiles stay approximate
+	_ = s.cpuSketch.Add(p.CPU)
+	_ = s.memorySketch.Add(float64(p.RSS))
+	if s.scanCPU != nil {
+		_ = s.scanCPU.Add(p.CPU)
+		_ = s.scanMemory.Add(float64(p.RSS))
+	}
+}
+
+// rollupScan merges the samples of the current scan into the rollup
+// windows, and starts a new scan. Caller must hold mu.
+func (s *TopNSampler) rollupScan() error {
+	if s.scanCPU == nil || s.scanCPU.GetCount() == 0 {
+		return nil
+	}
+
+	defer s.scanCPU.Reset()
+	defer s.scanMemory.Reset()
+	if err := s.cpuRollup.Merge(s.scanCPU); err != nil {
+		return fmt.Errorf("failed to roll up CPU sketch: %w", err)
+	}
+	if err := s.memoryRollup.Merge(s.scanMemory); err != nil {
+		return fmt.Errorf("failed to roll up memory sketch: %w", err)
+	}
+	return nil
+}
+
+// Rollup returns the percentiles of the process samples of the scans in the
+// last window, which cannot exceed RollupWindow. The samples received from
+// process events since the last Update count as a scan.
+func (s *TopNSampler) Rollup(window time.Duration) (RollupSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cpuRollup == nil {
+		return RollupSnapshot{}, fmt.Errorf("rollups are disabled")
+	}
+	if window <= 0 || window > s.config.RollupWindow {
+		return RollupSnapshot{}, fmt.Errorf("rollup window must be between 0 and %s", s.config.RollupWindow)
+	}
+
+	if err := s.rollupScan(); err != nil {
+		return RollupSnapshot{}, err
+	}
+
+	cpu := s.cpuRollup.SnapshotLast(window)
+	memory := s.memoryRollup.SnapshotLast(window)
+	return RollupSnapshot{
+		Window:  window,
+		Samples: cpu.GetCount(),
+		CPU:     snapshotPercentiles(cpu),
+		Memory:  snapshotPercentiles(memory),
+	}, nil
+}
+
+// snapshotPercentiles returns the percentiles of a sketch, zero if it is
+// empty.
+func snapshotPercentiles(sketch *sketch.DDSketch) Percentiles {
+	return Percentiles{
+		P50: percentile(sketch, 0.5),
+		P90: percentile(sketch, 0.9),
+		P95: percentile(sketch, 0.95),
+		P99: percentile(sketch, 0.99),
+	}
+}
+
+// GetCPUPercentile returns the CPU usage at quantile q, 0-1, of all process
+// samples received. It returns 0 if SketchEnabled is off or no sample was
+// received.
+func (s *TopNSampler) GetCPUPercentile(q float64) float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return percentile(s.cpuSketch, q)
+}
+
+// GetMemoryPercentile returns the RSS in bytes at quantile q, 0-1, of all
+// process samples received. It returns 0 if SketchEnabled is off or no sample
+// was received.
+func (s *TopNSampler) GetMemoryPercentile(q float64) float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return percentile(s.memorySketch, q)
+}
+
+// percentile returns the value at quantile q of a sketch, 0 if the sketch is
+// nil or empty.
+func percentile(sketch *sketch.DDSketch, q float64) float64 {
+	if sketch == nil {
+		return 0
+	}
+	value, err := sketch.GetValueAtQuantile(q)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// OthersAggregate is the resource usage of the processes below the top N cut
+// line, which are not retained individually.
+type OthersAggregate struct {
+	// CPU is the CPU usage of the other processes
+	CPU float64 `json:"cpu"`
+
+	// Memory is the RSS of the other processes in bytes
+	Memory int64 `json:"memory"`
+
+	// Count is the number of other processes
+	Count int `json:"count"`
+}
+
+// GetOthers returns the usage of the processes not in the top N, so the top
+// N and the others add up to the totals.
+func (s *TopNSampler) GetOthers() OthersAggregate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Tracked processes missing from the last Update are not in the totals
+	return OthersAggregate{
+		CPU:    math.Max(0, s.totalCPUUsage-s.heap.TotalCPU()),
+		Memory: max(0, s.totalRSSUsage-s.heap.TotalRSS()),
+		Count:  max(0, s.totalCount-s.heap.Len()),
+	}
+}
+
+// GetTotalCPU returns the CPU usage of all processes, from the last Update or
+// the last sample of each live process.
+func (s *TopNSampler) GetTotalCPU() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.totalCPUUsage
+}
+
 // GetTopN returns the top N processes according to the sampling strategy.
 func (s *TopNSampler) GetTopN(n int) []*ProcessInfo {
 	s.mu.RLock()
@@ -211,6 +579,34 @@ func (s *TopNSampler) Shutdown() error {
 	return nil
 }
 
+// SetCapabilities tells the sampler which process fields the scanner
+// collects, e.g. from ProcessScanner.Capabilities. When only one of CPU and
+// RSS is collected, processes are ranked on it with the weight of both, so
+// MinScore keeps its meaning. Call it before the first sample.
+func (s *TopNSampler) SetCapabilities(capabilities collector.ProcessCapabilities) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.capabilities = &capabilities
+}
+
+// scoreWeights returns the CPU and memory weights, moving the weight of a
+// field the scanner does not collect to the other one. Caller must hold mu.
+func (s *TopNSampler) scoreWeights() (float64, float64) {
+	cpuWeight, memoryWeight := s.config.CPUWeight, s.config.MemoryWeight
+	if s.capabilities == nil {
+		return cpuWeight, memoryWeight
+	}
+
+	switch {
+	case s.capabilities.CPUPercent && !s.capabilities.RSS:
+		return cpuWeight + memoryWeight, 0
+	case !s.capabilities.CPUPercent && s.capabilities.RSS:
+		return 0, cpuWeight + memoryWeight
+	}
+	return cpuWeight, memoryWeight
+}
+
 // calculateScore computes a score for a process based on CPU and RSS.
 func (s *TopNSampler) calculateScore(p *ProcessInfo) float64 {
 	// Apply weights to CPU and RSS
@@ -221,7 +617,8 @@ func (s *TopNSampler) calculateScore(p *ProcessInfo) float64 {
 	}
 
 	// Calculate new score
-	score := (s.config.CPUWeight * p.CPU) + (s.config.RSSWeight * normalizedRSS)
+	cpuWeight, memoryWeight := s.scoreWeights()
+	score := (cpuWeight * p.CPU) + (memoryWeight * normalizedRSS)
 
 	// Apply minimum score threshold
 	if score < s.config.MinScore {
@@ -236,7 +633,7 @@ func (s *TopNSampler) checkCircuitBreaker() {
 	// Check if we need to open the circuit breaker
 	if !s.circuitOpen {
 		// Open circuit breaker if CPU usage too high or churn rate exceeds threshold
-		if s.metrics["topn_update_time_seconds"]*100 > s.config.MaxSamplerCPU ||
+		if (s.config.MaxSamplerCPU > 0 && s.metrics["topn_update_time_seconds"]*100 > s.config.MaxSamplerCPU) ||
 			(s.config.ChurnHandlingEnabled && s.churnRate > float64(s.config.ChurnThreshold)) {
 			s.circuitOpen = true
 			// Log circuit breaker activation as an agent diagnostic event
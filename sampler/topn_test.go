@@ -2,8 +2,11 @@ package sampler
 
 import (
 	"context"
+	"math"
 	"testing"
 	"time"
+
+	"github.com/newrelic/infrastructure-agent/collector"
 )
 
 func TestTopNSampler_Init(t *testing.T) {
@@ -274,6 +277,304 @@ func TestTopNSampler_Shutdown(t *testing.T) {
 	}
 }
 
+// processEvent returns a scanner event for a process
+func processEvent(eventType collector.ProcessEventType, pid int, cpu float64, rss int64, timestamp time.Time) collector.ProcessEvent {
+	return collector.ProcessEvent{
+		Type:      eventType,
+		Process:   &collector.ProcessInfo{PID: pid, Name: "process", CPU: cpu, RSS: rss},
+		Timestamp: timestamp,
+	}
+}
+
+func TestTopNSampler_HandleProcessEvent(t *testing.T) {
+	config := DefaultConfig().TopN
+	config.MaxProcesses = 2
+	config.MemoryWeight = 0
+	config.HysteresisSamples = 0 // Replace tracked processes immediately
+	s := NewTopNSampler(config)
+
+	now := time.Now()
+	for pid, cpu := range []float64{10, 40, 30, 20} {
+		if err := s.HandleProcessEvent(processEvent(collector.ProcessCreated, pid, cpu, 1000, now)); err != nil {
+			t.Fatalf("HandleProcessEvent failed: %v", err)
+		}
+	}
+
+	top := s.GetTopN(2)
+	if len(top) != 2 || top[0].PID != 1 || top[1].PID != 2 {
+		t.Fatalf("Expected PIDs 1 and 2 on top, got %v", top)
+	}
+	if total := s.GetTotalCPU(); total != 100 {
+		t.Errorf("Expected total CPU 100, got %.1f", total)
+	}
+
+	metrics := s.Metrics()
+	if metrics["topn_processes_sampled"] != 2 {
+		t.Errorf("Expected 2 processes sampled, got %.1f", metrics["topn_processes_sampled"])
+	}
+	if metrics["topn_capture_ratio"] != 70 {
+		t.Errorf("Expected capture ratio 70, got %.1f", metrics["topn_capture_ratio"])
+	}
+
+	// A tracked process dropping lets the best untracked process in
+	if err := s.HandleProcessEvent(processEvent(collector.ProcessUpdated, 1, 5, 1000, now)); err != nil {
+		t.Fatalf("HandleProcessEvent failed: %v", err)
+	}
+	top = s.GetTopN(2)
+	if len(top) != 2 || top[0].PID != 2 || top[1].PID != 3 {
+		t.Fatalf("Expected PIDs 2 and 3 on top, got %v", top)
+	}
+
+	// A terminated process is forgotten and replaced
+	if err := s.HandleProcessEvent(processEvent(collector.ProcessTerminated, 2, 30, 1000, now)); err != nil {
+		t.Fatalf("HandleProcessEvent failed: %v", err)
+	}
+	top = s.GetTopN(2)
+	if len(top) != 2 || top[0].PID != 3 || top[1].PID != 0 {
+		t.Fatalf("Expected PIDs 3 and 0 on top, got %v", top)
+	}
+	if total := s.GetTotalCPU(); total != 35 {
+		t.Errorf("Expected total CPU 35, got %.1f", total)
+	}
+
+	if err := s.HandleProcessEvent(collector.ProcessEvent{Type: collector.ProcessCreated}); err == nil {
+		t.Errorf("Expected an error for an event without process")
+	}
+}
+
+func TestTopNSampler_ChurnFromEvents(t *testing.T) {
+	config := DefaultConfig().TopN
+	s := NewTopNSampler(config)
+
+	// 100 processes created every second
+	start := time.Now()
+	pid := 0
+	for second := 0; second <= 3; second++ {
+		for i := 0; i < 100; i++ {
+			timestamp := start.Add(time.Duration(second) * time.Second)
+			if err := s.HandleProcessEvent(processEvent(collector.ProcessCreated, pid, 1, 1000, timestamp)); err != nil {
+				t.Fatalf("HandleProcessEvent failed: %v", err)
+			}
+			pid++
+		}
+	}
+
+	if churn := s.Metrics()["topn_pid_churn_rate"]; churn < 20 || churn > 101 {
+		t.Errorf("Expected a churn rate up to 100 PIDs/s, got %.1f", churn)
+	}
+}
+
+func TestTopNSampler_Percentiles(t *testing.T) {
+	s := NewTopNSampler(DefaultConfig().TopN)
+
+	// Exponentially distributed CPU usage, most processes are idle
+	now := time.Now()
+	totalCPU := 0.0
+	for i := 0; i < 1000; i++ {
+		cpu := -10 * math.Log(1-float64(i)/1000)
+		totalCPU += cpu
+		if err := s.HandleProcessEvent(processEvent(collector.ProcessCreated, i, cpu, int64(i)*1024*1024, now)); err != nil {
+			t.Fatalf("HandleProcessEvent failed: %v", err)
+		}
+	}
+
+	expected := -10 * math.Log(1-0.95)
+	if p95 := s.GetCPUPercentile(0.95); math.Abs(p95-expected)/expected > 0.01 {
+		t.Errorf("Expected p95 CPU %.2f within 1%%, got %.2f", expected, p95)
+	}
+	if p50 := s.GetMemoryPercentile(0.5); math.Abs(p50-500*1024*1024)/(500*1024*1024) > 0.01 {
+		t.Errorf("Expected p50 memory of 500MB within 1%%, got %.0f", p50)
+	}
+	if total := s.GetTotalCPU(); math.Abs(total-totalCPU) > 1e-6 {
+		t.Errorf("Expected total CPU %.2f, got %.2f", totalCPU, total)
+	}
+
+	// Without sketches there are no percentiles
+	config := DefaultConfig().TopN
+	config.SketchEnabled = false
+	if p95 := NewTopNSampler(config).GetCPUPercentile(0.95); p95 != 0 {
+		t.Errorf("Expected no percentile without sketches, got %.2f", p95)
+	}
+}
+
+func TestTopNSampler_Others(t *testing.T) {
+	config := DefaultConfig().TopN
+	config.MaxProcesses = 10
+	s := NewTopNSampler(config)
+
+	now := time.Now()
+	totalCPU := 0.0
+	var totalMemory int64
+	for i := 0; i < 1000; i++ {
+		cpu := -10 * math.Log(1-float64(i)/1000)
+		memory := int64(i%100+1) * 1024 * 1024
+		totalCPU += cpu
+		totalMemory += memory
+		if err := s.HandleProcessEvent(processEvent(collector.ProcessCreated, i, cpu, memory, now)); err != nil {
+			t.Fatalf("HandleProcessEvent failed: %v", err)
+		}
+	}
+
+	top := s.GetTopN(10)
+	topCPU := 0.0
+	var topMemory int64
+	for _, p := range top {
+		topCPU += p.CPU
+		topMemory += p.RSS
+	}
+
+	others := s.GetOthers()
+	if others.Count != 990 {
+		t.Errorf("Expected 990 other processes, got %d", others.Count)
+	}
+	if math.Abs(topCPU+others.CPU-totalCPU)/totalCPU > 0.05 {
+		t.Errorf("Expected top and others CPU to add up to %.2f, got %.2f", totalCPU, topCPU+others.CPU)
+	}
+	if topMemory+others.Memory != totalMemory {
+		t.Errorf("Expected top and others memory to add up to %d, got %d", totalMemory, topMemory+others.Memory)
+	}
+
+	// Terminated processes leave the others
+	for i := 0; i < 10; i++ {
+		if err := s.HandleProcessEvent(processEvent(collector.ProcessTerminated, i, 0, 0, now)); err != nil {
+			t.Fatalf("HandleProcessEvent failed: %v", err)
+		}
+	}
+	if others = s.GetOthers(); others.Count != 980 {
+		t.Errorf("Expected 980 other processes, got %d", others.Count)
+	}
+}
+
+func TestTopNSampler_Hysteresis(t *testing.T) {
+	config := DefaultConfig().TopN
+	config.MaxProcesses = 2
+	config.MemoryWeight = 0
+
+	// Processes 2 and 3 take turns being slightly busier than the other, the
+	// quieter one sampled first
+	borderline := func(round int) []*ProcessInfo {
+		quieter := &ProcessInfo{PID: 2, Name: "Process2", CPU: 10}
+		busier := &ProcessInfo{PID: 3, Name: "Process3", CPU: 10.5}
+		if round%2 == 1 {
+			quieter.PID, busier.PID = 3, 2
+		}
+		return []*ProcessInfo{{PID: 1, Name: "Process1", CPU: 50}, quieter, busier}
+	}
+
+	s := NewTopNSampler(config)
+	for round := 0; round < 10; round++ {
+		if err := s.Update(borderline(round)); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		top := s.GetTopN(2)
+		if len(top) != 2 || top[0].PID != 1 || top[1].PID != 2 {
+			t.Fatalf("Expected PIDs 1 and 2 on top in round %d, got %v", round, top)
+		}
+	}
+	if churn := s.Metrics()["topn_churn_rate"]; churn != 0 {
+		t.Errorf("Expected no membership churn, got %.1f", churn)
+	}
+
+	// Without hysteresis the membership flaps every round
+	config.HysteresisSamples = 0
+	s = NewTopNSampler(config)
+	for round := 0; round < 10; round++ {
+		if err := s.Update(borderline(round)); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+	if exits := s.heap.Exits(); exits < 5 {
+		t.Errorf("Expected the membership to flap without hysteresis, got %d exits", exits)
+	}
+	if churn := s.Metrics()["topn_churn_rate"]; churn <= 0 {
+		t.Errorf("Expected membership churn without hysteresis, got %.1f", churn)
+	}
+
+	// A clearly busier process replaces the lowest after HysteresisSamples
+	config.HysteresisSamples = 3
+	s = NewTopNSampler(config)
+	now := time.Now()
+	for pid, cpu := range []float64{50, 10} {
+		if err := s.HandleProcessEvent(processEvent(collector.ProcessCreated, pid, cpu, 1000, now)); err != nil {
+			t.Fatalf("HandleProcessEvent failed: %v", err)
+		}
+	}
+	for sample := 1; sample <= 3; sample++ {
+		if err := s.HandleProcessEvent(processEvent(collector.ProcessUpdated, 2, 20, 1000, now)); err != nil {
+			t.Fatalf("HandleProcessEvent failed: %v", err)
+		}
+		if tracked := s.heap.Contains(2); tracked != (sample == 3) {
+			t.Errorf("Expected PID 2 tracked %v after %d samples", sample == 3, sample)
+		}
+	}
+}
+
+func TestTopNSampler_Rollup(t *testing.T) {
+	config := DefaultConfig().TopN
+	config.RollupWindow = time.Minute
+	config.RollupBuckets = 4
+	// Keep the circuit breaker from sampling a subset of the processes
+	config.ChurnHandlingEnabled = false
+	config.MaxSamplerCPU = 0
+	s := NewTopNSampler(config)
+
+	// Scans with the same exponential CPU distribution, one per sub-window
+	var scanP95 []float64
+	for scan := 0; scan < 6; scan++ {
+		processes := make([]*ProcessInfo, 1000)
+		for i := range processes {
+			cpu := -10 * math.Log(1-(float64(i)+0.5)/1000)
+			processes[i] = &ProcessInfo{PID: i, Name: "process", CPU: cpu, RSS: int64(i+1) * 1024 * 1024}
+		}
+		if err := s.Update(processes); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+
+		snapshot, err := s.Rollup(config.RollupWindow)
+		if err != nil {
+			t.Fatalf("Rollup failed: %v", err)
+		}
+		scanP95 = append(scanP95, snapshot.CPU.P95)
+
+		s.cpuRollup.Rotate()
+		s.memoryRollup.Rotate()
+	}
+
+	// The rollup holds the last 3 scans, the current sub-window being empty
+	snapshot, err := s.Rollup(config.RollupWindow)
+	if err != nil {
+		t.Fatalf("Rollup failed: %v", err)
+	}
+	if snapshot.Samples != 3000 {
+		t.Errorf("Expected 3000 samples in the rollup, got %d", snapshot.Samples)
+	}
+	expected := -10 * math.Log(1-0.95)
+	for scan, p95 := range append(scanP95, snapshot.CPU.P95) {
+		if math.Abs(p95-expected)/expected > 0.02 {
+			t.Errorf("Expected p95 CPU %.2f within 2%% after scan %d, got %.2f", expected, scan, p95)
+		}
+	}
+	if p50 := snapshot.Memory.P50; math.Abs(p50-500*1024*1024)/(500*1024*1024) > 0.02 {
+		t.Errorf("Expected p50 memory of 500MB within 2%%, got %.0f", p50)
+	}
+
+	last, err := s.Rollup(30 * time.Second)
+	if err != nil {
+		t.Fatalf("Rollup failed: %v", err)
+	}
+	if last.Samples != 1000 {
+		t.Errorf("Expected 1000 samples in the last 30s, got %d", last.Samples)
+	}
+
+	if _, err := s.Rollup(2 * time.Minute); err == nil {
+		t.Errorf("Expected an error for a window longer than the rollup window")
+	}
+	config.RollupWindow = 0
+	if _, err := NewTopNSampler(config).Rollup(time.Minute); err == nil {
+		t.Errorf("Expected an error with rollups disabled")
+	}
+}
+
 func BenchmarkTopNSampler_Update(b *testing.B) {
 	// Create a sampler with default config
 	s := NewTopNSampler(DefaultConfig().TopN)
@@ -326,3 +627,34 @@ func BenchmarkTopNSampler_GetTopN(b *testing.B) {
 		s.GetTopN(100)
 	}
 }
+
+func TestTopNSampler_SetCapabilities(t *testing.T) {
+	config := DefaultConfig().TopN
+	config.MinScore = 20
+	s := NewTopNSampler(config)
+	s.totalRSSUsage = 1000
+	p := &ProcessInfo{PID: 1, CPU: 0, RSS: 500}
+
+	// Without CPU the memory weight alone keeps the process under MinScore
+	if score := s.calculateScore(p); score != 0 {
+		t.Errorf("Expected score 0 below MinScore, got %.2f", score)
+	}
+
+	s.SetCapabilities(collector.ProcessCapabilities{RSS: true})
+	if score := s.calculateScore(p); math.Abs(score-50) > 1e-9 {
+		t.Errorf("Expected score 50 ranking on RSS only, got %.2f", score)
+	}
+
+	s.SetCapabilities(collector.ProcessCapabilities{CPUPercent: true})
+	p = &ProcessInfo{PID: 2, CPU: 40, RSS: 0}
+	if score := s.calculateScore(p); math.Abs(score-40) > 1e-9 {
+		t.Errorf("Expected score 40 ranking on CPU only, got %.2f", score)
+	}
+
+	// Both fields collected keep the configured weights
+	s.SetCapabilities(collector.ProcessCapabilities{CPUPercent: true, RSS: true})
+	p = &ProcessInfo{PID: 3, CPU: 40, RSS: 500}
+	if score := s.calculateScore(p); math.Abs(score-43) > 1e-9 {
+		t.Errorf("Expected score 43 with both weights, got %.2f", score)
+	}
+}
@@ -3,6 +3,7 @@ package sketch
 import (
 	"fmt"
 	"math"
+	"time"
 )
 
 // Config holds configuration parameters for sketches
@@ -12,6 +13,30 @@ type Config struct {
 	
 	// DDSketch specific configuration
 	DDSketch DDSketchConfig `yaml:"ddSketch"`
+	
+	// GK specific configuration
+	GK GKConfig `yaml:"gk"`
+	
+	// Window configuration for rolling sketches, left zero when no
+	// WindowedSketch is used
+	Window WindowConfig `yaml:"window"`
+}
+
+// GKConfig holds configuration for the GKSketch
+type GKConfig struct {
+	// Epsilon is the rank error bound, as a fraction of the count
+	// A quantile q returns a value whose rank is within (q ± Epsilon) * count
+	Epsilon float64 `yaml:"epsilon"`
+}
+
+// WindowConfig holds configuration for the WindowedSketch
+type WindowConfig struct {
+	// WindowDuration is the total time span covered by the rolling window
+	WindowDuration time.Duration `yaml:"windowDuration"`
+	
+	// Buckets is the number of sub-windows the window is divided into
+	// Old data ages out at a granularity of WindowDuration/Buckets
+	Buckets int `yaml:"buckets"`
 }
 
 // DDSketchConfig holds configuration for the DDSketch
@@ -29,6 +54,10 @@ type DDSketchConfig struct {
 	// InitialCapacity is the initial capacity for stores
 	InitialCapacity int `yaml:"initialCapacity"`
 	
+	// MaxBuckets caps the number of buckets held by the dense store
+	// Lowest buckets are collapsed beyond this limit (0 = unbounded)
+	MaxBuckets int `yaml:"maxBuckets"`
+	
 	// UseSparseStore determines whether to use a sparse store
 	UseSparseStore bool `yaml:"useSparseStore"`
 	
@@ -40,6 +69,27 @@ type DDSketchConfig struct {
 	
 	// SwitchThreshold is the density threshold for switching to dense store
 	SwitchThreshold float64 `yaml:"switchThreshold"`
+	
+	// SwitchConfirmations is the number of consecutive density checks past
+	// the threshold required before switching stores (0 or 1 = first check)
+	SwitchConfirmations int `yaml:"switchConfirmations"`
+	
+	// SwitchMinDwell is the minimum time spent in a store type before
+	// switching to the other one
+	SwitchMinDwell time.Duration `yaml:"switchMinDwell"`
+	
+	// AllowNegative enables tracking of negative and zero values
+	// When disabled, only positive values are accepted
+	AllowNegative bool `yaml:"allowNegative"`
+	
+	// InterpolateQuantiles interpolates quantiles within and between buckets
+	// instead of returning the representative value of a bucket, which
+	// reduces the error for small sample counts
+	InterpolateQuantiles bool `yaml:"interpolateQuantiles"`
+	
+	// MinReliableCount is the number of values below which
+	// GetValueAtQuantileWithConfidence flags quantiles as unreliable
+	MinReliableCount uint64 `yaml:"minReliableCount"`
 }
 
 // DefaultConfig returns a Config with sensible defaults
@@ -47,14 +97,27 @@ func DefaultConfig() Config {
 	return Config{
 		SketchType: "ddsketch",
 		DDSketch: DDSketchConfig{
-			RelativeAccuracy:  0.0075,          // Ensures p95/p99 error ≤ 1%
-			MinValue:          1e-9,            // Near-zero positive minimum
-			MaxValue:          1e9,             // Large maximum
-			InitialCapacity:   128,             // Reasonable initial size
-			UseSparseStore:    true,            // Sparse by default for memory efficiency
-			CollapseThreshold: 10,              // Collapse buckets with <= 10 counts
-			AutoSwitch:        true,            // Enable automatic switching
-			SwitchThreshold:   0.5,             // Switch to dense when 50% of buckets are used
+			RelativeAccuracy:     0.0075,           // Ensures p95/p99 error ≤ 1%
+			MinValue:             1e-9,             // Near-zero positive minimum
+			MaxValue:             1e9,              // Large maximum
+			InitialCapacity:      128,              // Reasonable initial size
+			MaxBuckets:           2048,             // Bound dense store memory to ~16KB
+			UseSparseStore:       true,             // Sparse by default for memory efficiency
+			CollapseThreshold:    10,               // Collapse buckets with <= 10 counts
+			AutoSwitch:           true,             // Enable automatic switching
+			SwitchThreshold:      0.5,              // Switch to dense when 50% of buckets are used
+			SwitchConfirmations:  3,                // Density must stay past the threshold for 3 checks
+			SwitchMinDwell:       30 * time.Second, // Keep a store type for at least 30s
+			AllowNegative:        false,            // Positive values only by default
+			InterpolateQuantiles: false,            // Representative bucket values by default
+			MinReliableCount:     20,               // p95 needs 20 values to differ from the max
+		},
+		GK: GKConfig{
+			Epsilon: 0.005, // Ranks within 0.5% of the count
+		},
+		Window: WindowConfig{
+			WindowDuration: 60 * time.Second, // Report percentiles over the last minute
+			Buckets:        6,                // Age out data every 10 seconds
 		},
 	}
 }
@@ -87,6 +150,11 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("initial capacity must be positive")
 		}
 		
+		// MaxBuckets cannot be negative
+		if c.DDSketch.MaxBuckets < 0 {
+			return fmt.Errorf("max buckets cannot be negative")
+		}
+		
 		// CollapseThreshold must be positive
 		if c.DDSketch.CollapseThreshold == 0 {
 			return fmt.Errorf("collapse threshold must be positive")
@@ -96,6 +164,29 @@ func (c *Config) Validate() error {
 		if c.DDSketch.AutoSwitch && (c.DDSketch.SwitchThreshold <= 0 || c.DDSketch.SwitchThreshold >= 1) {
 			return fmt.Errorf("switch threshold must be between 0 and 1")
 		}
+		
+		// SwitchConfirmations and SwitchMinDwell cannot be negative
+		if c.DDSketch.SwitchConfirmations < 0 {
+			return fmt.Errorf("switch confirmations cannot be negative")
+		}
+		if c.DDSketch.SwitchMinDwell < 0 {
+			return fmt.Errorf("switch min dwell cannot be negative")
+		}
+	}
+	
+	// Validate GK config
+	if c.SketchType == "gk" && (c.GK.Epsilon <= 0 || c.GK.Epsilon >= 1) {
+		return fmt.Errorf("gk epsilon must be between 0 and 1")
+	}
+	
+	// Validate window config, if a windowed sketch is configured
+	if c.Window != (WindowConfig{}) {
+		if c.Window.WindowDuration <= 0 {
+			return fmt.Errorf("window duration must be positive")
+		}
+		if c.Window.Buckets <= 0 {
+			return fmt.Errorf("window buckets must be positive")
+		}
 	}
 	
 	return nil
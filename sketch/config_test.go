@@ -0,0 +1,29 @@
+package sketch
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfig_ValidateWindow(t *testing.T) {
+	config := DefaultConfig()
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected default config to be valid, got %v", err)
+	}
+
+	// No windowed sketch configured
+	config.Window = WindowConfig{}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected config without a window to be valid, got %v", err)
+	}
+
+	// A configured window needs both fields
+	config.Window = WindowConfig{WindowDuration: time.Minute}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected window without buckets to be invalid")
+	}
+	config.Window = WindowConfig{Buckets: 6}
+	if err := config.Validate(); err == nil {
+		t.Error("Expected window without duration to be invalid")
+	}
+}
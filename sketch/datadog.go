@@ -0,0 +1,407 @@
+package sketch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// DataDog sketches-go protobuf field numbers
+//
+//	message DDSketch {
+//	  IndexMapping mapping = 1;
+//	  Store positiveValues = 2;
+//	  Store negativeValues = 3;
+//	  double zeroCount = 4;
+//	}
+//	message IndexMapping {
+//	  double gamma = 1;
+//	  double indexOffset = 2;
+//	  Interpolation interpolation = 3;
+//	}
+//	message Store {
+//	  map<sint32, double> binCounts = 1;
+//	  repeated double contiguousBinCounts = 2 [packed = true];
+//	  sint32 contiguousBinIndexOffset = 3;
+//	}
+const (
+	ddProtoSketchMapping        = 1
+	ddProtoSketchPositiveValues = 2
+	ddProtoSketchNegativeValues = 3
+	ddProtoSketchZeroCount      = 4
+
+	ddProtoMappingGamma         = 1
+	ddProtoMappingIndexOffset   = 2
+	ddProtoMappingInterpolation = 3
+
+	ddProtoStoreBinCounts                = 1
+	ddProtoStoreContiguousBinCounts      = 2
+	ddProtoStoreContiguousBinIndexOffset = 3
+
+	ddProtoMapEntryKey   = 1
+	ddProtoMapEntryValue = 2
+)
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// MarshalDatadogProto serializes the sketch as a DataDog sketches-go
+// DDSketch protobuf message. The logarithmic mapping uses the same bucket
+// boundaries, so bucket indices translate directly. Sparse stores are encoded
+// with binCounts, dense stores with contiguousBinCounts.
+// The DataDog format does not carry count, min, max or sum; those are
+// approximated from the buckets when the message is decoded.
+func (d *DDSketch) MarshalDatadogProto() ([]byte, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	// Encode index mapping: our index is ceil(multiplier*log(v) - offset)
+	// with multiplier = 1/ln(1+gamma), which is DataDog's logarithmic
+	// mapping with gamma' = 1+gamma and indexOffset = -offset
+	var mapping []byte
+	mapping = appendProtoDouble(mapping, ddProtoMappingGamma, 1+d.gamma)
+	mapping = appendProtoDouble(mapping, ddProtoMappingIndexOffset, -d.offset)
+
+	buf := make([]byte, 0, 64)
+	buf = appendProtoBytes(buf, ddProtoSketchMapping, mapping)
+
+	// Encode positive values
+	if d.store.GetTotalCount() > 0 {
+		var store []byte
+		if d.useSparseStore {
+			store = encodeDatadogBinCounts(d.store.GetNonEmptyBuckets())
+		} else {
+			store = encodeDatadogContiguousBinCounts(d.store)
+		}
+		buf = appendProtoBytes(buf, ddProtoSketchPositiveValues, store)
+	}
+
+	// Encode negative values
+	if d.negativeStore.GetTotalCount() > 0 {
+		store := encodeDatadogBinCounts(d.negativeStore.GetNonEmptyBuckets())
+		buf = appendProtoBytes(buf, ddProtoSketchNegativeValues, store)
+	}
+
+	// Encode zero count
+	if d.zeroCount > 0 {
+		buf = appendProtoDouble(buf, ddProtoSketchZeroCount, float64(d.zeroCount))
+	}
+
+	return buf, nil
+}
+
+// UnmarshalDatadogProto populates the sketch from a DataDog sketches-go
+// DDSketch protobuf message. Both the binCounts and contiguousBinCounts store
+// encodings are supported. Only the logarithmic mapping without interpolation
+// is accepted. Fractional counts are rounded to the nearest integer.
+// Since the DataDog format carries no summary statistics, min and max are set
+// to the values of the lowest and highest buckets and sum is estimated from
+// the bucket values.
+func (d *DDSketch) UnmarshalDatadogProto(data []byte) error {
+	var (
+		gamma         float64
+		indexOffset   float64
+		interpolation uint64
+		hasMapping    bool
+		zeroCount     float64
+	)
+	positive := make(map[int]uint64)
+	negative := make(map[int]uint64)
+
+	err := parseProtoFields(data, func(field protoField) error {
+		switch field.num {
+		case ddProtoSketchMapping:
+			if field.wireType != wireBytes {
+				return fmt.Errorf("invalid wire type %d for mapping", field.wireType)
+			}
+			hasMapping = true
+			return parseProtoFields(field.bytes, func(f protoField) error {
+				switch f.num {
+				case ddProtoMappingGamma:
+					gamma = math.Float64frombits(f.fixed64)
+				case ddProtoMappingIndexOffset:
+					indexOffset = math.Float64frombits(f.fixed64)
+				case ddProtoMappingInterpolation:
+					interpolation = f.varint
+				}
+				return nil
+			})
+		case ddProtoSketchPositiveValues:
+			if field.wireType != wireBytes {
+				return fmt.Errorf("invalid wire type %d for positive values", field.wireType)
+			}
+			return decodeDatadogStore(field.bytes, positive)
+		case ddProtoSketchNegativeValues:
+			if field.wireType != wireBytes {
+				return fmt.Errorf("invalid wire type %d for negative values", field.wireType)
+			}
+			return decodeDatadogStore(field.bytes, negative)
+		case ddProtoSketchZeroCount:
+			zeroCount = math.Float64frombits(field.fixed64)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("invalid DataDog sketch: %w", err)
+	}
+
+	// Validate mapping
+	if !hasMapping || gamma <= 1 {
+		return fmt.Errorf("invalid DataDog sketch: missing or invalid index mapping (gamma=%f)", gamma)
+	}
+	if interpolation != 0 {
+		return fmt.Errorf("unsupported DataDog index mapping interpolation: %d", interpolation)
+	}
+	if zeroCount < 0 {
+		return fmt.Errorf("invalid DataDog sketch: negative zero count %f", zeroCount)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	// Apply mapping
+	d.gamma = gamma - 1
+	d.multiplier = 1.0 / math.Log(gamma)
+	d.offset = -indexOffset
+
+	// Reset state
+	d.store.Clear()
+	d.negativeStore.Clear()
+	d.zeroCount = uint64(math.Round(zeroCount))
+	d.count = d.zeroCount
+	d.sum = 0
+	d.sumSquares = 0
+	d.min = math.Inf(1)
+	d.max = math.Inf(-1)
+
+	if d.zeroCount > 0 {
+		d.min = 0
+		d.max = 0
+	}
+
+	// Negative and zero values require a sketch that accepts them
+	if len(negative) > 0 || d.zeroCount > 0 {
+		d.allowNegative = true
+	}
+
+	// Load buckets and estimate statistics from bucket values
+	for idx, count := range positive {
+		d.store.Add(idx, count)
+		value := d.indexToValue(idx)
+		d.count += count
+		d.sum += value * float64(count)
+		d.sumSquares += value * value * float64(count)
+		d.min = math.Min(d.min, value)
+		d.max = math.Max(d.max, value)
+	}
+	for idx, count := range negative {
+		d.negativeStore.Add(idx, count)
+		value := -d.indexToValue(idx)
+		d.count += count
+		d.sum += value * float64(count)
+		d.sumSquares += value * value * float64(count)
+		d.min = math.Min(d.min, value)
+		d.max = math.Max(d.max, value)
+	}
+
+	return nil
+}
+
+// encodeDatadogBinCounts encodes buckets as a Store message using the
+// sparse binCounts map
+func encodeDatadogBinCounts(buckets map[int]uint64) []byte {
+	var store []byte
+	for idx, count := range buckets {
+		var entry []byte
+		entry = appendProtoSint32(entry, ddProtoMapEntryKey, int32(idx))
+		entry = appendProtoDouble(entry, ddProtoMapEntryValue, float64(count))
+		store = appendProtoBytes(store, ddProtoStoreBinCounts, entry)
+	}
+	return store
+}
+
+// encodeDatadogContiguousBinCounts encodes buckets as a Store message using
+// the packed contiguousBinCounts encoding
+func encodeDatadogContiguousBinCounts(s Store) []byte {
+	minIndex, hasMin := s.GetMinIndex()
+	maxIndex, hasMax := s.GetMaxIndex()
+	if !hasMin || !hasMax {
+		return nil
+	}
+
+	packed := make([]byte, 0, (maxIndex-minIndex+1)*8)
+	for i := minIndex; i <= maxIndex; i++ {
+		packed = binary.LittleEndian.AppendUint64(packed, math.Float64bits(float64(s.Get(i))))
+	}
+
+	var store []byte
+	store = appendProtoBytes(store, ddProtoStoreContiguousBinCounts, packed)
+	store = appendProtoSint32(store, ddProtoStoreContiguousBinIndexOffset, int32(minIndex))
+	return store
+}
+
+// decodeDatadogStore decodes a Store message into buckets, accepting both
+// the binCounts and contiguousBinCounts encodings
+func decodeDatadogStore(data []byte, buckets map[int]uint64) error {
+	var contiguous []float64
+	var contiguousOffset int
+
+	err := parseProtoFields(data, func(field protoField) error {
+		switch field.num {
+		case ddProtoStoreBinCounts:
+			if field.wireType != wireBytes {
+				return fmt.Errorf("invalid wire type %d for bin counts", field.wireType)
+			}
+			var key int32
+			var value float64
+			err := parseProtoFields(field.bytes, func(f protoField) error {
+				switch f.num {
+				case ddProtoMapEntryKey:
+					key = decodeZigZag32(f.varint)
+				case ddProtoMapEntryValue:
+					value = math.Float64frombits(f.fixed64)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			return addDatadogCount(buckets, int(key), value)
+		case ddProtoStoreContiguousBinCounts:
+			switch field.wireType {
+			case wireBytes:
+				// Packed encoding
+				if len(field.bytes)%8 != 0 {
+					return fmt.Errorf("invalid packed contiguous bin counts length: %d", len(field.bytes))
+				}
+				for i := 0; i < len(field.bytes); i += 8 {
+					contiguous = append(contiguous, math.Float64frombits(binary.LittleEndian.Uint64(field.bytes[i:])))
+				}
+			case wireFixed64:
+				// Unpacked encoding
+				contiguous = append(contiguous, math.Float64frombits(field.fixed64))
+			default:
+				return fmt.Errorf("invalid wire type %d for contiguous bin counts", field.wireType)
+			}
+		case ddProtoStoreContiguousBinIndexOffset:
+			contiguousOffset = int(decodeZigZag32(field.varint))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, value := range contiguous {
+		if err := addDatadogCount(buckets, contiguousOffset+i, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addDatadogCount adds a floating-point DataDog bin count to the buckets
+func addDatadogCount(buckets map[int]uint64, index int, value float64) error {
+	if value < 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("invalid bin count %f at index %d", value, index)
+	}
+
+	count := uint64(math.Round(value))
+	if count > 0 {
+		buckets[index] += count
+	}
+	return nil
+}
+
+// protoField is a single decoded protobuf field
+type protoField struct {
+	num      int
+	wireType int
+	varint   uint64
+	fixed64  uint64
+	bytes    []byte
+}
+
+// parseProtoFields walks the protobuf fields in data, skipping fields with
+// wire types that are not needed
+func parseProtoFields(data []byte, fn func(field protoField) error) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("invalid field tag")
+		}
+		data = data[n:]
+
+		field := protoField{
+			num:      int(tag >> 3),
+			wireType: int(tag & 0x7),
+		}
+
+		switch field.wireType {
+		case wireVarint:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return fmt.Errorf("invalid varint for field %d", field.num)
+			}
+			field.varint = v
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return fmt.Errorf("truncated fixed64 for field %d", field.num)
+			}
+			field.fixed64 = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireBytes:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return fmt.Errorf("truncated bytes for field %d", field.num)
+			}
+			field.bytes = data[n : n+int(length)]
+			data = data[n+int(length):]
+		case wireFixed32:
+			if len(data) < 4 {
+				return fmt.Errorf("truncated fixed32 for field %d", field.num)
+			}
+			data = data[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d for field %d", field.wireType, field.num)
+		}
+
+		if err := fn(field); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// appendProtoDouble appends a double field
+func appendProtoDouble(buf []byte, num int, value float64) []byte {
+	buf = binary.AppendUvarint(buf, uint64(num<<3|wireFixed64))
+	return binary.LittleEndian.AppendUint64(buf, math.Float64bits(value))
+}
+
+// appendProtoSint32 appends a zigzag-encoded sint32 field
+func appendProtoSint32(buf []byte, num int, value int32) []byte {
+	buf = binary.AppendUvarint(buf, uint64(num<<3|wireVarint))
+	return binary.AppendUvarint(buf, uint64(uint32((value<<1)^(value>>31))))
+}
+
+// appendProtoBytes appends a length-delimited field
+func appendProtoBytes(buf []byte, num int, value []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(num<<3|wireBytes))
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
+
+// decodeZigZag32 decodes a zigzag-encoded sint32 value
+func decodeZigZag32(v uint64) int32 {
+	u := uint32(v)
+	return int32(u>>1) ^ -int32(u&1)
+}
@@ -0,0 +1,196 @@
+package sketch
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestDatadogProto_RoundTrip(t *testing.T) {
+	for _, sparse := range []bool{true, false} {
+		config := DefaultConfig().DDSketch
+		config.UseSparseStore = sparse
+		sketch := NewDDSketch(config)
+
+		for i := 1; i <= 1000; i++ {
+			sketch.Add(float64(i))
+		}
+
+		data, err := sketch.MarshalDatadogProto()
+		if err != nil {
+			t.Fatalf("MarshalDatadogProto() returned error: %v", err)
+		}
+
+		newSketch := NewDDSketch(config)
+		if err := newSketch.UnmarshalDatadogProto(data); err != nil {
+			t.Fatalf("UnmarshalDatadogProto() returned error: %v", err)
+		}
+
+		if newSketch.GetCount() != sketch.GetCount() {
+			t.Errorf("sparse=%v: count mismatch: original=%d, decoded=%d",
+				sparse, sketch.GetCount(), newSketch.GetCount())
+		}
+
+		// Bucket indices translate directly, so quantiles must match
+		for _, q := range []float64{0.1, 0.5, 0.9, 0.99} {
+			origVal, _ := sketch.GetValueAtQuantile(q)
+			newVal, _ := newSketch.GetValueAtQuantile(q)
+			if math.Abs(origVal-newVal) > 1e-9*origVal {
+				t.Errorf("sparse=%v: quantile mismatch at q=%f: original=%f, decoded=%f",
+					sparse, q, origVal, newVal)
+			}
+		}
+	}
+}
+
+func TestDatadogProto_NegativeValues(t *testing.T) {
+	config := DefaultConfig().DDSketch
+	config.AllowNegative = true
+	sketch := NewDDSketch(config)
+
+	for i := -50; i <= 50; i++ {
+		sketch.Add(float64(i))
+	}
+
+	data, err := sketch.MarshalDatadogProto()
+	if err != nil {
+		t.Fatalf("MarshalDatadogProto() returned error: %v", err)
+	}
+
+	newSketch := NewDDSketch(DefaultConfig().DDSketch)
+	if err := newSketch.UnmarshalDatadogProto(data); err != nil {
+		t.Fatalf("UnmarshalDatadogProto() returned error: %v", err)
+	}
+
+	if newSketch.GetCount() != 101 {
+		t.Errorf("Expected count 101, got %d", newSketch.GetCount())
+	}
+
+	for _, q := range []float64{0.01, 0.25, 0.5, 0.75, 0.99} {
+		origVal, _ := sketch.GetValueAtQuantile(q)
+		newVal, _ := newSketch.GetValueAtQuantile(q)
+		if math.Abs(origVal-newVal) > 1e-9*math.Abs(origVal) {
+			t.Errorf("Quantile mismatch at q=%f: original=%f, decoded=%f", q, origVal, newVal)
+		}
+	}
+}
+
+func TestDatadogProto_ContiguousBinCounts(t *testing.T) {
+	config := DefaultConfig().DDSketch
+	reference := NewDDSketch(config)
+	reference.Add(10)
+	reference.Add(11)
+	reference.Add(11)
+
+	// Build a message by hand using contiguousBinCounts
+	first := reference.valueToIndex(10)
+	second := reference.valueToIndex(11)
+
+	var packed []byte
+	for i := first; i <= second; i++ {
+		var count float64
+		if i == first {
+			count++
+		}
+		if i == second {
+			count += 2
+		}
+		packed = binary.LittleEndian.AppendUint64(packed, math.Float64bits(count))
+	}
+
+	var mapping []byte
+	mapping = appendProtoDouble(mapping, ddProtoMappingGamma, 1+reference.gamma)
+
+	var store []byte
+	store = appendProtoBytes(store, ddProtoStoreContiguousBinCounts, packed)
+	store = appendProtoSint32(store, ddProtoStoreContiguousBinIndexOffset, int32(first))
+
+	var data []byte
+	data = appendProtoBytes(data, ddProtoSketchMapping, mapping)
+	data = appendProtoBytes(data, ddProtoSketchPositiveValues, store)
+
+	sketch := NewDDSketch(config)
+	if err := sketch.UnmarshalDatadogProto(data); err != nil {
+		t.Fatalf("UnmarshalDatadogProto() returned error: %v", err)
+	}
+
+	if sketch.GetCount() != 3 {
+		t.Errorf("Expected count 3, got %d", sketch.GetCount())
+	}
+
+	p50, _ := sketch.GetValueAtQuantile(0.5)
+	expected, _ := reference.GetValueAtQuantile(0.5)
+	if math.Abs(p50-expected) > 1e-9*expected {
+		t.Errorf("Expected p50 %f, got %f", expected, p50)
+	}
+}
+
+func TestDatadogProto_InvalidData(t *testing.T) {
+	sketch := NewDDSketch(DefaultConfig().DDSketch)
+
+	// Missing mapping
+	if err := sketch.UnmarshalDatadogProto(nil); err == nil {
+		t.Errorf("Expected error for missing mapping")
+	}
+
+	// Truncated message
+	if err := sketch.UnmarshalDatadogProto([]byte{0x0a, 0x10, 0x09}); err == nil {
+		t.Errorf("Expected error for truncated message")
+	}
+
+	// Interpolated mapping
+	var mapping []byte
+	mapping = appendProtoDouble(mapping, ddProtoMappingGamma, 1.02)
+	mapping = append(mapping, ddProtoMappingInterpolation<<3|wireVarint, 1)
+	data := appendProtoBytes(nil, ddProtoSketchMapping, mapping)
+	if err := sketch.UnmarshalDatadogProto(data); err == nil {
+		t.Errorf("Expected error for interpolated mapping")
+	}
+}
+
+func TestDatadogProto_Merge(t *testing.T) {
+	config := DefaultConfig().DDSketch
+	sketch := NewDDSketch(config)
+	for i := 1; i <= 1000; i++ {
+		sketch.Add(float64(i))
+	}
+
+	data, err := sketch.MarshalDatadogProto()
+	if err != nil {
+		t.Fatalf("MarshalDatadogProto() returned error: %v", err)
+	}
+
+	// 1+gamma loses the last bits of gamma
+	decoded := NewDDSketch(config)
+	if err := decoded.UnmarshalDatadogProto(data); err != nil {
+		t.Fatalf("UnmarshalDatadogProto() returned error: %v", err)
+	}
+
+	// A decoded sketch merges with sketches of the same accuracy, both ways
+	merged := NewDDSketch(config)
+	if err := merged.Merge(decoded); err != nil {
+		t.Fatalf("Merge() of decoded sketch returned error: %v", err)
+	}
+	if err := decoded.Merge(sketch); err != nil {
+		t.Fatalf("Merge() into decoded sketch returned error: %v", err)
+	}
+
+	if merged.GetCount() != 1000 {
+		t.Errorf("Expected count 1000, got %d", merged.GetCount())
+	}
+	if decoded.GetCount() != 2000 {
+		t.Errorf("Expected count 2000, got %d", decoded.GetCount())
+	}
+	p50, _ := sketch.GetValueAtQuantile(0.5)
+	mergedP50, _ := merged.GetValueAtQuantile(0.5)
+	if math.Abs(p50-mergedP50) > 1e-9*p50 {
+		t.Errorf("Expected merged p50 %f, got %f", p50, mergedP50)
+	}
+
+	// Sketches of another accuracy are still rejected
+	other := config
+	other.RelativeAccuracy = 0.01
+	if err := NewDDSketch(other).Merge(decoded); err == nil {
+		t.Error("Expected merge of a different accuracy to fail")
+	}
+}
@@ -32,17 +32,30 @@ type DDSketch struct {
 	useSparseStore bool     // Whether to use sparse store
 	autoSwitch   bool       // Whether to automatically switch between stores
 	switchThreshold float64 // Density threshold for switching to dense store
+	switchConfirmations int // Consecutive checks past a threshold before switching
+	switchMinDwell time.Duration // Minimum time in a store type before switching
+	maxBuckets   int        // Bucket cap for the dense store
+	interpolateQuantiles bool // Whether quantiles are interpolated within buckets
+	minReliableCount uint64 // Count below which quantiles are flagged unreliable
 	
 	min          float64    // Minimum value seen
 	max          float64    // Maximum value seen
 	sum          float64    // Sum of all values
+	sumSquares   float64    // Sum of squares of all values
 	count        uint64     // Count of all values
 	
 	sparseStore  Store      // Sparse store reference
 	denseStore   Store      // Dense store reference
 	
+	allowNegative bool      // Whether negative and zero values are accepted
+	negativeStore Store     // Bucket store for negative values, keyed on -value
+	zeroCount    uint64     // Count of zero values
+	
 	startTime    time.Time  // Time when the sketch was created
+	lastCheck    time.Time  // Time of last store density check
 	lastSwitch   time.Time  // Time of last store switch
+	switchVotes  int        // Consecutive checks past the threshold of the other store
+	storeSwitches uint64    // Number of store switches
 	
 	mutex        sync.RWMutex
 }
@@ -55,12 +68,12 @@ func NewDDSketch(config DDSketchConfig) *DDSketch {
 	if config.UseSparseStore {
 		store = NewSparseStore(config.CollapseThreshold)
 	} else {
-		store = NewDenseStore(config.InitialCapacity)
+		store = NewDenseStore(config.InitialCapacity, config.MaxBuckets)
 	}
 	
 	// Create both store types for potential switching
 	sparseStore := NewSparseStore(config.CollapseThreshold)
-	denseStore := NewDenseStore(config.InitialCapacity)
+	denseStore := NewDenseStore(config.InitialCapacity, config.MaxBuckets)
 	
 	return &DDSketch{
 		gamma:        gamma,
@@ -72,13 +85,22 @@ func NewDDSketch(config DDSketchConfig) *DDSketch {
 		useSparseStore: config.UseSparseStore,
 		autoSwitch:   config.AutoSwitch,
 		switchThreshold: config.SwitchThreshold,
+		switchConfirmations: config.SwitchConfirmations,
+		switchMinDwell: config.SwitchMinDwell,
+		maxBuckets:   config.MaxBuckets,
+		interpolateQuantiles: config.InterpolateQuantiles,
+		minReliableCount: config.MinReliableCount,
 		min:          math.Inf(1),
 		max:          math.Inf(-1),
 		sum:          0,
+		sumSquares:   0,
 		count:        0,
 		sparseStore:  sparseStore,
 		denseStore:   denseStore,
+		allowNegative: config.AllowNegative,
+		negativeStore: NewSparseStore(config.CollapseThreshold),
 		startTime:    time.Now(),
+		lastCheck:    time.Now(),
 		lastSwitch:   time.Now(),
 	}
 }
@@ -91,32 +113,37 @@ func (d *DDSketch) Add(value float64) error {
 // AddWithCount adds a value to the sketch with a specific count
 func (d *DDSketch) AddWithCount(value float64, count uint64) error {
 	// Validate input
-	if value <= 0 {
+	if value <= 0 && !d.allowNegative {
 		return fmt.Errorf("value must be positive: %f", value)
 	}
 	if count == 0 {
 		return nil
 	}
 	
-	// Bound value to min/max range
-	if value < d.minValue {
-		value = d.minValue
-	} else if value > d.maxValue {
-		value = d.maxValue
+	// Bound value to min/max range, keeping the sign for negative values
+	if value > 0 {
+		value = d.boundValue(value)
+	} else if value < 0 {
+		value = -d.boundValue(-value)
 	}
 	
-	// Calculate bucket index using logarithmic mapping
-	index := d.valueToIndex(value)
-	
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 	
-	// Add to store
-	d.store.Add(index, count)
+	// Add to the store matching the sign of the value
+	switch {
+	case value > 0:
+		d.store.Add(d.valueToIndex(value), count)
+	case value < 0:
+		d.negativeStore.Add(d.valueToIndex(-value), count)
+	default:
+		d.zeroCount += count
+	}
 	
 	// Update statistics
 	d.count += count
 	d.sum += value * float64(count)
+	d.sumSquares += value * value * float64(count)
 	
 	// Update min/max values
 	if value < d.min {
@@ -127,23 +154,136 @@ func (d *DDSketch) AddWithCount(value float64, count uint64) error {
 	}
 	
 	// Check if we should switch store type
-	if d.autoSwitch && time.Since(d.lastSwitch) > time.Second {
+	if d.autoSwitch && time.Since(d.lastCheck) > time.Second {
 		d.checkAndSwitchStores()
 	}
 	
 	return nil
 }
 
-// GetValueAtQuantile returns the value at the specified quantile
+// AddSampled adds a value sampled from a larger population, standing for
+// representationWeight values of it, e.g. the CPU of one of the processes
+// read by a scan limited by MaxProcessesPerScan, with a weight of the listed
+// processes over the read ones. It is AddWithCount, but rejects a weight of
+// 0, which would silently drop the sample
+func (d *DDSketch) AddSampled(value float64, representationWeight uint64) error {
+	if representationWeight == 0 {
+		return fmt.Errorf("representation weight must be positive")
+	}
+	return d.AddWithCount(value, representationWeight)
+}
+
+// batchValue is a value of AddBatch with the logarithm of its magnitude
+type batchValue struct {
+	value float64
+	log   float64 // Unused for zero
+}
+
+// AddBatch adds values to the sketch under a single lock acquisition, rather
+// than one per value as a loop over Add does. Values are validated, bounded
+// and their logarithms computed before locking. Non-positive values are
+// skipped unless AllowNegative is set. It returns how many values were
+// added, and an error if any was skipped
+func (d *DDSketch) AddBatch(values []float64) (int, error) {
+	batch := make([]batchValue, 0, len(values))
+	for _, value := range values {
+		if value <= 0 && !d.allowNegative {
+			continue
+		}
+		
+		// Bound value to min/max range, keeping the sign for negative values
+		switch {
+		case value > 0:
+			value = d.boundValue(value)
+			batch = append(batch, batchValue{value: value, log: math.Log(value)})
+		case value < 0:
+			value = -d.boundValue(-value)
+			batch = append(batch, batchValue{value: value, log: math.Log(-value)})
+		default:
+			batch = append(batch, batchValue{value: value})
+		}
+	}
+	
+	var err error
+	if skipped := len(values) - len(batch); skipped > 0 {
+		err = fmt.Errorf("skipped %d of %d values, values must be positive", skipped, len(values))
+	}
+	if len(batch) == 0 {
+		return 0, err
+	}
+	
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	
+	for _, v := range batch {
+		// Map with the current accuracy, as valueToIndex
+		index := int(math.Ceil(d.multiplier*v.log - d.offset))
+		switch {
+		case v.value > 0:
+			d.store.Add(index, 1)
+		case v.value < 0:
+			d.negativeStore.Add(index, 1)
+		default:
+			d.zeroCount++
+		}
+		
+		d.sum += v.value
+		d.sumSquares += v.value * v.value
+		if v.value < d.min {
+			d.min = v.value
+		}
+		if v.value > d.max {
+			d.max = v.value
+		}
+	}
+	d.count += uint64(len(batch))
+	
+	// Check if we should switch store type
+	if d.autoSwitch && time.Since(d.lastCheck) > time.Second {
+		d.checkAndSwitchStores()
+	}
+	
+	return len(batch), err
+}
+
+// GetValueAtQuantile returns the value at the specified quantile. With
+// InterpolateQuantiles set, the value is interpolated within and between
+// buckets instead of being the representative value of a bucket.
+// Quantiles are weighted: a value added with a count, e.g. by AddSampled,
+// ranks as that many values, so the quantiles of a weighted sample estimate
+// those of the whole population
 func (d *DDSketch) GetValueAtQuantile(q float64) (float64, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	
+	return d.valueAtQuantile(q)
+}
+
+// GetValueAtQuantileWithConfidence returns the value at the specified
+// quantile like GetValueAtQuantile, and whether the sketch holds at least
+// MinReliableCount values. The relative accuracy bounds the error against
+// the values added, not against the distribution they were sampled from:
+// with 5 values p99 is simply the maximum, and one outlier moves it
+// arbitrarily. Dashboards use reliable to suppress or flag such quantiles
+func (d *DDSketch) GetValueAtQuantileWithConfidence(q float64) (value float64, reliable bool, err error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	
+	value, err = d.valueAtQuantile(q)
+	if err != nil {
+		return 0, false, err
+	}
+	return value, d.count >= d.minReliableCount, nil
+}
+
+// valueAtQuantile returns the value at the specified quantile. Caller must
+// hold mutex
+func (d *DDSketch) valueAtQuantile(q float64) (float64, error) {
 	// Validate input
 	if q < 0 || q > 1 {
 		return 0, ErrInvalidQuantile
 	}
 	
-	d.mutex.RLock()
-	defer d.mutex.RUnlock()
-	
 	// Empty sketch check
 	if d.count == 0 {
 		return 0, ErrEmptySketch
@@ -157,35 +297,128 @@ func (d *DDSketch) GetValueAtQuantile(q float64) (float64, error) {
 		return d.max, nil
 	}
 	
+	if d.interpolateQuantiles {
+		return interpolateQuantile(q, d.count, d.min, d.max, d.interpolatedValueAtRank), nil
+	}
+	
 	// Calculate rank
 	rank := uint64(math.Ceil(q * float64(d.count)))
 	
-	// Find the bucket that contains the rank
+	sign, index, _, _, found := d.rankBucket(rank)
+	switch {
+	case !found:
+		// Fallback in case of unexpected error
+		return d.max, nil
+	case sign < 0:
+		return -d.indexToValue(index), nil
+	case sign == 0:
+		return 0, nil
+	default:
+		return d.indexToValue(index), nil
+	}
+}
+
+// rankBucket finds the bucket holding the value of the given 1-based rank,
+// returning the sign of its values (-1, 0 for the zero bucket or 1), its
+// index, the rank of the value within the bucket and the bucket count.
+// Caller must hold mutex
+func (d *DDSketch) rankBucket(rank uint64) (sign, index int, within, count uint64, found bool) {
+	// Walk negative buckets first, from the largest magnitude towards zero
+	var sum uint64
+	if negMin, hasNegMin := d.negativeStore.GetMinIndex(); hasNegMin {
+		negMax, _ := d.negativeStore.GetMaxIndex()
+		for i := negMax; i >= negMin; i-- {
+			count := d.negativeStore.Get(i)
+			if sum+count >= rank {
+				return -1, i, rank - sum, count, true
+			}
+			sum += count
+		}
+	}
+	
+	// Then the zero bucket
+	if sum+d.zeroCount >= rank {
+		return 0, 0, rank - sum, d.zeroCount, true
+	}
+	sum += d.zeroCount
+	
+	// Dense stores iterate in index order, so the walk can avoid a lock
+	// acquisition per index
+	if !d.useSparseStore {
+		d.store.ForEachBucket(func(i int, c uint64) bool {
+			if sum+c >= rank {
+				sign, index, within, count, found = 1, i, rank-sum, c, true
+				return false
+			}
+			sum += c
+			return true
+		})
+		return sign, index, within, count, found
+	}
+	
+	// Find the positive bucket that contains the rank
 	minIndex, hasMin := d.store.GetMinIndex()
 	maxIndex, hasMax := d.store.GetMaxIndex()
 	
-	if !hasMin || !hasMax {
-		return 0, ErrEmptySketch
+	if hasMin && hasMax {
+		for i := minIndex; i <= maxIndex; i++ {
+			count := d.store.Get(i)
+			if sum+count >= rank {
+				return 1, i, rank - sum, count, true
+			}
+			sum += count
+		}
 	}
 	
-	// Walk through buckets to find the one containing the rank
-	var sum uint64
-	for i := minIndex; i <= maxIndex; i++ {
-		sum += d.store.Get(i)
-		if sum >= rank {
-			// Found the bucket, convert index to value
-			return d.indexToValue(i), nil
-		}
+	return 0, 0, 0, 0, false
+}
+
+// interpolatedValueAtRank estimates the value of the given 1-based rank by
+// spreading the values of its bucket evenly between the bucket bounds.
+// Caller must hold mutex
+func (d *DDSketch) interpolatedValueAtRank(rank uint64) float64 {
+	sign, index, within, count, found := d.rankBucket(rank)
+	switch {
+	case !found:
+		return d.max
+	case sign == 0:
+		return 0
+	}
+	
+	// Bucket index holds the magnitudes in (indexToValue(index-1), indexToValue(index)]
+	lower, upper := d.indexToValue(index-1), d.indexToValue(index)
+	if sign < 0 {
+		// Ranks ascend towards zero, so from the largest magnitude
+		return -spreadInBucket(upper, lower, within, count)
+	}
+	return spreadInBucket(lower, upper, within, count)
+}
+
+// spreadInBucket returns the within-th of count values spread evenly from
+// from to to, each in the middle of its share of the bucket
+func spreadInBucket(from, to float64, within, count uint64) float64 {
+	return from + (float64(within)-0.5)/float64(count)*(to-from)
+}
+
+// interpolateQuantile returns the value at quantile q, 0 < q < 1, of count
+// values by interpolating linearly between the values of the ranks around
+// the fractional rank q*(count-1)+1. The result is clamped to [min, max]
+func interpolateQuantile(q float64, count uint64, min, max float64, valueAtRank func(rank uint64) float64) float64 {
+	position := q * float64(count-1)
+	rank := uint64(position) + 1
+	
+	value := valueAtRank(rank)
+	if fraction := position - math.Floor(position); fraction > 0 && rank < count {
+		value += fraction * (valueAtRank(rank+1) - value)
 	}
 	
-	// Fallback in case of unexpected error
-	return d.max, nil
+	return math.Max(min, math.Min(value, max))
 }
 
 // GetQuantileAtValue returns the quantile at which value falls
 func (d *DDSketch) GetQuantileAtValue(value float64) (float64, error) {
 	// Validate input
-	if value <= 0 {
+	if value <= 0 && !d.allowNegative {
 		return 0, fmt.Errorf("value must be positive: %f", value)
 	}
 	
@@ -197,11 +430,11 @@ func (d *DDSketch) GetQuantileAtValue(value float64) (float64, error) {
 		return 0, ErrEmptySketch
 	}
 	
-	// Bound value to min/max range
-	if value < d.minValue {
-		value = d.minValue
-	} else if value > d.maxValue {
-		value = d.maxValue
+	// Bound value to min/max range, keeping the sign for negative values
+	if value > 0 {
+		value = d.boundValue(value)
+	} else if value < 0 {
+		value = -d.boundValue(-value)
 	}
 	
 	// Handle edge cases
@@ -212,24 +445,155 @@ func (d *DDSketch) GetQuantileAtValue(value float64) (float64, error) {
 		return 1, nil
 	}
 	
+	// Find the number of elements below this value
+	var sum uint64
+	if value < 0 {
+		// Only negative values with a larger magnitude are below
+		index := d.valueToIndex(-value)
+		if negMax, hasNegMax := d.negativeStore.GetMaxIndex(); hasNegMax {
+			for i := negMax; i > index; i-- {
+				sum += d.negativeStore.Get(i)
+			}
+		}
+		return float64(sum) / float64(d.count), nil
+	}
+	
+	// All negative values are below zero and any positive value
+	sum += d.negativeStore.GetTotalCount()
+	if value == 0 {
+		return float64(sum) / float64(d.count), nil
+	}
+	sum += d.zeroCount
+	
 	// Calculate bucket index using logarithmic mapping
 	index := d.valueToIndex(value)
 	
-	// Find the number of elements below this value
-	minIndex, hasMin := d.store.GetMinIndex()
+	// Sum positive counts up to the index
+	if minIndex, hasMin := d.store.GetMinIndex(); hasMin {
+		for i := minIndex; i < index; i++ {
+			sum += d.store.Get(i)
+		}
+	}
 	
-	if !hasMin {
+	// Calculate quantile
+	return float64(sum) / float64(d.count), nil
+}
+
+// GetRank returns the number of values at or below the given value.
+// Resolution is bounded by bucket width, so values sharing the bucket of
+// the given value are counted
+func (d *DDSketch) GetRank(value float64) (uint64, error) {
+	return d.countUpTo(value, true)
+}
+
+// GetCountBelow returns the number of values strictly below the given value.
+// Values sharing the bucket of the given value are not counted
+func (d *DDSketch) GetCountBelow(value float64) (uint64, error) {
+	return d.countUpTo(value, false)
+}
+
+// ToHistogram returns the cumulative count of values below each upper bound,
+// as the buckets of a fixed-bucket histogram such as a Prometheus one. The
+// +Inf bucket is GetCount. Bounds must be positive and sorted ascending, and
+// carry the relative-error bound of the sketch: values in the bucket of a
+// bound may be counted in the next histogram bucket
+func (d *DDSketch) ToHistogram(bounds []float64) ([]uint64, error) {
+	return toHistogram(bounds, d.GetCountBelow)
+}
+
+// toHistogram returns the cumulative histogram of bounds from the count
+// below each bound
+func toHistogram(bounds []float64, countBelow func(float64) (uint64, error)) ([]uint64, error) {
+	for i, bound := range bounds {
+		if bound <= 0 {
+			return nil, fmt.Errorf("histogram bound must be positive: %f", bound)
+		}
+		if i > 0 && bound <= bounds[i-1] {
+			return nil, fmt.Errorf("histogram bounds must be sorted ascending: %f after %f", bound, bounds[i-1])
+		}
+	}
+	
+	counts := make([]uint64, len(bounds))
+	for i, bound := range bounds {
+		count, err := countBelow(bound)
+		if err == ErrEmptySketch {
+			return counts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		
+		// Values added between two bounds must not make the counts decrease
+		if i > 0 && count < counts[i-1] {
+			count = counts[i-1]
+		}
+		counts[i] = count
+	}
+	
+	return counts, nil
+}
+
+// countUpTo walks the buckets up to the given value and returns the
+// accumulated count, including the value's own bucket when inclusive is set
+func (d *DDSketch) countUpTo(value float64, inclusive bool) (uint64, error) {
+	// Validate input
+	if value <= 0 && !d.allowNegative {
+		return 0, fmt.Errorf("value must be positive: %f", value)
+	}
+	
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	
+	// Empty sketch check
+	if d.count == 0 {
 		return 0, ErrEmptySketch
 	}
 	
-	// Sum counts up to the index
+	// Handle edge cases
+	if value < d.min || (!inclusive && value == d.min) {
+		return 0, nil
+	}
+	if value > d.max || (inclusive && value == d.max) {
+		return d.count, nil
+	}
+	
 	var sum uint64
-	for i := minIndex; i < index; i++ {
-		sum += d.store.Get(i)
+	if value < 0 {
+		// Negative buckets are walked from the largest magnitude down
+		index := d.valueToIndex(-value)
+		if !inclusive {
+			index++
+		}
+		if negMax, hasNegMax := d.negativeStore.GetMaxIndex(); hasNegMax {
+			for i := negMax; i >= index; i-- {
+				sum += d.negativeStore.Get(i)
+			}
+		}
+		return sum, nil
 	}
 	
-	// Calculate quantile
-	return float64(sum) / float64(d.count), nil
+	// All negative values are below zero and any positive value
+	sum += d.negativeStore.GetTotalCount()
+	if value == 0 {
+		if inclusive {
+			sum += d.zeroCount
+		}
+		return sum, nil
+	}
+	sum += d.zeroCount
+	
+	// Sum positive counts up to the target index
+	index := d.valueToIndex(value)
+	if inclusive {
+		index++
+	}
+	if minIndex, hasMin := d.store.GetMinIndex(); hasMin {
+		for i := minIndex; i < index; i++ {
+			sum += d.store.Get(i)
+		}
+	}
+	
+	return sum, nil
 }
 
 // GetCount returns the total count of values in the sketch
@@ -263,7 +627,8 @@ func (d *DDSketch) GetMax() (float64, error) {
 	return d.max, nil
 }
 
-// GetSum returns the sum of all values added to the sketch
+// GetSum returns the sum of all values added to the sketch, each value
+// added with a count being summed that many times
 func (d *DDSketch) GetSum() (float64, error) {
 	d.mutex.RLock()
 	defer d.mutex.RUnlock()
@@ -275,7 +640,8 @@ func (d *DDSketch) GetSum() (float64, error) {
 	return d.sum, nil
 }
 
-// GetAvg returns the average of all values added to the sketch
+// GetAvg returns the average of all values added to the sketch, weighted
+// by the count each value was added with
 func (d *DDSketch) GetAvg() (float64, error) {
 	d.mutex.RLock()
 	defer d.mutex.RUnlock()
@@ -287,6 +653,59 @@ func (d *DDSketch) GetAvg() (float64, error) {
 	return d.sum / float64(d.count), nil
 }
 
+// GetVariance returns the population variance of all values added to the sketch.
+// Sketches decoded from formats without a sum of squares estimate it from
+// bucket representative values, in which case the result carries the same
+// relative-error bound as quantiles
+func (d *DDSketch) GetVariance() (float64, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	
+	if d.count == 0 {
+		return 0, ErrEmptySketch
+	}
+	
+	n := float64(d.count)
+	variance := (d.sumSquares - d.sum*d.sum/n) / n
+	
+	// Guard against floating-point cancellation
+	if variance < 0 {
+		variance = 0
+	}
+	
+	return variance, nil
+}
+
+// GetStdDev returns the population standard deviation of all values added to the sketch
+func (d *DDSketch) GetStdDev() (float64, error) {
+	variance, err := d.GetVariance()
+	if err != nil {
+		return 0, err
+	}
+	
+	return math.Sqrt(variance), nil
+}
+
+// estimateSumSquares estimates the sum of squares from bucket representative
+// values weighted by count. Caller must hold the mutex
+func (d *DDSketch) estimateSumSquares() float64 {
+	var sumSquares float64
+	for idx, count := range d.store.GetNonEmptyBuckets() {
+		value := d.indexToValue(idx)
+		sumSquares += value * value * float64(count)
+	}
+	for idx, count := range d.negativeStore.GetNonEmptyBuckets() {
+		value := d.indexToValue(idx)
+		sumSquares += value * value * float64(count)
+	}
+	return sumSquares
+}
+
+// gammaTolerance is the relative difference below which two gamma values
+// describe the same mapping. The DataDog format encodes 1+gamma, so a decoded
+// sketch loses the last bits of its gamma
+const gammaTolerance = 1e-9
+
 // Merge merges another sketch into this one
 func (d *DDSketch) Merge(other Sketch) error {
 	otherDD, ok := other.(*DDSketch)
@@ -295,7 +714,7 @@ func (d *DDSketch) Merge(other Sketch) error {
 	}
 	
 	// Check compatibility
-	if d.gamma != otherDD.gamma {
+	if math.Abs(d.gamma-otherDD.gamma) > gammaTolerance*math.Max(d.gamma, otherDD.gamma) {
 		return fmt.Errorf("cannot merge sketches with different gamma values: %f != %f", 
 			d.gamma, otherDD.gamma)
 	}
@@ -306,12 +725,20 @@ func (d *DDSketch) Merge(other Sketch) error {
 	otherDD.mutex.RLock()
 	defer otherDD.mutex.RUnlock()
 	
+	// A positive-only sketch cannot absorb negative or zero values
+	if !d.allowNegative && (otherDD.zeroCount > 0 || otherDD.negativeStore.GetTotalCount() > 0) {
+		return fmt.Errorf("cannot merge sketch with negative or zero values into a positive-only sketch")
+	}
+	
 	// Merge store data
 	d.store.Merge(otherDD.store)
+	d.negativeStore.Merge(otherDD.negativeStore)
+	d.zeroCount += otherDD.zeroCount
 	
 	// Update statistics
 	d.count += otherDD.count
 	d.sum += otherDD.sum
+	d.sumSquares += otherDD.sumSquares
 	
 	// Update min/max values
 	if otherDD.min < d.min {
@@ -343,24 +770,36 @@ func (d *DDSketch) Copy() Sketch {
 		useSparseStore: d.useSparseStore,
 		autoSwitch:   d.autoSwitch,
 		switchThreshold: d.switchThreshold,
+		switchConfirmations: d.switchConfirmations,
+		switchMinDwell: d.switchMinDwell,
+		maxBuckets:   d.maxBuckets,
+		interpolateQuantiles: d.interpolateQuantiles,
+		minReliableCount: d.minReliableCount,
 		min:          d.min,
 		max:          d.max,
 		sum:          d.sum,
+		sumSquares:   d.sumSquares,
 		count:        d.count,
+		allowNegative: d.allowNegative,
+		negativeStore: d.negativeStore.Copy(),
+		zeroCount:    d.zeroCount,
 		startTime:    d.startTime,
+		lastCheck:    d.lastCheck,
 		lastSwitch:   d.lastSwitch,
+		switchVotes:  d.switchVotes,
+		storeSwitches: d.storeSwitches,
 	}
 	
 	// Create fresh stores
 	newDD.sparseStore = NewSparseStore(10)
-	newDD.denseStore = NewDenseStore(128)
+	newDD.denseStore = NewDenseStore(128, d.maxBuckets)
 	
 	// Copy the active store
 	if d.useSparseStore {
 		newDD.store = d.store.Copy()
 		newDD.sparseStore = newDD.store
 		// Initialize dense store as empty
-		newDD.denseStore = NewDenseStore(128)
+		newDD.denseStore = NewDenseStore(128, d.maxBuckets)
 	} else {
 		newDD.store = d.store.Copy()
 		newDD.denseStore = newDD.store
@@ -376,17 +815,84 @@ func (d *DDSketch) Reset() {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 	
+	d.reset()
+}
+
+// reset clears the values of the sketch. Caller must hold mutex
+func (d *DDSketch) reset() {
 	d.store.Clear()
 	d.min = math.Inf(1)
 	d.max = math.Inf(-1)
 	d.sum = 0
+	d.sumSquares = 0
 	d.count = 0
 	
+	// Reset negative and zero values
+	d.negativeStore.Clear()
+	d.zeroCount = 0
+	
 	// Reset both store types
 	d.sparseStore.Clear()
 	d.denseStore.Clear()
 }
 
+// SetRelativeAccuracy loosens the relative accuracy of the sketch to
+// newAccuracy, re-mapping its buckets into the coarser buckets of the new
+// mapping to use less memory. Count, min, max and sum are preserved. A bucket
+// of the old mapping may straddle two buckets of the new one, so quantiles
+// carry both errors, (1+old)*(1+new)-1, unless 1+new is a power of 1+old.
+// Tightening the accuracy is rejected, as the lost precision cannot be
+// recovered
+func (d *DDSketch) SetRelativeAccuracy(newAccuracy float64) error {
+	if newAccuracy <= 0 || newAccuracy >= 1 {
+		return fmt.Errorf("relative accuracy must be between 0 and 1: %f", newAccuracy)
+	}
+	
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	
+	if newAccuracy < d.gamma {
+		return fmt.Errorf("cannot tighten relative accuracy from %f to %f", d.gamma, newAccuracy)
+	}
+	if newAccuracy == d.gamma {
+		return nil
+	}
+	
+	config := DDSketchConfig{RelativeAccuracy: newAccuracy}
+	gamma, multiplier, offset := config.LogarithmicMapping()
+	
+	// Bucket i holds the values up to indexToValue(i), which falls in the
+	// bucket of the new mapping holding that upper bound
+	remap := func(store Store) {
+		buckets := store.GetNonEmptyBuckets()
+		store.Clear()
+		for index, count := range buckets {
+			bound := (float64(index) + d.offset) / d.multiplier
+			// Tolerate rounding when the old bound is a new bucket boundary
+			store.Add(int(math.Ceil(multiplier*bound-offset-1e-9)), count)
+		}
+	}
+	remap(d.store)
+	remap(d.negativeStore)
+	
+	d.gamma, d.multiplier, d.offset = gamma, multiplier, offset
+	return nil
+}
+
+// ReduceAccuracyHandler returns a degradation action handler, e.g. for the
+// watchdog reduce_accuracy action, that loosens the sketch to
+// reducedAccuracy when a degradation level is active. The precision lost
+// cannot be restored, so the sketch keeps the reduced accuracy when the
+// degradation ends
+func (d *DDSketch) ReduceAccuracyHandler(reducedAccuracy float64) func(level int) error {
+	return func(level int) error {
+		if level <= 0 {
+			return nil
+		}
+		return d.SetRelativeAccuracy(reducedAccuracy)
+	}
+}
+
 // Bytes returns a serialized representation of the sketch
 // Actual implementation will be in serialization.go
 func (d *DDSketch) Bytes() ([]byte, error) {
@@ -413,7 +919,19 @@ func (d *DDSketch) Resources() map[string]float64 {
 		"sketch_memory_bytes":   float64(d.store.GetMemoryUsageBytes()),
 		"sketch_store_density":  d.store.GetStoreDensity() * 100, // as percentage
 		"sketch_uptime_seconds": time.Since(d.startTime).Seconds(),
+		"sketch_store_switches_total": float64(d.storeSwitches),
+	}
+}
+
+// boundValue clamps a positive magnitude to the configured min/max range
+func (d *DDSketch) boundValue(value float64) float64 {
+	if value < d.minValue {
+		return d.minValue
+	}
+	if value > d.maxValue {
+		return d.maxValue
 	}
+	return value
 }
 
 // valueToIndex maps a value to a bucket index
@@ -436,17 +954,40 @@ func (d *DDSketch) indexToValue(index int) float64 {
 
 // checkAndSwitchStores checks if we should switch between sparse and dense stores
 func (d *DDSketch) checkAndSwitchStores() {
+	d.checkAndSwitchStoresAt(time.Now())
+}
+
+// checkAndSwitchStoresAt checks the store density at most once a second. A
+// density hovering around a threshold would flip the store on every check,
+// so the store is only switched once the density stayed past the threshold
+// for switchConfirmations consecutive checks, and the current store was kept
+// for switchMinDwell
+func (d *DDSketch) checkAndSwitchStoresAt(now time.Time) {
 	// Only check periodically to avoid overhead
-	now := time.Now()
-	if now.Sub(d.lastSwitch) < time.Second {
+	if now.Sub(d.lastCheck) < time.Second {
 		return
 	}
-	d.lastSwitch = now
+	d.lastCheck = now
 	
 	// Get current store density
 	density := d.store.GetStoreDensity()
 	
-	if d.useSparseStore && density > d.switchThreshold {
+	toDense := d.useSparseStore && density > d.switchThreshold
+	toSparse := !d.useSparseStore && density < d.switchThreshold/2
+	if !toDense && !toSparse {
+		d.switchVotes = 0
+		return
+	}
+	
+	d.switchVotes++
+	if d.switchVotes < d.switchConfirmations || now.Sub(d.lastSwitch) < d.switchMinDwell {
+		return
+	}
+	d.switchVotes = 0
+	d.lastSwitch = now
+	d.storeSwitches++
+	
+	if toDense {
 		// Switch from sparse to dense
 		d.denseStore.Clear()
 		d.denseStore.Merge(d.store)
@@ -454,7 +995,7 @@ func (d *DDSketch) checkAndSwitchStores() {
 		d.useSparseStore = false
 		fmt.Printf("AgentDiagEvent: DDSketch switched from sparse to dense store (density: %.2f%%)\n", 
 			density * 100)
-	} else if !d.useSparseStore && density < d.switchThreshold/2 {
+	} else {
 		// Switch from dense to sparse
 		d.sparseStore.Clear()
 		d.sparseStore.Merge(d.store)
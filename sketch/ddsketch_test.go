@@ -3,6 +3,7 @@ package sketch
 import (
 	"math"
 	"math/rand"
+	"sort"
 	"sync"
 	"testing"
 	"time"
@@ -246,6 +247,196 @@ func TestDDSketch_AddWithCount(t *testing.T) {
 	}
 }
 
+func TestDDSketch_AddSampled(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	population := make([]float64, 20000)
+	for i := range population {
+		population[i] = math.Exp(rng.NormFloat64())
+	}
+	
+	// Every process of the population against one in ten, each standing for ten
+	const weight = 10
+	full := NewDDSketch(DefaultConfig().DDSketch)
+	sampled := NewDDSketch(DefaultConfig().DDSketch)
+	var sum float64
+	for i, index := range rng.Perm(len(population)) {
+		full.Add(population[index])
+		sum += population[index]
+		if i%weight == 0 {
+			if err := sampled.AddSampled(population[index], weight); err != nil {
+				t.Fatalf("AddSampled returned error: %v", err)
+			}
+		}
+	}
+	
+	if sampled.GetCount() != full.GetCount() {
+		t.Errorf("Expected the sampled count to be %d, got %d", full.GetCount(), sampled.GetCount())
+	}
+	
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		want, _ := full.GetValueAtQuantile(q)
+		got, err := sampled.GetValueAtQuantile(q)
+		if err != nil {
+			t.Fatalf("GetValueAtQuantile(%v) returned error: %v", q, err)
+		}
+		if math.Abs(got-want)/want > 0.1 {
+			t.Errorf("Expected sampled p%v to be within 10%% of %f, got %f", q*100, want, got)
+		}
+	}
+	
+	// The sum estimates the population, the average is unchanged by a uniform weight
+	sampledSum, _ := sampled.GetSum()
+	if math.Abs(sampledSum-sum)/sum > 0.05 {
+		t.Errorf("Expected sampled sum to be within 5%% of %f, got %f", sum, sampledSum)
+	}
+	avg, _ := full.GetAvg()
+	sampledAvg, _ := sampled.GetAvg()
+	if math.Abs(sampledAvg-sampledSum/float64(sampled.GetCount())) > 1e-9 || math.Abs(sampledAvg-avg)/avg > 0.05 {
+		t.Errorf("Expected sampled average to be within 5%% of %f, got %f", avg, sampledAvg)
+	}
+	
+	if err := sampled.AddSampled(1, 0); err == nil {
+		t.Errorf("Expected an error for a representation weight of 0")
+	}
+	if sampled.GetCount() != full.GetCount() {
+		t.Errorf("Expected a rejected sample not to be counted")
+	}
+}
+
+func TestDDSketch_AddBatch(t *testing.T) {
+	for _, allowNegative := range []bool{false, true} {
+		config := DefaultConfig().DDSketch
+		config.AllowNegative = allowNegative
+		batched := NewDDSketch(config)
+		looped := NewDDSketch(config)
+		
+		values := append(generateLogNormal(1000), -5, 0, -0.5, 1e12)
+		added, err := batched.AddBatch(values)
+		
+		accepted := 0
+		for _, value := range values {
+			if looped.Add(value) == nil {
+				accepted++
+			}
+		}
+		
+		if added != accepted {
+			t.Errorf("AllowNegative %v: expected %d values added, got %d", allowNegative, accepted, added)
+		}
+		if (err != nil) == allowNegative {
+			t.Errorf("AllowNegative %v: unexpected error %v", allowNegative, err)
+		}
+		
+		// The batch is added as by a loop over Add
+		if batched.GetCount() != looped.GetCount() {
+			t.Errorf("AllowNegative %v: expected count %d, got %d", allowNegative, looped.GetCount(), batched.GetCount())
+		}
+		for _, q := range []float64{0, 0.01, 0.25, 0.5, 0.75, 0.99, 1} {
+			expected, _ := looped.GetValueAtQuantile(q)
+			actual, _ := batched.GetValueAtQuantile(q)
+			if expected != actual {
+				t.Errorf("AllowNegative %v: expected quantile %f to be %f, got %f", allowNegative, q, expected, actual)
+			}
+		}
+		expectedSum, _ := looped.GetSum()
+		sum, _ := batched.GetSum()
+		if math.Abs(sum-expectedSum) > 1e-6*math.Abs(expectedSum) {
+			t.Errorf("AllowNegative %v: expected sum %f, got %f", allowNegative, expectedSum, sum)
+		}
+	}
+	
+	sketch := NewDDSketch(DefaultConfig().DDSketch)
+	if added, err := sketch.AddBatch([]float64{-1, 0}); added != 0 || err == nil {
+		t.Errorf("Expected no value added and an error, got %d %v", added, err)
+	}
+	if added, err := sketch.AddBatch(nil); added != 0 || err != nil {
+		t.Errorf("Expected an empty batch to add nothing, got %d %v", added, err)
+	}
+}
+
+func TestDDSketch_InterpolateQuantiles(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	for _, sign := range []float64{1, -1} {
+		values := make([]float64, 20)
+		for i := range values {
+			values[i] = sign * (10 + 90*rng.Float64())
+		}
+		sorted := append([]float64(nil), values...)
+		sort.Float64s(sorted)
+		
+		config := DefaultConfig().DDSketch
+		config.AllowNegative = sign < 0
+		config.RelativeAccuracy = 0.02
+		plain := NewDDSketch(config)
+		config.InterpolateQuantiles = true
+		interpolated := NewDDSketch(config)
+		for _, value := range values {
+			plain.Add(value)
+			interpolated.Add(value)
+		}
+		snapshot := interpolated.Snapshot()
+		
+		// Error against the exact quantile, interpolated between the sorted values
+		var plainErr, interpolatedErr float64
+		for q := 0.05; q < 1; q += 0.05 {
+			position := q * float64(len(sorted)-1)
+			lower := int(position)
+			exact := sorted[lower] + (position-float64(lower))*(sorted[lower+1]-sorted[lower])
+			
+			plainValue, _ := plain.GetValueAtQuantile(q)
+			interpolatedValue, _ := interpolated.GetValueAtQuantile(q)
+			snapshotValue, _ := snapshot.GetValueAtQuantile(q)
+			if snapshotValue != interpolatedValue {
+				t.Errorf("Sign %v q=%.2f: expected the snapshot to interpolate to %f, got %f", sign, q, interpolatedValue, snapshotValue)
+			}
+			
+			relErr := math.Abs(interpolatedValue-exact) / math.Abs(exact)
+			if relErr > config.RelativeAccuracy {
+				t.Errorf("Sign %v q=%.2f: expected %f within %.2f%%, got %f", sign, q, exact, config.RelativeAccuracy*100, interpolatedValue)
+			}
+			interpolatedErr += relErr
+			plainErr += math.Abs(plainValue-exact) / math.Abs(exact)
+		}
+		
+		if interpolatedErr >= plainErr {
+			t.Errorf("Sign %v: expected a smaller error interpolated, got %f against %f", sign, interpolatedErr, plainErr)
+		}
+		t.Logf("Sign %v: total relative error %f interpolated, %f plain", sign, interpolatedErr, plainErr)
+	}
+}
+
+func TestDDSketch_GetValueAtQuantileWithConfidence(t *testing.T) {
+	config := DefaultConfig().DDSketch
+	config.MinReliableCount = 10
+	sketch := NewDDSketch(config)
+	
+	if _, _, err := sketch.GetValueAtQuantileWithConfidence(0.5); err != ErrEmptySketch {
+		t.Errorf("Expected ErrEmptySketch, got %v", err)
+	}
+	
+	for i := 1; i <= 10; i++ {
+		sketch.Add(float64(i))
+		
+		value, reliable, err := sketch.GetValueAtQuantileWithConfidence(0.9)
+		if err != nil {
+			t.Fatalf("Count %d: failed to get quantile: %v", i, err)
+		}
+		if reliable != (i >= 10) {
+			t.Errorf("Count %d: expected reliable %v, got %v", i, i >= 10, reliable)
+		}
+		
+		// The value is the one GetValueAtQuantile returns
+		expected, _ := sketch.GetValueAtQuantile(0.9)
+		if value != expected {
+			t.Errorf("Count %d: expected %f, got %f", i, expected, value)
+		}
+	}
+	
+	if _, _, err := sketch.GetValueAtQuantileWithConfidence(1.5); err != ErrInvalidQuantile {
+		t.Errorf("Expected ErrInvalidQuantile, got %v", err)
+	}
+}
+
 func TestDDSketch_Merge(t *testing.T) {
 	// Create two sketches
 	config := DefaultConfig().DDSketch
@@ -432,6 +623,433 @@ func TestDDSketch_Accuracy(t *testing.T) {
 	}
 }
 
+func TestDDSketch_NegativeValues(t *testing.T) {
+	// Negative and zero values are rejected by default
+	sketch := NewDDSketch(DefaultConfig().DDSketch)
+	if err := sketch.Add(-1.0); err == nil {
+		t.Errorf("Add(-1.0) should return error when negative values are not allowed")
+	}
+	if err := sketch.Add(0); err == nil {
+		t.Errorf("Add(0) should return error when negative values are not allowed")
+	}
+	
+	// Create a sketch that accepts negative values
+	config := DefaultConfig().DDSketch
+	config.AllowNegative = true
+	sketch = NewDDSketch(config)
+	
+	values := []float64{-10.0, -5.0, 0, 0, 5.0, 10.0}
+	for _, v := range values {
+		if err := sketch.Add(v); err != nil {
+			t.Errorf("Add(%f) returned error: %v", v, err)
+		}
+	}
+	
+	if sketch.GetCount() != 6 {
+		t.Errorf("Sketch should have count 6, got %d", sketch.GetCount())
+	}
+	
+	min, _ := sketch.GetMin()
+	if min != -10.0 {
+		t.Errorf("Min should be -10.0, got %f", min)
+	}
+	
+	sum, _ := sketch.GetSum()
+	if sum != 0 {
+		t.Errorf("Sum should be 0, got %f", sum)
+	}
+	
+	// Quantiles must traverse negative, zero and positive buckets in order
+	testCases := []struct {
+		q        float64
+		expected float64
+	}{
+		{0.1, -10.0},
+		{0.3, -5.0},
+		{0.5, 0},
+		{0.6, 0},
+		{0.8, 5.0},
+		{0.9, 10.0},
+	}
+	
+	for _, tc := range testCases {
+		value, err := sketch.GetValueAtQuantile(tc.q)
+		if err != nil {
+			t.Errorf("GetValueAtQuantile(%f) returned error: %v", tc.q, err)
+			continue
+		}
+		if math.Abs(value-tc.expected) > math.Abs(tc.expected)*config.RelativeAccuracy {
+			t.Errorf("GetValueAtQuantile(%f) = %f, expected %f", tc.q, value, tc.expected)
+		}
+	}
+	
+	// Quantile at value must count negative and zero values below
+	quantileCases := []struct {
+		value    float64
+		expected float64
+	}{
+		{-7.0, 1.0 / 6.0},
+		{0, 2.0 / 6.0},
+		{7.0, 5.0 / 6.0},
+	}
+	
+	for _, tc := range quantileCases {
+		quantile, err := sketch.GetQuantileAtValue(tc.value)
+		if err != nil {
+			t.Errorf("GetQuantileAtValue(%f) returned error: %v", tc.value, err)
+			continue
+		}
+		if math.Abs(quantile-tc.expected) > 1e-9 {
+			t.Errorf("GetQuantileAtValue(%f) = %f, expected %f", tc.value, quantile, tc.expected)
+		}
+	}
+	
+	// Merging negative values into a positive-only sketch must fail
+	positiveOnly := NewDDSketch(DefaultConfig().DDSketch)
+	if err := positiveOnly.Merge(sketch); err == nil {
+		t.Errorf("Merging negative values into a positive-only sketch should fail")
+	}
+	
+	// Serialization must preserve negative and zero values
+	data, err := sketch.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes returned error: %v", err)
+	}
+	restored := NewDDSketch(DefaultConfig().DDSketch)
+	if err := restored.FromBytes(data); err != nil {
+		t.Fatalf("FromBytes returned error: %v", err)
+	}
+	p10, _ := restored.GetValueAtQuantile(0.1)
+	if math.Abs(p10+10.0) > 10.0*config.RelativeAccuracy {
+		t.Errorf("Restored P10 should be close to -10.0, got %f", p10)
+	}
+	p50, _ := restored.GetValueAtQuantile(0.5)
+	if p50 != 0 {
+		t.Errorf("Restored P50 should be 0, got %f", p50)
+	}
+}
+
+func TestDDSketch_AccuracyCenteredAtZero(t *testing.T) {
+	// Create a sketch that accepts negative values
+	config := DefaultConfig().DDSketch
+	config.RelativeAccuracy = 0.0075
+	config.AllowNegative = true
+	sketch := NewDDSketch(config)
+	
+	// Generate samples from a normal distribution centered at zero
+	samples := generateNormalCenteredAtZero(10000)
+	for _, v := range samples {
+		if err := sketch.Add(v); err != nil {
+			t.Fatalf("Add(%f) returned error: %v", v, err)
+		}
+	}
+	
+	// Sort samples for exact quantiles
+	sortedSamples := make([]float64, len(samples))
+	copy(sortedSamples, samples)
+	quickSort(sortedSamples)
+	
+	// Check quantiles on both sides of zero, skipping the median where
+	// relative error is meaningless
+	quantiles := []float64{0.01, 0.05, 0.1, 0.25, 0.75, 0.9, 0.95, 0.99}
+	for _, q := range quantiles {
+		// Use the same rank convention as the sketch
+		exactIndex := int(math.Ceil(q*float64(len(sortedSamples)))) - 1
+		exactValue := sortedSamples[exactIndex]
+		
+		approxValue, err := sketch.GetValueAtQuantile(q)
+		if err != nil {
+			t.Errorf("GetValueAtQuantile(%f) returned error: %v", q, err)
+			continue
+		}
+		
+		// Signs must match and the relative error must be within bounds
+		if (approxValue < 0) != (exactValue < 0) {
+			t.Errorf("q=%.2f: sign mismatch, exact=%.6f, approx=%.6f", q, exactValue, approxValue)
+			continue
+		}
+		
+		relError := math.Abs(approxValue-exactValue) / math.Abs(exactValue)
+		if relError > config.RelativeAccuracy {
+			t.Errorf("relative error at q=%.2f exceeded bound: "+
+				"exact=%.6f, approx=%.6f, error=%.6f, bound=%.6f",
+				q, exactValue, approxValue, relError, config.RelativeAccuracy)
+		}
+	}
+}
+
+func TestDDSketch_GetRank(t *testing.T) {
+	config := DefaultConfig().DDSketch
+	sketch := NewDDSketch(config)
+	
+	// Empty sketch
+	if _, err := sketch.GetRank(10); err != ErrEmptySketch {
+		t.Errorf("GetRank on empty sketch should return ErrEmptySketch, got %v", err)
+	}
+	
+	for i := 1; i <= 100; i++ {
+		sketch.Add(float64(i))
+	}
+	
+	// Invalid value
+	if _, err := sketch.GetRank(0); err == nil {
+		t.Errorf("GetRank(0) should return error")
+	}
+	if _, err := sketch.GetCountBelow(-1); err == nil {
+		t.Errorf("GetCountBelow(-1) should return error")
+	}
+	
+	// Edge cases
+	testCases := []struct {
+		value      float64
+		rank       uint64
+		countBelow uint64
+	}{
+		{0.5, 0, 0},
+		{1, 1, 0},
+		{100, 100, 99},
+		{200, 100, 100},
+	}
+	
+	for _, tc := range testCases {
+		rank, err := sketch.GetRank(tc.value)
+		if err != nil {
+			t.Errorf("GetRank(%f) returned error: %v", tc.value, err)
+		}
+		if rank != tc.rank {
+			t.Errorf("GetRank(%f) = %d, expected %d", tc.value, rank, tc.rank)
+		}
+		
+		below, err := sketch.GetCountBelow(tc.value)
+		if err != nil {
+			t.Errorf("GetCountBelow(%f) returned error: %v", tc.value, err)
+		}
+		if below != tc.countBelow {
+			t.Errorf("GetCountBelow(%f) = %d, expected %d", tc.value, below, tc.countBelow)
+		}
+	}
+	
+	// Interior values are accurate to within a bucket
+	for _, value := range []float64{10, 25, 50, 75, 90} {
+		rank, _ := sketch.GetRank(value)
+		below, _ := sketch.GetCountBelow(value)
+		if below > rank {
+			t.Errorf("GetCountBelow(%f) = %d exceeds GetRank = %d", value, below, rank)
+		}
+		if math.Abs(float64(rank)-value) > value*config.RelativeAccuracy*2+1 {
+			t.Errorf("GetRank(%f) = %d, expected about %f", value, rank, value)
+		}
+	}
+}
+
+func TestDDSketch_GetRankNegative(t *testing.T) {
+	config := DefaultConfig().DDSketch
+	config.AllowNegative = true
+	sketch := NewDDSketch(config)
+	
+	for i := -10; i <= 10; i++ {
+		sketch.Add(float64(i))
+	}
+	
+	rank, _ := sketch.GetRank(0)
+	if rank != 11 {
+		t.Errorf("GetRank(0) = %d, expected 11", rank)
+	}
+	
+	below, _ := sketch.GetCountBelow(0)
+	if below != 10 {
+		t.Errorf("GetCountBelow(0) = %d, expected 10", below)
+	}
+	
+	rank, _ = sketch.GetRank(-5)
+	if rank != 6 {
+		t.Errorf("GetRank(-5) = %d, expected 6", rank)
+	}
+	
+	below, _ = sketch.GetCountBelow(-5)
+	if below != 5 {
+		t.Errorf("GetCountBelow(-5) = %d, expected 5", below)
+	}
+}
+
+func TestDDSketch_ToHistogram(t *testing.T) {
+	sketch := NewDDSketch(DefaultConfig().DDSketch)
+	bounds := []float64{10, 50, 100, 500}
+	
+	// An empty sketch has empty buckets
+	counts, err := sketch.ToHistogram(bounds)
+	if err != nil {
+		t.Fatalf("ToHistogram returned error: %v", err)
+	}
+	for i, count := range counts {
+		if count != 0 {
+			t.Errorf("Expected empty bucket %d, got %d", i, count)
+		}
+	}
+	
+	for i := 1; i <= 1000; i++ {
+		sketch.Add(float64(i))
+	}
+	
+	counts, err = sketch.ToHistogram(bounds)
+	if err != nil {
+		t.Fatalf("ToHistogram returned error: %v", err)
+	}
+	
+	// Values in the bucket of a bound are not counted below it
+	config := DefaultConfig().DDSketch
+	for i, bound := range bounds {
+		expected := bound - 1
+		if math.Abs(float64(counts[i])-expected) > expected*2*config.RelativeAccuracy+1 {
+			t.Errorf("Expected about %v values below %v, got %d", expected, bound, counts[i])
+		}
+		if i > 0 && counts[i] < counts[i-1] {
+			t.Errorf("Expected cumulative counts, got %v", counts)
+		}
+	}
+	
+	invalid := [][]float64{{10, 5}, {10, 10}, {0, 10}, {-1}}
+	for _, bounds := range invalid {
+		if _, err := sketch.ToHistogram(bounds); err == nil {
+			t.Errorf("Expected an error for bounds %v", bounds)
+		}
+	}
+}
+
+func TestDDSketch_SetRelativeAccuracy(t *testing.T) {
+	config := DefaultConfig().DDSketch
+	config.AllowNegative = true
+	rng := rand.New(rand.NewSource(3))
+	
+	values := make([]float64, 10000)
+	for i := range values {
+		values[i] = rng.ExpFloat64() * 100
+		if i%10 == 0 {
+			values[i] = -values[i]
+		}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	
+	// Buckets merge exactly when 1+new is a power of 1+old, otherwise the
+	// errors add up
+	aligned := math.Pow(1+config.RelativeAccuracy, 4) - 1
+	loose := 0.05
+	bounds := map[float64]float64{
+		aligned: aligned,
+		loose:   (1+config.RelativeAccuracy)*(1+loose) - 1,
+	}
+	
+	for accuracy, bound := range bounds {
+		sketch := NewDDSketch(config)
+		for _, v := range values {
+			sketch.Add(v)
+		}
+		buckets := len(sketch.store.GetNonEmptyBuckets())
+		sum, _ := sketch.GetSum()
+		
+		if err := sketch.SetRelativeAccuracy(accuracy); err != nil {
+			t.Fatalf("SetRelativeAccuracy(%v) returned error: %v", accuracy, err)
+		}
+		
+		if reduced := len(sketch.store.GetNonEmptyBuckets()); reduced >= buckets {
+			t.Errorf("Expected fewer buckets than %d at accuracy %v, got %d", buckets, accuracy, reduced)
+		}
+		if count := sketch.GetCount(); count != uint64(len(values)) {
+			t.Errorf("Expected count %d, got %d", len(values), count)
+		}
+		if got, _ := sketch.GetSum(); got != sum {
+			t.Errorf("Expected sum %v, got %v", sum, got)
+		}
+		if min, _ := sketch.GetMin(); min != sorted[0] {
+			t.Errorf("Expected min %v, got %v", sorted[0], min)
+		}
+		
+		for _, q := range []float64{0.05, 0.5, 0.9, 0.95, 0.99} {
+			exact := sorted[int(math.Ceil(q*float64(len(sorted))))-1]
+			approx, err := sketch.GetValueAtQuantile(q)
+			if err != nil {
+				t.Fatalf("GetValueAtQuantile(%v) returned error: %v", q, err)
+			}
+			if math.Abs(approx-exact)/math.Abs(exact) > bound {
+				t.Errorf("Accuracy %v: q=%v exact=%v approx=%v exceeds bound %v", accuracy, q, exact, approx, bound)
+			}
+		}
+		
+		// Precision cannot be recovered
+		if err := sketch.SetRelativeAccuracy(config.RelativeAccuracy); err == nil {
+			t.Errorf("Expected an error tightening the accuracy")
+		}
+	}
+	
+	// The degradation handler only loosens the accuracy while degraded
+	sketch := NewDDSketch(config)
+	handler := sketch.ReduceAccuracyHandler(loose)
+	if err := handler(0); err != nil || sketch.gamma != config.RelativeAccuracy {
+		t.Errorf("Expected no change at level 0, got gamma %v and error %v", sketch.gamma, err)
+	}
+	if err := handler(2); err != nil || sketch.gamma != loose {
+		t.Errorf("Expected gamma %v when degraded, got %v and error %v", loose, sketch.gamma, err)
+	}
+	if err := handler(1); err != nil {
+		t.Errorf("Expected the handler to be idempotent, got %v", err)
+	}
+}
+
+func TestDDSketch_Variance(t *testing.T) {
+	config := DefaultConfig().DDSketch
+	sketch := NewDDSketch(config)
+	
+	// Empty sketch
+	if _, err := sketch.GetVariance(); err != ErrEmptySketch {
+		t.Errorf("GetVariance on empty sketch should return ErrEmptySketch, got %v", err)
+	}
+	
+	// Values 1..100 have population variance (n^2-1)/12
+	for i := 1; i <= 100; i++ {
+		sketch.Add(float64(i))
+	}
+	
+	expected := (100.0*100.0 - 1) / 12
+	variance, err := sketch.GetVariance()
+	if err != nil {
+		t.Fatalf("GetVariance returned error: %v", err)
+	}
+	if math.Abs(variance-expected) > expected*config.RelativeAccuracy {
+		t.Errorf("GetVariance() = %f, expected %f", variance, expected)
+	}
+	
+	stdDev, _ := sketch.GetStdDev()
+	if math.Abs(stdDev-math.Sqrt(expected)) > math.Sqrt(expected)*config.RelativeAccuracy {
+		t.Errorf("GetStdDev() = %f, expected %f", stdDev, math.Sqrt(expected))
+	}
+	
+	// Merge and copy carry the sum of squares
+	other := NewDDSketch(config)
+	for i := 1; i <= 100; i++ {
+		other.Add(float64(i))
+	}
+	sketch.Merge(other)
+	
+	merged, _ := sketch.GetVariance()
+	if math.Abs(merged-expected) > expected*config.RelativeAccuracy {
+		t.Errorf("After merge, GetVariance() = %f, expected %f", merged, expected)
+	}
+	
+	copied, _ := sketch.Copy().(*DDSketch).GetVariance()
+	if copied != merged {
+		t.Errorf("Copy GetVariance() = %f, expected %f", copied, merged)
+	}
+	
+	// Reset clears the sum of squares
+	sketch.Reset()
+	sketch.Add(5)
+	variance, _ = sketch.GetVariance()
+	if variance != 0 {
+		t.Errorf("After reset, GetVariance() = %f, expected 0", variance)
+	}
+}
+
 func TestDDSketch_Concurrent(t *testing.T) {
 	// Test concurrent access to the sketch
 	config := DefaultConfig().DDSketch
@@ -632,6 +1250,81 @@ func TestDDSketch_StoreSwitch(t *testing.T) {
 	}
 }
 
+// densityStore reports a fixed density for the store it wraps
+type densityStore struct {
+	Store
+	density float64
+}
+
+func (s *densityStore) GetStoreDensity() float64 {
+	return s.density
+}
+
+func TestDDSketch_StoreSwitchHysteresis(t *testing.T) {
+	config := DefaultConfig().DDSketch
+	config.UseSparseStore = true
+	config.AutoSwitch = true
+	config.SwitchThreshold = 0.5
+	config.SwitchConfirmations = 3
+	config.SwitchMinDwell = 10 * time.Second
+	ddSketch := NewDDSketch(config)
+	
+	sparse := &densityStore{Store: ddSketch.sparseStore}
+	dense := &densityStore{Store: ddSketch.denseStore}
+	ddSketch.sparseStore, ddSketch.denseStore, ddSketch.store = sparse, dense, sparse
+	
+	// The density oscillates around the thresholds on every check
+	start := ddSketch.lastSwitch
+	for i := 1; i <= 60; i++ {
+		if i%2 == 1 {
+			sparse.density, dense.density = 0.6, 0.6
+		} else {
+			sparse.density, dense.density = 0.1, 0.1
+		}
+		ddSketch.checkAndSwitchStoresAt(start.Add(time.Duration(i) * time.Second))
+	}
+	if switches := ddSketch.Resources()["sketch_store_switches_total"]; switches != 0 {
+		t.Errorf("Expected no switch for an oscillating density, got %v", switches)
+	}
+	
+	// A density staying past the threshold switches after 3 checks
+	now := start.Add(60 * time.Second)
+	sparse.density, dense.density = 0.6, 0.6
+	for i := 1; i <= 3; i++ {
+		now = now.Add(time.Second)
+		ddSketch.checkAndSwitchStoresAt(now)
+		if got := !ddSketch.useSparseStore; got != (i == 3) {
+			t.Fatalf("After %d checks past the threshold, expected dense store %v, got %v", i, i == 3, got)
+		}
+	}
+	
+	// Switching back waits for the minimum dwell time
+	sparse.density, dense.density = 0.1, 0.1
+	for i := 1; i < 10; i++ {
+		ddSketch.checkAndSwitchStoresAt(now.Add(time.Duration(i) * time.Second))
+		if ddSketch.useSparseStore {
+			t.Fatalf("Switched back to the sparse store %ds after switching to dense", i)
+		}
+	}
+	ddSketch.checkAndSwitchStoresAt(now.Add(10 * time.Second))
+	if !ddSketch.useSparseStore {
+		t.Errorf("Expected the sparse store once the minimum dwell time passed")
+	}
+	
+	// Checks more often than once a second are ignored
+	sparse.density, dense.density = 0.6, 0.6
+	for i := 1; i <= 5; i++ {
+		ddSketch.checkAndSwitchStoresAt(now.Add(10*time.Second + time.Duration(i)*100*time.Millisecond))
+	}
+	if ddSketch.switchVotes != 0 {
+		t.Errorf("Expected checks within a second to be skipped, got %d votes", ddSketch.switchVotes)
+	}
+	
+	if switches := ddSketch.Resources()["sketch_store_switches_total"]; switches != 2 {
+		t.Errorf("Expected 2 store switches, got %v", switches)
+	}
+}
+
 // Helper functions for generating test distributions
 
 func generateUniform(n int) []float64 {
@@ -662,6 +1355,21 @@ func generateNormal(n int) []float64 {
 	return result
 }
 
+func generateNormalCenteredAtZero(n int) []float64 {
+	rand.Seed(time.Now().UnixNano())
+	result := make([]float64, n)
+	for i := 0; i < n; i++ {
+		// Box-Muller transform
+		u1 := rand.Float64()
+		u2 := rand.Float64()
+		z0 := math.Sqrt(-2.0*math.Log(u1)) * math.Cos(2.0*math.Pi*u2)
+		
+		// Mean 0, std 15
+		result[i] = 15.0 * z0
+	}
+	return result
+}
+
 func generateExponential(n int) []float64 {
 	rand.Seed(time.Now().UnixNano())
 	result := make([]float64, n)
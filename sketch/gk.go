@@ -0,0 +1,428 @@
+package sketch
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"unsafe"
+)
+
+// Register the GKSketch at package initialization
+func init() {
+	RegisterSketch("gk", func() Sketch {
+		return NewGKSketch(DefaultConfig().GK)
+	})
+}
+
+// gkTuple is a sample of a GK summary. g is the difference between the
+// lowest possible rank of the sample and the one of the previous sample,
+// delta the difference between its highest and lowest possible ranks
+type gkTuple struct {
+	value float64
+	g     uint64
+	delta uint64
+}
+
+// GKSketch implements the Greenwald-Khanna quantile summary.
+// Unlike DDSketch, whose error is relative to the value, GKSketch bounds the
+// error on the rank: the value returned for quantile q has a rank within
+// (q ± epsilon) * count, whatever the range of the values. It suits
+// bounded ranges where the median must be close to exact, and accepts any
+// value including negative ones. Its size grows with log(epsilon * count)
+// rather than with the range of the values.
+// Based on the paper "Space-Efficient Online Computation of Quantile
+// Summaries" by Greenwald and Khanna
+type GKSketch struct {
+	epsilon float64   // Rank error bound as a fraction of the count
+	tuples  []gkTuple // Samples ordered by value
+	inserts int       // Inserts since the last compression
+
+	min   float64 // Minimum value seen
+	max   float64 // Maximum value seen
+	sum   float64 // Sum of all values
+	count uint64  // Count of all values
+
+	mutex sync.RWMutex
+}
+
+// NewGKSketch creates a new GKSketch with the given configuration
+func NewGKSketch(config GKConfig) *GKSketch {
+	return &GKSketch{
+		epsilon: config.Epsilon,
+		min:     math.Inf(1),
+		max:     math.Inf(-1),
+	}
+}
+
+// Add adds a value to the sketch
+func (g *GKSketch) Add(value float64) error {
+	return g.AddWithCount(value, 1)
+}
+
+// AddWithCount adds a value to the sketch with a specific count
+func (g *GKSketch) AddWithCount(value float64, count uint64) error {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("value must be finite: %f", value)
+	}
+	if count == 0 {
+		return nil
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	// Insert after the samples with the same value
+	idx := sort.Search(len(g.tuples), func(i int) bool {
+		return g.tuples[i].value > value
+	})
+
+	// The new minimum or maximum has an exact rank
+	var delta uint64
+	if idx > 0 && idx < len(g.tuples) {
+		delta = uint64(2 * g.epsilon * float64(g.count))
+	}
+
+	g.tuples = append(g.tuples, gkTuple{})
+	copy(g.tuples[idx+1:], g.tuples[idx:])
+	g.tuples[idx] = gkTuple{value: value, g: count, delta: delta}
+
+	g.count += count
+	g.sum += value * float64(count)
+	if value < g.min {
+		g.min = value
+	}
+	if value > g.max {
+		g.max = value
+	}
+
+	g.inserts++
+	if float64(g.inserts) >= 1/(2*g.epsilon) {
+		g.compress()
+	}
+
+	return nil
+}
+
+// compress merges the adjacent samples whose combined rank uncertainty stays
+// within 2 * epsilon * count. The first and last samples are kept so min and
+// max stay exact. Caller must hold the mutex
+func (g *GKSketch) compress() {
+	g.inserts = 0
+	if len(g.tuples) < 3 {
+		return
+	}
+
+	threshold := uint64(2 * g.epsilon * float64(g.count))
+
+	// Walk backwards, each sample absorbing the ones before it
+	compressed := make([]gkTuple, 0, len(g.tuples))
+	head := g.tuples[len(g.tuples)-1]
+	for i := len(g.tuples) - 2; i >= 1; i-- {
+		t := g.tuples[i]
+		if t.g+head.g+head.delta <= threshold {
+			head.g += t.g
+			continue
+		}
+		compressed = append(compressed, head)
+		head = t
+	}
+	compressed = append(compressed, head, g.tuples[0])
+
+	// Back to ascending order
+	for i, j := 0, len(compressed)-1; i < j; i, j = i+1, j-1 {
+		compressed[i], compressed[j] = compressed[j], compressed[i]
+	}
+	g.tuples = compressed
+}
+
+// GetValueAtQuantile returns the value at the specified quantile
+func (g *GKSketch) GetValueAtQuantile(q float64) (float64, error) {
+	if q < 0 || q > 1 {
+		return 0, ErrInvalidQuantile
+	}
+
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	if g.count == 0 {
+		return 0, ErrEmptySketch
+	}
+
+	// Handle edge cases
+	if q == 0 {
+		return g.min, nil
+	}
+	if q == 1 {
+		return g.max, nil
+	}
+
+	// The first sample whose lowest rank is within the error bound of the
+	// rank. The previous sample is below it, and g + delta <= 2 * bound keeps
+	// the highest rank of this one within the bound above it
+	rank := math.Ceil(q * float64(g.count))
+	bound := g.epsilon * float64(g.count)
+	var minRank uint64
+	for _, t := range g.tuples {
+		minRank += t.g
+		if float64(minRank)+bound >= rank {
+			return t.value, nil
+		}
+	}
+
+	return g.max, nil
+}
+
+// GetQuantileAtValue returns the quantile at which value falls, within
+// epsilon
+func (g *GKSketch) GetQuantileAtValue(value float64) (float64, error) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	if g.count == 0 {
+		return 0, ErrEmptySketch
+	}
+
+	var rank uint64
+	for _, t := range g.tuples {
+		if t.value > value {
+			break
+		}
+		rank += t.g
+	}
+
+	return float64(rank) / float64(g.count), nil
+}
+
+// GetCount returns the total count of values in the sketch
+func (g *GKSketch) GetCount() uint64 {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.count
+}
+
+// GetMin returns the minimum value added to the sketch
+func (g *GKSketch) GetMin() (float64, error) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	if g.count == 0 {
+		return 0, ErrEmptySketch
+	}
+
+	return g.min, nil
+}
+
+// GetMax returns the maximum value added to the sketch
+func (g *GKSketch) GetMax() (float64, error) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	if g.count == 0 {
+		return 0, ErrEmptySketch
+	}
+
+	return g.max, nil
+}
+
+// GetSum returns the sum of all values added to the sketch
+func (g *GKSketch) GetSum() (float64, error) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	if g.count == 0 {
+		return 0, ErrEmptySketch
+	}
+
+	return g.sum, nil
+}
+
+// GetAvg returns the average of all values added to the sketch
+func (g *GKSketch) GetAvg() (float64, error) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	if g.count == 0 {
+		return 0, ErrEmptySketch
+	}
+
+	return g.sum / float64(g.count), nil
+}
+
+// Merge merges another GKSketch into this one. The samples of both are
+// interleaved, each widening its delta by the rank uncertainty of the next
+// sample of the other sketch, and compressed against the combined count, so
+// the merged sketch keeps the epsilon bound of its inputs
+func (g *GKSketch) Merge(other Sketch) error {
+	otherGK, ok := other.(*GKSketch)
+	if !ok {
+		return ErrIncompatibleSketches
+	}
+	if otherGK == g {
+		return fmt.Errorf("cannot merge a sketch into itself")
+	}
+
+	// Check compatibility
+	if g.epsilon != otherGK.epsilon {
+		return fmt.Errorf("cannot merge sketches with different epsilon values: %f != %f",
+			g.epsilon, otherGK.epsilon)
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	otherGK.mutex.RLock()
+	defer otherGK.mutex.RUnlock()
+
+	if otherGK.count == 0 {
+		return nil
+	}
+
+	merged := make([]gkTuple, 0, len(g.tuples)+len(otherGK.tuples))
+	i, j := 0, 0
+	for i < len(g.tuples) || j < len(otherGK.tuples) {
+		if j == len(otherGK.tuples) || (i < len(g.tuples) && g.tuples[i].value <= otherGK.tuples[j].value) {
+			merged = append(merged, widenedTuple(g.tuples[i], otherGK.tuples, j))
+			i++
+		} else {
+			merged = append(merged, widenedTuple(otherGK.tuples[j], g.tuples, i))
+			j++
+		}
+	}
+	g.tuples = merged
+
+	g.count += otherGK.count
+	g.sum += otherGK.sum
+	g.min = math.Min(g.min, otherGK.min)
+	g.max = math.Max(g.max, otherGK.max)
+
+	g.compress()
+	return nil
+}
+
+// widenedTuple returns a sample of one sketch as merged with another sketch,
+// whose next sample is others[next]. The sample is below the next one, so its
+// highest rank grows by up to the highest rank of the next sample minus one,
+// while its lowest rank grows by the lowest rank of the previous sample
+func widenedTuple(t gkTuple, others []gkTuple, next int) gkTuple {
+	if next < len(others) {
+		t.delta += others[next].g + others[next].delta - 1
+	}
+	return t
+}
+
+// Copy creates a deep copy of the sketch
+func (g *GKSketch) Copy() Sketch {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	tuples := make([]gkTuple, len(g.tuples))
+	copy(tuples, g.tuples)
+
+	return &GKSketch{
+		epsilon: g.epsilon,
+		tuples:  tuples,
+		inserts: g.inserts,
+		min:     g.min,
+		max:     g.max,
+		sum:     g.sum,
+		count:   g.count,
+	}
+}
+
+// Reset resets the sketch to an empty state
+func (g *GKSketch) Reset() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.tuples = nil
+	g.inserts = 0
+	g.min = math.Inf(1)
+	g.max = math.Inf(-1)
+	g.sum = 0
+	g.count = 0
+}
+
+// gkState is the serialized form of a GKSketch
+type gkState struct {
+	Epsilon float64      `json:"epsilon"`
+	Min     float64      `json:"min"`
+	Max     float64      `json:"max"`
+	Sum     float64      `json:"sum"`
+	Count   uint64       `json:"count"`
+	Tuples  [][3]float64 `json:"tuples"` // Value, g and delta of each sample
+}
+
+// Bytes returns a serialized representation of the sketch
+func (g *GKSketch) Bytes() ([]byte, error) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	state := gkState{
+		Epsilon: g.epsilon,
+		Sum:     g.sum,
+		Count:   g.count,
+		Tuples:  make([][3]float64, len(g.tuples)),
+	}
+	// JSON has no infinities, min and max are only set when not empty
+	if g.count > 0 {
+		state.Min, state.Max = g.min, g.max
+	}
+	for i, t := range g.tuples {
+		state.Tuples[i] = [3]float64{t.value, float64(t.g), float64(t.delta)}
+	}
+
+	return json.Marshal(state)
+}
+
+// FromBytes populates the sketch from a serialized representation
+func (g *GKSketch) FromBytes(data []byte) error {
+	var state gkState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to decode gk sketch: %w", err)
+	}
+	if state.Epsilon <= 0 || state.Epsilon >= 1 {
+		return fmt.Errorf("invalid gk sketch epsilon: %f", state.Epsilon)
+	}
+
+	tuples := make([]gkTuple, len(state.Tuples))
+	var count uint64
+	for i, t := range state.Tuples {
+		tuples[i] = gkTuple{value: t[0], g: uint64(t[1]), delta: uint64(t[2])}
+		count += tuples[i].g
+		if i > 0 && tuples[i].value < tuples[i-1].value {
+			return fmt.Errorf("gk sketch samples are not ordered")
+		}
+	}
+	if count != state.Count {
+		return fmt.Errorf("gk sketch count %d does not match its samples %d", state.Count, count)
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.epsilon = state.Epsilon
+	g.tuples = tuples
+	g.inserts = 0
+	g.sum = state.Sum
+	g.count = state.Count
+	g.min, g.max = math.Inf(1), math.Inf(-1)
+	if state.Count > 0 {
+		g.min, g.max = state.Min, state.Max
+	}
+
+	return nil
+}
+
+// Resources returns resource usage of the sketch itself
+func (g *GKSketch) Resources() map[string]float64 {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	return map[string]float64{
+		"sketch_count":        float64(g.count),
+		"sketch_tuples":       float64(len(g.tuples)),
+		"sketch_memory_bytes": float64(cap(g.tuples)) * float64(unsafe.Sizeof(gkTuple{})),
+	}
+}
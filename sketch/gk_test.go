@@ -0,0 +1,223 @@
+package sketch
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// checkRankError checks that the value returned for each quantile has a
+// rank within epsilon of the quantile in the sorted values
+func checkRankError(t *testing.T, name string, sketch *GKSketch, sorted []float64, epsilon float64) {
+	t.Helper()
+
+	n := float64(len(sorted))
+	for _, q := range []float64{0.01, 0.1, 0.25, 0.5, 0.75, 0.9, 0.95, 0.99} {
+		value, err := sketch.GetValueAtQuantile(q)
+		if err != nil {
+			t.Fatalf("%s: GetValueAtQuantile(%v) returned error: %v", name, q, err)
+		}
+
+		// Ranks of the first and last occurrences of the value
+		low := float64(sort.SearchFloat64s(sorted, value))
+		high := float64(sort.Search(len(sorted), func(i int) bool { return sorted[i] > value }))
+		target := math.Ceil(q * n)
+		if target < low-epsilon*n || target > high+epsilon*n {
+			t.Errorf("%s: q=%v returned %v with ranks [%v, %v], expected rank %v ± %v",
+				name, q, value, low, high, target, epsilon*n)
+		}
+	}
+}
+
+func TestGKSketch_Accuracy(t *testing.T) {
+	config := DefaultConfig().GK
+	rng := rand.New(rand.NewSource(42))
+
+	distributions := map[string]func() float64{
+		"uniform":     func() float64 { return rng.Float64() * 100 },
+		"normal":      func() float64 { return rng.NormFloat64()*10 + 50 },
+		"exponential": func() float64 { return rng.ExpFloat64() * 10 },
+		"centered":    func() float64 { return rng.NormFloat64() },
+		"discrete":    func() float64 { return float64(rng.Intn(5)) },
+	}
+
+	for name, next := range distributions {
+		sketch := NewGKSketch(config)
+		values := make([]float64, 100000)
+		for i := range values {
+			values[i] = next()
+			if err := sketch.Add(values[i]); err != nil {
+				t.Fatalf("%s: Add returned error: %v", name, err)
+			}
+		}
+		sort.Float64s(values)
+
+		checkRankError(t, name, sketch, values, config.Epsilon)
+
+		// Far fewer samples than values
+		if tuples := len(sketch.tuples); tuples > 2000 {
+			t.Errorf("%s: expected a compressed summary, got %d samples", name, tuples)
+		}
+	}
+}
+
+func TestGKSketch_Accessors(t *testing.T) {
+	sketch := NewGKSketch(DefaultConfig().GK)
+
+	if _, err := sketch.GetValueAtQuantile(0.5); err != ErrEmptySketch {
+		t.Errorf("Expected ErrEmptySketch, got %v", err)
+	}
+	if _, err := sketch.GetMin(); err != ErrEmptySketch {
+		t.Errorf("GetMin on empty sketch should return ErrEmptySketch")
+	}
+
+	for _, v := range []float64{-2, 1, 3, 5, 8} {
+		if err := sketch.Add(v); err != nil {
+			t.Fatalf("Add(%v) returned error: %v", v, err)
+		}
+	}
+	if err := sketch.AddWithCount(4, 5); err != nil {
+		t.Fatalf("AddWithCount returned error: %v", err)
+	}
+	if err := sketch.Add(math.NaN()); err == nil {
+		t.Errorf("Expected an error adding NaN")
+	}
+
+	if count := sketch.GetCount(); count != 10 {
+		t.Errorf("Expected count 10, got %d", count)
+	}
+	if min, _ := sketch.GetMin(); min != -2 {
+		t.Errorf("Expected min -2, got %v", min)
+	}
+	if max, _ := sketch.GetMax(); max != 8 {
+		t.Errorf("Expected max 8, got %v", max)
+	}
+	if sum, _ := sketch.GetSum(); sum != 35 {
+		t.Errorf("Expected sum 35, got %v", sum)
+	}
+	if avg, _ := sketch.GetAvg(); avg != 3.5 {
+		t.Errorf("Expected avg 3.5, got %v", avg)
+	}
+	if median, _ := sketch.GetValueAtQuantile(0.5); median != 4 {
+		t.Errorf("Expected median 4, got %v", median)
+	}
+	if q, _ := sketch.GetQuantileAtValue(3); q != 0.3 {
+		t.Errorf("Expected quantile 0.3 at 3, got %v", q)
+	}
+	if _, err := sketch.GetValueAtQuantile(1.5); err != ErrInvalidQuantile {
+		t.Errorf("Expected ErrInvalidQuantile, got %v", err)
+	}
+
+	copied := sketch.Copy()
+	sketch.Reset()
+	if sketch.GetCount() != 0 {
+		t.Errorf("Expected an empty sketch after reset")
+	}
+	if copied.GetCount() != 10 {
+		t.Errorf("Expected the copy to keep its 10 values, got %d", copied.GetCount())
+	}
+}
+
+func TestGKSketch_Merge(t *testing.T) {
+	config := DefaultConfig().GK
+	rng := rand.New(rand.NewSource(7))
+
+	// Two shards with different distributions
+	low, high := NewGKSketch(config), NewGKSketch(config)
+	values := make([]float64, 0, 100000)
+	for i := 0; i < 50000; i++ {
+		a, b := rng.Float64()*50, 25+rng.Float64()*100
+		low.Add(a)
+		high.Add(b)
+		values = append(values, a, b)
+	}
+	sort.Float64s(values)
+
+	if err := low.Merge(high); err != nil {
+		t.Fatalf("Merge returned error: %v", err)
+	}
+	if count := low.GetCount(); count != 100000 {
+		t.Errorf("Expected count 100000, got %d", count)
+	}
+	checkRankError(t, "merged", low, values, config.Epsilon)
+
+	other := NewGKSketch(GKConfig{Epsilon: 0.01})
+	if err := low.Merge(other); err == nil {
+		t.Errorf("Expected an error merging sketches with different epsilon")
+	}
+	if err := low.Merge(NewDDSketch(DefaultConfig().DDSketch)); err != ErrIncompatibleSketches {
+		t.Errorf("Expected ErrIncompatibleSketches, got %v", err)
+	}
+}
+
+func TestGKSketch_MergeRankBounds(t *testing.T) {
+	config := GKConfig{Epsilon: 0.01}
+	rng := rand.New(rand.NewSource(11))
+
+	// Shards of the same distribution interleave their samples
+	merged := NewGKSketch(config)
+	values := make([]float64, 0, 80000)
+	for shard := 0; shard < 8; shard++ {
+		sketch := NewGKSketch(config)
+		for i := 0; i < 10000; i++ {
+			value := rng.Float64()
+			sketch.Add(value)
+			values = append(values, value)
+		}
+		if err := merged.Merge(sketch); err != nil {
+			t.Fatalf("Merge returned error: %v", err)
+		}
+	}
+	sort.Float64s(values)
+
+	// Each sample has its true rank within its lowest and highest ranks,
+	// which are at most 2 * epsilon * count apart
+	threshold := uint64(2 * config.Epsilon * float64(len(values)))
+	var minRank uint64
+	for _, tuple := range merged.tuples {
+		minRank += tuple.g
+		rank := uint64(sort.SearchFloat64s(values, tuple.value)) + 1
+		if rank < minRank || rank > minRank+tuple.delta {
+			t.Errorf("Sample %v has rank %d outside [%d, %d]", tuple.value, rank, minRank, minRank+tuple.delta)
+		}
+		if tuple.g+tuple.delta > threshold {
+			t.Errorf("Sample %v has g + delta %d over %d", tuple.value, tuple.g+tuple.delta, threshold)
+		}
+	}
+	checkRankError(t, "merged shards", merged, values, config.Epsilon)
+}
+
+func TestGKSketch_Bytes(t *testing.T) {
+	factory, ok := GetSketch("gk")
+	if !ok {
+		t.Fatalf("Expected the gk sketch to be registered")
+	}
+	sketch := factory()
+	for i := 1; i <= 1000; i++ {
+		sketch.Add(float64(i))
+	}
+
+	data, err := sketch.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes returned error: %v", err)
+	}
+	decoded := factory()
+	if err := decoded.FromBytes(data); err != nil {
+		t.Fatalf("FromBytes returned error: %v", err)
+	}
+
+	for _, q := range []float64{0, 0.5, 0.99, 1} {
+		expected, _ := sketch.GetValueAtQuantile(q)
+		if got, _ := decoded.GetValueAtQuantile(q); got != expected {
+			t.Errorf("Expected %v at q=%v after decoding, got %v", expected, q, got)
+		}
+	}
+	if avg, _ := decoded.GetAvg(); avg != 500.5 {
+		t.Errorf("Expected avg 500.5 after decoding, got %v", avg)
+	}
+
+	if err := decoded.FromBytes([]byte("{")); err == nil {
+		t.Errorf("Expected an error decoding invalid data")
+	}
+}
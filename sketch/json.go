@@ -0,0 +1,162 @@
+package sketch
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// jsonBucket is the JSON representation of a single bucket
+type jsonBucket struct {
+	Count uint64  `json:"count"`
+	Value float64 `json:"value"` // Representative value, informational only
+}
+
+// jsonSketch is the JSON representation of a DDSketch.
+// It is intended for inspection on debug endpoints, use Bytes() for a
+// compact representation.
+type jsonSketch struct {
+	Gamma           float64               `json:"gamma"`
+	MinValue        float64               `json:"minValue"`
+	MaxValue        float64               `json:"maxValue"`
+	Count           uint64                `json:"count"`
+	Min             *float64              `json:"min,omitempty"`
+	Max             *float64              `json:"max,omitempty"`
+	Sum             float64               `json:"sum"`
+	SumSquares      float64               `json:"sumSquares"`
+	UseSparseStore  bool                  `json:"useSparseStore"`
+	AllowNegative   bool                  `json:"allowNegative"`
+	ZeroCount       uint64                `json:"zeroCount,omitempty"`
+	Buckets         map[string]jsonBucket `json:"buckets"`
+	NegativeBuckets map[string]jsonBucket `json:"negativeBuckets,omitempty"`
+}
+
+// MarshalJSON returns a human-readable JSON dump of the sketch with every
+// non-empty bucket index, its count and a representative value
+func (d *DDSketch) MarshalJSON() ([]byte, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	js := jsonSketch{
+		Gamma:          d.gamma,
+		MinValue:       d.minValue,
+		MaxValue:       d.maxValue,
+		Count:          d.count,
+		Sum:            d.sum,
+		SumSquares:     d.sumSquares,
+		UseSparseStore: d.useSparseStore,
+		AllowNegative:  d.allowNegative,
+		ZeroCount:      d.zeroCount,
+		Buckets:        make(map[string]jsonBucket),
+	}
+
+	// Min and max are infinite when empty, which JSON cannot represent
+	if d.count > 0 {
+		min, max := d.min, d.max
+		js.Min = &min
+		js.Max = &max
+	}
+
+	for idx, count := range d.store.GetNonEmptyBuckets() {
+		js.Buckets[strconv.Itoa(idx)] = jsonBucket{
+			Count: count,
+			Value: d.indexToValue(idx),
+		}
+	}
+
+	if negativeBuckets := d.negativeStore.GetNonEmptyBuckets(); len(negativeBuckets) > 0 {
+		js.NegativeBuckets = make(map[string]jsonBucket, len(negativeBuckets))
+		for idx, count := range negativeBuckets {
+			js.NegativeBuckets[strconv.Itoa(idx)] = jsonBucket{
+				Count: count,
+				Value: -d.indexToValue(idx),
+			}
+		}
+	}
+
+	return json.Marshal(js)
+}
+
+// UnmarshalJSON rebuilds the sketch from a JSON dump produced by MarshalJSON.
+// Bucket indices and counts are authoritative, representative values are ignored
+func (d *DDSketch) UnmarshalJSON(data []byte) error {
+	var js jsonSketch
+	if err := json.Unmarshal(data, &js); err != nil {
+		return fmt.Errorf("invalid sketch JSON: %w", err)
+	}
+
+	if js.Gamma <= 0 || js.Gamma >= 1 {
+		return fmt.Errorf("invalid sketch JSON: gamma must be between 0 and 1, got %f", js.Gamma)
+	}
+
+	buckets, err := parseJSONBuckets(js.Buckets)
+	if err != nil {
+		return err
+	}
+	negativeBuckets, err := parseJSONBuckets(js.NegativeBuckets)
+	if err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	// Apply parameters
+	d.gamma = js.Gamma
+	d.multiplier = 1.0 / math.Log1p(d.gamma)
+	d.offset = 0
+	d.minValue = js.MinValue
+	d.maxValue = js.MaxValue
+	d.allowNegative = js.AllowNegative
+
+	// Apply statistics
+	d.count = js.Count
+	d.sum = js.Sum
+	d.sumSquares = js.SumSquares
+	d.zeroCount = js.ZeroCount
+	d.min = math.Inf(1)
+	d.max = math.Inf(-1)
+	if js.Min != nil {
+		d.min = *js.Min
+	}
+	if js.Max != nil {
+		d.max = *js.Max
+	}
+
+	// Choose store type
+	if js.UseSparseStore {
+		d.store = d.sparseStore
+	} else {
+		d.store = d.denseStore
+	}
+	d.useSparseStore = js.UseSparseStore
+
+	// Rebuild buckets
+	d.store.Clear()
+	for idx, count := range buckets {
+		d.store.Add(idx, count)
+	}
+
+	d.negativeStore.Clear()
+	for idx, count := range negativeBuckets {
+		d.negativeStore.Add(idx, count)
+	}
+
+	return nil
+}
+
+// parseJSONBuckets converts JSON bucket keys back into bucket indices
+func parseJSONBuckets(buckets map[string]jsonBucket) (map[int]uint64, error) {
+	parsed := make(map[int]uint64, len(buckets))
+	for key, bucket := range buckets {
+		idx, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sketch JSON: bad bucket index %q", key)
+		}
+		if bucket.Count > 0 {
+			parsed[idx] = bucket.Count
+		}
+	}
+	return parsed, nil
+}
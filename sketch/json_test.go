@@ -0,0 +1,90 @@
+package sketch
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestJSON_RoundTrip(t *testing.T) {
+	config := DefaultConfig().DDSketch
+	config.AllowNegative = true
+	sketch := NewDDSketch(config)
+
+	for i := -20; i <= 100; i++ {
+		sketch.Add(float64(i))
+	}
+
+	data, err := json.Marshal(sketch)
+	if err != nil {
+		t.Fatalf("json.Marshal returned error: %v", err)
+	}
+
+	// Human-readable fields are present
+	for _, field := range []string{`"gamma"`, `"count":121`, `"buckets"`, `"negativeBuckets"`, `"value"`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("Expected JSON to contain %s, got %s", field, data)
+		}
+	}
+
+	newSketch := NewDDSketch(DefaultConfig().DDSketch)
+	if err := json.Unmarshal(data, newSketch); err != nil {
+		t.Fatalf("json.Unmarshal returned error: %v", err)
+	}
+
+	if newSketch.GetCount() != sketch.GetCount() {
+		t.Errorf("Count mismatch: original=%d, decoded=%d", sketch.GetCount(), newSketch.GetCount())
+	}
+
+	origMin, _ := sketch.GetMin()
+	newMin, _ := newSketch.GetMin()
+	origMax, _ := sketch.GetMax()
+	newMax, _ := newSketch.GetMax()
+	if origMin != newMin || origMax != newMax {
+		t.Errorf("Min/max mismatch: original=[%f, %f], decoded=[%f, %f]", origMin, origMax, newMin, newMax)
+	}
+
+	for _, q := range []float64{0.01, 0.1, 0.5, 0.9, 0.99} {
+		origVal, _ := sketch.GetValueAtQuantile(q)
+		newVal, _ := newSketch.GetValueAtQuantile(q)
+		if math.Abs(origVal-newVal) > 1e-9*math.Abs(origVal) {
+			t.Errorf("Quantile mismatch at q=%f: original=%f, decoded=%f", q, origVal, newVal)
+		}
+	}
+}
+
+func TestJSON_EmptySketch(t *testing.T) {
+	sketch := NewDDSketch(DefaultConfig().DDSketch)
+
+	// Infinite min/max must not break encoding
+	data, err := json.Marshal(sketch)
+	if err != nil {
+		t.Fatalf("json.Marshal returned error: %v", err)
+	}
+
+	newSketch := NewDDSketch(DefaultConfig().DDSketch)
+	if err := json.Unmarshal(data, newSketch); err != nil {
+		t.Fatalf("json.Unmarshal returned error: %v", err)
+	}
+
+	if _, err := newSketch.GetMin(); err != ErrEmptySketch {
+		t.Errorf("Expected ErrEmptySketch, got %v", err)
+	}
+}
+
+func TestJSON_InvalidData(t *testing.T) {
+	sketch := NewDDSketch(DefaultConfig().DDSketch)
+
+	invalid := []string{
+		`not json`,
+		`{"gamma": 0}`,
+		`{"gamma": 0.01, "buckets": {"abc": {"count": 1}}}`,
+	}
+
+	for _, data := range invalid {
+		if err := json.Unmarshal([]byte(data), sketch); err == nil {
+			t.Errorf("Expected error for %s", data)
+		}
+	}
+}
@@ -19,6 +19,8 @@ const (
 	flagHasMin      = 1 << 1
 	flagHasMax      = 1 << 2
 	flagHasSum      = 1 << 3
+	flagNegative    = 1 << 4
+	flagSumSquares  = 1 << 5
 )
 
 // SerializedSketch represents a serialized sketch
@@ -38,10 +40,16 @@ type SerializedSketch struct {
 	Min       float64 // Minimum value (if flag set)
 	Max       float64 // Maximum value (if flag set)
 	Sum       float64 // Sum of values (if flag set)
+	SumSquares float64 // Sum of squares of values (if flag set)
 	
 	// Buckets
 	NumBuckets uint32             // Number of buckets
 	Buckets    map[int32]uint64   // Bucket index -> count
+	
+	// Negative values (if flag set)
+	ZeroCount          uint64           // Count of zero values
+	NumNegativeBuckets uint32           // Number of negative buckets
+	NegativeBuckets    map[int32]uint64 // Negative bucket index -> count
 }
 
 // Bytes returns a serialized representation of the DDSketch
@@ -60,9 +68,15 @@ func (d *DDSketch) Bytes() ([]byte, error) {
 	if !math.IsInf(d.max, -1) {
 		flags |= flagHasMax
 	}
-	if d.sum > 0 {
+	if d.sum != 0 {
 		flags |= flagHasSum
 	}
+	if d.allowNegative {
+		flags |= flagNegative
+	}
+	if d.sumSquares != 0 {
+		flags |= flagSumSquares
+	}
 	
 	// Get non-empty buckets
 	buckets := d.store.GetNonEmptyBuckets()
@@ -93,6 +107,9 @@ func (d *DDSketch) Bytes() ([]byte, error) {
 	if flags&flagHasSum != 0 {
 		binary.Write(buf, binary.LittleEndian, d.sum)
 	}
+	if flags&flagSumSquares != 0 {
+		binary.Write(buf, binary.LittleEndian, d.sumSquares)
+	}
 	
 	// Write buckets
 	binary.Write(buf, binary.LittleEndian, uint32(numBuckets))
@@ -102,6 +119,19 @@ func (d *DDSketch) Bytes() ([]byte, error) {
 		binary.Write(buf, binary.LittleEndian, count)
 	}
 	
+	// Write zero count and negative buckets
+	if flags&flagNegative != 0 {
+		binary.Write(buf, binary.LittleEndian, d.zeroCount)
+		
+		negativeBuckets := d.negativeStore.GetNonEmptyBuckets()
+		binary.Write(buf, binary.LittleEndian, uint32(len(negativeBuckets)))
+		
+		for idx, count := range negativeBuckets {
+			binary.Write(buf, binary.LittleEndian, int32(idx))
+			binary.Write(buf, binary.LittleEndian, count)
+		}
+	}
+	
 	return buf.Bytes(), nil
 }
 
@@ -115,8 +145,8 @@ func (d *DDSketch) FromBytes(data []byte) error {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 	
-	// Reset sketch
-	d.Reset()
+	// Reset sketch, the mutex is already held
+	d.reset()
 	
 	// Read from buffer
 	buf := bytes.NewBuffer(data)
@@ -140,6 +170,8 @@ func (d *DDSketch) FromBytes(data []byte) error {
 	hasMin := flags&flagHasMin != 0
 	hasMax := flags&flagHasMax != 0
 	hasSum := flags&flagHasSum != 0
+	hasNegative := flags&flagNegative != 0
+	hasSumSquares := flags&flagSumSquares != 0
 	
 	// Read parameters
 	binary.Read(buf, binary.LittleEndian, &d.gamma)
@@ -171,6 +203,12 @@ func (d *DDSketch) FromBytes(data []byte) error {
 		d.sum = 0
 	}
 	
+	if hasSumSquares {
+		binary.Read(buf, binary.LittleEndian, &d.sumSquares)
+	} else {
+		d.sumSquares = 0
+	}
+	
 	// Read buckets
 	var numBuckets uint32
 	binary.Read(buf, binary.LittleEndian, &numBuckets)
@@ -196,6 +234,31 @@ func (d *DDSketch) FromBytes(data []byte) error {
 		d.store.Add(int(idx), count)
 	}
 	
+	// Read zero count and negative buckets
+	d.allowNegative = hasNegative
+	d.negativeStore.Clear()
+	d.zeroCount = 0
+	
+	if hasNegative {
+		binary.Read(buf, binary.LittleEndian, &d.zeroCount)
+		
+		var numNegativeBuckets uint32
+		binary.Read(buf, binary.LittleEndian, &numNegativeBuckets)
+		
+		for i := uint32(0); i < numNegativeBuckets; i++ {
+			var idx int32
+			var count uint64
+			binary.Read(buf, binary.LittleEndian, &idx)
+			binary.Read(buf, binary.LittleEndian, &count)
+			d.negativeStore.Add(int(idx), count)
+		}
+	}
+	
+	// Estimate sum of squares for data written without it
+	if !hasSumSquares && d.count > 0 {
+		d.sumSquares = d.estimateSumSquares()
+	}
+	
 	return nil
 }
 
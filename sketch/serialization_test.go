@@ -64,6 +64,13 @@ func TestSerialization_Basic(t *testing.T) {
 			origSum, newSum)
 	}
 	
+	origVariance, _ := sketch.GetVariance()
+	newVariance, _ := newSketch.GetVariance()
+	if origVariance != newVariance {
+		t.Errorf("Deserialized variance mismatch: original=%f, deserialized=%f",
+			origVariance, newVariance)
+	}
+	
 	// Compare quantiles
 	quantiles := []float64{0.5, 0.9, 0.95, 0.99}
 	for _, q := range quantiles {
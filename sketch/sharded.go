@@ -0,0 +1,169 @@
+package sketch
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// ShardedDDSketch is a DDSketch variant for write-heavy workloads.
+// Add is fanned out across per-shard sub-sketches so concurrent writers rarely
+// contend on the same mutex. Reads lazily merge a snapshot copy of all shards,
+// so ingest keeps running while a query is answered.
+type ShardedDDSketch struct {
+	config   DDSketchConfig // Configuration of each shard
+	shards   []*DDSketch    // Per-shard sub-sketches
+	next     atomic.Uint32  // Round-robin counter for shard assignment
+	affinity sync.Pool      // Per-P cache of shard indices
+}
+
+// NewShardedDDSketch creates a new ShardedDDSketch with the given number of
+// shards. A non-positive shard count defaults to GOMAXPROCS
+func NewShardedDDSketch(config DDSketchConfig, shards int) *ShardedDDSketch {
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+
+	s := &ShardedDDSketch{
+		config: config,
+		shards: make([]*DDSketch, shards),
+	}
+	for i := range s.shards {
+		s.shards[i] = NewDDSketch(config)
+	}
+
+	// sync.Pool keeps a per-P cache, so goroutines running on the same P
+	// tend to reuse the same shard index. Indices dropped by the GC are
+	// simply reassigned round-robin, no data is lost
+	s.affinity.New = func() interface{} {
+		idx := int(s.next.Add(1)-1) % len(s.shards)
+		return &idx
+	}
+
+	return s
+}
+
+// Add adds a value to the sketch
+func (s *ShardedDDSketch) Add(value float64) error {
+	return s.AddWithCount(value, 1)
+}
+
+// AddWithCount adds a value with a specific count to one of the shards
+func (s *ShardedDDSketch) AddWithCount(value float64, count uint64) error {
+	idx := s.affinity.Get().(*int)
+	err := s.shards[*idx].AddWithCount(value, count)
+	s.affinity.Put(idx)
+	return err
+}
+
+// Snapshot returns a new DDSketch merging all shards
+func (s *ShardedDDSketch) Snapshot() *DDSketch {
+	merged := NewDDSketch(s.config)
+	for _, shard := range s.shards {
+		if shard.GetCount() == 0 {
+			continue
+		}
+		// Shards share the same configuration so merge cannot fail
+		_ = merged.Merge(shard)
+	}
+	return merged
+}
+
+// GetValueAtQuantile returns the value at the specified quantile
+func (s *ShardedDDSketch) GetValueAtQuantile(q float64) (float64, error) {
+	return s.Snapshot().GetValueAtQuantile(q)
+}
+
+// GetQuantileAtValue returns the quantile at which value falls
+func (s *ShardedDDSketch) GetQuantileAtValue(value float64) (float64, error) {
+	return s.Snapshot().GetQuantileAtValue(value)
+}
+
+// GetCount returns the total count of values across all shards
+func (s *ShardedDDSketch) GetCount() uint64 {
+	var count uint64
+	for _, shard := range s.shards {
+		count += shard.GetCount()
+	}
+	return count
+}
+
+// GetMin returns the minimum value added to the sketch
+func (s *ShardedDDSketch) GetMin() (float64, error) {
+	return s.Snapshot().GetMin()
+}
+
+// GetMax returns the maximum value added to the sketch
+func (s *ShardedDDSketch) GetMax() (float64, error) {
+	return s.Snapshot().GetMax()
+}
+
+// GetSum returns the sum of all values added to the sketch
+func (s *ShardedDDSketch) GetSum() (float64, error) {
+	return s.Snapshot().GetSum()
+}
+
+// GetAvg returns the average of all values added to the sketch
+func (s *ShardedDDSketch) GetAvg() (float64, error) {
+	return s.Snapshot().GetAvg()
+}
+
+// Merge merges another sketch into this one
+func (s *ShardedDDSketch) Merge(other Sketch) error {
+	switch o := other.(type) {
+	case *ShardedDDSketch:
+		return s.shards[0].Merge(o.Snapshot())
+	case *DDSketch:
+		return s.shards[0].Merge(o)
+	default:
+		return ErrIncompatibleSketches
+	}
+}
+
+// Copy creates a merged, unsharded copy of the sketch
+func (s *ShardedDDSketch) Copy() Sketch {
+	return s.Snapshot()
+}
+
+// Reset resets all shards to an empty state
+func (s *ShardedDDSketch) Reset() {
+	for _, shard := range s.shards {
+		shard.Reset()
+	}
+}
+
+// Bytes returns a serialized representation of the merged shards
+func (s *ShardedDDSketch) Bytes() ([]byte, error) {
+	return s.Snapshot().Bytes()
+}
+
+// FromBytes resets the sketch and loads the serialized data into the first shard
+func (s *ShardedDDSketch) FromBytes(data []byte) error {
+	temp := NewDDSketch(s.config)
+	if err := temp.FromBytes(data); err != nil {
+		return fmt.Errorf("failed to deserialize sharded sketch: %w", err)
+	}
+
+	s.Reset()
+	return s.shards[0].Merge(temp)
+}
+
+// Resources returns resource usage aggregated across all shards
+func (s *ShardedDDSketch) Resources() map[string]float64 {
+	resources := make(map[string]float64)
+	for _, shard := range s.shards {
+		for k, v := range shard.Resources() {
+			if k == "sketch_uptime_seconds" {
+				resources[k] = v
+				continue
+			}
+			resources[k] += v
+		}
+	}
+
+	// Report the average density rather than the sum
+	resources["sketch_store_density"] /= float64(len(s.shards))
+	resources["sketch_shards"] = float64(len(s.shards))
+	return resources
+}
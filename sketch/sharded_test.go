@@ -0,0 +1,153 @@
+package sketch
+
+import (
+	"math"
+	"sync"
+	"testing"
+)
+
+func TestShardedDDSketch_Basic(t *testing.T) {
+	// Dense store merges are lossless, so shards can be compared exactly
+	config := DefaultConfig().DDSketch
+	config.UseSparseStore = false
+	config.AutoSwitch = false
+	sharded := NewShardedDDSketch(config, 4)
+	reference := NewDDSketch(config)
+
+	// Empty sketch
+	if _, err := sharded.GetValueAtQuantile(0.5); err != ErrEmptySketch {
+		t.Errorf("Expected ErrEmptySketch, got %v", err)
+	}
+
+	for i := 1; i <= 1000; i++ {
+		sharded.Add(float64(i))
+		reference.Add(float64(i))
+	}
+
+	if sharded.GetCount() != 1000 {
+		t.Errorf("Expected count 1000, got %d", sharded.GetCount())
+	}
+
+	// Merged shards must answer exactly like a single sketch
+	for _, q := range []float64{0.1, 0.5, 0.9, 0.99} {
+		expected, _ := reference.GetValueAtQuantile(q)
+		actual, _ := sharded.GetValueAtQuantile(q)
+		if math.Abs(expected-actual) > 1e-9*expected {
+			t.Errorf("Quantile mismatch at q=%f: expected %f, got %f", q, expected, actual)
+		}
+	}
+
+	min, _ := sharded.GetMin()
+	max, _ := sharded.GetMax()
+	if min != 1 || max != 1000 {
+		t.Errorf("Expected min=1 max=1000, got min=%f max=%f", min, max)
+	}
+
+	// Serialization round trip
+	data, err := sharded.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() returned error: %v", err)
+	}
+	restored := NewShardedDDSketch(config, 2)
+	if err := restored.FromBytes(data); err != nil {
+		t.Fatalf("FromBytes() returned error: %v", err)
+	}
+	if restored.GetCount() != 1000 {
+		t.Errorf("Expected restored count 1000, got %d", restored.GetCount())
+	}
+
+	sharded.Reset()
+	if sharded.GetCount() != 0 {
+		t.Errorf("Expected count 0 after reset, got %d", sharded.GetCount())
+	}
+}
+
+func TestShardedDDSketch_Concurrent(t *testing.T) {
+	config := DefaultConfig().DDSketch
+	sharded := NewShardedDDSketch(config, 0)
+
+	goroutines := 10
+	opsPerGoroutine := 1000
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+
+	for g := 0; g < goroutines; g++ {
+		go func(id int) {
+			defer wg.Done()
+
+			for i := 0; i < opsPerGoroutine; i++ {
+				sharded.Add(float64(i%100 + 1))
+				if i%100 == 0 {
+					_, _ = sharded.GetValueAtQuantile(0.99)
+				}
+			}
+		}(g)
+	}
+
+	wg.Wait()
+
+	if sharded.GetCount() != uint64(goroutines*opsPerGoroutine) {
+		t.Errorf("Expected count %d, got %d", goroutines*opsPerGoroutine, sharded.GetCount())
+	}
+}
+
+func BenchmarkDDSketch_AddParallel(b *testing.B) {
+	sketch := NewDDSketch(DefaultConfig().DDSketch)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			sketch.Add(float64(i%1000 + 1))
+			i++
+		}
+	})
+}
+
+func BenchmarkShardedDDSketch_AddParallel(b *testing.B) {
+	sketch := NewShardedDDSketch(DefaultConfig().DDSketch, 0)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			sketch.Add(float64(i%1000 + 1))
+			i++
+		}
+	})
+}
+
+// benchmarkAddBatchParallel adds batches of scanSize values from parallel
+// goroutines, like consumers adding the samples of a scan
+func benchmarkAddBatchParallel(b *testing.B, add func(sketch *DDSketch, values []float64)) {
+	const scanSize = 500
+	sketch := NewDDSketch(DefaultConfig().DDSketch)
+	values := make([]float64, scanSize)
+	for i := range values {
+		values[i] = float64(i%1000 + 1)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			add(sketch, values)
+		}
+	})
+}
+
+func BenchmarkDDSketch_AddBatchParallel(b *testing.B) {
+	b.Run("loop", func(b *testing.B) {
+		benchmarkAddBatchParallel(b, func(sketch *DDSketch, values []float64) {
+			for _, value := range values {
+				sketch.Add(value)
+			}
+		})
+	})
+
+	b.Run("batch", func(b *testing.B) {
+		benchmarkAddBatchParallel(b, func(sketch *DDSketch, values []float64) {
+			sketch.AddBatch(values)
+		})
+	})
+}
@@ -0,0 +1,400 @@
+package sketch
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// snapshotBucket is a non-empty bucket of a DDSketchSnapshot
+type snapshotBucket struct {
+	index int
+	count uint64
+}
+
+// DDSketchSnapshot is an immutable point-in-time copy of a DDSketch. It
+// answers the read queries of the sketch without locking, so long-running
+// analytics do not block writers. Values added to the sketch after the
+// snapshot was taken are not reflected
+type DDSketchSnapshot struct {
+	multiplier           float64 // Mapping multiplier of the sketch
+	offset               float64 // Mapping offset of the sketch
+	minValue             float64 // Minimum allowed value
+	maxValue             float64 // Maximum allowed value
+	allowNegative        bool    // Whether negative and zero values are accepted
+	interpolateQuantiles bool    // Whether quantiles are interpolated within buckets
+
+	positive []snapshotBucket // Positive buckets, sorted by index
+	negative []snapshotBucket // Negative buckets keyed on -value, sorted by index
+
+	zeroCount  uint64
+	min        float64
+	max        float64
+	sum        float64
+	sumSquares float64
+	count      uint64
+}
+
+// Snapshot returns a point-in-time copy of the sketch. Buckets and
+// statistics are copied under a brief read lock, sorting and all queries on
+// the snapshot happen without holding it
+func (d *DDSketch) Snapshot() *DDSketchSnapshot {
+	d.mutex.RLock()
+	snapshot := d.snapshot()
+	d.mutex.RUnlock()
+
+	sortBuckets(snapshot.positive)
+	sortBuckets(snapshot.negative)
+	return snapshot
+}
+
+// Flush returns a snapshot of the sketch and resets it to empty in one
+// locked operation, so every value added concurrently is either in the
+// snapshot or in the sketch. Exporters use it to report and clear
+// per-interval sketches, instead of Copy then Reset
+func (d *DDSketch) Flush() *DDSketchSnapshot {
+	d.mutex.Lock()
+	snapshot := d.snapshot()
+	d.reset()
+	d.mutex.Unlock()
+
+	sortBuckets(snapshot.positive)
+	sortBuckets(snapshot.negative)
+	return snapshot
+}
+
+// snapshot copies the buckets and statistics of the sketch, with the
+// buckets unsorted. Caller must hold mutex
+func (d *DDSketch) snapshot() *DDSketchSnapshot {
+	return &DDSketchSnapshot{
+		multiplier:           d.multiplier,
+		offset:               d.offset,
+		minValue:             d.minValue,
+		maxValue:             d.maxValue,
+		allowNegative:        d.allowNegative,
+		interpolateQuantiles: d.interpolateQuantiles,
+		positive:             snapshotBuckets(d.store),
+		negative:             snapshotBuckets(d.negativeStore),
+		zeroCount:            d.zeroCount,
+		min:                  d.min,
+		max:                  d.max,
+		sum:                  d.sum,
+		sumSquares:           d.sumSquares,
+		count:                d.count,
+	}
+}
+
+// snapshotBuckets copies the non-empty buckets of a store, unsorted
+func snapshotBuckets(store Store) []snapshotBucket {
+	var buckets []snapshotBucket
+	store.ForEachBucket(func(index int, count uint64) bool {
+		buckets = append(buckets, snapshotBucket{index: index, count: count})
+		return true
+	})
+	return buckets
+}
+
+// sortBuckets sorts buckets by index
+func sortBuckets(buckets []snapshotBucket) {
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].index < buckets[j].index
+	})
+}
+
+// GetValueAtQuantile returns the value at the specified quantile,
+// interpolated if the sketch has InterpolateQuantiles set
+func (s *DDSketchSnapshot) GetValueAtQuantile(q float64) (float64, error) {
+	// Validate input
+	if q < 0 || q > 1 {
+		return 0, ErrInvalidQuantile
+	}
+
+	// Empty sketch check
+	if s.count == 0 {
+		return 0, ErrEmptySketch
+	}
+
+	// Handle edge cases
+	if q == 0 {
+		return s.min, nil
+	}
+	if q == 1 {
+		return s.max, nil
+	}
+
+	if s.interpolateQuantiles {
+		return interpolateQuantile(q, s.count, s.min, s.max, s.interpolatedValueAtRank), nil
+	}
+
+	// Calculate rank
+	rank := uint64(math.Ceil(q * float64(s.count)))
+
+	sign, index, _, _, found := s.rankBucket(rank)
+	switch {
+	case !found:
+		return s.max, nil
+	case sign < 0:
+		return -s.indexToValue(index), nil
+	case sign == 0:
+		return 0, nil
+	default:
+		return s.indexToValue(index), nil
+	}
+}
+
+// rankBucket finds the bucket holding the value of the given 1-based rank,
+// see DDSketch.rankBucket
+func (s *DDSketchSnapshot) rankBucket(rank uint64) (sign, index int, within, count uint64, found bool) {
+	// Walk negative buckets first, from the largest magnitude towards zero
+	var sum uint64
+	for i := len(s.negative) - 1; i >= 0; i-- {
+		bucket := s.negative[i]
+		if sum+bucket.count >= rank {
+			return -1, bucket.index, rank - sum, bucket.count, true
+		}
+		sum += bucket.count
+	}
+
+	// Then the zero bucket
+	if sum+s.zeroCount >= rank {
+		return 0, 0, rank - sum, s.zeroCount, true
+	}
+	sum += s.zeroCount
+
+	// Then the positive buckets
+	for _, bucket := range s.positive {
+		if sum+bucket.count >= rank {
+			return 1, bucket.index, rank - sum, bucket.count, true
+		}
+		sum += bucket.count
+	}
+
+	return 0, 0, 0, 0, false
+}
+
+// interpolatedValueAtRank estimates the value of the given 1-based rank by
+// spreading the values of its bucket evenly between the bucket bounds
+func (s *DDSketchSnapshot) interpolatedValueAtRank(rank uint64) float64 {
+	sign, index, within, count, found := s.rankBucket(rank)
+	switch {
+	case !found:
+		return s.max
+	case sign == 0:
+		return 0
+	}
+
+	lower, upper := s.indexToValue(index-1), s.indexToValue(index)
+	if sign < 0 {
+		return -spreadInBucket(upper, lower, within, count)
+	}
+	return spreadInBucket(lower, upper, within, count)
+}
+
+// GetQuantileAtValue returns the quantile at which value falls
+func (s *DDSketchSnapshot) GetQuantileAtValue(value float64) (float64, error) {
+	// Validate input
+	if value <= 0 && !s.allowNegative {
+		return 0, fmt.Errorf("value must be positive: %f", value)
+	}
+
+	// Empty sketch check
+	if s.count == 0 {
+		return 0, ErrEmptySketch
+	}
+
+	// Bound value to min/max range, keeping the sign for negative values
+	if value > 0 {
+		value = s.boundValue(value)
+	} else if value < 0 {
+		value = -s.boundValue(-value)
+	}
+
+	// Handle edge cases
+	if value <= s.min {
+		return 0, nil
+	}
+	if value >= s.max {
+		return 1, nil
+	}
+
+	// Find the number of elements below this value
+	var sum uint64
+	if value < 0 {
+		// Only negative values with a larger magnitude are below
+		sum = sumAbove(s.negative, s.valueToIndex(-value))
+		return float64(sum) / float64(s.count), nil
+	}
+
+	// All negative values are below zero and any positive value
+	sum += sumAbove(s.negative, math.MinInt)
+	if value == 0 {
+		return float64(sum) / float64(s.count), nil
+	}
+	sum += s.zeroCount
+
+	// Sum positive counts up to the index
+	sum += sumBelow(s.positive, s.valueToIndex(value))
+
+	return float64(sum) / float64(s.count), nil
+}
+
+// GetRank returns the number of values at or below the given value, as
+// DDSketch.GetRank
+func (s *DDSketchSnapshot) GetRank(value float64) (uint64, error) {
+	return s.countUpTo(value, true)
+}
+
+// GetCountBelow returns the number of values strictly below the given value,
+// as DDSketch.GetCountBelow
+func (s *DDSketchSnapshot) GetCountBelow(value float64) (uint64, error) {
+	return s.countUpTo(value, false)
+}
+
+// ToHistogram returns the cumulative count of values below each upper bound,
+// as DDSketch.ToHistogram
+func (s *DDSketchSnapshot) ToHistogram(bounds []float64) ([]uint64, error) {
+	return toHistogram(bounds, s.GetCountBelow)
+}
+
+// countUpTo returns the count of the buckets up to the given value,
+// including the value's own bucket when inclusive is set
+func (s *DDSketchSnapshot) countUpTo(value float64, inclusive bool) (uint64, error) {
+	// Validate input
+	if value <= 0 && !s.allowNegative {
+		return 0, fmt.Errorf("value must be positive: %f", value)
+	}
+
+	// Empty sketch check
+	if s.count == 0 {
+		return 0, ErrEmptySketch
+	}
+
+	// Handle edge cases
+	if value < s.min || (!inclusive && value == s.min) {
+		return 0, nil
+	}
+	if value > s.max || (inclusive && value == s.max) {
+		return s.count, nil
+	}
+
+	if value < 0 {
+		// Negative buckets are counted from the largest magnitude down
+		index := s.valueToIndex(-value)
+		if inclusive {
+			index--
+		}
+		return sumAbove(s.negative, index), nil
+	}
+
+	// All negative values are below zero and any positive value
+	sum := sumAbove(s.negative, math.MinInt)
+	if value == 0 {
+		if inclusive {
+			sum += s.zeroCount
+		}
+		return sum, nil
+	}
+	sum += s.zeroCount
+
+	// Sum positive counts up to the target index
+	index := s.valueToIndex(value)
+	if inclusive {
+		index++
+	}
+	return sum + sumBelow(s.positive, index), nil
+}
+
+// sumBelow returns the total count of the buckets with an index below index
+func sumBelow(buckets []snapshotBucket, index int) uint64 {
+	var sum uint64
+	for _, bucket := range buckets {
+		if bucket.index >= index {
+			break
+		}
+		sum += bucket.count
+	}
+	return sum
+}
+
+// sumAbove returns the total count of the buckets with an index above index
+func sumAbove(buckets []snapshotBucket, index int) uint64 {
+	var sum uint64
+	for i := len(buckets) - 1; i >= 0 && buckets[i].index > index; i-- {
+		sum += buckets[i].count
+	}
+	return sum
+}
+
+// GetCount returns the total count of values in the snapshot
+func (s *DDSketchSnapshot) GetCount() uint64 {
+	return s.count
+}
+
+// GetMin returns the minimum value in the snapshot
+func (s *DDSketchSnapshot) GetMin() (float64, error) {
+	if s.count == 0 {
+		return 0, ErrEmptySketch
+	}
+	return s.min, nil
+}
+
+// GetMax returns the maximum value in the snapshot
+func (s *DDSketchSnapshot) GetMax() (float64, error) {
+	if s.count == 0 {
+		return 0, ErrEmptySketch
+	}
+	return s.max, nil
+}
+
+// GetSum returns the sum of the values in the snapshot
+func (s *DDSketchSnapshot) GetSum() (float64, error) {
+	if s.count == 0 {
+		return 0, ErrEmptySketch
+	}
+	return s.sum, nil
+}
+
+// GetAvg returns the average of the values in the snapshot
+func (s *DDSketchSnapshot) GetAvg() (float64, error) {
+	if s.count == 0 {
+		return 0, ErrEmptySketch
+	}
+	return s.sum / float64(s.count), nil
+}
+
+// GetVariance returns the population variance of the values in the snapshot
+func (s *DDSketchSnapshot) GetVariance() (float64, error) {
+	if s.count == 0 {
+		return 0, ErrEmptySketch
+	}
+
+	n := float64(s.count)
+	variance := (s.sumSquares - s.sum*s.sum/n) / n
+
+	// Guard against floating-point cancellation
+	return max(variance, 0), nil
+}
+
+// GetStdDev returns the population standard deviation of the values in the snapshot
+func (s *DDSketchSnapshot) GetStdDev() (float64, error) {
+	variance, err := s.GetVariance()
+	if err != nil {
+		return 0, err
+	}
+	return math.Sqrt(variance), nil
+}
+
+// boundValue clamps a positive magnitude to the configured min/max range
+func (s *DDSketchSnapshot) boundValue(value float64) float64 {
+	return min(max(value, s.minValue), s.maxValue)
+}
+
+// valueToIndex maps a positive value to a bucket index of the sketch
+func (s *DDSketchSnapshot) valueToIndex(value float64) int {
+	return int(math.Ceil(s.multiplier*math.Log(value) - s.offset))
+}
+
+// indexToValue maps a bucket index to its representative value
+func (s *DDSketchSnapshot) indexToValue(index int) float64 {
+	return math.Exp((float64(index) + s.offset) / s.multiplier)
+}
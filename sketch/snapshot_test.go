@@ -0,0 +1,181 @@
+package sketch
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDDSketch_Snapshot(t *testing.T) {
+	for _, sparse := range []bool{true, false} {
+		config := DefaultConfig().DDSketch
+		config.UseSparseStore = sparse
+		config.AutoSwitch = false
+		config.AllowNegative = true
+		sketch := NewDDSketch(config)
+
+		if _, err := sketch.Snapshot().GetValueAtQuantile(0.5); err != ErrEmptySketch {
+			t.Errorf("Expected ErrEmptySketch, got %v", err)
+		}
+
+		for i := -100; i <= 1000; i++ {
+			sketch.Add(float64(i))
+		}
+		snapshot := sketch.Snapshot()
+
+		// The snapshot answers exactly like the sketch
+		for _, q := range []float64{0, 0.05, 0.1, 0.5, 0.9, 0.99, 1} {
+			expected, _ := sketch.GetValueAtQuantile(q)
+			actual, err := snapshot.GetValueAtQuantile(q)
+			if err != nil || math.Abs(expected-actual) > 1e-9*math.Abs(expected) {
+				t.Errorf("Sparse %v: quantile mismatch at q=%f: expected %f, got %f (%v)", sparse, q, expected, actual, err)
+			}
+		}
+		for _, value := range []float64{-100, -50.5, -1, 0, 1, 42, 500.5, 1000} {
+			expectedQuantile, _ := sketch.GetQuantileAtValue(value)
+			expectedRank, _ := sketch.GetRank(value)
+			expectedBelow, _ := sketch.GetCountBelow(value)
+			quantile, _ := snapshot.GetQuantileAtValue(value)
+			rank, _ := snapshot.GetRank(value)
+			below, _ := snapshot.GetCountBelow(value)
+			if quantile != expectedQuantile || rank != expectedRank || below != expectedBelow {
+				t.Errorf("Sparse %v: mismatch at value %f: expected quantile %f rank %d below %d, got %f %d %d",
+					sparse, value, expectedQuantile, expectedRank, expectedBelow, quantile, rank, below)
+			}
+		}
+
+		bounds := []float64{1, 10, 100, 1000}
+		expectedHistogram, _ := sketch.ToHistogram(bounds)
+		histogram, _ := snapshot.ToHistogram(bounds)
+		for i := range bounds {
+			if histogram[i] != expectedHistogram[i] {
+				t.Errorf("Sparse %v: histogram mismatch at bound %f: expected %d, got %d", sparse, bounds[i], expectedHistogram[i], histogram[i])
+			}
+		}
+
+		expectedStdDev, _ := sketch.GetStdDev()
+		stdDev, _ := snapshot.GetStdDev()
+		avg, _ := snapshot.GetAvg()
+		if stdDev != expectedStdDev || avg != 450 {
+			t.Errorf("Sparse %v: expected stddev %f avg 450, got %f %f", sparse, expectedStdDev, stdDev, avg)
+		}
+
+		// The snapshot is point-in-time
+		for i := 0; i < 1000; i++ {
+			sketch.Add(5000)
+		}
+		if snapshot.GetCount() != 1101 {
+			t.Errorf("Sparse %v: expected the snapshot count to stay 1101, got %d", sparse, snapshot.GetCount())
+		}
+		if max, _ := snapshot.GetMax(); max != 1000 {
+			t.Errorf("Sparse %v: expected the snapshot max to stay 1000, got %f", sparse, max)
+		}
+		if p99, _ := snapshot.GetValueAtQuantile(0.99); p99 > 1000*(1+config.RelativeAccuracy) {
+			t.Errorf("Sparse %v: expected the snapshot p99 to ignore later values, got %f", sparse, p99)
+		}
+	}
+}
+
+func TestDDSketch_Flush(t *testing.T) {
+	config := DefaultConfig().DDSketch
+	config.AllowNegative = true
+	sketch := NewDDSketch(config)
+
+	for i := 1; i <= 100; i++ {
+		sketch.Add(float64(i))
+	}
+	snapshot := sketch.Flush()
+	if snapshot.GetCount() != 100 || sketch.GetCount() != 0 {
+		t.Fatalf("Expected a snapshot of 100 values and an empty sketch, got %d and %d", snapshot.GetCount(), sketch.GetCount())
+	}
+	if p50, _ := snapshot.GetValueAtQuantile(0.5); math.Abs(p50-50) > 50*config.RelativeAccuracy {
+		t.Errorf("Expected the snapshot p50 to be close to 50, got %f", p50)
+	}
+	if _, err := sketch.GetMin(); err != ErrEmptySketch {
+		t.Errorf("Expected the flushed sketch to be empty, got %v", err)
+	}
+
+	// Adds interleaved with flushes are each in exactly one snapshot
+	const writers = 4
+	const addsPerWriter = 5000
+	var wg sync.WaitGroup
+	var done atomic.Bool
+	var flushed uint64
+	flushes := make(chan struct{})
+	go func() {
+		defer close(flushes)
+		for !done.Load() {
+			flushed += sketch.Flush().GetCount()
+		}
+	}()
+
+	wg.Add(writers)
+	for w := 0; w < writers; w++ {
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < addsPerWriter; i++ {
+				sketch.Add(float64(w*addsPerWriter + i - addsPerWriter))
+			}
+		}(w)
+	}
+	wg.Wait()
+	done.Store(true)
+	<-flushes
+
+	flushed += sketch.Flush().GetCount()
+	if flushed != writers*addsPerWriter {
+		t.Errorf("Expected %d values across the snapshots, got %d", writers*addsPerWriter, flushed)
+	}
+}
+
+// benchmarkAddDuringQueries measures the throughput of Add while another
+// goroutine keeps querying percentiles of a sketch spanning many buckets
+func benchmarkAddDuringQueries(b *testing.B, query func(*DDSketch)) {
+	config := DefaultConfig().DDSketch
+	config.AutoSwitch = false
+	sketch := NewDDSketch(config)
+	for i := 1; i <= 100000; i++ {
+		sketch.Add(float64(i))
+	}
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for !stop.Load() {
+			query(sketch)
+		}
+	}()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sketch.Add(float64(i%100000 + 1))
+	}
+	b.StopTimer()
+
+	stop.Store(true)
+	wg.Wait()
+}
+
+func BenchmarkDDSketch_AddDuringQueries(b *testing.B) {
+	quantiles := []float64{0.5, 0.9, 0.95, 0.99}
+
+	b.Run("locked", func(b *testing.B) {
+		benchmarkAddDuringQueries(b, func(sketch *DDSketch) {
+			for _, q := range quantiles {
+				_, _ = sketch.GetValueAtQuantile(q)
+			}
+		})
+	})
+
+	b.Run("snapshot", func(b *testing.B) {
+		benchmarkAddDuringQueries(b, func(sketch *DDSketch) {
+			snapshot := sketch.Snapshot()
+			for _, q := range quantiles {
+				_, _ = snapshot.GetValueAtQuantile(q)
+			}
+		})
+	})
+}
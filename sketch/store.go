@@ -19,6 +19,10 @@ type Store interface {
 	// GetNonEmptyBuckets returns a map of non-empty bucket indices to counts
 	GetNonEmptyBuckets() map[int]uint64
 	
+	// ForEachBucket calls fn for each non-empty bucket without allocating,
+	// stopping early when fn returns false. fn must not modify the store
+	ForEachBucket(fn func(index int, count uint64) bool)
+	
 	// GetTotalCount returns the sum of counts across all buckets
 	GetTotalCount() uint64
 	
@@ -41,6 +45,34 @@ type Store interface {
 	GetMemoryUsageBytes() int64
 }
 
+// sparseCollapseBuckets is the number of buckets above which a SparseStore
+// collapses its low-count buckets
+const sparseCollapseBuckets = 1000
+
+const (
+	// mapBucketSlots is the number of entries held by a bucket of a Go map
+	mapBucketSlots = 8
+	
+	// mapLoadFactor is the average number of entries per bucket above which a
+	// Go map doubles its buckets
+	mapLoadFactor = 6.5
+)
+
+// mapMemoryBytes estimates the memory held by a Go map that grew to entries
+// entries of entryBytes each. Maps allocate a power of two of buckets of 8
+// slots, each with a tophash byte per slot and an overflow pointer, and never
+// shrink
+func mapMemoryBytes(entries int, entryBytes int64) int64 {
+	buckets := int64(1)
+	for float64(entries) > mapLoadFactor*float64(buckets) {
+		buckets *= 2
+	}
+	
+	headerSize := int64(48)
+	bucketSize := mapBucketSlots*(entryBytes+1) + 8
+	return headerSize + buckets*bucketSize
+}
+
 // SparseStore is a memory-efficient implementation of Store using a map
 // It's best suited for sparse distributions where most buckets are empty
 type SparseStore struct {
@@ -50,6 +82,7 @@ type SparseStore struct {
 	maxIndex        int
 	hasElements     bool
 	collapseThreshold uint64
+	peakBins        int // Most bins held since the map was made, maps never shrink
 	mu              sync.RWMutex
 }
 
@@ -70,6 +103,7 @@ func (s *SparseStore) Add(index int, count uint64) {
 	
 	s.bins[index] += count
 	s.count += count
+	s.peakBins = max(s.peakBins, len(s.bins))
 	
 	// Update min/max indices
 	if index < s.minIndex {
@@ -82,7 +116,7 @@ func (s *SparseStore) Add(index int, count uint64) {
 	s.hasElements = true
 	
 	// Periodically collapse low-count buckets to save memory
-	if len(s.bins) > 1000 { // Only check when we have lots of buckets
+	if len(s.bins) > sparseCollapseBuckets { // Only check when we have lots of buckets
 		s.collapseBuckets()
 	}
 }
@@ -100,6 +134,7 @@ func (s *SparseStore) Clear() {
 	defer s.mu.Unlock()
 	
 	s.bins = make(map[int]uint64)
+	s.peakBins = 0
 	s.count = 0
 	s.minIndex = math.MaxInt32
 	s.maxIndex = math.MinInt32
@@ -119,6 +154,22 @@ func (s *SparseStore) GetNonEmptyBuckets() map[int]uint64 {
 	return buckets
 }
 
+// ForEachBucket calls fn for each non-empty bucket in no particular order,
+// stopping early when fn returns false
+func (s *SparseStore) ForEachBucket(fn func(index int, count uint64) bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	for idx, count := range s.bins {
+		if count == 0 {
+			continue
+		}
+		if !fn(idx, count) {
+			return
+		}
+	}
+}
+
 // GetTotalCount returns the sum of counts across all buckets
 func (s *SparseStore) GetTotalCount() uint64 {
 	s.mu.RLock()
@@ -171,13 +222,15 @@ func (s *SparseStore) Merge(other Store) {
 	
 	// Update total count
 	s.count += other.GetTotalCount()
+	s.peakBins = max(s.peakBins, len(s.bins))
 	
 	if s.count > 0 {
 		s.hasElements = true
 	}
 	
-	// Collapse buckets if we merged a lot
-	if len(otherBuckets) > 100 {
+	// Collapse buckets under the same bound as Add, so repeated merges, e.g.
+	// of scans into a window, stay as accurate as adding the values
+	if len(s.bins) > sparseCollapseBuckets {
 		s.collapseBuckets()
 	}
 }
@@ -197,6 +250,7 @@ func (s *SparseStore) Copy() Store {
 	for idx, count := range s.bins {
 		newStore.bins[idx] = count
 	}
+	newStore.peakBins = len(newStore.bins)
 	
 	return newStore
 }
@@ -224,16 +278,15 @@ func (s *SparseStore) GetMemoryUsageBytes() int64 {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	
-	// Rough estimate: 
-	// - map overhead: 48 bytes
-	// - each entry: 16 bytes (key) + 8 bytes (value) + 16 bytes (entry overhead)
+	// Estimate:
+	// - map: the buckets allocated for the most bins held, 8 bytes (key) +
+	//   8 bytes (value) per slot
 	// - other fields: 8 bytes each
 	
-	mapOverhead := int64(48)
-	entriesSize := int64(len(s.bins)) * int64(16+8+16)
-	otherFields := int64(8 * 5) // count, minIndex, maxIndex, hasElements, collapseThreshold
+	mapSize := mapMemoryBytes(s.peakBins, 8+8)
+	otherFields := int64(8 * 6) // count, minIndex, maxIndex, hasElements, collapseThreshold, peakBins
 	
-	return mapOverhead + entriesSize + otherFields
+	return mapSize + otherFields
 }
 
 // collapseBuckets combines adjacent low-count buckets to save memory
@@ -275,11 +328,17 @@ func (s *SparseStore) collapseBuckets() {
 			upperDist := upperIdx - idx
 			totalDist := lowerDist + upperDist
 			
-			lowerPortion := float64(upperDist) / float64(totalDist)
-			upperPortion := float64(lowerDist) / float64(totalDist)
-			
-			s.bins[lowerIdx] += uint64(float64(count) * lowerPortion)
-			s.bins[upperIdx] += uint64(float64(count) * upperPortion)
+			// The farther bucket gets its truncated portion and the nearer
+			// one the rest, so no count is lost to truncation
+			if lowerDist <= upperDist {
+				upperShare := uint64(float64(count) * float64(lowerDist) / float64(totalDist))
+				s.bins[upperIdx] += upperShare
+				s.bins[lowerIdx] += count - upperShare
+			} else {
+				lowerShare := uint64(float64(count) * float64(upperDist) / float64(totalDist))
+				s.bins[lowerIdx] += lowerShare
+				s.bins[upperIdx] += count - lowerShare
+			}
 		} else if lowerIdx != math.MinInt32 {
 			// Only have lower bucket
 			s.bins[lowerIdx] += count
@@ -323,6 +382,8 @@ func (s *SparseStore) recalculateMinMax() {
 
 // DenseStore is a memory-efficient implementation of Store using arrays
 // It's best suited for dense distributions with a narrow range of values
+// When maxBuckets is set, the array never grows beyond that many buckets;
+// extreme buckets are collapsed into the boundary bucket instead
 type DenseStore struct {
 	bins       []uint64
 	count      uint64
@@ -330,26 +391,46 @@ type DenseStore struct {
 	minIndex   int
 	maxIndex   int
 	hasElements bool
+	maxBuckets int  // Maximum number of buckets (0 = unbounded)
+	keepLowest bool  // Collapse the highest buckets instead of the lowest
 	mu         sync.RWMutex
 }
 
 // NewDenseStore creates a new dense store
-func NewDenseStore(initialCapacity int) *DenseStore {
+// When the number of buckets would exceed maxBuckets, the lowest buckets are
+// collapsed, preserving accuracy for the upper quantiles. A maxBuckets of 0
+// leaves the store unbounded.
+func NewDenseStore(initialCapacity int, maxBuckets int) *DenseStore {
+	if maxBuckets > 0 && initialCapacity > maxBuckets {
+		initialCapacity = maxBuckets
+	}
+	
 	return &DenseStore{
 		bins:       make([]uint64, initialCapacity),
 		offset:     0,
 		minIndex:   math.MaxInt32,
 		maxIndex:   math.MinInt32,
+		maxBuckets: maxBuckets,
 	}
 }
 
+// NewCollapsingHighestDenseStore creates a new dense store that collapses the
+// highest buckets when the number of buckets would exceed maxBuckets,
+// preserving accuracy for the lower quantiles
+func NewCollapsingHighestDenseStore(initialCapacity int, maxBuckets int) *DenseStore {
+	store := NewDenseStore(initialCapacity, maxBuckets)
+	store.keepLowest = true
+	return store
+}
+
 // Add increments the count for the bin at the given index
 func (d *DenseStore) Add(index int, count uint64) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	
-	// Ensure the index is within the array bounds
-	d.ensureCapacity(index)
+	// Ensure the index is within the array bounds, which may redirect
+	// it to a boundary bucket when the store is at capacity
+	index = d.ensureCapacity(index)
 	
 	// Calculate array index
 	arrIdx := index - d.offset
@@ -413,6 +494,22 @@ func (d *DenseStore) GetNonEmptyBuckets() map[int]uint64 {
 	return buckets
 }
 
+// ForEachBucket calls fn for each non-empty bucket in ascending index order,
+// stopping early when fn returns false
+func (d *DenseStore) ForEachBucket(fn func(index int, count uint64) bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	
+	for i, count := range d.bins {
+		if count == 0 {
+			continue
+		}
+		if !fn(i+d.offset, count) {
+			return
+		}
+	}
+}
+
 // GetTotalCount returns the sum of counts across all buckets
 func (d *DenseStore) GetTotalCount() uint64 {
 	d.mu.RLock()
@@ -442,7 +539,9 @@ func (d *DenseStore) GetMaxIndex() (int, bool) {
 	return d.maxIndex, true
 }
 
-// Merge merges another store into this one
+// Merge merges another store into this one. A bounded store never grows
+// beyond maxBuckets, incoming buckets outside its range are collapsed into
+// its boundary buckets like added ones
 func (d *DenseStore) Merge(other Store) {
 	// Get all non-empty buckets from the other store
 	otherBuckets := other.GetNonEmptyBuckets()
@@ -464,24 +563,28 @@ func (d *DenseStore) Merge(other Store) {
 			}
 		}
 		
-		// Ensure we have capacity for all indices
-		d.ensureCapacity(minIdx)
-		d.ensureCapacity(maxIdx)
+		// Ensure we have capacity for all indices. Bounded stores must cover
+		// the whole range at once, a second resize would drop the first
+		if d.maxBuckets > 0 {
+			d.coverRange(minIdx, maxIdx)
+		} else {
+			d.ensureCapacity(minIdx)
+			d.ensureCapacity(maxIdx)
+		}
 	}
 	
-	// Merge each bucket
+	// Merge each bucket, clamping the extremes of a bounded store into its
+	// boundary buckets so no count is dropped
 	for idx, count := range otherBuckets {
-		arrIdx := idx - d.offset
-		if arrIdx >= 0 && arrIdx < len(d.bins) {
-			d.bins[arrIdx] += count
-			
-			// Update min/max indices
-			if idx < d.minIndex {
-				d.minIndex = idx
-			}
-			if idx > d.maxIndex {
-				d.maxIndex = idx
-			}
+		idx = min(max(idx, d.offset), d.offset+len(d.bins)-1)
+		d.bins[idx-d.offset] += count
+		
+		// Update min/max indices
+		if idx < d.minIndex {
+			d.minIndex = idx
+		}
+		if idx > d.maxIndex {
+			d.maxIndex = idx
 		}
 	}
 	
@@ -505,6 +608,8 @@ func (d *DenseStore) Copy() Store {
 		minIndex:    d.minIndex,
 		maxIndex:    d.maxIndex,
 		hasElements: d.hasElements,
+		maxBuckets:  d.maxBuckets,
+		keepLowest:  d.keepLowest,
 	}
 	
 	// Copy all bins
@@ -538,29 +643,45 @@ func (d *DenseStore) GetMemoryUsageBytes() int64 {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 	
-	// Rough estimate: 
+	// Estimate:
 	// - array overhead: 24 bytes
-	// - array elements: 8 bytes each
+	// - array elements: 8 bytes for each allocated element, which may be
+	//   more than the bins in use
 	// - other fields: 8 bytes each
 	
 	arrayOverhead := int64(24)
-	elementsSize := int64(len(d.bins)) * int64(8)
-	otherFields := int64(8 * 5) // count, offset, minIndex, maxIndex, hasElements
+	elementsSize := int64(cap(d.bins)) * int64(8)
+	otherFields := int64(8 * 7) // count, offset, minIndex, maxIndex, hasElements, maxBuckets, keepLowest
 	
 	return arrayOverhead + elementsSize + otherFields
 }
 
-// ensureCapacity ensures the store has capacity for the given index
-func (d *DenseStore) ensureCapacity(index int) {
+// GetMaxBuckets returns the bucket cap of the store (0 = unbounded)
+func (d *DenseStore) GetMaxBuckets() int {
+	return d.maxBuckets
+}
+
+// ensureCapacity ensures the store has capacity for the given index and
+// returns the index to record counts at, which is a boundary bucket when
+// the given index falls into a collapsed range
+func (d *DenseStore) ensureCapacity(index int) int {
 	// If array is empty, initialize with index as the offset
 	if len(d.bins) == 0 {
 		d.bins = make([]uint64, 1)
 		d.offset = index
-		return
+		return index
 	}
 	
 	// Calculate array index
 	arrIdx := index - d.offset
+	if arrIdx >= 0 && arrIdx < len(d.bins) {
+		return index
+	}
+	
+	// Bounded stores collapse extreme buckets instead of growing without limit
+	if d.maxBuckets > 0 {
+		return d.growBounded(index)
+	}
 	
 	// If index is out of bounds, resize the array
 	if arrIdx < 0 {
@@ -576,4 +697,101 @@ func (d *DenseStore) ensureCapacity(index int) {
 		copy(newBins, d.bins)
 		d.bins = newBins
 	}
+	
+	return index
+}
+
+// growBounded resizes the array to cover the given index without exceeding
+// maxBuckets and returns the index to record counts at
+func (d *DenseStore) growBounded(index int) int {
+	d.coverRange(index, index)
+	
+	// Clamp into the collapsed range
+	if index < d.offset {
+		index = d.offset
+	} else if index >= d.offset+len(d.bins) {
+		index = d.offset + len(d.bins) - 1
+	}
+	
+	return index
+}
+
+// coverRange re-bases a bounded store onto a range spanning both its current
+// buckets and [lo, hi], collapsing extreme buckets when that exceeds maxBuckets
+func (d *DenseStore) coverRange(lo, hi int) {
+	if d.hasElements {
+		if d.minIndex < lo {
+			lo = d.minIndex
+		}
+		if d.maxIndex > hi {
+			hi = d.maxIndex
+		}
+	}
+	
+	// Fits within the cap, just re-base the array onto the used range
+	if hi-lo+1 <= d.maxBuckets {
+		d.rebin(lo, hi)
+		return
+	}
+	
+	if d.keepLowest {
+		d.collapseHighest(lo)
+	} else {
+		d.collapseLowest(hi)
+	}
+}
+
+// collapseLowest keeps the maxBuckets buckets ending at maxIndex and
+// merges every lower bucket into the new lowest bucket, returning its index
+func (d *DenseStore) collapseLowest(maxIndex int) int {
+	minIndex := maxIndex - d.maxBuckets + 1
+	d.rebin(minIndex, maxIndex)
+	return minIndex
+}
+
+// collapseHighest keeps the maxBuckets buckets starting at minIndex and
+// merges every higher bucket into the new highest bucket, returning its index
+func (d *DenseStore) collapseHighest(minIndex int) int {
+	maxIndex := minIndex + d.maxBuckets - 1
+	d.rebin(minIndex, maxIndex)
+	return maxIndex
+}
+
+// rebin moves all buckets into a new array covering [lo, hi], merging any
+// bucket outside that range into the nearest boundary bucket so that the
+// total count is preserved
+func (d *DenseStore) rebin(lo, hi int) {
+	newBins := make([]uint64, hi-lo+1)
+	
+	if d.hasElements {
+		for i := d.minIndex; i <= d.maxIndex; i++ {
+			count := d.bins[i-d.offset]
+			if count == 0 {
+				continue
+			}
+			
+			target := i
+			if target < lo {
+				target = lo
+			} else if target > hi {
+				target = hi
+			}
+			newBins[target-lo] += count
+		}
+		
+		// Boundary buckets now hold the collapsed extremes
+		if d.minIndex < lo {
+			d.minIndex = lo
+		} else if d.minIndex > hi {
+			d.minIndex = hi
+		}
+		if d.maxIndex > hi {
+			d.maxIndex = hi
+		} else if d.maxIndex < lo {
+			d.maxIndex = lo
+		}
+	}
+	
+	d.bins = newBins
+	d.offset = lo
 }
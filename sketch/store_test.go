@@ -1,6 +1,8 @@
 package sketch
 
 import (
+	"math/rand"
+	"runtime"
 	"sync"
 	"testing"
 )
@@ -271,9 +273,34 @@ func TestSparseStore_Collapse(t *testing.T) {
 	}
 }
 
+func TestSparseStore_CollapsePreservesCount(t *testing.T) {
+	store := NewSparseStore(4)
+	rng := rand.New(rand.NewSource(3))
+	
+	// Odd counts at uneven spacings split into fractional portions
+	var expected uint64
+	for round := 0; round < 50; round++ {
+		for i := 0; i < 40; i++ {
+			count := uint64(2*rng.Intn(3) + 1)
+			store.Add(rng.Intn(500), count)
+			expected += count
+		}
+		store.collapseBuckets()
+		
+		var total uint64
+		for _, count := range store.GetNonEmptyBuckets() {
+			total += count
+		}
+		if total != expected || store.GetTotalCount() != expected {
+			t.Fatalf("Round %d: expected %d counts after collapse, got %d in buckets and total %d",
+				round, expected, total, store.GetTotalCount())
+		}
+	}
+}
+
 func TestDenseStore_Basic(t *testing.T) {
 	// Create a new dense store
-	store := NewDenseStore(10)
+	store := NewDenseStore(10, 0)
 	
 	// Initial state checks
 	if store.GetTotalCount() != 0 {
@@ -352,7 +379,7 @@ func TestDenseStore_Basic(t *testing.T) {
 
 func TestDenseStore_Resize(t *testing.T) {
 	// Create a small store
-	store := NewDenseStore(5)
+	store := NewDenseStore(5, 0)
 	
 	// Add within initial capacity
 	store.Add(0, 1)
@@ -397,10 +424,166 @@ func TestDenseStore_Resize(t *testing.T) {
 	}
 }
 
+func TestDenseStore_MaxBuckets(t *testing.T) {
+	// Create a store capped at 10 buckets
+	store := NewDenseStore(5, 10)
+	
+	// Fill the store within the cap
+	for i := 0; i < 10; i++ {
+		store.Add(i, 1)
+	}
+	
+	// Add a far-away outlier which would force a huge allocation
+	store.Add(1000000, 5)
+	
+	// Lowest buckets must be collapsed into the new lowest bucket
+	minIdx, _ := store.GetMinIndex()
+	maxIdx, _ := store.GetMaxIndex()
+	if maxIdx != 1000000 {
+		t.Errorf("Max index should be 1000000, got %d", maxIdx)
+	}
+	if maxIdx-minIdx+1 > 10 {
+		t.Errorf("Store should span at most 10 buckets, spans %d", maxIdx-minIdx+1)
+	}
+	if store.Get(minIdx) != 10 {
+		t.Errorf("Lowest bucket should hold the 10 collapsed counts, got %d", store.Get(minIdx))
+	}
+	
+	// Values below the retained range land in the boundary bucket
+	store.Add(-50, 2)
+	if store.Get(minIdx) != 12 {
+		t.Errorf("Lowest bucket should hold 12 counts, got %d", store.Get(minIdx))
+	}
+	
+	// Total count must be preserved
+	if store.GetTotalCount() != 17 {
+		t.Errorf("Total count should be 17, got %d", store.GetTotalCount())
+	}
+	
+	var bucketTotal uint64
+	for _, count := range store.GetNonEmptyBuckets() {
+		bucketTotal += count
+	}
+	if bucketTotal != 17 {
+		t.Errorf("Bucket counts should sum to 17, got %d", bucketTotal)
+	}
+	
+	// Memory usage must stay bounded by the cap
+	if store.GetMemoryUsageBytes() > NewDenseStore(10, 10).GetMemoryUsageBytes() {
+		t.Errorf("Memory usage should be bounded by the cap, got %d bytes", store.GetMemoryUsageBytes())
+	}
+}
+
+func TestDenseStore_MaxBucketsCollapseHighest(t *testing.T) {
+	// Create a store capped at 10 buckets that keeps the lowest buckets
+	store := NewCollapsingHighestDenseStore(5, 10)
+	
+	for i := 0; i < 10; i++ {
+		store.Add(i, 1)
+	}
+	
+	// Add outliers on both sides
+	store.Add(1000000, 5)
+	store.Add(-3, 1)
+	
+	minIdx, _ := store.GetMinIndex()
+	maxIdx, _ := store.GetMaxIndex()
+	if minIdx != -3 {
+		t.Errorf("Min index should be -3, got %d", minIdx)
+	}
+	if maxIdx != minIdx+9 {
+		t.Errorf("Max index should be %d, got %d", minIdx+9, maxIdx)
+	}
+	
+	// Highest bucket holds the outlier and the collapsed upper buckets
+	if store.Get(maxIdx) != 9 {
+		t.Errorf("Highest bucket should hold 9 counts, got %d", store.Get(maxIdx))
+	}
+	
+	if store.GetTotalCount() != 16 {
+		t.Errorf("Total count should be 16, got %d", store.GetTotalCount())
+	}
+}
+
+func TestDenseStore_MaxBucketsMergeIntoEmpty(t *testing.T) {
+	source := NewDenseStore(16, 0)
+	for i := 100; i < 600; i++ {
+		source.Add(i, 1)
+	}
+	
+	// The whole incoming range fits under the cap, nothing may be collapsed
+	store := NewDenseStore(16, 1000)
+	store.Merge(source)
+	
+	if store.GetTotalCount() != 500 {
+		t.Errorf("Expected total count 500, got %d", store.GetTotalCount())
+	}
+	
+	for i := 100; i < 600; i++ {
+		if store.Get(i) != 1 {
+			t.Fatalf("Expected count 1 at index %d, got %d", i, store.Get(i))
+		}
+	}
+	
+	minIndex, _ := store.GetMinIndex()
+	maxIndex, _ := store.GetMaxIndex()
+	if minIndex != 100 || maxIndex != 599 {
+		t.Errorf("Expected index range [100, 599], got [%d, %d]", minIndex, maxIndex)
+	}
+}
+
+func TestDenseStore_MaxBucketsMergeOutlier(t *testing.T) {
+	// The incoming store spans a million indices because of one outlier
+	source := NewSparseStore(10)
+	for i := 0; i < 20; i++ {
+		source.Add(i, 2)
+	}
+	source.Add(1000000, 3)
+	
+	for _, keepLowest := range []bool{false, true} {
+		store := NewDenseStore(16, 64)
+		if keepLowest {
+			store = NewCollapsingHighestDenseStore(16, 64)
+		}
+		for i := 10; i < 30; i++ {
+			store.Add(i, 1)
+		}
+		store.Merge(source)
+		
+		if len(store.bins) > 64 {
+			t.Errorf("keepLowest %v: expected at most 64 buckets, got %d", keepLowest, len(store.bins))
+		}
+		
+		var total uint64
+		for _, count := range store.GetNonEmptyBuckets() {
+			total += count
+		}
+		if total != 63 || store.GetTotalCount() != 63 {
+			t.Errorf("keepLowest %v: expected 63 counts, got %d in buckets and total %d", keepLowest, total, store.GetTotalCount())
+		}
+		
+		// The extremes on the collapsed side end up in the boundary bucket
+		minIndex, _ := store.GetMinIndex()
+		maxIndex, _ := store.GetMaxIndex()
+		if keepLowest {
+			if minIndex != 0 || maxIndex != 63 || store.Get(63) != 3 {
+				t.Errorf("Expected the outlier collapsed into index 63 of [0, 63], got %d in [%d, %d]", store.Get(63), minIndex, maxIndex)
+			}
+		} else {
+			if minIndex != 1000000-63 || maxIndex != 1000000 || store.Get(1000000) != 3 {
+				t.Errorf("Expected the outlier kept at the top of [%d, 1000000], got %d in [%d, %d]", 1000000-63, store.Get(1000000), minIndex, maxIndex)
+			}
+			if store.Get(minIndex) != 60 {
+				t.Errorf("Expected the lowest bucket to hold the 60 collapsed counts, got %d", store.Get(minIndex))
+			}
+		}
+	}
+}
+
 func TestDenseStore_Merge(t *testing.T) {
 	// Create two stores
-	store1 := NewDenseStore(10)
-	store2 := NewDenseStore(10)
+	store1 := NewDenseStore(10, 0)
+	store2 := NewDenseStore(10, 0)
 	
 	// Add to first store
 	store1.Add(1, 5)
@@ -443,7 +626,7 @@ func TestDenseStore_Merge(t *testing.T) {
 
 func TestDenseStore_Copy(t *testing.T) {
 	// Create a store
-	store := NewDenseStore(10)
+	store := NewDenseStore(10, 0)
 	store.Add(1, 5)
 	store.Add(2, 10)
 	store.Add(3, 15)
@@ -480,7 +663,7 @@ func TestDenseStore_Copy(t *testing.T) {
 
 func TestDenseStore_Concurrent(t *testing.T) {
 	// Create a store
-	store := NewDenseStore(10)
+	store := NewDenseStore(10, 0)
 	
 	// Test concurrent access
 	var wg sync.WaitGroup
@@ -526,6 +709,56 @@ func TestDenseStore_Concurrent(t *testing.T) {
 	}
 }
 
+func TestStore_ForEachBucket(t *testing.T) {
+	stores := map[string]Store{
+		"sparse": NewSparseStore(10),
+		"dense":  NewDenseStore(16, 0),
+	}
+	
+	for name, store := range stores {
+		store.Add(5, 2)
+		store.Add(-3, 1)
+		store.Add(40, 7)
+		
+		// Visits exactly the non-empty buckets
+		visited := make(map[int]uint64)
+		store.ForEachBucket(func(index int, count uint64) bool {
+			visited[index] = count
+			return true
+		})
+		
+		expected := store.GetNonEmptyBuckets()
+		if len(visited) != len(expected) {
+			t.Errorf("%s: expected %d buckets, visited %d", name, len(expected), len(visited))
+		}
+		for idx, count := range expected {
+			if visited[idx] != count {
+				t.Errorf("%s: expected count %d at index %d, got %d", name, count, idx, visited[idx])
+			}
+		}
+		
+		// Stops early
+		calls := 0
+		store.ForEachBucket(func(index int, count uint64) bool {
+			calls++
+			return false
+		})
+		if calls != 1 {
+			t.Errorf("%s: expected iteration to stop after 1 call, got %d", name, calls)
+		}
+	}
+	
+	// Dense store iterates in ascending index order
+	var order []int
+	stores["dense"].ForEachBucket(func(index int, count uint64) bool {
+		order = append(order, index)
+		return true
+	})
+	if len(order) != 3 || order[0] != -3 || order[1] != 5 || order[2] != 40 {
+		t.Errorf("Expected dense iteration order [-3 5 40], got %v", order)
+	}
+}
+
 func TestStore_Density(t *testing.T) {
 	// Test sparse store density
 	sparseStore := NewSparseStore(10)
@@ -548,7 +781,7 @@ func TestStore_Density(t *testing.T) {
 	}
 	
 	// Test dense store density
-	denseStore := NewDenseStore(10)
+	denseStore := NewDenseStore(10, 0)
 	
 	// Empty store should have zero density
 	if denseStore.GetStoreDensity() != 0 {
@@ -573,6 +806,99 @@ func TestStore_Density(t *testing.T) {
 	}
 }
 
+func TestSparseStore_MemoryUsageGrowsWithMapCapacity(t *testing.T) {
+	store := NewSparseStore(10)
+	empty := store.GetMemoryUsageBytes()
+	
+	// Up to 6.5 entries per bucket fit before the buckets double
+	store.Add(0, 100)
+	if store.GetMemoryUsageBytes() != empty {
+		t.Errorf("One bin should fit the first bucket, got %d bytes, empty %d", store.GetMemoryUsageBytes(), empty)
+	}
+	for i := 1; i < 7; i++ {
+		store.Add(i, 100)
+	}
+	if grown := store.GetMemoryUsageBytes(); grown != empty+144 {
+		t.Errorf("7 bins should use 2 buckets of 144 bytes, got %d bytes, empty %d", grown, empty)
+	}
+	
+	// Maps keep their buckets when bins are removed, until cleared
+	for i := 7; i < 900; i++ {
+		store.Add(i, 100)
+	}
+	peak := store.GetMemoryUsageBytes()
+	
+	collapsing := NewSparseStore(1000)
+	collapsing.Merge(store)
+	collapsing.Add(-1, 1)
+	collapsing.Add(2000, 1)
+	for i := 0; i < 200; i++ {
+		collapsing.Add(3000+i, 1)
+	}
+	if len(collapsing.GetNonEmptyBuckets()) >= 900 {
+		t.Fatalf("Expected low-count bins to be collapsed, got %d bins", len(collapsing.GetNonEmptyBuckets()))
+	}
+	if collapsing.GetMemoryUsageBytes() < peak {
+		t.Errorf("Collapsing bins should not lower the estimate, got %d bytes, peak %d", collapsing.GetMemoryUsageBytes(), peak)
+	}
+	
+	collapsing.Clear()
+	if collapsing.GetMemoryUsageBytes() != empty {
+		t.Errorf("Clear should release the buckets, got %d bytes, empty %d", collapsing.GetMemoryUsageBytes(), empty)
+	}
+}
+
+func TestDenseStore_MemoryUsageCountsCapacity(t *testing.T) {
+	store := NewDenseStore(1000, 0)
+	store.Add(0, 1)
+	
+	// The whole allocated array counts, not just the bins in use
+	if usage := store.GetMemoryUsageBytes(); usage < 1000*8 {
+		t.Errorf("Expected at least 8000 bytes for 1000 allocated bins, got %d", usage)
+	}
+}
+
+// BenchmarkStore_MemoryUsageAccuracy compares the memory usage estimate of
+// stores holding 100k buckets against the heap growth they cause
+func BenchmarkStore_MemoryUsageAccuracy(b *testing.B) {
+	const buckets = 100000
+	
+	source := NewDenseStore(buckets, 0)
+	for i := 0; i < buckets; i++ {
+		source.Add(i, 100)
+	}
+	
+	stores := map[string]func() Store{
+		"sparse": func() Store { return NewSparseStore(10) },
+		"dense":  func() Store { return NewDenseStore(1, 0) },
+	}
+	
+	for name, newStore := range stores {
+		b.Run(name, func(b *testing.B) {
+			var estimated, actual float64
+			for i := 0; i < b.N; i++ {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				
+				store := newStore()
+				store.Merge(source)
+				
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				
+				estimated += float64(store.GetMemoryUsageBytes())
+				actual += float64(after.HeapAlloc) - float64(before.HeapAlloc)
+				runtime.KeepAlive(store)
+			}
+			
+			b.ReportMetric(estimated/float64(b.N), "estimated-bytes")
+			b.ReportMetric(actual/float64(b.N), "heap-bytes")
+			b.ReportMetric(estimated/actual, "estimate-ratio")
+		})
+	}
+}
+
 func BenchmarkSparseStore_Add(b *testing.B) {
 	store := NewSparseStore(10)
 	
@@ -599,7 +925,7 @@ func BenchmarkSparseStore_Get(b *testing.B) {
 }
 
 func BenchmarkDenseStore_Add(b *testing.B) {
-	store := NewDenseStore(1000)
+	store := NewDenseStore(1000, 0)
 	
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -609,7 +935,7 @@ func BenchmarkDenseStore_Add(b *testing.B) {
 }
 
 func BenchmarkDenseStore_Get(b *testing.B) {
-	store := NewDenseStore(1000)
+	store := NewDenseStore(1000, 0)
 	
 	// Populate store
 	for i := 0; i < 1000; i++ {
@@ -0,0 +1,191 @@
+package sketch
+
+import (
+	"sync"
+	"time"
+)
+
+// WindowedSketch provides rolling percentiles over a time window.
+// The window is split into sub-windows, each backed by its own DDSketch held
+// in a ring buffer. Values are added to the current sub-window and the oldest
+// sub-window is cleared on rotation, so old data ages out of queries.
+type WindowedSketch struct {
+	config         DDSketchConfig   // Configuration of each sub-window sketch
+	windows        []*DDSketch      // Ring buffer of sub-window sketches
+	current        int              // Index of the current sub-window
+	bucketDuration time.Duration    // Time span of a single sub-window
+	lastRotation   time.Time        // Time the current sub-window was started
+	now            func() time.Time // Clock driving rotation
+
+	mutex sync.Mutex
+}
+
+// NewWindowedSketch creates a new WindowedSketch with the given configuration.
+// Rotation is driven off the provided clock, which defaults to time.Now when nil
+func NewWindowedSketch(config DDSketchConfig, window WindowConfig, now func() time.Time) *WindowedSketch {
+	if now == nil {
+		now = time.Now
+	}
+
+	buckets := window.Buckets
+	if buckets <= 0 {
+		buckets = 1
+	}
+
+	windows := make([]*DDSketch, buckets)
+	for i := range windows {
+		windows[i] = NewDDSketch(config)
+	}
+
+	return &WindowedSketch{
+		config:         config,
+		windows:        windows,
+		current:        0,
+		bucketDuration: window.WindowDuration / time.Duration(buckets),
+		lastRotation:   now(),
+		now:            now,
+	}
+}
+
+// Add adds a value to the current sub-window
+func (w *WindowedSketch) Add(value float64) error {
+	return w.AddWithCount(value, 1)
+}
+
+// AddWithCount adds a value with a specific count to the current sub-window
+func (w *WindowedSketch) AddWithCount(value float64, count uint64) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.advance()
+	return w.windows[w.current].AddWithCount(value, count)
+}
+
+// Merge merges another sketch, e.g. the values of one scan, into the current
+// sub-window. It fails if the sketch does not have the same gamma
+func (w *WindowedSketch) Merge(other Sketch) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.advance()
+	return w.windows[w.current].Merge(other)
+}
+
+// Rotate starts a new sub-window immediately, clearing the oldest one
+func (w *WindowedSketch) Rotate() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.rotate()
+	w.lastRotation = w.now()
+}
+
+// GetValueAtQuantile returns the value at the specified quantile over the window
+func (w *WindowedSketch) GetValueAtQuantile(q float64) (float64, error) {
+	return w.Snapshot().GetValueAtQuantile(q)
+}
+
+// GetQuantileAtValue returns the quantile at which value falls over the window
+func (w *WindowedSketch) GetQuantileAtValue(value float64) (float64, error) {
+	return w.Snapshot().GetQuantileAtValue(value)
+}
+
+// GetCount returns the count of values in the window
+func (w *WindowedSketch) GetCount() uint64 {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.advance()
+
+	var count uint64
+	for _, window := range w.windows {
+		count += window.GetCount()
+	}
+	return count
+}
+
+// Snapshot returns a new DDSketch merging all live sub-windows
+func (w *WindowedSketch) Snapshot() *DDSketch {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.advance()
+
+	merged := NewDDSketch(w.config)
+	for _, window := range w.windows {
+		if window.GetCount() == 0 {
+			continue
+		}
+		// Sub-windows share the same configuration so merge cannot fail
+		_ = merged.Merge(window)
+	}
+	return merged
+}
+
+// SnapshotLast returns a new DDSketch merging the sub-windows covering the
+// last d, at least the current one and at most the whole window
+func (w *WindowedSketch) SnapshotLast(d time.Duration) *DDSketch {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.advance()
+
+	n := len(w.windows)
+	if w.bucketDuration > 0 {
+		n = min(n, max(1, int((d+w.bucketDuration-1)/w.bucketDuration)))
+	}
+
+	merged := NewDDSketch(w.config)
+	for i := 0; i < n; i++ {
+		window := w.windows[(w.current-i+len(w.windows))%len(w.windows)]
+		if window.GetCount() == 0 {
+			continue
+		}
+		// Sub-windows share the same configuration so merge cannot fail
+		_ = merged.Merge(window)
+	}
+	return merged
+}
+
+// Reset clears all sub-windows
+func (w *WindowedSketch) Reset() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for _, window := range w.windows {
+		window.Reset()
+	}
+	w.current = 0
+	w.lastRotation = w.now()
+}
+
+// advance rotates past every sub-window that has elapsed since the last
+// rotation. Caller must hold the mutex
+func (w *WindowedSketch) advance() {
+	if w.bucketDuration <= 0 {
+		return
+	}
+
+	elapsed := w.now().Sub(w.lastRotation)
+	if elapsed < w.bucketDuration {
+		return
+	}
+
+	steps := int(elapsed / w.bucketDuration)
+	if steps > len(w.windows) {
+		// Whole window has expired, no need to rotate more than once per sub-window
+		steps = len(w.windows)
+	}
+	for i := 0; i < steps; i++ {
+		w.rotate()
+	}
+
+	// Keep sub-window boundaries aligned to the rotation schedule
+	w.lastRotation = w.lastRotation.Add(elapsed - elapsed%w.bucketDuration)
+}
+
+// rotate moves to the next sub-window and clears it. Caller must hold the mutex
+func (w *WindowedSketch) rotate() {
+	w.current = (w.current + 1) % len(w.windows)
+	w.windows[w.current].Reset()
+}
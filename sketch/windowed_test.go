@@ -0,0 +1,133 @@
+package sketch
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock for deterministic rotation
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestWindowedSketch_Rotate(t *testing.T) {
+	config := DefaultConfig()
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	sketch := NewWindowedSketch(config.DDSketch, WindowConfig{WindowDuration: time.Minute, Buckets: 3}, clock.Now)
+
+	// Empty window
+	if _, err := sketch.GetValueAtQuantile(0.99); err != ErrEmptySketch {
+		t.Errorf("Expected ErrEmptySketch, got %v", err)
+	}
+
+	// A spike in the first sub-window
+	sketch.Add(1000)
+	for i := 0; i < 99; i++ {
+		sketch.Add(10)
+	}
+
+	p99, _ := sketch.GetValueAtQuantile(0.995)
+	if p99 < 900 {
+		t.Errorf("Expected spike to be visible, p99.5 = %f", p99)
+	}
+
+	// Still visible while the sub-window is live
+	sketch.Rotate()
+	sketch.Rotate()
+	if sketch.GetCount() != 100 {
+		t.Errorf("Expected count 100 after 2 rotations, got %d", sketch.GetCount())
+	}
+
+	// Third rotation clears the oldest sub-window
+	sketch.Add(10)
+	sketch.Rotate()
+	if sketch.GetCount() != 1 {
+		t.Errorf("Expected count 1 after spike aged out, got %d", sketch.GetCount())
+	}
+
+	max, _ := sketch.GetValueAtQuantile(1)
+	if max > 11 {
+		t.Errorf("Expected spike to be gone, max = %f", max)
+	}
+}
+
+func TestWindowedSketch_Clock(t *testing.T) {
+	config := DefaultConfig()
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	sketch := NewWindowedSketch(config.DDSketch, WindowConfig{WindowDuration: time.Minute, Buckets: 6}, clock.Now)
+
+	sketch.Add(100)
+
+	// Within the window
+	clock.Advance(45 * time.Second)
+	sketch.Add(200)
+	if sketch.GetCount() != 2 {
+		t.Errorf("Expected count 2, got %d", sketch.GetCount())
+	}
+
+	// First value ages out once its sub-window is recycled
+	clock.Advance(15 * time.Second)
+	if sketch.GetCount() != 1 {
+		t.Errorf("Expected count 1, got %d", sketch.GetCount())
+	}
+
+	// Everything ages out after a full window of inactivity
+	clock.Advance(10 * time.Minute)
+	if sketch.GetCount() != 0 {
+		t.Errorf("Expected count 0, got %d", sketch.GetCount())
+	}
+
+	// Sketch keeps working after a long gap
+	sketch.Add(300)
+	value, err := sketch.GetValueAtQuantile(0.5)
+	if err != nil {
+		t.Fatalf("GetValueAtQuantile returned error: %v", err)
+	}
+	if value < 297 || value > 303 {
+		t.Errorf("Expected p50 around 300, got %f", value)
+	}
+}
+
+func TestWindowedSketch_Merge(t *testing.T) {
+	config := DefaultConfig()
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	sketch := NewWindowedSketch(config.DDSketch, WindowConfig{WindowDuration: time.Minute, Buckets: 4}, clock.Now)
+
+	// One scan per sub-window, the newest being the busiest
+	for scan := 1; scan <= 4; scan++ {
+		values := NewDDSketch(config.DDSketch)
+		for i := 0; i < 100; i++ {
+			values.Add(float64(scan * 100))
+		}
+		if err := sketch.Merge(values); err != nil {
+			t.Fatalf("Merge failed: %v", err)
+		}
+		clock.Advance(15 * time.Second)
+	}
+
+	// The clock moved into a fifth, empty sub-window
+	if count := sketch.GetCount(); count != 300 {
+		t.Errorf("Expected 300 values in the window, got %d", count)
+	}
+	if count := sketch.SnapshotLast(30 * time.Second).GetCount(); count != 100 {
+		t.Errorf("Expected 100 values in the last 30s, got %d", count)
+	}
+	if count := sketch.SnapshotLast(time.Hour).GetCount(); count != 300 {
+		t.Errorf("Expected the snapshot to be capped to the window, got %d values", count)
+	}
+
+	// Sketches with another gamma cannot be merged
+	other := config.DDSketch
+	other.RelativeAccuracy = 0.05
+	if err := sketch.Merge(NewDDSketch(other)); err == nil {
+		t.Errorf("Expected an error merging a sketch with another gamma")
+	}
+}
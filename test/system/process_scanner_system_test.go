@@ -302,7 +302,7 @@ func TestSystem_ProcessScanner_RealProcesses(t *testing.T) {
 	consumer := newRecordingConsumer()
 	
 	// Register consumer
-	err = scanner.RegisterConsumer("test-consumer", consumer)
+	err = scanner.RegisterConsumer("test-consumer", collector.AdaptLegacyConsumer(consumer))
 	require.NoError(t, err)
 	
 	// Start scanner
@@ -425,7 +425,7 @@ func TestSystem_ProcessScanner_HighLoad(t *testing.T) {
 	consumer := newMetricsConsumer()
 	
 	// Register consumer
-	err = scanner.RegisterConsumer("metrics-consumer", consumer)
+	err = scanner.RegisterConsumer("metrics-consumer", collector.AdaptLegacyConsumer(consumer))
 	require.NoError(t, err)
 	
 	// Start scanner
@@ -551,7 +551,7 @@ func TestSystem_ProcessScanner_GGoalValidation(t *testing.T) {
 	consumer := newMetricsConsumer()
 	
 	// Register consumer
-	err = scanner.RegisterConsumer("metrics-consumer", consumer)
+	err = scanner.RegisterConsumer("metrics-consumer", collector.AdaptLegacyConsumer(consumer))
 	require.NoError(t, err)
 	
 	// Start scanner
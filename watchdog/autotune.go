@@ -0,0 +1,113 @@
+package watchdog
+
+import (
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/sketch"
+)
+
+// EffectiveThresholds are the CPU and memory thresholds a component is
+// checked against, either its static thresholds or the auto-tuned ones
+type EffectiveThresholds struct {
+	// MaxCPUPercent is the CPU threshold in use
+	MaxCPUPercent float64
+
+	// MaxMemoryMB is the memory threshold in use
+	MaxMemoryMB float64
+
+	// Tuned indicates the thresholds were computed from the usage history
+	Tuned bool
+
+	// UpdatedAt is when the thresholds were last computed, zero if not tuned
+	UpdatedAt time.Time
+}
+
+// componentDistribution is the usage distribution of one component
+type componentDistribution struct {
+	cpu        *sketch.DDSketch
+	memoryMB   *sketch.DDSketch
+	samples    int
+	thresholds EffectiveThresholds
+}
+
+// autoTuner derives thresholds from the percentiles of the usage of each
+// component. It is not safe for concurrent use, the ResourceMonitor guards it
+type autoTuner struct {
+	config        AutoTuneConfig
+	distributions map[string]*componentDistribution
+}
+
+// newAutoTuner creates an auto-tuner with the given configuration
+func newAutoTuner(config AutoTuneConfig) *autoTuner {
+	return &autoTuner{
+		config:        config,
+		distributions: make(map[string]*componentDistribution),
+	}
+}
+
+// observe adds a usage sample of a component, and recomputes its thresholds
+// if the warmup is over and they are older than UpdateInterval. The static
+// thresholds of the component are the ceiling of the tuned ones
+func (t *autoTuner) observe(componentName string, usage ResourceUsage, ceiling ComponentConfig, now time.Time) {
+	distribution, ok := t.distributions[componentName]
+	if !ok {
+		// Idle components use 0% CPU, which needs the zero bucket
+		sketchConfig := sketch.DefaultConfig().DDSketch
+		sketchConfig.AllowNegative = true
+
+		distribution = &componentDistribution{
+			cpu:      sketch.NewDDSketch(sketchConfig),
+			memoryMB: sketch.NewDDSketch(sketchConfig),
+		}
+		t.distributions[componentName] = distribution
+	}
+
+	if distribution.cpu.Add(usage.CPUPercent) != nil || distribution.memoryMB.Add(usage.MemoryMB()) != nil {
+		return
+	}
+	distribution.samples++
+
+	if distribution.samples < t.config.WarmupSamples {
+		return
+	}
+	if distribution.thresholds.Tuned && now.Sub(distribution.thresholds.UpdatedAt) < t.config.UpdateInterval {
+		return
+	}
+
+	cpu, err := distribution.cpu.GetValueAtQuantile(t.config.Percentile)
+	if err != nil {
+		return
+	}
+	memoryMB, err := distribution.memoryMB.GetValueAtQuantile(t.config.Percentile)
+	if err != nil {
+		return
+	}
+
+	margin := 1 + t.config.MarginPercent/100
+	distribution.thresholds = EffectiveThresholds{
+		MaxCPUPercent: min(cpu*margin, ceiling.MaxCPUPercent),
+		MaxMemoryMB:   min(memoryMB*margin, float64(ceiling.MaxMemoryMB)),
+		Tuned:         true,
+		UpdatedAt:     now,
+	}
+}
+
+// thresholds returns the thresholds of a component, its static thresholds
+// until they are tuned
+func (t *autoTuner) thresholds(componentName string, static ComponentConfig) EffectiveThresholds {
+	if t != nil {
+		if distribution, ok := t.distributions[componentName]; ok && distribution.thresholds.Tuned {
+			return distribution.thresholds
+		}
+	}
+
+	return EffectiveThresholds{
+		MaxCPUPercent: static.MaxCPUPercent,
+		MaxMemoryMB:   float64(static.MaxMemoryMB),
+	}
+}
+
+// remove forgets the distribution of a component
+func (t *autoTuner) remove(componentName string) {
+	delete(t.distributions, componentName)
+}
@@ -1,6 +1,7 @@
 package watchdog
 
 import (
+	"math/rand"
 	"sync"
 	"time"
 )
@@ -38,7 +39,8 @@ type CircuitBreakerStatus struct {
 	// State is the current state of the circuit breaker
 	State CircuitState
 	
-	// Failures is the current failure count
+	// Failures is the current failure count, the failures within
+	// FailureWindow as of the last recorded result in window mode
 	Failures int
 	
 	// SuccessesInHalfOpen is the current success count in half-open state
@@ -47,14 +49,37 @@ type CircuitBreakerStatus struct {
 	// LastStateChangeTime is the time of the last state change
 	LastStateChangeTime time.Time
 	
-	// OpenUntil is the time the circuit will remain open (if in open state)
+	// OpenUntil is the time the circuit will remain open (if in open state),
+	// zero if it was forced open without MaxOpenDuration
 	OpenUntil time.Time
+	
+	// Forced indicates the state was set by ForceOpen or ForceClose
+	Forced bool
 }
 
-// StateChangeListener is a function that is called when the circuit state changes
+// StateChangeListener is a function that is called when the circuit state
+// changes. Listeners are called in order, after the breaker is unlocked
 type StateChangeListener func(name string, oldState, newState CircuitState)
 
-// CircuitBreaker implements the circuit breaker pattern
+// stateChange is a transition waiting to be sent to the listeners
+type stateChange struct {
+	oldState CircuitState
+	newState CircuitState
+}
+
+// CircuitBreaker implements the circuit breaker pattern. It opens after
+// FailureThreshold consecutive failures or, if FailureWindow is set, when the
+// results recorded within FailureWindow include at least FailureThreshold
+// failures and their failure rate exceeds FailureRateThreshold, which also
+// catches intermittent failures. Once ResetTimeout elapsed, the next
+// recorded result is a probe that moves it to half-open, where
+// HalfOpenSuccessThreshold consecutive successes close it and any failure
+// opens it again for another ResetTimeout. The ResetTimeout is randomized by
+// ResetJitter and capped at MaxOpenDuration.
+//
+// ForceOpen and ForceClose override the state until Reset, and recorded
+// results are ignored meanwhile. A forced open circuit still moves to
+// half-open after MaxOpenDuration, if set
 type CircuitBreaker struct {
 	name                  string
 	config                CircuitBreakerConfig
@@ -63,20 +88,40 @@ type CircuitBreaker struct {
 	successesInHalfOpen   int
 	lastStateChangeTime   time.Time
 	openUntil             time.Time
+	forced                bool // State set by ForceOpen or ForceClose
+	window                failureWindow // Results recorded while closed, in window mode
 	listeners             []StateChangeListener
+	pending               []stateChange // Transitions to notify on unlock
+	now                   func() time.Time
+	rng                   *rand.Rand // Draws the reset timeout jitter, guarded by mu
 	mu                    sync.RWMutex
 }
 
 // NewCircuitBreaker creates a new circuit breaker with the given configuration
 func NewCircuitBreaker(name string, config CircuitBreakerConfig) *CircuitBreaker {
+	return NewCircuitBreakerWithClock(name, config, time.Now)
+}
+
+// NewCircuitBreakerWithClock creates a new circuit breaker that reads the
+// time from now, e.g. to test the reset timeout without sleeping
+func NewCircuitBreakerWithClock(name string, config CircuitBreakerConfig, now func() time.Time) *CircuitBreaker {
+	return NewCircuitBreakerWithClockAndRand(name, config, now, rand.New(rand.NewSource(time.Now().UnixNano())))
+}
+
+// NewCircuitBreakerWithClockAndRand creates a new circuit breaker that reads
+// the time from now and draws the reset timeout jitter from rng, e.g. a fixed
+// seed for deterministic timeouts
+func NewCircuitBreakerWithClockAndRand(name string, config CircuitBreakerConfig, now func() time.Time, rng *rand.Rand) *CircuitBreaker {
 	return &CircuitBreaker{
 		name:                name,
 		config:              config,
 		state:               CircuitClosed,
 		failures:            0,
 		successesInHalfOpen: 0,
-		lastStateChangeTime: time.Now(),
+		lastStateChangeTime: now(),
 		listeners:           make([]StateChangeListener, 0),
+		now:                 now,
+		rng:                 rng,
 	}
 }
 
@@ -93,31 +138,28 @@ func (cb *CircuitBreaker) Name() string {
 	return cb.name
 }
 
-// AllowOperation returns true if the operation is allowed
+// AllowOperation returns true if the operation is allowed, which is the
+// case unless the circuit is open and ResetTimeout has not elapsed
 func (cb *CircuitBreaker) AllowOperation() bool {
-	if !cb.config.Enabled {
-		return true
-	}
-
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
+	defer cb.unlockAndNotify()
 
-	switch cb.state {
-	case CircuitClosed:
-		return true
-	case CircuitOpen:
-		now := time.Now()
-		if now.After(cb.openUntil) {
-			cb.toHalfOpen()
-			return true
-		}
-		return false
-	case CircuitHalfOpen:
-		// In half-open state, we only allow one request at a time
-		return cb.successesInHalfOpen == 0
-	default:
+	if !cb.config.Enabled && !cb.forced {
 		return true
 	}
+
+	cb.probeIfExpired()
+	return cb.state != CircuitOpen
+}
+
+// State returns the current state of the circuit breaker. An open circuit
+// whose ResetTimeout elapsed is reported as half-open, as the next recorded
+// result is a probe
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	return cb.currentState()
 }
 
 // RecordSuccess records a successful operation
@@ -127,11 +169,19 @@ func (cb *CircuitBreaker) RecordSuccess() {
 	}
 
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
+	defer cb.unlockAndNotify()
 
+	cb.probeIfExpired()
+	if cb.forced {
+		return
+	}
 	switch cb.state {
 	case CircuitClosed:
-		cb.failures = 0
+		if cb.config.FailureWindow > 0 {
+			cb.recordInWindow(false)
+		} else {
+			cb.failures = 0
+		}
 	case CircuitHalfOpen:
 		cb.successesInHalfOpen++
 		if cb.successesInHalfOpen >= cb.config.HalfOpenSuccessThreshold {
@@ -147,10 +197,22 @@ func (cb *CircuitBreaker) RecordFailure() {
 	}
 
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
+	defer cb.unlockAndNotify()
 
+	cb.probeIfExpired()
+	if cb.forced {
+		return
+	}
 	switch cb.state {
 	case CircuitClosed:
+		if cb.config.FailureWindow > 0 {
+			cb.recordInWindow(true)
+			if cb.failures >= cb.config.FailureThreshold && cb.window.failureRate() > cb.config.FailureRateThreshold {
+				cb.toOpen()
+			}
+			return
+		}
+		
 		cb.failures++
 		if cb.failures >= cb.config.FailureThreshold {
 			cb.toOpen()
@@ -160,35 +222,114 @@ func (cb *CircuitBreaker) RecordFailure() {
 	}
 }
 
+// recordInWindow adds a result to the failure window, drops the results
+// older than FailureWindow and updates the failure count. Caller must hold mu
+func (cb *CircuitBreaker) recordInWindow(failed bool) {
+	now := cb.now()
+	cb.window.add(windowRecord{at: now, failed: failed})
+	cb.window.expire(now.Add(-cb.config.FailureWindow))
+	cb.failures = cb.window.failures
+}
+
 // Status returns the current status of the circuit breaker
 func (cb *CircuitBreaker) Status() CircuitBreakerStatus {
 	cb.mu.RLock()
 	defer cb.mu.RUnlock()
 	
 	return CircuitBreakerStatus{
-		State:               cb.state,
+		State:               cb.currentState(),
 		Failures:            cb.failures,
 		SuccessesInHalfOpen: cb.successesInHalfOpen,
 		LastStateChangeTime: cb.lastStateChangeTime,
 		OpenUntil:           cb.openUntil,
+		Forced:              cb.forced,
 	}
 }
 
-// Reset resets the circuit breaker to its initial state
+// Reset resets the circuit breaker to its initial state, clearing a forced state
 func (cb *CircuitBreaker) Reset() {
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
+	defer cb.unlockAndNotify()
 	
+	cb.forced = false
 	cb.toClosed()
 }
 
+// ForceOpen opens the circuit until Reset or ForceClose, or until
+// MaxOpenDuration elapsed if set
+func (cb *CircuitBreaker) ForceOpen() {
+	cb.mu.Lock()
+	defer cb.unlockAndNotify()
+	
+	cb.toOpen()
+	cb.forced = true
+	cb.openUntil = time.Time{}
+	if cb.config.MaxOpenDuration > 0 {
+		cb.openUntil = cb.now().Add(cb.config.MaxOpenDuration)
+	}
+}
+
+// ForceClose closes the circuit until Reset or ForceOpen
+func (cb *CircuitBreaker) ForceClose() {
+	cb.mu.Lock()
+	defer cb.unlockAndNotify()
+	
+	cb.toClosed()
+	cb.forced = true
+}
+
+// currentState returns the state, reporting an expired open circuit as
+// half-open. Caller must hold mu
+func (cb *CircuitBreaker) currentState() CircuitState {
+	if cb.openExpired() {
+		return CircuitHalfOpen
+	}
+	return cb.state
+}
+
+// probeIfExpired moves an open circuit to half-open once ResetTimeout
+// elapsed, which also ends a forced open. Caller must hold mu
+func (cb *CircuitBreaker) probeIfExpired() {
+	if cb.openExpired() {
+		cb.forced = false
+		cb.toHalfOpen()
+	}
+}
+
+// openExpired returns whether the circuit is open and its open period is
+// over. A circuit forced open without MaxOpenDuration never expires. Caller
+// must hold mu
+func (cb *CircuitBreaker) openExpired() bool {
+	if cb.state != CircuitOpen || (cb.forced && cb.openUntil.IsZero()) {
+		return false
+	}
+	return !cb.now().Before(cb.openUntil)
+}
+
+// openDuration returns ResetTimeout randomized by up to ResetJitter in either
+// direction, so circuits opened together do not probe together, and capped
+// at MaxOpenDuration. Caller must hold mu
+func (cb *CircuitBreaker) openDuration() time.Duration {
+	duration := cb.config.ResetTimeout
+	if cb.config.ResetJitter > 0 {
+		offset := cb.config.ResetJitter * (2*cb.rng.Float64() - 1)
+		duration = time.Duration(float64(duration) * (1 + offset))
+	}
+	
+	if cb.config.MaxOpenDuration > 0 && duration > cb.config.MaxOpenDuration {
+		duration = cb.config.MaxOpenDuration
+	}
+	return duration
+}
+
 // toOpen transitions the circuit breaker to the open state
 func (cb *CircuitBreaker) toOpen() {
 	oldState := cb.state
 	if cb.state != CircuitOpen {
 		cb.state = CircuitOpen
-		cb.openUntil = time.Now().Add(cb.config.ResetTimeout)
-		cb.lastStateChangeTime = time.Now()
+		cb.lastStateChangeTime = cb.now()
+		cb.openUntil = cb.lastStateChangeTime.Add(cb.openDuration())
+		cb.window.clear()
 		cb.notifyStateChange(oldState, CircuitOpen)
 	}
 }
@@ -199,7 +340,7 @@ func (cb *CircuitBreaker) toHalfOpen() {
 	if cb.state != CircuitHalfOpen {
 		cb.state = CircuitHalfOpen
 		cb.successesInHalfOpen = 0
-		cb.lastStateChangeTime = time.Now()
+		cb.lastStateChangeTime = cb.now()
 		cb.notifyStateChange(oldState, CircuitHalfOpen)
 	}
 }
@@ -211,14 +352,93 @@ func (cb *CircuitBreaker) toClosed() {
 		cb.state = CircuitClosed
 		cb.failures = 0
 		cb.successesInHalfOpen = 0
-		cb.lastStateChangeTime = time.Now()
+		cb.lastStateChangeTime = cb.now()
 		cb.notifyStateChange(oldState, CircuitClosed)
 	}
 }
 
-// notifyStateChange notifies all listeners of a state change
+// notifyStateChange queues a state change for the listeners. Caller must
+// hold mu and release it with unlockAndNotify
 func (cb *CircuitBreaker) notifyStateChange(oldState, newState CircuitState) {
-	for _, listener := range cb.listeners {
-		go listener(cb.name, oldState, newState)
+	cb.pending = append(cb.pending, stateChange{oldState: oldState, newState: newState})
+}
+
+// unlockAndNotify releases mu and calls the listeners with the queued state
+// changes, so listeners may use the circuit breaker
+func (cb *CircuitBreaker) unlockAndNotify() {
+	pending := cb.pending
+	cb.pending = nil
+	listeners := cb.listeners
+	cb.mu.Unlock()
+
+	for _, change := range pending {
+		for _, listener := range listeners {
+			listener(cb.name, change.oldState, change.newState)
+		}
 	}
 }
+
+// maxWindowRecords bounds the results kept in a failure window, dropping the
+// oldest ones first
+const maxWindowRecords = 1024
+
+// windowRecord is a result recorded in a failure window
+type windowRecord struct {
+	at     time.Time
+	failed bool
+}
+
+// failureWindow is a ring of the most recent results, oldest first
+type failureWindow struct {
+	records  []windowRecord
+	start    int // Index of the oldest record
+	count    int
+	failures int
+}
+
+// add appends a result, replacing the oldest one if the ring is full
+func (w *failureWindow) add(record windowRecord) {
+	if w.records == nil {
+		w.records = make([]windowRecord, maxWindowRecords)
+	}
+	if w.count == len(w.records) {
+		w.dropOldest()
+	}
+	
+	w.records[(w.start+w.count)%len(w.records)] = record
+	w.count++
+	if record.failed {
+		w.failures++
+	}
+}
+
+// expire drops the results recorded at or before cutoff
+func (w *failureWindow) expire(cutoff time.Time) {
+	for w.count > 0 && !w.records[w.start].at.After(cutoff) {
+		w.dropOldest()
+	}
+}
+
+// dropOldest removes the oldest result, the ring must not be empty
+func (w *failureWindow) dropOldest() {
+	if w.records[w.start].failed {
+		w.failures--
+	}
+	w.start = (w.start + 1) % len(w.records)
+	w.count--
+}
+
+// failureRate returns the fraction of failed results, 0 if there are none
+func (w *failureWindow) failureRate() float64 {
+	if w.count == 0 {
+		return 0
+	}
+	return float64(w.failures) / float64(w.count)
+}
+
+// clear removes all results
+func (w *failureWindow) clear() {
+	w.start = 0
+	w.count = 0
+	w.failures = 0
+}
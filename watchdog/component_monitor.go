@@ -22,27 +22,6 @@ const (
 	ResourceIO ResourceType = "IO"
 )
 
-// ResourceUsage represents the resource usage of a component
-type ResourceUsage struct {
-	// CPU is the CPU usage percentage
-	CPU float64
-	
-	// Memory is the memory usage in MB
-	Memory float64
-	
-	// Threads is the thread count
-	Threads int
-	
-	// IOReadBytes is the bytes read
-	IOReadBytes int64
-	
-	// IOWriteBytes is the bytes written
-	IOWriteBytes int64
-	
-	// Timestamp is when the resource usage was collected
-	Timestamp time.Time
-}
-
 // ResourceSample represents a sample of resource usage over time
 type ResourceSample struct {
 	// Usage is the resource usage
@@ -163,14 +142,14 @@ func (cm *ComponentMonitor) GetAverageUsage(duration time.Duration) ResourceUsag
 	startTime := now.Add(-duration)
 	
 	var totalCPU, totalMemory float64
-	var totalThreads int
+	var totalGoroutines int
 	var totalIORead, totalIOWrite int64
 	var totalDuration time.Duration
 	
 	// Start with current usage
-	totalCPU = cm.CurrentUsage.CPU
-	totalMemory = cm.CurrentUsage.Memory
-	totalThreads = cm.CurrentUsage.Threads
+	totalCPU = cm.CurrentUsage.CPUPercent
+	totalMemory = float64(cm.CurrentUsage.MemoryBytes)
+	totalGoroutines = cm.CurrentUsage.Goroutines
 	totalIORead = cm.CurrentUsage.IOReadBytes
 	totalIOWrite = cm.CurrentUsage.IOWriteBytes
 	
@@ -214,9 +193,9 @@ func (cm *ComponentMonitor) GetAverageUsage(duration time.Duration) ResourceUsag
 		}
 		
 		// Add weighted sample to totals
-		totalCPU += sample.Usage.CPU * float64(sampleDuration) / float64(duration)
-		totalMemory += sample.Usage.Memory * float64(sampleDuration) / float64(duration)
-		totalThreads += sample.Usage.Threads * int(sampleDuration) / int(duration)
+		totalCPU += sample.Usage.CPUPercent * float64(sampleDuration) / float64(duration)
+		totalMemory += float64(sample.Usage.MemoryBytes) * float64(sampleDuration) / float64(duration)
+		totalGoroutines += sample.Usage.Goroutines * int(sampleDuration) / int(duration)
 		totalIORead += sample.Usage.IOReadBytes * int64(sampleDuration) / int64(duration)
 		totalIOWrite += sample.Usage.IOWriteBytes * int64(sampleDuration) / int64(duration)
 		
@@ -233,15 +212,15 @@ func (cm *ComponentMonitor) GetAverageUsage(duration time.Duration) ResourceUsag
 		weightFactor := float64(duration) / float64(totalDuration)
 		totalCPU *= weightFactor
 		totalMemory *= weightFactor
-		totalThreads = int(float64(totalThreads) * weightFactor)
+		totalGoroutines = int(float64(totalGoroutines) * weightFactor)
 		totalIORead = int64(float64(totalIORead) * weightFactor)
 		totalIOWrite = int64(float64(totalIOWrite) * weightFactor)
 	}
 	
 	return ResourceUsage{
-		CPU:          totalCPU,
-		Memory:       totalMemory,
-		Threads:      totalThreads,
+		CPUPercent:   totalCPU,
+		MemoryBytes:  uint64(totalMemory),
+		Goroutines:   totalGoroutines,
 		IOReadBytes:  totalIORead,
 		IOWriteBytes: totalIOWrite,
 		Timestamp:    now,
@@ -253,7 +232,7 @@ func (cm *ComponentMonitor) checkThresholds() {
 	now := time.Now()
 	
 	// Check CPU threshold
-	if cm.CurrentUsage.CPU > cm.Thresholds[ResourceCPU] {
+	if cm.CurrentUsage.CPUPercent > cm.Thresholds[ResourceCPU] {
 		lastViolation, exists := cm.LastThresholdViolationTime[ResourceCPU]
 		if !exists {
 			cm.LastThresholdViolationTime[ResourceCPU] = now
@@ -267,7 +246,7 @@ func (cm *ComponentMonitor) checkThresholds() {
 	}
 	
 	// Check Memory threshold
-	if cm.CurrentUsage.Memory > cm.Thresholds[ResourceMemory] {
+	if cm.CurrentUsage.MemoryMB() > cm.Thresholds[ResourceMemory] {
 		lastViolation, exists := cm.LastThresholdViolationTime[ResourceMemory]
 		if !exists {
 			cm.LastThresholdViolationTime[ResourceMemory] = now
@@ -29,14 +29,32 @@ type CircuitBreakerConfig struct {
 	// Enabled indicates whether the circuit breaker is enabled
 	Enabled bool `yaml:"enabled"`
 	
-	// FailureThreshold is the number of consecutive failures before opening the circuit
+	// FailureThreshold is the number of consecutive failures before opening the
+	// circuit, or the minimum number of failures within FailureWindow
 	FailureThreshold int `yaml:"failure_threshold"`
 	
+	// FailureWindow enables the window mode, where the circuit opens when the
+	// failure rate of the results within this window exceeds
+	// FailureRateThreshold. 0 counts consecutive failures instead
+	FailureWindow time.Duration `yaml:"failure_window"`
+	
+	// FailureRateThreshold is the failure rate, 0-1, the results within
+	// FailureWindow must exceed to open the circuit
+	FailureRateThreshold float64 `yaml:"failure_rate_threshold"`
+	
 	// ResetTimeout is the time to wait before attempting to close the circuit
 	ResetTimeout time.Duration `yaml:"reset_timeout"`
 	
 	// HalfOpenSuccessThreshold is the number of consecutive successes in half-open state before closing the circuit
 	HalfOpenSuccessThreshold int `yaml:"half_open_success_threshold"`
+	
+	// ResetJitter randomizes each reset timeout by up to this fraction in
+	// either direction, 0-1, so circuits opened together are not probed together
+	ResetJitter float64 `yaml:"reset_jitter"`
+	
+	// MaxOpenDuration is the longest the circuit stays open, even when forced
+	// open or jitter extends the reset timeout, 0 disables it
+	MaxOpenDuration time.Duration `yaml:"max_open_duration"`
 }
 
 // DeadlockConfig holds configuration for deadlock detection
@@ -53,7 +71,14 @@ type DeadlockConfig struct {
 	// StackTraceEnabled indicates whether to capture stack traces on suspected deadlocks
 	StackTraceEnabled bool `yaml:"stack_trace_enabled"`
 	
-	// MaxOperationTime is the maximum allowed time for operations
+	// MaxStackTraceBytes trims captured stack traces to this size, 1MB when 0
+	MaxStackTraceBytes int `yaml:"max_stack_trace_bytes"`
+	
+	// CheckInterval is how often heartbeats are checked, HeartbeatInterval when 0
+	CheckInterval time.Duration `yaml:"check_interval"`
+	
+	// MaxOperationTime is how long an operation started with TrackOperation may run
+	// before it is reported as a deadlock
 	MaxOperationTime time.Duration `yaml:"max_operation_time"`
 }
 
@@ -76,6 +101,10 @@ type RestartConfig struct {
 	
 	// RestartBackoffFactor is the factor by which backoff increases
 	RestartBackoffFactor float64 `yaml:"restart_backoff_factor"`
+	
+	// BackoffJitter randomizes each backoff by up to this fraction in either
+	// direction, 0-1, so components failing together do not retry in lockstep
+	BackoffJitter float64 `yaml:"backoff_jitter"`
 }
 
 // DiagnosticConfig holds configuration for diagnostic information collection
@@ -93,6 +122,80 @@ type DiagnosticConfig struct {
 	IncludeSystemMetrics bool `yaml:"include_system_metrics"`
 }
 
+// IncidentStoreConfig holds configuration for the persistent incident history
+type IncidentStoreConfig struct {
+	// Enabled indicates whether incidents are written to disk
+	Enabled bool `yaml:"enabled"`
+	
+	// Path is the file incidents are appended to as JSON lines
+	Path string `yaml:"path"`
+	
+	// MaxSizeBytes rotates the file when it would grow beyond this size, 0 disables it
+	MaxSizeBytes int64 `yaml:"max_size_bytes"`
+	
+	// MaxAge rotates the file when its oldest incident is older, 0 disables it
+	MaxAge time.Duration `yaml:"max_age"`
+	
+	// MaxFiles is the number of rotated files to keep
+	MaxFiles int `yaml:"max_files"`
+}
+
+// SelfBudgetConfig holds the resource budget of the resource monitor itself.
+// Its own usage is reported as the SelfComponentName component, and once it
+// exceeds the budget the monitor sheds non-essential work
+type SelfBudgetConfig struct {
+	// Enabled indicates whether the monitor reports and guards its own usage
+	Enabled bool `yaml:"enabled"`
+	
+	// MaxCPUPercent is the maximum CPU percentage spent checking components, 0 disables it
+	MaxCPUPercent float64 `yaml:"max_cpu_percent"`
+	
+	// MaxMemoryMB is the maximum memory held by the usage history in MB, 0 disables it
+	MaxMemoryMB float64 `yaml:"max_memory_mb"`
+	
+	// MaxGoroutines is the maximum number of goroutines of the monitor, 0 disables it
+	MaxGoroutines int `yaml:"max_goroutines"`
+	
+	// GuardMonitoringInterval is the monitoring interval once the budget was
+	// exceeded, 0 keeps the configured interval
+	GuardMonitoringInterval time.Duration `yaml:"guard_monitoring_interval"`
+	
+	// GuardHistoryLen is the number of readings kept per component once the
+	// budget was exceeded
+	GuardHistoryLen int `yaml:"guard_history_len"`
+}
+
+// GlobalBudgetConfig holds the resource budget of all monitored components
+// together. When the total usage exceeds it, the highest consuming degradable
+// components are degraded
+type GlobalBudgetConfig struct {
+	// MaxCPUPercent is the maximum total CPU percentage, 0 disables it
+	MaxCPUPercent float64 `yaml:"max_cpu_percent"`
+	
+	// MaxMemoryMB is the maximum total memory usage in MB, 0 disables it
+	MaxMemoryMB int `yaml:"max_memory_mb"`
+}
+
+// AutoTuneConfig holds configuration for thresholds derived from the usage
+// history of each component. The static CPU and memory thresholds of a
+// component are the ceiling of its tuned thresholds
+type AutoTuneConfig struct {
+	// Enabled indicates whether CPU and memory thresholds are auto-tuned
+	Enabled bool `yaml:"enabled"`
+	
+	// Percentile of the usage the thresholds are based on, e.g. 0.99
+	Percentile float64 `yaml:"percentile"`
+	
+	// MarginPercent is added to the percentile, e.g. 20 sets the thresholds 20% above it
+	MarginPercent float64 `yaml:"margin_percent"`
+	
+	// WarmupSamples is the number of samples before thresholds are tuned
+	WarmupSamples int `yaml:"warmup_samples"`
+	
+	// UpdateInterval is how often the tuned thresholds are recomputed
+	UpdateInterval time.Duration `yaml:"update_interval"`
+}
+
 // ComponentConfig holds configuration for a specific component
 type ComponentConfig struct {
 	// Enabled indicates whether the component is monitored
@@ -110,6 +213,19 @@ type ComponentConfig struct {
 	// MaxGoroutines is the maximum allowed goroutines
 	MaxGoroutines int `yaml:"max_goroutines"`
 	
+	// MaxGCPercent is the maximum percentage of time spent in GC pauses, as
+	// reported by the component, 0 disables the check
+	MaxGCPercent float64 `yaml:"max_gc_percent"`
+	
+	// GoroutineLeakSamples is the number of consecutive history samples in
+	// which the goroutine count must rise to report a leak, 0 disables it. The
+	// monitor keeps the last 20 samples
+	GoroutineLeakSamples int `yaml:"goroutine_leak_samples"`
+	
+	// GoroutineLeakSlope is the average number of goroutines per sample the
+	// count must rise by over GoroutineLeakSamples to report a leak
+	GoroutineLeakSlope float64 `yaml:"goroutine_leak_slope"`
+	
 	// CircuitBreaker contains circuit breaker configuration
 	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker"`
 	
@@ -117,17 +233,82 @@ type ComponentConfig struct {
 	DegradationLevels []DegradationLevel `yaml:"degradation_levels"`
 }
 
+// thresholds returns the resource thresholds of the component
+func (c ComponentConfig) thresholds() ResourceThresholds {
+	return ResourceThresholds{
+		MaxCPUPercent:  c.MaxCPUPercent,
+		MaxMemoryMB:    c.MaxMemoryMB,
+		MaxGoroutines:  c.MaxGoroutines,
+		MaxFileHandles: c.MaxFileDescriptors,
+		MaxGCPercent:   c.MaxGCPercent,
+	}
+}
+
+// DefaultComponentConfig returns the configuration of a component missing
+// from Config.ComponentConfigs. It is monitored against the default resource
+// thresholds, without a circuit breaker or degradation levels, and is not
+// restarted
+func DefaultComponentConfig(name string) ComponentConfig {
+	thresholds := DefaultResourceThresholds()
+	return ComponentConfig{
+		Enabled:            true,
+		MaxCPUPercent:      thresholds.MaxCPUPercent,
+		MaxMemoryMB:        thresholds.MaxMemoryMB,
+		MaxFileDescriptors: thresholds.MaxFileHandles,
+		MaxGoroutines:      thresholds.MaxGoroutines,
+		MaxGCPercent:       thresholds.MaxGCPercent,
+	}
+}
+
 // Config holds the configuration for the watchdog module
 type Config struct {
 	// Enabled indicates whether the watchdog is enabled
 	Enabled bool `yaml:"enabled"`
 	
+	// ObserveOnly detects and reports incidents without restarting or
+	// degrading components, to validate thresholds before enforcing them
+	ObserveOnly bool `yaml:"observe_only"`
+	
 	// MonitoringInterval is how often to check resource usage
 	MonitoringInterval time.Duration `yaml:"monitoring_interval"`
 	
+	// SmoothingAlpha is the weight of the latest sample in the exponential
+	// moving average of CPU and memory the thresholds are checked against,
+	// between 0 and 1. 0 and 1 check the latest sample as is
+	SmoothingAlpha float64 `yaml:"smoothing_alpha"`
+	
+	// ThresholdConsecutiveSamples is the number of consecutive checks a
+	// threshold must be exceeded in before it is reported, 0 or 1 reports
+	// the first one
+	ThresholdConsecutiveSamples int `yaml:"threshold_consecutive_samples"`
+	
+	// ThresholdHandlerMode is how the resource monitor calls threshold handlers, async if empty
+	ThresholdHandlerMode HandlerMode `yaml:"threshold_handler_mode"`
+	
+	// ThresholdHandlerWorkers is the number of goroutines calling threshold handlers in async mode
+	ThresholdHandlerWorkers int `yaml:"threshold_handler_workers"`
+	
 	// ComponentConfigs contains per-component configurations
 	ComponentConfigs map[string]ComponentConfig `yaml:"components"`
 	
+	// GlobalBudget contains the resource budget of all components together
+	GlobalBudget GlobalBudgetConfig `yaml:"global_budget"`
+	
+	// SustainedDegradationDuration is how long a component may stay at the
+	// maximum degradation level before an incident is reported, 0 disables it
+	SustainedDegradationDuration time.Duration `yaml:"sustained_degradation_duration"`
+	
+	// MaxRestartsPerMinute limits the restarts of all components together,
+	// restarts over it are deferred. 0 does not limit restarts
+	MaxRestartsPerMinute int `yaml:"max_restarts_per_minute"`
+	
+	// MeasurementHistoryLen is the number of resource usage measurements kept
+	// in the status of each component, 0 disables the history
+	MeasurementHistoryLen int `yaml:"measurement_history_len"`
+	
+	// AutoTune contains the threshold auto-tuning configuration
+	AutoTune AutoTuneConfig `yaml:"auto_tune"`
+	
 	// DeadlockDetection contains deadlock detection configuration
 	DeadlockDetection DeadlockConfig `yaml:"deadlock_detection"`
 	
@@ -136,13 +317,21 @@ type Config struct {
 	
 	// DiagnosticCollection contains diagnostic collection configuration
 	DiagnosticCollection DiagnosticConfig `yaml:"diagnostic_collection"`
+	
+	// IncidentStore contains the persistent incident history configuration
+	IncidentStore IncidentStoreConfig `yaml:"incident_store"`
+	
+	// SelfBudget contains the resource budget of the monitor itself
+	SelfBudget SelfBudgetConfig `yaml:"self_budget"`
 }
 
 // DefaultConfig returns a new Config with default values
 func DefaultConfig() Config {
 	return Config{
-		Enabled:            true,
-		MonitoringInterval: 15 * time.Second,
+		Enabled:                 true,
+		MonitoringInterval:      15 * time.Second,
+		ThresholdHandlerMode:    HandlerModeAsync,
+		ThresholdHandlerWorkers: 4,
 		ComponentConfigs: map[string]ComponentConfig{
 			"collector": {
 				Enabled:           true,
@@ -261,11 +450,26 @@ func DefaultConfig() Config {
 				},
 			},
 		},
+		GlobalBudget: GlobalBudgetConfig{
+			MaxCPUPercent: 0.75,
+			MaxMemoryMB:   30,
+		},
+		SustainedDegradationDuration: 10 * time.Minute,
+		MaxRestartsPerMinute:         10,
+		MeasurementHistoryLen:        60,
+		AutoTune: AutoTuneConfig{
+			Enabled:        false,
+			Percentile:     0.99,
+			MarginPercent:  20,
+			WarmupSamples:  240,
+			UpdateInterval: 15 * time.Minute,
+		},
 		DeadlockDetection: DeadlockConfig{
 			Enabled:               true,
 			HeartbeatInterval:     5 * time.Second,
 			HeartbeatMissThreshold: 3,
 			StackTraceEnabled:     true,
+			MaxStackTraceBytes:    64 * 1024,
 			MaxOperationTime:      30 * time.Second,
 		},
 		RestartPolicy: RestartConfig{
@@ -282,6 +486,21 @@ func DefaultConfig() Config {
 			IncludeStackTraces:  true,
 			IncludeSystemMetrics: true,
 		},
+		IncidentStore: IncidentStoreConfig{
+			Enabled:      false,
+			Path:         "watchdog-incidents.jsonl",
+			MaxSizeBytes: 10 * 1024 * 1024,
+			MaxAge:       7 * 24 * time.Hour,
+			MaxFiles:     5,
+		},
+		SelfBudget: SelfBudgetConfig{
+			Enabled:                 false,
+			MaxCPUPercent:           1,
+			MaxMemoryMB:             5,
+			MaxGoroutines:           20,
+			GuardMonitoringInterval: 60 * time.Second,
+			GuardHistoryLen:         5,
+		},
 	}
 }
 
@@ -295,6 +514,24 @@ func (c *Config) Validate() error {
 		return errors.New("monitoring interval must be positive")
 	}
 	
+	if c.SmoothingAlpha < 0 || c.SmoothingAlpha > 1 {
+		return fmt.Errorf("invalid smoothing alpha: %f", c.SmoothingAlpha)
+	}
+	
+	if c.ThresholdConsecutiveSamples < 0 {
+		return fmt.Errorf("invalid threshold consecutive samples: %d", c.ThresholdConsecutiveSamples)
+	}
+	
+	if c.ThresholdHandlerMode != "" &&
+		c.ThresholdHandlerMode != HandlerModeAsync &&
+		c.ThresholdHandlerMode != HandlerModeSync {
+		return fmt.Errorf("invalid threshold handler mode: %s", c.ThresholdHandlerMode)
+	}
+	
+	if c.ThresholdHandlerWorkers < 0 {
+		return fmt.Errorf("invalid threshold handler workers: %d", c.ThresholdHandlerWorkers)
+	}
+	
 	if len(c.ComponentConfigs) == 0 {
 		return errors.New("at least one component configuration must be specified")
 	}
@@ -308,6 +545,18 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("invalid max memory MB for component %s: %d", name, config.MaxMemoryMB)
 		}
 		
+		if config.MaxGCPercent < 0 || config.MaxGCPercent > 100 {
+			return fmt.Errorf("invalid max GC percent for component %s: %f", name, config.MaxGCPercent)
+		}
+		
+		if config.GoroutineLeakSamples < 0 || config.GoroutineLeakSamples == 1 {
+			return fmt.Errorf("invalid goroutine leak samples for component %s: %d", name, config.GoroutineLeakSamples)
+		}
+		
+		if config.GoroutineLeakSamples > 0 && config.GoroutineLeakSlope <= 0 {
+			return fmt.Errorf("invalid goroutine leak slope for component %s: %f", name, config.GoroutineLeakSlope)
+		}
+		
 		if config.CircuitBreaker.Enabled {
 			if config.CircuitBreaker.FailureThreshold <= 0 {
 				return fmt.Errorf("invalid failure threshold for component %s: %d", name, config.CircuitBreaker.FailureThreshold)
@@ -320,6 +569,23 @@ func (c *Config) Validate() error {
 			if config.CircuitBreaker.HalfOpenSuccessThreshold <= 0 {
 				return fmt.Errorf("invalid half-open success threshold for component %s: %d", name, config.CircuitBreaker.HalfOpenSuccessThreshold)
 			}
+			
+			if config.CircuitBreaker.FailureWindow < 0 {
+				return fmt.Errorf("invalid failure window for component %s: %v", name, config.CircuitBreaker.FailureWindow)
+			}
+			
+			if config.CircuitBreaker.FailureWindow > 0 &&
+				(config.CircuitBreaker.FailureRateThreshold < 0 || config.CircuitBreaker.FailureRateThreshold >= 1) {
+				return fmt.Errorf("invalid failure rate threshold for component %s: %f", name, config.CircuitBreaker.FailureRateThreshold)
+			}
+			
+			if config.CircuitBreaker.ResetJitter < 0 || config.CircuitBreaker.ResetJitter > 1 {
+				return fmt.Errorf("invalid reset jitter for component %s: %f", name, config.CircuitBreaker.ResetJitter)
+			}
+			
+			if config.CircuitBreaker.MaxOpenDuration < 0 {
+				return fmt.Errorf("invalid max open duration for component %s: %v", name, config.CircuitBreaker.MaxOpenDuration)
+			}
 		}
 		
 		for i, level := range config.DegradationLevels {
@@ -337,6 +603,44 @@ func (c *Config) Validate() error {
 		}
 	}
 	
+	if c.GlobalBudget.MaxCPUPercent < 0 || c.GlobalBudget.MaxCPUPercent > 100 {
+		return fmt.Errorf("invalid global budget CPU percentage: %f", c.GlobalBudget.MaxCPUPercent)
+	}
+	
+	if c.GlobalBudget.MaxMemoryMB < 0 {
+		return fmt.Errorf("invalid global budget memory MB: %d", c.GlobalBudget.MaxMemoryMB)
+	}
+	
+	if c.SustainedDegradationDuration < 0 {
+		return fmt.Errorf("invalid sustained degradation duration: %v", c.SustainedDegradationDuration)
+	}
+	
+	if c.MaxRestartsPerMinute < 0 {
+		return fmt.Errorf("invalid max restarts per minute: %d", c.MaxRestartsPerMinute)
+	}
+	
+	if c.MeasurementHistoryLen < 0 {
+		return fmt.Errorf("invalid measurement history length: %d", c.MeasurementHistoryLen)
+	}
+	
+	if c.AutoTune.Enabled {
+		if c.AutoTune.Percentile <= 0 || c.AutoTune.Percentile > 1 {
+			return fmt.Errorf("invalid auto-tune percentile: %f", c.AutoTune.Percentile)
+		}
+		
+		if c.AutoTune.MarginPercent < 0 {
+			return fmt.Errorf("invalid auto-tune margin percentage: %f", c.AutoTune.MarginPercent)
+		}
+		
+		if c.AutoTune.WarmupSamples <= 0 {
+			return fmt.Errorf("invalid auto-tune warmup samples: %d", c.AutoTune.WarmupSamples)
+		}
+		
+		if c.AutoTune.UpdateInterval <= 0 {
+			return fmt.Errorf("invalid auto-tune update interval: %v", c.AutoTune.UpdateInterval)
+		}
+	}
+	
 	if c.DeadlockDetection.Enabled {
 		if c.DeadlockDetection.HeartbeatInterval <= 0 {
 			return errors.New("heartbeat interval must be positive")
@@ -349,6 +653,10 @@ func (c *Config) Validate() error {
 		if c.DeadlockDetection.MaxOperationTime <= 0 {
 			return errors.New("max operation time must be positive")
 		}
+		
+		if c.DeadlockDetection.MaxStackTraceBytes < 0 {
+			return fmt.Errorf("invalid max stack trace bytes: %d", c.DeadlockDetection.MaxStackTraceBytes)
+		}
 	}
 	
 	if c.RestartPolicy.Enabled {
@@ -371,11 +679,55 @@ func (c *Config) Validate() error {
 		if c.RestartPolicy.RestartBackoffFactor <= 1.0 {
 			return errors.New("restart backoff factor must be greater than 1.0")
 		}
+		
+		if c.RestartPolicy.BackoffJitter < 0 || c.RestartPolicy.BackoffJitter > 1 {
+			return errors.New("backoff jitter must be between 0 and 1")
+		}
 	}
 	
 	if c.DiagnosticCollection.MaxEvents <= 0 {
 		return errors.New("max events must be positive")
 	}
 	
+	if c.IncidentStore.Enabled {
+		if c.IncidentStore.Path == "" {
+			return errors.New("incident store path must be set")
+		}
+		
+		if c.IncidentStore.MaxSizeBytes < 0 {
+			return errors.New("incident store max size must not be negative")
+		}
+		
+		if c.IncidentStore.MaxAge < 0 {
+			return errors.New("incident store max age must not be negative")
+		}
+		
+		if c.IncidentStore.MaxFiles <= 0 {
+			return errors.New("incident store max files must be positive")
+		}
+	}
+	
+	if c.SelfBudget.Enabled {
+		if c.SelfBudget.MaxCPUPercent < 0 || c.SelfBudget.MaxCPUPercent > 100 {
+			return fmt.Errorf("invalid self budget CPU percentage: %f", c.SelfBudget.MaxCPUPercent)
+		}
+		
+		if c.SelfBudget.MaxMemoryMB < 0 {
+			return fmt.Errorf("invalid self budget memory MB: %f", c.SelfBudget.MaxMemoryMB)
+		}
+		
+		if c.SelfBudget.MaxGoroutines < 0 {
+			return fmt.Errorf("invalid self budget goroutines: %d", c.SelfBudget.MaxGoroutines)
+		}
+		
+		if c.SelfBudget.GuardMonitoringInterval < 0 {
+			return fmt.Errorf("invalid self budget guard monitoring interval: %v", c.SelfBudget.GuardMonitoringInterval)
+		}
+		
+		if c.SelfBudget.GuardHistoryLen <= 0 {
+			return fmt.Errorf("invalid self budget guard history length: %d", c.SelfBudget.GuardHistoryLen)
+		}
+	}
+	
 	return nil
 }
@@ -1,11 +1,29 @@
 package watchdog
 
 import (
+	"fmt"
 	"runtime"
 	"sync"
 	"time"
 )
 
+// Deadlock is a component that stopped sending heartbeats, as returned by
+// DetectDeadlocks.
+type Deadlock struct {
+	// ComponentName is the name of the deadlocked component.
+	ComponentName string
+	
+	// Description explains how long the component has been silent.
+	Description string
+	
+	// Remediation suggests what to do about the deadlock.
+	Remediation string
+	
+	// StackTrace holds the stacks of all goroutines at detection, empty
+	// unless StackTraceEnabled is set.
+	StackTrace string
+}
+
 // DeadlockInfo contains information about a detected deadlock.
 type DeadlockInfo struct {
 	// ComponentName is the name of the deadlocked component.
@@ -24,61 +42,208 @@ type DeadlockInfo struct {
 	AdditionalInfo map[string]string
 }
 
-// DeadlockDetector is responsible for detecting deadlocks in components.
+// trackedOperation is an operation started with TrackOperation that has
+// not completed yet.
+type trackedOperation struct {
+	componentName string
+	name          string
+	start         time.Time
+	reported      bool // Whether the operation was returned by DetectDeadlocks
+}
+
+// DeadlockDetector flags components whose last heartbeat is older than
+// HeartbeatInterval * HeartbeatMissThreshold, and operations running longer
+// than MaxOperationTime.
 type DeadlockDetector struct {
-	config              Config
-	componentMonitor    *ComponentMonitor
+	config              DeadlockConfig
+	heartbeats          map[string]time.Time // Last heartbeat per registered component
 	detectedDeadlocks   map[string]DeadlockInfo
+	operations          map[uint64]*trackedOperation // Outstanding operations by ID
+	nextOperationID     uint64
+	now                 func() time.Time
 	mu                  sync.RWMutex
 }
 
 // NewDeadlockDetector creates a new deadlock detector.
-func NewDeadlockDetector(config Config, monitor *ComponentMonitor) *DeadlockDetector {
-	detector := &DeadlockDetector{
+func NewDeadlockDetector(config DeadlockConfig) (*DeadlockDetector, error) {
+	return NewDeadlockDetectorWithClock(config, time.Now)
+}
+
+// NewDeadlockDetectorWithClock creates a new deadlock detector that reads
+// the time from now, e.g. to test missed heartbeats without sleeping.
+func NewDeadlockDetectorWithClock(config DeadlockConfig, now func() time.Time) (*DeadlockDetector, error) {
+	if config.HeartbeatInterval <= 0 {
+		return nil, fmt.Errorf("heartbeat interval must be positive")
+	}
+	
+	if config.HeartbeatMissThreshold <= 0 {
+		return nil, fmt.Errorf("heartbeat miss threshold must be positive")
+	}
+	
+	return &DeadlockDetector{
 		config:             config,
-		componentMonitor:   monitor,
+		heartbeats:         make(map[string]time.Time),
 		detectedDeadlocks:  make(map[string]DeadlockInfo),
+		operations:         make(map[uint64]*trackedOperation),
+		now:                now,
+	}, nil
+}
+
+// RegisterComponent starts tracking the heartbeats of a component. The
+// registration counts as its first heartbeat.
+func (d *DeadlockDetector) RegisterComponent(componentName string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	
+	d.heartbeats[componentName] = d.now()
+}
+
+// UnregisterComponent stops tracking a component and forgets its deadlock
+// and outstanding operations.
+func (d *DeadlockDetector) UnregisterComponent(componentName string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	
+	delete(d.heartbeats, componentName)
+	delete(d.detectedDeadlocks, componentName)
+	for id, operation := range d.operations {
+		if operation.componentName == componentName {
+			delete(d.operations, id)
+		}
 	}
+}
+
+// TrackOperation records the start of an operation of a component and
+// returns the function to call when it completes. An operation still
+// outstanding after MaxOperationTime is reported by DetectDeadlocks, even if
+// the component keeps sending heartbeats from other goroutines:
+//
+//	done := detector.TrackOperation("sampler", "flush")
+//	defer done()
+func (d *DeadlockDetector) TrackOperation(componentName, operation string) (done func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	
-	// Register for deadlock events from the monitor
-	monitor.AddDeadlockDetectedHandler(detector.handleDeadlockDetected)
+	d.nextOperationID++
+	id := d.nextOperationID
+	d.operations[id] = &trackedOperation{
+		componentName: componentName,
+		name:          operation,
+		start:         d.now(),
+	}
 	
-	return detector
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		
+		delete(d.operations, id)
+	}
 }
 
-// handleDeadlockDetected is called when a deadlock is detected.
-func (d *DeadlockDetector) handleDeadlockDetected(componentName string, metrics ComponentMetrics) {
+// Heartbeat records that a registered component is alive, which clears its
+// deadlock if one was detected. Heartbeats of unknown components are ignored.
+func (d *DeadlockDetector) Heartbeat(componentName string) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	
-	// Check if we already have a record for this deadlock
-	if _, exists := d.detectedDeadlocks[componentName]; exists {
+	if _, exists := d.heartbeats[componentName]; !exists {
 		return
 	}
 	
-	// Create deadlock info
-	deadlockInfo := DeadlockInfo{
-		ComponentName:    componentName,
-		DetectedAt:       time.Now(),
-		LastResponseTime: metrics.LastResponseTime,
-		GoroutineStacks:  d.captureGoroutineStacks(),
-		AdditionalInfo:   make(map[string]string),
+	d.heartbeats[componentName] = d.now()
+	delete(d.detectedDeadlocks, componentName)
+}
+
+// DetectDeadlocks returns the components that missed HeartbeatMissThreshold
+// heartbeats, and the operations outstanding for longer than
+// MaxOperationTime. Each missed heartbeat deadlock is returned once, until
+// the component sends a heartbeat again, and each operation once.
+func (d *DeadlockDetector) DetectDeadlocks() []Deadlock {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	
+	now := d.now()
+	limit := d.config.HeartbeatInterval * time.Duration(d.config.HeartbeatMissThreshold)
+	
+	var deadlocks []Deadlock
+	var stacks string
+	for componentName, lastHeartbeat := range d.heartbeats {
+		silence := now.Sub(lastHeartbeat)
+		if silence <= limit {
+			continue
+		}
+		if _, reported := d.detectedDeadlocks[componentName]; reported {
+			continue
+		}
+		
+		// One capture covers all components deadlocked in this check
+		if d.config.StackTraceEnabled && stacks == "" {
+			stacks = CaptureStackTrace(d.config.MaxStackTraceBytes)
+		}
+		
+		d.detectedDeadlocks[componentName] = DeadlockInfo{
+			ComponentName:    componentName,
+			DetectedAt:       now,
+			LastResponseTime: silence,
+			GoroutineStacks:  stacks,
+			AdditionalInfo: map[string]string{
+				"last_heartbeat": lastHeartbeat.Format(time.RFC3339),
+				"missed":         fmt.Sprintf("%d", int(silence/d.config.HeartbeatInterval)),
+			},
+		}
+		
+		deadlocks = append(deadlocks, Deadlock{
+			ComponentName: componentName,
+			Description: fmt.Sprintf("no heartbeat for %s, expected every %s",
+				silence.Round(time.Millisecond), d.config.HeartbeatInterval),
+			Remediation: "Check the goroutine stacks of the component for blocked operations and restart it",
+			StackTrace:  stacks,
+		})
 	}
 	
-	// Add any component-specific information
-	deadlockInfo.AdditionalInfo["state"] = metrics.State
-	deadlockInfo.AdditionalInfo["health"] = metrics.HealthStatus
-	deadlockInfo.AdditionalInfo["goroutines"] = string(metrics.GoroutineCount)
+	if d.config.MaxOperationTime > 0 {
+		for _, operation := range d.operations {
+			elapsed := now.Sub(operation.start)
+			if operation.reported || elapsed <= d.config.MaxOperationTime {
+				continue
+			}
+			operation.reported = true
+			
+			if d.config.StackTraceEnabled && stacks == "" {
+				stacks = CaptureStackTrace(d.config.MaxStackTraceBytes)
+			}
+			
+			deadlocks = append(deadlocks, Deadlock{
+				ComponentName: operation.componentName,
+				Description: fmt.Sprintf("operation %s running for %s, longer than %s",
+					operation.name, elapsed.Round(time.Millisecond), d.config.MaxOperationTime),
+				Remediation: fmt.Sprintf("Check the goroutine stacks of the component for where %s is blocked and restart it", operation.name),
+				StackTrace:  stacks,
+			})
+		}
+	}
 	
-	// Store the deadlock info
-	d.detectedDeadlocks[componentName] = deadlockInfo
+	return deadlocks
 }
 
-// captureGoroutineStacks returns stack traces for all goroutines.
-func (d *DeadlockDetector) captureGoroutineStacks() string {
-	buf := make([]byte, 1<<20) // 1MB buffer
+// stackTraceTruncated marks a stack trace trimmed by CaptureStackTrace.
+const stackTraceTruncated = "\n... stack trace truncated"
+
+// CaptureStackTrace returns the stack traces of all goroutines, trimmed to
+// maxBytes if positive or to 1MB otherwise. A trimmed trace ends with a
+// truncation marker.
+func CaptureStackTrace(maxBytes int) string {
+	if maxBytes <= 0 {
+		maxBytes = 1 << 20
+	}
+	
+	// One spare byte tells whether runtime.Stack had more to write
+	buf := make([]byte, maxBytes+1)
 	stackLen := runtime.Stack(buf, true)
-	return string(buf[:stackLen])
+	if stackLen <= maxBytes {
+		return string(buf[:stackLen])
+	}
+	return string(buf[:maxBytes]) + stackTraceTruncated
 }
 
 // GetDetectedDeadlocks returns information about detected deadlocks.
@@ -95,7 +260,8 @@ func (d *DeadlockDetector) GetDetectedDeadlocks() map[string]DeadlockInfo {
 	return deadlocks
 }
 
-// ClearDeadlock clears the deadlock record for a component.
+// ClearDeadlock clears the deadlock record for a component, so it is
+// reported again if it is still silent.
 func (d *DeadlockDetector) ClearDeadlock(componentName string) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -1,10 +1,23 @@
 package watchdog
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 )
 
+// ActionHandler carries out a degradation action. It is called with the level
+// whose actions include it when the level activates, and with the level below
+// when that level deactivates, so it can revert to the state of that level
+type ActionHandler func(level int) error
+
+// actionCall is a handler call collected under the mutex and run after it
+type actionCall struct {
+	action  string
+	level   int
+	handler ActionHandler
+}
+
 // DegradationController manages component degradation
 type DegradationController struct {
 	// maxLevel is the maximum degradation level
@@ -19,6 +32,9 @@ type DegradationController struct {
 	// componentLevels tracks current degradation levels by component
 	componentLevels map[string]int
 	
+	// actionHandlers maps actions to the handlers carrying them out
+	actionHandlers map[string][]ActionHandler
+	
 	// mutex protects the controller state
 	mutex sync.RWMutex
 }
@@ -34,9 +50,20 @@ func NewDegradationController(maxLevel int) (*DegradationController, error) {
 		levelActions:      make(map[int][]string),
 		levelDescriptions: make(map[int]string),
 		componentLevels:   make(map[string]int),
+		actionHandlers:    make(map[string][]ActionHandler),
 	}, nil
 }
 
+// RegisterActionHandler registers a handler for an action, e.g.
+// "reduce_scan_frequency". Handlers run without the controller lock held, in
+// the order they were registered
+func (dc *DegradationController) RegisterActionHandler(action string, fn func(level int) error) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+	
+	dc.actionHandlers[action] = append(dc.actionHandlers[action], fn)
+}
+
 // SetLevelActions sets the actions for a degradation level
 func (dc *DegradationController) SetLevelActions(level int, actions []string, description string) error {
 	dc.mutex.Lock()
@@ -52,18 +79,63 @@ func (dc *DegradationController) SetLevelActions(level int, actions []string, de
 	return nil
 }
 
-// SetComponentLevel sets the degradation level for a component
+// SetComponentLevel sets the degradation level for a component and runs the
+// action handlers of the levels it activates or deactivates. The level is set
+// even if handlers fail, and their errors are returned joined
 func (dc *DegradationController) SetComponentLevel(component string, level int) error {
 	dc.mutex.Lock()
-	defer dc.mutex.Unlock()
 	
 	if level < 0 || level > dc.maxLevel {
+		dc.mutex.Unlock()
 		return fmt.Errorf("invalid degradation level: %d (max: %d)", level, dc.maxLevel)
 	}
 	
+	calls := dc.changeLevel(component, level)
+	dc.mutex.Unlock()
+	
+	return runActionHandlers(component, calls)
+}
+
+// changeLevel sets the level of a component and returns the handler calls
+// for the change: the actions of each activated level, lowest first, or the
+// actions of each deactivated level, highest first. Caller must hold mutex
+func (dc *DegradationController) changeLevel(component string, level int) []actionCall {
+	previous := dc.componentLevels[component]
 	dc.componentLevels[component] = level
 	
-	return nil
+	var calls []actionCall
+	for l := previous + 1; l <= level; l++ {
+		calls = dc.appendActionCalls(calls, l, l)
+	}
+	for l := previous; l > level; l-- {
+		calls = dc.appendActionCalls(calls, l, l-1)
+	}
+	
+	return calls
+}
+
+// appendActionCalls appends a call with handlerLevel to the handlers of each
+// action of level. Caller must hold mutex
+func (dc *DegradationController) appendActionCalls(calls []actionCall, level int, handlerLevel int) []actionCall {
+	for _, action := range dc.levelActions[level] {
+		for _, handler := range dc.actionHandlers[action] {
+			calls = append(calls, actionCall{action: action, level: handlerLevel, handler: handler})
+		}
+	}
+	
+	return calls
+}
+
+// runActionHandlers runs the handler calls of a component, joining their errors
+func runActionHandlers(component string, calls []actionCall) error {
+	var errs []error
+	for _, call := range calls {
+		if err := call.handler(call.level); err != nil {
+			errs = append(errs, fmt.Errorf("action %s at level %d failed for component %s: %w", call.action, call.level, component, err))
+		}
+	}
+	
+	return errors.Join(errs...)
 }
 
 // GetComponentLevel gets the current degradation level for a component
@@ -138,20 +210,28 @@ func (dc *DegradationController) GetComponentsAtLevel(level int) ([]string, erro
 	return components, nil
 }
 
-// ResetComponent resets a component to no degradation
-func (dc *DegradationController) ResetComponent(component string) {
-	dc.mutex.Lock()
-	defer dc.mutex.Unlock()
-	
-	dc.componentLevels[component] = 0
+// ResetComponent resets a component to no degradation, reverting the actions
+// of its levels
+func (dc *DegradationController) ResetComponent(component string) error {
+	return dc.SetComponentLevel(component, 0)
 }
 
-// ResetAllComponents resets all components to no degradation
-func (dc *DegradationController) ResetAllComponents() {
+// ResetAllComponents resets all components to no degradation, reverting the
+// actions of their levels
+func (dc *DegradationController) ResetAllComponents() error {
 	dc.mutex.Lock()
-	defer dc.mutex.Unlock()
-	
+	calls := make(map[string][]actionCall, len(dc.componentLevels))
 	for component := range dc.componentLevels {
-		dc.componentLevels[component] = 0
+		calls[component] = dc.changeLevel(component, 0)
 	}
+	dc.mutex.Unlock()
+	
+	var errs []error
+	for component, componentCalls := range calls {
+		if err := runActionHandlers(component, componentCalls); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	
+	return errors.Join(errs...)
 }
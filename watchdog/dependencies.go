@@ -0,0 +1,99 @@
+package watchdog
+
+import "sort"
+
+// dependencyGraph maps a component to the components it depends on. The
+// dependencies do not need to be registered
+type dependencyGraph map[string][]string
+
+// cycle returns the path of the cycle adding the dependencies of name would
+// create, starting and ending at name, or nil if there is none
+func (g dependencyGraph) cycle(name string, dependsOn []string) []string {
+	visited := make(map[string]bool)
+
+	// find returns the path from component back to name, if any
+	var find func(component string) []string
+	find = func(component string) []string {
+		if component == name {
+			return []string{name}
+		}
+		if visited[component] {
+			return nil
+		}
+		visited[component] = true
+
+		for _, dependency := range g[component] {
+			if path := find(dependency); path != nil {
+				return append([]string{component}, path...)
+			}
+		}
+		return nil
+	}
+
+	for _, dependency := range dependsOn {
+		if path := find(dependency); path != nil {
+			return append([]string{name}, path...)
+		}
+	}
+	return nil
+}
+
+// dependentsInOrder returns the components depending on name, directly or
+// transitively, in topological order: every component comes after the
+// dependencies it shares with the others. Ties are broken by name
+func (g dependencyGraph) dependentsInOrder(name string) []string {
+	// Collect the transitive dependents
+	dependents := make(map[string]bool)
+	pending := []string{name}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+
+		for component, dependencies := range g {
+			if dependents[component] || component == name {
+				continue
+			}
+			for _, dependency := range dependencies {
+				if dependency == current {
+					dependents[component] = true
+					pending = append(pending, component)
+					break
+				}
+			}
+		}
+	}
+
+	// Place the dependents whose dependencies among them are placed, in rounds
+	ordered := make([]string, 0, len(dependents))
+	placed := make(map[string]bool, len(dependents))
+	for len(ordered) < len(dependents) {
+		var ready []string
+		for component := range dependents {
+			if !placed[component] && g.dependenciesPlaced(component, dependents, placed) {
+				ready = append(ready, component)
+			}
+		}
+		if len(ready) == 0 {
+			// Only reachable with a cycle, which registration rejects
+			break
+		}
+
+		sort.Strings(ready)
+		for _, component := range ready {
+			placed[component] = true
+		}
+		ordered = append(ordered, ready...)
+	}
+	return ordered
+}
+
+// dependenciesPlaced returns whether all dependencies of component that are
+// in the set are placed
+func (g dependencyGraph) dependenciesPlaced(component string, set, placed map[string]bool) bool {
+	for _, dependency := range g[component] {
+		if set[dependency] && !placed[dependency] {
+			return false
+		}
+	}
+	return true
+}
@@ -62,7 +62,7 @@ func (d *DiagnosticsProvider) EmitAgentDiagEvent(incident Incident) {
 	event := DiagnosticEvent{
 		ID:            incident.ID,
 		Type:          string(incident.Type),
-		ComponentName: "",  // Will be filled by caller
+		ComponentName: incident.ComponentName,
 		Timestamp:     incident.Timestamp,
 		Severity:      incidentSeverity(incident.Type),
 		Message:       incident.Description,
@@ -71,9 +71,9 @@ func (d *DiagnosticsProvider) EmitAgentDiagEvent(incident Incident) {
 	
 	// Add resource usage details
 	event.Details["cpu_percent"] = incident.ResourceUsage.CPUPercent
-	event.Details["memory_mb"] = incident.ResourceUsage.MemoryMB
+	event.Details["memory_mb"] = incident.ResourceUsage.MemoryMB()
 	event.Details["goroutines"] = incident.ResourceUsage.Goroutines
-	event.Details["file_handles"] = incident.ResourceUsage.FileHandles
+	event.Details["file_descriptors"] = incident.ResourceUsage.FileDescriptors
 	event.Details["gc_percent"] = incident.ResourceUsage.GCPercent
 	
 	// Add remediation
@@ -81,6 +81,11 @@ func (d *DiagnosticsProvider) EmitAgentDiagEvent(incident Incident) {
 		event.Details["remediation"] = incident.Remediation
 	}
 	
+	// Add the stack trace
+	if d.includeStackTraces && incident.StackTrace != "" {
+		event.Details["stack_trace"] = incident.StackTrace
+	}
+	
 	// Add to events list
 	d.events = append(d.events, event)
 	
@@ -166,7 +171,7 @@ func (d *DiagnosticsProvider) SetIncludeStackTraces(include bool) {
 // incidentSeverity returns the severity level for an incident type
 func incidentSeverity(incidentType IncidentType) string {
 	switch incidentType {
-	case IncidentResourceExceeded:
+	case IncidentResourceExceeded, IncidentBudgetExceeded:
 		return "warning"
 	case IncidentDeadlockDetected:
 		return "critical"
@@ -0,0 +1,116 @@
+package watchdog
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// eventBufferSize is the number of events buffered per subscriber. Events
+// sent to a full buffer are dropped
+const eventBufferSize = 64
+
+// WatchdogEventType is the type of a watchdog event
+type WatchdogEventType string
+
+const (
+	// EventHealthChanged indicates the health of a component changed
+	EventHealthChanged WatchdogEventType = "health_changed"
+
+	// EventCircuitStateChanged indicates the circuit state of a component changed
+	EventCircuitStateChanged WatchdogEventType = "circuit_state_changed"
+
+	// EventDegradationChanged indicates the degradation level of a component changed
+	EventDegradationChanged WatchdogEventType = "degradation_changed"
+
+	// EventIncident indicates an incident was created
+	EventIncident WatchdogEventType = "incident"
+)
+
+// WatchdogEvent is a lifecycle event of a component, as sent to subscribers.
+// Only the fields of its type are set
+type WatchdogEvent struct {
+	// Type is the type of the event
+	Type WatchdogEventType
+
+	// ComponentName is the name of the component
+	ComponentName string
+
+	// Timestamp is when the event occurred
+	Timestamp time.Time
+
+	// OldHealth and NewHealth are set for EventHealthChanged
+	OldHealth HealthStatus
+	NewHealth HealthStatus
+
+	// OldCircuitState and NewCircuitState are set for EventCircuitStateChanged
+	OldCircuitState CircuitState
+	NewCircuitState CircuitState
+
+	// OldDegradationLevel and NewDegradationLevel are set for EventDegradationChanged
+	OldDegradationLevel int
+	NewDegradationLevel int
+
+	// Incident is set for EventIncident
+	Incident *Incident
+}
+
+// eventBroker sends events to subscribers without blocking the sender, so a
+// slow subscriber cannot stall monitoring
+type eventBroker struct {
+	subscribers map[int]chan WatchdogEvent
+	nextID      int
+	dropped     atomic.Uint64
+	mu          sync.Mutex
+}
+
+// newEventBroker creates a broker without subscribers
+func newEventBroker() *eventBroker {
+	return &eventBroker{
+		subscribers: make(map[int]chan WatchdogEvent),
+	}
+}
+
+// subscribe returns a channel receiving the events published from now on,
+// and a function closing it. The function may be called more than once
+func (b *eventBroker) subscribe() (<-chan WatchdogEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	events := make(chan WatchdogEvent, eventBufferSize)
+	b.subscribers[id] = events
+
+	var once sync.Once
+	return events, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			delete(b.subscribers, id)
+			close(events)
+		})
+	}
+}
+
+// publish sends an event to every subscriber whose buffer is not full, and
+// counts the event as dropped for the others
+func (b *eventBroker) publish(event WatchdogEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, events := range b.subscribers {
+		select {
+		case events <- event:
+		default:
+			b.dropped.Add(1)
+		}
+	}
+}
+
+// droppedEvents returns the number of events dropped because a subscriber
+// buffer was full
+func (b *eventBroker) droppedEvents() uint64 {
+	return b.dropped.Load()
+}
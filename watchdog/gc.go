@@ -0,0 +1,40 @@
+package watchdog
+
+import (
+	"sync"
+	"time"
+)
+
+// GCPauseTracker derives the percentage of wall time spent in GC pauses
+// between two readings of runtime.MemStats.PauseTotalNs. Components use it to
+// report ResourceUsage.GCPercent over their own reporting interval, unlike
+// MemStats.GCCPUFraction which covers the whole life of the process
+type GCPauseTracker struct {
+	lastPauseTotalNs uint64
+	lastTime         time.Time
+	mu               sync.Mutex
+}
+
+// NewGCPauseTracker creates a tracker without a previous reading
+func NewGCPauseTracker() *GCPauseTracker {
+	return &GCPauseTracker{}
+}
+
+// Percent records a reading of PauseTotalNs taken at now, and returns the
+// percentage of the time since the previous reading spent in GC pauses. It
+// returns 0 for the first reading, or if the clock or the total went back
+func (t *GCPauseTracker) Percent(pauseTotalNs uint64, now time.Time) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lastPauseTotalNs, lastTime := t.lastPauseTotalNs, t.lastTime
+	t.lastPauseTotalNs, t.lastTime = pauseTotalNs, now
+
+	elapsed := now.Sub(lastTime)
+	if lastTime.IsZero() || elapsed <= 0 || pauseTotalNs < lastPauseTotalNs {
+		return 0
+	}
+
+	percent := float64(pauseTotalNs-lastPauseTotalNs) / float64(elapsed.Nanoseconds()) * 100
+	return min(percent, 100)
+}
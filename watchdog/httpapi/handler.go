@@ -0,0 +1,291 @@
+// Package httpapi exposes the status of a watchdog over HTTP. It is kept out
+// of the watchdog package so that only agents serving it depend on net/http.
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/watchdog"
+)
+
+// StatusSource provides component statuses and accepts threshold updates,
+// implemented by watchdog.Watchdog
+type StatusSource interface {
+	GetComponentStatus(name string) (watchdog.ComponentStatus, error)
+	GetAllComponentStatuses() map[string]watchdog.ComponentStatus
+	SetThresholds(name string, thresholds watchdog.ResourceThresholds) error
+}
+
+// ThresholdSource provides the thresholds components are checked against,
+// implemented by watchdog.ResourceMonitor
+type ThresholdSource interface {
+	GetEffectiveThresholds() map[string]watchdog.EffectiveThresholds
+}
+
+// CircuitController overrides circuit breakers, implemented by
+// watchdog.Watchdog. The circuit endpoints are only served if the
+// StatusSource implements it
+type CircuitController interface {
+	ForceOpenCircuit(name string) error
+	ForceCloseCircuit(name string) error
+	ResetCircuit(name string) error
+}
+
+// Handler serves the watchdog endpoints:
+//
+//	GET  /watchdog/status                status of all components
+//	GET  /watchdog/status/{name}         status of one component
+//	POST /watchdog/thresholds/{name}     replace the thresholds of a component
+//	POST /watchdog/circuit/{name}/open   force the circuit of a component open
+//	POST /watchdog/circuit/{name}/close  force the circuit of a component closed
+//	POST /watchdog/circuit/{name}/reset  clear the override and close the circuit
+type Handler struct {
+	source     StatusSource
+	thresholds ThresholdSource
+	mux        *http.ServeMux
+}
+
+// NewHandler creates a handler serving the status of the given source
+func NewHandler(source StatusSource) *Handler {
+	h := &Handler{
+		source: source,
+		mux:    http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc("GET /watchdog/status", h.allStatuses)
+	h.mux.HandleFunc("GET /watchdog/status/{name}", h.componentStatus)
+	h.mux.HandleFunc("POST /watchdog/thresholds/{name}", h.setThresholds)
+
+	if circuits, ok := source.(CircuitController); ok {
+		h.mux.HandleFunc("POST /watchdog/circuit/{name}/open", overrideCircuit(circuits.ForceOpenCircuit))
+		h.mux.HandleFunc("POST /watchdog/circuit/{name}/close", overrideCircuit(circuits.ForceCloseCircuit))
+		h.mux.HandleFunc("POST /watchdog/circuit/{name}/reset", overrideCircuit(circuits.ResetCircuit))
+	}
+
+	return h
+}
+
+// SetThresholdSource adds the effective thresholds of each component to its
+// status. It must be called before the handler serves requests
+func (h *Handler) SetThresholdSource(thresholds ThresholdSource) {
+	h.thresholds = thresholds
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// componentStatusResponse is the JSON form of a watchdog.ComponentStatus
+type componentStatusResponse struct {
+	Name             string                `json:"name"`
+	Health           watchdog.HealthStatus `json:"health"`
+	CircuitState     string                `json:"circuit_state"`
+	DegradationLevel int                   `json:"degradation_level"`
+	RestartCount     int                   `json:"restart_count"`
+	LastRestart      *time.Time            `json:"last_restart,omitempty"`
+	ResourceUsage    resourceUsageResponse `json:"resource_usage"`
+	Incidents        []incidentResponse    `json:"incidents"`
+
+	// Thresholds are only set if the handler has a ThresholdSource
+	Thresholds *effectiveThresholdsResponse `json:"effective_thresholds,omitempty"`
+}
+
+// effectiveThresholdsResponse is the JSON form of watchdog.EffectiveThresholds
+type effectiveThresholdsResponse struct {
+	MaxCPUPercent float64    `json:"max_cpu_percent"`
+	MaxMemoryMB   float64    `json:"max_memory_mb"`
+	Tuned         bool       `json:"tuned"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+}
+
+// resourceUsageResponse is the JSON form of a watchdog.ResourceUsage
+type resourceUsageResponse struct {
+	CPUPercent      float64   `json:"cpu_percent"`
+	MemoryBytes     uint64    `json:"memory_bytes"`
+	FileDescriptors int       `json:"file_descriptors"`
+	Goroutines      int       `json:"goroutines"`
+	GCPercent       float64   `json:"gc_percent"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// incidentResponse is the JSON form of a watchdog.Incident
+type incidentResponse struct {
+	ID          string                `json:"id"`
+	Timestamp   time.Time             `json:"timestamp"`
+	Type        watchdog.IncidentType `json:"type"`
+	Description string                `json:"description"`
+	Remediation string                `json:"remediation,omitempty"`
+	StackTrace  string                `json:"stack_trace,omitempty"`
+}
+
+// thresholdsRequest is the body of a threshold update. All thresholds are
+// required, so a typo cannot silently set a threshold to 0
+type thresholdsRequest struct {
+	MaxCPUPercent  *float64 `json:"max_cpu_percent"`
+	MaxMemoryMB    *int     `json:"max_memory_mb"`
+	MaxGoroutines  *int     `json:"max_goroutines"`
+	MaxFileHandles *int     `json:"max_file_handles"`
+	MaxGCPercent   *float64 `json:"max_gc_percent"`
+}
+
+// errorResponse is the body of failed requests
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// allStatuses writes the status of all components, sorted by name
+func (h *Handler) allStatuses(w http.ResponseWriter, r *http.Request) {
+	statuses := h.source.GetAllComponentStatuses()
+	thresholds := h.effectiveThresholds()
+
+	response := make([]componentStatusResponse, 0, len(statuses))
+	for _, status := range statuses {
+		response = append(response, newComponentStatusResponse(status, thresholds))
+	}
+	sort.Slice(response, func(i, j int) bool {
+		return response[i].Name < response[j].Name
+	})
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// componentStatus writes the status of the component in the path
+func (h *Handler) componentStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := h.source.GetComponentStatus(r.PathValue("name"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newComponentStatusResponse(status, h.effectiveThresholds()))
+}
+
+// effectiveThresholds returns the thresholds of the threshold source, nil if
+// the handler has none
+func (h *Handler) effectiveThresholds() map[string]watchdog.EffectiveThresholds {
+	if h.thresholds == nil {
+		return nil
+	}
+	return h.thresholds.GetEffectiveThresholds()
+}
+
+// setThresholds replaces the thresholds of the component in the path
+func (h *Handler) setThresholds(w http.ResponseWriter, r *http.Request) {
+	var request thresholdsRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid thresholds: %v", err)})
+		return
+	}
+
+	thresholds, err := request.thresholds()
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.source.SetThresholds(r.PathValue("name"), thresholds); err != nil {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// overrideCircuit returns a handler applying override to the circuit of the
+// component in the path
+func overrideCircuit(override func(name string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := override(r.PathValue("name")); err != nil {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: err.Error()})
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// thresholds validates the request and converts it to watchdog thresholds
+func (t thresholdsRequest) thresholds() (watchdog.ResourceThresholds, error) {
+	if t.MaxCPUPercent == nil || t.MaxMemoryMB == nil || t.MaxGoroutines == nil ||
+		t.MaxFileHandles == nil || t.MaxGCPercent == nil {
+		return watchdog.ResourceThresholds{}, fmt.Errorf("invalid thresholds: all of max_cpu_percent, max_memory_mb, max_goroutines, max_file_handles and max_gc_percent are required")
+	}
+
+	if *t.MaxCPUPercent <= 0 || *t.MaxMemoryMB <= 0 || *t.MaxGoroutines <= 0 ||
+		*t.MaxFileHandles <= 0 || *t.MaxGCPercent <= 0 {
+		return watchdog.ResourceThresholds{}, fmt.Errorf("invalid thresholds: all thresholds must be positive")
+	}
+
+	return watchdog.ResourceThresholds{
+		MaxCPUPercent:  *t.MaxCPUPercent,
+		MaxMemoryMB:    *t.MaxMemoryMB,
+		MaxGoroutines:  *t.MaxGoroutines,
+		MaxFileHandles: *t.MaxFileHandles,
+		MaxGCPercent:   *t.MaxGCPercent,
+	}, nil
+}
+
+// newComponentStatusResponse converts a component status and its thresholds,
+// if any, to its JSON form
+func newComponentStatusResponse(status watchdog.ComponentStatus, thresholds map[string]watchdog.EffectiveThresholds) componentStatusResponse {
+	response := componentStatusResponse{
+		Name:             status.Name,
+		Health:           status.Health,
+		CircuitState:     status.CircuitState.String(),
+		DegradationLevel: status.DegradationLevel,
+		RestartCount:     status.RestartCount,
+		ResourceUsage: resourceUsageResponse{
+			CPUPercent:      status.ResourceUsage.CPUPercent,
+			MemoryBytes:     status.ResourceUsage.MemoryBytes,
+			FileDescriptors: status.ResourceUsage.FileDescriptors,
+			Goroutines:      status.ResourceUsage.Goroutines,
+			GCPercent:       status.ResourceUsage.GCPercent,
+			Timestamp:       status.ResourceUsage.Timestamp,
+		},
+		Incidents: make([]incidentResponse, 0, len(status.Incidents)),
+	}
+
+	if !status.LastRestart.IsZero() {
+		lastRestart := status.LastRestart
+		response.LastRestart = &lastRestart
+	}
+
+	if effective, ok := thresholds[status.Name]; ok {
+		response.Thresholds = &effectiveThresholdsResponse{
+			MaxCPUPercent: effective.MaxCPUPercent,
+			MaxMemoryMB:   effective.MaxMemoryMB,
+			Tuned:         effective.Tuned,
+		}
+		if !effective.UpdatedAt.IsZero() {
+			updatedAt := effective.UpdatedAt
+			response.Thresholds.UpdatedAt = &updatedAt
+		}
+	}
+
+	for _, incident := range status.Incidents {
+		response.Incidents = append(response.Incidents, incidentResponse{
+			ID:          incident.ID,
+			Timestamp:   incident.Timestamp,
+			Type:        incident.Type,
+			Description: incident.Description,
+			Remediation: incident.Remediation,
+			StackTrace:  incident.StackTrace,
+		})
+	}
+
+	return response
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,272 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/watchdog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource serves fixed statuses and records threshold updates
+type fakeSource struct {
+	statuses   map[string]watchdog.ComponentStatus
+	thresholds map[string]watchdog.ResourceThresholds
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{
+		statuses: map[string]watchdog.ComponentStatus{
+			"sampler": {
+				Name:         "sampler",
+				Health:       watchdog.HealthOK,
+				CircuitState: watchdog.CircuitClosed,
+			},
+			"collector": {
+				Name:             "collector",
+				Health:           watchdog.HealthDegraded,
+				CircuitState:     watchdog.CircuitOpen,
+				DegradationLevel: 1,
+				RestartCount:     2,
+				LastRestart:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				ResourceUsage: watchdog.ResourceUsage{
+					CPUPercent:  95,
+					MemoryBytes: 64 * 1024 * 1024,
+					Goroutines:  12,
+				},
+				Incidents: []watchdog.Incident{{
+					ID:          "collector-CPU-1",
+					Type:        watchdog.IncidentResourceExceeded,
+					Description: "CPU usage exceeded",
+				}, {
+					ID:          "collector-deadlock-1",
+					Type:        watchdog.IncidentDeadlockDetected,
+					Description: "Deadlock detected",
+					StackTrace:  "goroutine 1 [running]:",
+				}},
+			},
+		},
+		thresholds: make(map[string]watchdog.ResourceThresholds),
+	}
+}
+
+func (f *fakeSource) GetComponentStatus(name string) (watchdog.ComponentStatus, error) {
+	status, exists := f.statuses[name]
+	if !exists {
+		return watchdog.ComponentStatus{}, fmt.Errorf("component not registered: %s", name)
+	}
+	return status, nil
+}
+
+func (f *fakeSource) GetAllComponentStatuses() map[string]watchdog.ComponentStatus {
+	return f.statuses
+}
+
+func (f *fakeSource) SetThresholds(name string, thresholds watchdog.ResourceThresholds) error {
+	if _, exists := f.statuses[name]; !exists {
+		return fmt.Errorf("component not registered: %s", name)
+	}
+	f.thresholds[name] = thresholds
+	return nil
+}
+
+func (f *fakeSource) ForceOpenCircuit(name string) error {
+	return f.setCircuit(name, watchdog.CircuitOpen)
+}
+
+func (f *fakeSource) ForceCloseCircuit(name string) error {
+	return f.setCircuit(name, watchdog.CircuitClosed)
+}
+
+func (f *fakeSource) ResetCircuit(name string) error {
+	return f.setCircuit(name, watchdog.CircuitClosed)
+}
+
+func (f *fakeSource) setCircuit(name string, state watchdog.CircuitState) error {
+	status, exists := f.statuses[name]
+	if !exists {
+		return fmt.Errorf("component not registered: %s", name)
+	}
+	status.CircuitState = state
+	f.statuses[name] = status
+	return nil
+}
+
+// fakeThresholds serves fixed effective thresholds
+type fakeThresholds map[string]watchdog.EffectiveThresholds
+
+func (f fakeThresholds) GetEffectiveThresholds() map[string]watchdog.EffectiveThresholds {
+	return f
+}
+
+// serve sends a request to a handler of the source and returns the response
+func serve(source StatusSource, method, path, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	NewHandler(source).ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return recorder
+}
+
+func TestHandler_AllStatuses(t *testing.T) {
+	response := serve(newFakeSource(), http.MethodGet, "/watchdog/status", "")
+	require.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+
+	var statuses []componentStatusResponse
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &statuses))
+	require.Len(t, statuses, 2)
+
+	// Sorted by name
+	assert.Equal(t, "collector", statuses[0].Name)
+	assert.Equal(t, "sampler", statuses[1].Name)
+	assert.Nil(t, statuses[1].LastRestart)
+	assert.NotNil(t, statuses[1].Incidents)
+}
+
+func TestHandler_ComponentStatus(t *testing.T) {
+	response := serve(newFakeSource(), http.MethodGet, "/watchdog/status/collector", "")
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &fields))
+	assert.Equal(t, "collector", fields["name"])
+	assert.Equal(t, "degraded", fields["health"])
+	assert.Equal(t, "Open", fields["circuit_state"])
+	assert.Equal(t, 1.0, fields["degradation_level"])
+	assert.Equal(t, 2.0, fields["restart_count"])
+	assert.Equal(t, "2024-01-01T00:00:00Z", fields["last_restart"])
+
+	usage := fields["resource_usage"].(map[string]interface{})
+	assert.Equal(t, 95.0, usage["cpu_percent"])
+	assert.Equal(t, float64(64*1024*1024), usage["memory_bytes"])
+
+	_, hasThresholds := fields["effective_thresholds"]
+	assert.False(t, hasThresholds)
+
+	incidents := fields["incidents"].([]interface{})
+	require.Len(t, incidents, 2)
+	assert.Equal(t, "collector-CPU-1", incidents[0].(map[string]interface{})["id"])
+	assert.Equal(t, "resource_exceeded", incidents[0].(map[string]interface{})["type"])
+
+	// The stack trace is only set on incidents that captured one
+	_, hasStackTrace := incidents[0].(map[string]interface{})["stack_trace"]
+	assert.False(t, hasStackTrace)
+	assert.Equal(t, "goroutine 1 [running]:", incidents[1].(map[string]interface{})["stack_trace"])
+
+	response = serve(newFakeSource(), http.MethodGet, "/watchdog/status/unknown", "")
+	assert.Equal(t, http.StatusNotFound, response.Code)
+	assert.Contains(t, response.Body.String(), "component not registered: unknown")
+}
+
+func TestHandler_EffectiveThresholds(t *testing.T) {
+	handler := NewHandler(newFakeSource())
+	handler.SetThresholdSource(fakeThresholds{
+		"collector": {
+			MaxCPUPercent: 0.6,
+			MaxMemoryMB:   42.5,
+			Tuned:         true,
+			UpdatedAt:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"sampler": {
+			MaxCPUPercent: 0.5,
+			MaxMemoryMB:   50,
+		},
+	})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/watchdog/status", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var statuses []componentStatusResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &statuses))
+	require.Len(t, statuses, 2)
+
+	tuned := statuses[0].Thresholds
+	require.NotNil(t, tuned)
+	assert.Equal(t, 0.6, tuned.MaxCPUPercent)
+	assert.Equal(t, 42.5, tuned.MaxMemoryMB)
+	assert.True(t, tuned.Tuned)
+	require.NotNil(t, tuned.UpdatedAt)
+
+	static := statuses[1].Thresholds
+	require.NotNil(t, static)
+	assert.False(t, static.Tuned)
+	assert.Nil(t, static.UpdatedAt)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/watchdog/status/collector", nil))
+	assert.Contains(t, recorder.Body.String(), `"effective_thresholds":{"max_cpu_percent":0.6`)
+}
+
+func TestHandler_SetThresholds(t *testing.T) {
+	source := newFakeSource()
+	body := `{"max_cpu_percent": 50, "max_memory_mb": 128, "max_goroutines": 100, "max_file_handles": 200, "max_gc_percent": 5}`
+
+	response := serve(source, http.MethodPost, "/watchdog/thresholds/collector", body)
+	require.Equal(t, http.StatusNoContent, response.Code)
+	assert.Equal(t, watchdog.ResourceThresholds{
+		MaxCPUPercent:  50,
+		MaxMemoryMB:    128,
+		MaxGoroutines:  100,
+		MaxFileHandles: 200,
+		MaxGCPercent:   5,
+	}, source.thresholds["collector"])
+
+	// Unknown component
+	response = serve(source, http.MethodPost, "/watchdog/thresholds/unknown", body)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	// Invalid bodies are rejected without updating the thresholds
+	for _, invalid := range []string{
+		`not json`,
+		`{"max_cpu_percent": 50}`,
+		`{"max_cpu_percent": 50, "max_memory_mb": 128, "max_goroutines": 100, "max_file_handles": 200, "max_gc_percent": 0}`,
+		`{"max_cpu_percent": 50, "max_memory_mb": 128, "max_goroutines": 100, "max_file_handles": 200, "max_gc_percent": 5, "max_cpu": 1}`,
+	} {
+		response = serve(source, http.MethodPost, "/watchdog/thresholds/sampler", invalid)
+		assert.Equal(t, http.StatusBadRequest, response.Code, invalid)
+	}
+	assert.NotContains(t, source.thresholds, "sampler")
+}
+
+func TestHandler_OverrideCircuit(t *testing.T) {
+	source := newFakeSource()
+
+	response := serve(source, http.MethodPost, "/watchdog/circuit/sampler/open", "")
+	require.Equal(t, http.StatusNoContent, response.Code)
+	assert.Equal(t, watchdog.CircuitOpen, source.statuses["sampler"].CircuitState)
+
+	response = serve(source, http.MethodPost, "/watchdog/circuit/sampler/close", "")
+	require.Equal(t, http.StatusNoContent, response.Code)
+	assert.Equal(t, watchdog.CircuitClosed, source.statuses["sampler"].CircuitState)
+
+	response = serve(source, http.MethodPost, "/watchdog/circuit/collector/reset", "")
+	require.Equal(t, http.StatusNoContent, response.Code)
+	assert.Equal(t, watchdog.CircuitClosed, source.statuses["collector"].CircuitState)
+
+	// Unknown component
+	response = serve(source, http.MethodPost, "/watchdog/circuit/unknown/open", "")
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	// Unknown action
+	response = serve(source, http.MethodPost, "/watchdog/circuit/sampler/toggle", "")
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	// Sources that cannot override circuits do not serve the endpoints
+	response = serve(struct{ StatusSource }{source}, http.MethodPost, "/watchdog/circuit/sampler/open", "")
+	assert.Equal(t, http.StatusNotFound, response.Code)
+	assert.Equal(t, watchdog.CircuitClosed, source.statuses["sampler"].CircuitState)
+}
+
+func TestHandler_MethodNotAllowed(t *testing.T) {
+	response := serve(newFakeSource(), http.MethodPost, "/watchdog/status", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, response.Code)
+
+	response = serve(newFakeSource(), http.MethodGet, "/watchdog/thresholds/collector", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, response.Code)
+}
@@ -0,0 +1,297 @@
+package watchdog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxIncidentLineSize is the longest incident line, without its newline,
+// QueryIncidents reads. Longer lines are skipped
+const maxIncidentLineSize = 1 << 20
+
+// IncidentFilter selects the incidents returned by QueryIncidents. Zero
+// fields match every incident
+type IncidentFilter struct {
+	// Since excludes incidents before this time
+	Since time.Time
+
+	// Until excludes incidents at or after this time
+	Until time.Time
+
+	// ComponentName only matches incidents of this component
+	ComponentName string
+
+	// Types only matches incidents of these types
+	Types []IncidentType
+
+	// Limit returns only the most recent matching incidents
+	Limit int
+}
+
+// Matches returns whether an incident is selected by the filter
+func (f IncidentFilter) Matches(incident Incident) bool {
+	if !f.Since.IsZero() && incident.Timestamp.Before(f.Since) {
+		return false
+	}
+
+	if !f.Until.IsZero() && !incident.Timestamp.Before(f.Until) {
+		return false
+	}
+
+	if f.ComponentName != "" && incident.ComponentName != f.ComponentName {
+		return false
+	}
+
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, incidentType := range f.Types {
+		if incident.Type == incidentType {
+			return true
+		}
+	}
+	return false
+}
+
+// IncidentStore persists incidents beyond the last ones kept in
+// ComponentStatus, so they survive restarts of the agent
+type IncidentStore interface {
+	// Append stores an incident
+	Append(incident Incident) error
+
+	// QueryIncidents returns the stored incidents selected by the filter,
+	// oldest first
+	QueryIncidents(filter IncidentFilter) ([]Incident, error)
+}
+
+// FileIncidentStore stores incidents as JSON lines in a file. The file is
+// rotated to <path>.1, <path>.2, ... when it exceeds MaxSizeBytes or its
+// oldest incident exceeds MaxAge, keeping MaxFiles rotated files
+type FileIncidentStore struct {
+	config IncidentStoreConfig
+	oldest time.Time // Time of the first incident in the current file, zero if unknown
+	now    func() time.Time
+	mu     sync.Mutex
+}
+
+// NewFileIncidentStore creates a store appending to config.Path
+func NewFileIncidentStore(config IncidentStoreConfig) (*FileIncidentStore, error) {
+	return NewFileIncidentStoreWithClock(config, time.Now)
+}
+
+// NewFileIncidentStoreWithClock creates a store that reads the time from
+// now, e.g. to test rotation by age
+func NewFileIncidentStoreWithClock(config IncidentStoreConfig, now func() time.Time) (*FileIncidentStore, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("incident store path must be set")
+	}
+
+	if config.MaxFiles <= 0 {
+		return nil, fmt.Errorf("incident store max files must be positive")
+	}
+
+	return &FileIncidentStore{
+		config: config,
+		now:    now,
+	}, nil
+}
+
+// Append writes an incident to the current file, rotating it first if needed.
+// Its stack trace is trimmed if the incident would not fit in a line
+// QueryIncidents reads
+func (s *FileIncidentStore) Append(incident Incident) error {
+	line, err := encodeIncident(incident)
+	if err != nil {
+		return fmt.Errorf("failed to encode incident %s: %w", incident.ID, err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.rotateIfNeeded(int64(len(line))); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.config.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open incident store: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(line); err != nil {
+		return fmt.Errorf("failed to write incident %s: %w", incident.ID, err)
+	}
+
+	if s.oldest.IsZero() {
+		s.oldest = s.now()
+	}
+	return nil
+}
+
+// encodeIncident encodes an incident as JSON, trimming its stack trace until
+// the line fits in maxIncidentLineSize. Escaping makes the trace longer in
+// JSON, so it is trimmed by the excess of the encoded line
+func encodeIncident(incident Incident) ([]byte, error) {
+	line, err := json.Marshal(incident)
+	for err == nil && len(line) > maxIncidentLineSize && incident.StackTrace != "" {
+		keep := len(incident.StackTrace) - len(stackTraceTruncated) - (len(line) - maxIncidentLineSize)
+		if keep > 0 {
+			incident.StackTrace = strings.ToValidUTF8(incident.StackTrace[:keep], "") + stackTraceTruncated
+		} else {
+			incident.StackTrace = ""
+		}
+		line, err = json.Marshal(incident)
+	}
+	return line, err
+}
+
+// rotateIfNeeded rotates the current file if appending size bytes would
+// exceed MaxSizeBytes, or if its oldest incident is older than MaxAge.
+// Caller must hold mu
+func (s *FileIncidentStore) rotateIfNeeded(size int64) error {
+	info, err := os.Stat(s.config.Path)
+	if os.IsNotExist(err) {
+		s.oldest = time.Time{}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat incident store: %w", err)
+	}
+	if info.Size() == 0 {
+		return nil
+	}
+
+	// The age of a file written before a restart is its first incident
+	if s.oldest.IsZero() {
+		s.oldest = s.firstIncidentTime()
+	}
+
+	tooBig := s.config.MaxSizeBytes > 0 && info.Size()+size > s.config.MaxSizeBytes
+	tooOld := s.config.MaxAge > 0 && s.now().Sub(s.oldest) > s.config.MaxAge
+	if !tooBig && !tooOld {
+		return nil
+	}
+
+	// Shift <path>.i to <path>.i+1, dropping the oldest file
+	if err := os.Remove(s.rotatedPath(s.config.MaxFiles)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove rotated incident store: %w", err)
+	}
+	for i := s.config.MaxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(s.rotatedPath(i), s.rotatedPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate incident store: %w", err)
+		}
+	}
+	if err := os.Rename(s.config.Path, s.rotatedPath(1)); err != nil {
+		return fmt.Errorf("failed to rotate incident store: %w", err)
+	}
+
+	s.oldest = time.Time{}
+	return nil
+}
+
+// firstIncidentTime returns the timestamp of the first incident in the
+// current file, or now if it cannot be read. Caller must hold mu
+func (s *FileIncidentStore) firstIncidentTime() time.Time {
+	file, err := os.Open(s.config.Path)
+	if err != nil {
+		return s.now()
+	}
+	defer file.Close()
+
+	line, _ := readIncidentLine(bufio.NewReader(file))
+	var incident Incident
+	if err := json.Unmarshal(line, &incident); err == nil && !incident.Timestamp.IsZero() {
+		return incident.Timestamp
+	}
+	return s.now()
+}
+
+// rotatedPath returns the path of the i-th most recent rotated file
+func (s *FileIncidentStore) rotatedPath(i int) string {
+	return fmt.Sprintf("%s.%d", s.config.Path, i)
+}
+
+// QueryIncidents reads the rotated files and the current file, oldest first,
+// and returns the incidents selected by the filter. Lines that cannot be
+// decoded, e.g. a line truncated by a crash, and lines longer than
+// maxIncidentLineSize are skipped
+func (s *FileIncidentStore) QueryIncidents(filter IncidentFilter) ([]Incident, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paths := make([]string, 0, s.config.MaxFiles+1)
+	for i := s.config.MaxFiles; i >= 1; i-- {
+		paths = append(paths, s.rotatedPath(i))
+	}
+	paths = append(paths, s.config.Path)
+
+	var incidents []Incident
+	for _, path := range paths {
+		var err error
+		incidents, err = readIncidents(path, filter, incidents)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if filter.Limit > 0 && len(incidents) > filter.Limit {
+		incidents = incidents[len(incidents)-filter.Limit:]
+	}
+	return incidents, nil
+}
+
+// readIncidents appends the incidents of a file selected by the filter
+func readIncidents(path string, filter IncidentFilter, incidents []Incident) ([]Incident, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return incidents, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open incident store: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := readIncidentLine(reader)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read incident store %s: %w", path, err)
+		}
+
+		var incident Incident
+		if json.Unmarshal(line, &incident) == nil && filter.Matches(incident) {
+			incidents = append(incidents, incident)
+		}
+		if err == io.EOF {
+			return incidents, nil
+		}
+	}
+}
+
+// readIncidentLine returns the next line of reader without its newline, and
+// io.EOF after the last line. A line longer than maxIncidentLineSize is read
+// to its end and returned as nil
+func readIncidentLine(reader *bufio.Reader) ([]byte, error) {
+	var line []byte
+	tooLong := false
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		chunk = bytes.TrimSuffix(chunk, []byte{'\n'})
+		if !tooLong && len(line)+len(chunk) <= maxIncidentLineSize {
+			line = append(line, chunk...)
+		} else {
+			tooLong, line = true, nil
+		}
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
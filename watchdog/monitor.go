@@ -7,7 +7,8 @@ import (
 	"time"
 )
 
-// ResourceUsage represents the resource usage of a component
+// ResourceUsage represents the resource usage of a component. It is shared
+// by the watchdog, the resource monitor and the component monitor.
 type ResourceUsage struct {
 	// CPUPercent is the CPU usage percentage
 	CPUPercent float64
@@ -21,8 +22,23 @@ type ResourceUsage struct {
 	// Goroutines is the number of active goroutines
 	Goroutines int
 	
-	// LastUpdated is when this resource usage was last updated
-	LastUpdated time.Time
+	// GCPercent is the percentage of wall time spent in GC pauses since the
+	// previous measurement, 0 if not measured. See GCPauseTracker
+	GCPercent float64
+	
+	// IOReadBytes is the bytes read, 0 if not measured
+	IOReadBytes int64
+	
+	// IOWriteBytes is the bytes written, 0 if not measured
+	IOWriteBytes int64
+	
+	// Timestamp is when the measurement was taken
+	Timestamp time.Time
+}
+
+// MemoryMB returns the memory usage in MB
+func (u ResourceUsage) MemoryMB() float64 {
+	return float64(u.MemoryBytes) / (1024 * 1024)
 }
 
 // ThresholdExceededEvent represents a resource threshold exceeded event
@@ -46,6 +62,37 @@ type ThresholdExceededEvent struct {
 // ThresholdHandler is a function that is called when a threshold is exceeded
 type ThresholdHandler func(event ThresholdExceededEvent)
 
+// HandlerID identifies a threshold handler for RemoveThresholdHandler
+type HandlerID int
+
+// HandlerMode is how the resource monitor calls threshold handlers
+type HandlerMode string
+
+const (
+	// HandlerModeAsync calls handlers from a pool of worker goroutines, so slow
+	// handlers do not delay monitoring. Events may be handled out of order
+	HandlerModeAsync HandlerMode = "async"
+	
+	// HandlerModeSync calls handlers on the monitoring goroutine, one event
+	// and one handler at a time, in the order they were added
+	HandlerModeSync HandlerMode = "sync"
+)
+
+// defaultHandlerWorkers is the number of handler workers if not configured
+const defaultHandlerWorkers = 4
+
+// thresholdHandler is a handler registered with AddThresholdHandler
+type thresholdHandler struct {
+	id      HandlerID
+	handler ThresholdHandler
+}
+
+// handlerCall is a handler call queued for the async workers
+type handlerCall struct {
+	handler ThresholdHandler
+	event   ThresholdExceededEvent
+}
+
 // Component defines the interface for components that can be monitored
 type Component interface {
 	// Name returns the name of the component
@@ -64,14 +111,28 @@ type Component interface {
 	Start() error
 }
 
-// ResourceMonitor monitors the resource usage of components
+// ResourceMonitor monitors the resource usage of components. With
+// Config.SmoothingAlpha set, thresholds are checked against an exponential
+// moving average of the CPU and memory usage, and with
+// Config.ThresholdConsecutiveSamples a threshold must be exceeded in that many
+// consecutive checks, so a single spike does not raise an event
 type ResourceMonitor struct {
 	config        Config
 	components    map[string]Component
 	usageHistory  map[string][]ResourceUsage
 	historyMaxLen int
-	handlers      []ThresholdHandler
+	handlers      []thresholdHandler
+	nextHandlerID HandlerID
+	pending       []ThresholdExceededEvent // Events of the current check, notified after unlocking
+	calls         chan handlerCall         // Queue of the async workers
+	workersWg     sync.WaitGroup
 	degradationState map[string]string // component name -> current degradation level
+	smoothed      map[string]ResourceUsage    // component name -> moving average of the usage
+	overThreshold map[string]map[string]int   // component name -> resource type -> consecutive checks over threshold
+	autoTuner     *autoTuner                  // nil unless Config.AutoTune is enabled
+	gcPauses      *GCPauseTracker             // GC pauses of the process between GetTotalResourceUsage calls
+	lastCheckDuration time.Duration           // Time spent in the last checkResources
+	selfGuardTripped  bool                    // Whether the monitor exceeded Config.SelfBudget
 	ctx          context.Context
 	cancel       context.CancelFunc
 	wg           sync.WaitGroup
@@ -82,16 +143,32 @@ type ResourceMonitor struct {
 func NewResourceMonitor(config Config) *ResourceMonitor {
 	ctx, cancel := context.WithCancel(context.Background())
 	
-	return &ResourceMonitor{
+	var tuner *autoTuner
+	if config.AutoTune.Enabled {
+		tuner = newAutoTuner(config.AutoTune)
+	}
+	
+	rm := &ResourceMonitor{
 		config:        config,
 		components:    make(map[string]Component),
 		usageHistory:  make(map[string][]ResourceUsage),
 		historyMaxLen: 20, // Keep last 20 readings
-		handlers:      make([]ThresholdHandler, 0),
+		handlers:      make([]thresholdHandler, 0),
 		degradationState: make(map[string]string),
+		smoothed:      make(map[string]ResourceUsage),
+		overThreshold: make(map[string]map[string]int),
+		autoTuner:     tuner,
+		gcPauses:      NewGCPauseTracker(),
 		ctx:          ctx,
 		cancel:       cancel,
 	}
+	
+	// The monitor reports its own usage like any other component
+	if config.SelfBudget.Enabled {
+		rm.AddComponent(&selfComponent{rm: rm})
+	}
+	
+	return rm
 }
 
 // AddComponent adds a component to be monitored
@@ -103,6 +180,8 @@ func (rm *ResourceMonitor) AddComponent(component Component) error {
 	rm.components[name] = component
 	rm.usageHistory[name] = make([]ResourceUsage, 0, rm.historyMaxLen)
 	rm.degradationState[name] = ""
+	delete(rm.smoothed, name)
+	rm.overThreshold[name] = make(map[string]int)
 	
 	return nil
 }
@@ -115,33 +194,95 @@ func (rm *ResourceMonitor) RemoveComponent(name string) {
 	delete(rm.components, name)
 	delete(rm.usageHistory, name)
 	delete(rm.degradationState, name)
+	delete(rm.smoothed, name)
+	delete(rm.overThreshold, name)
+	if rm.autoTuner != nil {
+		rm.autoTuner.remove(name)
+	}
 }
 
-// AddThresholdHandler adds a handler to be called when a threshold is exceeded
-func (rm *ResourceMonitor) AddThresholdHandler(handler ThresholdHandler) {
+// AddThresholdHandler adds a handler to be called when a threshold is
+// exceeded, and returns its ID for RemoveThresholdHandler
+func (rm *ResourceMonitor) AddThresholdHandler(handler ThresholdHandler) HandlerID {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 	
-	rm.handlers = append(rm.handlers, handler)
+	rm.nextHandlerID++
+	rm.handlers = append(rm.handlers, thresholdHandler{id: rm.nextHandlerID, handler: handler})
+	
+	return rm.nextHandlerID
+}
+
+// RemoveThresholdHandler removes a handler, and returns false if it was not
+// added. Events already queued for the handler may still be delivered
+func (rm *ResourceMonitor) RemoveThresholdHandler(id HandlerID) bool {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	
+	for i, registered := range rm.handlers {
+		if registered.id == id {
+			rm.handlers = append(rm.handlers[:i:i], rm.handlers[i+1:]...)
+			return true
+		}
+	}
+	
+	return false
 }
 
 // Start starts the resource monitoring
 func (rm *ResourceMonitor) Start() error {
+	if rm.handlerMode() == HandlerModeAsync {
+		workers := rm.config.ThresholdHandlerWorkers
+		if workers <= 0 {
+			workers = defaultHandlerWorkers
+		}
+		
+		rm.calls = make(chan handlerCall, workers)
+		rm.workersWg.Add(workers)
+		for i := 0; i < workers; i++ {
+			go rm.handlerWorker()
+		}
+	}
+	
 	rm.wg.Add(1)
 	go rm.monitorLoop()
 	
 	return nil
 }
 
-// Stop stops the resource monitoring
+// Stop stops the resource monitoring, after the handlers of the last check
+// have been called
 func (rm *ResourceMonitor) Stop() error {
 	rm.cancel()
 	rm.wg.Wait()
 	
+	if rm.calls != nil {
+		close(rm.calls)
+		rm.workersWg.Wait()
+	}
+	
 	return nil
 }
 
-// GetResourceUsage returns the current resource usage for a component
+// handlerMode returns the configured handler mode, async if not set
+func (rm *ResourceMonitor) handlerMode() HandlerMode {
+	if rm.config.ThresholdHandlerMode == "" {
+		return HandlerModeAsync
+	}
+	return rm.config.ThresholdHandlerMode
+}
+
+// handlerWorker calls the queued handlers until the queue is closed
+func (rm *ResourceMonitor) handlerWorker() {
+	defer rm.workersWg.Done()
+	
+	for call := range rm.calls {
+		call.handler(call.event)
+	}
+}
+
+// GetResourceUsage returns the current resource usage for a component, as
+// reported by it without smoothing
 func (rm *ResourceMonitor) GetResourceUsage(componentName string) (ResourceUsage, bool) {
 	rm.mu.RLock()
 	defer rm.mu.RUnlock()
@@ -171,6 +312,24 @@ func (rm *ResourceMonitor) GetResourceHistory(componentName string) ([]ResourceU
 	return result, true
 }
 
+// GetEffectiveThresholds returns the CPU and memory thresholds each
+// monitored component is checked against, auto-tuned if enabled
+func (rm *ResourceMonitor) GetEffectiveThresholds() map[string]EffectiveThresholds {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+	
+	thresholds := make(map[string]EffectiveThresholds, len(rm.components))
+	for name := range rm.components {
+		componentConfig, ok := rm.config.ComponentConfigs[name]
+		if !ok {
+			continue
+		}
+		thresholds[name] = rm.autoTuner.thresholds(name, componentConfig)
+	}
+	
+	return thresholds
+}
+
 // GetDegradationLevel returns the current degradation level for a component
 func (rm *ResourceMonitor) GetDegradationLevel(componentName string) (string, bool) {
 	rm.mu.RLock()
@@ -184,7 +343,8 @@ func (rm *ResourceMonitor) GetDegradationLevel(componentName string) (string, bo
 func (rm *ResourceMonitor) monitorLoop() {
 	defer rm.wg.Done()
 	
-	ticker := time.NewTicker(rm.config.MonitoringInterval)
+	interval := rm.config.MonitoringInterval
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	
 	for {
@@ -192,21 +352,33 @@ func (rm *ResourceMonitor) monitorLoop() {
 		case <-rm.ctx.Done():
 			return
 		case <-ticker.C:
-			rm.checkResources()
+			rm.notifyThresholdHandlers(rm.checkResources())
+			
+			// The self guard may have lengthened the interval
+			rm.mu.RLock()
+			current := rm.currentInterval()
+			rm.mu.RUnlock()
+			if current != interval {
+				interval = current
+				ticker.Reset(interval)
+			}
 		}
 	}
 }
 
-// checkResources checks the resource usage of all components
-func (rm *ResourceMonitor) checkResources() {
+// checkResources checks the resource usage of all components and returns
+// the threshold exceeded events
+func (rm *ResourceMonitor) checkResources() []ThresholdExceededEvent {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 	
+	rm.pending = nil
+	
 	now := time.Now()
 	
 	for name, component := range rm.components {
 		usage := component.ResourceUsage()
-		usage.LastUpdated = now
+		usage.Timestamp = now
 		
 		// Add to history, maintaining max length
 		history := rm.usageHistory[name]
@@ -218,9 +390,57 @@ func (rm *ResourceMonitor) checkResources() {
 		history = append(history, usage)
 		rm.usageHistory[name] = history
 		
+		// Learn the usage distribution before checking the thresholds it sets
+		if componentConfig, ok := rm.config.ComponentConfigs[name]; ok && rm.autoTuner != nil {
+			rm.autoTuner.observe(name, usage, componentConfig, now)
+		}
+		
 		// Check against thresholds
-		rm.checkThresholds(name, usage)
+		rm.checkThresholds(name, rm.smooth(name, usage))
+	}
+	
+	rm.lastCheckDuration = time.Since(now)
+	rm.checkSelfBudget(now)
+	
+	return rm.pending
+}
+
+// smooth returns the usage with the CPU and memory replaced by their
+// exponential moving average, or the usage itself if smoothing is disabled.
+// Caller must hold mu
+func (rm *ResourceMonitor) smooth(componentName string, usage ResourceUsage) ResourceUsage {
+	alpha := rm.config.SmoothingAlpha
+	if alpha <= 0 || alpha >= 1 {
+		return usage
+	}
+	
+	previous, ok := rm.smoothed[componentName]
+	if ok {
+		usage.CPUPercent = alpha*usage.CPUPercent + (1-alpha)*previous.CPUPercent
+		usage.MemoryBytes = uint64(alpha*float64(usage.MemoryBytes) + (1-alpha)*float64(previous.MemoryBytes))
+	}
+	rm.smoothed[componentName] = usage
+	
+	return usage
+}
+
+// sustained records whether a resource of a component is over its threshold
+// in this check, and returns whether it was in the last
+// ThresholdConsecutiveSamples checks. Caller must hold mu
+func (rm *ResourceMonitor) sustained(componentName string, resourceType string, over bool) bool {
+	counts, ok := rm.overThreshold[componentName]
+	if !ok {
+		counts = make(map[string]int)
+		rm.overThreshold[componentName] = counts
 	}
+	
+	if !over {
+		delete(counts, resourceType)
+		return false
+	}
+	
+	counts[resourceType]++
+	return counts[resourceType] >= rm.config.ThresholdConsecutiveSamples
 }
 
 // checkThresholds checks resource usage against thresholds
@@ -230,16 +450,16 @@ func (rm *ResourceMonitor) checkThresholds(componentName string, usage ResourceU
 		return
 	}
 	
-	// Convert memory from bytes to MB for comparison
-	memoryMB := float64(usage.MemoryBytes) / (1024 * 1024)
+	memoryMB := usage.MemoryMB()
+	thresholds := rm.autoTuner.thresholds(componentName, componentConfig)
 	
 	// Check CPU threshold
-	if usage.CPUPercent > componentConfig.MaxCPUPercent {
+	if rm.sustained(componentName, "CPU", usage.CPUPercent > thresholds.MaxCPUPercent) {
 		event := ThresholdExceededEvent{
 			ComponentName: componentName,
 			ResourceType:  "CPU",
 			CurrentValue:  usage.CPUPercent,
-			ThresholdValue: componentConfig.MaxCPUPercent,
+			ThresholdValue: thresholds.MaxCPUPercent,
 			Timestamp:     time.Now(),
 		}
 		
@@ -247,12 +467,12 @@ func (rm *ResourceMonitor) checkThresholds(componentName string, usage ResourceU
 	}
 	
 	// Check memory threshold
-	if memoryMB > float64(componentConfig.MaxMemoryMB) {
+	if rm.sustained(componentName, "Memory", memoryMB > thresholds.MaxMemoryMB) {
 		event := ThresholdExceededEvent{
 			ComponentName: componentName,
 			ResourceType:  "Memory",
 			CurrentValue:  memoryMB,
-			ThresholdValue: float64(componentConfig.MaxMemoryMB),
+			ThresholdValue: thresholds.MaxMemoryMB,
 			Timestamp:     time.Now(),
 		}
 		
@@ -260,7 +480,7 @@ func (rm *ResourceMonitor) checkThresholds(componentName string, usage ResourceU
 	}
 	
 	// Check file descriptor threshold
-	if usage.FileDescriptors > componentConfig.MaxFileDescriptors {
+	if rm.sustained(componentName, "FileDescriptors", usage.FileDescriptors > componentConfig.MaxFileDescriptors) {
 		event := ThresholdExceededEvent{
 			ComponentName: componentName,
 			ResourceType:  "FileDescriptors",
@@ -273,7 +493,7 @@ func (rm *ResourceMonitor) checkThresholds(componentName string, usage ResourceU
 	}
 	
 	// Check goroutine threshold
-	if usage.Goroutines > componentConfig.MaxGoroutines {
+	if rm.sustained(componentName, "Goroutines", usage.Goroutines > componentConfig.MaxGoroutines) {
 		event := ThresholdExceededEvent{
 			ComponentName: componentName,
 			ResourceType:  "Goroutines",
@@ -285,10 +505,58 @@ func (rm *ResourceMonitor) checkThresholds(componentName string, usage ResourceU
 		rm.notifyThresholdExceeded(event)
 	}
 	
+	// Check GC threshold, components thrashing the GC can be degraded
+	if componentConfig.MaxGCPercent > 0 && rm.sustained(componentName, "GC", usage.GCPercent > componentConfig.MaxGCPercent) {
+		event := ThresholdExceededEvent{
+			ComponentName: componentName,
+			ResourceType:  "GC",
+			CurrentValue:  usage.GCPercent,
+			ThresholdValue: componentConfig.MaxGCPercent,
+			Timestamp:     time.Now(),
+		}
+		
+		rm.notifyThresholdExceeded(event)
+	}
+	
+	// Check for a goroutine leak, which may stay below MaxGoroutines for a long time
+	if componentConfig.GoroutineLeakSamples > 0 {
+		slope, rising := goroutineSlope(rm.usageHistory[componentName], componentConfig.GoroutineLeakSamples)
+		if rising && slope > componentConfig.GoroutineLeakSlope {
+			event := ThresholdExceededEvent{
+				ComponentName: componentName,
+				ResourceType:  "GoroutineLeak",
+				CurrentValue:  slope,
+				ThresholdValue: componentConfig.GoroutineLeakSlope,
+				Timestamp:     time.Now(),
+			}
+			
+			rm.notifyThresholdExceeded(event)
+		}
+	}
+	
 	// Check degradation levels
 	rm.checkDegradationLevels(componentName, usage)
 }
 
+// goroutineSlope returns the average increase of the goroutine count per
+// sample over the last samples entries of history, and whether the count
+// rose in every one of them. It returns false if the history is shorter.
+func goroutineSlope(history []ResourceUsage, samples int) (float64, bool) {
+	if samples < 2 || len(history) < samples {
+		return 0, false
+	}
+	
+	recent := history[len(history)-samples:]
+	for i := 1; i < len(recent); i++ {
+		if recent[i].Goroutines <= recent[i-1].Goroutines {
+			return 0, false
+		}
+	}
+	
+	rise := recent[len(recent)-1].Goroutines - recent[0].Goroutines
+	return float64(rise) / float64(samples-1), true
+}
+
 // checkDegradationLevels checks resource usage against degradation levels
 func (rm *ResourceMonitor) checkDegradationLevels(componentName string, usage ResourceUsage) {
 	componentConfig, ok := rm.config.ComponentConfigs[componentName]
@@ -296,8 +564,7 @@ func (rm *ResourceMonitor) checkDegradationLevels(componentName string, usage Re
 		return
 	}
 	
-	// Convert memory from bytes to MB for comparison
-	memoryMB := float64(usage.MemoryBytes) / (1024 * 1024)
+	memoryMB := usage.MemoryMB()
 	
 	// Find the highest applicable degradation level
 	currentLevel := ""
@@ -338,23 +605,53 @@ func (rm *ResourceMonitor) checkDegradationLevels(componentName string, usage Re
 	}
 }
 
-// notifyThresholdExceeded notifies handlers of a threshold exceeded event
+// notifyThresholdExceeded queues a threshold exceeded event for the handlers.
+// Caller must hold mu
 func (rm *ResourceMonitor) notifyThresholdExceeded(event ThresholdExceededEvent) {
-	for _, handler := range rm.handlers {
-		go handler(event)
+	rm.pending = append(rm.pending, event)
+}
+
+// notifyThresholdHandlers calls the handlers for events in order. It must
+// not hold mu, so handlers may call the monitor
+func (rm *ResourceMonitor) notifyThresholdHandlers(events []ThresholdExceededEvent) {
+	if len(events) == 0 {
+		return
+	}
+	
+	rm.mu.RLock()
+	handlers := make([]ThresholdHandler, len(rm.handlers))
+	for i, registered := range rm.handlers {
+		handlers[i] = registered.handler
+	}
+	rm.mu.RUnlock()
+	
+	for _, event := range events {
+		for _, handler := range handlers {
+			if rm.calls == nil {
+				handler(event)
+			} else {
+				// Blocks while all workers are busy
+				rm.calls <- handlerCall{handler: handler, event: event}
+			}
+		}
 	}
 }
 
-// GetTotalResourceUsage returns the total resource usage of the agent
+// GetTotalResourceUsage returns the total resource usage of the agent. The
+// GC percentage covers the time since the previous call, 0 on the first call.
+// The usage includes the monitor's own, which is also reported as the
+// SelfComponentName component when Config.SelfBudget is enabled
 func (rm *ResourceMonitor) GetTotalResourceUsage() ResourceUsage {
 	var memStats runtime.MemStats
 	runtime.ReadMemStats(&memStats)
+	now := time.Now()
 	
 	return ResourceUsage{
 		CPUPercent:      0, // Not available directly
 		MemoryBytes:     memStats.Alloc,
 		FileDescriptors: 0, // Not available directly in Go
 		Goroutines:      runtime.NumGoroutine(),
-		LastUpdated:     time.Now(),
+		GCPercent:       rm.gcPauses.Percent(memStats.PauseTotalNs, now),
+		Timestamp:       now,
 	}
 }
@@ -0,0 +1,69 @@
+package watchdog
+
+import (
+	"sync"
+	"time"
+)
+
+// RestartRateLimiter is a token bucket limiting the restarts of all
+// components together, so a host-wide problem does not make the watchdog
+// restart many components in a tight loop. The bucket holds up to perMinute
+// tokens and refills at perMinute tokens per minute
+type RestartRateLimiter struct {
+	perMinute int
+	tokens    float64
+	last      time.Time // When tokens was last refilled
+	mu        sync.Mutex
+}
+
+// NewRestartRateLimiter creates a full limiter allowing perMinute restarts
+// per minute. A limiter with perMinute 0 allows every restart
+func NewRestartRateLimiter(perMinute int) *RestartRateLimiter {
+	return &RestartRateLimiter{
+		perMinute: perMinute,
+		tokens:    float64(perMinute),
+	}
+}
+
+// Allow takes a token at now and returns true, or returns false if the
+// bucket is empty and the restart must be deferred
+func (l *RestartRateLimiter) Allow(now time.Time) bool {
+	if l.perMinute <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill(now)
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Available returns the number of restarts allowed at now, -1 if restarts
+// are not limited
+func (l *RestartRateLimiter) Available(now time.Time) int {
+	if l.perMinute <= 0 {
+		return -1
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill(now)
+	return int(l.tokens)
+}
+
+// refill adds the tokens earned since the last refill. Caller must hold mu
+func (l *RestartRateLimiter) refill(now time.Time) {
+	if !l.last.IsZero() && now.After(l.last) {
+		earned := now.Sub(l.last).Minutes() * float64(l.perMinute)
+		l.tokens = min(l.tokens+earned, float64(l.perMinute))
+	}
+	if now.After(l.last) {
+		l.last = now
+	}
+}
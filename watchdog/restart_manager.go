@@ -3,6 +3,7 @@ package watchdog
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"sync"
 	"time"
 )
@@ -21,25 +22,50 @@ type RestartManager struct {
 	// lastRestartTime is when the component was last restarted
 	lastRestartTime time.Time
 	
-	// currentBackoff is the current backoff duration
+	// currentBackoff is the backoff before jitter for the next failure
 	currentBackoff time.Duration
 	
+	// nextDelay is the jittered delay to wait after the last failure
+	nextDelay time.Duration
+	
+	// rng draws the jitter, guarded by mutex
+	rng *rand.Rand
+	
 	// mutex protects the manager state
 	mutex sync.RWMutex
 }
 
 // NewRestartManager creates a new restart manager
 func NewRestartManager(config RestartConfig, component Restartable) *RestartManager {
+	return NewRestartManagerWithRand(config, component, rand.New(rand.NewSource(time.Now().UnixNano())))
+}
+
+// NewRestartManagerWithRand creates a new restart manager that draws the
+// backoff jitter from rng, e.g. a fixed seed for deterministic delays
+func NewRestartManagerWithRand(config RestartConfig, component Restartable, rng *rand.Rand) *RestartManager {
 	return &RestartManager{
 		config:          config,
 		component:       component,
 		restartAttempts: 0,
 		currentBackoff:  config.RestartBackoffInitial,
+		rng:             rng,
 	}
 }
 
-// AttemptRestart attempts to restart the component
+// AttemptRestart attempts to restart the component, unless it is running
 func (rm *RestartManager) AttemptRestart(ctx context.Context) (bool, error) {
+	return rm.restart(ctx, false)
+}
+
+// ForceRestart restarts the component even if it is running, e.g. because a
+// component it depends on was restarted
+func (rm *RestartManager) ForceRestart(ctx context.Context) (bool, error) {
+	return rm.restart(ctx, true)
+}
+
+// restart shuts down and starts the component, subject to the restart limits
+// and backoff. A running component is only restarted if force is set
+func (rm *RestartManager) restart(ctx context.Context, force bool) (bool, error) {
 	rm.mutex.Lock()
 	defer rm.mutex.Unlock()
 	
@@ -54,15 +80,15 @@ func (rm *RestartManager) AttemptRestart(ctx context.Context) (bool, error) {
 	}
 	
 	// Check if component is already running
-	if rm.component.IsRunning() {
+	if !force && rm.component.IsRunning() {
 		return true, nil
 	}
 	
 	// Check if we need to wait for backoff
 	if !rm.lastRestartTime.IsZero() {
 		timeElapsed := time.Since(rm.lastRestartTime)
-		if timeElapsed < rm.currentBackoff {
-			return false, fmt.Errorf("backoff in progress, %s remaining", rm.currentBackoff-timeElapsed)
+		if timeElapsed < rm.nextDelay {
+			return false, fmt.Errorf("backoff in progress, %s remaining", rm.nextDelay-timeElapsed)
 		}
 	}
 	
@@ -84,7 +110,8 @@ func (rm *RestartManager) AttemptRestart(ctx context.Context) (bool, error) {
 		rm.restartAttempts++
 		rm.lastRestartTime = time.Now()
 		
-		// Increase backoff duration
+		// Wait the current backoff before the next attempt, then increase it
+		rm.nextDelay = rm.jitter(rm.currentBackoff)
 		rm.currentBackoff = time.Duration(float64(rm.currentBackoff) * rm.config.RestartBackoffFactor)
 		if rm.currentBackoff > rm.config.RestartBackoffMax {
 			rm.currentBackoff = rm.config.RestartBackoffMax
@@ -97,10 +124,50 @@ func (rm *RestartManager) AttemptRestart(ctx context.Context) (bool, error) {
 	rm.restartAttempts = 0
 	rm.lastRestartTime = time.Now()
 	rm.currentBackoff = rm.config.RestartBackoffInitial
+	rm.nextDelay = 0
 	
 	return true, nil
 }
 
+// RestartDue returns whether AttemptRestart, or ForceRestart if force is set,
+// would shut down and start the component now. It is false while the backoff
+// runs, once the restart attempts are exhausted, and without force while the
+// component is running
+func (rm *RestartManager) RestartDue(force bool) bool {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+	
+	if !rm.config.Enabled || rm.restartAttempts >= rm.config.MaxRestartAttempts {
+		return false
+	}
+	
+	if !force && rm.component.IsRunning() {
+		return false
+	}
+	
+	return rm.lastRestartTime.IsZero() || time.Since(rm.lastRestartTime) >= rm.nextDelay
+}
+
+// jitter randomizes a backoff by up to BackoffJitter in either direction.
+// Caller must hold mutex
+func (rm *RestartManager) jitter(backoff time.Duration) time.Duration {
+	if rm.config.BackoffJitter <= 0 {
+		return backoff
+	}
+	
+	offset := rm.config.BackoffJitter * (2*rm.rng.Float64() - 1)
+	return time.Duration(float64(backoff) * (1 + offset))
+}
+
+// GetBackoff returns the delay to wait after the last failed restart before
+// the next attempt, 0 if the last restart succeeded
+func (rm *RestartManager) GetBackoff() time.Duration {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+	
+	return rm.nextDelay
+}
+
 // GetRestartAttempts returns the number of restart attempts
 func (rm *RestartManager) GetRestartAttempts() int {
 	rm.mutex.RLock()
@@ -124,4 +191,5 @@ func (rm *RestartManager) ResetRestartAttempts() {
 	
 	rm.restartAttempts = 0
 	rm.currentBackoff = rm.config.RestartBackoffInitial
+	rm.nextDelay = 0
 }
@@ -0,0 +1,135 @@
+package watchdog
+
+import (
+	"context"
+	"time"
+	"unsafe"
+)
+
+// SelfComponentName is the name under which the resource monitor reports its
+// own resource usage when Config.SelfBudget is enabled
+const SelfComponentName = "watchdog"
+
+// usageBytes is the memory held by one ResourceUsage reading
+const usageBytes = uint64(unsafe.Sizeof(ResourceUsage{}))
+
+// selfComponent reports the resource usage of the resource monitor itself.
+// Its ResourceUsage is only called by checkResources, holding mu
+type selfComponent struct {
+	rm *ResourceMonitor
+}
+
+// Name returns SelfComponentName
+func (s *selfComponent) Name() string {
+	return SelfComponentName
+}
+
+// ResourceUsage returns the usage of the monitor. Caller must hold mu
+func (s *selfComponent) ResourceUsage() ResourceUsage {
+	return s.rm.selfUsage()
+}
+
+// Heartbeat does nothing, the monitor is alive while it checks itself
+func (s *selfComponent) Heartbeat() error {
+	return nil
+}
+
+// Shutdown does nothing, the monitor is stopped with Stop
+func (s *selfComponent) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// Start does nothing, the monitor is started with Start
+func (s *selfComponent) Start() error {
+	return nil
+}
+
+// selfUsage returns the resource usage of the monitor: the CPU share of the
+// interval spent in the last check, the memory held by the usage history and
+// the goroutines of the monitoring loop and handler workers. Caller must hold mu
+func (rm *ResourceMonitor) selfUsage() ResourceUsage {
+	var entries uint64
+	for _, history := range rm.usageHistory {
+		entries += uint64(cap(history))
+	}
+	entries += uint64(len(rm.smoothed))
+
+	goroutines := 1
+	if rm.calls != nil {
+		goroutines += cap(rm.calls)
+	}
+
+	var cpuPercent float64
+	if interval := rm.currentInterval(); interval > 0 {
+		cpuPercent = min(float64(rm.lastCheckDuration)/float64(interval)*100, 100)
+	}
+
+	return ResourceUsage{
+		CPUPercent:  cpuPercent,
+		MemoryBytes: entries * usageBytes,
+		Goroutines:  goroutines,
+		Timestamp:   time.Now(),
+	}
+}
+
+// checkSelfBudget trips the self guard once the usage of the monitor exceeds
+// Config.SelfBudget. The guard sheds non-essential monitoring for the life of
+// the monitor: the history of each component is trimmed to GuardHistoryLen
+// and checks run every GuardMonitoringInterval. Caller must hold mu
+func (rm *ResourceMonitor) checkSelfBudget(now time.Time) {
+	budget := rm.config.SelfBudget
+	if !budget.Enabled || rm.selfGuardTripped {
+		return
+	}
+
+	usage := rm.selfUsage()
+	var events []ThresholdExceededEvent
+	exceeded := func(resourceType string, current, threshold float64) {
+		if threshold > 0 && current > threshold {
+			events = append(events, ThresholdExceededEvent{
+				ComponentName:  SelfComponentName,
+				ResourceType:   resourceType,
+				CurrentValue:   current,
+				ThresholdValue: threshold,
+				Timestamp:      now,
+			})
+		}
+	}
+	exceeded("CPU", usage.CPUPercent, budget.MaxCPUPercent)
+	exceeded("Memory", usage.MemoryMB(), budget.MaxMemoryMB)
+	exceeded("Goroutines", float64(usage.Goroutines), float64(budget.MaxGoroutines))
+	if len(events) == 0 {
+		return
+	}
+
+	rm.selfGuardTripped = true
+	rm.historyMaxLen = budget.GuardHistoryLen
+	for name, history := range rm.usageHistory {
+		// Copy into a new slice so the larger backing array is released
+		trimmed := make([]ResourceUsage, 0, rm.historyMaxLen)
+		trimmed = append(trimmed, history[max(len(history)-rm.historyMaxLen, 0):]...)
+		rm.usageHistory[name] = trimmed
+	}
+
+	for _, event := range events {
+		rm.notifyThresholdExceeded(event)
+	}
+}
+
+// SelfGuardTripped returns whether the monitor exceeded Config.SelfBudget and
+// shed non-essential monitoring
+func (rm *ResourceMonitor) SelfGuardTripped() bool {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	return rm.selfGuardTripped
+}
+
+// currentInterval returns the monitoring interval, GuardMonitoringInterval
+// once the self guard tripped. Caller must hold mu
+func (rm *ResourceMonitor) currentInterval() time.Duration {
+	if rm.selfGuardTripped && rm.config.SelfBudget.GuardMonitoringInterval > 0 {
+		return rm.config.SelfBudget.GuardMonitoringInterval
+	}
+	return rm.config.MonitoringInterval
+}
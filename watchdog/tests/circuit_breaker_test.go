@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"math/rand"
 	"testing"
 	"time"
 
@@ -156,3 +157,318 @@ func TestCircuitBreakerDisabled(t *testing.T) {
 	// State should still be closed
 	assert.Equal(t, watchdog.CircuitClosed, cb.State())
 }
+
+// fakeClock is a manually advanced clock for circuit breaker tests
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newClockedCircuitBreaker(failureThreshold, successThreshold int) (*watchdog.CircuitBreaker, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cb := watchdog.NewCircuitBreakerWithClock("test-component", watchdog.CircuitBreakerConfig{
+		Enabled:                  true,
+		FailureThreshold:         failureThreshold,
+		ResetTimeout:             time.Minute,
+		HalfOpenSuccessThreshold: successThreshold,
+	}, clock.Now)
+	return cb, clock
+}
+
+func TestCircuitBreakerConsecutiveFailures(t *testing.T) {
+	cb, _ := newClockedCircuitBreaker(2, 1)
+	
+	// A success in between resets the failure count
+	cb.RecordFailure()
+	cb.RecordSuccess()
+	cb.RecordFailure()
+	assert.Equal(t, watchdog.CircuitClosed, cb.State())
+	
+	cb.RecordFailure()
+	assert.Equal(t, watchdog.CircuitOpen, cb.State())
+}
+
+func TestCircuitBreakerOpenUntilResetTimeout(t *testing.T) {
+	cb, clock := newClockedCircuitBreaker(1, 1)
+	
+	cb.RecordFailure()
+	assert.Equal(t, clock.Now().Add(time.Minute), cb.Status().OpenUntil)
+	
+	// Results recorded while open do not change the state
+	clock.Advance(59 * time.Second)
+	cb.RecordSuccess()
+	assert.Equal(t, watchdog.CircuitOpen, cb.State())
+	assert.False(t, cb.AllowOperation())
+	
+	clock.Advance(time.Second)
+	assert.Equal(t, watchdog.CircuitHalfOpen, cb.State())
+	assert.True(t, cb.AllowOperation())
+}
+
+func TestCircuitBreakerHalfOpenSuccessThreshold(t *testing.T) {
+	cb, clock := newClockedCircuitBreaker(1, 3)
+	
+	cb.RecordFailure()
+	clock.Advance(time.Minute)
+	
+	// The first success is the probe that moves the circuit to half-open
+	cb.RecordSuccess()
+	assert.Equal(t, watchdog.CircuitHalfOpen, cb.State())
+	assert.Equal(t, 1, cb.Status().SuccessesInHalfOpen)
+	assert.True(t, cb.AllowOperation())
+	
+	cb.RecordSuccess()
+	assert.Equal(t, watchdog.CircuitHalfOpen, cb.State())
+	assert.Equal(t, 2, cb.Status().SuccessesInHalfOpen)
+	
+	cb.RecordSuccess()
+	assert.Equal(t, watchdog.CircuitClosed, cb.State())
+	assert.Equal(t, 0, cb.Status().Failures)
+}
+
+func TestCircuitBreakerHalfOpenFailureReopens(t *testing.T) {
+	cb, clock := newClockedCircuitBreaker(1, 2)
+	
+	var changes []watchdog.CircuitState
+	cb.AddStateChangeListener(func(name string, oldState, newState watchdog.CircuitState) {
+		changes = append(changes, newState)
+	})
+	
+	cb.RecordFailure()
+	clock.Advance(time.Minute)
+	cb.RecordSuccess()
+	assert.Equal(t, watchdog.CircuitHalfOpen, cb.State())
+	
+	// A failure reopens the circuit and restarts the timeout
+	clock.Advance(10 * time.Second)
+	cb.RecordFailure()
+	assert.Equal(t, watchdog.CircuitOpen, cb.State())
+	assert.Equal(t, clock.Now().Add(time.Minute), cb.Status().OpenUntil)
+	
+	clock.Advance(59 * time.Second)
+	assert.Equal(t, watchdog.CircuitOpen, cb.State())
+	
+	// A failing probe reopens it right away
+	clock.Advance(time.Second)
+	cb.RecordFailure()
+	assert.Equal(t, watchdog.CircuitOpen, cb.State())
+	
+	assert.Equal(t, []watchdog.CircuitState{
+		watchdog.CircuitOpen,
+		watchdog.CircuitHalfOpen,
+		watchdog.CircuitOpen,
+		watchdog.CircuitHalfOpen,
+		watchdog.CircuitOpen,
+	}, changes)
+}
+
+func TestCircuitBreakerResetJitter(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	config := watchdog.CircuitBreakerConfig{
+		Enabled:                  true,
+		FailureThreshold:         1,
+		ResetTimeout:             time.Minute,
+		HalfOpenSuccessThreshold: 1,
+		ResetJitter:              0.5,
+	}
+	
+	// Each open period is within ResetTimeout +/- 50%, and they differ
+	timeouts := make(map[time.Duration]bool)
+	for seed := int64(0); seed < 10; seed++ {
+		cb := watchdog.NewCircuitBreakerWithClockAndRand("test-component", config, clock.Now, rand.New(rand.NewSource(seed)))
+		cb.RecordFailure()
+		
+		timeout := cb.Status().OpenUntil.Sub(clock.Now())
+		assert.GreaterOrEqual(t, timeout, 30*time.Second)
+		assert.LessOrEqual(t, timeout, 90*time.Second)
+		timeouts[timeout] = true
+	}
+	assert.Greater(t, len(timeouts), 1)
+	
+	// The same seed gives the same timeout
+	first := watchdog.NewCircuitBreakerWithClockAndRand("test-component", config, clock.Now, rand.New(rand.NewSource(1)))
+	second := watchdog.NewCircuitBreakerWithClockAndRand("test-component", config, clock.Now, rand.New(rand.NewSource(1)))
+	first.RecordFailure()
+	second.RecordFailure()
+	assert.Equal(t, first.Status().OpenUntil, second.Status().OpenUntil)
+}
+
+func TestCircuitBreakerMaxOpenDuration(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cb := watchdog.NewCircuitBreakerWithClockAndRand("test-component", watchdog.CircuitBreakerConfig{
+		Enabled:                  true,
+		FailureThreshold:         1,
+		ResetTimeout:             time.Hour,
+		HalfOpenSuccessThreshold: 1,
+		ResetJitter:              1,
+		MaxOpenDuration:          10 * time.Minute,
+	}, clock.Now, rand.New(rand.NewSource(1)))
+	
+	// The reset timeout is capped at MaxOpenDuration
+	cb.RecordFailure()
+	assert.Equal(t, clock.Now().Add(10*time.Minute), cb.Status().OpenUntil)
+	
+	clock.Advance(10 * time.Minute)
+	assert.Equal(t, watchdog.CircuitHalfOpen, cb.State())
+	
+	// A forced open circuit is probed after MaxOpenDuration too
+	cb.ForceOpen()
+	clock.Advance(9 * time.Minute)
+	cb.RecordSuccess()
+	assert.Equal(t, watchdog.CircuitOpen, cb.State())
+	assert.True(t, cb.Status().Forced)
+	
+	clock.Advance(time.Minute)
+	assert.Equal(t, watchdog.CircuitHalfOpen, cb.State())
+	assert.True(t, cb.AllowOperation())
+	assert.False(t, cb.Status().Forced)
+	
+	cb.RecordSuccess()
+	assert.Equal(t, watchdog.CircuitClosed, cb.State())
+}
+
+func TestCircuitBreakerForceOpen(t *testing.T) {
+	cb, clock := newClockedCircuitBreaker(1, 1)
+	
+	var changes []watchdog.CircuitState
+	cb.AddStateChangeListener(func(name string, oldState, newState watchdog.CircuitState) {
+		changes = append(changes, newState)
+	})
+	
+	cb.ForceOpen()
+	assert.Equal(t, watchdog.CircuitOpen, cb.State())
+	assert.False(t, cb.AllowOperation())
+	assert.True(t, cb.Status().Forced)
+	
+	// Without MaxOpenDuration it stays open, whatever is recorded
+	clock.Advance(24 * time.Hour)
+	cb.RecordSuccess()
+	assert.Equal(t, watchdog.CircuitOpen, cb.State())
+	assert.False(t, cb.AllowOperation())
+	
+	cb.Reset()
+	assert.Equal(t, watchdog.CircuitClosed, cb.State())
+	assert.False(t, cb.Status().Forced)
+	assert.Equal(t, []watchdog.CircuitState{watchdog.CircuitOpen, watchdog.CircuitClosed}, changes)
+}
+
+func TestCircuitBreakerForceClose(t *testing.T) {
+	cb, _ := newClockedCircuitBreaker(1, 1)
+	
+	cb.RecordFailure()
+	assert.Equal(t, watchdog.CircuitOpen, cb.State())
+	
+	// Failures are ignored while forced closed
+	cb.ForceClose()
+	cb.RecordFailure()
+	cb.RecordFailure()
+	assert.Equal(t, watchdog.CircuitClosed, cb.State())
+	assert.True(t, cb.AllowOperation())
+	assert.True(t, cb.Status().Forced)
+	
+	// Forcing it open replaces the override
+	cb.ForceOpen()
+	assert.Equal(t, watchdog.CircuitOpen, cb.State())
+	
+	// After a reset failures count again
+	cb.Reset()
+	cb.RecordFailure()
+	assert.Equal(t, watchdog.CircuitOpen, cb.State())
+}
+
+func newWindowedCircuitBreaker(failureThreshold int, window time.Duration, rateThreshold float64) (*watchdog.CircuitBreaker, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cb := watchdog.NewCircuitBreakerWithClock("test-component", watchdog.CircuitBreakerConfig{
+		Enabled:                  true,
+		FailureThreshold:         failureThreshold,
+		FailureWindow:            window,
+		FailureRateThreshold:     rateThreshold,
+		ResetTimeout:             time.Minute,
+		HalfOpenSuccessThreshold: 1,
+	}, clock.Now)
+	return cb, clock
+}
+
+// recordIntermittent records 9 failures, 1 success and 9 more failures, a
+// second apart
+func recordIntermittent(cb *watchdog.CircuitBreaker, clock *fakeClock) {
+	for i := 0; i < 19; i++ {
+		if i == 9 {
+			cb.RecordSuccess()
+		} else {
+			cb.RecordFailure()
+		}
+		clock.Advance(time.Second)
+	}
+}
+
+func TestCircuitBreakerConsecutiveModeMissesIntermittentFailures(t *testing.T) {
+	cb, clock := newClockedCircuitBreaker(10, 1)
+	
+	recordIntermittent(cb, clock)
+	assert.Equal(t, watchdog.CircuitClosed, cb.State())
+	assert.Equal(t, 9, cb.Status().Failures)
+}
+
+func TestCircuitBreakerFailureWindow(t *testing.T) {
+	cb, clock := newWindowedCircuitBreaker(10, time.Minute, 0.5)
+	
+	// The 10th failure opens it, at a failure rate of 10/11
+	recordIntermittent(cb, clock)
+	assert.Equal(t, watchdog.CircuitOpen, cb.State())
+	assert.Equal(t, 10, cb.Status().Failures)
+}
+
+func TestCircuitBreakerFailureWindowRate(t *testing.T) {
+	cb, clock := newWindowedCircuitBreaker(3, time.Minute, 0.5)
+	
+	// 4 failures out of 10 results do not exceed the rate
+	for i := 0; i < 10; i++ {
+		if i%5 >= 3 {
+			cb.RecordFailure()
+		} else {
+			cb.RecordSuccess()
+		}
+		clock.Advance(time.Second)
+	}
+	assert.Equal(t, watchdog.CircuitClosed, cb.State())
+	assert.Equal(t, 4, cb.Status().Failures)
+	
+	// Once the successes leave the window, the failures make up most of it
+	clock.Advance(55 * time.Second)
+	cb.RecordFailure()
+	assert.Equal(t, watchdog.CircuitOpen, cb.State())
+}
+
+func TestCircuitBreakerFailureWindowExpiry(t *testing.T) {
+	cb, clock := newWindowedCircuitBreaker(3, time.Minute, 0.5)
+	
+	// Failures spread over more than the window never reach the minimum
+	for i := 0; i < 10; i++ {
+		cb.RecordFailure()
+		clock.Advance(31 * time.Second)
+	}
+	assert.Equal(t, watchdog.CircuitClosed, cb.State())
+	assert.Equal(t, 2, cb.Status().Failures)
+	
+	// A burst within the window opens it
+	cb.RecordFailure()
+	cb.RecordFailure()
+	assert.Equal(t, watchdog.CircuitOpen, cb.State())
+	
+	// After closing again, failures recorded before opening are forgotten
+	clock.Advance(time.Minute)
+	cb.RecordSuccess()
+	assert.Equal(t, watchdog.CircuitClosed, cb.State())
+	cb.RecordFailure()
+	cb.RecordFailure()
+	assert.Equal(t, watchdog.CircuitClosed, cb.State())
+	assert.Equal(t, 2, cb.Status().Failures)
+}
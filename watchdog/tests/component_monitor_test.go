@@ -54,9 +54,9 @@ func TestUpdateResourceUsage(t *testing.T) {
 	// Update resource usage below thresholds
 	now := time.Now()
 	usage := watchdog.ResourceUsage{
-		CPU:          50.0,
-		Memory:       100.0,
-		Threads:      10,
+		CPUPercent:   50.0,
+		MemoryBytes:  100 * 1024 * 1024,
+		Goroutines:   10,
 		IOReadBytes:  1024,
 		IOWriteBytes: 2048,
 		Timestamp:    now,
@@ -74,9 +74,9 @@ func TestUpdateResourceUsage(t *testing.T) {
 	
 	// Update resource usage above thresholds
 	usage = watchdog.ResourceUsage{
-		CPU:          90.0, // > 80.0 threshold
-		Memory:       250.0, // > 200.0 threshold
-		Threads:      20,
+		CPUPercent:   90.0, // > 80.0 threshold
+		MemoryBytes:  250 * 1024 * 1024, // > 200.0 threshold
+		Goroutines:   20,
 		IOReadBytes:  2048,
 		IOWriteBytes: 4096,
 		Timestamp:    now.Add(1 * time.Second),
@@ -153,9 +153,9 @@ func TestAverageUsage(t *testing.T) {
 	
 	// First sample at t=0
 	monitor.UpdateResourceUsage(watchdog.ResourceUsage{
-		CPU:          10.0,
-		Memory:       50.0,
-		Threads:      5,
+		CPUPercent:   10.0,
+		MemoryBytes:  50 * 1024 * 1024,
+		Goroutines:   5,
 		IOReadBytes:  1000,
 		IOWriteBytes: 2000,
 		Timestamp:    now,
@@ -163,9 +163,9 @@ func TestAverageUsage(t *testing.T) {
 	
 	// Second sample at t=1s
 	monitor.UpdateResourceUsage(watchdog.ResourceUsage{
-		CPU:          20.0,
-		Memory:       60.0,
-		Threads:      6,
+		CPUPercent:   20.0,
+		MemoryBytes:  60 * 1024 * 1024,
+		Goroutines:   6,
 		IOReadBytes:  1200,
 		IOWriteBytes: 2200,
 		Timestamp:    now.Add(1 * time.Second),
@@ -173,9 +173,9 @@ func TestAverageUsage(t *testing.T) {
 	
 	// Third sample at t=2s
 	monitor.UpdateResourceUsage(watchdog.ResourceUsage{
-		CPU:          30.0,
-		Memory:       70.0,
-		Threads:      7,
+		CPUPercent:   30.0,
+		MemoryBytes:  70 * 1024 * 1024,
+		Goroutines:   7,
 		IOReadBytes:  1400,
 		IOWriteBytes: 2400,
 		Timestamp:    now.Add(2 * time.Second),
@@ -183,9 +183,9 @@ func TestAverageUsage(t *testing.T) {
 	
 	// Fourth sample (current) at t=3s
 	currentUsage := watchdog.ResourceUsage{
-		CPU:          40.0,
-		Memory:       80.0,
-		Threads:      8,
+		CPUPercent:   40.0,
+		MemoryBytes:  80 * 1024 * 1024,
+		Goroutines:   8,
 		IOReadBytes:  1600,
 		IOWriteBytes: 2600,
 		Timestamp:    now.Add(3 * time.Second),
@@ -197,9 +197,9 @@ func TestAverageUsage(t *testing.T) {
 	
 	// Average should be weighted toward the recent values
 	// Should be approximately average of the last 2 samples plus current
-	assert.InDelta(t, 35.0, avgUsage.CPU, 5.0)          // ~(30+40)/2
-	assert.InDelta(t, 75.0, avgUsage.Memory, 5.0)       // ~(70+80)/2
-	assert.InDelta(t, 7.5, float64(avgUsage.Threads), 1.0) // ~(7+8)/2
+	assert.InDelta(t, 35.0, avgUsage.CPUPercent, 5.0)          // ~(30+40)/2
+	assert.InDelta(t, 75.0, avgUsage.MemoryMB(), 5.0)       // ~(70+80)/2
+	assert.InDelta(t, 7.5, float64(avgUsage.Goroutines), 1.0) // ~(7+8)/2
 }
 
 // TestThresholdViolationDuration tests measuring the duration of threshold violations
@@ -229,9 +229,9 @@ func TestThresholdViolationDuration(t *testing.T) {
 	// Update with a threshold violation
 	now := time.Now()
 	monitor.UpdateResourceUsage(watchdog.ResourceUsage{
-		CPU:          90.0, // > 80.0 threshold
-		Memory:       100.0,
-		Threads:      10,
+		CPUPercent:   90.0, // > 80.0 threshold
+		MemoryBytes:  100 * 1024 * 1024,
+		Goroutines:   10,
 		Timestamp:    now,
 	})
 	
@@ -244,9 +244,9 @@ func TestThresholdViolationDuration(t *testing.T) {
 	
 	// Update again with violation still occurring
 	monitor.UpdateResourceUsage(watchdog.ResourceUsage{
-		CPU:          95.0, // still > 80.0 threshold
-		Memory:       100.0,
-		Threads:      10,
+		CPUPercent:   95.0, // still > 80.0 threshold
+		MemoryBytes:  100 * 1024 * 1024,
+		Goroutines:   10,
 		Timestamp:    now.Add(100 * time.Millisecond),
 	})
 	
@@ -262,9 +262,9 @@ func TestThresholdViolationDuration(t *testing.T) {
 	
 	// Update with values below threshold
 	monitor.UpdateResourceUsage(watchdog.ResourceUsage{
-		CPU:          70.0, // < 80.0 threshold
-		Memory:       100.0,
-		Threads:      10,
+		CPUPercent:   70.0, // < 80.0 threshold
+		MemoryBytes:  100 * 1024 * 1024,
+		Goroutines:   10,
 		Timestamp:    now.Add(200 * time.Millisecond),
 	})
 	
@@ -296,9 +296,9 @@ func TestMultipleResourceThresholds(t *testing.T) {
 	// Update with multiple threshold violations
 	now := time.Now()
 	monitor.UpdateResourceUsage(watchdog.ResourceUsage{
-		CPU:          90.0, // > 80.0 CPU threshold
-		Memory:       250.0, // > 200.0 Memory threshold
-		Threads:      10,
+		CPUPercent:   90.0, // > 80.0 CPU threshold
+		MemoryBytes:  250 * 1024 * 1024, // > 200.0 Memory threshold
+		Goroutines:   10,
 		Timestamp:    now,
 	})
 	
@@ -308,9 +308,9 @@ func TestMultipleResourceThresholds(t *testing.T) {
 	
 	// Fix just the CPU threshold
 	monitor.UpdateResourceUsage(watchdog.ResourceUsage{
-		CPU:          70.0, // < 80.0 CPU threshold
-		Memory:       250.0, // still > 200.0 Memory threshold
-		Threads:      10,
+		CPUPercent:   70.0, // < 80.0 CPU threshold
+		MemoryBytes:  250 * 1024 * 1024, // still > 200.0 Memory threshold
+		Goroutines:   10,
 		Timestamp:    now.Add(100 * time.Millisecond),
 	})
 	
@@ -320,9 +320,9 @@ func TestMultipleResourceThresholds(t *testing.T) {
 	
 	// Fix the memory threshold
 	monitor.UpdateResourceUsage(watchdog.ResourceUsage{
-		CPU:          70.0,
-		Memory:       150.0, // < 200.0 Memory threshold
-		Threads:      10,
+		CPUPercent:   70.0,
+		MemoryBytes:  150 * 1024 * 1024, // < 200.0 Memory threshold
+		Goroutines:   10,
 		Timestamp:    now.Add(200 * time.Millisecond),
 	})
 	
@@ -33,10 +33,18 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, 0.5, sampler.MaxCPUPercent)
 	assert.Equal(t, 50, sampler.MaxMemoryMB)
 	
+	// Verify global budget config
+	assert.Equal(t, 0.75, config.GlobalBudget.MaxCPUPercent)
+	assert.Equal(t, 30, config.GlobalBudget.MaxMemoryMB)
+	assert.Equal(t, 10*time.Minute, config.SustainedDegradationDuration)
+	assert.Equal(t, 10, config.MaxRestartsPerMinute)
+	assert.Equal(t, 60, config.MeasurementHistoryLen)
+	
 	// Verify deadlock detection config
 	assert.True(t, config.DeadlockDetection.Enabled)
 	assert.Equal(t, 5*time.Second, config.DeadlockDetection.HeartbeatInterval)
 	assert.Equal(t, 3, config.DeadlockDetection.HeartbeatMissThreshold)
+	assert.Equal(t, 64*1024, config.DeadlockDetection.MaxStackTraceBytes)
 	
 	// Verify restart policy config
 	assert.True(t, config.RestartPolicy.Enabled)
@@ -47,6 +55,10 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, "normal", config.DiagnosticCollection.DetailLevel)
 	assert.Equal(t, 100, config.DiagnosticCollection.MaxEvents)
 	assert.True(t, config.DiagnosticCollection.IncludeStackTraces)
+	
+	// Verify self budget config
+	assert.False(t, config.SelfBudget.Enabled)
+	assert.Equal(t, 5, config.SelfBudget.GuardHistoryLen)
 }
 
 func TestConfigValidation(t *testing.T) {
@@ -74,6 +86,91 @@ func TestConfigValidation(t *testing.T) {
 			},
 			shouldFail: true,
 		},
+		{
+			name: "negative global budget CPU",
+			modifyConfig: func(c *watchdog.Config) {
+				c.GlobalBudget.MaxCPUPercent = -1
+			},
+			shouldFail: true,
+		},
+		{
+			name: "negative global budget memory",
+			modifyConfig: func(c *watchdog.Config) {
+				c.GlobalBudget.MaxMemoryMB = -1
+			},
+			shouldFail: true,
+		},
+		{
+			name: "disabled global budget",
+			modifyConfig: func(c *watchdog.Config) {
+				c.GlobalBudget = watchdog.GlobalBudgetConfig{}
+			},
+			shouldFail: false,
+		},
+		{
+			name: "negative sustained degradation duration",
+			modifyConfig: func(c *watchdog.Config) {
+				c.SustainedDegradationDuration = -1 * time.Minute
+			},
+			shouldFail: true,
+		},
+		{
+			name: "negative max restarts per minute",
+			modifyConfig: func(c *watchdog.Config) {
+				c.MaxRestartsPerMinute = -1
+			},
+			shouldFail: true,
+		},
+		{
+			name: "negative measurement history length",
+			modifyConfig: func(c *watchdog.Config) {
+				c.MeasurementHistoryLen = -1
+			},
+			shouldFail: true,
+		},
+		{
+			name: "invalid auto-tune percentile",
+			modifyConfig: func(c *watchdog.Config) {
+				c.AutoTune.Enabled = true
+				c.AutoTune.Percentile = 99
+			},
+			shouldFail: true,
+		},
+		{
+			name: "valid auto-tune",
+			modifyConfig: func(c *watchdog.Config) {
+				c.AutoTune.Enabled = true
+			},
+			shouldFail: false,
+		},
+		{
+			name: "invalid smoothing alpha",
+			modifyConfig: func(c *watchdog.Config) {
+				c.SmoothingAlpha = 1.5
+			},
+			shouldFail: true,
+		},
+		{
+			name: "negative threshold consecutive samples",
+			modifyConfig: func(c *watchdog.Config) {
+				c.ThresholdConsecutiveSamples = -1
+			},
+			shouldFail: true,
+		},
+		{
+			name: "invalid threshold handler mode",
+			modifyConfig: func(c *watchdog.Config) {
+				c.ThresholdHandlerMode = "parallel"
+			},
+			shouldFail: true,
+		},
+		{
+			name: "negative threshold handler workers",
+			modifyConfig: func(c *watchdog.Config) {
+				c.ThresholdHandlerWorkers = -1
+			},
+			shouldFail: true,
+		},
 		{
 			name: "no component configs",
 			modifyConfig: func(c *watchdog.Config) {
@@ -117,6 +214,71 @@ func TestConfigValidation(t *testing.T) {
 			},
 			shouldFail: true,
 		},
+		{
+			name: "invalid circuit breaker failure window",
+			modifyConfig: func(c *watchdog.Config) {
+				config := c.ComponentConfigs["collector"]
+				config.CircuitBreaker.FailureWindow = -1 * time.Second
+				c.ComponentConfigs["collector"] = config
+			},
+			shouldFail: true,
+		},
+		{
+			name: "invalid circuit breaker failure rate threshold",
+			modifyConfig: func(c *watchdog.Config) {
+				config := c.ComponentConfigs["collector"]
+				config.CircuitBreaker.FailureWindow = time.Minute
+				config.CircuitBreaker.FailureRateThreshold = 1
+				c.ComponentConfigs["collector"] = config
+			},
+			shouldFail: true,
+		},
+		{
+			name: "invalid circuit breaker reset jitter",
+			modifyConfig: func(c *watchdog.Config) {
+				config := c.ComponentConfigs["collector"]
+				config.CircuitBreaker.ResetJitter = 1.5
+				c.ComponentConfigs["collector"] = config
+			},
+			shouldFail: true,
+		},
+		{
+			name: "invalid circuit breaker max open duration",
+			modifyConfig: func(c *watchdog.Config) {
+				config := c.ComponentConfigs["collector"]
+				config.CircuitBreaker.MaxOpenDuration = -1 * time.Second
+				c.ComponentConfigs["collector"] = config
+			},
+			shouldFail: true,
+		},
+		{
+			name: "invalid max GC percent",
+			modifyConfig: func(c *watchdog.Config) {
+				config := c.ComponentConfigs["collector"]
+				config.MaxGCPercent = 150
+				c.ComponentConfigs["collector"] = config
+			},
+			shouldFail: true,
+		},
+		{
+			name: "invalid goroutine leak samples",
+			modifyConfig: func(c *watchdog.Config) {
+				config := c.ComponentConfigs["collector"]
+				config.GoroutineLeakSamples = 1
+				config.GoroutineLeakSlope = 1
+				c.ComponentConfigs["collector"] = config
+			},
+			shouldFail: true,
+		},
+		{
+			name: "invalid goroutine leak slope",
+			modifyConfig: func(c *watchdog.Config) {
+				config := c.ComponentConfigs["collector"]
+				config.GoroutineLeakSamples = 5
+				c.ComponentConfigs["collector"] = config
+			},
+			shouldFail: true,
+		},
 		{
 			name: "invalid heartbeat interval",
 			modifyConfig: func(c *watchdog.Config) {
@@ -138,6 +300,13 @@ func TestConfigValidation(t *testing.T) {
 			},
 			shouldFail: true,
 		},
+		{
+			name: "invalid max stack trace bytes",
+			modifyConfig: func(c *watchdog.Config) {
+				c.DeadlockDetection.MaxStackTraceBytes = -1
+			},
+			shouldFail: true,
+		},
 		{
 			name: "invalid graceful shutdown timeout",
 			modifyConfig: func(c *watchdog.Config) {
@@ -159,6 +328,13 @@ func TestConfigValidation(t *testing.T) {
 			},
 			shouldFail: true,
 		},
+		{
+			name: "invalid backoff jitter",
+			modifyConfig: func(c *watchdog.Config) {
+				c.RestartPolicy.BackoffJitter = 1.5
+			},
+			shouldFail: true,
+		},
 		{
 			name: "invalid max events",
 			modifyConfig: func(c *watchdog.Config) {
@@ -166,6 +342,30 @@ func TestConfigValidation(t *testing.T) {
 			},
 			shouldFail: true,
 		},
+		{
+			name: "invalid incident store max files",
+			modifyConfig: func(c *watchdog.Config) {
+				c.IncidentStore.Enabled = true
+				c.IncidentStore.MaxFiles = 0
+			},
+			shouldFail: true,
+		},
+		{
+			name: "invalid self budget CPU",
+			modifyConfig: func(c *watchdog.Config) {
+				c.SelfBudget.Enabled = true
+				c.SelfBudget.MaxCPUPercent = 150
+			},
+			shouldFail: true,
+		},
+		{
+			name: "invalid self budget guard history length",
+			modifyConfig: func(c *watchdog.Config) {
+				c.SelfBudget.Enabled = true
+				c.SelfBudget.GuardHistoryLen = 0
+			},
+			shouldFail: true,
+		},
 		{
 			name: "valid custom config",
 			modifyConfig: func(c *watchdog.Config) {
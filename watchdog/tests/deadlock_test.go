@@ -1,121 +1,191 @@
 package tests
 
 import (
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/newrelic/infrastructure-agent/watchdog"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
-// MockComponentMonitor mocks the ComponentMonitor for testing
-type MockComponentMonitor struct {
-	mock.Mock
-}
-
-// AddDeadlockDetectedHandler implements the interface
-func (m *MockComponentMonitor) AddDeadlockDetectedHandler(handler func(string, watchdog.ComponentMetrics)) {
-	m.Called(handler)
+// newClockedDeadlockDetector creates a detector expecting a heartbeat every
+// second and reporting after 3 missed ones
+func newClockedDeadlockDetector(t *testing.T, stackTraces bool) (*watchdog.DeadlockDetector, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	detector, err := watchdog.NewDeadlockDetectorWithClock(watchdog.DeadlockConfig{
+		Enabled:                true,
+		HeartbeatInterval:      time.Second,
+		HeartbeatMissThreshold: 3,
+		StackTraceEnabled:      stackTraces,
+	}, clock.Now)
+	require.NoError(t, err)
+	return detector, clock
 }
 
 // TestDeadlockDetectorCreation tests creating a deadlock detector
 func TestDeadlockDetectorCreation(t *testing.T) {
-	config := watchdog.Config{
-		DeadlockDetection: watchdog.DeadlockConfig{
-			Enabled:              true,
-			CheckInterval:        10 * time.Second,
-			GoroutineThreshold:   100,
-		},
-	}
-	
-	monitor := new(MockComponentMonitor)
-	monitor.On("AddDeadlockDetectedHandler", mock.Anything).Return()
-	
-	detector := watchdog.NewDeadlockDetector(config, monitor)
+	detector, err := watchdog.NewDeadlockDetector(watchdog.DeadlockConfig{
+		Enabled:                true,
+		HeartbeatInterval:      time.Second,
+		HeartbeatMissThreshold: 3,
+	})
+	assert.NoError(t, err)
 	assert.NotNil(t, detector)
 	
-	monitor.AssertExpectations(t)
+	// Heartbeats are required to detect missed ones
+	_, err = watchdog.NewDeadlockDetector(watchdog.DeadlockConfig{
+		Enabled:                true,
+		HeartbeatMissThreshold: 3,
+	})
+	assert.Error(t, err)
+	
+	_, err = watchdog.NewDeadlockDetector(watchdog.DeadlockConfig{
+		Enabled:           true,
+		HeartbeatInterval: time.Second,
+	})
+	assert.Error(t, err)
 }
 
-// TestDeadlockDetection tests detecting a deadlock
+// TestDeadlockDetection tests detecting a component that missed heartbeats
 func TestDeadlockDetection(t *testing.T) {
-	config := watchdog.Config{
-		DeadlockDetection: watchdog.DeadlockConfig{
-			Enabled:              true,
-			CheckInterval:        10 * time.Second,
-			GoroutineThreshold:   100,
-		},
-	}
+	detector, clock := newClockedDeadlockDetector(t, true)
+	detector.RegisterComponent("test-component")
 	
-	monitor := new(MockComponentMonitor)
+	// Up to HeartbeatInterval * HeartbeatMissThreshold of silence is fine
+	clock.Advance(3 * time.Second)
+	assert.Empty(t, detector.DetectDeadlocks())
 	
-	// Capture the handler function when it's registered
-	var deadlockHandler func(string, watchdog.ComponentMetrics)
-	monitor.On("AddDeadlockDetectedHandler", mock.Anything).Run(func(args mock.Arguments) {
-		deadlockHandler = args.Get(0).(func(string, watchdog.ComponentMetrics))
-	}).Return()
-	
-	detector := watchdog.NewDeadlockDetector(config, monitor)
-	assert.NotNil(t, detector)
-	
-	// Make sure the handler was captured
-	assert.NotNil(t, deadlockHandler)
-	
-	// Simulate a deadlock by calling the handler
-	metrics := watchdog.ComponentMetrics{
-		LastResponseTime: 30 * time.Second,
-		GoroutineCount:   200,
-		State:            "blocked",
-		HealthStatus:     "degraded",
-	}
-	
-	deadlockHandler("test-component", metrics)
-	
-	// Check that the deadlock was detected
-	deadlocks := detector.GetDetectedDeadlocks()
-	assert.Len(t, deadlocks, 1)
-	assert.Contains(t, deadlocks, "test-component")
+	clock.Advance(time.Millisecond)
+	deadlocks := detector.DetectDeadlocks()
+	require.Len(t, deadlocks, 1)
+	assert.Equal(t, "test-component", deadlocks[0].ComponentName)
+	assert.Contains(t, deadlocks[0].Description, "no heartbeat for 3.001s")
+	assert.NotEmpty(t, deadlocks[0].Remediation)
+	assert.Contains(t, deadlocks[0].StackTrace, "goroutine")
 	
 	// Verify deadlock info
-	deadlock := deadlocks["test-component"]
-	assert.Equal(t, "test-component", deadlock.ComponentName)
-	assert.Equal(t, 30*time.Second, deadlock.LastResponseTime)
-	assert.NotEmpty(t, deadlock.GoroutineStacks)
-	assert.NotNil(t, deadlock.AdditionalInfo)
-	assert.Equal(t, "blocked", deadlock.AdditionalInfo["state"])
-	assert.Equal(t, "degraded", deadlock.AdditionalInfo["health"])
+	info := detector.GetDetectedDeadlocks()["test-component"]
+	assert.Equal(t, clock.Now(), info.DetectedAt)
+	assert.Equal(t, 3*time.Second+time.Millisecond, info.LastResponseTime)
+	assert.Contains(t, info.GoroutineStacks, "goroutine")
+	assert.Equal(t, "3", info.AdditionalInfo["missed"])
+	
+	// A deadlock is reported once
+	clock.Advance(time.Second)
+	assert.Empty(t, detector.DetectDeadlocks())
+	assert.True(t, detector.HasDeadlock("test-component"))
 }
 
-// TestClearDeadlock tests clearing a detected deadlock
-func TestClearDeadlock(t *testing.T) {
-	config := watchdog.Config{
-		DeadlockDetection: watchdog.DeadlockConfig{
-			Enabled:              true,
-			CheckInterval:        10 * time.Second,
-			GoroutineThreshold:   100,
-		},
+// TestDeadlockStackTraceDisabled tests that stacks are only captured when enabled
+func TestDeadlockStackTraceDisabled(t *testing.T) {
+	detector, clock := newClockedDeadlockDetector(t, false)
+	detector.RegisterComponent("test-component")
+	
+	clock.Advance(4 * time.Second)
+	deadlocks := detector.DetectDeadlocks()
+	require.Len(t, deadlocks, 1)
+	assert.Empty(t, deadlocks[0].StackTrace)
+	assert.Empty(t, detector.GetDetectedDeadlocks()["test-component"].GoroutineStacks)
+}
+
+// TestCaptureStackTrace tests trimming captured stack traces
+func TestCaptureStackTrace(t *testing.T) {
+	full := watchdog.CaptureStackTrace(0)
+	assert.Contains(t, full, "TestCaptureStackTrace")
+	assert.NotContains(t, full, "stack trace truncated")
+	
+	trimmed := watchdog.CaptureStackTrace(64)
+	assert.True(t, strings.HasPrefix(trimmed, full[:64]))
+	assert.True(t, strings.HasSuffix(trimmed, "\n... stack trace truncated"))
+}
+
+// TestOperationTimeout tests detecting an operation that never completes
+func TestOperationTimeout(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	detector, err := watchdog.NewDeadlockDetectorWithClock(watchdog.DeadlockConfig{
+		Enabled:                true,
+		HeartbeatInterval:      time.Second,
+		HeartbeatMissThreshold: 3,
+		MaxOperationTime:       5 * time.Second,
+	}, clock.Now)
+	require.NoError(t, err)
+	detector.RegisterComponent("test-component")
+	
+	hung := detector.TrackOperation("test-component", "flush")
+	done := detector.TrackOperation("test-component", "scan")
+	
+	// Completed operations are not reported, heartbeats keep the component alive
+	clock.Advance(2 * time.Second)
+	done()
+	detector.Heartbeat("test-component")
+	clock.Advance(2 * time.Second)
+	detector.Heartbeat("test-component")
+	clock.Advance(time.Second)
+	assert.Empty(t, detector.DetectDeadlocks())
+	
+	clock.Advance(time.Millisecond)
+	deadlocks := detector.DetectDeadlocks()
+	require.Len(t, deadlocks, 1)
+	assert.Equal(t, "test-component", deadlocks[0].ComponentName)
+	assert.Contains(t, deadlocks[0].Description, "operation flush running for 5.001s")
+	assert.Contains(t, deadlocks[0].Remediation, "flush")
+	
+	// An operation is reported once, and is forgotten when it completes
+	detector.Heartbeat("test-component")
+	assert.Empty(t, detector.DetectDeadlocks())
+	hung()
+	hung = detector.TrackOperation("test-component", "flush")
+	clock.Advance(6 * time.Second)
+	detector.Heartbeat("test-component")
+	require.Len(t, detector.DetectDeadlocks(), 1)
+	
+	// Unregistering a component forgets its operations
+	detector.TrackOperation("test-component", "compact")
+	detector.UnregisterComponent("test-component")
+	clock.Advance(6 * time.Second)
+	assert.Empty(t, detector.DetectDeadlocks())
+	hung()
+}
+
+// TestHeartbeatClearsDeadlock tests that a heartbeat resets the detection
+func TestHeartbeatClearsDeadlock(t *testing.T) {
+	detector, clock := newClockedDeadlockDetector(t, false)
+	detector.RegisterComponent("test-component")
+	
+	// Regular heartbeats keep the component alive
+	for i := 0; i < 10; i++ {
+		clock.Advance(2 * time.Second)
+		detector.Heartbeat("test-component")
+		assert.Empty(t, detector.DetectDeadlocks())
 	}
 	
-	monitor := new(MockComponentMonitor)
+	clock.Advance(4 * time.Second)
+	require.Len(t, detector.DetectDeadlocks(), 1)
 	
-	// Capture the handler function when it's registered
-	var deadlockHandler func(string, watchdog.ComponentMetrics)
-	monitor.On("AddDeadlockDetectedHandler", mock.Anything).Run(func(args mock.Arguments) {
-		deadlockHandler = args.Get(0).(func(string, watchdog.ComponentMetrics))
-	}).Return()
+	// The component recovers, and is reported again if it stalls again
+	detector.Heartbeat("test-component")
+	assert.False(t, detector.HasDeadlock("test-component"))
 	
-	detector := watchdog.NewDeadlockDetector(config, monitor)
+	clock.Advance(4 * time.Second)
+	assert.Len(t, detector.DetectDeadlocks(), 1)
 	
-	// Simulate a deadlock
-	metrics := watchdog.ComponentMetrics{
-		LastResponseTime: 30 * time.Second,
-		GoroutineCount:   200,
-		State:            "blocked",
-		HealthStatus:     "degraded",
+	// Heartbeats of unregistered components are ignored
+	detector.Heartbeat("unknown")
+	clock.Advance(4 * time.Second)
+	for _, deadlock := range detector.DetectDeadlocks() {
+		assert.NotEqual(t, "unknown", deadlock.ComponentName)
 	}
+}
+
+// TestClearDeadlock tests clearing a detected deadlock
+func TestClearDeadlock(t *testing.T) {
+	detector, clock := newClockedDeadlockDetector(t, false)
+	detector.RegisterComponent("test-component")
 	
-	deadlockHandler("test-component", metrics)
+	clock.Advance(4 * time.Second)
+	detector.DetectDeadlocks()
 	
 	// Verify the deadlock was detected
 	assert.True(t, detector.HasDeadlock("test-component"))
@@ -126,43 +196,27 @@ func TestClearDeadlock(t *testing.T) {
 	// Verify it was cleared
 	assert.False(t, detector.HasDeadlock("test-component"))
 	assert.Empty(t, detector.GetDetectedDeadlocks())
+	
+	// It is reported again while still silent
+	assert.Len(t, detector.DetectDeadlocks(), 1)
 }
 
 // TestMultipleDeadlocks tests detecting multiple deadlocks
 func TestMultipleDeadlocks(t *testing.T) {
-	config := watchdog.Config{
-		DeadlockDetection: watchdog.DeadlockConfig{
-			Enabled:              true,
-			CheckInterval:        10 * time.Second,
-			GoroutineThreshold:   100,
-		},
-	}
-	
-	monitor := new(MockComponentMonitor)
-	
-	// Capture the handler function when it's registered
-	var deadlockHandler func(string, watchdog.ComponentMetrics)
-	monitor.On("AddDeadlockDetectedHandler", mock.Anything).Run(func(args mock.Arguments) {
-		deadlockHandler = args.Get(0).(func(string, watchdog.ComponentMetrics))
-	}).Return()
+	detector, clock := newClockedDeadlockDetector(t, true)
 	
-	detector := watchdog.NewDeadlockDetector(config, monitor)
-	
-	// Simulate deadlocks in multiple components
 	components := []string{"component1", "component2", "component3"}
-	
 	for _, component := range components {
-		metrics := watchdog.ComponentMetrics{
-			LastResponseTime: 30 * time.Second,
-			GoroutineCount:   200,
-			State:            "blocked",
-			HealthStatus:     "degraded",
-		}
-		
-		deadlockHandler(component, metrics)
+		detector.RegisterComponent(component)
 	}
+	detector.RegisterComponent("healthy")
+	
+	clock.Advance(2 * time.Second)
+	detector.Heartbeat("healthy")
+	clock.Advance(2 * time.Second)
 	
-	// Verify all deadlocks were detected
+	// Verify all silent components were detected
+	assert.Len(t, detector.DetectDeadlocks(), 3)
 	deadlocks := detector.GetDetectedDeadlocks()
 	assert.Len(t, deadlocks, 3)
 	
@@ -170,63 +224,15 @@ func TestMultipleDeadlocks(t *testing.T) {
 		assert.True(t, detector.HasDeadlock(component))
 		assert.Contains(t, deadlocks, component)
 	}
+	assert.False(t, detector.HasDeadlock("healthy"))
 	
-	// Clear one deadlock
-	detector.ClearDeadlock("component2")
+	// Unregister one component
+	detector.UnregisterComponent("component2")
 	
-	// Verify only that one was cleared
+	// Verify only that one was removed
 	deadlocks = detector.GetDetectedDeadlocks()
 	assert.Len(t, deadlocks, 2)
 	assert.True(t, detector.HasDeadlock("component1"))
 	assert.False(t, detector.HasDeadlock("component2"))
 	assert.True(t, detector.HasDeadlock("component3"))
 }
-
-// TestDeadlockTimestamps tests that deadlock timestamps are set correctly
-func TestDeadlockTimestamps(t *testing.T) {
-	config := watchdog.Config{
-		DeadlockDetection: watchdog.DeadlockConfig{
-			Enabled:              true,
-			CheckInterval:        10 * time.Second,
-			GoroutineThreshold:   100,
-		},
-	}
-	
-	monitor := new(MockComponentMonitor)
-	
-	// Capture the handler function when it's registered
-	var deadlockHandler func(string, watchdog.ComponentMetrics)
-	monitor.On("AddDeadlockDetectedHandler", mock.Anything).Run(func(args mock.Arguments) {
-		deadlockHandler = args.Get(0).(func(string, watchdog.ComponentMetrics))
-	}).Return()
-	
-	detector := watchdog.NewDeadlockDetector(config, monitor)
-	
-	// Remember the current time
-	beforeTime := time.Now()
-	
-	// Wait a bit
-	time.Sleep(10 * time.Millisecond)
-	
-	// Simulate a deadlock
-	metrics := watchdog.ComponentMetrics{
-		LastResponseTime: 30 * time.Second,
-		GoroutineCount:   200,
-		State:            "blocked",
-		HealthStatus:     "degraded",
-	}
-	
-	deadlockHandler("test-component", metrics)
-	
-	// Wait a bit more
-	time.Sleep(10 * time.Millisecond)
-	afterTime := time.Now()
-	
-	// Get the deadlock info
-	deadlocks := detector.GetDetectedDeadlocks()
-	deadlock := deadlocks["test-component"]
-	
-	// Verify the timestamp is between the before and after times
-	assert.True(t, deadlock.DetectedAt.After(beforeTime))
-	assert.True(t, deadlock.DetectedAt.Before(afterTime))
-}
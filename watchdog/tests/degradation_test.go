@@ -1,8 +1,11 @@
 package tests
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
+	"github.com/newrelic/infrastructure-agent/sketch"
 	"github.com/newrelic/infrastructure-agent/watchdog"
 	"github.com/stretchr/testify/assert"
 )
@@ -269,3 +272,89 @@ func TestResetAllComponents(t *testing.T) {
 	assert.Equal(t, 0, controller.GetComponentLevel("component2"))
 	assert.Equal(t, 0, controller.GetComponentLevel("component3"))
 }
+
+// TestActionHandlers tests that handlers follow the levels of a component
+func TestActionHandlers(t *testing.T) {
+	controller, err := watchdog.NewDegradationController(3)
+	assert.NoError(t, err)
+	
+	controller.SetLevelActions(1, []string{"reduce_scan_frequency"}, "Reduce scan frequency")
+	controller.SetLevelActions(2, []string{"reduce_scan_frequency", "filter_events"}, "Filter events")
+	controller.SetLevelActions(3, []string{"failing_action"}, "Fail")
+	
+	var calls []string
+	record := func(action string) func(level int) error {
+		return func(level int) error {
+			calls = append(calls, fmt.Sprintf("%s:%d", action, level))
+			return nil
+		}
+	}
+	controller.RegisterActionHandler("reduce_scan_frequency", record("reduce_scan_frequency"))
+	controller.RegisterActionHandler("filter_events", record("filter_events"))
+	controller.RegisterActionHandler("failing_action", func(level int) error {
+		return errors.New("action failed")
+	})
+	
+	// Escalating activates each level in turn
+	err = controller.SetComponentLevel("collector", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"reduce_scan_frequency:1",
+		"reduce_scan_frequency:2",
+		"filter_events:2",
+	}, calls)
+	
+	// Other components are independent
+	calls = nil
+	err = controller.SetComponentLevel("sampler", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, calls)
+	
+	// A failing handler still sets the level
+	err = controller.SetComponentLevel("collector", 3)
+	assert.ErrorContains(t, err, "action failing_action at level 3 failed for component collector: action failed")
+	assert.Equal(t, 3, controller.GetComponentLevel("collector"))
+	
+	// De-escalating reverts each level to the one below, highest first
+	calls = nil
+	err = controller.SetComponentLevel("collector", 1)
+	assert.ErrorContains(t, err, "action failing_action at level 2 failed")
+	assert.Equal(t, []string{
+		"reduce_scan_frequency:1",
+		"filter_events:1",
+	}, calls)
+	
+	calls = nil
+	err = controller.ResetComponent("collector")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"reduce_scan_frequency:0"}, calls)
+}
+
+// TestReduceAccuracyAction tests the reduce_accuracy action of the default
+// sketch levels loosening a sketch
+func TestReduceAccuracyAction(t *testing.T) {
+	levels := watchdog.DefaultConfig().ComponentConfigs["sketch"].DegradationLevels
+	controller, err := watchdog.NewDegradationController(len(levels))
+	assert.NoError(t, err)
+	for i, level := range levels {
+		assert.NoError(t, controller.SetLevelActions(i+1, level.Actions, level.Description))
+	}
+	
+	ddSketch := sketch.NewDDSketch(sketch.DefaultConfig().DDSketch)
+	for i := 1; i <= 1000; i++ {
+		assert.NoError(t, ddSketch.Add(float64(i)))
+	}
+	controller.RegisterActionHandler("reduce_accuracy", ddSketch.ReduceAccuracyHandler(0.05))
+	
+	// The warning level only switches stores
+	assert.NoError(t, controller.SetComponentLevel("sketch", 1))
+	assert.Error(t, ddSketch.Merge(sketch.NewDDSketch(sketch.DDSketchConfig{RelativeAccuracy: 0.05})))
+	
+	assert.NoError(t, controller.SetComponentLevel("sketch", 2))
+	assert.NoError(t, ddSketch.Merge(sketch.NewDDSketch(sketch.DDSketchConfig{RelativeAccuracy: 0.05})))
+	assert.Equal(t, uint64(1000), ddSketch.GetCount())
+	
+	p95, err := ddSketch.GetValueAtQuantile(0.95)
+	assert.NoError(t, err)
+	assert.InEpsilon(t, 950, p95, 0.06)
+}
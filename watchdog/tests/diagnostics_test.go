@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -24,12 +25,12 @@ func TestEmitAgentDiagEvent(t *testing.T) {
 	
 	// Create an incident
 	resourceUsage := watchdog.ResourceUsage{
-		CPUPercent:  90.0,
-		MemoryMB:    500.0,
-		Goroutines:  100,
-		FileHandles: 50,
-		GCPercent:   5.0,
-		Timestamp:   time.Now(),
+		CPUPercent:      90.0,
+		MemoryBytes:     500 * 1024 * 1024,
+		Goroutines:      100,
+		FileDescriptors: 50,
+		GCPercent:       5.0,
+		Timestamp:       time.Now(),
 	}
 	
 	incident := watchdog.Incident{
@@ -57,11 +58,40 @@ func TestEmitAgentDiagEvent(t *testing.T) {
 	
 	// Check details
 	assert.Equal(t, incident.ResourceUsage.CPUPercent, event.Details["cpu_percent"])
-	assert.Equal(t, incident.ResourceUsage.MemoryMB, event.Details["memory_mb"])
+	assert.Equal(t, incident.ResourceUsage.MemoryMB(), event.Details["memory_mb"])
 	assert.Equal(t, incident.ResourceUsage.Goroutines, event.Details["goroutines"])
-	assert.Equal(t, incident.ResourceUsage.FileHandles, event.Details["file_handles"])
+	assert.Equal(t, incident.ResourceUsage.FileDescriptors, event.Details["file_descriptors"])
 	assert.Equal(t, incident.ResourceUsage.GCPercent, event.Details["gc_percent"])
 	assert.Equal(t, incident.Remediation, event.Details["remediation"])
+	
+	// No stack trace was captured
+	assert.NotContains(t, event.Details, "stack_trace")
+}
+
+// TestEmitAgentDiagEventStackTrace tests that stack traces are only included
+// when enabled
+func TestEmitAgentDiagEventStackTrace(t *testing.T) {
+	provider := watchdog.NewDiagnosticsProvider()
+	
+	incident := watchdog.Incident{
+		ID:            "test-deadlock-1",
+		Timestamp:     time.Now(),
+		ComponentName: "test-component",
+		Type:          watchdog.IncidentDeadlockDetected,
+		Description:   "Deadlock detected",
+		StackTrace:    "goroutine 1 [running]:",
+	}
+	
+	provider.EmitAgentDiagEvent(incident)
+	
+	provider.SetIncludeStackTraces(false)
+	incident.ID = "test-deadlock-2"
+	provider.EmitAgentDiagEvent(incident)
+	
+	events := provider.GetEvents()
+	assert.Len(t, events, 2)
+	assert.Equal(t, incident.StackTrace, events[0].Details["stack_trace"])
+	assert.NotContains(t, events[1].Details, "stack_trace")
 }
 
 // TestMultipleEvents tests recording multiple events
@@ -70,12 +100,12 @@ func TestMultipleEvents(t *testing.T) {
 	
 	// Create resource usage for incidents
 	resourceUsage := watchdog.ResourceUsage{
-		CPUPercent:  90.0,
-		MemoryMB:    500.0,
-		Goroutines:  100,
-		FileHandles: 50,
-		GCPercent:   5.0,
-		Timestamp:   time.Now(),
+		CPUPercent:      90.0,
+		MemoryBytes:     500 * 1024 * 1024,
+		Goroutines:      100,
+		FileDescriptors: 50,
+		GCPercent:       5.0,
+		Timestamp:       time.Now(),
 	}
 	
 	// Create and emit multiple incidents
@@ -119,12 +149,12 @@ func TestMaxEvents(t *testing.T) {
 	
 	// Create and emit more than the max number of events
 	resourceUsage := watchdog.ResourceUsage{
-		CPUPercent:  90.0,
-		MemoryMB:    500.0,
-		Goroutines:  100,
-		FileHandles: 50,
-		GCPercent:   5.0,
-		Timestamp:   time.Now(),
+		CPUPercent:      90.0,
+		MemoryBytes:     500 * 1024 * 1024,
+		Goroutines:      100,
+		FileDescriptors: 50,
+		GCPercent:       5.0,
+		Timestamp:       time.Now(),
 	}
 	
 	for i := 0; i < 10; i++ {
@@ -155,12 +185,12 @@ func TestClearEvents(t *testing.T) {
 	
 	// Create and emit some events
 	resourceUsage := watchdog.ResourceUsage{
-		CPUPercent:  90.0,
-		MemoryMB:    500.0,
-		Goroutines:  100,
-		FileHandles: 50,
-		GCPercent:   5.0,
-		Timestamp:   time.Now(),
+		CPUPercent:      90.0,
+		MemoryBytes:     500 * 1024 * 1024,
+		Goroutines:      100,
+		FileDescriptors: 50,
+		GCPercent:       5.0,
+		Timestamp:       time.Now(),
 	}
 	
 	for i := 0; i < 5; i++ {
@@ -193,12 +223,12 @@ func TestGetEventsByType(t *testing.T) {
 	
 	// Create resource usage for incidents
 	resourceUsage := watchdog.ResourceUsage{
-		CPUPercent:  90.0,
-		MemoryMB:    500.0,
-		Goroutines:  100,
-		FileHandles: 50,
-		GCPercent:   5.0,
-		Timestamp:   time.Now(),
+		CPUPercent:      90.0,
+		MemoryBytes:     500 * 1024 * 1024,
+		Goroutines:      100,
+		FileDescriptors: 50,
+		GCPercent:       5.0,
+		Timestamp:       time.Now(),
 	}
 	
 	// Create and emit multiple incidents with different types
@@ -241,12 +271,12 @@ func TestGetEventsByComponent(t *testing.T) {
 	
 	// Create resource usage for incidents
 	resourceUsage := watchdog.ResourceUsage{
-		CPUPercent:  90.0,
-		MemoryMB:    500.0,
-		Goroutines:  100,
-		FileHandles: 50,
-		GCPercent:   5.0,
-		Timestamp:   time.Now(),
+		CPUPercent:      90.0,
+		MemoryBytes:     500 * 1024 * 1024,
+		Goroutines:      100,
+		FileDescriptors: 50,
+		GCPercent:       5.0,
+		Timestamp:       time.Now(),
 	}
 	
 	// Create and emit events for different components
@@ -256,6 +286,7 @@ func TestGetEventsByComponent(t *testing.T) {
 		incident := watchdog.Incident{
 			ID:            fmt.Sprintf("test-incident-%d", i+1),
 			Timestamp:     time.Now(),
+			ComponentName: component,
 			Type:          watchdog.IncidentResourceExceeded,
 			Description:   fmt.Sprintf("Incident for %s", component),
 			ResourceUsage: resourceUsage,
@@ -263,11 +294,6 @@ func TestGetEventsByComponent(t *testing.T) {
 		
 		// Emit the event
 		provider.EmitAgentDiagEvent(incident)
-		
-		// Set the component name (since we're testing the implementation)
-		events := provider.GetEvents()
-		lastEvent := &events[len(events)-1]
-		lastEvent.ComponentName = component
 	}
 	
 	// Get events by component
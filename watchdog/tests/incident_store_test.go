@@ -0,0 +1,236 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/watchdog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestIncident creates an incident of a component at the given time
+func newTestIncident(id int, component string, incidentType watchdog.IncidentType, timestamp time.Time) watchdog.Incident {
+	return watchdog.Incident{
+		ID:            fmt.Sprintf("incident-%d", id),
+		Timestamp:     timestamp,
+		ComponentName: component,
+		Type:          incidentType,
+		Description:   fmt.Sprintf("Incident %d of %s", id, component),
+	}
+}
+
+// incidentIDs returns the IDs of the incidents in order
+func incidentIDs(incidents []watchdog.Incident) []string {
+	ids := make([]string, len(incidents))
+	for i, incident := range incidents {
+		ids[i] = incident.ID
+	}
+	return ids
+}
+
+// TestIncidentStoreCreation tests creating an incident store
+func TestIncidentStoreCreation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "incidents.jsonl")
+
+	store, err := watchdog.NewFileIncidentStore(watchdog.IncidentStoreConfig{Path: path, MaxFiles: 3})
+	assert.NoError(t, err)
+	assert.NotNil(t, store)
+
+	_, err = watchdog.NewFileIncidentStore(watchdog.IncidentStoreConfig{MaxFiles: 3})
+	assert.Error(t, err)
+
+	_, err = watchdog.NewFileIncidentStore(watchdog.IncidentStoreConfig{Path: path})
+	assert.Error(t, err)
+
+	// Nothing stored yet
+	incidents, err := store.QueryIncidents(watchdog.IncidentFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, incidents)
+}
+
+// TestIncidentStoreQuery tests querying incidents by time range, component and type
+func TestIncidentStoreQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "incidents.jsonl")
+	store, err := watchdog.NewFileIncidentStore(watchdog.IncidentStoreConfig{Path: path, MaxFiles: 3})
+	require.NoError(t, err)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, store.Append(newTestIncident(1, "collector", watchdog.IncidentResourceExceeded, start)))
+	require.NoError(t, store.Append(newTestIncident(2, "sampler", watchdog.IncidentDeadlockDetected, start.Add(time.Minute))))
+	require.NoError(t, store.Append(newTestIncident(3, "collector", watchdog.IncidentRestartFailed, start.Add(2*time.Minute))))
+	require.NoError(t, store.Append(newTestIncident(4, "collector", watchdog.IncidentCrash, start.Add(3*time.Minute))))
+
+	// All incidents, oldest first
+	incidents, err := store.QueryIncidents(watchdog.IncidentFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"incident-1", "incident-2", "incident-3", "incident-4"}, incidentIDs(incidents))
+	assert.Equal(t, "collector", incidents[0].ComponentName)
+	assert.True(t, start.Equal(incidents[0].Timestamp))
+
+	// Since is inclusive, Until exclusive
+	incidents, err = store.QueryIncidents(watchdog.IncidentFilter{
+		Since: start.Add(time.Minute),
+		Until: start.Add(3 * time.Minute),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"incident-2", "incident-3"}, incidentIDs(incidents))
+
+	incidents, err = store.QueryIncidents(watchdog.IncidentFilter{ComponentName: "collector"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"incident-1", "incident-3", "incident-4"}, incidentIDs(incidents))
+
+	incidents, err = store.QueryIncidents(watchdog.IncidentFilter{
+		Types: []watchdog.IncidentType{watchdog.IncidentDeadlockDetected, watchdog.IncidentCrash},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"incident-2", "incident-4"}, incidentIDs(incidents))
+
+	// Limit keeps the most recent matches
+	incidents, err = store.QueryIncidents(watchdog.IncidentFilter{ComponentName: "collector", Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"incident-3", "incident-4"}, incidentIDs(incidents))
+
+	// A new store, e.g. after a restart, reads the same history
+	reopened, err := watchdog.NewFileIncidentStore(watchdog.IncidentStoreConfig{Path: path, MaxFiles: 3})
+	require.NoError(t, err)
+	incidents, err = reopened.QueryIncidents(watchdog.IncidentFilter{})
+	require.NoError(t, err)
+	assert.Len(t, incidents, 4)
+}
+
+// TestIncidentStoreRotationBySize tests rotating the file when it grows too big
+func TestIncidentStoreRotationBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "incidents.jsonl")
+	store, err := watchdog.NewFileIncidentStore(watchdog.IncidentStoreConfig{
+		Path:         path,
+		MaxSizeBytes: 1, // Every incident goes to a new file
+		MaxFiles:     2,
+	})
+	require.NoError(t, err)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, store.Append(newTestIncident(i, "collector", watchdog.IncidentCrash, start.Add(time.Duration(i)*time.Minute))))
+	}
+
+	// The current file and two rotated ones are kept
+	assert.FileExists(t, path)
+	assert.FileExists(t, path+".1")
+	assert.FileExists(t, path+".2")
+	assert.NoFileExists(t, path+".3")
+
+	incidents, err := store.QueryIncidents(watchdog.IncidentFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"incident-3", "incident-4", "incident-5"}, incidentIDs(incidents))
+
+	// Time ranges span rotated files
+	incidents, err = store.QueryIncidents(watchdog.IncidentFilter{Until: start.Add(5 * time.Minute)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"incident-3", "incident-4"}, incidentIDs(incidents))
+}
+
+// TestIncidentStoreRotationByAge tests rotating the file when its oldest incident is too old
+func TestIncidentStoreRotationByAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "incidents.jsonl")
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	config := watchdog.IncidentStoreConfig{
+		Path:         path,
+		MaxSizeBytes: 1024 * 1024,
+		MaxAge:       time.Hour,
+		MaxFiles:     3,
+	}
+	store, err := watchdog.NewFileIncidentStoreWithClock(config, clock.Now)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Append(newTestIncident(1, "collector", watchdog.IncidentCrash, clock.Now())))
+	clock.Advance(30 * time.Minute)
+	require.NoError(t, store.Append(newTestIncident(2, "collector", watchdog.IncidentCrash, clock.Now())))
+	assert.NoFileExists(t, path+".1")
+
+	// The first incident of the file is now older than MaxAge
+	clock.Advance(31 * time.Minute)
+	require.NoError(t, store.Append(newTestIncident(3, "collector", watchdog.IncidentCrash, clock.Now())))
+	assert.FileExists(t, path+".1")
+
+	// A new store takes the age from the first incident in the file
+	clock.Advance(2 * time.Hour)
+	reopened, err := watchdog.NewFileIncidentStoreWithClock(config, clock.Now)
+	require.NoError(t, err)
+	require.NoError(t, reopened.Append(newTestIncident(4, "collector", watchdog.IncidentCrash, clock.Now())))
+	assert.FileExists(t, path+".2")
+
+	incidents, err := reopened.QueryIncidents(watchdog.IncidentFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"incident-1", "incident-2", "incident-3", "incident-4"}, incidentIDs(incidents))
+}
+
+// TestIncidentStoreSkipsTruncatedLines tests that a partially written line does not break queries
+func TestIncidentStoreSkipsTruncatedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "incidents.jsonl")
+	store, err := watchdog.NewFileIncidentStore(watchdog.IncidentStoreConfig{Path: path, MaxFiles: 3})
+	require.NoError(t, err)
+
+	require.NoError(t, store.Append(newTestIncident(1, "collector", watchdog.IncidentCrash, time.Now())))
+
+	// Simulate a crash in the middle of a write
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"ID":"incident-2","Times` + "\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	require.NoError(t, store.Append(newTestIncident(3, "collector", watchdog.IncidentCrash, time.Now())))
+
+	incidents, err := store.QueryIncidents(watchdog.IncidentFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"incident-1", "incident-3"}, incidentIDs(incidents))
+}
+
+// TestIncidentStoreSkipsOverlongLines tests that a line too long to read does not break queries
+func TestIncidentStoreSkipsOverlongLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "incidents.jsonl")
+	store, err := watchdog.NewFileIncidentStore(watchdog.IncidentStoreConfig{Path: path, MaxFiles: 3})
+	require.NoError(t, err)
+
+	require.NoError(t, store.Append(newTestIncident(1, "collector", watchdog.IncidentCrash, time.Now())))
+
+	// A line written without the stack trace limit
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"ID":"incident-2","StackTrace":"` + strings.Repeat("x", 2<<20) + `"}` + "\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	require.NoError(t, store.Append(newTestIncident(3, "collector", watchdog.IncidentCrash, time.Now())))
+
+	incidents, err := store.QueryIncidents(watchdog.IncidentFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"incident-1", "incident-3"}, incidentIDs(incidents))
+}
+
+// TestIncidentStoreTrimsStackTraces tests that an incident with a large stack trace can be queried
+func TestIncidentStoreTrimsStackTraces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "incidents.jsonl")
+	store, err := watchdog.NewFileIncidentStore(watchdog.IncidentStoreConfig{Path: path, MaxFiles: 3})
+	require.NoError(t, err)
+
+	// A 1MB trace grows once escaped in JSON
+	incident := newTestIncident(1, "collector", watchdog.IncidentDeadlockDetected, time.Now())
+	incident.StackTrace = strings.Repeat("goroutine 1 [\"running\"]:\n\tmain.main()\n", 1<<20/40)
+	require.NoError(t, store.Append(incident))
+	require.NoError(t, store.Append(newTestIncident(2, "collector", watchdog.IncidentCrash, time.Now())))
+
+	incidents, err := store.QueryIncidents(watchdog.IncidentFilter{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"incident-1", "incident-2"}, incidentIDs(incidents))
+
+	trace := incidents[0].StackTrace
+	assert.True(t, strings.HasSuffix(trace, "\n... stack trace truncated"))
+	assert.True(t, strings.HasPrefix(incident.StackTrace, strings.TrimSuffix(trace, "\n... stack trace truncated")))
+	assert.Greater(t, len(trace), 1<<19)
+}
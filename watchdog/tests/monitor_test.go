@@ -2,7 +2,10 @@ package tests
 
 import (
 	"context"
+	"fmt"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -74,7 +77,7 @@ func NewMockMonitorableComponent(name string) *MockMonitorableComponent {
 			MemoryBytes: 100 * 1024 * 1024, // 100 MB
 			FileDescriptors: 10,
 			Goroutines: 5,
-			LastUpdated: time.Now(),
+			Timestamp: time.Now(),
 		},
 		running: true,
 	}
@@ -189,7 +192,7 @@ func TestThresholdHandlers(t *testing.T) {
 		MemoryBytes: 300 * 1024 * 1024, // > 200 MB threshold
 		FileDescriptors: 50,
 		Goroutines: 50,
-		LastUpdated: time.Now(),
+		Timestamp: time.Now(),
 	})
 	
 	// Add the component
@@ -248,6 +251,405 @@ eventLoop:
 	assert.InDelta(t, 200.0, memoryEvent.ThresholdValue, 0.1)
 }
 
+// overThresholdsConfig returns a config whose thresholds are all exceeded by
+// a component with highUsage
+func overThresholdsConfig(names ...string) watchdog.Config {
+	config := watchdog.Config{
+		MonitoringInterval: 10 * time.Millisecond,
+		ComponentConfigs:   map[string]watchdog.ComponentConfig{},
+	}
+	for _, name := range names {
+		config.ComponentConfigs[name] = watchdog.ComponentConfig{
+			Enabled: true,
+			MaxCPUPercent: 80.0,
+			MaxMemoryMB: 200,
+			MaxFileDescriptors: 100,
+			MaxGoroutines: 100,
+		}
+	}
+	return config
+}
+
+// highUsage exceeds the CPU, memory, file descriptor and goroutine thresholds
+// of overThresholdsConfig
+var highUsage = watchdog.ResourceUsage{
+	CPUPercent: 90.0,
+	MemoryBytes: 300 * 1024 * 1024,
+	FileDescriptors: 200,
+	Goroutines: 200,
+}
+
+func TestSyncThresholdHandlerOrdering(t *testing.T) {
+	config := overThresholdsConfig("test-component")
+	config.ThresholdHandlerMode = watchdog.HandlerModeSync
+	monitor := watchdog.NewResourceMonitor(config)
+	
+	component := NewMockMonitorableComponent("test-component")
+	component.SetResourceUsage(highUsage)
+	assert.NoError(t, monitor.AddComponent(component))
+	
+	// Handlers of one monitor run one at a time, so no locking is needed
+	var calls []string
+	monitor.AddThresholdHandler(func(event watchdog.ThresholdExceededEvent) {
+		calls = append(calls, "first:"+event.ResourceType)
+	})
+	monitor.AddThresholdHandler(func(event watchdog.ThresholdExceededEvent) {
+		calls = append(calls, "second:"+event.ResourceType)
+	})
+	
+	assert.NoError(t, monitor.Start())
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, monitor.Stop())
+	
+	// Every check notifies the events in order, each to the handlers in order
+	check := []string{
+		"first:CPU", "second:CPU",
+		"first:Memory", "second:Memory",
+		"first:FileDescriptors", "second:FileDescriptors",
+		"first:Goroutines", "second:Goroutines",
+	}
+	assert.GreaterOrEqual(t, len(calls), 2*len(check))
+	for i, call := range calls {
+		assert.Equal(t, check[i%len(check)], call)
+	}
+}
+
+func TestAsyncThresholdHandlerWorkers(t *testing.T) {
+	names := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	config := overThresholdsConfig(names...)
+	config.ThresholdHandlerMode = watchdog.HandlerModeAsync
+	config.ThresholdHandlerWorkers = 3
+	monitor := watchdog.NewResourceMonitor(config)
+	
+	for _, name := range names {
+		component := NewMockMonitorableComponent(name)
+		component.SetResourceUsage(highUsage)
+		assert.NoError(t, monitor.AddComponent(component))
+	}
+	
+	// Every check raises 32 events, each handled slowly
+	var running, maxRunning, handled int64
+	monitor.AddThresholdHandler(func(event watchdog.ThresholdExceededEvent) {
+		current := atomic.AddInt64(&running, 1)
+		for {
+			previous := atomic.LoadInt64(&maxRunning)
+			if current <= previous || atomic.CompareAndSwapInt64(&maxRunning, previous, current) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt64(&running, -1)
+		atomic.AddInt64(&handled, 1)
+	})
+	
+	assert.NoError(t, monitor.Start())
+	time.Sleep(100 * time.Millisecond)
+	assert.NoError(t, monitor.Stop())
+	
+	assert.Greater(t, atomic.LoadInt64(&handled), int64(32))
+	assert.LessOrEqual(t, atomic.LoadInt64(&maxRunning), int64(3))
+	assert.Greater(t, atomic.LoadInt64(&maxRunning), int64(1))
+	
+	// Stop waits for the queued handlers
+	assert.Equal(t, int64(0), atomic.LoadInt64(&running))
+}
+
+func TestRemoveThresholdHandler(t *testing.T) {
+	config := overThresholdsConfig("test-component")
+	config.ThresholdHandlerMode = watchdog.HandlerModeSync
+	monitor := watchdog.NewResourceMonitor(config)
+	
+	component := NewMockMonitorableComponent("test-component")
+	component.SetResourceUsage(highUsage)
+	assert.NoError(t, monitor.AddComponent(component))
+	
+	var removedCalls, keptCalls int64
+	removed := monitor.AddThresholdHandler(func(event watchdog.ThresholdExceededEvent) {
+		atomic.AddInt64(&removedCalls, 1)
+	})
+	monitor.AddThresholdHandler(func(event watchdog.ThresholdExceededEvent) {
+		atomic.AddInt64(&keptCalls, 1)
+	})
+	
+	assert.True(t, monitor.RemoveThresholdHandler(removed))
+	assert.False(t, monitor.RemoveThresholdHandler(removed))
+	
+	assert.NoError(t, monitor.Start())
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, monitor.Stop())
+	
+	assert.Equal(t, int64(0), atomic.LoadInt64(&removedCalls))
+	assert.Greater(t, atomic.LoadInt64(&keptCalls), int64(0))
+}
+
+// leakingComponent reports more goroutines every time its usage is read
+type leakingComponent struct {
+	*MockMonitorableComponent
+	goroutines int
+	step       int
+}
+
+// ResourceUsage implements Component interface
+func (l *leakingComponent) ResourceUsage() watchdog.ResourceUsage {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	
+	l.goroutines += l.step
+	usage := l.resourceUsage
+	usage.Goroutines = l.goroutines
+	return usage
+}
+
+func TestGoroutineLeakDetection(t *testing.T) {
+	config := watchdog.Config{
+		MonitoringInterval: 10 * time.Millisecond,
+		ComponentConfigs: map[string]watchdog.ComponentConfig{
+			"leaking": {
+				Enabled: true,
+				MaxCPUPercent: 80.0,
+				MaxMemoryMB: 200,
+				MaxFileDescriptors: 1000,
+				MaxGoroutines: 100000, // Never reached by the test
+				GoroutineLeakSamples: 5,
+				GoroutineLeakSlope: 2,
+			},
+			"slow": {
+				Enabled: true,
+				MaxCPUPercent: 80.0,
+				MaxMemoryMB: 200,
+				MaxFileDescriptors: 1000,
+				MaxGoroutines: 100000,
+				GoroutineLeakSamples: 5,
+				GoroutineLeakSlope: 2,
+			},
+		},
+	}
+	
+	monitor := watchdog.NewResourceMonitor(config)
+	
+	// Both climb steadily, but only one faster than the slope
+	leaking := &leakingComponent{MockMonitorableComponent: NewMockMonitorableComponent("leaking"), goroutines: 10, step: 3}
+	slow := &leakingComponent{MockMonitorableComponent: NewMockMonitorableComponent("slow"), goroutines: 10, step: 1}
+	assert.NoError(t, monitor.AddComponent(leaking))
+	assert.NoError(t, monitor.AddComponent(slow))
+	
+	eventCh := make(chan watchdog.ThresholdExceededEvent, 100)
+	monitor.AddThresholdHandler(func(event watchdog.ThresholdExceededEvent) {
+		if event.ResourceType == "GoroutineLeak" {
+			eventCh <- event
+		}
+	})
+	
+	err := monitor.Start()
+	assert.NoError(t, err)
+	
+	var leakEvent watchdog.ThresholdExceededEvent
+	select {
+	case leakEvent = <-eventCh:
+	case <-time.After(time.Second):
+		t.Fatal("goroutine leak not detected")
+	}
+	
+	// Let the slow component accumulate enough samples too
+	time.Sleep(100 * time.Millisecond)
+	
+	err = monitor.Stop()
+	assert.NoError(t, err)
+	
+	assert.Equal(t, "leaking", leakEvent.ComponentName)
+	assert.InDelta(t, 3.0, leakEvent.CurrentValue, 0.01)
+	assert.InDelta(t, 2.0, leakEvent.ThresholdValue, 0.01)
+	
+	for len(eventCh) > 0 {
+		assert.Equal(t, "leaking", (<-eventCh).ComponentName)
+	}
+}
+
+// scriptedComponent reports the CPU usages of a script, one per read, then
+// the last one
+type scriptedComponent struct {
+	*MockMonitorableComponent
+	cpu []float64
+}
+
+// ResourceUsage implements Component interface
+func (s *scriptedComponent) ResourceUsage() watchdog.ResourceUsage {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	
+	usage := s.resourceUsage
+	usage.CPUPercent = s.cpu[0]
+	if len(s.cpu) > 1 {
+		s.cpu = s.cpu[1:]
+	}
+	return usage
+}
+
+func TestSmoothingIgnoresSpikes(t *testing.T) {
+	config := overThresholdsConfig("spiky")
+	config.ThresholdHandlerMode = watchdog.HandlerModeSync
+	config.SmoothingAlpha = 0.2
+	monitor := watchdog.NewResourceMonitor(config)
+	
+	// A single sample over the 80% threshold
+	spiky := &scriptedComponent{
+		MockMonitorableComponent: NewMockMonitorableComponent("spiky"),
+		cpu:                      []float64{10, 10, 100, 10},
+	}
+	assert.NoError(t, monitor.AddComponent(spiky))
+	
+	var events []watchdog.ThresholdExceededEvent
+	monitor.AddThresholdHandler(func(event watchdog.ThresholdExceededEvent) {
+		if event.ResourceType == "CPU" {
+			events = append(events, event)
+		}
+	})
+	
+	assert.NoError(t, monitor.Start())
+	time.Sleep(80 * time.Millisecond)
+	assert.NoError(t, monitor.Stop())
+	
+	assert.Empty(t, events)
+	
+	// The history keeps the raw samples
+	history, ok := monitor.GetResourceHistory("spiky")
+	assert.True(t, ok)
+	assert.GreaterOrEqual(t, len(history), 3)
+	assert.Equal(t, 100.0, history[2].CPUPercent)
+}
+
+func TestSmoothingReportsSustainedUsage(t *testing.T) {
+	config := overThresholdsConfig("busy")
+	config.ThresholdHandlerMode = watchdog.HandlerModeSync
+	config.SmoothingAlpha = 0.5
+	monitor := watchdog.NewResourceMonitor(config)
+	
+	// The average crosses 80% on the 4th sample: 10, 55, 77.5, 88.75
+	busy := &scriptedComponent{
+		MockMonitorableComponent: NewMockMonitorableComponent("busy"),
+		cpu:                      []float64{10, 100},
+	}
+	assert.NoError(t, monitor.AddComponent(busy))
+	
+	eventCh := make(chan watchdog.ThresholdExceededEvent, 100)
+	monitor.AddThresholdHandler(func(event watchdog.ThresholdExceededEvent) {
+		if event.ResourceType == "CPU" {
+			eventCh <- event
+		}
+	})
+	
+	assert.NoError(t, monitor.Start())
+	var event watchdog.ThresholdExceededEvent
+	select {
+	case event = <-eventCh:
+	case <-time.After(time.Second):
+		t.Fatal("sustained usage not reported")
+	}
+	assert.NoError(t, monitor.Stop())
+	
+	assert.InDelta(t, 88.75, event.CurrentValue, 0.01)
+}
+
+func TestThresholdConsecutiveSamples(t *testing.T) {
+	config := overThresholdsConfig("test-component")
+	config.ThresholdHandlerMode = watchdog.HandlerModeSync
+	config.ThresholdConsecutiveSamples = 3
+	monitor := watchdog.NewResourceMonitor(config)
+	
+	// Over the threshold twice, then continuously
+	component := &scriptedComponent{
+		MockMonitorableComponent: NewMockMonitorableComponent("test-component"),
+		cpu:                      []float64{90, 90, 10, 90},
+	}
+	assert.NoError(t, monitor.AddComponent(component))
+	
+	samplesAtEvent := make(chan int, 100)
+	monitor.AddThresholdHandler(func(event watchdog.ThresholdExceededEvent) {
+		if event.ResourceType == "CPU" {
+			history, _ := monitor.GetResourceHistory("test-component")
+			samplesAtEvent <- len(history)
+		}
+	})
+	
+	assert.NoError(t, monitor.Start())
+	var samples int
+	select {
+	case samples = <-samplesAtEvent:
+	case <-time.After(time.Second):
+		t.Fatal("sustained usage not reported")
+	}
+	assert.NoError(t, monitor.Stop())
+	
+	// Reported on the third consecutive sample over the threshold
+	assert.Equal(t, 6, samples)
+}
+
+func TestAutoTuneThresholds(t *testing.T) {
+	config := overThresholdsConfig("uniform", "capped")
+	config.MonitoringInterval = time.Millisecond
+	config.ThresholdHandlerMode = watchdog.HandlerModeSync
+	config.AutoTune = watchdog.AutoTuneConfig{
+		Enabled:        true,
+		Percentile:     0.9,
+		MarginPercent:  10,
+		WarmupSamples:  50,
+		UpdateInterval: time.Hour,
+	}
+	capped := config.ComponentConfigs["capped"]
+	capped.MaxMemoryMB = 105
+	config.ComponentConfigs["capped"] = capped
+	monitor := watchdog.NewResourceMonitor(config)
+	
+	// CPU uniformly distributed over 1-10% during the warmup, then 10%
+	var script []float64
+	for i := 0; i < 5; i++ {
+		for cpu := 1; cpu <= 10; cpu++ {
+			script = append(script, float64(cpu))
+		}
+	}
+	for _, name := range []string{"uniform", "capped"} {
+		component := &scriptedComponent{
+			MockMonitorableComponent: NewMockMonitorableComponent(name),
+			cpu:                      append([]float64(nil), script...),
+		}
+		assert.NoError(t, monitor.AddComponent(component))
+	}
+	
+	// The static thresholds apply until the warmup is over
+	thresholds := monitor.GetEffectiveThresholds()
+	assert.False(t, thresholds["uniform"].Tuned)
+	assert.Equal(t, 80.0, thresholds["uniform"].MaxCPUPercent)
+	assert.Equal(t, 200.0, thresholds["uniform"].MaxMemoryMB)
+	
+	eventCh := make(chan watchdog.ThresholdExceededEvent, 1000)
+	monitor.AddThresholdHandler(func(event watchdog.ThresholdExceededEvent) {
+		if event.ComponentName == "uniform" && event.ResourceType == "CPU" {
+			eventCh <- event
+		}
+	})
+	
+	assert.NoError(t, monitor.Start())
+	var event watchdog.ThresholdExceededEvent
+	select {
+	case event = <-eventCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("tuned threshold not applied")
+	}
+	assert.NoError(t, monitor.Stop())
+	
+	// p90 of 1-10% is 9%, plus a 10% margin
+	thresholds = monitor.GetEffectiveThresholds()
+	assert.True(t, thresholds["uniform"].Tuned)
+	assert.False(t, thresholds["uniform"].UpdatedAt.IsZero())
+	assert.InDelta(t, 9.9, thresholds["uniform"].MaxCPUPercent, 0.2)
+	assert.InDelta(t, 9.9, event.ThresholdValue, 0.2)
+	assert.Equal(t, 10.0, event.CurrentValue)
+	
+	// The memory is constant at 100MB, the static threshold caps the margin
+	assert.InDelta(t, 110.0, thresholds["uniform"].MaxMemoryMB, 2)
+	assert.Equal(t, 105.0, thresholds["capped"].MaxMemoryMB)
+}
+
 func TestResourceHistory(t *testing.T) {
 	config := watchdog.Config{
 		MonitoringInterval: 10 * time.Millisecond,
@@ -285,7 +687,7 @@ func TestResourceHistory(t *testing.T) {
 			MemoryBytes: (100 + uint64(i*50)) * 1024 * 1024,
 			FileDescriptors: 10 + i*5,
 			Goroutines: 5 + i*2,
-			LastUpdated: time.Now(),
+			Timestamp: time.Now(),
 		})
 		time.Sleep(15 * time.Millisecond)
 	}
@@ -300,6 +702,63 @@ func TestResourceHistory(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestSelfBudgetGuardTrimsHistory(t *testing.T) {
+	config := watchdog.Config{
+		MonitoringInterval: 10 * time.Millisecond,
+		ThresholdHandlerMode: watchdog.HandlerModeSync,
+		SelfBudget: watchdog.SelfBudgetConfig{
+			Enabled:                 true,
+			MaxMemoryMB:             0.05,
+			GuardMonitoringInterval: 20 * time.Millisecond,
+			GuardHistoryLen:         2,
+		},
+	}
+	
+	monitor := watchdog.NewResourceMonitor(config)
+	
+	var mu sync.Mutex
+	var events []watchdog.ThresholdExceededEvent
+	monitor.AddThresholdHandler(func(event watchdog.ThresholdExceededEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+	
+	// Inflate the usage history beyond the memory budget of the monitor
+	names := make([]string, 40)
+	for i := range names {
+		names[i] = fmt.Sprintf("component-%d", i)
+		assert.NoError(t, monitor.AddComponent(NewMockMonitorableComponent(names[i])))
+	}
+	
+	assert.NoError(t, monitor.Start())
+	defer monitor.Stop()
+	
+	assert.Eventually(t, monitor.SelfGuardTripped, time.Second, 5*time.Millisecond)
+	
+	// The monitor reports its own usage like any other component
+	usage, ok := monitor.GetResourceUsage(watchdog.SelfComponentName)
+	assert.True(t, ok)
+	assert.Equal(t, 1, usage.Goroutines)
+	
+	// Wait for further checks, the history must stay trimmed
+	time.Sleep(100 * time.Millisecond)
+	for _, name := range names {
+		history, ok := monitor.GetResourceHistory(name)
+		assert.True(t, ok)
+		assert.NotEmpty(t, history)
+		assert.LessOrEqual(t, len(history), 2, name)
+	}
+	
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, watchdog.SelfComponentName, events[0].ComponentName)
+		assert.Equal(t, "Memory", events[0].ResourceType)
+		assert.Equal(t, 0.05, events[0].ThresholdValue)
+	}
+}
+
 func TestDegradationLevels(t *testing.T) {
 	config := watchdog.Config{
 		MonitoringInterval: 10 * time.Millisecond,
@@ -349,7 +808,7 @@ func TestDegradationLevels(t *testing.T) {
 		MemoryBytes: 160 * 1024 * 1024, // > 150 MB warning threshold
 		FileDescriptors: 50,
 		Goroutines: 50,
-		LastUpdated: time.Now(),
+		Timestamp: time.Now(),
 	})
 	
 	// Wait for degradation to be detected
@@ -366,7 +825,7 @@ func TestDegradationLevels(t *testing.T) {
 		MemoryBytes: 190 * 1024 * 1024, // > 180 MB critical threshold
 		FileDescriptors: 50,
 		Goroutines: 50,
-		LastUpdated: time.Now(),
+		Timestamp: time.Now(),
 	})
 	
 	// Wait for degradation to be updated
@@ -383,7 +842,7 @@ func TestDegradationLevels(t *testing.T) {
 		MemoryBytes: 100 * 1024 * 1024, // Below warning threshold
 		FileDescriptors: 50,
 		Goroutines: 50,
-		LastUpdated: time.Now(),
+		Timestamp: time.Now(),
 	})
 	
 	// Wait for degradation to be updated
@@ -399,6 +858,102 @@ func TestDegradationLevels(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestGCPauseTracker(t *testing.T) {
+	tracker := watchdog.NewGCPauseTracker()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	
+	// The first reading has nothing to compare to
+	assert.Equal(t, 0.0, tracker.Percent(5e9, start))
+	
+	// 50ms of pauses in 1s
+	assert.InDelta(t, 5.0, tracker.Percent(5.05e9, start.Add(time.Second)), 0.001)
+	
+	// No GC
+	assert.Equal(t, 0.0, tracker.Percent(5.05e9, start.Add(2*time.Second)))
+	
+	// 300ms of pauses in 500ms
+	assert.InDelta(t, 60.0, tracker.Percent(5.35e9, start.Add(2500*time.Millisecond)), 0.001)
+	
+	// A total that went back, e.g. another process, is ignored
+	assert.Equal(t, 0.0, tracker.Percent(1e9, start.Add(3*time.Second)))
+}
+
+// gcThrashingComponent reports the GC percentage of a simulated
+// PauseTotalNs rising by pausePerRead on every read, a second apart
+type gcThrashingComponent struct {
+	*MockMonitorableComponent
+	tracker      *watchdog.GCPauseTracker
+	pauseTotalNs uint64
+	pausePerRead time.Duration
+	now          time.Time
+}
+
+// ResourceUsage implements Component interface
+func (g *gcThrashingComponent) ResourceUsage() watchdog.ResourceUsage {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	
+	g.pauseTotalNs += uint64(g.pausePerRead)
+	g.now = g.now.Add(time.Second)
+	
+	usage := g.resourceUsage
+	usage.GCPercent = g.tracker.Percent(g.pauseTotalNs, g.now)
+	return usage
+}
+
+func TestGCThreshold(t *testing.T) {
+	config := watchdog.Config{
+		MonitoringInterval: 10 * time.Millisecond,
+		ThresholdHandlerMode: watchdog.HandlerModeSync,
+		ComponentConfigs: map[string]watchdog.ComponentConfig{
+			"thrashing": {Enabled: true, MaxCPUPercent: 80, MaxMemoryMB: 200, MaxFileDescriptors: 100, MaxGoroutines: 100, MaxGCPercent: 20},
+			"steady":    {Enabled: true, MaxCPUPercent: 80, MaxMemoryMB: 200, MaxFileDescriptors: 100, MaxGoroutines: 100, MaxGCPercent: 20},
+		},
+	}
+	monitor := watchdog.NewResourceMonitor(config)
+	
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	thrashing := &gcThrashingComponent{
+		MockMonitorableComponent: NewMockMonitorableComponent("thrashing"),
+		tracker:                  watchdog.NewGCPauseTracker(),
+		pausePerRead:             300 * time.Millisecond,
+		now:                      start,
+	}
+	steady := &gcThrashingComponent{
+		MockMonitorableComponent: NewMockMonitorableComponent("steady"),
+		tracker:                  watchdog.NewGCPauseTracker(),
+		pausePerRead:             10 * time.Millisecond,
+		now:                      start,
+	}
+	assert.NoError(t, monitor.AddComponent(thrashing))
+	assert.NoError(t, monitor.AddComponent(steady))
+	
+	events := make(chan watchdog.ThresholdExceededEvent, 100)
+	monitor.AddThresholdHandler(func(event watchdog.ThresholdExceededEvent) {
+		if event.ResourceType == "GC" {
+			events <- event
+		}
+	})
+	
+	assert.NoError(t, monitor.Start())
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, monitor.Stop())
+	close(events)
+	
+	var count int
+	for event := range events {
+		assert.Equal(t, "thrashing", event.ComponentName)
+		assert.InDelta(t, 30.0, event.CurrentValue, 0.001)
+		assert.Equal(t, 20.0, event.ThresholdValue)
+		count++
+	}
+	assert.Greater(t, count, 0)
+	
+	usage, ok := monitor.GetResourceUsage("steady")
+	assert.True(t, ok)
+	assert.InDelta(t, 1.0, usage.GCPercent, 0.001)
+}
+
 func TestTotalResourceUsage(t *testing.T) {
 	config := watchdog.Config{
 		MonitoringInterval: 10 * time.Millisecond,
@@ -412,5 +967,12 @@ func TestTotalResourceUsage(t *testing.T) {
 	// Basic validation of returned data
 	assert.True(t, usage.MemoryBytes > 0)
 	assert.True(t, usage.Goroutines > 0)
-	assert.False(t, usage.LastUpdated.IsZero())
+	assert.False(t, usage.Timestamp.IsZero())
+	
+	// The GC percentage covers the time since the previous call
+	assert.Equal(t, 0.0, usage.GCPercent)
+	runtime.GC()
+	usage = monitor.GetTotalResourceUsage()
+	assert.GreaterOrEqual(t, usage.GCPercent, 0.0)
+	assert.LessOrEqual(t, usage.GCPercent, 100.0)
 }
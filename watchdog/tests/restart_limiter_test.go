@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/watchdog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartRateLimiter(t *testing.T) {
+	limiter := watchdog.NewRestartRateLimiter(3)
+	now := time.Now()
+
+	// A full bucket allows a burst of perMinute restarts
+	for i := 0; i < 3; i++ {
+		assert.True(t, limiter.Allow(now), "restart %d", i)
+	}
+	assert.False(t, limiter.Allow(now))
+	assert.Equal(t, 0, limiter.Available(now))
+
+	// Tokens refill at perMinute per minute
+	assert.False(t, limiter.Allow(now.Add(10*time.Second)))
+	assert.True(t, limiter.Allow(now.Add(20*time.Second)))
+	assert.False(t, limiter.Allow(now.Add(20*time.Second)))
+
+	// The bucket never holds more than perMinute tokens
+	assert.Equal(t, 3, limiter.Available(now.Add(time.Hour)))
+
+	// A clock going back does not refill the bucket
+	assert.Equal(t, 3, limiter.Available(now))
+}
+
+func TestRestartRateLimiterUnlimited(t *testing.T) {
+	limiter := watchdog.NewRestartRateLimiter(0)
+	now := time.Now()
+
+	for i := 0; i < 100; i++ {
+		assert.True(t, limiter.Allow(now))
+	}
+	assert.Equal(t, -1, limiter.Available(now))
+}
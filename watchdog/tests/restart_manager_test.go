@@ -3,6 +3,7 @@ package tests
 import (
 	"context"
 	"errors"
+	"math/rand"
 	"testing"
 	"time"
 
@@ -128,6 +129,36 @@ func TestAlreadyRunning(t *testing.T) {
 }
 
 // TestFailedRestart tests a failed restart
+func TestForceRestartRunning(t *testing.T) {
+	config := watchdog.RestartConfig{
+		Enabled:                true,
+		GracefulShutdownTimeout: 1 * time.Second,
+		MaxRestartAttempts:     3,
+		RestartBackoffInitial:  1 * time.Second,
+		RestartBackoffMax:      30 * time.Second,
+		RestartBackoffFactor:   2.0,
+	}
+	
+	component := new(MockRestartableComponent)
+	
+	// Set up the component to be running
+	component.On("IsRunning").Return(true)
+	component.On("Shutdown", mock.Anything).Return(nil)
+	component.On("Start", mock.Anything).Return(nil)
+	
+	manager := watchdog.NewRestartManager(config, component)
+	
+	// Force a restart
+	success, err := manager.ForceRestart(context.Background())
+	assert.True(t, success)
+	assert.NoError(t, err)
+	
+	// Verify the component was restarted anyway
+	component.AssertCalled(t, "Shutdown", mock.Anything)
+	component.AssertCalled(t, "Start", mock.Anything)
+	assert.False(t, manager.GetLastRestartTime().IsZero())
+}
+
 func TestFailedRestart(t *testing.T) {
 	config := watchdog.RestartConfig{
 		Enabled:                true,
@@ -219,6 +250,7 @@ func TestBackoff(t *testing.T) {
 		RestartBackoffInitial:  100 * time.Millisecond,
 		RestartBackoffMax:      500 * time.Millisecond,
 		RestartBackoffFactor:   2.0,
+		BackoffJitter:          0.2,
 	}
 	
 	component := new(MockRestartableComponent)
@@ -228,13 +260,18 @@ func TestBackoff(t *testing.T) {
 	component.On("Shutdown", mock.Anything).Return(nil)
 	component.On("Start", mock.Anything).Return(errors.New("start failed"))
 	
-	manager := watchdog.NewRestartManager(config, component)
+	manager := watchdog.NewRestartManagerWithRand(config, component, rand.New(rand.NewSource(1)))
 	
 	// First attempt
 	success, err := manager.AttemptRestart(context.Background())
 	assert.False(t, success)
 	assert.Error(t, err)
 	
+	// The first backoff is the initial one, within the jitter
+	backoff := manager.GetBackoff()
+	assert.GreaterOrEqual(t, backoff, 80*time.Millisecond)
+	assert.LessOrEqual(t, backoff, 120*time.Millisecond)
+	
 	// Try again immediately (should fail due to backoff)
 	success, err = manager.AttemptRestart(context.Background())
 	assert.False(t, success)
@@ -242,7 +279,7 @@ func TestBackoff(t *testing.T) {
 	assert.Contains(t, err.Error(), "backoff in progress")
 	
 	// Wait for first backoff to complete
-	time.Sleep(110 * time.Millisecond)
+	time.Sleep(backoff + 10*time.Millisecond)
 	
 	// Second attempt
 	success, err = manager.AttemptRestart(context.Background())
@@ -250,6 +287,11 @@ func TestBackoff(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to restart component")
 	
+	// The backoff doubled, within the jitter
+	backoff = manager.GetBackoff()
+	assert.GreaterOrEqual(t, backoff, 160*time.Millisecond)
+	assert.LessOrEqual(t, backoff, 240*time.Millisecond)
+	
 	// Try again immediately (should fail due to increased backoff)
 	success, err = manager.AttemptRestart(context.Background())
 	assert.False(t, success)
@@ -260,6 +302,45 @@ func TestBackoff(t *testing.T) {
 	component.AssertNumberOfCalls(t, "Start", 2)
 }
 
+// TestBackoffJitterDeterministic tests that the jitter follows the injected RNG
+func TestBackoffJitterDeterministic(t *testing.T) {
+	config := watchdog.RestartConfig{
+		Enabled:                true,
+		GracefulShutdownTimeout: 1 * time.Second,
+		MaxRestartAttempts:     10,
+		RestartBackoffInitial:  1 * time.Second,
+		RestartBackoffMax:      30 * time.Second,
+		RestartBackoffFactor:   2.0,
+		BackoffJitter:          0.5,
+	}
+	
+	backoffs := func(seed int64) time.Duration {
+		component := new(MockRestartableComponent)
+		component.On("IsRunning").Return(false)
+		component.On("Shutdown", mock.Anything).Return(nil)
+		component.On("Start", mock.Anything).Return(errors.New("start failed"))
+		
+		manager := watchdog.NewRestartManagerWithRand(config, component, rand.New(rand.NewSource(seed)))
+		manager.AttemptRestart(context.Background())
+		return manager.GetBackoff()
+	}
+	
+	// The same seed gives the same delay, others spread around the backoff
+	assert.Equal(t, backoffs(42), backoffs(42))
+	spread := map[time.Duration]bool{}
+	for seed := int64(0); seed < 10; seed++ {
+		backoff := backoffs(seed)
+		assert.GreaterOrEqual(t, backoff, 500*time.Millisecond)
+		assert.LessOrEqual(t, backoff, 1500*time.Millisecond)
+		spread[backoff] = true
+	}
+	assert.Greater(t, len(spread), 1)
+	
+	// Without jitter the delay is exact
+	config.BackoffJitter = 0
+	assert.Equal(t, 1*time.Second, backoffs(42))
+}
+
 // TestShutdownFailure tests handling a failed shutdown
 func TestShutdownFailure(t *testing.T) {
 	config := watchdog.RestartConfig{
@@ -366,3 +447,50 @@ func TestDisabledRestart(t *testing.T) {
 	component.AssertNotCalled(t, "Shutdown")
 	component.AssertNotCalled(t, "Start")
 }
+
+// TestRestartDue tests reporting whether a restart would happen
+func TestRestartDue(t *testing.T) {
+	config := watchdog.RestartConfig{
+		Enabled:                true,
+		GracefulShutdownTimeout: 1 * time.Second,
+		MaxRestartAttempts:     2,
+		RestartBackoffInitial:  50 * time.Millisecond,
+		RestartBackoffMax:      30 * time.Second,
+		RestartBackoffFactor:   2.0,
+	}
+	
+	// A running component is only restarted by force
+	running := new(MockRestartableComponent)
+	running.On("IsRunning").Return(true)
+	manager := watchdog.NewRestartManager(config, running)
+	assert.False(t, manager.RestartDue(false))
+	assert.True(t, manager.RestartDue(true))
+	
+	// A stopped component that fails to start waits for the backoff
+	component := new(MockRestartableComponent)
+	component.On("IsRunning").Return(false)
+	component.On("Shutdown", mock.Anything).Return(nil)
+	component.On("Start", mock.Anything).Return(errors.New("start failed"))
+	manager = watchdog.NewRestartManager(config, component)
+	assert.True(t, manager.RestartDue(false))
+	
+	_, err := manager.AttemptRestart(context.Background())
+	assert.Error(t, err)
+	assert.False(t, manager.RestartDue(false))
+	assert.False(t, manager.RestartDue(true))
+	
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, manager.RestartDue(false))
+	
+	// Not once the restart attempts are exhausted
+	_, err = manager.AttemptRestart(context.Background())
+	assert.Error(t, err)
+	time.Sleep(110 * time.Millisecond)
+	assert.False(t, manager.RestartDue(false))
+	assert.False(t, manager.RestartDue(true))
+	
+	// Not when restarts are disabled
+	config.Enabled = false
+	manager = watchdog.NewRestartManager(config, component)
+	assert.False(t, manager.RestartDue(false))
+}
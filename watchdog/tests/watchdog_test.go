@@ -3,6 +3,7 @@ package tests
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -43,28 +44,40 @@ func (m *MockComponent) SetHealth(health watchdog.HealthStatus) {
 
 // SetResourceUsage sets up the mock to return a specific resource usage
 func (m *MockComponent) SetResourceUsage(usage watchdog.ResourceUsage) {
-	m.On("GetResourceUsage").Return(usage)
+	m.Replace("GetResourceUsage").Return(usage)
+}
+
+// Replace removes the expectation set for a method before adding a new one,
+// as the mock returns the first matching expectation
+func (m *MockComponent) Replace(method string, arguments ...interface{}) *mock.Call {
+	for _, call := range append([]*mock.Call(nil), m.ExpectedCalls...) {
+		if call.Method == method {
+			call.Unset()
+			break
+		}
+	}
+	return m.On(method, arguments...)
 }
 
 // Shutdown implements the Restartable interface
 func (m *MockComponent) Shutdown(ctx context.Context) error {
 	args := m.Called(ctx)
-	
+
 	m.mutex.Lock()
 	m.running = false
 	m.mutex.Unlock()
-	
+
 	return args.Error(0)
 }
 
 // Start implements the Restartable interface
 func (m *MockComponent) Start(ctx context.Context) error {
 	args := m.Called(ctx)
-	
+
 	m.mutex.Lock()
 	m.running = true
 	m.mutex.Unlock()
-	
+
 	return args.Error(0)
 }
 
@@ -75,16 +88,23 @@ func (m *MockComponent) IsRunning() bool {
 	return m.running
 }
 
+// SetRunning sets the running state for testing, e.g. to simulate a crash
+func (m *MockComponent) SetRunning(running bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.running = running
+}
+
 // SetDegradationLevel implements the Degradable interface
 func (m *MockComponent) SetDegradationLevel(level int) error {
 	args := m.Called(level)
-	
+
 	if args.Error(0) == nil {
 		m.mutex.Lock()
 		m.degradLevel = level
 		m.mutex.Unlock()
 	}
-	
+
 	return args.Error(0)
 }
 
@@ -95,88 +115,109 @@ func (m *MockComponent) GetDegradationLevel() int {
 	return m.degradLevel
 }
 
+// testConfig returns a valid configuration checking every 10ms the given
+// components against generous thresholds, each with a circuit breaker and
+// three degradation levels. Deadlock detection, the global budget and the
+// sustained degradation check are disabled
+func testConfig(names ...string) watchdog.Config {
+	config := watchdog.DefaultConfig()
+	config.MonitoringInterval = 10 * time.Millisecond
+	config.DeadlockDetection.Enabled = false
+	config.GlobalBudget = watchdog.GlobalBudgetConfig{}
+	config.SustainedDegradationDuration = 0
+	config.ComponentConfigs = make(map[string]watchdog.ComponentConfig, len(names))
+	for _, name := range names {
+		config.ComponentConfigs[name] = testComponentConfig()
+	}
+	return config
+}
+
+// testComponentConfig returns the component configuration of testConfig
+func testComponentConfig() watchdog.ComponentConfig {
+	return watchdog.ComponentConfig{
+		Enabled:            true,
+		MaxCPUPercent:      90.0,
+		MaxMemoryMB:        1000,
+		MaxFileDescriptors: 1000,
+		MaxGoroutines:      1000,
+		MaxGCPercent:       10.0,
+		CircuitBreaker: watchdog.CircuitBreakerConfig{
+			Enabled:                  true,
+			FailureThreshold:         3,
+			ResetTimeout:             30 * time.Second,
+			HalfOpenSuccessThreshold: 2,
+		},
+		DegradationLevels: []watchdog.DegradationLevel{
+			{Name: "warning", CPUThresholdPercent: 50, MemoryThresholdMB: 500, Actions: []string{"reduce_scan_frequency"}},
+			{Name: "severe", CPUThresholdPercent: 70, MemoryThresholdMB: 700, Actions: []string{"reduce_scan_frequency", "filter_events"}},
+			{Name: "critical", CPUThresholdPercent: 80, MemoryThresholdMB: 800, Actions: []string{"reduce_scan_frequency", "filter_events", "reduce_tracked_processes"}},
+		},
+	}
+}
+
 // NewMockComponent creates a new mock component for testing
 func NewMockComponent() *MockComponent {
-	mock := &MockComponent{
+	component := &MockComponent{
 		healthStatus: watchdog.HealthOK,
 		running:      true,
 		degradLevel:  0,
 	}
-	
+
 	// Setup default behavior
-	mock.On("Shutdown", mock.Anything).Return(nil)
-	mock.On("Start", mock.Anything).Return(nil)
-	mock.On("SetDegradationLevel", mock.Anything).Return(nil)
-	
+	component.On("Shutdown", mock.Anything).Return(nil)
+	component.On("Start", mock.Anything).Return(nil)
+	component.On("SetDegradationLevel", mock.Anything).Return(nil)
+
 	// Setup default resource usage
 	defaultUsage := watchdog.ResourceUsage{
-		CPUPercent:  1.0,
-		MemoryMB:    10.0,
-		Goroutines:  10,
-		FileHandles: 5,
-		GCPercent:   0.5,
-		Timestamp:   time.Now(),
+		CPUPercent:      1.0,
+		MemoryBytes:     10 * 1024 * 1024,
+		Goroutines:      10,
+		FileDescriptors: 5,
+		GCPercent:       0.5,
+		Timestamp:       time.Now(),
 	}
-	mock.SetResourceUsage(defaultUsage)
-	
-	return mock
+	component.SetResourceUsage(defaultUsage)
+
+	return component
 }
 
 func TestWatchdogStartStop(t *testing.T) {
-	config := watchdog.Config{
-		MonitorInterval: 10 * time.Millisecond,
-		GlobalThresholds: watchdog.ResourceThresholds{
-			MaxCPUPercent:  90.0,
-			MaxMemoryMB:    1000,
-			MaxGoroutines:  1000,
-			MaxFileHandles: 1000,
-			MaxGCPercent:   10.0,
-		},
-		DeadlockDetection: watchdog.DeadlockConfig{
-			Enabled:             true,
-			CheckInterval:       50 * time.Millisecond,
-			GoroutineThreshold:  1000,
-		},
-		DegradationEnabled:  true,
-		DegradationLevels:   3,
-		EventsEnabled:       true,
+	config := testConfig("test-component")
+	config.DeadlockDetection = watchdog.DeadlockConfig{
+		Enabled:                true,
+		HeartbeatInterval:      50 * time.Millisecond,
+		HeartbeatMissThreshold: 3,
+		CheckInterval:          50 * time.Millisecond,
+		MaxOperationTime:       time.Second,
 	}
-	
+
 	wd, err := watchdog.NewWatchdog(config)
 	assert.NoError(t, err)
 	assert.NotNil(t, wd)
-	
+
 	// Start the watchdog
 	err = wd.Start()
 	assert.NoError(t, err)
-	
+
 	// Stop the watchdog
 	err = wd.Stop()
 	assert.NoError(t, err)
 }
 
 func TestRegisterComponent(t *testing.T) {
-	config := watchdog.Config{
-		MonitorInterval: 10 * time.Millisecond,
-		GlobalThresholds: watchdog.ResourceThresholds{
-			MaxCPUPercent:  90.0,
-			MaxMemoryMB:    1000,
-			MaxGoroutines:  1000,
-			MaxFileHandles: 1000,
-			MaxGCPercent:   10.0,
-		},
-	}
-	
+	config := testConfig("test-component")
+
 	wd, err := watchdog.NewWatchdog(config)
 	assert.NoError(t, err)
-	
+
 	// Create a mock component
 	mockComponent := NewMockComponent()
-	
+
 	// Register the component
 	err = wd.RegisterComponent("test-component", mockComponent)
 	assert.NoError(t, err)
-	
+
 	// Get component status
 	status, err := wd.GetComponentStatus("test-component")
 	assert.NoError(t, err)
@@ -185,77 +226,59 @@ func TestRegisterComponent(t *testing.T) {
 	assert.Equal(t, watchdog.CircuitClosed, status.CircuitState)
 	assert.Equal(t, 0, status.RestartCount)
 	assert.Equal(t, 0, status.DegradationLevel)
-	
+
 	// Try to register the same component again
 	err = wd.RegisterComponent("test-component", mockComponent)
 	assert.Error(t, err)
-	
+
 	// Register a component that doesn't implement Monitorable
 	err = wd.RegisterComponent("invalid-component", &struct{}{})
 	assert.Error(t, err)
 }
 
 func TestUnregisterComponent(t *testing.T) {
-	config := watchdog.Config{
-		MonitorInterval: 10 * time.Millisecond,
-		GlobalThresholds: watchdog.ResourceThresholds{
-			MaxCPUPercent:  90.0,
-			MaxMemoryMB:    1000,
-			MaxGoroutines:  1000,
-			MaxFileHandles: 1000,
-			MaxGCPercent:   10.0,
-		},
-	}
-	
+	config := testConfig("test-component")
+
 	wd, err := watchdog.NewWatchdog(config)
 	assert.NoError(t, err)
-	
+
 	// Create a mock component
 	mockComponent := NewMockComponent()
-	
+
 	// Register the component
 	err = wd.RegisterComponent("test-component", mockComponent)
 	assert.NoError(t, err)
-	
+
 	// Unregister the component
 	err = wd.UnregisterComponent("test-component")
 	assert.NoError(t, err)
-	
+
 	// Check that the component is no longer registered
 	_, err = wd.GetComponentStatus("test-component")
 	assert.Error(t, err)
-	
+
 	// Try to unregister a non-registered component
 	err = wd.UnregisterComponent("non-existent")
 	assert.Error(t, err)
 }
 
 func TestGetAllComponentStatuses(t *testing.T) {
-	config := watchdog.Config{
-		MonitorInterval: 10 * time.Millisecond,
-		GlobalThresholds: watchdog.ResourceThresholds{
-			MaxCPUPercent:  90.0,
-			MaxMemoryMB:    1000,
-			MaxGoroutines:  1000,
-			MaxFileHandles: 1000,
-			MaxGCPercent:   10.0,
-		},
-	}
-	
+	config := testConfig("component1", "component2")
+
 	wd, err := watchdog.NewWatchdog(config)
 	assert.NoError(t, err)
-	
+
 	// Create mock components
 	component1 := NewMockComponent()
 	component2 := NewMockComponent()
-	
+
 	// Register the components
 	err = wd.RegisterComponent("component1", component1)
 	assert.NoError(t, err)
-	
+
 	err = wd.RegisterComponent("component2", component2)
 	assert.NoError(t, err)
-	
+
 	// Get all component statuses
 	statuses := wd.GetAllComponentStatuses()
 	assert.Len(t, statuses, 2)
@@ -264,27 +287,18 @@ func TestGetAllComponentStatuses(t *testing.T) {
 }
 
 func TestSetThresholds(t *testing.T) {
-	config := watchdog.Config{
-		MonitorInterval: 10 * time.Millisecond,
-		GlobalThresholds: watchdog.ResourceThresholds{
-			MaxCPUPercent:  90.0,
-			MaxMemoryMB:    1000,
-			MaxGoroutines:  1000,
-			MaxFileHandles: 1000,
-			MaxGCPercent:   10.0,
-		},
-	}
-	
+	config := testConfig("test-component")
+
 	wd, err := watchdog.NewWatchdog(config)
 	assert.NoError(t, err)
-	
+
 	// Create a mock component
 	mockComponent := NewMockComponent()
-	
+
 	// Register the component
 	err = wd.RegisterComponent("test-component", mockComponent)
 	assert.NoError(t, err)
-	
+
 	// Set new thresholds
 	newThresholds := watchdog.ResourceThresholds{
 		MaxCPUPercent:  50.0,
@@ -293,50 +307,185 @@ func TestSetThresholds(t *testing.T) {
 		MaxFileHandles: 500,
 		MaxGCPercent:   5.0,
 	}
-	
+
 	err = wd.SetThresholds("test-component", newThresholds)
 	assert.NoError(t, err)
-	
+
 	// Try to set thresholds for a non-registered component
 	err = wd.SetThresholds("non-existent", newThresholds)
 	assert.Error(t, err)
 }
 
-func TestComponentMonitoring(t *testing.T) {
-	config := watchdog.Config{
-		MonitorInterval: 10 * time.Millisecond,
-		GlobalThresholds: watchdog.ResourceThresholds{
-			MaxCPUPercent:  90.0,
-			MaxMemoryMB:    1000,
-			MaxGoroutines:  1000,
-			MaxFileHandles: 1000,
-			MaxGCPercent:   10.0,
-		},
+func TestReloadConfig(t *testing.T) {
+	config := testConfig("test-component")
+	componentConfig := config.ComponentConfigs["test-component"]
+
+	wd, err := watchdog.NewWatchdog(config)
+	assert.NoError(t, err)
+
+	incidents := make(chan watchdog.Incident, 100)
+	wd.AddIncidentHook(func(incident watchdog.Incident) error {
+		incidents <- incident
+		return nil
+	})
+
+	// The component uses 50% CPU, within its thresholds
+	mockComponent := NewMockComponent()
+	mockComponent.SetResourceUsage(watchdog.ResourceUsage{
+		CPUPercent: 50.0,
+		Timestamp:  time.Now(),
+	})
+	mockComponent.SetHealth(watchdog.HealthOK)
+
+	err = wd.RegisterComponent("test-component", mockComponent)
+	assert.NoError(t, err)
+	err = wd.RegisterComponent("other-component", NewMockComponent())
+	assert.NoError(t, err)
+
+	err = wd.Start()
+	assert.NoError(t, err)
+
+	// Wait for a few ticks without incidents
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, incidents)
+	before := wd.GetAllComponentStatuses()
+	assert.Equal(t, watchdog.HealthOK, before["test-component"].Health)
+
+	// An invalid configuration is rejected
+	invalid := config
+	invalid.MonitoringInterval = -1
+	err = wd.ReloadConfig(invalid)
+	assert.Error(t, err)
+
+	// Lower the CPU threshold of the component, drop its critical
+	// level and toggle restarts
+	reloaded := config
+	lowered := componentConfig
+	lowered.MaxCPUPercent = 40.0
+	lowered.DegradationLevels = componentConfig.DegradationLevels[:1]
+	reloaded.ComponentConfigs = map[string]watchdog.ComponentConfig{
+		"test-component": lowered,
 	}
-	
+	reloaded.RestartPolicy.Enabled = !config.RestartPolicy.Enabled
+	err = wd.ReloadConfig(reloaded)
+	assert.NoError(t, err)
+
+	// The new threshold takes effect on the next tick
+	select {
+	case incident := <-incidents:
+		assert.Equal(t, "test-component", incident.ComponentName)
+		assert.Equal(t, watchdog.IncidentResourceExceeded, incident.Type)
+	case <-time.After(time.Second):
+		t.Fatal("reloaded threshold not applied")
+	}
+
+	err = wd.Stop()
+	assert.NoError(t, err)
+
+	// No component status was dropped
+	after := wd.GetAllComponentStatuses()
+	assert.Len(t, after, 2)
+	assert.Equal(t, watchdog.HealthOK, after["other-component"].Health)
+	assert.NotEmpty(t, after["test-component"].Incidents)
+}
+
+func TestReloadConfigRemovesComponent(t *testing.T) {
+	config := testConfig("collector", "sampler")
+
 	wd, err := watchdog.NewWatchdog(config)
 	assert.NoError(t, err)
-	
+
+	incidents := make(chan watchdog.Incident, 100)
+	wd.AddIncidentHook(func(incident watchdog.Incident) error {
+		incidents <- incident
+		return nil
+	})
+
+	// The component uses 80% CPU, within its configured thresholds but
+	// above the default ones
+	component := &loadComponent{
+		MockComponent: NewMockComponent(),
+		usage:         watchdog.ResourceUsage{CPUPercent: 80.0},
+	}
+	err = wd.RegisterComponent("collector", component)
+	assert.NoError(t, err)
+
+	err = wd.Start()
+	assert.NoError(t, err)
+	defer wd.Stop()
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Empty(t, incidents)
+
+	// Dropping its configuration falls back to the defaults, without a
+	// circuit breaker or restarts
+	err = wd.ReloadConfig(testConfig("sampler"))
+	assert.NoError(t, err)
+	assert.Error(t, wd.ForceOpenCircuit("collector"))
+	waitIncident(t, incidents, watchdog.IncidentResourceExceeded)
+
+	component.SetRunning(false)
+	waitIncident(t, incidents, watchdog.IncidentCrash)
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, component.IsRunning())
+	component.AssertNotCalled(t, "Start", mock.Anything)
+
+	// Configuring it again restores them
+	err = wd.ReloadConfig(config)
+	assert.NoError(t, err)
+	assert.NoError(t, wd.ForceCloseCircuit("collector"))
+
+	component.SetRunning(true)
+	time.Sleep(30 * time.Millisecond)
+	component.SetRunning(false)
+	waitIncident(t, incidents, watchdog.IncidentCrash)
+	assert.Eventually(t, component.IsRunning, time.Second, 10*time.Millisecond)
+	component.AssertCalled(t, "Start", mock.Anything)
+}
+
+// waitIncident waits for an incident of the given type, skipping others
+func waitIncident(t *testing.T, incidents <-chan watchdog.Incident, incidentType watchdog.IncidentType) watchdog.Incident {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case incident := <-incidents:
+			if incident.Type == incidentType {
+				return incident
+			}
+		case <-timeout:
+			t.Fatalf("no %s incident", incidentType)
+			return watchdog.Incident{}
+		}
+	}
+}
+
+func TestComponentMonitoring(t *testing.T) {
+	config := testConfig("test-component")
+
+	wd, err := watchdog.NewWatchdog(config)
+	assert.NoError(t, err)
+
 	// Create a mock component
 	mockComponent := NewMockComponent()
-	
+
 	// Set resource usage that exceeds thresholds
 	highUsage := watchdog.ResourceUsage{
-		CPUPercent:  95.0,
-		MemoryMB:    1500.0,
-		Goroutines:  1500,
-		FileHandles: 1500,
-		GCPercent:   15.0,
-		Timestamp:   time.Now(),
+		CPUPercent:      95.0,
+		MemoryBytes:     1500 * 1024 * 1024,
+		Goroutines:      1500,
+		FileDescriptors: 1500,
+		GCPercent:       15.0,
+		Timestamp:       time.Now(),
 	}
-	
+
 	mockComponent.SetResourceUsage(highUsage)
 	mockComponent.SetHealth(watchdog.HealthDegraded)
-	
+
 	// Register the component
 	err = wd.RegisterComponent("test-component", mockComponent)
 	assert.NoError(t, err)
-	
+
 	// Set component-specific thresholds
 	componentThresholds := watchdog.ResourceThresholds{
 		MaxCPUPercent:  50.0,
@@ -345,143 +494,524 @@ func TestComponentMonitoring(t *testing.T) {
 		MaxFileHandles: 500,
 		MaxGCPercent:   5.0,
 	}
-	
+
 	err = wd.SetThresholds("test-component", componentThresholds)
 	assert.NoError(t, err)
-	
+
 	// Start the watchdog
 	err = wd.Start()
 	assert.NoError(t, err)
-	
+
 	// Wait for monitoring to trigger
 	time.Sleep(50 * time.Millisecond)
-	
+
 	// Get component status
 	status, err := wd.GetComponentStatus("test-component")
 	assert.NoError(t, err)
-	
+
 	// Check that health was updated
 	assert.Equal(t, watchdog.HealthDegraded, status.Health)
-	
+
 	// Check that resource usage was updated
 	assert.InDelta(t, 95.0, status.ResourceUsage.CPUPercent, 0.1)
-	assert.InDelta(t, 1500.0, status.ResourceUsage.MemoryMB, 0.1)
-	
+	assert.InDelta(t, 1500.0, status.ResourceUsage.MemoryMB(), 0.1)
+
 	// Check that circuit breaker was updated (should be open due to threshold violations)
 	assert.Equal(t, watchdog.CircuitOpen, status.CircuitState)
-	
+
 	// Check that incidents were recorded
 	assert.Greater(t, len(status.Incidents), 0)
-	
+
 	// Stop the watchdog
 	err = wd.Stop()
 	assert.NoError(t, err)
 }
 
-func TestRestartableComponent(t *testing.T) {
-	config := watchdog.Config{
-		MonitorInterval: 10 * time.Millisecond,
-		GlobalThresholds: watchdog.ResourceThresholds{
-			MaxCPUPercent:  90.0,
-			MaxMemoryMB:    1000,
-			MaxGoroutines:  1000,
-			MaxFileHandles: 1000,
-			MaxGCPercent:   10.0,
-		},
+func TestIncidentHooks(t *testing.T) {
+	config := testConfig("test-component")
+
+	wd, err := watchdog.NewWatchdog(config)
+	assert.NoError(t, err)
+
+	// A failing hook must not keep the others from being called
+	wd.AddIncidentHook(func(incident watchdog.Incident) error {
+		return errors.New("hook failed")
+	})
+
+	incidents := make(chan watchdog.Incident, 100)
+	wd.AddIncidentHook(func(incident watchdog.Incident) error {
+		incidents <- incident
+		return nil
+	})
+
+	// Create a mock component exceeding the CPU threshold
+	mockComponent := NewMockComponent()
+	mockComponent.SetResourceUsage(watchdog.ResourceUsage{
+		CPUPercent: 95.0,
+		Timestamp:  time.Now(),
+	})
+	mockComponent.SetHealth(watchdog.HealthDegraded)
+
+	err = wd.RegisterComponent("test-component", mockComponent)
+	assert.NoError(t, err)
+
+	err = wd.Start()
+	assert.NoError(t, err)
+
+	// Wait for the hook to be called
+	select {
+	case incident := <-incidents:
+		assert.Equal(t, "test-component", incident.ComponentName)
+		assert.Equal(t, watchdog.IncidentResourceExceeded, incident.Type)
+	case <-time.After(time.Second):
+		t.Fatal("incident hook not called")
 	}
-	
+
+	// Stop the watchdog
+	err = wd.Stop()
+	assert.NoError(t, err)
+}
+
+// nextEvent returns the next event of a subscription, failing the test if
+// none is received in time
+func nextEvent(t *testing.T, events <-chan watchdog.WatchdogEvent) watchdog.WatchdogEvent {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return watchdog.WatchdogEvent{}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	config := testConfig("test-component")
+
+	wd, err := watchdog.NewWatchdog(config)
+	assert.NoError(t, err)
+
+	events, unsubscribe := wd.Subscribe()
+
+	// Create a mock component exceeding the CPU threshold
+	mockComponent := NewMockComponent()
+	mockComponent.SetResourceUsage(watchdog.ResourceUsage{
+		CPUPercent: 95.0,
+		Timestamp:  time.Now(),
+	})
+	mockComponent.SetHealth(watchdog.HealthDegraded)
+
+	err = wd.RegisterComponent("test-component", mockComponent)
+	assert.NoError(t, err)
+
+	err = wd.Start()
+	assert.NoError(t, err)
+
+	// The first check reports the incident, then the status changes
+	event := nextEvent(t, events)
+	assert.Equal(t, watchdog.EventIncident, event.Type)
+	assert.Equal(t, "test-component", event.ComponentName)
+	if assert.NotNil(t, event.Incident) {
+		assert.Equal(t, watchdog.IncidentResourceExceeded, event.Incident.Type)
+	}
+
+	event = nextEvent(t, events)
+	assert.Equal(t, watchdog.EventHealthChanged, event.Type)
+	assert.Equal(t, watchdog.HealthUnknown, event.OldHealth)
+	assert.Equal(t, watchdog.HealthDegraded, event.NewHealth)
+
+	// The repeated breaches degrade the component and open the circuit
+	for {
+		event = nextEvent(t, events)
+		if event.Type == watchdog.EventCircuitStateChanged {
+			break
+		}
+		assert.Contains(t, []watchdog.WatchdogEventType{watchdog.EventIncident, watchdog.EventDegradationChanged}, event.Type)
+	}
+	assert.Equal(t, "test-component", event.ComponentName)
+	assert.Equal(t, watchdog.CircuitClosed, event.OldCircuitState)
+	assert.Equal(t, watchdog.CircuitOpen, event.NewCircuitState)
+
+	err = wd.Stop()
+	assert.NoError(t, err)
+
+	// Unsubscribing closes the channel, and may be repeated
+	unsubscribe()
+	unsubscribe()
+	for range events {
+	}
+}
+
+func TestRestartableComponent(t *testing.T) {
+	config := testConfig("test-component")
+
 	wd, err := watchdog.NewWatchdog(config)
 	assert.NoError(t, err)
-	
+
 	// Create a mock component that will exceed thresholds
 	mockComponent := NewMockComponent()
-	
+
 	// Set resource usage that exceeds thresholds
 	highUsage := watchdog.ResourceUsage{
-		CPUPercent:  95.0,
-		MemoryMB:    1500.0,
-		Goroutines:  1500,
-		FileHandles: 1500,
-		GCPercent:   15.0,
-		Timestamp:   time.Now(),
+		CPUPercent:      95.0,
+		MemoryBytes:     1500 * 1024 * 1024,
+		Goroutines:      1500,
+		FileDescriptors: 1500,
+		GCPercent:       15.0,
+		Timestamp:       time.Now(),
 	}
-	
+
 	mockComponent.SetResourceUsage(highUsage)
 	mockComponent.SetHealth(watchdog.HealthCritical)
-	
+
 	// Register the component
 	err = wd.RegisterComponent("test-component", mockComponent)
 	assert.NoError(t, err)
-	
+
 	// Start the watchdog
 	err = wd.Start()
 	assert.NoError(t, err)
-	
+
 	// Wait for restart to happen
 	time.Sleep(100 * time.Millisecond)
-	
+
 	// Get component status
 	status, err := wd.GetComponentStatus("test-component")
 	assert.NoError(t, err)
-	
+
 	// Check that the component was restarted
 	assert.GreaterOrEqual(t, status.RestartCount, 1)
 	assert.False(t, status.LastRestart.IsZero())
-	
+
 	// Stop the watchdog
 	err = wd.Stop()
 	assert.NoError(t, err)
 }
 
-func TestFailedRestartComponent(t *testing.T) {
-	config := watchdog.Config{
-		MonitorInterval: 10 * time.Millisecond,
-		GlobalThresholds: watchdog.ResourceThresholds{
-			MaxCPUPercent:  90.0,
-			MaxMemoryMB:    1000,
-			MaxGoroutines:  1000,
-			MaxFileHandles: 1000,
-			MaxGCPercent:   10.0,
+func TestCrashDetection(t *testing.T) {
+	config := testConfig("test-component")
+
+	wd, err := watchdog.NewWatchdog(config)
+	assert.NoError(t, err)
+
+	incidents := make(chan watchdog.Incident, 100)
+	wd.AddIncidentHook(func(incident watchdog.Incident) error {
+		incidents <- incident
+		return nil
+	})
+
+	// A healthy component within its thresholds
+	mockComponent := NewMockComponent()
+	err = wd.RegisterComponent("test-component", mockComponent)
+	assert.NoError(t, err)
+
+	err = wd.Start()
+	assert.NoError(t, err)
+
+	// Let the watchdog see the component running
+	time.Sleep(30 * time.Millisecond)
+	assert.Empty(t, incidents)
+
+	// The component stops on its own
+	mockComponent.SetRunning(false)
+
+	select {
+	case incident := <-incidents:
+		assert.Equal(t, "test-component", incident.ComponentName)
+		assert.Equal(t, watchdog.IncidentCrash, incident.Type)
+	case <-time.After(time.Second):
+		t.Fatal("crash not detected")
+	}
+
+	// The crashed component is restarted
+	assert.Eventually(t, mockComponent.IsRunning, time.Second, 10*time.Millisecond)
+	mockComponent.AssertCalled(t, "Start", mock.Anything)
+
+	err = wd.Stop()
+	assert.NoError(t, err)
+
+	status, err := wd.GetComponentStatus("test-component")
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, status.RestartCount, 1)
+}
+
+func TestReplaceComponent(t *testing.T) {
+	config := testConfig("test-component")
+
+	wd, err := watchdog.NewWatchdog(config)
+	assert.NoError(t, err)
+
+	// Replacing needs a registered component
+	err = wd.ReplaceComponent("test-component", NewMockComponent())
+	assert.Error(t, err)
+
+	oldComponent := &loadComponent{MockComponent: NewMockComponent()}
+	err = wd.RegisterComponent("test-component", oldComponent)
+	assert.NoError(t, err)
+
+	err = wd.Start()
+	assert.NoError(t, err)
+	defer wd.Stop()
+
+	// Build up some history with a crash and its restart
+	time.Sleep(30 * time.Millisecond)
+	oldComponent.SetRunning(false)
+	assert.Eventually(t, func() bool {
+		status, err := wd.GetComponentStatus("test-component")
+		return err == nil && status.RestartCount >= 1
+	}, time.Second, 10*time.Millisecond)
+
+	before, err := wd.GetComponentStatus("test-component")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, before.Incidents)
+
+	newComponent := &loadComponent{MockComponent: NewMockComponent()}
+	err = wd.ReplaceComponent("test-component", newComponent)
+	assert.NoError(t, err)
+	oldReads := oldComponent.Reads()
+
+	// The history is kept
+	after, err := wd.GetComponentStatus("test-component")
+	assert.NoError(t, err)
+	assert.Equal(t, before.RestartCount, after.RestartCount)
+	assert.Equal(t, before.LastRestart, after.LastRestart)
+	assert.Equal(t, before.Incidents, after.Incidents)
+	assert.Equal(t, watchdog.CircuitClosed, after.CircuitState)
+
+	// Only the new instance is monitored
+	assert.Eventually(t, func() bool {
+		return newComponent.Reads() > 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, oldReads, oldComponent.Reads())
+
+	// Components must still be monitorable
+	err = wd.ReplaceComponent("test-component", struct{}{})
+	assert.Error(t, err)
+}
+
+func TestObserveOnly(t *testing.T) {
+	config := testConfig("test-component")
+	config.ObserveOnly = true
+
+	wd, err := watchdog.NewWatchdog(config)
+	assert.NoError(t, err)
+
+	incidents := make(chan watchdog.Incident, 100)
+	wd.AddIncidentHook(func(incident watchdog.Incident) error {
+		incidents <- incident
+		return nil
+	})
+
+	// A critical component exceeding its thresholds
+	mockComponent := &loadComponent{
+		MockComponent: NewMockComponent(),
+		usage: watchdog.ResourceUsage{
+			CPUPercent:  95.0,
+			MemoryBytes: 10 * 1024 * 1024,
+			Timestamp:   time.Now(),
 		},
 	}
-	
+	mockComponent.SetHealth(watchdog.HealthCritical)
+
+	err = wd.RegisterComponent("test-component", mockComponent)
+	assert.NoError(t, err)
+
+	err = wd.Start()
+	assert.NoError(t, err)
+
+	select {
+	case incident := <-incidents:
+		assert.Equal(t, watchdog.IncidentResourceExceeded, incident.Type)
+	case <-time.After(time.Second):
+		t.Fatal("resource incident not reported")
+	}
+
+	// The component stops on its own
+	mockComponent.SetRunning(false)
+
+	assert.Eventually(t, func() bool {
+		for {
+			select {
+			case incident := <-incidents:
+				if incident.Type == watchdog.IncidentCrash {
+					return true
+				}
+			default:
+				return false
+			}
+		}
+	}, time.Second, 10*time.Millisecond)
+
+	err = wd.Stop()
+	assert.NoError(t, err)
+
+	// Incidents are recorded, but the component is neither restarted nor degraded
+	status, err := wd.GetComponentStatus("test-component")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, status.Incidents)
+	assert.Equal(t, 0, status.RestartCount)
+	assert.Equal(t, 0, status.DegradationLevel)
+	assert.False(t, mockComponent.IsRunning())
+	mockComponent.AssertNotCalled(t, "Start", mock.Anything)
+	mockComponent.AssertNotCalled(t, "SetDegradationLevel", mock.Anything)
+}
+
+func TestIntentionalStopIsNotACrash(t *testing.T) {
+	config := testConfig("test-component")
+
+	wd, err := watchdog.NewWatchdog(config)
+	assert.NoError(t, err)
+
+	incidents := make(chan watchdog.Incident, 100)
+	wd.AddIncidentHook(func(incident watchdog.Incident) error {
+		incidents <- incident
+		return nil
+	})
+
+	mockComponent := NewMockComponent()
+	err = wd.RegisterComponent("test-component", mockComponent)
+	assert.NoError(t, err)
+
+	err = wd.Start()
+	assert.NoError(t, err)
+
+	// Let the watchdog see the component running
+	time.Sleep(30 * time.Millisecond)
+
+	// The component is shut down gracefully
+	err = wd.MarkStopped("test-component")
+	assert.NoError(t, err)
+	mockComponent.SetRunning(false)
+
+	time.Sleep(50 * time.Millisecond)
+
+	err = wd.Stop()
+	assert.NoError(t, err)
+
+	// Neither reported nor restarted
+	assert.Empty(t, incidents)
+	mockComponent.AssertNotCalled(t, "Start", mock.Anything)
+	assert.False(t, mockComponent.IsRunning())
+
+	// Unknown components cannot be marked
+	err = wd.MarkStopped("non-existent")
+	assert.Error(t, err)
+}
+
+func TestCascadingRestart(t *testing.T) {
+	config := testConfig("collector", "sampler", "export")
+
+	wd, err := watchdog.NewWatchdog(config)
+	assert.NoError(t, err)
+
+	// The collector exceeds its thresholds and is restarted
+	collector := NewMockComponent()
+	collector.SetResourceUsage(watchdog.ResourceUsage{
+		CPUPercent: 95.0,
+		Timestamp:  time.Now(),
+	})
+	collector.SetHealth(watchdog.HealthCritical)
+
+	// The sampler and export are healthy but depend on the collector
+	sampler := NewMockComponent()
+	export := NewMockComponent()
+
+	// Dependencies may be registered in any order
+	err = wd.RegisterComponentWithDeps("export", export, []string{"sampler", "collector"})
+	assert.NoError(t, err)
+	err = wd.RegisterComponentWithDeps("sampler", sampler, []string{"collector"})
+	assert.NoError(t, err)
+	err = wd.RegisterComponent("collector", collector)
+	assert.NoError(t, err)
+
+	// Start the watchdog
+	err = wd.Start()
+	assert.NoError(t, err)
+
+	// Wait for the restarts to happen
+	time.Sleep(100 * time.Millisecond)
+
+	// Stop the watchdog
+	err = wd.Stop()
+	assert.NoError(t, err)
+
+	// Check that the dependents were restarted although they are running
+	for _, name := range []string{"sampler", "export"} {
+		status, err := wd.GetComponentStatus(name)
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, status.RestartCount, 1, name)
+		assert.False(t, status.LastRestart.IsZero(), name)
+	}
+	sampler.AssertCalled(t, "Start", mock.Anything)
+	export.AssertCalled(t, "Start", mock.Anything)
+}
+
+func TestDependencyCycle(t *testing.T) {
+	config := testConfig("collector", "sampler", "export")
+
+	wd, err := watchdog.NewWatchdog(config)
+	assert.NoError(t, err)
+
+	err = wd.RegisterComponentWithDeps("sampler", NewMockComponent(), []string{"collector"})
+	assert.NoError(t, err)
+	err = wd.RegisterComponentWithDeps("export", NewMockComponent(), []string{"sampler"})
+	assert.NoError(t, err)
+
+	// The collector cannot depend on its dependents
+	err = wd.RegisterComponentWithDeps("collector", NewMockComponent(), []string{"export"})
+	assert.ErrorContains(t, err, "collector -> export -> sampler -> collector")
+
+	// Nor on itself
+	err = wd.RegisterComponentWithDeps("collector", NewMockComponent(), []string{"collector"})
+	assert.Error(t, err)
+
+	// The rejected component is not registered
+	_, err = wd.GetComponentStatus("collector")
+	assert.Error(t, err)
+}
+
+func TestFailedRestartComponent(t *testing.T) {
+	config := testConfig("test-component")
+
 	wd, err := watchdog.NewWatchdog(config)
 	assert.NoError(t, err)
-	
+
 	// Create a mock component that will exceed thresholds
 	mockComponent := NewMockComponent()
-	
+
 	// Set resource usage that exceeds thresholds
 	highUsage := watchdog.ResourceUsage{
-		CPUPercent:  95.0,
-		MemoryMB:    1500.0,
-		Goroutines:  1500,
-		FileHandles: 1500,
-		GCPercent:   15.0,
-		Timestamp:   time.Now(),
+		CPUPercent:      95.0,
+		MemoryBytes:     1500 * 1024 * 1024,
+		Goroutines:      1500,
+		FileDescriptors: 1500,
+		GCPercent:       15.0,
+		Timestamp:       time.Now(),
 	}
-	
+
 	mockComponent.SetResourceUsage(highUsage)
 	mockComponent.SetHealth(watchdog.HealthCritical)
-	
-	// Make restart fail
-	mockComponent.On("Start", mock.Anything).Return(errors.New("failed to start"))
-	
+
+	// Make restart of the stopped component fail
+	mockComponent.SetRunning(false)
+	mockComponent.Replace("Start", mock.Anything).Return(errors.New("failed to start"))
+
 	// Register the component
 	err = wd.RegisterComponent("test-component", mockComponent)
 	assert.NoError(t, err)
-	
+
 	// Start the watchdog
 	err = wd.Start()
 	assert.NoError(t, err)
-	
+
 	// Wait for restart attempts
 	time.Sleep(100 * time.Millisecond)
-	
+
 	// Get component status
 	status, err := wd.GetComponentStatus("test-component")
 	assert.NoError(t, err)
-	
+
 	// Check that incidents include restart failures
 	var hasRestartFailure bool
 	for _, incident := range status.Incidents {
@@ -491,80 +1021,419 @@ func TestFailedRestartComponent(t *testing.T) {
 		}
 	}
 	assert.True(t, hasRestartFailure)
-	
+
 	// Stop the watchdog
 	err = wd.Stop()
 	assert.NoError(t, err)
 }
 
-func TestDegradableComponent(t *testing.T) {
-	config := watchdog.Config{
-		MonitorInterval:    10 * time.Millisecond,
-		DegradationEnabled: true,
-		DegradationLevels:  3,
-		GlobalThresholds: watchdog.ResourceThresholds{
-			MaxCPUPercent:  90.0,
-			MaxMemoryMB:    1000,
-			MaxGoroutines:  1000,
-			MaxFileHandles: 1000,
-			MaxGCPercent:   10.0,
-		},
+func TestGlobalBudget(t *testing.T) {
+	config := testConfig("collector", "sampler")
+	config.GlobalBudget = watchdog.GlobalBudgetConfig{
+		MaxCPUPercent: 1.5,
+		MaxMemoryMB:   100,
 	}
-	
+
+	wd, err := watchdog.NewWatchdog(config)
+	assert.NoError(t, err)
+
+	incidents := make(chan watchdog.Incident, 100)
+	wd.AddIncidentHook(func(incident watchdog.Incident) error {
+		incidents <- incident
+		return nil
+	})
+
+	// Both components are within their thresholds but not within the budget
+	collector := NewMockComponent()
+	collector.SetResourceUsage(watchdog.ResourceUsage{
+		CPUPercent:  1.2,
+		MemoryBytes: 20 * 1024 * 1024,
+		Timestamp:   time.Now(),
+	})
+	sampler := NewMockComponent()
+	sampler.SetResourceUsage(watchdog.ResourceUsage{
+		CPUPercent:  0.5,
+		MemoryBytes: 10 * 1024 * 1024,
+		Timestamp:   time.Now(),
+	})
+
+	err = wd.RegisterComponent("collector", collector)
+	assert.NoError(t, err)
+	err = wd.RegisterComponent("sampler", sampler)
+	assert.NoError(t, err)
+
+	err = wd.Start()
+	assert.NoError(t, err)
+
+	// The breach is reported once
+	select {
+	case incident := <-incidents:
+		assert.Equal(t, watchdog.IncidentBudgetExceeded, incident.Type)
+		assert.InDelta(t, 1.7, incident.ResourceUsage.CPUPercent, 0.001)
+	case <-time.After(time.Second):
+		t.Fatal("budget incident not reported")
+	}
+
+	// The highest consumer is degraded first, then the next one once it
+	// cannot be degraded further
+	assert.Eventually(t, func() bool {
+		status, err := wd.GetComponentStatus("sampler")
+		return err == nil && status.DegradationLevel > 0
+	}, time.Second, 10*time.Millisecond)
+
+	err = wd.Stop()
+	assert.NoError(t, err)
+
+	status, err := wd.GetComponentStatus("collector")
+	assert.NoError(t, err)
+	assert.Equal(t, len(config.ComponentConfigs["collector"].DegradationLevels), status.DegradationLevel)
+	assert.Empty(t, incidents)
+
+	// The aggregate covers all components
+	total := wd.GetTotalResourceUsage()
+	assert.InDelta(t, 1.7, total.CPUPercent, 0.001)
+	assert.Equal(t, uint64(30*1024*1024), total.MemoryBytes)
+}
+
+func TestDegradableComponent(t *testing.T) {
+	config := testConfig("test-component")
+
 	wd, err := watchdog.NewWatchdog(config)
 	assert.NoError(t, err)
-	
+
 	// Create a mock component
 	mockComponent := NewMockComponent()
-	
+
 	// Set resource usage that exceeds thresholds
 	highUsage := watchdog.ResourceUsage{
-		CPUPercent:  95.0,
-		MemoryMB:    1500.0,
-		Goroutines:  1500,
-		FileHandles: 1500,
-		GCPercent:   15.0,
-		Timestamp:   time.Now(),
+		CPUPercent:      95.0,
+		MemoryBytes:     1500 * 1024 * 1024,
+		Goroutines:      1500,
+		FileDescriptors: 1500,
+		GCPercent:       15.0,
+		Timestamp:       time.Now(),
 	}
-	
+
 	mockComponent.SetResourceUsage(highUsage)
-	
+
 	// First set health as degraded
 	mockComponent.SetHealth(watchdog.HealthDegraded)
-	
+
 	// Register the component
 	err = wd.RegisterComponent("test-component", mockComponent)
 	assert.NoError(t, err)
-	
+
 	// Start the watchdog
 	err = wd.Start()
 	assert.NoError(t, err)
-	
+
 	// Wait for degradation to happen
 	time.Sleep(50 * time.Millisecond)
-	
+
 	// Get component status
 	status, err := wd.GetComponentStatus("test-component")
 	assert.NoError(t, err)
-	
+
 	// Check that degradation level was set
 	assert.Greater(t, status.DegradationLevel, 0)
-	
+
 	// Now change health to critical
 	mockComponent.SetHealth(watchdog.HealthCritical)
-	
+
 	// Wait for degradation to increase
 	time.Sleep(50 * time.Millisecond)
-	
+
 	// Get updated status
 	newStatus, err := wd.GetComponentStatus("test-component")
 	assert.NoError(t, err)
-	
+
 	// Degradation level should be higher or at max
 	assert.GreaterOrEqual(t, newStatus.DegradationLevel, status.DegradationLevel)
-	assert.LessOrEqual(t, newStatus.DegradationLevel, config.DegradationLevels)
-	
+	assert.LessOrEqual(t, newStatus.DegradationLevel, len(config.ComponentConfigs["test-component"].DegradationLevels))
+
 	// Stop the watchdog
 	err = wd.Stop()
 	assert.NoError(t, err)
 }
+
+// loadComponent is a MockComponent whose resource usage can change while
+// it is monitored
+type loadComponent struct {
+	*MockComponent
+	usage watchdog.ResourceUsage
+	reads int
+	mutex sync.Mutex
+}
+
+// GetResourceUsage implements the Monitorable interface
+func (c *loadComponent) GetResourceUsage() watchdog.ResourceUsage {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.reads++
+	return c.usage
+}
+
+// Reads returns the number of times the resource usage was read
+func (c *loadComponent) Reads() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.reads
+}
+
+// SetLoad sets the resource usage returned from now on
+func (c *loadComponent) SetLoad(usage watchdog.ResourceUsage) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.usage = usage
+}
+
+func TestStartContext(t *testing.T) {
+	config := testConfig("collector")
+
+	wd, err := watchdog.NewWatchdog(config)
+	assert.NoError(t, err)
+
+	component := &loadComponent{MockComponent: NewMockComponent()}
+	err = wd.RegisterComponent("collector", component)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = wd.StartContext(ctx)
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return component.Reads() > 0
+	}, time.Second, 10*time.Millisecond)
+
+	// Cancelling the parent stops monitoring without calling Stop
+	cancel()
+	time.Sleep(2 * config.MonitoringInterval)
+
+	reads := component.Reads()
+	time.Sleep(5 * config.MonitoringInterval)
+	assert.Equal(t, reads, component.Reads())
+
+	// A done context does not start the watchdog
+	err = wd.StartContext(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Monitoring resumes with a new context, without calling Stop first
+	err = wd.Start()
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return component.Reads() > reads
+	}, time.Second, 10*time.Millisecond)
+
+	err = wd.Stop()
+	assert.NoError(t, err)
+}
+
+func TestStopDuringMonitoring(t *testing.T) {
+	config := testConfig("collector")
+	config.MonitoringInterval = time.Millisecond
+
+	wd, err := watchdog.NewWatchdog(config)
+	assert.NoError(t, err)
+
+	err = wd.RegisterComponent("collector", NewMockComponent())
+	assert.NoError(t, err)
+
+	// Stopping while the loops tick must not deadlock
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			assert.NoError(t, wd.Start())
+			time.Sleep(time.Duration(i%3) * time.Millisecond)
+			assert.NoError(t, wd.Stop())
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop deadlocked with the monitoring loop")
+	}
+}
+
+func TestSustainedDegradation(t *testing.T) {
+	config := testConfig("collector")
+	config.SustainedDegradationDuration = 100 * time.Millisecond
+
+	wd, err := watchdog.NewWatchdog(config)
+	assert.NoError(t, err)
+
+	incidents := make(chan watchdog.Incident, 100)
+	wd.AddIncidentHook(func(incident watchdog.Incident) error {
+		if incident.Type == watchdog.IncidentSustainedDegradation {
+			incidents <- incident
+		}
+		return nil
+	})
+
+	highUsage := watchdog.ResourceUsage{
+		CPUPercent:  95.0,
+		MemoryBytes: 10 * 1024 * 1024,
+		Timestamp:   time.Now(),
+	}
+	lowUsage := watchdog.ResourceUsage{
+		CPUPercent:  1.0,
+		MemoryBytes: 10 * 1024 * 1024,
+		Timestamp:   time.Now(),
+	}
+
+	// A critical component exceeding its thresholds is degraded to the max
+	component := &loadComponent{MockComponent: NewMockComponent(), usage: highUsage}
+	component.SetHealth(watchdog.HealthCritical)
+
+	err = wd.RegisterComponent("collector", component)
+	assert.NoError(t, err)
+
+	start := time.Now()
+	err = wd.Start()
+	assert.NoError(t, err)
+	defer wd.Stop()
+
+	// The incident is reported once the max level is held past the duration
+	select {
+	case incident := <-incidents:
+		assert.Equal(t, "collector", incident.ComponentName)
+		assert.GreaterOrEqual(t, time.Since(start), config.SustainedDegradationDuration)
+		assert.Contains(t, incident.Remediation, "capacity")
+	case <-time.After(time.Second):
+		t.Fatal("sustained degradation incident not reported")
+	}
+
+	status, err := wd.GetComponentStatus("collector")
+	assert.NoError(t, err)
+	assert.Equal(t, len(config.ComponentConfigs["collector"].DegradationLevels), status.DegradationLevel)
+
+	// The incident is not repeated while the component stays at the max
+	select {
+	case <-incidents:
+		t.Fatal("sustained degradation incident reported twice")
+	case <-time.After(3 * config.SustainedDegradationDuration):
+	}
+
+	// Once the component recovers the condition is cleared
+	component.SetLoad(lowUsage)
+	component.SetHealth(watchdog.HealthOK)
+	err = wd.ForceCloseCircuit("collector")
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		status, err := wd.GetComponentStatus("collector")
+		return err == nil && status.DegradationLevel == 0
+	}, time.Second, 10*time.Millisecond)
+
+	// and a new sustained degradation is reported again
+	component.SetLoad(highUsage)
+	component.SetHealth(watchdog.HealthCritical)
+
+	select {
+	case incident := <-incidents:
+		assert.Equal(t, "collector", incident.ComponentName)
+	case <-time.After(time.Second):
+		t.Fatal("sustained degradation incident not reported after recovery")
+	}
+}
+
+func TestMeasurementHistory(t *testing.T) {
+	config := testConfig("collector")
+	config.MeasurementHistoryLen = 5
+
+	wd, err := watchdog.NewWatchdog(config)
+	assert.NoError(t, err)
+
+	component := &loadComponent{MockComponent: NewMockComponent()}
+	err = wd.RegisterComponent("collector", component)
+	assert.NoError(t, err)
+
+	err = wd.Start()
+	assert.NoError(t, err)
+	defer wd.Stop()
+
+	// The history accumulates up to MeasurementHistoryLen
+	assert.Eventually(t, func() bool {
+		status, err := wd.GetComponentStatus("collector")
+		return err == nil && len(status.Measurements) == config.MeasurementHistoryLen
+	}, time.Second, 10*time.Millisecond)
+
+	// Later measurements replace the oldest ones
+	component.SetLoad(watchdog.ResourceUsage{CPUPercent: 42.0, MemoryBytes: 10 * 1024 * 1024})
+	assert.Eventually(t, func() bool {
+		status, err := wd.GetComponentStatus("collector")
+		return err == nil && status.Measurements[0].Usage.CPUPercent == 42.0
+	}, time.Second, 10*time.Millisecond)
+
+	status, err := wd.GetComponentStatus("collector")
+	assert.NoError(t, err)
+	assert.Len(t, status.Measurements, config.MeasurementHistoryLen)
+	for i := 1; i < len(status.Measurements); i++ {
+		assert.True(t, status.Measurements[i].Timestamp.After(status.Measurements[i-1].Timestamp))
+	}
+
+	// Statuses hold their own copy of the history
+	status.Measurements[0].Usage.CPUPercent = -1
+	all := wd.GetAllComponentStatuses()
+	assert.Len(t, all["collector"].Measurements, config.MeasurementHistoryLen)
+	assert.NotEqual(t, -1.0, all["collector"].Measurements[0].Usage.CPUPercent)
+}
+
+func TestMaxRestartsPerMinute(t *testing.T) {
+	config := testConfig("component-0", "component-1", "component-2", "component-3", "component-4")
+	config.MaxRestartsPerMinute = 2
+
+	wd, err := watchdog.NewWatchdog(config)
+	assert.NoError(t, err)
+
+	incidents := make(chan watchdog.Incident, 100)
+	wd.AddIncidentHook(func(incident watchdog.Incident) error {
+		incidents <- incident
+		return nil
+	})
+
+	components := make([]*MockComponent, 5)
+	for i := range components {
+		components[i] = NewMockComponent()
+		err = wd.RegisterComponent(fmt.Sprintf("component-%d", i), components[i])
+		assert.NoError(t, err)
+	}
+
+	err = wd.Start()
+	assert.NoError(t, err)
+	defer wd.Stop()
+
+	// Let the watchdog see the components running, then crash them all
+	time.Sleep(30 * time.Millisecond)
+	for _, component := range components {
+		component.SetRunning(false)
+	}
+
+	running := func() int {
+		count := 0
+		for _, component := range components {
+			if component.IsRunning() {
+				count++
+			}
+		}
+		return count
+	}
+	assert.Eventually(t, func() bool { return running() == config.MaxRestartsPerMinute }, time.Second, 10*time.Millisecond)
+
+	// The other restarts are deferred, each reported once
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, config.MaxRestartsPerMinute, running())
+
+	throttled := make(map[string]int)
+	for len(incidents) > 0 {
+		incident := <-incidents
+		if incident.Type == watchdog.IncidentRestartThrottled {
+			throttled[incident.ComponentName]++
+		}
+	}
+	assert.Len(t, throttled, len(components)-config.MaxRestartsPerMinute)
+	for name, count := range throttled {
+		assert.Equal(t, 1, count, name)
+	}
+}
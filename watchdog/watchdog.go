@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"reflect"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -14,146 +17,123 @@ type HealthStatus string
 const (
 	// HealthOK indicates the component is healthy
 	HealthOK HealthStatus = "ok"
-	
+
 	// HealthDegraded indicates the component has degraded functionality
 	HealthDegraded HealthStatus = "degraded"
-	
+
 	// HealthCritical indicates the component is in a critical state
 	HealthCritical HealthStatus = "critical"
-	
+
 	// HealthUnknown indicates the component's health is unknown
 	HealthUnknown HealthStatus = "unknown"
 )
 
-// CircuitState represents the state of a circuit breaker
-type CircuitState string
-
-const (
-	// CircuitClosed indicates normal operation
-	CircuitClosed CircuitState = "closed"
-	
-	// CircuitOpen indicates the component is disabled
-	CircuitOpen CircuitState = "open"
-	
-	// CircuitHalfOpen indicates the component is being tested
-	CircuitHalfOpen CircuitState = "half-open"
-)
-
-// ResourceUsage captures resource usage metrics for a component
-type ResourceUsage struct {
-	// CPUPercent is the CPU usage percentage
-	CPUPercent float64
-	
-	// MemoryMB is the memory usage in MB
-	MemoryMB float64
-	
-	// Goroutines is the number of goroutines
-	Goroutines int
-	
-	// FileHandles is the number of open file handles
-	FileHandles int
-	
-	// GCPercent is the percentage of time spent in GC
-	GCPercent float64
-	
-	// Timestamp is when the measurement was taken
-	Timestamp time.Time
-	
-	// Measurements are historical measurements
-	Measurements []TimestampedMeasurement
-}
-
-// TimestampedMeasurement is a measurement with a timestamp
-type TimestampedMeasurement struct {
-	// Timestamp is when the measurement was taken
-	Timestamp time.Time
-	
-	// CPUPercent is the CPU usage percentage
-	CPUPercent float64
-	
-	// MemoryMB is the memory usage in MB
-	MemoryMB float64
-	
-	// Goroutines is the number of goroutines
-	Goroutines int
-	
-	// FileHandles is the number of open file handles
-	FileHandles int
-	
-	// GCPercent is the percentage of time spent in GC
-	GCPercent float64
-}
-
 // IncidentType represents the type of incident
 type IncidentType string
 
 const (
 	// IncidentResourceExceeded indicates a resource threshold was exceeded
 	IncidentResourceExceeded IncidentType = "resource_exceeded"
-	
+
 	// IncidentDeadlockDetected indicates a deadlock was detected
 	IncidentDeadlockDetected IncidentType = "deadlock_detected"
-	
+
 	// IncidentRestartFailed indicates a component restart failed
 	IncidentRestartFailed IncidentType = "restart_failed"
-	
+
 	// IncidentCrash indicates a component crashed
 	IncidentCrash IncidentType = "crash"
+
+	// IncidentBudgetExceeded indicates all components together exceeded the global budget
+	IncidentBudgetExceeded IncidentType = "budget_exceeded"
+
+	// IncidentSustainedDegradation indicates a component stayed at the maximum
+	// degradation level for longer than the configured duration
+	IncidentSustainedDegradation IncidentType = "sustained_degradation"
+
+	// IncidentRestartThrottled indicates a component restart was deferred
+	// because all components together reached MaxRestartsPerMinute
+	IncidentRestartThrottled IncidentType = "restart_throttled"
 )
 
 // Incident represents a detected problem
 type Incident struct {
 	// ID is a unique identifier for the incident
 	ID string
-	
+
 	// Timestamp is when the incident occurred
 	Timestamp time.Time
-	
+
+	// ComponentName is the name of the affected component
+	ComponentName string
+
 	// Type is the type of incident
 	Type IncidentType
-	
+
 	// Description is a human-readable description of the incident
 	Description string
-	
+
 	// ResourceUsage is the resource usage at the time of the incident
 	ResourceUsage ResourceUsage
-	
+
 	// Remediation is a suggested remediation action
 	Remediation string
+
+	// StackTrace holds the goroutine stacks captured for the incident, if
+	// stack traces are enabled
+	StackTrace string
 }
 
 // ComponentStatus represents the status of a monitored component
 type ComponentStatus struct {
 	// Name is the name of the component
 	Name string
-	
+
 	// Health is the health status of the component
 	Health HealthStatus
-	
+
 	// CircuitState is the state of the circuit breaker
 	CircuitState CircuitState
-	
+
 	// ResourceUsage is the current resource usage
 	ResourceUsage ResourceUsage
-	
+
 	// LastRestart is when the component was last restarted
 	LastRestart time.Time
-	
+
 	// RestartCount is the number of times the component has been restarted
 	RestartCount int
-	
+
 	// Incidents are recent incidents for the component
 	Incidents []Incident
-	
+
 	// DegradationLevel is the current degradation level (0 = none)
 	DegradationLevel int
+
+	// Measurements are the recent resource usage measurements, oldest first,
+	// at most Config.MeasurementHistoryLen
+	Measurements []TimestampedMeasurement
+}
+
+// TimestampedMeasurement is a resource usage measurement taken by the
+// watchdog, e.g. for sparklines of the recent usage of a component
+type TimestampedMeasurement struct {
+	// Timestamp is when the watchdog took the measurement
+	Timestamp time.Time
+
+	// Usage is the resource usage reported by the component
+	Usage ResourceUsage
 }
 
+// NotificationHook is called with every incident created by the watchdog.
+// Hooks run in their own goroutine, and their errors are only logged
+type NotificationHook func(incident Incident) error
+
 // Monitorable defines the interface for components that can be monitored
 type Monitorable interface {
 	// GetResourceUsage returns the resource usage for the component
 	GetResourceUsage() ResourceUsage
-	
+
 	// GetHealth returns the health status of the component
 	GetHealth() HealthStatus
 }
@@ -162,10 +142,10 @@ type Monitorable interface {
 type Restartable interface {
 	// Shutdown performs a graceful shutdown of the component
 	Shutdown(ctx context.Context) error
-	
+
 	// Start starts the component
 	Start(ctx context.Context) error
-	
+
 	// IsRunning returns whether the component is running
 	IsRunning() bool
 }
@@ -174,7 +154,7 @@ type Restartable interface {
 type Degradable interface {
 	// SetDegradationLevel sets the degradation level for the component
 	SetDegradationLevel(level int) error
-	
+
 	// GetDegradationLevel returns the current degradation level
 	GetDegradationLevel() int
 }
@@ -183,72 +163,163 @@ type Degradable interface {
 type Watchdog interface {
 	// Start starts the watchdog monitoring
 	Start() error
-	
+
+	// StartContext starts the watchdog monitoring until ctx is done or Stop
+	// is called
+	StartContext(ctx context.Context) error
+
 	// Stop stops the watchdog monitoring
 	Stop() error
-	
+
 	// RegisterComponent registers a component for monitoring
 	RegisterComponent(name string, component interface{}) error
-	
+
+	// RegisterComponentWithDeps registers a component that depends on other
+	// components. It is restarted after any of them is restarted
+	RegisterComponentWithDeps(name string, component interface{}, dependsOn []string) error
+
+	// ReplaceComponent swaps the instance of a registered component, e.g.
+	// during a hot reload. Its status history is kept
+	ReplaceComponent(name string, component interface{}) error
+
 	// UnregisterComponent removes a component from monitoring
 	UnregisterComponent(name string) error
-	
+
 	// GetComponentStatus returns the status of a monitored component
 	GetComponentStatus(name string) (ComponentStatus, error)
-	
+
 	// GetAllComponentStatuses returns the status of all monitored components
 	GetAllComponentStatuses() map[string]ComponentStatus
-	
+
+	// GetTotalResourceUsage returns the current resource usage of all
+	// monitored components together, as checked against the global budget
+	GetTotalResourceUsage() ResourceUsage
+
 	// SetThresholds updates the thresholds for a component
 	SetThresholds(name string, thresholds ResourceThresholds) error
+
+	// ReloadConfig applies a new configuration without restarting the
+	// watchdog. The statuses of the components are kept
+	ReloadConfig(config Config) error
+
+	// MarkStopped records that a component is being stopped on purpose, so it
+	// is not reported as crashed. It applies until the component runs again
+	MarkStopped(name string) error
+
+	// ForceOpenCircuit opens the circuit breaker of a component until it is
+	// reset or force closed, e.g. to stop a misbehaving component
+	ForceOpenCircuit(name string) error
+
+	// ForceCloseCircuit closes the circuit breaker of a component until it is
+	// reset or force opened, ignoring its failures meanwhile
+	ForceCloseCircuit(name string) error
+
+	// ResetCircuit clears an override of the circuit breaker of a component
+	// and closes it
+	ResetCircuit(name string) error
+
+	// Heartbeat records that a component is alive. Components that miss
+	// DeadlockConfig.HeartbeatMissThreshold heartbeats are reported as deadlocked
+	Heartbeat(name string) error
+
+	// TrackOperation records the start of an operation of a component and
+	// returns the function to call when it completes. Operations outstanding
+	// for longer than DeadlockConfig.MaxOperationTime are reported as
+	// deadlocks
+	TrackOperation(name, operation string) (done func())
+
+	// AddIncidentHook registers a hook called for every resource, deadlock
+	// and restart failure incident
+	AddIncidentHook(hook NotificationHook)
+
+	// Subscribe returns a channel receiving the health, circuit state,
+	// degradation and incident events of all components, and a function that
+	// unsubscribes and closes the channel. Events are dropped, not queued, if
+	// the subscriber does not keep up
+	Subscribe() (<-chan WatchdogEvent, func())
+
+	// DroppedEvents returns the number of events dropped because a
+	// subscriber did not keep up
+	DroppedEvents() uint64
 }
 
 // watchdogImpl is the implementation of the Watchdog interface
 type watchdogImpl struct {
 	config Config
-	
+
 	// components are the monitored components
 	components map[string]interface{}
-	
+
 	// componentConfigs are the configurations for monitored components
 	componentConfigs map[string]ComponentConfig
-	
+
 	// componentStatuses are the current statuses of monitored components
 	componentStatuses map[string]ComponentStatus
-	
+
 	// circuitBreakers are the circuit breakers for monitored components
 	circuitBreakers map[string]*CircuitBreaker
-	
+
 	// restartManagers are the restart managers for restartable components
 	restartManagers map[string]*RestartManager
-	
-	// monitor is the resource monitor
-	monitor *Monitor
-	
+
+	// dependencies are the components each component depends on
+	dependencies dependencyGraph
+
+	// lastRunning is whether each restartable component was running in the last check
+	lastRunning map[string]bool
+
+	// intentionalStops are the components stopped on purpose, which are not crashes
+	intentionalStops map[string]bool
+
+	// restartLimiter limits the restarts of all components together
+	restartLimiter *RestartRateLimiter
+
+	// throttledRestarts are the components whose restart was deferred by
+	// restartLimiter, true if it was a forced restart after a dependency
+	throttledRestarts map[string]bool
+
 	// deadlockDetector is the deadlock detector
 	deadlockDetector *DeadlockDetector
-	
-	// degradationController is the degradation controller
-	degradationController *DegradationController
-	
+
 	// diagnostics is the diagnostics provider
 	diagnostics *DiagnosticsProvider
-	
+
+	// incidentStore persists incidents if configured
+	incidentStore IncidentStore
+
+	// incidentHooks are notified of every incident
+	incidentHooks []NotificationHook
+
+	// events sends lifecycle events to subscribers
+	events *eventBroker
+
+	// budgetExceeded indicates the total usage exceeded the global budget in the last check
+	budgetExceeded bool
+
+	// maxDegradedSince is when each component reached the maximum degradation level
+	maxDegradedSince map[string]time.Time
+
+	// sustainedDegradations are the components reported for sustained degradation
+	sustainedDegradations map[string]bool
+
 	// mutex protects the watchdog state
 	mutex sync.RWMutex
-	
+
 	// running indicates whether the watchdog is running
 	running bool
-	
+
 	// monitorContext is the context for the monitoring loop
 	monitorContext context.Context
-	
+
 	// monitorCancel is the cancel function for the monitoring loop
 	monitorCancel context.CancelFunc
-	
+
+	// deadlockCancel is the cancel function for the deadlock detection loop
+	deadlockCancel context.CancelFunc
+
 	// monitorWg is a wait group for the monitoring goroutines
 	monitorWg sync.WaitGroup
-	
+
 	// startTime is when the watchdog was started
 	startTime time.Time
 }
@@ -258,7 +329,7 @@ func NewWatchdog(config Config) (Watchdog, error) {
 	if err := config.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid watchdog configuration: %w", err)
 	}
-	
+
 	w := &watchdogImpl{
 		config:            config,
 		components:        make(map[string]interface{}),
@@ -266,15 +337,17 @@ func NewWatchdog(config Config) (Watchdog, error) {
 		componentStatuses: make(map[string]ComponentStatus),
 		circuitBreakers:   make(map[string]*CircuitBreaker),
 		restartManagers:   make(map[string]*RestartManager),
+		dependencies:      make(dependencyGraph),
+		lastRunning:       make(map[string]bool),
+		intentionalStops:  make(map[string]bool),
+		restartLimiter:    NewRestartRateLimiter(config.MaxRestartsPerMinute),
+		throttledRestarts: make(map[string]bool),
+		events:            newEventBroker(),
+
+		maxDegradedSince:      make(map[string]time.Time),
+		sustainedDegradations: make(map[string]bool),
 	}
-	
-	// Create monitor with the global thresholds
-	monitor, err := NewMonitor(config.GlobalThresholds)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create resource monitor: %w", err)
-	}
-	w.monitor = monitor
-	
+
 	// Create deadlock detector if enabled
 	if config.DeadlockDetection.Enabled {
 		detector, err := NewDeadlockDetector(config.DeadlockDetection)
@@ -283,128 +356,216 @@ func NewWatchdog(config Config) (Watchdog, error) {
 		}
 		w.deadlockDetector = detector
 	}
-	
-	// Create degradation controller if enabled
-	if config.DegradationEnabled {
-		controller, err := NewDegradationController(config.DegradationLevels)
+
+	// Create diagnostics provider
+	w.diagnostics = NewDiagnosticsProvider()
+	w.diagnostics.SetMaxEvents(config.DiagnosticCollection.MaxEvents)
+	w.diagnostics.SetIncludeStackTraces(config.DiagnosticCollection.IncludeStackTraces)
+
+	// Create incident store if enabled
+	if config.IncidentStore.Enabled {
+		store, err := NewFileIncidentStore(config.IncidentStore)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create degradation controller: %w", err)
+			return nil, fmt.Errorf("failed to create incident store: %w", err)
 		}
-		w.degradationController = controller
+		w.incidentStore = store
 	}
-	
-	// Create diagnostics provider if events are enabled
-	if config.EventsEnabled {
-		w.diagnostics = NewDiagnosticsProvider()
-	}
-	
+
 	return w, nil
 }
 
 // Start starts the watchdog monitoring
 func (w *watchdogImpl) Start() error {
+	return w.StartContext(context.Background())
+}
+
+// StartContext starts the watchdog monitoring with loops derived from ctx.
+// Cancelling ctx stops all loops, Stop still waits for them to exit, and a
+// later start runs them again
+func (w *watchdogImpl) StartContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("watchdog not started: %w", err)
+	}
+
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
-	
-	if w.running {
+
+	if w.isRunning() {
 		return nil // Already running
 	}
-	
+
 	// Create a context for the monitoring loop
-	w.monitorContext, w.monitorCancel = context.WithCancel(context.Background())
-	
+	w.monitorContext, w.monitorCancel = context.WithCancel(ctx)
+
 	// Start the monitoring loop
 	w.monitorWg.Add(1)
-	go w.monitorLoop()
-	
+	go w.monitorLoop(w.monitorContext)
+
 	// Start the deadlock detector if enabled
 	if w.deadlockDetector != nil {
-		w.monitorWg.Add(1)
-		go w.deadlockDetectionLoop()
+		w.startDeadlockDetection()
 	}
-	
+
 	w.running = true
 	w.startTime = time.Now()
-	
+
 	log.Printf("Watchdog started with %d configured components", len(w.componentConfigs))
-	
+
 	return nil
 }
 
-// Stop stops the watchdog monitoring
+// Stop stops the watchdog monitoring and waits for its loops to exit, also
+// after the context of StartContext was cancelled
 func (w *watchdogImpl) Stop() error {
 	w.mutex.Lock()
-	defer w.mutex.Unlock()
-	
-	if !w.running {
-		return nil // Not running
-	}
-	
+	wasRunning := w.running
+	w.running = false
+
 	// Stop the monitoring loop
 	if w.monitorCancel != nil {
 		w.monitorCancel()
 	}
-	
-	// Wait for monitoring goroutines to finish
+	w.mutex.Unlock()
+
+	// Wait for monitoring goroutines to finish, without the mutex the loops
+	// take on every tick
 	w.monitorWg.Wait()
-	
-	w.running = false
-	
-	log.Println("Watchdog stopped")
-	
+
+	if wasRunning {
+		log.Println("Watchdog stopped")
+	}
+
 	return nil
 }
 
+// isRunning returns whether the monitoring loops run, false once the
+// context of StartContext is done. Caller must hold mutex
+func (w *watchdogImpl) isRunning() bool {
+	return w.running && w.monitorContext.Err() == nil
+}
+
 // RegisterComponent registers a component for monitoring
 func (w *watchdogImpl) RegisterComponent(name string, component interface{}) error {
+	return w.RegisterComponentWithDeps(name, component, nil)
+}
+
+// RegisterComponentWithDeps registers a component for monitoring that is
+// restarted after any of the components it depends on. The dependencies may
+// be registered later, but must not form a cycle
+func (w *watchdogImpl) RegisterComponentWithDeps(name string, component interface{}, dependsOn []string) error {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
-	
+
 	// Check if the component is already registered
 	if _, exists := w.components[name]; exists {
 		return fmt.Errorf("component already registered: %s", name)
 	}
-	
+
+	// Check that the dependencies do not form a cycle
+	if cycle := w.dependencies.cycle(name, dependsOn); cycle != nil {
+		return fmt.Errorf("dependency cycle for component %s: %s", name, strings.Join(cycle, " -> "))
+	}
+
 	// Check if the component implements the required interfaces
 	_, monitorable := component.(Monitorable)
 	if !monitorable {
 		return fmt.Errorf("component does not implement Monitorable interface: %s", name)
 	}
-	
+
 	// Get or create component configuration
-	config, exists := w.componentConfigs[name]
+	config, exists := w.config.ComponentConfigs[name]
 	if !exists {
 		// Create default configuration
 		config = DefaultComponentConfig(name)
-		w.componentConfigs[name] = config
 	}
-	
-	// Create circuit breaker
-	circuitBreaker := NewCircuitBreaker(config.Name)
-	w.circuitBreakers[name] = circuitBreaker
-	
-	// Create restart manager if component is restartable
-	if restartable, ok := component.(Restartable); ok {
-		restartManager := NewRestartManager(config.Restart, restartable)
-		w.restartManagers[name] = restartManager
+	w.componentConfigs[name] = config
+
+	// Create circuit breaker if enabled
+	if config.CircuitBreaker.Enabled {
+		w.circuitBreakers[name] = NewCircuitBreaker(name, config.CircuitBreaker)
 	}
-	
+
 	// Store the component
 	w.components[name] = component
-	
+
+	// Create restart manager if component is restartable
+	if restartable, ok := component.(Restartable); ok {
+		w.setRestartManager(name)
+		w.lastRunning[name] = restartable.IsRunning()
+	}
+	if len(dependsOn) > 0 {
+		w.dependencies[name] = append([]string(nil), dependsOn...)
+	}
+
+	// Track heartbeats from now on
+	if w.deadlockDetector != nil {
+		w.deadlockDetector.RegisterComponent(name)
+	}
+
 	// Initialize component status
 	w.componentStatuses[name] = ComponentStatus{
-		Name:            name,
-		Health:          HealthUnknown,
-		CircuitState:    CircuitClosed,
-		LastRestart:     time.Time{},
-		RestartCount:    0,
-		Incidents:       []Incident{},
+		Name:             name,
+		Health:           HealthUnknown,
+		CircuitState:     CircuitClosed,
+		LastRestart:      time.Time{},
+		RestartCount:     0,
+		Incidents:        []Incident{},
 		DegradationLevel: 0,
 	}
-	
+
 	log.Printf("Component registered for monitoring: %s", name)
-	
+
+	return nil
+}
+
+// ReplaceComponent atomically swaps the instance of a registered component.
+// The new instance gets a new circuit breaker and restart manager, and starts
+// at degradation level 0. Its dependencies, configuration, restart count and
+// incidents are kept
+func (w *watchdogImpl) ReplaceComponent(name string, component interface{}) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	// Check if the component is registered
+	if _, exists := w.components[name]; !exists {
+		return fmt.Errorf("component not registered: %s", name)
+	}
+
+	// Check if the component implements the required interfaces
+	if _, monitorable := component.(Monitorable); !monitorable {
+		return fmt.Errorf("component does not implement Monitorable interface: %s", name)
+	}
+
+	config := w.componentConfigs[name]
+	w.circuitBreakers[name] = NewCircuitBreaker(name, config.CircuitBreaker)
+
+	// Replace the restart manager, the new instance may not be restartable
+	delete(w.restartManagers, name)
+	delete(w.lastRunning, name)
+	delete(w.intentionalStops, name)
+	delete(w.throttledRestarts, name)
+	w.components[name] = component
+	if restartable, ok := component.(Restartable); ok {
+		w.setRestartManager(name)
+		w.lastRunning[name] = restartable.IsRunning()
+	}
+
+	// Heartbeats and deadlocks of the old instance do not apply
+	if w.deadlockDetector != nil {
+		w.deadlockDetector.UnregisterComponent(name)
+		w.deadlockDetector.RegisterComponent(name)
+	}
+
+	delete(w.maxDegradedSince, name)
+	delete(w.sustainedDegradations, name)
+
+	status := w.componentStatuses[name]
+	status.CircuitState = CircuitClosed
+	status.DegradationLevel = 0
+	w.updateStatus(name, status)
+
+	log.Printf("Component replaced: %s", name)
+
 	return nil
 }
 
@@ -412,21 +573,112 @@ func (w *watchdogImpl) RegisterComponent(name string, component interface{}) err
 func (w *watchdogImpl) UnregisterComponent(name string) error {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
-	
+
 	// Check if the component is registered
 	if _, exists := w.components[name]; !exists {
 		return fmt.Errorf("component not registered: %s", name)
 	}
-	
+
 	// Remove the component
 	delete(w.components, name)
 	delete(w.componentConfigs, name)
 	delete(w.componentStatuses, name)
 	delete(w.circuitBreakers, name)
 	delete(w.restartManagers, name)
-	
+	delete(w.dependencies, name)
+	delete(w.lastRunning, name)
+	delete(w.intentionalStops, name)
+	delete(w.throttledRestarts, name)
+	delete(w.maxDegradedSince, name)
+	delete(w.sustainedDegradations, name)
+	if w.deadlockDetector != nil {
+		w.deadlockDetector.UnregisterComponent(name)
+	}
+
 	log.Printf("Component unregistered from monitoring: %s", name)
-	
+
+	return nil
+}
+
+// Heartbeat records that a monitored component is alive
+func (w *watchdogImpl) Heartbeat(name string) error {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	if _, exists := w.components[name]; !exists {
+		return fmt.Errorf("component not registered: %s", name)
+	}
+
+	if w.deadlockDetector != nil {
+		w.deadlockDetector.Heartbeat(name)
+	}
+
+	return nil
+}
+
+// TrackOperation records the start of an operation of a component. Without
+// deadlock detection the returned function does nothing
+func (w *watchdogImpl) TrackOperation(name, operation string) (done func()) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	if w.deadlockDetector == nil {
+		return func() {}
+	}
+
+	return w.deadlockDetector.TrackOperation(name, operation)
+}
+
+// MarkStopped records that a component is being stopped on purpose
+func (w *watchdogImpl) MarkStopped(name string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, exists := w.components[name]; !exists {
+		return fmt.Errorf("component not registered: %s", name)
+	}
+
+	w.intentionalStops[name] = true
+	delete(w.throttledRestarts, name)
+
+	return nil
+}
+
+// ForceOpenCircuit opens the circuit breaker of a component until it is reset
+func (w *watchdogImpl) ForceOpenCircuit(name string) error {
+	return w.overrideCircuit(name, (*CircuitBreaker).ForceOpen)
+}
+
+// ForceCloseCircuit closes the circuit breaker of a component until it is reset
+func (w *watchdogImpl) ForceCloseCircuit(name string) error {
+	return w.overrideCircuit(name, (*CircuitBreaker).ForceClose)
+}
+
+// ResetCircuit clears an override of the circuit breaker of a component
+func (w *watchdogImpl) ResetCircuit(name string) error {
+	return w.overrideCircuit(name, (*CircuitBreaker).Reset)
+}
+
+// overrideCircuit applies an override to the circuit breaker of a component
+// and updates its status
+func (w *watchdogImpl) overrideCircuit(name string, override func(*CircuitBreaker)) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	status, exists := w.componentStatuses[name]
+	if !exists {
+		return fmt.Errorf("component not registered: %s", name)
+	}
+
+	circuitBreaker, exists := w.circuitBreakers[name]
+	if !exists {
+		return fmt.Errorf("circuit breaker not enabled for component: %s", name)
+	}
+
+	override(circuitBreaker)
+	status.CircuitState = circuitBreaker.State()
+	w.updateStatus(name, status)
+
 	return nil
 }
 
@@ -434,83 +686,326 @@ func (w *watchdogImpl) UnregisterComponent(name string) error {
 func (w *watchdogImpl) GetComponentStatus(name string) (ComponentStatus, error) {
 	w.mutex.RLock()
 	defer w.mutex.RUnlock()
-	
+
 	// Check if the component is registered
 	status, exists := w.componentStatuses[name]
 	if !exists {
 		return ComponentStatus{}, fmt.Errorf("component not registered: %s", name)
 	}
-	
-	return status, nil
+
+	return copyStatus(status), nil
 }
 
 // GetAllComponentStatuses returns the status of all monitored components
 func (w *watchdogImpl) GetAllComponentStatuses() map[string]ComponentStatus {
 	w.mutex.RLock()
 	defer w.mutex.RUnlock()
-	
+
 	// Create a copy of the component statuses
 	statuses := make(map[string]ComponentStatus, len(w.componentStatuses))
 	for name, status := range w.componentStatuses {
-		statuses[name] = status
+		statuses[name] = copyStatus(status)
 	}
-	
+
 	return statuses
 }
 
+// copyStatus returns a status with its own copy of the measurements, which
+// the monitoring loop updates in place
+func copyStatus(status ComponentStatus) ComponentStatus {
+	status.Measurements = append([]TimestampedMeasurement(nil), status.Measurements...)
+	return status
+}
+
+// GetTotalResourceUsage returns the resource usage of all monitored components together
+func (w *watchdogImpl) GetTotalResourceUsage() ResourceUsage {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return w.totalResourceUsage()
+}
+
+// totalResourceUsage sums the last resource usage of all components. GC is
+// shared by the process, so the highest GC percentage is reported. Caller
+// must hold mutex
+func (w *watchdogImpl) totalResourceUsage() ResourceUsage {
+	var total ResourceUsage
+	for _, status := range w.componentStatuses {
+		usage := status.ResourceUsage
+		total.CPUPercent += usage.CPUPercent
+		total.MemoryBytes += usage.MemoryBytes
+		total.FileDescriptors += usage.FileDescriptors
+		total.Goroutines += usage.Goroutines
+		total.IOReadBytes += usage.IOReadBytes
+		total.IOWriteBytes += usage.IOWriteBytes
+		if usage.GCPercent > total.GCPercent {
+			total.GCPercent = usage.GCPercent
+		}
+		if usage.Timestamp.After(total.Timestamp) {
+			total.Timestamp = usage.Timestamp
+		}
+	}
+
+	return total
+}
+
 // SetThresholds updates the thresholds for a component
 func (w *watchdogImpl) SetThresholds(name string, thresholds ResourceThresholds) error {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
-	
+
 	// Check if the component is registered
 	config, exists := w.componentConfigs[name]
 	if !exists {
 		return fmt.Errorf("component not registered: %s", name)
 	}
-	
+
 	// Update the thresholds
-	config.Thresholds = thresholds
+	config.MaxCPUPercent = thresholds.MaxCPUPercent
+	config.MaxMemoryMB = thresholds.MaxMemoryMB
+	config.MaxGoroutines = thresholds.MaxGoroutines
+	config.MaxFileDescriptors = thresholds.MaxFileHandles
+	config.MaxGCPercent = thresholds.MaxGCPercent
 	w.componentConfigs[name] = config
-	
+
 	log.Printf("Thresholds updated for component: %s", name)
-	
+
 	return nil
 }
 
-// monitorLoop is the main monitoring loop
-func (w *watchdogImpl) monitorLoop() {
-	defer w.monitorWg.Done()
-	
-	ticker := time.NewTicker(w.config.MonitorInterval)
-	defer ticker.Stop()
-	
-	for {
+// ReloadConfig validates a new configuration and applies it atomically.
+// Components whose configuration did not change keep their thresholds,
+// circuit breakers and degradation levels. Changed components get new ones,
+// and their circuit is closed. Components missing from the new configuration
+// fall back to DefaultComponentConfig and lose their restart manager, and
+// components added to it get one. Statuses are kept for all components
+func (w *watchdogImpl) ReloadConfig(config Config) error {
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid watchdog configuration: %w", err)
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	oldConfig := w.config
+
+	// Create everything that may fail first, so a failed reload keeps the
+	// running configuration
+	deadlockChanged := config.DeadlockDetection != oldConfig.DeadlockDetection
+	var deadlockDetector *DeadlockDetector
+	if deadlockChanged && config.DeadlockDetection.Enabled {
+		detector, err := NewDeadlockDetector(config.DeadlockDetection)
+		if err != nil {
+			return fmt.Errorf("failed to create deadlock detector: %w", err)
+		}
+		deadlockDetector = detector
+	}
+
+	incidentStoreChanged := config.IncidentStore != oldConfig.IncidentStore
+	var incidentStore IncidentStore
+	if incidentStoreChanged && config.IncidentStore.Enabled {
+		store, err := NewFileIncidentStore(config.IncidentStore)
+		if err != nil {
+			return fmt.Errorf("failed to create incident store: %w", err)
+		}
+		incidentStore = store
+	}
+
+	w.config = config
+
+	for name := range w.components {
+		w.reloadComponentConfig(name, oldConfig)
+	}
+
+	if config.RestartPolicy != oldConfig.RestartPolicy {
+		w.reloadRestartManagers()
+	}
+
+	if deadlockChanged {
+		w.reloadDeadlockDetection(deadlockDetector)
+	}
+
+	if incidentStoreChanged {
+		w.incidentStore = incidentStore
+	}
+
+	if w.diagnostics != nil {
+		w.diagnostics.SetMaxEvents(config.DiagnosticCollection.MaxEvents)
+		w.diagnostics.SetIncludeStackTraces(config.DiagnosticCollection.IncludeStackTraces)
+	}
+
+	log.Printf("Watchdog configuration reloaded")
+
+	return nil
+}
+
+// reloadComponentConfig applies the configuration of a registered component
+// if it changed from oldConfig. Components missing from a configuration have
+// the default one. Caller must hold mutex
+func (w *watchdogImpl) reloadComponentConfig(name string, oldConfig Config) {
+	config, configured := w.config.ComponentConfigs[name]
+	if !configured {
+		config = DefaultComponentConfig(name)
+	}
+	previous, wasConfigured := oldConfig.ComponentConfigs[name]
+	if !wasConfigured {
+		previous = DefaultComponentConfig(name)
+	}
+
+	// Only configured components are restarted
+	if configured != wasConfigured {
+		w.setRestartManager(name)
+	}
+
+	// Unchanged components keep thresholds set by SetThresholds
+	if reflect.DeepEqual(previous, config) {
+		return
+	}
+	current := w.componentConfigs[name]
+	w.componentConfigs[name] = config
+
+	status := w.componentStatuses[name]
+	if config.CircuitBreaker != current.CircuitBreaker {
+		if config.CircuitBreaker.Enabled {
+			w.circuitBreakers[name] = NewCircuitBreaker(name, config.CircuitBreaker)
+		} else {
+			delete(w.circuitBreakers, name)
+		}
+		status.CircuitState = CircuitClosed
+	}
+
+	// A component cannot stay at a level that no longer exists
+	if !reflect.DeepEqual(config.DegradationLevels, current.DegradationLevels) {
+		w.clampDegradationLevel(name, &status, len(config.DegradationLevels))
+	}
+	w.updateStatus(name, status)
+
+	log.Printf("Configuration reloaded for component: %s", name)
+}
+
+// reloadRestartManagers replaces the restart managers of the restartable
+// components with ones using the reloaded RestartPolicy, or removes them if
+// restarts are disabled. Restart attempts start over. Caller must hold mutex
+func (w *watchdogImpl) reloadRestartManagers() {
+	for name := range w.components {
+		w.setRestartManager(name)
+	}
+}
+
+// setRestartManager gives a restartable component configured in
+// ComponentConfigs a new restart manager if restarts are enabled, and removes
+// its manager otherwise. Caller must hold mutex
+func (w *watchdogImpl) setRestartManager(name string) {
+	restartable, ok := w.components[name].(Restartable)
+	_, configured := w.config.ComponentConfigs[name]
+	if ok && configured && w.config.RestartPolicy.Enabled {
+		w.restartManagers[name] = NewRestartManager(w.config.RestartPolicy, restartable)
+	} else {
+		delete(w.restartManagers, name)
+	}
+}
+
+// reloadDeadlockDetection replaces the deadlock detector, nil disabling
+// detection, and restarts its loop with the new interval. Registered
+// components count as having sent a heartbeat. Caller must hold mutex
+func (w *watchdogImpl) reloadDeadlockDetection(detector *DeadlockDetector) {
+	if w.deadlockCancel != nil {
+		w.deadlockCancel()
+		w.deadlockCancel = nil
+	}
+
+	w.deadlockDetector = detector
+	if detector == nil {
+		return
+	}
+
+	for name := range w.components {
+		detector.RegisterComponent(name)
+	}
+
+	if w.isRunning() {
+		w.startDeadlockDetection()
+	}
+}
+
+// clampDegradationLevel lowers the degradation level of a component above
+// maxLevel to it, updating status. Caller must hold mutex
+func (w *watchdogImpl) clampDegradationLevel(name string, status *ComponentStatus, maxLevel int) {
+	if status.DegradationLevel <= maxLevel {
+		return
+	}
+
+	degradable, ok := w.components[name].(Degradable)
+	if !ok {
+		return
+	}
+	if err := degradable.SetDegradationLevel(maxLevel); err != nil {
+		log.Printf("Failed to set degradation level of component %s: %v", name, err)
+		return
+	}
+	status.DegradationLevel = maxLevel
+}
+
+// monitorInterval returns the configured monitoring interval
+func (w *watchdogImpl) monitorInterval() time.Duration {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return w.config.MonitoringInterval
+}
+
+// monitorLoop is the main monitoring loop, running until ctx is done
+func (w *watchdogImpl) monitorLoop(ctx context.Context) {
+	defer w.monitorWg.Done()
+
+	interval := w.monitorInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
 		select {
-		case <-w.monitorContext.Done():
+		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			w.monitor.CollectGlobalMetrics()
 			w.monitorComponents()
+
+			// Pick up an interval changed by ReloadConfig
+			if newInterval := w.monitorInterval(); newInterval != interval {
+				interval = newInterval
+				ticker.Reset(interval)
+			}
 		}
 	}
 }
 
-// deadlockDetectionLoop runs the deadlock detection loop
-func (w *watchdogImpl) deadlockDetectionLoop() {
+// startDeadlockDetection starts the deadlock detection loop with the
+// configured interval. Caller must hold mutex
+func (w *watchdogImpl) startDeadlockDetection() {
+	interval := w.config.DeadlockDetection.CheckInterval
+	if interval <= 0 {
+		interval = w.config.DeadlockDetection.HeartbeatInterval
+	}
+
+	ctx, cancel := context.WithCancel(w.monitorContext)
+	w.deadlockCancel = cancel
+
+	w.monitorWg.Add(1)
+	go w.deadlockDetectionLoop(ctx, interval)
+}
+
+// deadlockDetectionLoop runs the deadlock detection loop until ctx is done
+func (w *watchdogImpl) deadlockDetectionLoop(ctx context.Context, interval time.Duration) {
 	defer w.monitorWg.Done()
-	
-	ticker := time.NewTicker(w.config.DeadlockDetection.CheckInterval)
+
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
-	
+
 	for {
 		select {
-		case <-w.monitorContext.Done():
+		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			if w.deadlockDetector != nil {
-				w.detectDeadlocks()
-			}
+			w.detectDeadlocks()
 		}
 	}
 }
@@ -519,58 +1014,63 @@ func (w *watchdogImpl) deadlockDetectionLoop() {
 func (w *watchdogImpl) monitorComponents() {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
-	
+
 	for name, component := range w.components {
 		monitorable, ok := component.(Monitorable)
 		if !ok {
 			continue
 		}
-		
+
 		// Get component configuration
 		config := w.componentConfigs[name]
-		
+
 		// Get current component status
 		status := w.componentStatuses[name]
-		
+
+		// Check whether the component stopped since the last check
+		w.checkCrash(name, component, &status)
+
 		// Get resource usage
 		resourceUsage := monitorable.GetResourceUsage()
 		status.ResourceUsage = resourceUsage
-		
+		w.recordMeasurement(&status, resourceUsage, time.Now())
+
 		// Get health status
 		health := monitorable.GetHealth()
 		status.Health = health
-		
+
 		// Check thresholds
-		exceeded, resource := w.checkThresholds(name, resourceUsage, config.Thresholds)
+		thresholds := config.thresholds()
+		exceeded, resource := w.checkThresholds(name, resourceUsage, thresholds)
 		if exceeded {
 			// Create an incident
-			incident := w.createResourceIncident(name, resource, resourceUsage, config.Thresholds)
+			incident := w.createResourceIncident(name, resource, resourceUsage, thresholds)
 			status.Incidents = append(status.Incidents, incident)
-			
+
 			// Limit the number of incidents
 			if len(status.Incidents) > 10 {
 				status.Incidents = status.Incidents[len(status.Incidents)-10:]
 			}
-			
+
 			// Update circuit breaker
 			circuitBreaker := w.circuitBreakers[name]
 			if circuitBreaker != nil {
 				circuitBreaker.RecordFailure()
 				status.CircuitState = circuitBreaker.State()
 			}
-			
+
 			// Handle degradation if component supports it
-			if w.config.DegradationEnabled && w.degradationController != nil {
+			if len(config.DegradationLevels) > 0 {
 				if degradable, ok := component.(Degradable); ok {
 					w.handleDegradation(name, degradable, &status)
 				}
 			}
-			
+
 			// Handle restart if component supports it and circuit is open
-			if restartManager, exists := w.restartManagers[name]; exists && 
-				status.CircuitState == CircuitOpen && 
-				config.Restart.Enabled {
-				w.handleRestart(name, restartManager, &status)
+			if restartManager, exists := w.restartManagers[name]; exists &&
+				status.CircuitState == CircuitOpen &&
+				w.config.RestartPolicy.Enabled {
+				w.handleRestart(name, restartManager, &status, false)
 			}
 		} else {
 			// Update circuit breaker with success
@@ -579,11 +1079,12 @@ func (w *watchdogImpl) monitorComponents() {
 				circuitBreaker.RecordSuccess()
 				status.CircuitState = circuitBreaker.State()
 			}
-			
-			// If circuit is closed, reset degradation if applicable
-			if status.CircuitState == CircuitClosed && 
-				w.config.DegradationEnabled && 
-				w.degradationController != nil {
+
+			// If circuit is closed, reset degradation if applicable, unless
+			// the component was degraded to fit the global budget
+			if status.CircuitState == CircuitClosed &&
+				!w.budgetExceeded &&
+				len(config.DegradationLevels) > 0 {
 				if degradable, ok := component.(Degradable); ok && status.DegradationLevel > 0 {
 					if err := degradable.SetDegradationLevel(0); err == nil {
 						status.DegradationLevel = 0
@@ -591,9 +1092,283 @@ func (w *watchdogImpl) monitorComponents() {
 				}
 			}
 		}
-		
+
+		// Report a component held at the maximum degradation level
+		w.checkSustainedDegradation(name, &status, time.Now())
+
 		// Update component status
-		w.componentStatuses[name] = status
+		w.updateStatus(name, status)
+	}
+
+	w.enforceGlobalBudget()
+}
+
+// recordMeasurement appends a measurement to the history of a component,
+// dropping the oldest beyond MeasurementHistoryLen. Caller must hold mutex
+func (w *watchdogImpl) recordMeasurement(status *ComponentStatus, usage ResourceUsage, now time.Time) {
+	maxLen := w.config.MeasurementHistoryLen
+	if maxLen <= 0 {
+		status.Measurements = nil
+		return
+	}
+
+	measurements := status.Measurements
+	if len(measurements) >= maxLen {
+		// Shift elements left, dropping the oldest
+		copy(measurements, measurements[len(measurements)-maxLen+1:])
+		measurements = measurements[:maxLen-1]
+	}
+	status.Measurements = append(measurements, TimestampedMeasurement{Timestamp: now, Usage: usage})
+}
+
+// checkSustainedDegradation reports a component once when it stays at its
+// maximum degradation level for longer than SustainedDegradationDuration.
+// The condition clears when the component leaves the maximum level. Caller
+// must hold mutex
+func (w *watchdogImpl) checkSustainedDegradation(name string, status *ComponentStatus, now time.Time) {
+	maxLevel := len(w.componentConfigs[name].DegradationLevels)
+	if w.config.SustainedDegradationDuration <= 0 || maxLevel <= 0 || status.DegradationLevel < maxLevel {
+		delete(w.maxDegradedSince, name)
+		delete(w.sustainedDegradations, name)
+		return
+	}
+
+	since, ok := w.maxDegradedSince[name]
+	if !ok {
+		w.maxDegradedSince[name] = now
+		return
+	}
+
+	held := now.Sub(since)
+	if w.sustainedDegradations[name] || held < w.config.SustainedDegradationDuration {
+		return
+	}
+	w.sustainedDegradations[name] = true
+
+	incident := w.createSustainedDegradationIncident(name, maxLevel, status.ResourceUsage, held)
+	status.Incidents = append(status.Incidents, incident)
+
+	// Limit the number of incidents
+	if len(status.Incidents) > 10 {
+		status.Incidents = status.Incidents[len(status.Incidents)-10:]
+	}
+}
+
+// createSustainedDegradationIncident creates and publishes an incident for a
+// component held at its maximum degradation level for the given time
+func (w *watchdogImpl) createSustainedDegradationIncident(name string, maxLevel int, usage ResourceUsage, held time.Duration) Incident {
+	description := fmt.Sprintf(
+		"Component %s has been at the maximum degradation level %d for %s",
+		name, maxLevel, held.Round(time.Second),
+	)
+
+	remediation := fmt.Sprintf(
+		"Degradation alone cannot keep component %s within its thresholds. Consider adding capacity, "+
+			"raising its thresholds or reducing its load.",
+		name,
+	)
+
+	incident := Incident{
+		ID:            fmt.Sprintf("%s-sustained-degradation-%d", name, time.Now().UnixNano()),
+		Timestamp:     time.Now(),
+		ComponentName: name,
+		Type:          IncidentSustainedDegradation,
+		Description:   description,
+		ResourceUsage: usage,
+		Remediation:   remediation,
+	}
+
+	// Log the incident
+	log.Printf("Incident detected: %s", description)
+	w.publishIncident(incident)
+
+	// Emit a diagnostic event if enabled
+	if w.diagnostics != nil {
+		w.diagnostics.EmitAgentDiagEvent(incident)
+	}
+
+	return incident
+}
+
+// enforceGlobalBudget degrades the highest consuming degradable components
+// one level when the total usage exceeds the global budget. Components are
+// degraded until their usage covers the excess, the next check degrades
+// further if the agent still does not fit. Components without degradation
+// levels, or at their last level, are skipped. Caller must hold mutex
+func (w *watchdogImpl) enforceGlobalBudget() {
+	budget := w.config.GlobalBudget
+	total := w.totalResourceUsage()
+
+	var cpuExcess, memoryExcess float64
+	if budget.MaxCPUPercent > 0 {
+		cpuExcess = total.CPUPercent - budget.MaxCPUPercent
+	}
+	if budget.MaxMemoryMB > 0 {
+		memoryExcess = total.MemoryMB() - float64(budget.MaxMemoryMB)
+	}
+
+	exceeded := cpuExcess > 0 || memoryExcess > 0
+	if exceeded && !w.budgetExceeded {
+		w.createBudgetIncident(total)
+	}
+	w.budgetExceeded = exceeded
+
+	if !exceeded || w.config.ObserveOnly {
+		return
+	}
+
+	for _, name := range w.componentsByConsumption(cpuExcess > 0, memoryExcess > 0) {
+		if cpuExcess <= 0 && memoryExcess <= 0 {
+			break
+		}
+
+		degradable, ok := w.components[name].(Degradable)
+		status := w.componentStatuses[name]
+		if !ok || status.DegradationLevel >= len(w.componentConfigs[name].DegradationLevels) {
+			continue
+		}
+
+		newLevel := status.DegradationLevel + 1
+		if err := degradable.SetDegradationLevel(newLevel); err != nil {
+			log.Printf("Failed to degrade component %s to fit the global budget: %v", name, err)
+			continue
+		}
+		status.DegradationLevel = newLevel
+		w.updateStatus(name, status)
+		log.Printf("Component %s degraded to level %d to fit the global budget", name, newLevel)
+
+		cpuExcess -= status.ResourceUsage.CPUPercent
+		memoryExcess -= status.ResourceUsage.MemoryMB()
+	}
+}
+
+// componentsByConsumption returns the components sorted by their share of
+// the exceeded budgets, highest first. Caller must hold mutex
+func (w *watchdogImpl) componentsByConsumption(cpu, memory bool) []string {
+	budget := w.config.GlobalBudget
+	shares := make(map[string]float64, len(w.componentStatuses))
+	names := make([]string, 0, len(w.componentStatuses))
+	for name, status := range w.componentStatuses {
+		var share float64
+		if cpu {
+			share += status.ResourceUsage.CPUPercent / budget.MaxCPUPercent
+		}
+		if memory {
+			share += status.ResourceUsage.MemoryMB() / float64(budget.MaxMemoryMB)
+		}
+		shares[name] = share
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if shares[names[i]] != shares[names[j]] {
+			return shares[names[i]] > shares[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	return names
+}
+
+// createBudgetIncident creates an incident for the total usage exceeding the
+// global budget. Caller must hold mutex
+func (w *watchdogImpl) createBudgetIncident(total ResourceUsage) {
+	budget := w.config.GlobalBudget
+	description := fmt.Sprintf(
+		"Global resource budget exceeded: CPU %.2f%% (budget %.2f%%), memory %.2fMB (budget %dMB)",
+		total.CPUPercent, budget.MaxCPUPercent, total.MemoryMB(), budget.MaxMemoryMB,
+	)
+
+	incident := Incident{
+		ID:            fmt.Sprintf("global-budget-%d", time.Now().UnixNano()),
+		Timestamp:     time.Now(),
+		Type:          IncidentBudgetExceeded,
+		Description:   description,
+		ResourceUsage: total,
+		Remediation:   "Consider increasing the global budget or reducing the load of the highest consuming components.",
+	}
+
+	// Log the incident
+	log.Printf("Incident detected: %s", description)
+	w.publishIncident(incident)
+
+	// Emit a diagnostic event if enabled
+	if w.diagnostics != nil {
+		w.diagnostics.EmitAgentDiagEvent(incident)
+	}
+}
+
+// checkCrash reports a crash if a restartable component stopped running
+// since the last check and was not stopped on purpose, and restarts it if
+// restarts are enabled. Caller must hold mutex
+func (w *watchdogImpl) checkCrash(name string, component interface{}, status *ComponentStatus) {
+	restartable, ok := component.(Restartable)
+	if !ok {
+		return
+	}
+
+	running := restartable.IsRunning()
+	crashed := w.lastRunning[name] && !running && !w.intentionalStops[name]
+	if running {
+		delete(w.intentionalStops, name)
+	}
+	w.lastRunning[name] = running
+	if !crashed {
+		// Retry a restart deferred by the global restart rate limit. Only a
+		// restart after a dependency is forced while the component runs
+		force, throttled := w.throttledRestarts[name]
+		if throttled && (force || !running) && w.config.RestartPolicy.Enabled {
+			if restartManager, exists := w.restartManagers[name]; exists {
+				w.handleRestart(name, restartManager, status, force)
+			}
+		}
+		return
+	}
+
+	// Create a crash incident
+	incident := Incident{
+		ID:            fmt.Sprintf("%s-crash-%d", name, time.Now().UnixNano()),
+		Timestamp:     time.Now(),
+		ComponentName: name,
+		Type:          IncidentCrash,
+		Description:   fmt.Sprintf("Component %s stopped unexpectedly", name),
+		ResourceUsage: status.ResourceUsage,
+		Remediation:   "Check component logs for the cause of the crash.",
+	}
+	status.Incidents = append(status.Incidents, incident)
+
+	// Limit the number of incidents
+	if len(status.Incidents) > 10 {
+		status.Incidents = status.Incidents[len(status.Incidents)-10:]
+	}
+
+	// Update circuit breaker
+	circuitBreaker := w.circuitBreakers[name]
+	if circuitBreaker != nil {
+		circuitBreaker.RecordFailure()
+		status.CircuitState = circuitBreaker.State()
+	}
+
+	// Log the incident
+	log.Printf("Crash detected: %s", incident.Description)
+	w.publishIncident(incident)
+
+	// Emit a diagnostic event if enabled
+	if w.diagnostics != nil {
+		w.diagnostics.EmitAgentDiagEvent(incident)
+	}
+
+	if restartManager, exists := w.restartManagers[name]; exists && w.config.RestartPolicy.Enabled {
+		w.handleRestart(name, restartManager, status, false)
+	}
+}
+
+// updateRunning records whether a restartable component runs after the
+// watchdog restarted it, so a failed restart is not reported as a crash.
+// Caller must hold mutex
+func (w *watchdogImpl) updateRunning(name string) {
+	if restartable, ok := w.components[name].(Restartable); ok {
+		w.lastRunning[name] = restartable.IsRunning()
 	}
 }
 
@@ -602,52 +1377,52 @@ func (w *watchdogImpl) checkThresholds(name string, usage ResourceUsage, thresho
 	if usage.CPUPercent > thresholds.MaxCPUPercent {
 		return true, "CPU"
 	}
-	
-	if usage.MemoryMB > float64(thresholds.MaxMemoryMB) {
+
+	if usage.MemoryMB() > float64(thresholds.MaxMemoryMB) {
 		return true, "Memory"
 	}
-	
+
 	if usage.Goroutines > thresholds.MaxGoroutines {
 		return true, "Goroutines"
 	}
-	
-	if usage.FileHandles > thresholds.MaxFileHandles {
-		return true, "FileHandles"
+
+	if usage.FileDescriptors > thresholds.MaxFileHandles {
+		return true, "FileDescriptors"
 	}
-	
-	if usage.GCPercent > thresholds.MaxGCPercent {
+
+	if thresholds.MaxGCPercent > 0 && usage.GCPercent > thresholds.MaxGCPercent {
 		return true, "GC"
 	}
-	
+
 	return false, ""
 }
 
 // createResourceIncident creates a resource incident
 func (w *watchdogImpl) createResourceIncident(
-	name string, 
-	resource string, 
-	usage ResourceUsage, 
+	name string,
+	resource string,
+	usage ResourceUsage,
 	thresholds ResourceThresholds,
 ) Incident {
 	var value float64
 	var threshold float64
 	var unit string
-	
+
 	switch resource {
 	case "CPU":
 		value = usage.CPUPercent
 		threshold = thresholds.MaxCPUPercent
 		unit = "%"
 	case "Memory":
-		value = usage.MemoryMB
+		value = usage.MemoryMB()
 		threshold = float64(thresholds.MaxMemoryMB)
 		unit = "MB"
 	case "Goroutines":
 		value = float64(usage.Goroutines)
 		threshold = float64(thresholds.MaxGoroutines)
 		unit = ""
-	case "FileHandles":
-		value = float64(usage.FileHandles)
+	case "FileDescriptors":
+		value = float64(usage.FileDescriptors)
 		threshold = float64(thresholds.MaxFileHandles)
 		unit = ""
 	case "GC":
@@ -655,60 +1430,69 @@ func (w *watchdogImpl) createResourceIncident(
 		threshold = thresholds.MaxGCPercent
 		unit = "%"
 	}
-	
+
 	description := fmt.Sprintf(
 		"%s usage exceeded for component %s: %.2f%s > %.2f%s",
 		resource, name, value, unit, threshold, unit,
 	)
-	
+
 	remediation := fmt.Sprintf(
 		"Consider increasing %s threshold or optimizing %s usage in component %s.",
 		resource, resource, name,
 	)
-	
+
 	// Create an incident
 	incident := Incident{
 		ID:            fmt.Sprintf("%s-%s-%d", name, resource, time.Now().UnixNano()),
 		Timestamp:     time.Now(),
+		ComponentName: name,
 		Type:          IncidentResourceExceeded,
 		Description:   description,
 		ResourceUsage: usage,
 		Remediation:   remediation,
 	}
-	
+
 	// Log the incident
 	log.Printf("Incident detected: %s", description)
-	
+	w.publishIncident(incident)
+
 	// Emit a diagnostic event if enabled
-	if w.config.EventsEnabled && w.diagnostics != nil {
+	if w.diagnostics != nil {
 		w.diagnostics.EmitAgentDiagEvent(incident)
 	}
-	
+
 	return incident
 }
 
-// handleDegradation handles degradation for a component
+// handleDegradation handles degradation for a component, unless the
+// watchdog only observes
 func (w *watchdogImpl) handleDegradation(
-	name string, 
-	degradable Degradable, 
+	name string,
+	degradable Degradable,
 	status *ComponentStatus,
 ) {
+	if w.config.ObserveOnly {
+		log.Printf("Observe-only mode, not degrading component %s", name)
+		return
+	}
+
 	currentLevel := status.DegradationLevel
-	
+	maxLevel := len(w.componentConfigs[name].DegradationLevels)
+
 	// Calculate new degradation level based on severity
 	var newLevel int
 	switch status.Health {
 	case HealthCritical:
-		newLevel = w.config.DegradationLevels // Max degradation
+		newLevel = maxLevel // Max degradation
 	case HealthDegraded:
 		newLevel = currentLevel + 1
-		if newLevel > w.config.DegradationLevels {
-			newLevel = w.config.DegradationLevels
+		if newLevel > maxLevel {
+			newLevel = maxLevel
 		}
 	default:
 		newLevel = currentLevel
 	}
-	
+
 	// Apply new degradation level if it has changed
 	if newLevel != currentLevel {
 		if err := degradable.SetDegradationLevel(newLevel); err == nil {
@@ -718,98 +1502,308 @@ func (w *watchdogImpl) handleDegradation(
 	}
 }
 
-// handleRestart handles restart for a component
+// handleRestart handles restart for a component, unless the watchdog only
+// observes. With force it is restarted even if it is running, e.g. a restart
+// after a dependency that was deferred
 func (w *watchdogImpl) handleRestart(
-	name string, 
-	restartManager *RestartManager, 
+	name string,
+	restartManager *RestartManager,
 	status *ComponentStatus,
+	force bool,
 ) {
+	if w.config.ObserveOnly {
+		log.Printf("Observe-only mode, not restarting component %s", name)
+		return
+	}
+
+	// Defer the restart while all components together restart too often.
+	// Only a restart that will happen takes a token, so a component waiting
+	// for its backoff or out of attempts does not starve the others
+	if restartManager.RestartDue(force) && !w.restartLimiter.Allow(time.Now()) {
+		w.recordRestartThrottled(name, force, status)
+		return
+	}
+	delete(w.throttledRestarts, name)
+
 	// Attempt to restart the component
-	success, err := restartManager.AttemptRestart(w.monitorContext)
-	
+	restart := restartManager.AttemptRestart
+	if force {
+		restart = restartManager.ForceRestart
+	}
+	success, err := restart(w.monitorContext)
+	w.updateRunning(name)
+
 	if success {
 		// Update restart metrics
 		status.LastRestart = time.Now()
 		status.RestartCount++
 		log.Printf("Component %s restarted successfully", name)
+
+		// Components depending on it must restart too
+		w.restartDependents(name)
 	} else {
-		// Create a restart failure incident
-		incident := Incident{
-			ID:          fmt.Sprintf("%s-restart-failure-%d", name, time.Now().UnixNano()),
-			Timestamp:   time.Now(),
-			Type:        IncidentRestartFailed,
-			Description: fmt.Sprintf("Failed to restart component %s: %v", name, err),
-			Remediation: "Check component implementation and logs for errors.",
+		w.recordRestartFailure(name, err, status)
+	}
+}
+
+// restartDependents restarts the components depending on name, directly or
+// transitively, with every component after its dependencies. A component is
+// skipped if one of its dependencies failed to restart or was deferred by the
+// global restart rate limit. Caller must hold mutex
+func (w *watchdogImpl) restartDependents(name string) {
+	failed := make(map[string]bool)
+
+	for _, dependent := range w.dependencies.dependentsInOrder(name) {
+		restartManager, exists := w.restartManagers[dependent]
+		if !exists {
+			continue
 		}
-		status.Incidents = append(status.Incidents, incident)
-		
-		// Log the incident
-		log.Printf("Restart failed: %s", incident.Description)
-		
-		// Emit a diagnostic event if enabled
-		if w.config.EventsEnabled && w.diagnostics != nil {
-			w.diagnostics.EmitAgentDiagEvent(incident)
+
+		if failedDependency := w.failedDependency(dependent, failed); failedDependency != "" {
+			failed[dependent] = true
+			log.Printf("Skipping restart of component %s, dependency %s was not restarted", dependent, failedDependency)
+			continue
+		}
+
+		status := w.componentStatuses[dependent]
+
+		// A deferred restart is retried by the next check, which restarts
+		// the dependents of the component after it
+		if restartManager.RestartDue(true) && !w.restartLimiter.Allow(time.Now()) {
+			failed[dependent] = true
+			w.recordRestartThrottled(dependent, true, &status)
+			w.updateStatus(dependent, status)
+			continue
+		}
+		delete(w.throttledRestarts, dependent)
+
+		success, err := restartManager.ForceRestart(w.monitorContext)
+		w.updateRunning(dependent)
+		if success {
+			status.LastRestart = time.Now()
+			status.RestartCount++
+			log.Printf("Component %s restarted after dependency %s", dependent, name)
+		} else {
+			failed[dependent] = true
+			w.recordRestartFailure(dependent, err, &status)
+		}
+
+		w.updateStatus(dependent, status)
+	}
+}
+
+// failedDependency returns a dependency of component in failed, or "" if none
+func (w *watchdogImpl) failedDependency(component string, failed map[string]bool) string {
+	for _, dependency := range w.dependencies[component] {
+		if failed[dependency] {
+			return dependency
+		}
+	}
+	return ""
+}
+
+// recordRestartFailure creates a restart failure incident for a component
+func (w *watchdogImpl) recordRestartFailure(name string, err error, status *ComponentStatus) {
+	incident := Incident{
+		ID:            fmt.Sprintf("%s-restart-failure-%d", name, time.Now().UnixNano()),
+		Timestamp:     time.Now(),
+		ComponentName: name,
+		Type:          IncidentRestartFailed,
+		Description:   fmt.Sprintf("Failed to restart component %s: %v", name, err),
+		Remediation:   "Check component implementation and logs for errors.",
+	}
+	status.Incidents = append(status.Incidents, incident)
+
+	// Log the incident
+	log.Printf("Restart failed: %s", incident.Description)
+	w.publishIncident(incident)
+
+	// Emit a diagnostic event if enabled
+	if w.diagnostics != nil {
+		w.diagnostics.EmitAgentDiagEvent(incident)
+	}
+}
+
+// recordRestartThrottled creates a restart throttled incident for a
+// component, once until one of its restarts is allowed. force records that
+// the deferred restart was forced. Caller must hold mutex
+func (w *watchdogImpl) recordRestartThrottled(name string, force bool, status *ComponentStatus) {
+	if _, throttled := w.throttledRestarts[name]; throttled {
+		w.throttledRestarts[name] = w.throttledRestarts[name] || force
+		return
+	}
+	w.throttledRestarts[name] = force
+
+	incident := Incident{
+		ID:            fmt.Sprintf("%s-restart-throttled-%d", name, time.Now().UnixNano()),
+		Timestamp:     time.Now(),
+		ComponentName: name,
+		Type:          IncidentRestartThrottled,
+		Description:   fmt.Sprintf("Restart of component %s deferred, the watchdog reached %d restarts per minute", name, w.config.MaxRestartsPerMinute),
+		ResourceUsage: status.ResourceUsage,
+		Remediation:   "Check for a host-wide problem making many components fail, or raise max_restarts_per_minute.",
+	}
+	status.Incidents = append(status.Incidents, incident)
+
+	// Limit the number of incidents
+	if len(status.Incidents) > 10 {
+		status.Incidents = status.Incidents[len(status.Incidents)-10:]
+	}
+
+	log.Printf("Restart throttled: %s", incident.Description)
+	w.publishIncident(incident)
+
+	// Emit a diagnostic event if enabled
+	if w.diagnostics != nil {
+		w.diagnostics.EmitAgentDiagEvent(incident)
+	}
+}
+
+// Subscribe returns a channel receiving the lifecycle events of all components
+func (w *watchdogImpl) Subscribe() (<-chan WatchdogEvent, func()) {
+	return w.events.subscribe()
+}
+
+// DroppedEvents returns the number of events dropped because a subscriber did not keep up
+func (w *watchdogImpl) DroppedEvents() uint64 {
+	return w.events.droppedEvents()
+}
+
+// updateStatus stores the status of a component and publishes an event for
+// each change of its health, circuit state and degradation level. Caller
+// must hold mutex
+func (w *watchdogImpl) updateStatus(name string, status ComponentStatus) {
+	old := w.componentStatuses[name]
+	w.componentStatuses[name] = status
+
+	now := time.Now()
+	if status.Health != old.Health {
+		w.events.publish(WatchdogEvent{
+			Type:          EventHealthChanged,
+			ComponentName: name,
+			Timestamp:     now,
+			OldHealth:     old.Health,
+			NewHealth:     status.Health,
+		})
+	}
+
+	if status.CircuitState != old.CircuitState {
+		w.events.publish(WatchdogEvent{
+			Type:            EventCircuitStateChanged,
+			ComponentName:   name,
+			Timestamp:       now,
+			OldCircuitState: old.CircuitState,
+			NewCircuitState: status.CircuitState,
+		})
+	}
+
+	if status.DegradationLevel != old.DegradationLevel {
+		w.events.publish(WatchdogEvent{
+			Type:                EventDegradationChanged,
+			ComponentName:       name,
+			Timestamp:           now,
+			OldDegradationLevel: old.DegradationLevel,
+			NewDegradationLevel: status.DegradationLevel,
+		})
+	}
+}
+
+// AddIncidentHook registers a hook called for every incident
+func (w *watchdogImpl) AddIncidentHook(hook NotificationHook) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.incidentHooks = append(w.incidentHooks, hook)
+}
+
+// publishIncident writes an incident to the incident store, if configured,
+// and notifies the incident hooks and subscribers. Failures are only logged
+// so they do not interrupt monitoring. Caller must hold mutex
+func (w *watchdogImpl) publishIncident(incident Incident) {
+	w.events.publish(WatchdogEvent{
+		Type:          EventIncident,
+		ComponentName: incident.ComponentName,
+		Timestamp:     incident.Timestamp,
+		Incident:      &incident,
+	})
+
+	if w.incidentStore != nil {
+		if err := w.incidentStore.Append(incident); err != nil {
+			log.Printf("Failed to persist incident %s: %v", incident.ID, err)
 		}
 	}
+
+	// Hooks may be slow, e.g. webhooks, so they do not run on the monitor goroutines
+	for _, hook := range w.incidentHooks {
+		go runIncidentHook(hook, incident)
+	}
+}
+
+// runIncidentHook calls a hook, logging its error or panic
+func runIncidentHook(hook NotificationHook, incident Incident) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Incident hook panicked for incident %s: %v", incident.ID, r)
+		}
+	}()
+
+	if err := hook(incident); err != nil {
+		log.Printf("Incident hook failed for incident %s: %v", incident.ID, err)
+	}
 }
 
 // detectDeadlocks checks for deadlocks in all components
 func (w *watchdogImpl) detectDeadlocks() {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
-	
+
 	if w.deadlockDetector == nil {
 		return
 	}
-	
-	// Get global goroutine count
-	globalMetrics := w.monitor.GetGlobalMetrics()
-	
-	// Skip detailed analysis if below threshold
-	if globalMetrics.Goroutines < w.config.DeadlockDetection.GoroutineThreshold {
-		return
-	}
-	
+
 	// Detect deadlocks
 	deadlocks := w.deadlockDetector.DetectDeadlocks()
-	
+
 	// Handle detected deadlocks
 	for _, deadlock := range deadlocks {
 		// Find the affected component
 		componentName := deadlock.ComponentName
-		
+
 		// Skip if component not registered
 		status, exists := w.componentStatuses[componentName]
 		if !exists {
 			continue
 		}
-		
+
 		// Create a deadlock incident
 		incident := Incident{
-			ID:          fmt.Sprintf("%s-deadlock-%d", componentName, time.Now().UnixNano()),
-			Timestamp:   time.Now(),
-			Type:        IncidentDeadlockDetected,
-			Description: fmt.Sprintf("Deadlock detected in component %s: %s", componentName, deadlock.Description),
-			Remediation: deadlock.Remediation,
+			ID:            fmt.Sprintf("%s-deadlock-%d", componentName, time.Now().UnixNano()),
+			Timestamp:     time.Now(),
+			ComponentName: componentName,
+			Type:          IncidentDeadlockDetected,
+			Description:   fmt.Sprintf("Deadlock detected in component %s: %s", componentName, deadlock.Description),
+			Remediation:   deadlock.Remediation,
+			StackTrace:    deadlock.StackTrace,
 		}
 		status.Incidents = append(status.Incidents, incident)
-		
+
 		// Update circuit breaker
 		circuitBreaker := w.circuitBreakers[componentName]
 		if circuitBreaker != nil {
 			circuitBreaker.RecordFailure()
 			status.CircuitState = circuitBreaker.State()
 		}
-		
+
 		// Log the incident
 		log.Printf("Deadlock detected: %s", incident.Description)
-		
+		w.publishIncident(incident)
+
 		// Emit a diagnostic event if enabled
-		if w.config.EventsEnabled && w.diagnostics != nil {
+		if w.diagnostics != nil {
 			w.diagnostics.EmitAgentDiagEvent(incident)
 		}
-		
+
 		// Update component status
-		w.componentStatuses[componentName] = status
+		w.updateStatus(componentName, status)
 	}
 }
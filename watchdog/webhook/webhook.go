@@ -0,0 +1,131 @@
+// Package webhook provides a watchdog incident hook posting incidents to an
+// HTTP endpoint. It is kept out of the watchdog package so that only agents
+// using it depend on net/http.
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/watchdog"
+)
+
+// Config holds the configuration of a webhook hook
+type Config struct {
+	// URL the incidents are posted to
+	URL string `yaml:"url"`
+
+	// Headers are added to every request, e.g. for authentication
+	Headers map[string]string `yaml:"headers"`
+
+	// Timeout of a single request
+	Timeout time.Duration `yaml:"timeout"`
+
+	// MaxRetries is the number of retries after a failed request
+	MaxRetries int `yaml:"max_retries"`
+
+	// InitialBackoff is the wait before the first retry, doubled for every
+	// further retry
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+
+	// MaxBackoff caps the wait between retries
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+// DefaultConfig returns a Config posting to url with default retries
+func DefaultConfig(url string) Config {
+	return Config{
+		URL:            url,
+		Timeout:        5 * time.Second,
+		MaxRetries:     3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+	}
+}
+
+// Hook posts incidents as JSON to a URL, retrying failed requests
+type Hook struct {
+	config Config
+	client *http.Client
+}
+
+// NewHook creates a webhook hook. Register its Notify method with
+// Watchdog.AddIncidentHook
+func NewHook(config Config) (*Hook, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("webhook URL must be set")
+	}
+
+	if config.Timeout <= 0 {
+		return nil, fmt.Errorf("webhook timeout must be positive")
+	}
+
+	if config.MaxRetries < 0 {
+		return nil, fmt.Errorf("webhook max retries must not be negative")
+	}
+
+	if config.MaxRetries > 0 && (config.InitialBackoff <= 0 || config.MaxBackoff < config.InitialBackoff) {
+		return nil, fmt.Errorf("webhook backoff must be positive and not exceed the max backoff")
+	}
+
+	return &Hook{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+// Notify posts the incident, retrying network errors, 429 and 5xx responses
+// with exponential backoff. It implements watchdog.NotificationHook
+func (h *Hook) Notify(incident watchdog.Incident) error {
+	body, err := json.Marshal(incident)
+	if err != nil {
+		return fmt.Errorf("failed to encode incident %s: %w", incident.ID, err)
+	}
+
+	backoff := h.config.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := h.post(body)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= h.config.MaxRetries {
+			return fmt.Errorf("failed to post incident %s after %d attempts: %w", incident.ID, attempt+1, err)
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > h.config.MaxBackoff {
+			backoff = h.config.MaxBackoff
+		}
+	}
+}
+
+// post sends one request and returns its error and whether it may be retried
+func (h *Hook) post(body []byte) (bool, error) {
+	request, err := http.NewRequest(http.MethodPost, h.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range h.config.Headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := h.client.Do(request)
+	if err != nil {
+		return true, err
+	}
+	response.Body.Close()
+
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode < 300:
+		return false, nil
+	case response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500:
+		return true, fmt.Errorf("unexpected status %s", response.Status)
+	default:
+		return false, fmt.Errorf("unexpected status %s", response.Status)
+	}
+}
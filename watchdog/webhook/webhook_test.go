@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/newrelic/infrastructure-agent/watchdog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testConfig returns a config with short backoffs
+func testConfig(url string) Config {
+	return Config{
+		URL:            url,
+		Headers:        map[string]string{"X-Api-Key": "secret"},
+		Timeout:        time.Second,
+		MaxRetries:     2,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	}
+}
+
+// statusServer responds with the given status codes in order, repeating the last one
+func statusServer(t *testing.T, codes ...int) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&requests, 1))
+		if n > len(codes) {
+			n = len(codes)
+		}
+		w.WriteHeader(codes[n-1])
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestNewHook(t *testing.T) {
+	_, err := NewHook(DefaultConfig("http://localhost"))
+	assert.NoError(t, err)
+
+	_, err = NewHook(DefaultConfig(""))
+	assert.Error(t, err)
+
+	config := DefaultConfig("http://localhost")
+	config.MaxBackoff = config.InitialBackoff / 2
+	_, err = NewHook(config)
+	assert.Error(t, err)
+}
+
+func TestHook_PostsIncident(t *testing.T) {
+	received := make(chan watchdog.Incident, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+
+		var incident watchdog.Incident
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&incident))
+		received <- incident
+	}))
+	defer server.Close()
+
+	hook, err := NewHook(testConfig(server.URL))
+	require.NoError(t, err)
+
+	incident := watchdog.Incident{
+		ID:            "collector-deadlock-1",
+		ComponentName: "collector",
+		Type:          watchdog.IncidentDeadlockDetected,
+		Description:   "Deadlock detected",
+	}
+	require.NoError(t, hook.Notify(incident))
+
+	got := <-received
+	assert.Equal(t, incident.ID, got.ID)
+	assert.Equal(t, incident.ComponentName, got.ComponentName)
+	assert.Equal(t, incident.Type, got.Type)
+}
+
+func TestHook_RetriesServerErrors(t *testing.T) {
+	server, requests := statusServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+
+	hook, err := NewHook(testConfig(server.URL))
+	require.NoError(t, err)
+
+	assert.NoError(t, hook.Notify(watchdog.Incident{ID: "incident-1"}))
+	assert.Equal(t, int32(3), atomic.LoadInt32(requests))
+}
+
+func TestHook_GivesUpAfterMaxRetries(t *testing.T) {
+	server, requests := statusServer(t, http.StatusInternalServerError)
+
+	hook, err := NewHook(testConfig(server.URL))
+	require.NoError(t, err)
+
+	err = hook.Notify(watchdog.Incident{ID: "incident-1"})
+	assert.ErrorContains(t, err, "after 3 attempts")
+	assert.Equal(t, int32(3), atomic.LoadInt32(requests))
+}
+
+func TestHook_DoesNotRetryClientErrors(t *testing.T) {
+	server, requests := statusServer(t, http.StatusBadRequest)
+
+	hook, err := NewHook(testConfig(server.URL))
+	require.NoError(t, err)
+
+	err = hook.Notify(watchdog.Incident{ID: "incident-1"})
+	assert.ErrorContains(t, err, "400")
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+}